@@ -0,0 +1,152 @@
+package orderedjson
+
+import (
+	"unicode/utf8"
+	"unsafe"
+)
+
+// Arena is memory for parsing that's reused from one document to the next instead of left to the
+// garbage collector, for services that parse a document per request and drop it when the request
+// is done. the tokens, the strings of the tree and its arrays are allocated in chunks that Reset
+// hands back all at once:
+//
+//	arena := NewArena()
+//	for request := range requests {
+//		tree, err := ParseOptions{Arena: arena}.Parse(request.Body)
+//		...
+//		arena.Reset()
+//	}
+//
+// objects and boxed numbers still come from the heap, since the ordered map allocates its own
+// entries. an arena isn't safe for concurrent use, so give each goroutine its own, e.g. with a
+// sync.Pool. don't combine it with an Interner that outlives the document, the interner would
+// keep strings that live in the arena.
+type Arena struct {
+	tokens  []Token
+	runes   []rune
+	offsets []int
+	bytes   chunks[byte]
+	items   chunks[interface{}]
+	// an array's items while it's being parsed, by depth
+	buffers [][]interface{}
+}
+
+// the size of a chunk in elements, bigger allocations get a chunk of their own
+const arenaChunkSize = 32 << 10
+
+func NewArena() *Arena {
+	return &Arena{}
+}
+
+// Reset frees everything allocated in the arena for reuse. the trees parsed with it must not be
+// used any more: their strings and arrays will be overwritten by the next parse.
+func (arena *Arena) Reset() {
+	clear(arena.tokens[:cap(arena.tokens)])
+	arena.tokens = arena.tokens[:0]
+	arena.bytes.reset()
+	arena.items.reset()
+}
+
+// the methods below work on a nil arena too, and allocate on the heap then
+
+func (arena *Arena) tokenBuffer() []Token {
+	if arena == nil {
+		return make([]Token, 0)
+	}
+
+	return arena.tokens[:0]
+}
+
+func (arena *Arena) keepTokens(tokens []Token) {
+	if arena != nil {
+		arena.tokens = tokens
+	}
+}
+
+func (arena *Arena) runesOf(data []byte) []rune {
+	if arena == nil {
+		return []rune(string(data))
+	}
+
+	runes := arena.runes[:0]
+	for i := 0; i < len(data); {
+		char, size := utf8.DecodeRune(data[i:])
+		runes = append(runes, char)
+		i += size
+	}
+
+	arena.runes = runes
+	return runes
+}
+
+func (arena *Arena) offsetBuffer(n int) []int {
+	if arena == nil {
+		return make([]int, 0, n)
+	}
+
+	if cap(arena.offsets) < n {
+		arena.offsets = make([]int, 0, n)
+	}
+
+	return arena.offsets[:0]
+}
+
+func (arena *Arena) string(b []byte) string {
+	if arena == nil || len(b) == 0 {
+		return string(b)
+	}
+
+	s := arena.bytes.alloc(len(b))
+	copy(s, b)
+	return unsafe.String(&s[0], len(s))
+}
+
+func (arena *Arena) itemBuffer(depth int) []interface{} {
+	for len(arena.buffers) <= depth {
+		arena.buffers = append(arena.buffers, nil)
+	}
+
+	return arena.buffers[depth][:0]
+}
+
+func (arena *Arena) keepItems(depth int, items []interface{}) {
+	arena.buffers[depth] = items
+}
+
+// a copy of items in the arena. the buffer is cleared so it doesn't keep the values alive.
+func (arena *Arena) array(items []interface{}) []interface{} {
+	result := arena.items.alloc(len(items))
+	copy(result, items)
+	clear(items)
+	return result
+}
+
+// fixed size chunks handed out front to back, and reused after reset
+type chunks[T any] struct {
+	list    [][]T
+	current int
+}
+
+func (c *chunks[T]) alloc(n int) []T {
+	for ; c.current < len(c.list); c.current++ {
+		chunk := c.list[c.current]
+		if used := len(chunk); cap(chunk)-used >= n {
+			c.list[c.current] = chunk[:used+n]
+			return chunk[used : used+n : used+n]
+		}
+	}
+
+	chunk := make([]T, n, max(n, arenaChunkSize))
+	c.list = append(c.list, chunk)
+	return chunk[:n:n]
+}
+
+func (c *chunks[T]) reset() {
+	for i, chunk := range c.list {
+		// so the garbage collector can free what the chunk pointed to
+		clear(chunk)
+		c.list[i] = chunk[:0]
+	}
+
+	c.current = 0
+}
@@ -0,0 +1,45 @@
+package orderedjson
+
+import (
+	"reflect"
+
+	"github.com/michaelhelvey/orderedjson/v2/cbor"
+)
+
+// BinaryObject implements encoding.BinaryMarshaler and encoding.BinaryUnmarshaler for an ordered
+// tree, so it can be cached (redis, memcached) or sent between processes without going back through
+// json text. encoding/gob picks the methods up too, so a BinaryObject can be a field of a gob
+// encoded struct. like SQLObject, it's a wrapper because JsonObject is an alias we can't add
+// methods to.
+//
+// the bytes are CBOR (see the cbor package), which keeps the key order and decodes a lot faster than
+// parsing json.
+type BinaryObject struct {
+	Tree *JsonObject
+}
+
+func (object BinaryObject) MarshalBinary() ([]byte, error) {
+	if object.Tree == nil {
+		return cbor.Encode(nil)
+	}
+
+	return cbor.Encode(object.Tree)
+}
+
+func (object *BinaryObject) UnmarshalBinary(data []byte) error {
+	value, err := cbor.Decode(data)
+	if err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case nil:
+		object.Tree = nil
+	case *JsonObject:
+		object.Tree = v
+	default:
+		return &UnmarshalTypeError{Value: TypeName(value), Type: reflect.TypeOf(object.Tree)}
+	}
+
+	return nil
+}
@@ -0,0 +1,796 @@
+// Package bson converts between ordered json trees and BSON documents. BSON documents are ordered
+// like the tree is, so a document can be read, changed and written back without its fields moving.
+//
+// types json doesn't have are written as MongoDB Extended JSON (v2) objects: {"$oid": "..."},
+// {"$date": "..."}, {"$binary": {"base64": "...", "subType": "00"}}, {"$numberDecimal": "..."} and
+// so on. Encode turns those back into their BSON types, so they survive the round trip.
+package bson
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type JsonObject = orderedmap.OrderedMap[string, interface{}]
+
+// element types
+const (
+	typeDouble     = 0x01
+	typeString     = 0x02
+	typeDocument   = 0x03
+	typeArray      = 0x04
+	typeBinary     = 0x05
+	typeUndefined  = 0x06
+	typeObjectID   = 0x07
+	typeBool       = 0x08
+	typeDateTime   = 0x09
+	typeNull       = 0x0a
+	typeRegex      = 0x0b
+	typeDBPointer  = 0x0c
+	typeCode       = 0x0d
+	typeSymbol     = 0x0e
+	typeCodeScope  = 0x0f
+	typeInt32      = 0x10
+	typeTimestamp  = 0x11
+	typeInt64      = 0x12
+	typeDecimal128 = 0x13
+	typeMinKey     = 0xff
+	typeMaxKey     = 0x7f
+)
+
+// Encode encodes document as BSON. integral numbers become int32 when they fit and int64 when
+// they don't (up to 2^53, where float64 stops being exact, or the whole int64 range for
+// json.Numbers), everything else is a double. use {"$numberDouble": "1.0"} (or $numberInt,
+// $numberLong) for a specific type.
+func Encode(document *JsonObject) ([]byte, error) {
+	return appendDocument(make([]byte, 0, 64), document)
+}
+
+func appendDocument(buf []byte, document *JsonObject) ([]byte, error) {
+	start := len(buf)
+	buf = append(buf, 0, 0, 0, 0)
+	for pair := document.Oldest(); pair != nil; pair = pair.Next() {
+		var err error
+		if buf, err = appendElement(buf, pair.Key, pair.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	buf = append(buf, 0)
+	binary.LittleEndian.PutUint32(buf[start:], uint32(len(buf)-start))
+	return buf, nil
+}
+
+func appendCString(buf []byte, s string) ([]byte, error) {
+	if bytes.IndexByte([]byte(s), 0) >= 0 {
+		return nil, fmt.Errorf("bson: %q has a NUL byte, which BSON can't have in names and patterns", s)
+	}
+
+	return append(append(buf, s...), 0), nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(s)+1))
+	return append(append(buf, s...), 0)
+}
+
+func appendElement(buf []byte, name string, value interface{}) ([]byte, error) {
+	head := len(buf)
+	buf, err := appendCString(append(buf, 0), name)
+	if err != nil {
+		return nil, err
+	}
+
+	elementType, buf, err := appendValue(buf, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	buf[head] = elementType
+	return buf, nil
+}
+
+func appendValue(buf []byte, value interface{}) (byte, []byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return typeNull, buf, nil
+	case bool:
+		if v {
+			return typeBool, append(buf, 1), nil
+		}
+
+		return typeBool, append(buf, 0), nil
+	case string:
+		return typeString, appendString(buf, v), nil
+	case float64:
+		switch {
+		case v == 0 && math.Signbit(v):
+			// -0 is only a double
+		case v == math.Trunc(v) && v >= math.MinInt32 && v <= math.MaxInt32:
+			return typeInt32, binary.LittleEndian.AppendUint32(buf, uint32(int32(v))), nil
+		case v == math.Trunc(v) && math.Abs(v) <= 1<<53:
+			return typeInt64, binary.LittleEndian.AppendUint64(buf, uint64(int64(v))), nil
+		}
+
+		return typeDouble, appendDouble(buf, v), nil
+	case json.Number:
+		// integers keep all their digits when they fit in an int64
+		if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			if n >= math.MinInt32 && n <= math.MaxInt32 {
+				return typeInt32, binary.LittleEndian.AppendUint32(buf, uint32(int32(n))), nil
+			}
+
+			return typeInt64, binary.LittleEndian.AppendUint64(buf, uint64(n)), nil
+		}
+
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return 0, nil, fmt.Errorf("bson: %q isn't a number", string(v))
+		}
+
+		return appendValue(buf, f)
+	case []interface{}:
+		array := orderedmap.New[string, interface{}](len(v))
+		for i, item := range v {
+			array.Set(strconv.Itoa(i), item)
+		}
+
+		buf, err := appendDocument(buf, array)
+		return typeArray, buf, err
+	case *JsonObject:
+		if elementType, encoded, ok, err := appendExtended(buf, v); ok || err != nil {
+			return elementType, encoded, err
+		}
+
+		buf, err := appendDocument(buf, v)
+		return typeDocument, buf, err
+	}
+
+	return 0, nil, fmt.Errorf("bson: cannot encode %T", value)
+}
+
+func appendDouble(buf []byte, n float64) []byte {
+	if math.IsNaN(n) {
+		// the quiet NaN other encoders write, rather than math.NaN's
+		n = math.Float64frombits(0x7ff8000000000000)
+	}
+
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(n))
+}
+
+// the keys of object, when it has exactly the ones in keys
+func wrapper(object *JsonObject, keys ...string) ([]interface{}, bool) {
+	if object.Len() != len(keys) {
+		return nil, false
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		value, ok := object.Get(key)
+		if !ok {
+			return nil, false
+		}
+
+		values[i] = value
+	}
+
+	return values, true
+}
+
+// the values of the keys of value, when it's an object with exactly those keys
+func fieldsOf(value interface{}, keys ...string) ([]interface{}, bool) {
+	object, ok := value.(*JsonObject)
+	if !ok {
+		return make([]interface{}, len(keys)), false
+	}
+
+	values, ok := wrapper(object, keys...)
+	if !ok {
+		return make([]interface{}, len(keys)), false
+	}
+
+	return values, true
+}
+
+// writes an extended json object as the type it stands for. ok is false for any other object,
+// which includes things like {"$set": ...} that only look like one.
+func appendExtended(buf []byte, object *JsonObject) (elementType byte, result []byte, ok bool, err error) {
+	if object.Len() == 0 || object.Oldest().Key == "" || object.Oldest().Key[0] != '$' {
+		return 0, nil, false, nil
+	}
+
+	fail := func(format string, args ...interface{}) (byte, []byte, bool, error) {
+		return 0, nil, true, fmt.Errorf("bson: "+format, args...)
+	}
+
+	if values, ok := wrapper(object, "$oid"); ok {
+		id, err := hexString(values[0], 12)
+		if err != nil {
+			return fail("$oid: %v", err)
+		}
+
+		return typeObjectID, append(buf, id...), true, nil
+	}
+
+	if values, ok := wrapper(object, "$date"); ok {
+		millis, err := dateMillis(values[0])
+		if err != nil {
+			return fail("$date: %v", err)
+		}
+
+		return typeDateTime, binary.LittleEndian.AppendUint64(buf, uint64(millis)), true, nil
+	}
+
+	if values, ok := wrapper(object, "$numberInt"); ok {
+		text, _ := values[0].(string)
+		n, err := strconv.ParseInt(text, 10, 32)
+		if err != nil {
+			return fail("$numberInt %v isn't a 32 bit integer", values[0])
+		}
+
+		return typeInt32, binary.LittleEndian.AppendUint32(buf, uint32(int32(n))), true, nil
+	}
+
+	if values, ok := wrapper(object, "$numberLong"); ok {
+		n, err := numberLong(values[0])
+		if err != nil {
+			return fail("%v", err)
+		}
+
+		return typeInt64, binary.LittleEndian.AppendUint64(buf, uint64(n)), true, nil
+	}
+
+	if values, ok := wrapper(object, "$numberDouble"); ok {
+		text, _ := values[0].(string)
+		var n float64
+		switch text {
+		case "Infinity":
+			n = math.Inf(1)
+		case "-Infinity":
+			n = math.Inf(-1)
+		case "NaN":
+			n = math.NaN()
+		default:
+			if n, err = strconv.ParseFloat(text, 64); err != nil {
+				return fail("$numberDouble %v isn't a number", values[0])
+			}
+		}
+
+		return typeDouble, appendDouble(buf, n), true, nil
+	}
+
+	if values, ok := wrapper(object, "$numberDecimal"); ok {
+		text, _ := values[0].(string)
+		high, low, err := parseDecimal128(text)
+		if err != nil {
+			return fail("$numberDecimal: %v", err)
+		}
+
+		buf = binary.LittleEndian.AppendUint64(buf, low)
+		return typeDecimal128, binary.LittleEndian.AppendUint64(buf, high), true, nil
+	}
+
+	if values, ok := wrapper(object, "$binary"); ok {
+		parts, ok := fieldsOf(values[0], "base64", "subType")
+		if !ok {
+			return fail("$binary should be an object with base64 and subType")
+		}
+
+		return appendBinary(buf, parts[0], parts[1], fail)
+	}
+
+	if values, ok := wrapper(object, "$binary", "$type"); ok {
+		// the legacy form
+		return appendBinary(buf, values[0], values[1], fail)
+	}
+
+	if values, ok := wrapper(object, "$regularExpression"); ok {
+		parts, ok := fieldsOf(values[0], "pattern", "options")
+		if !ok {
+			return fail("$regularExpression should be an object with pattern and options")
+		}
+
+		for _, part := range parts {
+			text, _ := part.(string)
+			if buf, err = appendCString(buf, text); err != nil {
+				return 0, nil, true, err
+			}
+		}
+
+		return typeRegex, buf, true, nil
+	}
+
+	if values, ok := wrapper(object, "$timestamp"); ok {
+		parts, ok := fieldsOf(values[0], "t", "i")
+		if !ok {
+			return fail("$timestamp should be an object with t and i")
+		}
+
+		t, tOK := parts[0].(float64)
+		i, iOK := parts[1].(float64)
+		if !tOK || !iOK || t < 0 || i < 0 || t > math.MaxUint32 || i > math.MaxUint32 {
+			return fail("$timestamp t and i should be 32 bit unsigned integers")
+		}
+
+		return typeTimestamp, binary.LittleEndian.AppendUint64(buf, uint64(t)<<32|uint64(i)), true, nil
+	}
+
+	if values, ok := wrapper(object, "$code"); ok {
+		code, isString := values[0].(string)
+		if !isString {
+			return fail("$code should be a string")
+		}
+
+		return typeCode, appendString(buf, code), true, nil
+	}
+
+	if values, ok := wrapper(object, "$code", "$scope"); ok {
+		code, isString := values[0].(string)
+		scope, isObject := values[1].(*JsonObject)
+		if !isString || !isObject {
+			return fail("$code should be a string and $scope an object")
+		}
+
+		start := len(buf)
+		buf = appendString(append(buf, 0, 0, 0, 0), code)
+		if buf, err = appendDocument(buf, scope); err != nil {
+			return 0, nil, true, err
+		}
+
+		binary.LittleEndian.PutUint32(buf[start:], uint32(len(buf)-start))
+		return typeCodeScope, buf, true, nil
+	}
+
+	if values, ok := wrapper(object, "$symbol"); ok {
+		symbol, isString := values[0].(string)
+		if !isString {
+			return fail("$symbol should be a string")
+		}
+
+		return typeSymbol, appendString(buf, symbol), true, nil
+	}
+
+	if values, ok := wrapper(object, "$dbPointer"); ok {
+		parts, ok := fieldsOf(values[0], "$ref", "$id")
+		namespace, isString := parts[0].(string)
+		if !ok || !isString {
+			return fail("$dbPointer should be an object with $ref and $id")
+		}
+
+		oid, ok := fieldsOf(parts[1], "$oid")
+		if !ok {
+			return fail("$dbPointer $id should be an $oid")
+		}
+
+		raw, err := hexString(oid[0], 12)
+		if err != nil {
+			return fail("$dbPointer: %v", err)
+		}
+
+		return typeDBPointer, append(appendString(buf, namespace), raw...), true, nil
+	}
+
+	for key, elementType := range map[string]byte{"$minKey": typeMinKey, "$maxKey": typeMaxKey, "$undefined": typeUndefined} {
+		if _, ok := wrapper(object, key); ok {
+			return elementType, buf, true, nil
+		}
+	}
+
+	return 0, nil, false, nil
+}
+
+func appendBinary(buf []byte, data, subType interface{}, fail func(string, ...interface{}) (byte, []byte, bool, error)) (byte, []byte, bool, error) {
+	text, _ := data.(string)
+	raw, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return fail("$binary base64: %v", err)
+	}
+
+	kind, err := hexString(subType, 1)
+	if err != nil {
+		return fail("$binary subType: %v", err)
+	}
+
+	if kind[0] == 0x02 {
+		// the old binary subtype has its length again inside
+		raw = append(binary.LittleEndian.AppendUint32(nil, uint32(len(raw))), raw...)
+	}
+
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(raw)))
+	return typeBinary, append(append(buf, kind[0]), raw...), true, nil
+}
+
+// the bytes of a hex string value of size bytes
+func hexString(value interface{}, size int) ([]byte, error) {
+	text, ok := value.(string)
+	if len(text) == 1 {
+		// subTypes can be written as one digit
+		text = "0" + text
+	}
+
+	raw, err := hex.DecodeString(text)
+	if !ok || err != nil || len(raw) != size {
+		return nil, fmt.Errorf("expected %d hex digits, got %v", size*2, value)
+	}
+
+	return raw, nil
+}
+
+func numberLong(value interface{}) (int64, error) {
+	text, _ := value.(string)
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("$numberLong %v isn't a 64 bit integer", value)
+	}
+
+	return n, nil
+}
+
+// milliseconds since the epoch for a $date value: an RFC 3339 string, a number, or a $numberLong
+func dateMillis(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return 0, err
+		}
+
+		return t.UnixMilli(), nil
+	case float64:
+		return int64(v), nil
+	case *JsonObject:
+		if values, ok := wrapper(v, "$numberLong"); ok {
+			return numberLong(values[0])
+		}
+	}
+
+	return 0, fmt.Errorf("expected a date string or milliseconds, got %v", value)
+}
+
+// Decode decodes a BSON document into relaxed extended json: numbers that fit in a float64
+// exactly are plain numbers, and dates between the years 1970 and 9999 are RFC 3339 strings.
+func Decode(data []byte) (*JsonObject, error) {
+	return decode(data, false)
+}
+
+// DecodeCanonical decodes a BSON document into canonical extended json, where every number says
+// what type it is ({"$numberInt": "1"}, {"$numberDouble": "1.0"}...), so Encode gives back exactly
+// the same bytes.
+func DecodeCanonical(data []byte) (*JsonObject, error) {
+	return decode(data, true)
+}
+
+func decode(data []byte, canonical bool) (*JsonObject, error) {
+	decoder := &decoder{data: data, canonical: canonical}
+	document, err := decoder.decodeDocument()
+	if err != nil {
+		return nil, err
+	}
+
+	if decoder.idx != len(data) {
+		return nil, fmt.Errorf("bson: %d trailing bytes after document", len(data)-decoder.idx)
+	}
+
+	return document, nil
+}
+
+type decoder struct {
+	data      []byte
+	idx       int
+	depth     int
+	canonical bool
+}
+
+func (decoder *decoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("bson: %s at offset %d", fmt.Sprintf(format, args...), decoder.idx)
+}
+
+func (decoder *decoder) read(n int) ([]byte, error) {
+	if n < 0 || n > len(decoder.data)-decoder.idx {
+		return nil, decoder.errorf("unexpected end of data")
+	}
+
+	result := decoder.data[decoder.idx : decoder.idx+n]
+	decoder.idx += n
+	return result, nil
+}
+
+func (decoder *decoder) readUint32() (uint32, error) {
+	b, err := decoder.read(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (decoder *decoder) readUint64() (uint64, error) {
+	b, err := decoder.read(8)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func (decoder *decoder) readCString() (string, error) {
+	end := bytes.IndexByte(decoder.data[decoder.idx:], 0)
+	if end < 0 {
+		return "", decoder.errorf("unterminated name")
+	}
+
+	result := string(decoder.data[decoder.idx : decoder.idx+end])
+	decoder.idx += end + 1
+	return result, nil
+}
+
+func (decoder *decoder) readString() (string, error) {
+	length, err := decoder.readUint32()
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := decoder.read(int(int32(length)))
+	if err != nil {
+		return "", err
+	}
+
+	if len(raw) == 0 || raw[len(raw)-1] != 0 {
+		return "", decoder.errorf("string isn't NUL terminated")
+	}
+
+	return string(raw[:len(raw)-1]), nil
+}
+
+// an object with one key
+func single(key string, value interface{}) *JsonObject {
+	object := orderedmap.New[string, interface{}]()
+	object.Set(key, value)
+	return object
+}
+
+func (decoder *decoder) decodeDocument() (*JsonObject, error) {
+	decoder.depth++
+	defer func() { decoder.depth-- }()
+
+	if decoder.depth > 10000 {
+		return nil, decoder.errorf("nesting too deep")
+	}
+
+	start := decoder.idx
+	length, err := decoder.readUint32()
+	if err != nil {
+		return nil, err
+	}
+
+	end := start + int(int32(length))
+	if int32(length) < 5 || end > len(decoder.data) {
+		return nil, decoder.errorf("invalid document length %d", int32(length))
+	}
+
+	result := orderedmap.New[string, interface{}]()
+	for decoder.idx < end-1 {
+		elementType := decoder.data[decoder.idx]
+		decoder.idx++
+
+		name, err := decoder.readCString()
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := decoder.decodeValue(elementType)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Set(name, value)
+	}
+
+	if decoder.idx != end-1 || decoder.data[decoder.idx] != 0 {
+		return nil, decoder.errorf("document doesn't end where its length says")
+	}
+	decoder.idx++
+
+	return result, nil
+}
+
+func (decoder *decoder) decodeValue(elementType byte) (interface{}, error) {
+	switch elementType {
+	case typeDouble:
+		bits, err := decoder.readUint64()
+		n := math.Float64frombits(bits)
+		if decoder.canonical || math.IsNaN(n) || math.IsInf(n, 0) {
+			return single("$numberDouble", formatDouble(n)), err
+		}
+
+		return n, err
+	case typeString:
+		return decoder.readString()
+	case typeDocument:
+		return decoder.decodeDocument()
+	case typeArray:
+		document, err := decoder.decodeDocument()
+		if err != nil {
+			return nil, err
+		}
+
+		array := make([]interface{}, 0, document.Len())
+		for pair := document.Oldest(); pair != nil; pair = pair.Next() {
+			array = append(array, pair.Value)
+		}
+
+		return array, nil
+	case typeBinary:
+		length, err := decoder.readUint32()
+		if err != nil {
+			return nil, err
+		}
+
+		kind, err := decoder.read(1)
+		if err != nil {
+			return nil, err
+		}
+
+		raw, err := decoder.read(int(int32(length)))
+		if err != nil {
+			return nil, err
+		}
+
+		if kind[0] == 0x02 && len(raw) >= 4 {
+			raw = raw[4:]
+		}
+
+		fields := orderedmap.New[string, interface{}]()
+		fields.Set("base64", base64.StdEncoding.EncodeToString(raw))
+		fields.Set("subType", hex.EncodeToString(kind))
+		return single("$binary", fields), nil
+	case typeUndefined:
+		return single("$undefined", true), nil
+	case typeObjectID:
+		id, err := decoder.read(12)
+		return single("$oid", hex.EncodeToString(id)), err
+	case typeBool:
+		b, err := decoder.read(1)
+		if err != nil {
+			return nil, err
+		}
+
+		return b[0] != 0, nil
+	case typeDateTime:
+		n, err := decoder.readUint64()
+		millis := int64(n)
+		t := time.UnixMilli(millis).UTC()
+		if decoder.canonical || t.Year() < 1970 || t.Year() > 9999 {
+			return single("$date", single("$numberLong", strconv.FormatInt(millis, 10))), err
+		}
+
+		return single("$date", t.Format("2006-01-02T15:04:05.000Z07:00")), err
+	case typeNull:
+		return nil, nil
+	case typeRegex:
+		pattern, err := decoder.readCString()
+		if err != nil {
+			return nil, err
+		}
+
+		options, err := decoder.readCString()
+		fields := orderedmap.New[string, interface{}]()
+		fields.Set("pattern", pattern)
+		fields.Set("options", options)
+		return single("$regularExpression", fields), err
+	case typeDBPointer:
+		namespace, err := decoder.readString()
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := decoder.read(12)
+		fields := orderedmap.New[string, interface{}]()
+		fields.Set("$ref", namespace)
+		fields.Set("$id", single("$oid", hex.EncodeToString(id)))
+		return single("$dbPointer", fields), err
+	case typeCode:
+		code, err := decoder.readString()
+		return single("$code", code), err
+	case typeSymbol:
+		symbol, err := decoder.readString()
+		return single("$symbol", symbol), err
+	case typeCodeScope:
+		if _, err := decoder.readUint32(); err != nil {
+			return nil, err
+		}
+
+		code, err := decoder.readString()
+		if err != nil {
+			return nil, err
+		}
+
+		scope, err := decoder.decodeDocument()
+		result := single("$code", code)
+		result.Set("$scope", scope)
+		return result, err
+	case typeInt32:
+		n, err := decoder.readUint32()
+		if decoder.canonical {
+			return single("$numberInt", strconv.Itoa(int(int32(n)))), err
+		}
+
+		return float64(int32(n)), err
+	case typeTimestamp:
+		n, err := decoder.readUint64()
+		fields := orderedmap.New[string, interface{}]()
+		fields.Set("t", float64(n>>32))
+		fields.Set("i", float64(uint32(n)))
+		return single("$timestamp", fields), err
+	case typeInt64:
+		n, err := decoder.readUint64()
+		if decoder.canonical || int64(n) > 1<<53 || int64(n) < -(1<<53) {
+			return single("$numberLong", strconv.FormatInt(int64(n), 10)), err
+		}
+
+		return float64(int64(n)), err
+	case typeDecimal128:
+		low, err := decoder.readUint64()
+		if err != nil {
+			return nil, err
+		}
+
+		high, err := decoder.readUint64()
+		return single("$numberDecimal", formatDecimal128(high, low)), err
+	case typeMinKey:
+		return single("$minKey", 1.0), nil
+	case typeMaxKey:
+		return single("$maxKey", 1.0), nil
+	}
+
+	decoder.idx--
+	return nil, decoder.errorf("unknown element type 0x%02x", elementType)
+}
+
+func formatDouble(n float64) string {
+	switch {
+	case math.IsNaN(n):
+		return "NaN"
+	case math.IsInf(n, 1):
+		return "Infinity"
+	case math.IsInf(n, -1):
+		return "-Infinity"
+	}
+
+	text := strconv.FormatFloat(n, 'G', -1, 64)
+	if n == math.Trunc(n) && !bytes.ContainsAny([]byte(text), ".E") {
+		text += ".0"
+	}
+
+	return text
+}
+
+// Split splits data into the documents in it, for files with more than one document one after
+// another, like the .bson files mongodump writes.
+func Split(data []byte) ([][]byte, error) {
+	var documents [][]byte
+	for offset := 0; offset < len(data); {
+		if len(data)-offset < 5 {
+			return nil, fmt.Errorf("bson: unexpected end of data at offset %d", offset)
+		}
+
+		length := int(int32(binary.LittleEndian.Uint32(data[offset:])))
+		if length < 5 || length > len(data)-offset {
+			return nil, fmt.Errorf("bson: invalid document length %d at offset %d", length, offset)
+		}
+
+		documents = append(documents, data[offset:offset+length])
+		offset += length
+	}
+
+	return documents, nil
+}
@@ -0,0 +1,286 @@
+package bson
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/michaelhelvey/orderedjson/v2/internal/testtree"
+)
+
+// documents come back byte for byte through relaxed extended json, besides doubles that are whole
+// numbers, which are written as the smallest integer that holds them
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		bson    string
+		encoded string
+	}{
+		{"1b000000107a00010000000361000c000000106200020000000000", ""},
+		{"0c0000001069000000008000", ""},
+		{"10000000126100000000800000000000", ""},
+		{"10000000126100ffffffffffffff7f00", ""},
+		{"10000000016400000000000000f83f00", ""},
+		{"10000000016400000000000000f03f00", "0c0000001064000100000000"},
+		{"10000000016400000000000000008000", ""},
+		{"10000000016400000000000000f87f00", ""},
+		{"1400000007610056e1fc72e0c917e9c471416100", ""},
+		{"10000000096100000000000000000000", ""},
+		{"10000000096100ffffffffffffffff00", ""},
+		{"0f0000000578000200000080ffff00", ""},
+		{"0f0000000b610061626300696d0000", ""},
+		{"100000001161002a00000015cd5b0700", ""},
+		{"180000001364000100000000000000000000000000403000", ""},
+		{"08000000ff610000", ""},
+		// operator keys are plain keys
+		{"170000000324736574000c000000106100010000000000", ""},
+	}
+
+	for _, test := range tests {
+		raw, _ := hex.DecodeString(test.bson)
+		decoded, err := Decode(raw)
+		if err != nil {
+			t.Errorf("Decode(%s): %v", test.bson, err)
+			continue
+		}
+
+		want := test.encoded
+		if want == "" {
+			want = test.bson
+		}
+
+		data, err := Encode(decoded)
+		if got := hex.EncodeToString(data); err != nil || got != want {
+			t.Errorf("Encode(Decode(%s)) = %s, %v, want %s (from %s)", test.bson, got, err, want, testtree.Marshal(t, decoded))
+		}
+
+		// canonical extended json keeps the types of every number
+		canonical, err := DecodeCanonical(raw)
+		if err != nil {
+			t.Errorf("DecodeCanonical(%s): %v", test.bson, err)
+			continue
+		}
+
+		if data, err := Encode(canonical); err != nil || hex.EncodeToString(data) != test.bson {
+			t.Errorf("Encode(DecodeCanonical(%s)) = %x, %v", test.bson, data, err)
+		}
+	}
+}
+
+// the examples of bsonspec.org and the canonical extended json of the bson corpus, which
+// DecodeCanonical gives back exactly
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		json string
+		bson string
+	}{
+		{`{"hello":"world"}`, "160000000268656c6c6f0006000000776f726c640000"},
+		{`{"BSON":["awesome",{"$numberDouble":"5.05"},{"$numberInt":"1986"}]}`, "310000000442534f4e002600000002300008000000617765736f6d65000131003333333333331440103200c20700000000"},
+		{`{"a":null}`, "080000000a610000"},
+		{`{"b":true}`, "090000000862000100"},
+		{`{"x":{}}`, "0d000000037800050000000000"},
+		{`{"a":[]}`, "0d000000046100050000000000"},
+		{`{"i":{"$numberInt":"-2147483648"}}`, "0c0000001069000000008000"},
+		{`{"a":{"$numberLong":"9223372036854775807"}}`, "10000000126100ffffffffffffff7f00"},
+		{`{"d":{"$numberDouble":"1.0"}}`, "10000000016400000000000000f03f00"},
+		{`{"d":{"$numberDouble":"-0.0"}}`, "10000000016400000000000000008000"},
+		{`{"d":{"$numberDouble":"NaN"}}`, "10000000016400000000000000f87f00"},
+		{`{"d":{"$numberDouble":"-Infinity"}}`, "10000000016400000000000000f0ff00"},
+		{`{"d":{"$numberDecimal":"1"}}`, "180000001364000100000000000000000000000000403000"},
+		{`{"x":{"$binary":{"base64":"//8=","subType":"00"}}}`, "0f0000000578000200000000ffff00"},
+		{`{"x":{"$binary":{"base64":"//8=","subType":"80"}}}`, "0f0000000578000200000080ffff00"},
+		{`{"a":{"$oid":"56e1fc72e0c917e9c4714161"}}`, "1400000007610056e1fc72e0c917e9c471416100"},
+		{`{"a":{"$date":{"$numberLong":"0"}}}`, "10000000096100000000000000000000"},
+		{`{"a":{"$regularExpression":{"pattern":"abc","options":"im"}}}`, "0f0000000b610061626300696d0000"},
+		{`{"a":{"$timestamp":{"t":123456789,"i":42}}}`, "100000001161002a00000015cd5b0700"},
+		{`{"a":{"$code":"abcd"}}`, "110000000d610005000000616263640000"},
+		{`{"a":{"$symbol":"abcd"}}`, "110000000e610005000000616263640000"},
+		{`{"a":{"$minKey":1}}`, "08000000ff610000"},
+		{`{"a":{"$maxKey":1}}`, "080000007f610000"},
+		{`{"a":{"$undefined":true}}`, "0800000006610000"},
+	}
+
+	for _, test := range tests {
+		data, err := Encode(testtree.Object(t, test.json))
+		if err != nil {
+			t.Errorf("Encode(%s): %v", test.json, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(data); got != test.bson {
+			t.Errorf("Encode(%s) = %s, want %s", test.json, got, test.bson)
+		}
+
+		raw, _ := hex.DecodeString(test.bson)
+		decoded, err := DecodeCanonical(raw)
+		if err != nil {
+			t.Errorf("DecodeCanonical(%s): %v", test.bson, err)
+			continue
+		}
+
+		if got := testtree.Marshal(t, decoded); got != test.json {
+			t.Errorf("DecodeCanonical(%s) = %s, want %s", test.bson, got, test.json)
+		}
+	}
+}
+
+// what relaxed extended json makes of the types json has numbers and strings for
+func TestDecodeRelaxed(t *testing.T) {
+	tests := []struct {
+		bson string
+		json string
+	}{
+		{"0c0000001069000000008000", `{"i":-2147483648}`},
+		{"10000000126100ffffffffffff1f0000", `{"a":9007199254740991}`},
+		{"10000000126100ffffffffffffff7f00", `{"a":{"$numberLong":"9223372036854775807"}}`},
+		{"10000000016400000000000000f03f00", `{"d":1}`},
+		{"10000000016400000000000000f87f00", `{"d":{"$numberDouble":"NaN"}}`},
+		{"10000000096100000000000000000000", `{"a":{"$date":"1970-01-01T00:00:00.000Z"}}`},
+		{"10000000096100ffffffffffffffff00", `{"a":{"$date":{"$numberLong":"-1"}}}`},
+		{"120000000578000500000002010000006100", `{"x":{"$binary":{"base64":"YQ==","subType":"02"}}}`},
+	}
+
+	for _, test := range tests {
+		raw, _ := hex.DecodeString(test.bson)
+		decoded, err := Decode(raw)
+		if err != nil {
+			t.Errorf("Decode(%s): %v", test.bson, err)
+			continue
+		}
+
+		if got := testtree.Marshal(t, decoded); got != test.json {
+			t.Errorf("Decode(%s) = %s, want %s", test.bson, got, test.json)
+		}
+	}
+}
+
+// integers past 2^53 are int64s when they're json.Numbers, and come back as $numberLong
+func TestBigIntegers(t *testing.T) {
+	tests := []struct {
+		number json.Number
+		bson   string
+	}{
+		{"2147483648", "10000000126100000000800000000000"},
+		{"9007199254740993", "10000000126100010000000000200000"},
+		{"-9223372036854775808", "10000000126100000000000000008000"},
+	}
+
+	for _, test := range tests {
+		document := testtree.Object(t, `{}`)
+		document.Set("a", test.number)
+		data, err := Encode(document)
+		if err != nil {
+			t.Errorf("Encode(%s): %v", test.number, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(data); got != test.bson {
+			t.Errorf("Encode(%s) = %s, want %s", test.number, got, test.bson)
+		}
+	}
+
+	document := testtree.Object(t, `{}`)
+	document.Set("a", json.Number("9007199254740993"))
+	data, _ := Encode(document)
+	decoded, err := Decode(data)
+	if err != nil || testtree.Marshal(t, decoded) != `{"a":{"$numberLong":"9007199254740993"}}` {
+		t.Errorf("Decode(Encode(9007199254740993)) = %s, %v", testtree.Marshal(t, decoded), err)
+	}
+
+	// a float64 past 2^53 isn't exact any more, so it's a double
+	document.Set("a", float64(1<<54))
+	if data, err := Encode(document); err != nil || data[4] != typeDouble {
+		t.Errorf("Encode(2^54) = %x, %v, want a double", data, err)
+	}
+}
+
+func TestSpecialFloats(t *testing.T) {
+	for _, value := range []float64{math.Inf(1), math.Inf(-1), math.NaN(), math.Copysign(0, -1)} {
+		document := testtree.Object(t, `{}`)
+		document.Set("d", value)
+		data, err := Encode(document)
+		if err != nil {
+			t.Errorf("Encode(%v): %v", value, err)
+			continue
+		}
+
+		if data[4] != typeDouble {
+			t.Errorf("Encode(%v) = %x, want a double", value, data)
+		}
+
+		decoded, err := DecodeCanonical(data)
+		if err != nil {
+			t.Errorf("DecodeCanonical(Encode(%v)): %v", value, err)
+			continue
+		}
+
+		again, err := Encode(decoded)
+		if err != nil || hex.EncodeToString(again) != hex.EncodeToString(data) {
+			t.Errorf("Encode(DecodeCanonical(Encode(%v))) = %x, %v", value, again, err)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	one, _ := hex.DecodeString("080000000a610000")
+	two, _ := hex.DecodeString("090000000862000100")
+	documents, err := Split(append(append([]byte{}, one...), two...))
+	if err != nil || len(documents) != 2 || string(documents[0]) != string(one) || string(documents[1]) != string(two) {
+		t.Errorf("Split = %x, %v", documents, err)
+	}
+
+	if _, err := Split(append(append([]byte{}, one...), 1, 0)); err == nil {
+		t.Errorf("Split with a cut off document: want an error")
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"05000000",
+		"0400000000",
+		"06000000000000",
+		"0500000001",
+		"080000000a6100",
+		"0900000008620001",
+		"080000001461000000",
+		"0d000000026100010000006100",
+		"0d0000000261000200000061000000",
+		"0c00000002610005000000610000",
+		"0d000000037800060000000000",
+		"10000000126100ffffffffffffff7f",
+		"0f0000000578000200000000ffff0000",
+		"080000000a61000000",
+	} {
+		raw, _ := hex.DecodeString(input)
+		if value, err := Decode(raw); err == nil {
+			t.Errorf("Decode(%s) = %s, want an error", input, testtree.Marshal(t, value))
+		}
+	}
+
+	for _, input := range []string{
+		`{"a\u0000":1}`,
+		`{"a":{"$oid":"56e1"}}`,
+		`{"a":{"$numberInt":"2147483648"}}`,
+		`{"a":{"$numberLong":"1.5"}}`,
+		`{"a":{"$numberDouble":"x"}}`,
+		`{"a":{"$numberDecimal":"1x"}}`,
+		`{"a":{"$binary":{"base64":"!","subType":"00"}}}`,
+		`{"a":{"$binary":"AA=="}}`,
+		`{"a":{"$timestamp":{"t":-1,"i":0}}}`,
+		`{"a":{"$date":"yesterday"}}`,
+		`{"a":{"$regularExpression":{"pattern":"a\u0000","options":""}}}`,
+	} {
+		if data, err := Encode(testtree.Object(t, input)); err == nil {
+			t.Errorf("Encode(%s) = %x, want an error", input, data)
+		}
+	}
+
+	for _, value := range []interface{}{json.Number("1x"), 1, map[string]interface{}{}} {
+		document := testtree.Object(t, `{}`)
+		document.Set("a", value)
+		if data, err := Encode(document); err == nil {
+			t.Errorf("Encode(%#v) = %x, want an error", value, data)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package bson
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// decimal128 (IEEE 754-2008, binary integer decimal), which json has no type for, so it goes
+// through $numberDecimal strings. the formatting follows the BSON decimal128 spec.
+
+const (
+	decimalBias        = 6176
+	decimalMaxExponent = 6111
+	decimalMinExponent = -6176
+	decimalMaxDigits   = 34
+)
+
+func formatDecimal128(high, low uint64) string {
+	sign := ""
+	if high>>63 == 1 {
+		sign = "-"
+	}
+
+	switch (high >> 58) & 0x1f {
+	case 0x1f:
+		return "NaN"
+	case 0x1e:
+		return sign + "Infinity"
+	}
+
+	var exponent int
+	coefficient := new(big.Int)
+	if (high>>61)&3 == 3 {
+		// the coefficient would be bigger than 34 digits, which isn't canonical, so it's zero
+		exponent = int((high>>47)&0x3fff) - decimalBias
+	} else {
+		exponent = int((high>>49)&0x3fff) - decimalBias
+		coefficient.SetUint64(high & (1<<49 - 1))
+		coefficient.Lsh(coefficient, 64)
+		coefficient.Or(coefficient, new(big.Int).SetUint64(low))
+	}
+
+	digits := coefficient.String()
+	if len(digits) > decimalMaxDigits {
+		digits = "0"
+	}
+
+	adjusted := exponent + len(digits) - 1
+	if exponent > 0 || adjusted < -6 {
+		// scientific notation
+		text := digits[:1]
+		if len(digits) > 1 {
+			text += "." + digits[1:]
+		}
+
+		return fmt.Sprintf("%s%sE%+d", sign, text, adjusted)
+	}
+
+	if exponent == 0 {
+		return sign + digits
+	}
+
+	point := len(digits) + exponent
+	if point <= 0 {
+		return sign + "0." + strings.Repeat("0", -point) + digits
+	}
+
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+func parseDecimal128(text string) (high, low uint64, err error) {
+	var sign uint64
+	rest := text
+	if strings.HasPrefix(rest, "-") || strings.HasPrefix(rest, "+") {
+		if rest[0] == '-' {
+			sign = 1 << 63
+		}
+		rest = rest[1:]
+	}
+
+	switch strings.ToLower(rest) {
+	case "nan":
+		return 0x1f << 58, 0, nil
+	case "inf", "infinity":
+		return sign | 0x1e<<58, 0, nil
+	}
+
+	mantissa, exponentText, hasExponent := strings.Cut(strings.ToUpper(rest), "E")
+	exponent := 0
+	if hasExponent {
+		if _, err := fmt.Sscanf(exponentText, "%d", &exponent); err != nil || strings.TrimLeft(exponentText, "+-0123456789") != "" {
+			return 0, 0, fmt.Errorf("%q isn't a decimal", text)
+		}
+	}
+
+	whole, fraction, _ := strings.Cut(mantissa, ".")
+	digits := whole + fraction
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return 0, 0, fmt.Errorf("%q isn't a decimal", text)
+	}
+
+	exponent -= len(fraction)
+	digits = strings.TrimLeft(digits, "0")
+
+	// make it fit by taking off trailing zeros or adding them, which doesn't change the value
+	for len(digits) > decimalMaxDigits && strings.HasSuffix(digits, "0") || exponent < decimalMinExponent && strings.HasSuffix(digits, "0") {
+		digits = digits[:len(digits)-1]
+		exponent++
+	}
+
+	for exponent > decimalMaxExponent && len(digits) < decimalMaxDigits && digits != "" {
+		digits += "0"
+		exponent--
+	}
+
+	if digits == "" {
+		exponent = min(max(exponent, decimalMinExponent), decimalMaxExponent)
+		digits = "0"
+	}
+
+	if len(digits) > decimalMaxDigits || exponent < decimalMinExponent || exponent > decimalMaxExponent {
+		return 0, 0, fmt.Errorf("%q can't be stored exactly in a decimal128", text)
+	}
+
+	coefficient, _ := new(big.Int).SetString(digits, 10)
+	low = new(big.Int).And(coefficient, new(big.Int).SetUint64(^uint64(0))).Uint64()
+	high = new(big.Int).Rsh(coefficient, 64).Uint64()
+
+	return sign | uint64(exponent+decimalBias)<<49 | high, low, nil
+}
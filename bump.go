@@ -0,0 +1,85 @@
+package orderedjson
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/michaelhelvey/orderedjson/v2/npmjson"
+)
+
+// BumpVersion increments part ("major", "minor", "patch" or "prerelease") of the semantic version
+// in the string at path, and returns data with only those bytes changed, so the rest of the file
+// keeps its layout. a v in front of the version stays.
+func BumpVersion(data []byte, path Path, part string) ([]byte, string, error) {
+	bom := bytes.HasPrefix(data, utf8BOM)
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, span := range doc.spans {
+		if !span.path.Equal(path) {
+			continue
+		}
+
+		value, err := doc.value(span)
+		if err != nil {
+			return nil, "", err
+		}
+
+		current, ok := value.(string)
+		if !ok {
+			return nil, "", fmt.Errorf("%s is %s, not a version string", path, TypeName(value))
+		}
+
+		version, err := npmjson.ParseVersion(current)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", path, err)
+		}
+
+		bumped, err := version.Bump(npmjson.Part(part))
+		if err != nil {
+			return nil, "", err
+		}
+
+		next := bumped.String()
+		if strings.HasPrefix(current, "v") {
+			next = "v" + next
+		}
+
+		quoted, err := marshalValue(next)
+		if err != nil {
+			return nil, "", err
+		}
+
+		var result bytes.Buffer
+		if bom {
+			result.Write(utf8BOM)
+		}
+
+		result.Write(doc.Text[:span.start])
+		result.WriteString(quoted)
+		result.Write(doc.Text[span.end:])
+		return result.Bytes(), next, nil
+	}
+
+	return nil, "", fmt.Errorf("there's no %s", path)
+}
+
+// the value at span in the tree
+func (doc *Document) value(span valueSpan) (interface{}, error) {
+	var value interface{} = doc.Tree
+	for _, element := range span.path {
+		switch container := value.(type) {
+		case *JsonObject:
+			value, _ = container.Get(element.(string))
+		case []interface{}:
+			value = container[element.(int)]
+		default:
+			return nil, fmt.Errorf("no value at %s", span.path)
+		}
+	}
+
+	return value, nil
+}
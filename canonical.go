@@ -0,0 +1,210 @@
+package orderedjson
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Canonical writes value in the JSON Canonicalization Scheme (RFC 8785): no whitespace, keys
+// sorted by their UTF-16 code units, numbers the way javascript prints them and strings with only
+// the escapes json requires. documents that mean the same give the same bytes, whatever their
+// layout or key order was.
+func Canonical(value interface{}) ([]byte, error) {
+	var result strings.Builder
+	if err := writeCanonical(&result, value); err != nil {
+		return nil, err
+	}
+
+	return []byte(result.String()), nil
+}
+
+// Hash is the digest of the canonical form of tree with algo, e.g. crypto.SHA256, for an ETag or
+// to tell whether a document really changed. SHA-224 to SHA-512 are linked in, other algorithms
+// need their package imported.
+func Hash(tree interface{}, algo crypto.Hash) ([]byte, error) {
+	if !algo.Available() {
+		return nil, fmt.Errorf("hash function %v isn't available", algo)
+	}
+
+	canonical, err := Canonical(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	h := algo.New()
+	h.Write(canonical)
+	return h.Sum(nil), nil
+}
+
+func writeCanonical(result *strings.Builder, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		result.WriteString("null")
+	case bool:
+		result.WriteString(strconv.FormatBool(v))
+	case string:
+		writeCanonicalString(result, v)
+	case float64, json.Number:
+		// json.Numbers too are serialized as the double they stand for, like rfc 8785 says
+		f, ok := floatValue(v)
+		if !ok {
+			return fmt.Errorf("%v is out of range of a double", v)
+		}
+
+		number, err := canonicalNumber(f)
+		if err != nil {
+			return err
+		}
+
+		result.WriteString(number)
+	case *JsonObject:
+		keys := make([]string, 0, v.Len())
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			keys = append(keys, pair.Key)
+		}
+
+		sort.Slice(keys, func(i, j int) bool {
+			return compareUTF16(keys[i], keys[j]) < 0
+		})
+
+		result.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				result.WriteByte(',')
+			}
+
+			writeCanonicalString(result, key)
+			result.WriteByte(':')
+			member, _ := v.Get(key)
+			if err := writeCanonical(result, member); err != nil {
+				return err
+			}
+		}
+
+		result.WriteByte('}')
+	case []interface{}:
+		result.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				result.WriteByte(',')
+			}
+
+			if err := writeCanonical(result, item); err != nil {
+				return err
+			}
+		}
+
+		result.WriteByte(']')
+	default:
+		// anything else goes through encoding/json first, like Typed.Object does
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		converted, err := FromStdJSONValue(data)
+		if err != nil {
+			return err
+		}
+
+		return writeCanonical(result, converted)
+	}
+
+	return nil
+}
+
+// the order of keys in RFC 8785, which compares UTF-16 code units instead of code points, so
+// characters outside the BMP sort before U+E000 to U+FFFF
+func compareUTF16(a, b string) int {
+	x, y := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if x[i] != y[i] {
+			return int(x[i]) - int(y[i])
+		}
+	}
+
+	return len(x) - len(y)
+}
+
+func writeCanonicalString(result *strings.Builder, s string) {
+	result.WriteByte('"')
+	for _, char := range s {
+		switch char {
+		case '"':
+			result.WriteString(`\"`)
+		case '\\':
+			result.WriteString(`\\`)
+		case '\b':
+			result.WriteString(`\b`)
+		case '\f':
+			result.WriteString(`\f`)
+		case '\n':
+			result.WriteString(`\n`)
+		case '\r':
+			result.WriteString(`\r`)
+		case '\t':
+			result.WriteString(`\t`)
+		default:
+			if char < 0x20 {
+				fmt.Fprintf(result, `\u%04x`, char)
+			} else {
+				result.WriteRune(char)
+			}
+		}
+	}
+
+	result.WriteByte('"')
+}
+
+// n the way javascript's Number.prototype.toString writes it, which is what RFC 8785 asks for
+func canonicalNumber(n float64) (string, error) {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return "", errors.New("NaN and infinity have no canonical form")
+	}
+
+	if n == 0 {
+		// including -0
+		return "0", nil
+	}
+
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+
+	// the shortest digits that round trip, and where the decimal point goes: 1.5e3 is 15 and 4
+	scientific := strconv.FormatFloat(n, 'e', -1, 64)
+	mantissa, exponent, _ := strings.Cut(scientific, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	power, _ := strconv.Atoi(exponent)
+	point := power + 1
+
+	switch {
+	case len(digits) <= point && point <= 21:
+		return sign + digits + strings.Repeat("0", point-len(digits)), nil
+	case 0 < point && point <= 21:
+		return sign + digits[:point] + "." + digits[point:], nil
+	case -6 < point && point <= 0:
+		return sign + "0." + strings.Repeat("0", -point) + digits, nil
+	}
+
+	result := sign + digits[:1]
+	if len(digits) > 1 {
+		result += "." + digits[1:]
+	}
+
+	if power > 0 {
+		return result + "e+" + strconv.Itoa(power), nil
+	}
+
+	return result + "e" + strconv.Itoa(power), nil
+}
@@ -0,0 +1,129 @@
+package orderedjson
+
+import (
+	"crypto"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// the examples of RFC 8785, section 3.2
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{
+			`{"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001], "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/", "literals": [null, true, false]}`,
+			`{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		// keys are sorted by utf-16 code units, so the emoji's surrogates come before U+FB33
+		{
+			`{"\u20ac": "Euro Sign", "\r": "Carriage Return", "\ufb33": "Hebrew Letter Dalet With Dagesh", "1": "One", "\ud83d\ude00": "Emoji: Grinning Face", "\u0080": "Control", "\u00f6": "Latin Small Letter O With Diaeresis"}`,
+			"{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"ö\":\"Latin Small Letter O With Diaeresis\",\"€\":\"Euro Sign\",\"😀\":\"Emoji: Grinning Face\",\"דּ\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{`{"b": {"z": [], "a": {}}, "a": "<&>\u2028"}`, "{\"a\":\"<&>\u2028\",\"b\":{\"a\":{},\"z\":[]}}"},
+		{`{"ab": 1, "a": 2, "": 3}`, `{"":3,"a":2,"ab":1}`},
+		{`{}`, `{}`},
+	}
+
+	for _, test := range tests {
+		for _, numbers := range []NumberMode{NumberFloat64, NumberJSONNumber} {
+			tree, err := ParseOptions{Numbers: numbers}.Parse([]byte(test.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, err := Canonical(tree); err != nil || string(got) != test.want {
+				t.Errorf("Canonical(%s) = %s, %v, want %s", test.input, got, err, test.want)
+			}
+		}
+	}
+}
+
+// the number examples of RFC 8785, appendix B
+func TestCanonicalNumbers(t *testing.T) {
+	tests := []struct {
+		bits uint64
+		want string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	}
+
+	for _, test := range tests {
+		if got, err := Canonical(math.Float64frombits(test.bits)); err != nil || string(got) != test.want {
+			t.Errorf("Canonical(%#016x) = %s, %v, want %s", test.bits, got, err, test.want)
+		}
+	}
+
+	// json.Numbers are written as the double they stand for
+	numbers := map[json.Number]string{"9007199254740993": "9007199254740992", "1.50e2": "150", "-0.0": "0", "1E-7": "1e-7"}
+	for number, want := range numbers {
+		if got, err := Canonical(number); err != nil || string(got) != want {
+			t.Errorf("Canonical(json.Number(%s)) = %s, %v, want %s", number, got, err, want)
+		}
+	}
+
+	for _, value := range []interface{}{math.NaN(), math.Inf(1), math.Inf(-1), json.Number("1e400"), []interface{}{math.NaN()}} {
+		if got, err := Canonical(value); err == nil {
+			t.Errorf("Canonical(%v) = %s, want an error", value, got)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	// the same document in another layout and key order
+	var hashes []string
+	for _, input := range []string{`{"a":[1,"x"],"b":null}`, "{\n  \"b\": null,\n  \"a\": [1.0, \"\\u0078\"]\n}"} {
+		tree, err := ParseOptions{}.Parse([]byte(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sum, err := Hash(tree, crypto.SHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hashes = append(hashes, hex.EncodeToString(sum))
+	}
+
+	// sha256 of {"a":[1,"x"],"b":null}
+	for _, got := range hashes {
+		if got != "854ef06dc57f5dfed10206344ab2d02e0b6c84b0e19436703a5afd0f1f9f2687" {
+			t.Errorf("Hash = %s", got)
+		}
+	}
+
+	if sum, err := Hash(map[string]interface{}{"a": 1}, crypto.SHA512); err != nil || len(sum) != 64 {
+		t.Errorf("Hash(SHA512) = %x, %v", sum, err)
+	}
+
+	if sum, err := Hash(nil, crypto.MD4); err == nil {
+		t.Errorf("Hash(MD4) = %x, want an error", sum)
+	}
+}
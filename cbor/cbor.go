@@ -0,0 +1,495 @@
+// Package cbor converts between ordered json trees and CBOR (RFC 8949).
+//
+// maps are written in insertion order (which RFC 8949 allows, it just isn't the "core deterministic"
+// order), and everything else uses preferred serialization: the shortest integer and float encodings
+// that hold the value exactly. so the same tree always encodes to the same bytes.
+//
+// integers too big for a float64 to hold exactly come out of Decode as json.Numbers with all their
+// digits, and Encode writes json.Numbers (like the ones ParseOptions.Numbers or EncodeTree give) that
+// are integers as cbor integers, so they make it through both ways.
+package cbor
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type JsonObject = orderedmap.OrderedMap[string, interface{}]
+
+const (
+	majorUnsigned = 0
+	majorNegative = 1
+	majorBytes    = 2
+	majorText     = 3
+	majorArray    = 4
+	majorMap      = 5
+	majorTag      = 6
+	majorSimple   = 7
+)
+
+// Encode encodes any value from the tree (*JsonObject, []interface{}, string, float64, json.Number,
+// bool or nil).
+func Encode(value interface{}) ([]byte, error) {
+	return appendValue(make([]byte, 0, 64), value)
+}
+
+func appendHead(buf []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(buf, major<<5|byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, major<<5|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, major<<5|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, major<<5|26), uint32(n))
+	}
+
+	return binary.BigEndian.AppendUint64(append(buf, major<<5|27), n)
+}
+
+func appendValue(buf []byte, value interface{}) ([]byte, error) {
+	var err error
+
+	switch v := value.(type) {
+	case nil:
+		return append(buf, majorSimple<<5|22), nil
+	case bool:
+		if v {
+			return append(buf, majorSimple<<5|21), nil
+		}
+
+		return append(buf, majorSimple<<5|20), nil
+	case string:
+		buf = appendHead(buf, majorText, uint64(len(v)))
+		return append(buf, v...), nil
+	case float64:
+		return appendNumber(buf, v), nil
+	case json.Number:
+		return appendJSONNumber(buf, v)
+	case []interface{}:
+		buf = appendHead(buf, majorArray, uint64(len(v)))
+		for _, item := range v {
+			if buf, err = appendValue(buf, item); err != nil {
+				return nil, err
+			}
+		}
+
+		return buf, nil
+	case *JsonObject:
+		buf = appendHead(buf, majorMap, uint64(v.Len()))
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			buf = appendHead(buf, majorText, uint64(len(pair.Key)))
+			buf = append(buf, pair.Key...)
+			if buf, err = appendValue(buf, pair.Value); err != nil {
+				return nil, err
+			}
+		}
+
+		return buf, nil
+	}
+
+	return nil, fmt.Errorf("cbor: cannot encode %T", value)
+}
+
+func appendNumber(buf []byte, v float64) []byte {
+	// integral values go out as cbor integers, apart from -0 which an integer can't hold
+	if v == math.Trunc(v) && !math.IsInf(v, 0) && math.Abs(v) <= 1<<53 && !(v == 0 && math.Signbit(v)) {
+		if v >= 0 {
+			return appendHead(buf, majorUnsigned, uint64(v))
+		}
+
+		return appendHead(buf, majorNegative, uint64(-v)-1)
+	}
+
+	if bits, ok := float16Bits(v); ok {
+		return binary.BigEndian.AppendUint16(append(buf, majorSimple<<5|25), bits)
+	}
+
+	if f32 := float32(v); float64(f32) == v || math.IsNaN(v) {
+		return binary.BigEndian.AppendUint32(append(buf, majorSimple<<5|26), math.Float32bits(f32))
+	}
+
+	return binary.BigEndian.AppendUint64(append(buf, majorSimple<<5|27), math.Float64bits(v))
+}
+
+// integers go out as cbor integers with all their digits, or as bignums when they don't fit in one,
+// and the rest like float64s
+func appendJSONNumber(buf []byte, v json.Number) ([]byte, error) {
+	if n, ok := new(big.Int).SetString(v.String(), 10); ok {
+		major, tag := byte(majorUnsigned), uint64(2)
+		if n.Sign() < 0 {
+			major, tag = majorNegative, 3
+			n.Sub(big.NewInt(-1), n)
+		}
+
+		if n.IsUint64() {
+			return appendHead(buf, major, n.Uint64()), nil
+		}
+
+		buf = appendHead(buf, majorTag, tag)
+		raw := n.Bytes()
+		buf = appendHead(buf, majorBytes, uint64(len(raw)))
+		return append(buf, raw...), nil
+	}
+
+	f, err := v.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("cbor: cannot encode number %s", v)
+	}
+
+	return appendNumber(buf, f), nil
+}
+
+// returns the half precision encoding of v if it can hold v exactly
+func float16Bits(v float64) (uint16, bool) {
+	switch {
+	case math.IsNaN(v):
+		return 0x7e00, true
+	case math.IsInf(v, 1):
+		return 0x7c00, true
+	case math.IsInf(v, -1):
+		return 0xfc00, true
+	case v == 0 && math.Signbit(v):
+		return 0x8000, true
+	}
+
+	f32 := float32(v)
+	if float64(f32) != v {
+		return 0, false
+	}
+
+	bits := math.Float32bits(f32)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23&0xff) - 127
+	mantissa := bits & 0x7fffff
+
+	switch {
+	case exp >= -14 && exp <= 15:
+		// normal half, the low 13 mantissa bits have to be zero
+		if mantissa&0x1fff != 0 {
+			return 0, false
+		}
+
+		return sign | uint16(exp+15)<<10 | uint16(mantissa>>13), true
+	case exp >= -24 && exp < -14:
+		// subnormal half
+		full := mantissa | 0x800000
+		shift := uint(-exp - 14 + 13)
+		if full&(1<<shift-1) != 0 {
+			return 0, false
+		}
+
+		return sign | uint16(full>>shift), true
+	}
+
+	return 0, false
+}
+
+// Decode decodes a single CBOR data item. maps become ordered objects in the order their entries
+// appear, byte strings become base64url strings (RFC 8949 section 6.1) and tags are dropped, apart
+// from bignums which become numbers. integers are float64s, or json.Numbers past 2^53.
+func Decode(data []byte) (interface{}, error) {
+	decoder := &decoder{data: data}
+	value, err := decoder.decodeValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := value.(breakCode); ok {
+		return nil, decoder.errorf("unexpected break")
+	}
+
+	if decoder.idx != len(data) {
+		return nil, fmt.Errorf("cbor: %d trailing bytes after data item", len(data)-decoder.idx)
+	}
+
+	return value, nil
+}
+
+type decoder struct {
+	data  []byte
+	idx   int
+	depth int
+}
+
+// returned by decodeValue when it hits the "break" stop code of an indefinite length item
+type breakCode struct{}
+
+func (decoder *decoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("cbor: %s at offset %d", fmt.Sprintf(format, args...), decoder.idx)
+}
+
+func (decoder *decoder) read(n int) ([]byte, error) {
+	if n < 0 || decoder.idx+n > len(decoder.data) {
+		return nil, decoder.errorf("unexpected end of data")
+	}
+
+	result := decoder.data[decoder.idx : decoder.idx+n]
+	decoder.idx += n
+	return result, nil
+}
+
+// reads the initial byte and argument. indefinite is set for the 0x1f additional info.
+func (decoder *decoder) readHead() (major byte, info byte, arg uint64, err error) {
+	initial, err := decoder.read(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	major, info = initial[0]>>5, initial[0]&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info == 24:
+		b, err := decoder.read(1)
+		return major, info, uint64(b[0]), err
+	case info == 25:
+		b, err := decoder.read(2)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		return major, info, uint64(binary.BigEndian.Uint16(b)), nil
+	case info == 26:
+		b, err := decoder.read(4)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		return major, info, uint64(binary.BigEndian.Uint32(b)), nil
+	case info == 27:
+		b, err := decoder.read(8)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		return major, info, binary.BigEndian.Uint64(b), nil
+	case info == 31:
+		return major, info, 0, nil
+	}
+
+	return 0, 0, 0, decoder.errorf("reserved additional information %d", info)
+}
+
+func (decoder *decoder) decodeValue() (interface{}, error) {
+	decoder.depth++
+	defer func() { decoder.depth-- }()
+
+	if decoder.depth > 10000 {
+		return nil, decoder.errorf("nesting too deep")
+	}
+
+	major, info, arg, err := decoder.readHead()
+	if err != nil {
+		return nil, err
+	}
+
+	indefinite := info == 31
+
+	switch major {
+	case majorUnsigned:
+		if indefinite {
+			return nil, decoder.errorf("indefinite length integer")
+		}
+
+		if arg > 1<<53 {
+			return json.Number(strconv.FormatUint(arg, 10)), nil
+		}
+
+		return float64(arg), nil
+	case majorNegative:
+		if indefinite {
+			return nil, decoder.errorf("indefinite length integer")
+		}
+
+		if arg >= 1<<53 {
+			return json.Number(new(big.Int).Sub(big.NewInt(-1), new(big.Int).SetUint64(arg)).String()), nil
+		}
+
+		return -1 - float64(arg), nil
+	case majorBytes, majorText:
+		raw, err := decoder.decodeString(major, arg, indefinite)
+		if err != nil {
+			return nil, err
+		}
+
+		if major == majorBytes {
+			return base64.RawURLEncoding.EncodeToString(raw), nil
+		}
+
+		return string(raw), nil
+	case majorArray:
+		result := make([]interface{}, 0)
+		for i := uint64(0); indefinite || i < arg; i++ {
+			item, err := decoder.decodeValue()
+			if err != nil {
+				return nil, err
+			}
+
+			if _, done := item.(breakCode); done {
+				if !indefinite {
+					return nil, decoder.errorf("unexpected break")
+				}
+				break
+			}
+
+			result = append(result, item)
+		}
+
+		return result, nil
+	case majorMap:
+		result := orderedmap.New[string, interface{}]()
+		for i := uint64(0); indefinite || i < arg; i++ {
+			key, err := decoder.decodeValue()
+			if err != nil {
+				return nil, err
+			}
+
+			if _, done := key.(breakCode); done {
+				if !indefinite {
+					return nil, decoder.errorf("unexpected break")
+				}
+				break
+			}
+
+			name, ok := key.(string)
+			if !ok {
+				return nil, decoder.errorf("map keys must be strings to convert to json, got %T", key)
+			}
+
+			value, err := decoder.decodeValue()
+			if err != nil {
+				return nil, err
+			}
+
+			if _, done := value.(breakCode); done {
+				return nil, decoder.errorf("map is missing a value")
+			}
+
+			result.Set(name, value)
+		}
+
+		return result, nil
+	case majorTag:
+		start := decoder.idx
+		content, err := decoder.decodeValue()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := content.(breakCode); ok {
+			return nil, decoder.errorf("tag %d has no content", arg)
+		}
+
+		if arg == 2 || arg == 3 {
+			// bignums, the content is a byte string. decode it again to get at the raw bytes
+			decoder.idx = start
+			_, info, length, err := decoder.readHead()
+			if err != nil {
+				return nil, err
+			}
+
+			raw, err := decoder.decodeString(majorBytes, length, info == 31)
+			if err != nil {
+				return nil, err
+			}
+
+			n := new(big.Int).SetBytes(raw)
+			if arg == 3 {
+				n.Sub(big.NewInt(-1), n)
+			}
+
+			if n.IsInt64() && math.Abs(float64(n.Int64())) <= 1<<53 {
+				return float64(n.Int64()), nil
+			}
+
+			return json.Number(n.String()), nil
+		}
+
+		return content, nil
+	}
+
+	// major type 7
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		return float16ToFloat64(uint16(arg)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(arg))), nil
+	case 27:
+		return math.Float64frombits(arg), nil
+	case 31:
+		return breakCode{}, nil
+	}
+
+	return nil, decoder.errorf("unsupported simple value %d", arg)
+}
+
+func (decoder *decoder) decodeString(major byte, length uint64, indefinite bool) ([]byte, error) {
+	if !indefinite {
+		if length > uint64(len(decoder.data)) {
+			return nil, decoder.errorf("string length %d is too large", length)
+		}
+
+		return decoder.read(int(length))
+	}
+
+	// indefinite strings are a series of definite chunks of the same major type
+	result := make([]byte, 0)
+	for {
+		chunkMajor, info, chunkLength, err := decoder.readHead()
+		if err != nil {
+			return nil, err
+		}
+
+		if chunkMajor == majorSimple && info == 31 {
+			return result, nil
+		}
+
+		if chunkMajor != major || info == 31 {
+			return nil, decoder.errorf("invalid chunk in indefinite length string")
+		}
+
+		chunk, err := decoder.decodeString(major, chunkLength, false)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, chunk...)
+	}
+}
+
+func float16ToFloat64(bits uint16) float64 {
+	exp := int(bits>>10) & 0x1f
+	mantissa := float64(bits & 0x3ff)
+
+	var value float64
+	switch exp {
+	case 0:
+		value = math.Ldexp(mantissa, -24)
+	case 31:
+		if mantissa == 0 {
+			value = math.Inf(1)
+		} else {
+			value = math.NaN()
+		}
+	default:
+		value = math.Ldexp(mantissa+1024, exp-25)
+	}
+
+	if bits&0x8000 != 0 {
+		return -value
+	}
+
+	return value
+}
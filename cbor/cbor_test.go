@@ -0,0 +1,276 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/michaelhelvey/orderedjson/v2/internal/testtree"
+)
+
+// tags, indefinite lengths and floats smaller than 64 bits are read, and written back in the
+// shortest definite form that keeps the value
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		cbor    string
+		json    string
+		encoded string
+	}{
+		// the self-described cbor tag, and the tags of dates, are dropped
+		{"d9d9f7a16161f93e00", `{"a":1.5}`, "a16161f93e00"},
+		{"c11a514b67b0", `1363896240`, "1a514b67b0"},
+		{"a16174c074323031332d30332d32315432303a30343a30305a", `{"t":"2013-03-21T20:04:00Z"}`, "a1617474323031332d30332d32315432303a30343a30305a"},
+		{"d820a0", `{}`, "a0"},
+		// indefinite lengths become definite ones, in the order the items came in
+		{"bf617a0161619f0203ffff", `{"z":1,"a":[2,3]}`, "a2617a016161820203"},
+		{"7f657374726561646d696e67ff", `"streaming"`, "6973747265616d696e67"},
+		{"9f9fffbfffff", `[[],{}]`, "8280a0"},
+		// byte strings are base64url text
+		{"5f42010243030405ff", `"AQIDBAU"`, "6741514944424155"},
+		// each float in the smallest width that holds it exactly, and whole ones as integers
+		{"f93c01", `1.0009765625`, "f93c01"},
+		{"fa3f801000", `1.00048828125`, "fa3f801000"},
+		{"fb3ff0000000000001", `1.0000000000000002`, "fb3ff0000000000001"},
+		{"f90001", `5.960464477539063e-8`, "f90001"},
+		{"fb3e70000000000000", `5.960464477539063e-8`, "f90001"},
+		{"f97bff", `65504`, "19ffe0"},
+		{"fa47c35000", `100000`, "1a000186a0"},
+		{"fb40f86a0000000000", `100000`, "1a000186a0"},
+	}
+
+	for _, test := range tests {
+		raw, _ := hex.DecodeString(test.cbor)
+		decoded, err := Decode(raw)
+		if err != nil || testtree.Marshal(t, decoded) != test.json {
+			t.Errorf("Decode(%s) = %v, %v, want %s", test.cbor, decoded, err, test.json)
+			continue
+		}
+
+		data, err := Encode(decoded)
+		if got := hex.EncodeToString(data); err != nil || got != test.encoded {
+			t.Errorf("Encode(Decode(%s)) = %s, %v, want %s", test.cbor, got, err, test.encoded)
+			continue
+		}
+
+		if again, err := Decode(data); err != nil || testtree.Marshal(t, again) != test.json {
+			t.Errorf("Decode(%s) = %v, %v, want %s", test.encoded, again, err, test.json)
+		}
+	}
+}
+
+// the examples of RFC 8949 appendix A that json can hold. integral floats are written as integers,
+// so 0.0, 65504.0 and the like are left out.
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		json string
+		cbor string
+	}{
+		{`0`, "00"},
+		{`23`, "17"},
+		{`24`, "1818"},
+		{`100`, "1864"},
+		{`1000`, "1903e8"},
+		{`1000000`, "1a000f4240"},
+		{`1000000000000`, "1b000000e8d4a51000"},
+		{`-1`, "20"},
+		{`-10`, "29"},
+		{`-100`, "3863"},
+		{`-1000`, "3903e7"},
+		{`1.1`, "fb3ff199999999999a"},
+		{`1.5`, "f93e00"},
+		{`-4.1`, "fbc010666666666666"},
+		{`1e+300`, "fb7e37e43c8800759c"},
+		{`5.960464477539063e-8`, "f90001"},
+		{`0.00006103515625`, "f90400"},
+		{`100000.5`, "fa47c35040"},
+		{`false`, "f4"},
+		{`true`, "f5"},
+		{`null`, "f6"},
+		{`""`, "60"},
+		{`"a"`, "6161"},
+		{`"IETF"`, "6449455446"},
+		{`"\"\\"`, "62225c"},
+		{`"ü"`, "62c3bc"},
+		{`"水"`, "63e6b0b4"},
+		{`[]`, "80"},
+		{`[1,2,3]`, "83010203"},
+		{`[1,[2,3],[4,5]]`, "8301820203820405"},
+		{`{}`, "a0"},
+		{`{"a":1,"b":[2,3]}`, "a26161016162820203"},
+		{`["a",{"b":"c"}]`, "826161a161626163"},
+		{`{"a":"A","b":"B","c":"C","d":"D","e":"E"}`, "a56161614161626142616361436164614461656145"},
+	}
+
+	for _, test := range tests {
+		data, err := Encode(testtree.Parse(t, test.json))
+		if err != nil {
+			t.Errorf("Encode(%s): %v", test.json, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(data); got != test.cbor {
+			t.Errorf("Encode(%s) = %s, want %s", test.json, got, test.cbor)
+		}
+
+		raw, _ := hex.DecodeString(test.cbor)
+		decoded, err := Decode(raw)
+		if err != nil {
+			t.Errorf("Decode(%s): %v", test.cbor, err)
+			continue
+		}
+
+		if got := testtree.Marshal(t, decoded); got != test.json {
+			t.Errorf("Decode(%s) = %s, want %s", test.cbor, got, test.json)
+		}
+	}
+}
+
+// what Decode makes of the appendix A examples json doesn't have
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		cbor string
+		json string
+	}{
+		{"f90000", `0`},
+		{"f97bff", `65504`},
+		{"fa7f7fffff", `3.4028234663852886e+38`},
+		{"1bffffffffffffffff", `18446744073709551615`},
+		{"3bffffffffffffffff", `-18446744073709551616`},
+		{"c249010000000000000000", `18446744073709551616`},
+		{"c349010000000000000000", `-18446744073709551617`},
+		{"c11a514b67b0", `1363896240`},
+		{"c074323031332d30332d32315432303a30343a30305a", `"2013-03-21T20:04:00Z"`},
+		{"40", `""`},
+		{"4401020304", `"AQIDBA"`},
+		{"5f42010243030405ff", `"AQIDBAU"`},
+		{"7f657374726561646d696e67ff", `"streaming"`},
+		{"9fff", `[]`},
+		{"9f018202039f0405ffff", `[1,[2,3],[4,5]]`},
+		{"83018202039f0405ff", `[1,[2,3],[4,5]]`},
+		{"bf61610161629f0203ffff", `{"a":1,"b":[2,3]}`},
+		{"bf6346756ef563416d7421ff", `{"Fun":true,"Amt":-2}`},
+		{"a26162016161f7", `{"b":1,"a":null}`},
+	}
+
+	for _, test := range tests {
+		raw, _ := hex.DecodeString(test.cbor)
+		decoded, err := Decode(raw)
+		if err != nil {
+			t.Errorf("Decode(%s): %v", test.cbor, err)
+			continue
+		}
+
+		if got := testtree.Marshal(t, decoded); got != test.json {
+			t.Errorf("Decode(%s) = %s, want %s", test.cbor, got, test.json)
+		}
+	}
+}
+
+// integers past 2^53 are json.Numbers, since a float64 would lose digits, and bignums past 64 bits
+func TestBigIntegers(t *testing.T) {
+	tests := []struct {
+		number json.Number
+		cbor   string
+	}{
+		{"9007199254740993", "1b0020000000000001"},
+		{"18446744073709551615", "1bffffffffffffffff"},
+		{"-18446744073709551616", "3bffffffffffffffff"},
+		{"18446744073709551616", "c249010000000000000000"},
+		{"-18446744073709551617", "c349010000000000000000"},
+		{"-9007199254740993", "3b0020000000000000"},
+	}
+
+	for _, test := range tests {
+		object := testtree.Parse(t, `{}`).(*JsonObject)
+		object.Set("n", test.number)
+		data, err := Encode(object)
+		if err != nil {
+			t.Errorf("Encode(%s): %v", test.number, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(data); got != "a1616e"+test.cbor {
+			t.Errorf("Encode(%s) = %s, want a1616e%s", test.number, got, test.cbor)
+		}
+
+		decoded, err := Decode(data)
+		if err != nil {
+			t.Errorf("Decode(Encode(%s)): %v", test.number, err)
+			continue
+		}
+
+		if got := decoded.(*JsonObject).Value("n"); got != test.number {
+			t.Errorf("Decode(Encode(%s)) = %#v", test.number, got)
+		}
+	}
+
+	// the json.Numbers that aren't integers are written like float64s
+	if data, err := Encode(json.Number("1.5")); err != nil || hex.EncodeToString(data) != "f93e00" {
+		t.Errorf("Encode(json.Number(1.5)) = %x, %v", data, err)
+	}
+}
+
+func TestSpecialFloats(t *testing.T) {
+	tests := []struct {
+		value float64
+		cbor  string
+	}{
+		{math.Inf(1), "f97c00"},
+		{math.Inf(-1), "f9fc00"},
+		{math.NaN(), "f97e00"},
+		{math.Copysign(0, -1), "f98000"},
+	}
+
+	for _, test := range tests {
+		data, err := Encode(test.value)
+		if err != nil {
+			t.Errorf("Encode(%v): %v", test.value, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(data); got != test.cbor {
+			t.Errorf("Encode(%v) = %s, want %s", test.value, got, test.cbor)
+		}
+
+		decoded, err := Decode(data)
+		f, ok := decoded.(float64)
+		if err != nil || !ok || !(f == test.value || math.IsNaN(f) && math.IsNaN(test.value)) || math.Signbit(f) != math.Signbit(test.value) {
+			t.Errorf("Decode(%s) = %v, %v", test.cbor, decoded, err)
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"19",
+		"1901",
+		"62c3",
+		"83010203ff",
+		"0000",
+		"1c",
+		"ff",
+		"a10102",
+		"a161",
+		"a16161",
+		"bf6161ff",
+		"5a7fffffff",
+		"5f6161ff",
+		"5f5f4101ffff",
+		"1f",
+		"f0",
+		"9f01",
+	} {
+		raw, _ := hex.DecodeString(input)
+		if value, err := Decode(raw); err == nil {
+			t.Errorf("Decode(%s) = %v, want an error", input, value)
+		}
+	}
+
+	for _, value := range []interface{}{json.Number("1x"), 1, map[string]interface{}{}} {
+		if data, err := Encode(value); err == nil {
+			t.Errorf("Encode(%#v) = %x, want an error", value, data)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+func runBump(flags *flag.FlagSet) func(args []string) error {
+	field := flags.String("field", "version", "the key of the version, with dots between the keys of nested objects")
+	edits := addEditFlags(flags, "write the result back to the files and print the new versions, instead of printing the documents")
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		args, err := parseInterspersed(flags, args)
+		if err != nil {
+			return err
+		}
+
+		if err := edits.check(); err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return usageError("bump needs the part of the version to increment: major, minor, patch or prerelease")
+		}
+
+		part := args[0]
+		switch part {
+		case "major", "minor", "patch", "prerelease":
+		default:
+			return usageError("unknown version part %q, expected major, minor, patch or prerelease", part)
+		}
+
+		var path orderedjson.Path
+		for _, key := range strings.Split(*field, ".") {
+			path = append(path, key)
+		}
+
+		paths, err := files.expandPaths(args[1:])
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(file string, out io.Writer) error {
+			raw, err := readInput(file)
+			if err != nil {
+				return err
+			}
+
+			bumped, version, err := orderedjson.BumpVersion(raw, path, part)
+			var syntax *orderedjson.SyntaxError
+			if errors.As(err, &syntax) {
+				return &syntaxError{path: file, err: syntax, source: raw}
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if !edits.enabled(file) {
+				_, err = out.Write(bumped)
+				return err
+			}
+
+			if err := edits.apply(file, raw, bumped, out); err != nil {
+				return err
+			}
+
+			fmt.Fprintln(out, version)
+			return nil
+		})
+	}
+}
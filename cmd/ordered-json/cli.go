@@ -0,0 +1,877 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+	"github.com/michaelhelvey/orderedjson/v2/bson"
+	"github.com/michaelhelvey/orderedjson/v2/hcl"
+	"github.com/michaelhelvey/orderedjson/v2/query"
+	"github.com/michaelhelvey/orderedjson/v2/toml"
+)
+
+// running the binary without any arguments still does the package.json demo in main(). anything
+// else is treated as a subcommand. the exit code is 0 on success, 2 for usage errors, 3 for syntax
+// errors, 4 when a document doesn't match its schema, 5 for I/O errors and 1 for anything else.
+// commands that take files also take --quiet and --verbose. without any files they read stdin and
+// write stdout, so they chain into pipelines (cat a.json | ordered-json set .x 1 | ordered-json fmt)
+// without temporary files. only -w writes files, and never stdin.
+
+type command struct {
+	usage   string
+	summary string
+	// defines the command's flags, and returns what runs the command with the arguments left over
+	// after parsing them. this way the flags can be listed for help, completion and the man page
+	// without running anything.
+	setup func(flags *flag.FlagSet) func(args []string) error
+}
+
+var commands = map[string]command{
+	"lsp": {
+		usage:   "lsp",
+		summary: "run a language server on stdin/stdout",
+		setup:   runLSP,
+	},
+	"query": {
+		usage:   "query [--color when] <expression> [files...]",
+		summary: "run a jq-like expression against a document",
+		setup:   runQuery,
+	},
+	"grep": {
+		usage:   "grep [--regex] [--type t] [--path pattern] [--keys|--values] <pattern> [files...]",
+		summary: "find keys and values by json literal, regular expression or type, printed as file:path:line",
+		setup:   runGrep,
+	},
+	"rewrite": {
+		usage:   "rewrite --expr script [-w] [files...]",
+		summary: "change documents with set, del and rename steps, keeping the rest of the text as it was",
+		setup:   runRewrite,
+	},
+	"set": {
+		usage:   "set [-w] <path> <value> [files...]",
+		summary: "set the value at a path like .a.b, which is json or else a string, keeping the rest of the text as it was",
+		setup:   runSet,
+	},
+	"redact": {
+		usage:   "redact [--keys a,b] [--pattern re] [--remove] [files...]",
+		summary: "mask sensitive values",
+		setup:   runRedact,
+	},
+	"minify": {
+		usage:   "minify [files...]",
+		summary: "print documents without any insignificant whitespace",
+		setup:   runMinify,
+	},
+	"convert": {
+		usage:   "convert [--from format] [--to format] [--output file] [files...]",
+		summary: "convert documents between formats, like yaml to json or ndjson to csv (see help convert for all of them)",
+		setup:   runConvert,
+	},
+	"to-yaml": {
+		usage:   "to-yaml [files...]",
+		summary: "convert a json document to yaml",
+		setup:   runToYAML,
+	},
+	"from-yaml": {
+		usage:   "from-yaml [files...]",
+		summary: "convert a yaml document to json",
+		setup:   runFromYAML,
+	},
+	"to-toml": {
+		usage:   "to-toml [files...]",
+		summary: "convert a json document to toml",
+		setup:   runToTOML,
+	},
+	"to-csv": {
+		usage:   "to-csv [--path query] [--tsv] [--strict] [--no-header] [--flatten sep] [files...]",
+		summary: "convert an array of objects (or a stream of them) to csv, with columns in key order",
+		setup:   runToCSV,
+	},
+	"from-csv": {
+		usage:   "from-csv [--tsv] [--infer] [--no-header] [--unflatten sep] [--wrap key] [files...]",
+		summary: "convert csv with a header row to {\"rows\": [...]}, objects with keys in column order",
+		setup:   runFromCSV,
+	},
+	"to-xml": {
+		usage:   "to-xml [--indent] [--root name] [--attr-prefix @] [--text-key #text] [--children-key key] [files...]",
+		summary: "convert a json document to xml, writing elements in key order",
+		setup:   runToXML,
+	},
+	"from-xml": {
+		usage:   "from-xml [--attr-prefix @] [--text-key #text] [--children-key key] [files...]",
+		summary: "convert an xml document to json, keeping the order of attributes and elements",
+		setup:   runFromXML,
+	},
+	"to-form": {
+		usage:   "to-form [files...]",
+		summary: "encode a document as a form/query string (a[b][0]=x), keeping the key order",
+		setup:   runToForm,
+	},
+	"from-form": {
+		usage:   "from-form [files...]",
+		summary: "decode a form/query string (or a url with one) to json, keeping the parameter order",
+		setup:   runFromForm,
+	},
+	"fmt": {
+		usage:   "fmt [-w] [--check] [--diff] [--max-width n] [--standard] [--fix] [--jsonc] [--profile name] [--color when] [files...]",
+		summary: "pretty print documents",
+		setup:   runFmt,
+	},
+	"fix": {
+		usage:   "fix [-w] [--diff] [files...]",
+		summary: "repair comments, trailing commas, missing quotes and other almost-json",
+		setup:   runFix,
+	},
+	"bump": {
+		usage:   "bump <major|minor|patch|prerelease> [--field version] [-w] [files...]",
+		summary: "increment the semantic version in a manifest, leaving the rest of the file alone",
+		setup:   runBump,
+	},
+	"embed": {
+		usage:   "embed [--package name] [--var name] [--lazy] [-o file] [file]",
+		summary: "generate go source that holds a document as an ordered tree",
+		setup:   runEmbed,
+	},
+	"explain": {
+		usage:   "explain [--env-prefix prefix] <path> <base> [overlays...]",
+		summary: "show which layer of a merged config set a value, and what it overrides",
+		setup:   runExplain,
+	},
+	"render": {
+		usage:   "render [--values file] [templates...]",
+		summary: "fill in {{ }} placeholders in a json template, keeping its key order",
+		setup:   runRender,
+	},
+	"hash": {
+		usage:   "hash [--algo sha256] [--etag] [files...]",
+		summary: "fingerprint documents by hashing their canonical form (RFC 8785)",
+		setup:   runHash,
+	},
+	"from-toml": {
+		usage:   "from-toml [files...]",
+		summary: "convert a toml document to json",
+		setup:   runFromTOML,
+	},
+	"to-hcl": {
+		usage:   "to-hcl [--terraform] [--blocks a,b] [files...]",
+		summary: "convert a json document (shaped like .tf.json) to hcl, keeping the order of attributes and blocks",
+		setup:   runToHCL,
+	},
+	"from-hcl": {
+		usage:   "from-hcl [files...]",
+		summary: "convert hcl (like terraform's .tf files) to json in the shape of .tf.json, keeping the order",
+		setup:   runFromHCL,
+	},
+	"to-bson": {
+		usage:   "to-bson [files...]",
+		summary: "convert documents (with extended json for $oid, $date...) to BSON, keeping the field order",
+		setup:   runToBSON,
+	},
+	"from-bson": {
+		usage:   "from-bson [--canonical] [files...]",
+		summary: "print BSON documents (like a mongodump .bson file) as extended json, one per line",
+		setup:   runFromBSON,
+	},
+	"to-env": {
+		usage:   "to-env [--sep __] [files...]",
+		summary: "convert a json document to a .env file, flattening nested keys",
+		setup:   runToDotenv,
+	},
+	"from-env": {
+		usage:   "from-env [--unflatten sep] [files...]",
+		summary: "convert a .env file to json, keeping the line order",
+		setup:   runFromDotenv,
+	},
+	"to-properties": {
+		usage:   "to-properties [--sep .] [files...]",
+		summary: "convert a json document to a java .properties file, flattening nested keys",
+		setup:   runToProperties,
+	},
+	"from-properties": {
+		usage:   "from-properties [--unflatten sep] [files...]",
+		summary: "convert a java .properties file to json, keeping the line order",
+		setup:   runFromProperties,
+	},
+	"gen": {
+		usage:   "gen [--package name] [--type name] [files...]",
+		summary: "generate go types for a document",
+		setup:   runGen,
+	},
+	"infer-schema": {
+		usage:   "infer-schema [--go-structs] [files...]",
+		summary: "infer a json schema (or go types) from sample documents",
+		setup:   runInferSchema,
+	},
+	"validate": {
+		usage:   "validate [--schema file] [files...]",
+		summary: "check documents against a json schema",
+		setup:   runValidate,
+	},
+}
+
+func runCommand(args []string) error {
+	// --output goes before the command, since convert has an --output flag of its own
+	if format, rest, ok := outputFormat(args); ok {
+		switch {
+		case len(rest) == 0:
+			return usageError("--output needs a command after it")
+		case format == "json" && (rest[0] == "lsp" || rest[0] == "explore"):
+			return usageError("%s doesn't have json output", rest[0])
+		case format == "json" && report == nil:
+			return runReported(rest[0], rest)
+		case format == "json" || format == "text":
+			args = rest
+		default:
+			return usageError("unknown output %q, expected text or json", format)
+		}
+	}
+
+	name := args[0]
+	if name == "-h" || name == "--help" {
+		printUsage(os.Stdout)
+		return nil
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		printUsage(os.Stderr)
+		return usageError("unknown command %q", name)
+	}
+
+	flags := commandFlags(name)
+	run := cmd.setup(flags)
+	if err := parseFlags(flags, args[1:]); err != nil {
+		return err
+	}
+
+	return run(flags.Args())
+}
+
+// the value of --output (or --output=) at the start of args, and the arguments after it
+func outputFormat(args []string) (string, []string, bool) {
+	switch {
+	case strings.HasPrefix(args[0], "--output="):
+		return strings.TrimPrefix(args[0], "--output="), args[1:], true
+	case args[0] == "--output" && len(args) > 1:
+		return args[1], args[2:], true
+	case args[0] == "--output":
+		return "", nil, true
+	}
+
+	return "", args, false
+}
+
+// a flag set for the command, which prints its usage and flags on -h
+func commandFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		printCommandUsage(flags.Output(), name, flags)
+	}
+
+	return flags
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: ordered-json <command> [arguments]")
+	fmt.Fprintln(w)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, name := range commandNames() {
+		fmt.Fprintf(table, "  %s\t%s\n", name, commands[name].summary)
+	}
+	table.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `run "ordered-json help <command>" for the arguments and flags of a command, and put`)
+	fmt.Fprintln(w, `--output json before the command to get what it did as one json object`)
+}
+
+func printCommandUsage(w io.Writer, name string, flags *flag.FlagSet) {
+	cmd := commands[name]
+	fmt.Fprintf(w, "usage: ordered-json %s\n\n%s\n", cmd.usage, cmd.summary)
+
+	hasFlags := false
+	flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "flags:")
+		flags.SetOutput(w)
+		flags.PrintDefaults()
+	}
+}
+
+func runHelp(*flag.FlagSet) func(args []string) error {
+	return func(args []string) error {
+		if len(args) == 0 {
+			printUsage(os.Stdout)
+			return nil
+		}
+
+		cmd, ok := commands[args[0]]
+		if !ok {
+			return usageError("unknown command %q", args[0])
+		}
+
+		flags := commandFlags(args[0])
+		cmd.setup(flags)
+		printCommandUsage(os.Stdout, args[0], flags)
+		return nil
+	}
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// windows tools love to put one of these at the start of files
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// reads the file at path, or stdin when the path is empty or "-"
+func readInput(path string) ([]byte, error) {
+	if path == "" || path == "-" {
+		raw, err := io.ReadAll(os.Stdin)
+		return raw, withExitCode(exitIO, err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, withExitCode(exitIO, fmt.Errorf("could not read file %s: %v", path, err))
+	}
+
+	return raw, nil
+}
+
+func parseInput(path string) (*orderedjson.JsonObject, error) {
+	raw, err := readInput(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSource(path, raw, orderedjson.ParseOptions{})
+}
+
+// parses the contents of the file at path, with the line and column of syntax errors
+func parseSource(path string, raw []byte, opts orderedjson.ParseOptions) (*orderedjson.JsonObject, error) {
+	tree, err := opts.Parse(raw)
+	var syntax *orderedjson.SyntaxError
+	if errors.As(err, &syntax) {
+		return nil, &syntaxError{path: path, err: syntax, source: raw}
+	}
+
+	return tree, withExitCode(exitSyntax, err)
+}
+
+// parseSource for a Document, which knows where everything is in raw
+func parseDocumentSource(path string, raw []byte) (*orderedjson.Document, error) {
+	doc, err := orderedjson.ParseDocument(raw)
+	var syntax *orderedjson.SyntaxError
+	if errors.As(err, &syntax) {
+		return nil, &syntaxError{path: path, err: syntax, source: raw}
+	}
+
+	return doc, withExitCode(exitSyntax, err)
+}
+
+// marshals any value of a tree with the default options
+func marshalValue(value interface{}) (string, error) {
+	return orderedjson.MarshalOptions{}.Marshal(value)
+}
+
+// parses every file in paths
+func parseInputs(paths []string) ([]interface{}, error) {
+	docs := make([]interface{}, 0, len(paths))
+	for _, path := range paths {
+		tree, err := parseInput(path)
+		if err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, tree)
+	}
+
+	return docs, nil
+}
+
+func runQuery(flags *flag.FlagSet) func(args []string) error {
+	color := addColorFlag(flags)
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		if len(args) < 1 {
+			return usageError("usage: ordered-json query <expression> [files...]")
+		}
+
+		paths, err := files.expandPaths(args[1:])
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			tree, err := parseInput(path)
+			if err != nil {
+				return err
+			}
+
+			results, err := query.Query(tree, args[0])
+			if err != nil {
+				return err
+			}
+
+			for _, result := range results {
+				data, err := marshalValue(result)
+				if err != nil {
+					return err
+				}
+
+				if color.enabled() {
+					data = colorize(data)
+				}
+
+				fmt.Fprintln(out, data)
+			}
+
+			return nil
+		})
+	}
+}
+
+func runMinify(flags *flag.FlagSet) func(args []string) error {
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			tree, err := parseInput(path)
+			if err != nil {
+				return err
+			}
+
+			data, err := orderedjson.MarshalCompact(tree)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(out, data)
+			return nil
+		})
+	}
+}
+
+func runToYAML(flags *flag.FlagSet) func(args []string) error {
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			tree, err := parseInput(path)
+			if err != nil {
+				return err
+			}
+
+			data, err := orderedjson.ToYAML(tree)
+			if err != nil {
+				return err
+			}
+
+			_, err = out.Write(data)
+			return err
+		})
+	}
+}
+
+func runFromYAML(flags *flag.FlagSet) func(args []string) error {
+	files := addFileFlags(flags, ".yaml", ".yml")
+
+	return func(args []string) error {
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			raw, err := readInput(path)
+			if err != nil {
+				return err
+			}
+
+			tree, err := orderedjson.FromYAML(raw)
+			if err != nil {
+				return withExitCode(exitSyntax, err)
+			}
+
+			data, err := marshalValue(tree)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(out, data)
+			return nil
+		})
+	}
+}
+
+func runToTOML(flags *flag.FlagSet) func(args []string) error {
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			tree, err := parseInput(path)
+			if err != nil {
+				return err
+			}
+
+			data, err := toml.Encode(tree)
+			if err != nil {
+				return err
+			}
+
+			_, err = out.Write(data)
+			return err
+		})
+	}
+}
+
+func runFromTOML(flags *flag.FlagSet) func(args []string) error {
+	files := addFileFlags(flags, ".toml")
+
+	return func(args []string) error {
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			raw, err := readInput(path)
+			if err != nil {
+				return err
+			}
+
+			tree, err := toml.Decode(raw)
+			if err != nil {
+				return withExitCode(exitSyntax, err)
+			}
+
+			data, err := marshalValue(tree)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(out, data)
+			return nil
+		})
+	}
+}
+
+func runToHCL(flags *flag.FlagSet) func(args []string) error {
+	terraform := flags.Bool("terraform", false, "only write terraform's block types (and their labels) as blocks, other objects are attributes")
+	blocks := flags.String("blocks", "", "comma separated keys to write as blocks too, like ingress")
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		var opts hcl.EncodeOptions
+		if *terraform {
+			opts = hcl.EncodeOptions{Labels: hcl.Terraform.Labels, Blocks: maps.Clone(hcl.Terraform.Blocks)}
+		}
+
+		if *blocks != "" {
+			if opts.Blocks == nil {
+				opts.Blocks = map[string]bool{}
+			}
+
+			for _, key := range strings.Split(*blocks, ",") {
+				opts.Blocks[strings.TrimSpace(key)] = true
+			}
+		}
+
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			tree, err := parseInput(path)
+			if err != nil {
+				return err
+			}
+
+			data, err := hcl.Encode(tree, opts)
+			if err != nil {
+				return err
+			}
+
+			_, err = out.Write(data)
+			return err
+		})
+	}
+}
+
+func runFromHCL(flags *flag.FlagSet) func(args []string) error {
+	files := addFileFlags(flags, ".hcl", ".tf")
+
+	return func(args []string) error {
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			raw, err := readInput(path)
+			if err != nil {
+				return err
+			}
+
+			tree, err := hcl.Decode(raw)
+			if err != nil {
+				return withExitCode(exitSyntax, err)
+			}
+
+			data, err := marshalValue(tree)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(out, data)
+			return nil
+		})
+	}
+}
+
+func runToBSON(flags *flag.FlagSet) func(args []string) error {
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			raw, err := readInput(path)
+			if err != nil {
+				return err
+			}
+
+			// every document in the file, like mongodump writes them
+			reader := orderedjson.NewStreamReader(bytes.NewReader(raw), orderedjson.ConcatenatedStream)
+			for {
+				tree, err := reader.Next()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return withExitCode(exitSyntax, fmt.Errorf("%s: %w", displayName(path), err))
+				}
+
+				data, err := bson.Encode(tree)
+				if err != nil {
+					return err
+				}
+
+				if _, err := out.Write(data); err != nil {
+					return err
+				}
+			}
+		})
+	}
+}
+
+func runFromBSON(flags *flag.FlagSet) func(args []string) error {
+	canonical := flags.Bool("canonical", false, "write every number with its BSON type, so to-bson gives back the same bytes")
+	files := addFileFlags(flags, ".bson")
+
+	return func(args []string) error {
+		decode := bson.Decode
+		if *canonical {
+			decode = bson.DecodeCanonical
+		}
+
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			raw, err := readInput(path)
+			if err != nil {
+				return err
+			}
+
+			documents, err := bson.Split(raw)
+			if err != nil {
+				return withExitCode(exitSyntax, fmt.Errorf("%s: %w", displayName(path), err))
+			}
+
+			for _, document := range documents {
+				tree, err := decode(document)
+				if err != nil {
+					return withExitCode(exitSyntax, fmt.Errorf("%s: %w", displayName(path), err))
+				}
+
+				data, err := orderedjson.MarshalCompact(tree)
+				if err != nil {
+					return err
+				}
+
+				fmt.Fprintln(out, data)
+			}
+
+			return nil
+		})
+	}
+}
+
+func runInferSchema(flags *flag.FlagSet) func(args []string) error {
+	goStructs := flags.Bool("go-structs", false, "print go type declarations instead of a json schema")
+	typeName := flags.String("type", "Document", "name of the top level go type")
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		docs, err := parseInputs(paths)
+		if err != nil {
+			return err
+		}
+
+		if *goStructs {
+			source, err := orderedjson.InferGoStructs(*typeName, docs...)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprint(files.stdout(), source)
+			return nil
+		}
+
+		data, err := marshalValue(orderedjson.InferSchema(docs...))
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(files.stdout(), data)
+		return nil
+	}
+}
+
+func runGen(flags *flag.FlagSet) func(args []string) error {
+	packageName := flags.String("package", "main", "package clause of the generated file")
+	typeName := flags.String("type", "Document", "name of the top level type")
+	output := flags.String("o", "", "write the generated file here instead of stdout")
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		docs, err := parseInputs(paths)
+		if err != nil {
+			return err
+		}
+
+		source, err := orderedjson.GenerateGo(*packageName, *typeName, docs...)
+		if err != nil {
+			return err
+		}
+
+		if *output != "" {
+			return withExitCode(exitIO, os.WriteFile(*output, source, 0644))
+		}
+
+		_, err = files.stdout().Write(source)
+		return err
+	}
+}
+
+func runRedact(flags *flag.FlagSet) func(args []string) error {
+	keys := flags.String("keys", "password,token,secret", "comma separated keys to redact, ignoring case")
+	pattern := flags.String("pattern", "", "also redact keys matching this regular expression")
+	remove := flags.Bool("remove", false, "drop the matched keys instead of masking their values")
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		matchers := []orderedjson.KeyMatcher{}
+		if *keys != "" {
+			matchers = append(matchers, orderedjson.MatchKeys(strings.Split(*keys, ",")...))
+		}
+
+		if *pattern != "" {
+			re, err := regexp.Compile(*pattern)
+			if err != nil {
+				return usageError("invalid --pattern: %v", err)
+			}
+
+			matchers = append(matchers, orderedjson.MatchPattern(re))
+		}
+
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			raw, err := readInput(path)
+			if err != nil {
+				return err
+			}
+
+			doc, err := parseDocumentSource(path, raw)
+			if err != nil {
+				return err
+			}
+
+			tree := doc.Tree
+			if *remove {
+				tree = orderedjson.RemoveKeys(tree, matchers...)
+			} else {
+				tree = orderedjson.Redact(tree, matchers...)
+			}
+
+			// only the redacted values change, the rest of the file stays byte for byte
+			text, err := doc.Patch(tree)
+			if err != nil {
+				return err
+			}
+
+			if bytes.HasPrefix(raw, utf8BOM) {
+				text = append(append([]byte{}, utf8BOM...), text...)
+			}
+
+			_, err = out.Write(text)
+			return err
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHelp(t *testing.T) {
+	overview, err := runCLI(t, "help")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range commandNames() {
+		// the name, then the summary, without the arguments
+		want := "  " + name + " "
+		line := ""
+		for _, candidate := range strings.Split(overview, "\n") {
+			if strings.HasPrefix(candidate, want) {
+				line = candidate
+			}
+		}
+
+		if line == "" || !strings.HasSuffix(line, " "+commands[name].summary) {
+			t.Errorf("help lists %s as %q", name, line)
+		}
+
+		if between := strings.TrimSuffix(strings.TrimPrefix(line, want), commands[name].summary); strings.TrimSpace(between) != "" {
+			t.Errorf("help lists the arguments of %s: %q", name, line)
+		}
+	}
+
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"fmt", []string{"usage: ordered-json fmt [-w] [--check]", "pretty print documents", "flags:", "-dry-run"}},
+		{"query", []string{"usage: ordered-json query [--color when] <expression> [files...]"}},
+	}
+
+	for _, test := range tests {
+		out, err := runCLI(t, "help", test.command)
+		if err != nil {
+			t.Errorf("help %s: %v", test.command, err)
+			continue
+		}
+
+		for _, want := range test.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("help %s doesn't say %q:\n%s", test.command, want, out)
+			}
+		}
+	}
+
+	if _, err := runCLI(t, "help", "nope"); exitCode(err) != exitUsage {
+		t.Errorf("help for an unknown command: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// syntax highlighting for json written to a terminal
+
+const (
+	colorReset  = "\x1b[0m"
+	colorKey    = "\x1b[1;34m"
+	colorString = "\x1b[32m"
+	colorNumber = "\x1b[36m"
+	colorAtom   = "\x1b[35m"
+	colorPunct  = "\x1b[2m"
+	// comments in JSONC output
+	colorComment = "\x1b[2;3m"
+)
+
+type colorMode string
+
+const (
+	colorAuto   colorMode = "auto"
+	colorAlways colorMode = "always"
+	colorNever  colorMode = "never"
+)
+
+func addColorFlag(flags *flag.FlagSet) *colorMode {
+	mode := colorAuto
+	flags.Func("color", "highlight the output: auto (when writing to a terminal), always or never", func(value string) error {
+		switch colorMode(value) {
+		case colorAuto, colorAlways, colorNever:
+			mode = colorMode(value)
+			return nil
+		}
+
+		return fmt.Errorf("expected auto, always or never")
+	})
+
+	return &mode
+}
+
+// whether output to stdout should be highlighted. auto respects NO_COLOR (https://no-color.org).
+func (mode colorMode) enabled() bool {
+	switch mode {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+
+	return os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && isTerminal(int(os.Stdout.Fd()))
+}
+
+// highlights marshaled json. it only looks at tokens, so it works on any layout.
+func colorize(text string) string {
+	var result strings.Builder
+	result.Grow(len(text) * 2)
+
+	for i := 0; i < len(text); {
+		switch char := text[i]; {
+		case char == '"':
+			end := i + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(text))
+
+			// a string followed by a colon is a key
+			next := end
+			for next < len(text) && strings.IndexByte(" \t\r\n", text[next]) >= 0 {
+				next++
+			}
+
+			color := colorString
+			if next < len(text) && text[next] == ':' {
+				color = colorKey
+			}
+
+			result.WriteString(color + text[i:end] + colorReset)
+			i = end
+		case strings.HasPrefix(text[i:], "//") || strings.HasPrefix(text[i:], "/*"):
+			end := strings.IndexByte(text[i:], '\n')
+			if text[i+1] == '*' {
+				if end = strings.Index(text[i:], "*/"); end >= 0 {
+					end += 2
+				}
+			}
+
+			if end < 0 {
+				end = len(text) - i
+			}
+
+			result.WriteString(colorComment + text[i:i+end] + colorReset)
+			i += end
+		case char == '-' || (char >= '0' && char <= '9'):
+			end := i + 1
+			for end < len(text) && strings.IndexByte("0123456789.eE+-", text[end]) >= 0 {
+				end++
+			}
+
+			result.WriteString(colorNumber + text[i:end] + colorReset)
+			i = end
+		case char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z':
+			// true, false, null, and NaN or Infinity when they're allowed
+			end := i + 1
+			for end < len(text) && (text[end] >= 'a' && text[end] <= 'z' || text[end] >= 'A' && text[end] <= 'Z') {
+				end++
+			}
+
+			result.WriteString(colorAtom + text[i:end] + colorReset)
+			i = end
+		case strings.IndexByte("{}[]:,", char) >= 0:
+			result.WriteString(colorPunct + string(char) + colorReset)
+			i++
+		default:
+			result.WriteByte(char)
+			i++
+		}
+	}
+
+	return result.String()
+}
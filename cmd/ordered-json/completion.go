@@ -0,0 +1,297 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// shell completion scripts and the man page are generated from the commands and their flags, so
+// they can't go out of date.
+
+func init() {
+	// these list the commands, so they can't be part of the commands literal
+	commands["help"] = command{
+		usage:   "help [command]",
+		summary: "show the commands, or the flags of one command",
+		setup:   runHelp,
+	}
+	commands["completion"] = command{
+		usage:   "completion bash|zsh|fish",
+		summary: "print a shell completion script",
+		setup:   runCompletion,
+	}
+	commands["explore"] = command{
+		usage:   "explore <file>",
+		summary: "browse, search and edit a document in the terminal",
+		setup:   runExplore,
+	}
+	commands["man"] = command{
+		usage:   "man",
+		summary: "print the man page",
+		setup:   runMan,
+	}
+}
+
+func runCompletion(*flag.FlagSet) func(args []string) error {
+	return func(args []string) error {
+		if len(args) != 1 {
+			return usageError("usage: ordered-json completion bash|zsh|fish")
+		}
+
+		switch args[0] {
+		case "bash":
+			writeBashCompletion(os.Stdout)
+		case "zsh":
+			writeZshCompletion(os.Stdout)
+		case "fish":
+			writeFishCompletion(os.Stdout)
+		default:
+			return usageError("unsupported shell %q, expected bash, zsh or fish", args[0])
+		}
+
+		return nil
+	}
+}
+
+func runMan(*flag.FlagSet) func(args []string) error {
+	return func(args []string) error {
+		writeManPage(os.Stdout)
+		return nil
+	}
+}
+
+// the flags of a command, in alphabetical order
+func flagsOf(name string) []*flag.Flag {
+	flags := commandFlags(name)
+	commands[name].setup(flags)
+
+	var result []*flag.Flag
+	flags.VisitAll(func(f *flag.Flag) {
+		result = append(result, f)
+	})
+
+	return result
+}
+
+// how the flag is usually written. the flag package takes one or two dashes for any flag, but
+// single letter flags look better with one.
+func flagName(f *flag.Flag) string {
+	if len(f.Name) == 1 {
+		return "-" + f.Name
+	}
+
+	return "--" + f.Name
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
+
+func writeBashCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# bash completion for ordered-json, load it with: source <(ordered-json completion bash)")
+	fmt.Fprintln(w, "_ordered_json() {")
+	fmt.Fprintln(w, `	local cur="${COMP_WORDS[COMP_CWORD]}"`)
+	fmt.Fprintln(w, `	if [ "$COMP_CWORD" -eq 1 ]; then`)
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(commandNames(), " "))
+	fmt.Fprintln(w, "\t\treturn")
+	fmt.Fprintln(w, "\tfi")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `	case "${COMP_WORDS[1]}" in`)
+	for _, name := range commandNames() {
+		switch name {
+		case "help":
+			fmt.Fprintf(w, "\t%s)\n\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n\t\treturn\n\t\t;;\n", name, strings.Join(commandNames(), " "))
+			continue
+		case "completion":
+			fmt.Fprintf(w, "\t%s)\n\t\tCOMPREPLY=($(compgen -W \"bash zsh fish\" -- \"$cur\"))\n\t\treturn\n\t\t;;\n", name)
+			continue
+		}
+
+		flags := flagsOf(name)
+		if len(flags) == 0 {
+			continue
+		}
+
+		names := make([]string, 0, len(flags))
+		for _, f := range flags {
+			names = append(names, flagName(f))
+		}
+
+		fmt.Fprintf(w, "\t%s)\n", name)
+		fmt.Fprintln(w, `		if [[ "$cur" == -* ]]; then`)
+		fmt.Fprintf(w, "\t\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+		fmt.Fprintln(w, "\t\t\treturn")
+		fmt.Fprintln(w, "\t\tfi")
+		fmt.Fprintln(w, "\t\t;;")
+	}
+	fmt.Fprintln(w, "\tesac")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `	COMPREPLY=($(compgen -f -- "$cur"))`)
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -o filenames -F _ordered_json ordered-json")
+}
+
+func writeZshCompletion(w io.Writer) {
+	// zsh wants [, ], : and \ escaped in descriptions, and everything goes in single quotes
+	escape := strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, ":", `\:`, "'", `'\''`)
+
+	fmt.Fprintln(w, "#compdef ordered-json")
+	fmt.Fprintln(w, "# zsh completion for ordered-json, save it as _ordered-json somewhere in $fpath")
+	fmt.Fprintln(w, "_ordered_json() {")
+	fmt.Fprintln(w, "\tlocal -a commands")
+	fmt.Fprintln(w, "\tcommands=(")
+	for _, name := range commandNames() {
+		fmt.Fprintf(w, "\t\t'%s:%s'\n", name, escape.Replace(commands[name].summary))
+	}
+	fmt.Fprintln(w, "\t)")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\tif (( CURRENT == 2 )); then")
+	fmt.Fprintln(w, "\t\t_describe 'command' commands")
+	fmt.Fprintln(w, "\t\treturn")
+	fmt.Fprintln(w, "\tfi")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "\tlocal cmd=$words[2]")
+	fmt.Fprintln(w, "\tshift words")
+	fmt.Fprintln(w, "\t(( CURRENT-- ))")
+	fmt.Fprintln(w, "\tcase $cmd in")
+	for _, name := range commandNames() {
+		fmt.Fprintf(w, "\t%s)\n", name)
+		switch name {
+		case "help":
+			fmt.Fprintln(w, "\t\t_describe 'command' commands")
+		case "completion":
+			fmt.Fprintln(w, "\t\t_values 'shell' bash zsh fish")
+		default:
+			fmt.Fprint(w, "\t\t_arguments")
+			for _, f := range flagsOf(name) {
+				spec := fmt.Sprintf("%s[%s]", flagName(f), escape.Replace(f.Usage))
+				if !isBoolFlag(f) {
+					spec += ":" + f.Name + ":"
+				}
+
+				fmt.Fprintf(w, " \\\n\t\t\t'%s'", spec)
+			}
+			fmt.Fprint(w, " \\\n\t\t\t'*:file:_files'\n")
+		}
+		fmt.Fprintln(w, "\t\t;;")
+	}
+	fmt.Fprintln(w, "\tesac")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `if [ "$funcstack[1]" = "_ordered_json" ] || [ "$funcstack[1]" = "_ordered-json" ]; then`)
+	fmt.Fprintln(w, `	_ordered_json "$@"`)
+	fmt.Fprintln(w, "else")
+	fmt.Fprintln(w, "\tcompdef _ordered_json ordered-json")
+	fmt.Fprintln(w, "fi")
+}
+
+func writeFishCompletion(w io.Writer) {
+	quote := func(s string) string {
+		return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+	}
+
+	fmt.Fprintln(w, "# fish completion for ordered-json, save it as ~/.config/fish/completions/ordered-json.fish")
+	fmt.Fprintln(w, "complete -c ordered-json -f")
+	for _, name := range commandNames() {
+		fmt.Fprintf(w, "complete -c ordered-json -n __fish_use_subcommand -a %s -d %s\n", name, quote(commands[name].summary))
+	}
+
+	for _, name := range commandNames() {
+		condition := quote("__fish_seen_subcommand_from " + name)
+		switch name {
+		case "help":
+			fmt.Fprintf(w, "complete -c ordered-json -n %s -a %s\n", condition, quote(strings.Join(commandNames(), " ")))
+			continue
+		case "completion":
+			fmt.Fprintf(w, "complete -c ordered-json -n %s -a 'bash zsh fish'\n", condition)
+			continue
+		case "lsp", "man":
+			continue
+		}
+
+		for _, f := range flagsOf(name) {
+			option := "-l " + f.Name
+			if len(f.Name) == 1 {
+				option = "-s " + f.Name
+			}
+
+			if !isBoolFlag(f) {
+				option += " -r"
+			}
+
+			fmt.Fprintf(w, "complete -c ordered-json -n %s %s -d %s\n", condition, option, quote(f.Usage))
+		}
+
+		fmt.Fprintf(w, "complete -c ordered-json -n %s -F\n", condition)
+	}
+}
+
+func writeManPage(w io.Writer) {
+	fmt.Fprintln(w, `.TH ORDERED-JSON 1`)
+	fmt.Fprintln(w, `.SH NAME`)
+	fmt.Fprintln(w, `ordered-json \- work with json documents without losing the order of their keys`)
+	fmt.Fprintln(w, `.SH SYNOPSIS`)
+	fmt.Fprintln(w, `.B ordered-json`)
+	fmt.Fprintln(w, `.I command`)
+	fmt.Fprintln(w, `[\fIarguments\fR]`)
+	fmt.Fprintln(w, `.SH DESCRIPTION`)
+	fmt.Fprintln(w, `Commands that take files read stdin when there are none. Directories are searched for files with`)
+	fmt.Fprintln(w, `the extensions the command reads, and globs may use ** for any number of directories.`)
+	fmt.Fprintln(w, `.SH COMMANDS`)
+	for _, name := range commandNames() {
+		fmt.Fprintln(w, ".TP")
+		fmt.Fprintf(w, ".B %s\n", roffEscape(commands[name].usage))
+		fmt.Fprintln(w, roffEscape(commands[name].summary))
+
+		flags := flagsOf(name)
+		if len(flags) == 0 {
+			continue
+		}
+
+		fmt.Fprintln(w, ".RS")
+		for _, f := range flags {
+			fmt.Fprintln(w, ".TP")
+			argument := ""
+			if !isBoolFlag(f) {
+				argument = " " + f.Name
+				if f.DefValue != "" {
+					argument = fmt.Sprintf(" %s (default %q)", f.Name, f.DefValue)
+				}
+			}
+
+			fmt.Fprintf(w, `\fB%s\fR%s`+"\n", roffEscape(flagName(f)), roffEscape(argument))
+			fmt.Fprintln(w, roffEscape(f.Usage))
+		}
+		fmt.Fprintln(w, ".RE")
+	}
+
+	fmt.Fprintln(w, `.SH EXIT STATUS`)
+	for _, status := range []struct {
+		code        int
+		description string
+	}{
+		{exitOK, "success"},
+		{exitFailure, "any other failure"},
+		{exitUsage, "an unknown command, a bad flag or missing arguments"},
+		{exitSyntax, "a document doesn't parse"},
+		{exitSchema, "a document doesn't match its schema"},
+		{exitIO, "a file couldn't be found, read or written"},
+	} {
+		fmt.Fprintln(w, ".TP")
+		fmt.Fprintf(w, ".B %d\n", status.code)
+		fmt.Fprintln(w, roffEscape(status.description))
+	}
+}
+
+func roffEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+
+	return s
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/michaelhelvey/orderedjson/v2/formats"
+)
+
+// the convert command reads any format in the formats registry and writes any other. formats that
+// can hold more than one document (json streams, yaml, bson) are read and written a document at a
+// time, so big files go through in constant memory.
+
+func runConvert(flags *flag.FlagSet) func(args []string) error {
+	from := flags.String("from", "", "the input format, instead of going by the file extension or the content")
+	to := flags.String("to", "", "the output format, which can be left out when --output has an extension for one: "+strings.Join(formats.Names(), ", "))
+	output := flags.String("output", "", "write to this file instead of stdout")
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		names := strings.Join(formats.Names(), ", ")
+		if _, ok := formats.Lookup(*from); *from != "" && !ok {
+			return usageError("unknown format %q, expected one of %s", *from, names)
+		}
+
+		if *to == "" && *output != "" {
+			*to, _ = formats.ForPath(*output)
+		}
+
+		target, ok := formats.Lookup(*to)
+		switch {
+		case *to == "":
+			return usageError("--to is needed (one of %s)", names)
+		case !ok:
+			return usageError("unknown format %q, expected one of %s", *to, names)
+		}
+
+		// directories are searched for every format convert reads (or just the one from --from)
+		files.extensions = nil
+		for _, name := range formats.Names() {
+			if *from == "" || name == *from {
+				codec, _ := formats.Lookup(name)
+				files.extensions = append(files.extensions, codec.Extensions...)
+			}
+		}
+
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		out := files.stdout()
+		if *output != "" {
+			file, err := os.Create(*output)
+			if err != nil {
+				return withExitCode(exitIO, err)
+			}
+			defer file.Close()
+
+			out = file
+		}
+
+		buffered := bufio.NewWriter(out)
+		if err := convertFiles(paths, *from, *to, target, buffered); err != nil {
+			if *output != "" {
+				os.Remove(*output)
+			}
+
+			return err
+		}
+
+		return withExitCode(exitIO, buffered.Flush())
+	}
+}
+
+func convertFiles(paths []string, from, to string, target formats.Codec, out *bufio.Writer) error {
+	var collected []interface{}
+	index := 0
+	for _, path := range paths {
+		err := convertFile(path, from, func(value interface{}) error {
+			if target.Encode == nil {
+				collected = append(collected, value)
+				return nil
+			}
+
+			index++
+			if err := target.Encode(out, value, index-1); err != nil {
+				return withExitCode(exitFailure, fmt.Errorf("%s: %w", to, err))
+			}
+
+			// documents go out as they're converted, for pipes
+			return withExitCode(exitIO, out.Flush())
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", displayName(path), err)
+		}
+	}
+
+	if target.Encode == nil {
+		if err := target.EncodeAll(out, collected); err != nil {
+			return fmt.Errorf("%s: %w", to, err)
+		}
+	}
+
+	return nil
+}
+
+func convertFile(path, from string, each func(value interface{}) error) error {
+	var input io.Reader = os.Stdin
+	if path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return withExitCode(exitIO, err)
+		}
+		defer file.Close()
+
+		input = file
+	}
+
+	reader := bufio.NewReader(input)
+	if from == "" {
+		// peeking fails on short inputs, which still have what was there
+		head, _ := reader.Peek(512)
+		name, ok := formats.Detect(path, head)
+		if !ok {
+			return usageError("can't tell what format this is, use --from")
+		}
+
+		from = name
+	}
+
+	codec, _ := formats.Lookup(from)
+	err := codec.Decode(reader, each)
+
+	// errors from each already have their code, so anything else is the input's fault
+	var coded *exitError
+	if err != nil && !errors.As(err, &coded) {
+		return withExitCode(exitSyntax, err)
+	}
+
+	return err
+}
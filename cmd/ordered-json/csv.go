@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+	"github.com/michaelhelvey/orderedjson/v2/query"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+func runToCSV(flags *flag.FlagSet) func(args []string) error {
+	tsv := flags.Bool("tsv", false, "separate fields with tabs instead of commas")
+	strict := flags.Bool("strict", false, "fail when an object's keys differ from the first one's, instead of adding columns")
+	noHeader := flags.Bool("no-header", false, "leave out the header row")
+	flatten := flags.String("flatten", "", "flatten nested values into columns, joining keys with this")
+	query := flags.String("path", "", "query for the array of objects in each document. without it, every document in the file (NDJSON or concatenated) is a row, or every element when the file is one array or a document with nothing but a \"rows\" array, like from-csv writes")
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		opts := orderedjson.CSVOptions{NoHeader: *noHeader, Flatten: *flatten}
+		if *tsv {
+			opts.Comma = '\t'
+		}
+		if *strict {
+			opts.Columns = orderedjson.StrictColumns
+		}
+
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			rows, err := csvRows(path, *query)
+			if err != nil {
+				return err
+			}
+
+			data, err := orderedjson.ToCSV(rows, opts)
+			if err != nil {
+				return err
+			}
+
+			_, err = out.Write(data)
+			return err
+		})
+	}
+}
+
+func runFromCSV(flags *flag.FlagSet) func(args []string) error {
+	tsv := flags.Bool("tsv", false, "fields are separated with tabs instead of commas")
+	infer := flags.Bool("infer", false, "turn numbers and true/false into json numbers and bools, and empty cells into null")
+	noHeader := flags.Bool("no-header", false, "there's no header row, name the columns 1, 2, 3...")
+	unflatten := flags.String("unflatten", "", "nest columns whose names are keys joined by this")
+	wrap := flags.String("wrap", "rows", "the key of the rows in the document written, so the other commands can read it. empty writes the bare array, which only to-csv reads")
+	files := addFileFlags(flags, ".csv", ".tsv")
+
+	return func(args []string) error {
+		opts := orderedjson.CSVOptions{NoHeader: *noHeader, Flatten: *unflatten}
+		if *infer {
+			opts.InferNumbers, opts.InferBools, opts.EmptyNull = true, true, true
+		}
+
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			raw, err := readInput(path)
+			if err != nil {
+				return err
+			}
+
+			fileOpts := opts
+			if *tsv || strings.HasSuffix(path, ".tsv") {
+				fileOpts.Comma = '\t'
+			}
+
+			rows, err := orderedjson.FromCSV(raw, fileOpts)
+			if err != nil {
+				return withExitCode(exitSyntax, fmt.Errorf("%s: %w", displayName(path), err))
+			}
+
+			var result interface{} = rows
+			if *wrap != "" {
+				object := orderedmap.New[string, interface{}]()
+				object.Set(*wrap, rows)
+				result = object
+			}
+
+			data, err := marshalValue(result)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(out, data)
+			return nil
+		})
+	}
+}
+
+// the rows to-csv writes for the file at path
+func csvRows(path, expr string) ([]interface{}, error) {
+	raw, err := readInput(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// a file that's one array of rows (like from-csv --wrap "" writes) isn't a document, but it's
+	// the obvious input for a table
+	if trimmed := bytes.TrimLeft(raw, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+		value, err := orderedjson.FromStdJSONValue(trimmed)
+		if err != nil {
+			return nil, withExitCode(exitSyntax, fmt.Errorf("%s: %w", displayName(path), err))
+		}
+
+		if expr == "" {
+			return value.([]interface{}), nil
+		}
+
+		return queryRows(value, expr)
+	}
+
+	var rows []interface{}
+	reader := orderedjson.NewStreamReader(bytes.NewReader(raw), orderedjson.ConcatenatedStream)
+	for {
+		tree, err := reader.Next()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, withExitCode(exitSyntax, fmt.Errorf("%s: %w", displayName(path), err))
+		}
+
+		if expr == "" {
+			// the document from-csv writes by default is its rows
+			if tree.Len() == 1 {
+				if wrapped, ok := tree.Value("rows").([]interface{}); ok {
+					rows = append(rows, wrapped...)
+					continue
+				}
+			}
+
+			rows = append(rows, tree)
+			continue
+		}
+
+		results, err := queryRows(tree, expr)
+		if err != nil {
+			return nil, err
+		}
+
+		rows = append(rows, results...)
+	}
+}
+
+// the rows expr finds in value: the elements of the arrays it gives, and anything else as it is
+func queryRows(value interface{}, expr string) ([]interface{}, error) {
+	results, err := query.Query(value, expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []interface{}
+	for _, result := range results {
+		if items, ok := result.([]interface{}); ok {
+			rows = append(rows, items...)
+		} else {
+			rows = append(rows, result)
+		}
+	}
+
+	return rows, nil
+}
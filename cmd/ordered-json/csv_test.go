@@ -0,0 +1,94 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestToCSVCommand(t *testing.T) {
+	tests := []struct {
+		input string
+		args  []string
+		want  string
+	}{
+		// one array of rows, like from-csv --wrap "" writes
+		{`[{"b": 1, "a": "x"}, {"a": "y", "b": 2}]`, nil, "b,a\n1,x\n2,y\n"},
+		{"  \n[]", nil, ""},
+		// a document per row
+		{"{\"b\": 1, \"a\": \"x\"}\n{\"a\": \"y\", \"b\": 2}\n", nil, "b,a\n1,x\n2,y\n"},
+		{`{"rows": [{"a": 1}, {"a": 2}]}`, []string{"--path", ".rows"}, "a\n1\n2\n"},
+		{`[{"rows": [{"a": 1}]}, {"rows": [{"a": 2}]}]`, []string{"--path", ".[].rows"}, "a\n1\n2\n"},
+		{`[{"a": 1, "b": 2}]`, []string{"--tsv"}, "a\tb\n1\t2\n"},
+	}
+
+	for _, test := range tests {
+		dir := writeFiles(t, map[string]string{"a.json": test.input})
+		args := append(append([]string{"to-csv"}, test.args...), filepath.Join(dir, "a.json"))
+		got, err := runCLI(t, args...)
+		if err != nil {
+			t.Errorf("%s on %q: %v", strings.Join(args[:len(args)-1], " "), test.input, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("%s on %q printed %q, want %q", strings.Join(args[:len(args)-1], " "), test.input, got, test.want)
+		}
+	}
+}
+
+func TestFromCSVCommand(t *testing.T) {
+	const input = "b,a\n1,x\n,true\n"
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, `{"rows": [{"b": "1", "a": "x"}, {"b": "", "a": "true"}]}` + "\n"},
+		{[]string{"--infer"}, `{"rows": [{"b": 1, "a": "x"}, {"b": null, "a": true}]}` + "\n"},
+		{[]string{"--wrap", "users"}, `{"users": [{"b": "1", "a": "x"}, {"b": "", "a": "true"}]}` + "\n"},
+		{[]string{"--wrap", ""}, `[{"b": "1", "a": "x"}, {"b": "", "a": "true"}]` + "\n"},
+	}
+
+	for _, test := range tests {
+		name := strings.Join(append([]string{"from-csv"}, test.args...), " ")
+		dir := writeFiles(t, map[string]string{"a.csv": input})
+		got, err := runCLI(t, append(append([]string{"from-csv"}, test.args...), filepath.Join(dir, "a.csv"))...)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("%s printed %q, want %q", name, got, test.want)
+			continue
+		}
+
+		// to-csv turns it back into the same csv, and a wrapped one is a document for the rest
+		out := filepath.Join(writeFiles(t, map[string]string{"a.json": got}), "a.json")
+		if back, err := runCLI(t, "to-csv", "--path", ".[]", out); err != nil || back != input {
+			t.Errorf("to-csv of %s = %q (%v), want %q", name, back, err, input)
+		}
+
+		if _, err := runCLI(t, "fmt", out); err != nil && strings.HasPrefix(got, "{") {
+			t.Errorf("fmt of %s: %v", name, err)
+		}
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	const input = "b,a\n1,x\n,true\n"
+	for _, args := range [][]string{nil, {"--infer"}, {"--wrap", ""}} {
+		name := strings.Join(append([]string{"from-csv"}, args...), " ")
+		dir := writeFiles(t, map[string]string{"a.csv": input})
+		converted, err := runCLI(t, append(append([]string{"from-csv"}, args...), filepath.Join(dir, "a.csv"))...)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		out := filepath.Join(writeFiles(t, map[string]string{"a.json": converted}), "a.json")
+		if back, err := runCLI(t, "to-csv", out); err != nil || back != input {
+			t.Errorf("%s | to-csv = %q (%v), want %q", name, back, err, input)
+		}
+	}
+}
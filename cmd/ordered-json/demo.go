@@ -0,0 +1,55 @@
+//go:build !js && !wasip1
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+// what running without a command does: the original experiment, adding a key to package.json
+// and writing it to package-new.json, then running prettier on that
+func runDefault() {
+	file, err := os.Open("package.json")
+
+	if err != nil {
+		panic(fmt.Errorf("could not open file package.json: %v", err))
+	}
+	defer file.Close()
+
+	raw, err := io.ReadAll(file)
+	if err != nil {
+		panic(fmt.Errorf("could not read from file package.json: %v", err))
+	}
+
+	parser := orderedjson.NewParser(raw)
+	result, err := parser.Parse()
+
+	if err != nil {
+		panic(fmt.Errorf("could not parse to btree: %v", err))
+	}
+
+	result.Set("custom_key", "some value")
+
+	data, err := orderedjson.MarshalOptions{}.Marshal(result)
+	if err != nil {
+		panic(fmt.Errorf("could not marshsall btree: %v", err))
+	}
+
+	fmt.Printf("[DEBUG]: marshaled final result to: %s\n", string(data))
+	err = os.WriteFile("package-new.json", []byte(data), 0644)
+	if err != nil {
+		panic(fmt.Errorf("could not write to file: %+v", err))
+	}
+
+	cmd := exec.Command("prettier", "-w", "package-new.json")
+	_, err = cmd.Output()
+
+	if err != nil {
+		panic(fmt.Errorf("could not execute prettier on result: %v", err))
+	}
+}
@@ -0,0 +1,13 @@
+//go:build wasip1
+
+package main
+
+import (
+	"os"
+)
+
+// there's no prettier to run under wasi, so without a command this only says what the commands are
+func runDefault() {
+	printUsage(os.Stderr)
+	os.Exit(exitUsage)
+}
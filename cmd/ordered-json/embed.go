@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+func runEmbed(flags *flag.FlagSet) func(args []string) error {
+	packageName := flags.String("package", "main", "package clause of the generated file")
+	varName := flags.String("var", "Document", "name of the variable holding the tree")
+	lazy := flags.Bool("lazy", false, "embed the document as json and parse it on first use, instead of building the tree at init time")
+	output := flags.String("o", "", "write the generated file here instead of stdout")
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		if len(args) > 1 {
+			return usageError("embed takes one document")
+		}
+
+		path := "-"
+		if len(args) == 1 {
+			path = args[0]
+		}
+
+		tree, err := parseInput(path)
+		if err != nil {
+			return err
+		}
+
+		source, err := orderedjson.GenerateEmbed(*packageName, *varName, tree, *lazy)
+		if err != nil {
+			return err
+		}
+
+		if *output != "" {
+			return withExitCode(exitIO, os.WriteFile(*output, source, 0644))
+		}
+
+		_, err = files.stdout().Write(source)
+		return err
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+// exit codes of the command line tool, so scripts can tell what kind of failure they got
+const (
+	exitOK = 0
+	// anything that doesn't fit one of the others
+	exitFailure = 1
+	// unknown commands, bad flags or missing arguments
+	exitUsage = 2
+	// a document (or a schema) doesn't parse
+	exitSyntax = 3
+	// a document parses, but doesn't match its schema
+	exitSchema = 4
+	// a file couldn't be found, read or written
+	exitIO = 5
+)
+
+// an error that decides the exit code of the command
+type exitError struct {
+	code int
+	err  error
+}
+
+func (err *exitError) Error() string {
+	return err.err.Error()
+}
+
+func (err *exitError) Unwrap() error {
+	return err.err
+}
+
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &exitError{code: code, err: err}
+}
+
+func usageError(format string, args ...interface{}) error {
+	return withExitCode(exitUsage, fmt.Errorf(format, args...))
+}
+
+func exitCode(err error) int {
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+
+	var coded *exitError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+
+	var syntax *syntaxError
+	if errors.As(err, &syntax) {
+		return exitSyntax
+	}
+
+	return exitFailure
+}
+
+// a syntax error in a file, printed as file:line:col like compilers do
+type syntaxError struct {
+	path string
+	err  *orderedjson.SyntaxError
+	// the contents of the file, for showing where the error is
+	source []byte
+}
+
+func (err *syntaxError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", displayName(err.path), err.err.Line, err.err.Column, err.err.Msg)
+}
+
+func (err *syntaxError) Unwrap() error {
+	return err.err
+}
+
+// the error as it's printed, with the lines around a syntax error under it
+func errorText(err error) string {
+	var syntax *syntaxError
+	if !errors.As(err, &syntax) || syntax.source == nil {
+		return err.Error()
+	}
+
+	return err.Error() + "\n" + strings.TrimSuffix(syntax.err.Snippet(syntax.source), "\n")
+}
+
+// parses flags, turning a bad flag into a usage error
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return withExitCode(exitUsage, err)
+	}
+
+	return err
+}
+
+// parses flags that come after positional arguments too, like `bump patch file.json -w`, and
+// returns the positional ones. the flag package stops at the first argument that isn't a flag.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := parseFlags(flags, args); err != nil {
+			return nil, err
+		}
+
+		rest := flags.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+
+		if parsed := len(args) - len(rest); parsed > 0 && args[parsed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+
+		args = rest
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+	"github.com/michaelhelvey/orderedjson/v2/config"
+)
+
+func runExplain(flags *flag.FlagSet) func(args []string) error {
+	envPrefix := flags.String("env-prefix", "", "also apply environment overrides like PREFIX_SERVER__PORT")
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		args, err := parseInterspersed(flags, args)
+		if err != nil {
+			return err
+		}
+
+		if len(args) < 2 {
+			return usageError("explain needs a dotted path and the base file, then any overlays")
+		}
+
+		path := args[0]
+		loaded, err := config.Load(config.Options{Base: args[1], Overlays: args[2:], EnvPrefix: *envPrefix})
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			return withExitCode(exitIO, err)
+		}
+
+		if err != nil {
+			return withExitCode(exitSyntax, err)
+		}
+
+		explained := loaded.Explain(path)
+		if len(explained) == 0 {
+			return fmt.Errorf("there's no %s", path)
+		}
+
+		paths := make([]string, 0, len(explained))
+		for key := range explained {
+			paths = append(paths, key)
+		}
+
+		sort.Strings(paths)
+
+		out := files.stdout()
+		for _, key := range paths {
+			value, err := marshalValue(dottedValue(loaded.Tree, key))
+			if err != nil {
+				return err
+			}
+
+			provenance := explained[key]
+			fmt.Fprintf(out, "%s = %s\n  set by %s\n", key, value, provenance)
+			for _, previous := range provenance.Overrides {
+				fmt.Fprintf(out, "  overrides %s\n", previous)
+			}
+		}
+
+		return nil
+	}
+}
+
+// the value at a dotted path like server.port
+func dottedValue(tree *orderedjson.JsonObject, path string) interface{} {
+	var value interface{} = tree
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(*orderedjson.JsonObject)
+		if !ok {
+			return nil
+		}
+
+		value, _ = object.Get(key)
+	}
+
+	return value
+}
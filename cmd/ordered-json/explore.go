@@ -0,0 +1,573 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+// explore shows a document as an outline that can be folded, searched and edited in the terminal.
+// saving only rewrites the values that were edited (see Document.Patch), so the rest of the file
+// keeps its formatting, and goes through editFlags like the other commands that change files: with
+// --backup the original is kept, and with --dry-run nothing is written and the diff of the last save
+// is printed on the way out.
+
+const exploreHelp = "j/k move  h/l fold  enter toggle  / search  n/N next/previous  y copy path  e edit  s save  q quit"
+
+type exploreMode int
+
+const (
+	exploreBrowse exploreMode = iota
+	exploreSearch
+	exploreEdit
+)
+
+type explorer struct {
+	path  string
+	edits *editFlags
+	// the file as it was last read or saved, and the tree with the edits since then
+	raw      []byte
+	doc      *orderedjson.Document
+	bom      bool
+	tree     *orderedjson.JsonObject
+	modified bool
+	// what --dry-run would have written, as a diff
+	diff bytes.Buffer
+
+	// which containers are unfolded, by their path
+	expanded map[string]bool
+	rows     []exploreRow
+	cursor   int
+	scroll   int
+
+	mode exploreMode
+	// what's being typed after / or e
+	input  []rune
+	search string
+	status string
+	// written with the next frame, to copy text with an OSC 52 escape sequence
+	clipboard string
+	// the next q quits even though there are unsaved changes
+	confirmQuit bool
+}
+
+// one line of the outline
+type exploreRow struct {
+	path  orderedjson.Path
+	value interface{}
+}
+
+func runExplore(flags *flag.FlagSet) func(args []string) error {
+	// saving is what writes, so there's no -w
+	edits := &editFlags{write: true}
+	flags.BoolVar(&edits.dryRun, "dry-run", false, "print a diff of what saving would change when quitting, without changing the file")
+	flags.StringVar(&edits.backup, "backup", "", "keep the original of the file next to it when saving, with this suffix (like .bak)")
+
+	return func(args []string) error {
+		if len(args) != 1 || args[0] == "-" {
+			// stdin is where the keys come from
+			return usageError("usage: ordered-json explore <file>")
+		}
+
+		raw, err := readInput(args[0])
+		if err != nil {
+			return err
+		}
+
+		doc, err := orderedjson.ParseDocument(raw)
+		var syntax *orderedjson.SyntaxError
+		if errors.As(err, &syntax) {
+			return &syntaxError{path: args[0], err: syntax, source: raw}
+		}
+		if err != nil {
+			return withExitCode(exitSyntax, err)
+		}
+
+		exp := newExplorer(args[0], raw, doc, edits)
+		if err := exp.run(); err != nil {
+			return err
+		}
+
+		return printDiff(os.Stdout, exp.diff.String())
+	}
+}
+
+// the terminal loop, until q
+func (exp *explorer) run() error {
+	state, err := makeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return fmt.Errorf("explore needs a terminal: %v", err)
+	}
+	defer state.restore()
+
+	// the alternate screen, without a cursor
+	fmt.Fprint(os.Stdout, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(os.Stdout, "\x1b[?25h\x1b[?1049l")
+
+	buf := make([]byte, 64)
+	for {
+		width, height, err := terminalSize(int(os.Stdout.Fd()))
+		if err != nil || width <= 0 || height <= 0 {
+			width, height = 80, 24
+		}
+
+		exp.render(os.Stdout, width, height)
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+
+		if exp.handleKey(string(buf[:n]), height) {
+			return nil
+		}
+	}
+}
+
+func newExplorer(path string, raw []byte, doc *orderedjson.Document, edits *editFlags) *explorer {
+	exp := &explorer{path: path, edits: edits, raw: raw, doc: doc, bom: bytes.HasPrefix(raw, utf8BOM), tree: doc.Tree, expanded: make(map[string]bool), status: exploreHelp}
+	exp.buildRows()
+	return exp
+}
+
+// the visible rows: the top level values, and the children of every unfolded container
+func (exp *explorer) buildRows() {
+	exp.rows = exp.rows[:0]
+	orderedjson.Accept(exp.tree, orderedjson.VisitorFunc(func(path orderedjson.Path, value interface{}) bool {
+		if len(path) == 0 {
+			return true
+		}
+
+		exp.rows = append(exp.rows, exploreRow{path: path, value: value})
+		return exp.expanded[path.String()]
+	}))
+
+	exp.cursor = max(min(exp.cursor, len(exp.rows)-1), 0)
+}
+
+func isContainer(value interface{}) bool {
+	switch value.(type) {
+	case *orderedjson.JsonObject, []interface{}:
+		return true
+	}
+
+	return false
+}
+
+func (exp *explorer) selected() *exploreRow {
+	if len(exp.rows) == 0 {
+		return nil
+	}
+
+	return &exp.rows[exp.cursor]
+}
+
+// moves the cursor to path, unfolding everything above it
+func (exp *explorer) reveal(path orderedjson.Path) {
+	for i := 1; i < len(path); i++ {
+		exp.expanded[path[:i].String()] = true
+	}
+	exp.buildRows()
+
+	for i, row := range exp.rows {
+		if row.path.Equal(path) {
+			exp.cursor = i
+			return
+		}
+	}
+}
+
+// returns true when it's time to quit. height is the height of the screen, for page up and down.
+func (exp *explorer) handleKey(key string, height int) bool {
+	switch exp.mode {
+	case exploreSearch, exploreEdit:
+		exp.handleInput(key)
+		return false
+	}
+
+	quitting := exp.confirmQuit
+	exp.confirmQuit = false
+	exp.status = ""
+	row := exp.selected()
+
+	switch key {
+	case "q", "\x03":
+		if exp.modified && !quitting {
+			exp.status = "there are unsaved changes, press q again to quit without saving them"
+			exp.confirmQuit = true
+			return false
+		}
+
+		return true
+	case "j", "\x1b[B", "\x0e":
+		exp.cursor = min(exp.cursor+1, len(exp.rows)-1)
+	case "k", "\x1b[A", "\x10":
+		exp.cursor = max(exp.cursor-1, 0)
+	case "\x1b[6~", "\x06":
+		exp.cursor = min(exp.cursor+exp.outlineHeight(height), len(exp.rows)-1)
+	case "\x1b[5~", "\x02":
+		exp.cursor = max(exp.cursor-exp.outlineHeight(height), 0)
+	case "g", "\x1b[H":
+		exp.cursor = 0
+	case "G", "\x1b[F":
+		exp.cursor = max(len(exp.rows)-1, 0)
+	case "l", "\x1b[C":
+		if row == nil || !isContainer(row.value) {
+			break
+		}
+
+		if exp.expanded[row.path.String()] {
+			exp.cursor = min(exp.cursor+1, len(exp.rows)-1)
+		} else {
+			exp.expanded[row.path.String()] = true
+			exp.buildRows()
+		}
+	case "h", "\x1b[D":
+		if row == nil {
+			break
+		}
+
+		if isContainer(row.value) && exp.expanded[row.path.String()] {
+			delete(exp.expanded, row.path.String())
+			exp.buildRows()
+		} else if len(row.path) > 1 {
+			exp.reveal(row.path[:len(row.path)-1])
+		}
+	case "\r", "\n", " ":
+		if row != nil && isContainer(row.value) {
+			name := row.path.String()
+			exp.expanded[name] = !exp.expanded[name]
+			exp.buildRows()
+		}
+	case "/":
+		exp.mode = exploreSearch
+		exp.input = nil
+	case "n":
+		exp.findNext(1)
+	case "N":
+		exp.findNext(-1)
+	case "y":
+		if row != nil {
+			exp.clipboard = row.path.String()
+			exp.status = "copied " + row.path.String()
+		}
+	case "e":
+		if row != nil {
+			text, _ := marshalValue(row.value)
+			exp.mode = exploreEdit
+			exp.input = []rune(text)
+		}
+	case "s":
+		if err := exp.save(); err != nil {
+			exp.status = "could not save: " + err.Error()
+		} else if exp.edits.dryRun {
+			exp.status = "not saved (--dry-run), the diff is printed when you quit"
+		} else {
+			exp.status = "saved " + exp.path
+		}
+	case "?":
+		exp.status = exploreHelp
+	}
+
+	return false
+}
+
+// typing into the search or edit line
+func (exp *explorer) handleInput(key string) {
+	switch key {
+	case "\x1b", "\x03":
+		exp.mode = exploreBrowse
+		exp.status = ""
+	case "\x7f", "\b":
+		if len(exp.input) > 0 {
+			exp.input = exp.input[:len(exp.input)-1]
+		}
+	case "\r", "\n":
+		mode := exp.mode
+		exp.mode = exploreBrowse
+		if mode == exploreSearch {
+			exp.search = string(exp.input)
+			exp.findNext(1)
+		} else {
+			exp.applyEdit(string(exp.input))
+		}
+	default:
+		if strings.HasPrefix(key, "\x1b") {
+			// arrows and such, there's no cursor to move
+			return
+		}
+
+		for _, char := range key {
+			if char >= ' ' {
+				exp.input = append(exp.input, char)
+			}
+		}
+	}
+}
+
+// jumps to the next (or previous) key or value containing the search text, in document order,
+// starting from the selected row and wrapping around
+func (exp *explorer) findNext(direction int) {
+	if exp.search == "" {
+		return
+	}
+
+	var paths []orderedjson.Path
+	orderedjson.Accept(exp.tree, orderedjson.VisitorFunc(func(path orderedjson.Path, value interface{}) bool {
+		if len(path) > 0 {
+			paths = append(paths, path)
+		}
+
+		return true
+	}))
+
+	needle := strings.ToLower(exp.search)
+	matches := func(path orderedjson.Path) bool {
+		value := valueAt(exp.tree, path)
+		text := fmt.Sprint(path[len(path)-1])
+		if !isContainer(value) {
+			marshaled, _ := marshalValue(value)
+			text += "\n" + marshaled
+		}
+
+		return strings.Contains(strings.ToLower(text), needle)
+	}
+
+	current := -1
+	if row := exp.selected(); row != nil {
+		for i, path := range paths {
+			if path.Equal(row.path) {
+				current = i
+				break
+			}
+		}
+	}
+
+	if direction < 0 && current < 0 {
+		current = 0
+	}
+
+	count := 0
+	for _, path := range paths {
+		if matches(path) {
+			count++
+		}
+	}
+
+	if count == 0 {
+		exp.status = fmt.Sprintf("no match for %q", exp.search)
+		return
+	}
+
+	for step := 1; step <= len(paths); step++ {
+		i := ((current+direction*step)%len(paths) + len(paths)) % len(paths)
+		if matches(paths[i]) {
+			exp.reveal(paths[i])
+			exp.status = fmt.Sprintf("%s (%d matches)", paths[i], count)
+			return
+		}
+	}
+}
+
+func valueAt(value interface{}, path orderedjson.Path) interface{} {
+	for _, element := range path {
+		switch v := value.(type) {
+		case *orderedjson.JsonObject:
+			value, _ = v.Get(element.(string))
+		case []interface{}:
+			value = v[element.(int)]
+		}
+	}
+
+	return value
+}
+
+// the edit line is json, so strings need their quotes
+func (exp *explorer) applyEdit(text string) {
+	row := exp.selected()
+	if row == nil {
+		return
+	}
+
+	value, err := orderedjson.FromStdJSONValue([]byte(text))
+	if err != nil {
+		exp.status = fmt.Sprintf("not a json value (strings need quotes): %v", err)
+		return
+	}
+
+	exp.tree = orderedjson.ReplaceAt(exp.tree, row.path, value)
+	exp.modified = true
+	exp.status = "changed " + row.path.String() + ", press s to save"
+	exp.buildRows()
+}
+
+func (exp *explorer) save() error {
+	data, err := exp.doc.Patch(exp.tree)
+	if err != nil {
+		return err
+	}
+
+	// what's saved is what the next save has to patch
+	doc, err := orderedjson.ParseDocument(data)
+	if err != nil {
+		return err
+	}
+
+	if exp.bom {
+		data = append(append([]byte{}, utf8BOM...), data...)
+	}
+
+	// a dry run leaves the file as it is, so the diff is always from the file to the latest save
+	exp.diff.Reset()
+	if err := exp.edits.apply(exp.path, exp.raw, data, &exp.diff); err != nil {
+		return err
+	}
+
+	exp.modified = false
+	if !exp.edits.dryRun {
+		exp.raw, exp.doc = data, doc
+	}
+
+	return nil
+}
+
+func (exp *explorer) previewHeight(height int) int {
+	return min(max(height/3, 3), 12)
+}
+
+func (exp *explorer) outlineHeight(height int) int {
+	// a title, the preview with its separator, and the status line
+	return max(height-exp.previewHeight(height)-3, 1)
+}
+
+func (exp *explorer) render(w io.Writer, width, height int) {
+	var screen strings.Builder
+	screen.WriteString("\x1b[H\x1b[2J")
+	if exp.clipboard != "" {
+		screen.WriteString("\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(exp.clipboard)) + "\x07")
+		exp.clipboard = ""
+	}
+
+	line := func(text string, reverse bool) {
+		text = truncate(text, width)
+		if reverse {
+			text = "\x1b[7m" + text + strings.Repeat(" ", width-utf8.RuneCountInString(text)) + "\x1b[0m"
+		}
+
+		screen.WriteString(text + "\x1b[K\r\n")
+	}
+
+	title := exp.path
+	if exp.modified {
+		title += " [modified]"
+	}
+	line(title, true)
+
+	outline := exp.outlineHeight(height)
+	if exp.cursor < exp.scroll {
+		exp.scroll = exp.cursor
+	} else if exp.cursor >= exp.scroll+outline {
+		exp.scroll = exp.cursor - outline + 1
+	}
+
+	for i := exp.scroll; i < exp.scroll+outline; i++ {
+		if i >= len(exp.rows) {
+			line("", false)
+			continue
+		}
+
+		line(exp.describe(exp.rows[i]), i == exp.cursor)
+	}
+
+	preview := []string{}
+	if row := exp.selected(); row != nil {
+		line(row.path.String(), true)
+		text, err := orderedjson.MarshalOptions{Indent: "  "}.Marshal(row.value)
+		if err != nil {
+			text = err.Error()
+		}
+		preview = strings.Split(text, "\n")
+	} else {
+		line("", true)
+	}
+
+	for i := 0; i < exp.previewHeight(height); i++ {
+		switch {
+		case i == exp.previewHeight(height)-1 && len(preview) > i+1:
+			line(fmt.Sprintf("... %d more lines", len(preview)-i), false)
+		case i < len(preview):
+			line(preview[i], false)
+		default:
+			line("", false)
+		}
+	}
+
+	switch exp.mode {
+	case exploreSearch:
+		screen.WriteString(truncate("/"+string(exp.input), width) + "\x1b[K")
+	case exploreEdit:
+		screen.WriteString(truncate("edit: "+string(exp.input), width) + "\x1b[K")
+	default:
+		screen.WriteString(truncate(exp.status, width) + "\x1b[K")
+	}
+
+	io.WriteString(w, screen.String())
+}
+
+// a row as "key: value", with containers folded to a summary
+func (exp *explorer) describe(row exploreRow) string {
+	indent := strings.Repeat("  ", len(row.path)-1)
+	var label string
+	switch key := row.path[len(row.path)-1].(type) {
+	case int:
+		label = fmt.Sprintf("[%d]", key)
+	case string:
+		label, _ = marshalValue(key)
+	}
+
+	switch v := row.value.(type) {
+	case *orderedjson.JsonObject:
+		marker := "▸ "
+		if exp.expanded[row.path.String()] {
+			marker = "▾ "
+		}
+
+		return fmt.Sprintf("%s%s%s: {%d keys}", indent, marker, label, v.Len())
+	case []interface{}:
+		marker := "▸ "
+		if exp.expanded[row.path.String()] {
+			marker = "▾ "
+		}
+
+		return fmt.Sprintf("%s%s%s: [%d items]", indent, marker, label, len(v))
+	}
+
+	text, err := marshalValue(row.value)
+	if err != nil {
+		text = err.Error()
+	}
+
+	return fmt.Sprintf("%s  %s: %s", indent, label, text)
+}
+
+// cuts text down to width characters
+func truncate(text string, width int) string {
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+
+	runes := []rune(text)
+	if width < 1 {
+		return ""
+	}
+
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+// opens path in an explorer, and changes the first value to 2
+func editFirst(t *testing.T, path string, edits *editFlags) *explorer {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := orderedjson.ParseDocument(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := newExplorer(path, raw, doc, edits)
+	exp.applyEdit("2")
+	if !exp.modified {
+		t.Fatalf("applyEdit: %s", exp.status)
+	}
+
+	return exp
+}
+
+func TestExploreSave(t *testing.T) {
+	original := "{\n    \"a\": 1,  \"b\": [true]\n}\n"
+	dir := writeFiles(t, map[string]string{"a.json": original})
+	path := filepath.Join(dir, "a.json")
+	if err := os.Chmod(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := editFirst(t, path, &editFlags{write: true, backup: ".bak"})
+	if err := exp.save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	// only the edited value changes, and the file keeps its mode
+	want := "{\n    \"a\": 2,  \"b\": [true]\n}\n"
+	if data, _ := os.ReadFile(path); string(data) != want || exp.modified {
+		t.Errorf("saved %q, modified %v, want %q", data, exp.modified, want)
+	}
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+
+	if data, _ := os.ReadFile(path + ".bak"); string(data) != original {
+		t.Errorf("backup = %q, want %q", data, original)
+	}
+
+	// saving again has the saved file to patch, and doesn't write when nothing changed
+	os.Remove(path + ".bak")
+	if err := exp.save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+		t.Errorf("saving without changes made a backup: %v", err)
+	}
+}
+
+func TestExploreDryRun(t *testing.T) {
+	original := "\ufeff{\"a\": 1}"
+	dir := writeFiles(t, map[string]string{"a.json": original})
+	path := filepath.Join(dir, "a.json")
+
+	exp := editFirst(t, path, &editFlags{write: true, dryRun: true})
+	for i := 0; i < 2; i++ {
+		if err := exp.save(); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Errorf("dry run wrote %q", data)
+	}
+
+	// the diff of the last save, from the file as it is
+	diff := exp.diff.String()
+	if !strings.HasPrefix(diff, "--- ") || strings.Count(diff, "+\ufeff{\"a\": 2}") != 1 {
+		t.Errorf("diff = %q", diff)
+	}
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// every command that reads documents takes any number of files, globs (including ** for any number
+// of directories) and directories, which are searched recursively for files with the extensions the
+// command reads (.json unless it converts from another format).
+
+type fileFlags struct {
+	// the file extensions to look for in directories
+	extensions []string
+	exclude    []string
+	jobs       int
+	// quiet drops everything but errors, verbose adds how long each file took
+	quiet, verbose bool
+}
+
+func addFileFlags(flags *flag.FlagSet, extensions ...string) *fileFlags {
+	if len(extensions) == 0 {
+		extensions = []string{".json"}
+	}
+
+	files := &fileFlags{extensions: extensions}
+	flags.Func("exclude", "skip files matching this glob (can be repeated)", func(pattern string) error {
+		files.exclude = append(files.exclude, pattern)
+		return nil
+	})
+	flags.IntVar(&files.jobs, "jobs", runtime.NumCPU(), "how many files to process at the same time")
+	flags.BoolVar(&files.quiet, "quiet", false, "only print errors, the exit code tells how it went")
+	flags.BoolVar(&files.verbose, "verbose", false, "print how long every file took to stderr")
+	return files
+}
+
+// where the command's normal output goes
+func (files *fileFlags) stdout() io.Writer {
+	if files.quiet {
+		return io.Discard
+	}
+
+	if report != nil {
+		return report
+	}
+
+	return os.Stdout
+}
+
+// expandPaths turns the file arguments into a sorted list of files. "-" (stdin) is passed through,
+// and no arguments at all means stdin.
+func (files *fileFlags) expandPaths(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return []string{"-"}, nil
+	}
+
+	excludes := make([]*regexp.Regexp, 0, len(files.exclude))
+	for _, pattern := range files.exclude {
+		excludes = append(excludes, globRegexp(pattern))
+	}
+
+	excluded := func(path string) bool {
+		slashed := filepath.ToSlash(path)
+		for _, re := range excludes {
+			if re.MatchString(slashed) || re.MatchString(filepath.Base(path)) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	paths := make([]string, 0, len(args))
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if !seen[path] && !excluded(path) {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	for _, arg := range args {
+		if arg == "-" {
+			add(arg)
+			continue
+		}
+
+		if strings.ContainsAny(arg, "*?[") {
+			matches, err := glob(arg)
+			if err != nil {
+				return nil, withExitCode(exitIO, err)
+			}
+
+			if len(matches) == 0 {
+				return nil, withExitCode(exitIO, fmt.Errorf("no files match %s", arg))
+			}
+
+			for _, match := range matches {
+				add(match)
+			}
+
+			continue
+		}
+
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, withExitCode(exitIO, err)
+		}
+
+		if !info.IsDir() {
+			add(arg)
+			continue
+		}
+
+		var found []string
+		err = filepath.WalkDir(arg, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if entry.IsDir() && path != arg && excluded(path) {
+				return filepath.SkipDir
+			}
+
+			if !entry.IsDir() && files.hasExtension(path) && !excluded(path) {
+				found = append(found, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, withExitCode(exitIO, err)
+		}
+
+		sort.Strings(found)
+		for _, path := range found {
+			add(path)
+		}
+	}
+
+	return paths, nil
+}
+
+func (files *fileFlags) hasExtension(path string) bool {
+	for _, extension := range files.extensions {
+		if strings.EqualFold(filepath.Ext(path), extension) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// like filepath.Glob, but ** matches any number of directories
+func glob(pattern string) ([]string, error) {
+	// walk from the longest directory prefix without wildcards
+	root := "."
+	parts := strings.Split(filepath.ToSlash(pattern), "/")
+	for i, part := range parts {
+		if strings.ContainsAny(part, "*?[") {
+			if i > 0 {
+				root = strings.Join(parts[:i], "/")
+				if root == "" {
+					root = "/"
+				}
+			}
+
+			break
+		}
+	}
+
+	re := globRegexp(pattern)
+	var matches []string
+	err := filepath.WalkDir(filepath.FromSlash(root), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() && re.MatchString(strings.TrimPrefix(filepath.ToSlash(path), "./")) {
+			matches = append(matches, path)
+		}
+
+		return nil
+	})
+
+	sort.Strings(matches)
+	return matches, err
+}
+
+func globRegexp(pattern string) *regexp.Regexp {
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+
+	var result strings.Builder
+	result.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch char := pattern[i]; char {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				result.WriteString("(.*/)?")
+				i += 2
+			} else if strings.HasPrefix(pattern[i:], "**") {
+				result.WriteString(".*")
+				i++
+			} else {
+				result.WriteString("[^/]*")
+			}
+		case '?':
+			result.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				result.WriteString(regexp.QuoteMeta(string(char)))
+				continue
+			}
+
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			result.WriteString("[" + class + "]")
+			i += end
+		default:
+			result.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	result.WriteString("$")
+
+	re, err := regexp.Compile(result.String())
+	if err != nil {
+		// a broken character class, match it literally instead
+		return regexp.MustCompile("^" + regexp.QuoteMeta(pattern) + "$")
+	}
+
+	return re
+}
+
+// writes data to a temporary file next to path and renames it over path, so the file is never half
+// written. the file keeps its permissions.
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := os.Chmod(file.Name(), mode); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return nil
+}
+
+// commands that change files in place get -w, --dry-run and --backup from here, so they all behave
+// the same and write files the same way
+type editFlags struct {
+	write, dryRun bool
+	// the suffix of backups, none when empty
+	backup string
+}
+
+func addEditFlags(flags *flag.FlagSet, usage string) *editFlags {
+	edits := &editFlags{}
+	flags.BoolVar(&edits.write, "w", false, usage)
+	flags.BoolVar(&edits.dryRun, "dry-run", false, "print a diff of what -w would change, without changing anything")
+	flags.StringVar(&edits.backup, "backup", "", "with -w, keep the original of every file that changes next to it, with this suffix (like .bak)")
+	return edits
+}
+
+func (edits *editFlags) check() error {
+	if edits.backup != "" && !edits.write {
+		return usageError("--backup only goes with -w")
+	}
+
+	return nil
+}
+
+// whether the file at path is edited (or would be, with --dry-run) instead of printed. stdin never
+// is.
+func (edits *editFlags) enabled(path string) bool {
+	return (edits.write || edits.dryRun) && path != "-"
+}
+
+// replaces old, the contents of the file at path, with new. with --dry-run it prints the diff to out
+// instead, and with --backup it keeps old in a file of its own first. files that stay the same
+// aren't touched.
+func (edits *editFlags) apply(path string, old, new []byte, out io.Writer) error {
+	if bytes.Equal(old, new) {
+		return nil
+	}
+
+	if edits.dryRun {
+		return printDiff(out, unifiedDiff(displayName(path), old, new))
+	}
+
+	if edits.backup != "" {
+		if err := writeFileAtomic(path+edits.backup, old); err != nil {
+			return withExitCode(exitIO, err)
+		}
+	}
+
+	return withExitCode(exitIO, writeFileAtomic(path, new))
+}
+
+// runs fn for every file, up to jobs at a time. the output of each file is written to stdout in the
+// order of paths, no matter which one finishes first. a file that fails doesn't stop the others: its
+// error is printed to stderr and the whole run fails at the end, with the exit code of the failures
+// when they all failed the same way.
+func (files *fileFlags) run(paths []string, fn func(path string, out io.Writer) error) error {
+	jobs := files.jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	type result struct {
+		output  commandOutput
+		err     error
+		elapsed time.Duration
+		done    chan struct{}
+	}
+
+	results := make([]*result, len(paths))
+	for i := range results {
+		results[i] = &result{done: make(chan struct{})}
+	}
+
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, jobs)
+	for i, path := range paths {
+		wg.Add(1)
+		go func(path string, result *result) {
+			defer wg.Done()
+			defer close(result.done)
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			start := time.Now()
+			result.err = fn(path, &result.output)
+			result.elapsed = time.Since(start)
+		}(path, results[i])
+	}
+
+	failed := 0
+	code := exitOK
+	for i, result := range results {
+		<-result.done
+		if report != nil {
+			report.addFile(paths[i], &result.output, result.err)
+		} else {
+			files.stdout().Write(result.output.all.Bytes())
+		}
+
+		if files.verbose {
+			status := "ok"
+			if result.err != nil {
+				status = "failed"
+			}
+
+			fmt.Fprintf(os.Stderr, "%s: %s in %v\n", displayName(paths[i]), status, result.elapsed.Round(time.Microsecond))
+		}
+
+		if result.err == nil {
+			continue
+		}
+
+		failed++
+		if code == exitOK {
+			code = exitCode(result.err)
+		} else if code != exitCode(result.err) {
+			code = exitFailure
+		}
+
+		if len(paths) > 1 && report == nil {
+			var syntax *syntaxError
+			if errors.As(result.err, &syntax) {
+				// already starts with the file name
+				fmt.Fprintln(os.Stderr, errorText(result.err))
+			} else {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", displayName(paths[i]), result.err)
+			}
+		}
+	}
+	wg.Wait()
+
+	switch {
+	case failed == 0:
+		return nil
+	case len(paths) == 1:
+		return results[0].err
+	}
+
+	return withExitCode(code, fmt.Errorf("%d of %d files failed", failed, len(paths)))
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// runs the command line args like main does, and returns what it printed
+func runCLI(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	out, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	stdout := os.Stdout
+	os.Stdout = out
+	err = runCommand(args)
+	os.Stdout = stdout
+
+	if _, seekErr := out.Seek(0, io.SeekStart); seekErr != nil {
+		t.Fatal(seekErr)
+	}
+
+	printed, readErr := io.ReadAll(out)
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+
+	return string(printed), err
+}
+
+// writes the files into a new directory and returns it
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(contents)
+}
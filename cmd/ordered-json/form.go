@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+func runToForm(flags *flag.FlagSet) func(args []string) error {
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			tree, err := parseInput(path)
+			if err != nil {
+				return err
+			}
+
+			query, err := orderedjson.ToForm(tree)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(out, query)
+			return nil
+		})
+	}
+}
+
+func runFromForm(flags *flag.FlagSet) func(args []string) error {
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			raw, err := readInput(path)
+			if err != nil {
+				return err
+			}
+
+			// a whole url works too, for pasting one from a log
+			query := strings.TrimSpace(string(raw))
+			if _, after, ok := strings.Cut(query, "?"); ok {
+				query = after
+			}
+			query, _, _ = strings.Cut(query, "#")
+
+			tree, err := orderedjson.FromForm(query)
+			if err != nil {
+				return withExitCode(exitSyntax, fmt.Errorf("%s: %w", displayName(path), err))
+			}
+
+			data, err := marshalValue(tree)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(out, data)
+			return nil
+		})
+	}
+}
@@ -0,0 +1,459 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+func runFmt(flags *flag.FlagSet) func(args []string) error {
+	edits := addEditFlags(flags, "write the result back to the files instead of stdout")
+	check := flags.Bool("check", false, "only list the files that aren't formatted, and fail if there are any")
+	diff := flags.Bool("diff", false, "print a unified diff of what formatting would change")
+	indent := flags.String("indent", "  ", "indentation per level, instead of the one from .editorconfig or .orderedjsonrc")
+	maxWidth := flags.Int("max-width", 0, "keep objects and arrays that fit in this many characters on one line")
+	standard := flags.Bool("standard", false, "use the standard profile, which takes no settings and gives the same output everywhere")
+	fix := flags.Bool("fix", false, "fix single quotes, unquoted keys, = instead of :, python's True, False and None, and numbers like 012 or .5")
+	profile := flags.String("profile", "", "also sort and dedupe the dependencies of manifests: package-json, composer, tsconfig, or auto to go by the file name")
+	jsonc := flags.Bool("jsonc", false, "allow comments and trailing commas, like in tsconfig.json, and keep the comments (always on for .jsonc files)")
+	color := addColorFlag(flags)
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		if err := edits.check(); err != nil {
+			return err
+		}
+
+		if _, ok := orderedjson.LookupManifestProfile(*profile); !ok && *profile != "" && *profile != "auto" {
+			return usageError("unknown profile %q, expected package-json, composer, tsconfig or auto", *profile)
+		}
+
+		set := make(map[string]bool)
+		flags.Visit(func(f *flag.Flag) {
+			set[f.Name] = true
+		})
+
+		// --check and --diff only report, they'd quietly leave the files alone otherwise
+		for _, name := range []string{"check", "diff"} {
+			if set[name] && edits.write {
+				return usageError("--%s can't be combined with -w", name)
+			}
+
+			if set[name] && edits.dryRun {
+				return usageError("--%s can't be combined with --dry-run", name)
+			}
+		}
+
+		for _, name := range []string{"indent", "max-width"} {
+			if *standard && set[name] {
+				return usageError("--standard can't be combined with --%s", name)
+			}
+		}
+
+		// the standard profile doesn't look at config files
+		var loader *formatConfigLoader
+		if !*standard {
+			loader = newFormatConfigLoader()
+		}
+
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		var unformatted atomic.Int32
+		err = files.run(paths, func(path string, out io.Writer) error {
+			opts := orderedjson.FormatOptions{}
+			if loader != nil {
+				configured, err := loader.optionsFor(path, orderedjson.FormatOptions{Indent: *indent, MaxWidth: *maxWidth})
+				if err != nil {
+					return err
+				}
+
+				opts = configured
+			}
+
+			if set["indent"] {
+				opts.Indent = *indent
+			}
+
+			if set["max-width"] {
+				opts.MaxWidth = *maxWidth
+			}
+
+			raw, err := readInput(path)
+			if err != nil {
+				return err
+			}
+
+			var tree *orderedjson.JsonObject
+			var doc *orderedjson.Document
+			if *jsonc || strings.HasSuffix(path, ".jsonc") {
+				doc, err = orderedjson.ParseOptions{Comments: true, Numbers: orderedjson.NumberJSONNumber}.ParseDocument(raw)
+				var syntax *orderedjson.SyntaxError
+				if errors.As(err, &syntax) {
+					return &syntaxError{path: path, err: syntax, source: raw}
+				}
+
+				if err != nil {
+					return withExitCode(exitSyntax, err)
+				}
+
+				tree = doc.Tree
+				opts.Comments = true
+				opts.Meta = doc.Meta
+			} else if tree, err = parseSource(path, raw, orderedjson.ParseOptions{Lenient: *fix, Numbers: orderedjson.NumberJSONNumber}); err != nil {
+				return err
+			}
+
+			manifest, ok := orderedjson.LookupManifestProfile(*profile)
+			if *profile == "auto" {
+				manifest, ok = orderedjson.ManifestProfileFor(path)
+			}
+
+			if ok {
+				if doc == nil {
+					if doc, err = (orderedjson.ParseOptions{Lenient: *fix, Numbers: orderedjson.NumberJSONNumber}).ParseDocument(raw); err != nil {
+						return err
+					}
+				}
+
+				for _, problem := range orderedjson.TidyManifest(doc, manifest) {
+					fmt.Fprintf(os.Stderr, "%s: %s\n", displayName(path), problem)
+				}
+
+				tree = doc.Tree
+			}
+
+			formatted, err := opts.FormatTree(tree)
+			if err != nil {
+				return err
+			}
+
+			changed := !bytes.Equal(raw, formatted)
+			if changed {
+				unformatted.Add(1)
+			}
+			reportChanged(out, changed)
+
+			switch {
+			case *check || *diff:
+				if changed && *check {
+					fmt.Fprintln(out, displayName(path))
+				}
+
+				if changed && *diff {
+					if err := printDiff(out, unifiedDiff(displayName(path), raw, formatted)); err != nil {
+						return err
+					}
+				}
+			case edits.enabled(path):
+				return edits.apply(path, raw, formatted, out)
+			case color.enabled():
+				_, err = io.WriteString(out, colorize(string(formatted)))
+				return err
+			default:
+				_, err = out.Write(formatted)
+				return err
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if *check && unformatted.Load() > 0 {
+			return fmt.Errorf("%d of %d files are not formatted", unformatted.Load(), len(paths))
+		}
+
+		return nil
+	}
+}
+
+func displayName(path string) string {
+	if path == "" || path == "-" {
+		return "<stdin>"
+	}
+
+	return path
+}
+
+// a unified diff between two versions of a file, with three lines of context around each change
+func unifiedDiff(name string, old, new []byte) string {
+	a := splitLines(old)
+	b := splitLines(new)
+
+	type line struct {
+		op   byte
+		text string
+		// line numbers in a and b, 1 based
+		oldLine, newLine int
+	}
+
+	// the lines between two unchanged ones were removed or added, the end is where the last ones are
+	var lines []line
+	i, j := 0, 0
+	for _, match := range append(commonLines(a, b), [2]int{len(a), len(b)}) {
+		for ; i < match[0]; i++ {
+			lines = append(lines, line{'-', a[i], i + 1, j + 1})
+		}
+
+		for ; j < match[1]; j++ {
+			lines = append(lines, line{'+', b[j], i + 1, j + 1})
+		}
+
+		if i < len(a) {
+			lines = append(lines, line{' ', a[i], i + 1, j + 1})
+			i++
+			j++
+		}
+	}
+
+	const context = 3
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("--- %s\n+++ %s (formatted)\n", name, name))
+
+	for start := 0; start < len(lines); {
+		if lines[start].op == ' ' {
+			start++
+			continue
+		}
+
+		// a hunk runs until there are more than 2*context unchanged lines in a row
+		end := start
+		for k := start; k < len(lines); k++ {
+			if lines[k].op != ' ' {
+				end = k + 1
+			} else if k-end >= 2*context {
+				break
+			}
+		}
+
+		from := max(start-context, 0)
+		to := min(end+context, len(lines))
+
+		oldCount, newCount := 0, 0
+		for _, l := range lines[from:to] {
+			if l.op != '+' {
+				oldCount++
+			}
+
+			if l.op != '-' {
+				newCount++
+			}
+		}
+
+		oldStart, newStart := lines[from].oldLine, lines[from].newLine
+		if oldCount == 0 {
+			oldStart--
+		}
+
+		if newCount == 0 {
+			newStart--
+		}
+
+		result.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount))
+		for _, l := range lines[from:to] {
+			result.WriteByte(l.op)
+			result.WriteString(l.text)
+			result.WriteByte('\n')
+		}
+
+		start = to
+	}
+
+	return result.String()
+}
+
+func splitLines(data []byte) []string {
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(text, "\n")
+}
+
+// the lines that stay the same between a and b, as pairs of their indexes, in order. it's Myers'
+// diff in linear space (the way diff-match-patch bisects), so big files with lots of changes take
+// memory for the lines and not for every pair of them. lines that are only on one side can't stay
+// the same and are left out first, which makes reindenting a file cheap to diff.
+func commonLines(a, b []string) [][2]int {
+	ids := make(map[string]int)
+	id := func(line string) int {
+		if n, ok := ids[line]; ok {
+			return n
+		}
+
+		ids[line] = len(ids)
+		return len(ids) - 1
+	}
+
+	aIDs := make([]int, len(a))
+	for i, line := range a {
+		aIDs[i] = id(line)
+	}
+
+	inB := make(map[int]bool)
+	bIDs := make([]int, len(b))
+	for i, line := range b {
+		bIDs[i] = id(line)
+		inB[bIDs[i]] = true
+	}
+
+	inA := make(map[int]bool)
+	for _, n := range aIDs {
+		inA[n] = true
+	}
+
+	matcher := &lineMatcher{}
+	for i, n := range aIDs {
+		if inB[n] {
+			matcher.a = append(matcher.a, n)
+			matcher.aLines = append(matcher.aLines, i)
+		}
+	}
+
+	for i, n := range bIDs {
+		if inA[n] {
+			matcher.b = append(matcher.b, n)
+			matcher.bLines = append(matcher.bLines, i)
+		}
+	}
+
+	matcher.match(0, len(matcher.a), 0, len(matcher.b))
+	return matcher.matches
+}
+
+type lineMatcher struct {
+	// the ids of the lines that are on both sides, and where they are in a and b
+	a, b           []int
+	aLines, bLines []int
+	matches        [][2]int
+}
+
+// matches a[aLo:aHi] against b[bLo:bHi]: the lines they start and end with, and the rest split at
+// the middle of a shortest edit script
+func (matcher *lineMatcher) match(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && matcher.a[aLo] == matcher.b[bLo] {
+		matcher.add(aLo, bLo)
+		aLo++
+		bLo++
+	}
+
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && matcher.a[aHi-1-suffix] == matcher.b[bHi-1-suffix] {
+		suffix++
+	}
+
+	if aLo < aHi-suffix && bLo < bHi-suffix {
+		x, y := matcher.bisect(aLo, aHi-suffix, bLo, bHi-suffix)
+		// a split at either end would never finish, the lines have nothing in common then
+		if (x != aLo || y != bLo) && (x != aHi-suffix || y != bHi-suffix) && x >= 0 {
+			matcher.match(aLo, x, bLo, y)
+			matcher.match(x, aHi-suffix, y, bHi-suffix)
+		}
+	}
+
+	for k := suffix; k > 0; k-- {
+		matcher.add(aHi-k, bHi-k)
+	}
+}
+
+func (matcher *lineMatcher) add(i, j int) {
+	matcher.matches = append(matcher.matches, [2]int{matcher.aLines[i], matcher.bLines[j]})
+}
+
+// where the forward and backward searches for a shortest edit script of a[aLo:aHi] and b[bLo:bHi]
+// meet, or -1, -1 when they don't. forward[k] is how far into a the furthest path on diagonal k
+// (x - y = k) gets, and backward[k] the same from the ends.
+func (matcher *lineMatcher) bisect(aLo, aHi, bLo, bHi int) (int, int) {
+	a, b := matcher.a[aLo:aHi], matcher.b[bLo:bHi]
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	size := 2*maxD + 2
+	forward, backward := make([]int, size), make([]int, size)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	delta := n - m
+	// with an odd delta the forward paths are the ones that run into the backward ones
+	front := delta%2 != 0
+	// the diagonals that have gone off the edges don't have to be looked at again
+	forwardStart, forwardEnd, backwardStart, backwardEnd := 0, 0, 0, 0
+	for d := 0; d < maxD; d++ {
+		for k := -d + forwardStart; k <= d-forwardEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -d || k != d && forward[i-1] < forward[i+1] {
+				x = forward[i+1]
+			} else {
+				x = forward[i-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[i] = x
+
+			switch {
+			case x > n:
+				forwardEnd += 2
+			case y > m:
+				forwardStart += 2
+			case front:
+				j := offset + delta - k
+				if j >= 0 && j < size && backward[j] != -1 && x >= n-backward[j] {
+					return aLo + x, bLo + y
+				}
+			}
+		}
+
+		for k := -d + backwardStart; k <= d-backwardEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -d || k != d && backward[i-1] < backward[i+1] {
+				x = backward[i+1]
+			} else {
+				x = backward[i-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			backward[i] = x
+
+			switch {
+			case x > n:
+				backwardEnd += 2
+			case y > m:
+				backwardStart += 2
+			case !front:
+				j := offset + delta - k
+				if j >= 0 && j < size && forward[j] != -1 {
+					forwardX := forward[j]
+					if forwardX >= n-x {
+						return aLo + forwardX, bLo + forwardX - (j - offset)
+					}
+				}
+			}
+		}
+	}
+
+	return -1, -1
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		old, new, want string
+	}{
+		{"a\nb\nc\n", "a\nb\nc\n", "--- f\n+++ f (formatted)\n"},
+		{"a\nb\nc\n", "a\nx\nc\n", "--- f\n+++ f (formatted)\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n"},
+		{"", "a\n", "--- f\n+++ f (formatted)\n@@ -0,0 +1,1 @@\n+a\n"},
+		{"a\n", "", "--- f\n+++ f (formatted)\n@@ -1,1 +0,0 @@\n-a\n"},
+		{
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			"0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			"--- f\n+++ f (formatted)\n@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n@@ -7,4 +8,3 @@\n 7\n 8\n 9\n-10\n",
+		},
+		{
+			`{"a": 1, "b": [2]}` + "\n",
+			"{\n  \"a\": 1,\n  \"b\": [\n    2\n  ]\n}\n",
+			"--- f\n+++ f (formatted)\n@@ -1,1 +1,6 @@\n-{\"a\": 1, \"b\": [2]}\n+{\n+  \"a\": 1,\n+  \"b\": [\n+    2\n+  ]\n+}\n",
+		},
+	}
+
+	for _, test := range tests {
+		if got := unifiedDiff("f", []byte(test.old), []byte(test.new)); got != test.want {
+			t.Errorf("unifiedDiff(%q, %q) =\n%s\nwant\n%s", test.old, test.new, got, test.want)
+		}
+	}
+}
+
+// commonLines has to find a longest common subsequence, like the quadratic table does
+func TestCommonLines(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for n := 0; n < 2000; n++ {
+		a := make([]string, random.Intn(12))
+		b := make([]string, random.Intn(12))
+		for i := range a {
+			a[i] = string(rune('a' + random.Intn(4)))
+		}
+		for i := range b {
+			b[i] = string(rune('a' + random.Intn(4)))
+		}
+
+		matches := commonLines(a, b)
+		previous := [2]int{-1, -1}
+		for _, match := range matches {
+			if match[0] <= previous[0] || match[1] <= previous[1] || a[match[0]] != b[match[1]] {
+				t.Fatalf("commonLines(%q, %q) = %v, which isn't a common subsequence", a, b, matches)
+			}
+			previous = match
+		}
+
+		if want := lcsLength(a, b); len(matches) != want {
+			t.Fatalf("commonLines(%q, %q) = %v, %d lines, want %d", a, b, matches, len(matches), want)
+		}
+	}
+}
+
+func lcsLength(a, b []string) int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	return lengths[0][0]
+}
+
+// a table for every pair of lines of these would take gigabytes
+func TestUnifiedDiffBigFile(t *testing.T) {
+	var old, new strings.Builder
+	for i := 0; i < 30000; i++ {
+		fmt.Fprintf(&old, "  \"key%d\": %d,\n", i, i)
+		fmt.Fprintf(&new, "    \"key%d\": %d,\n", i, i)
+		if i%7 == 0 {
+			fmt.Fprintf(&new, "  \"key%d\": %d,\n", i, i)
+		}
+	}
+
+	diff := unifiedDiff("f", []byte(old.String()), []byte(new.String()))
+	if got := strings.Count(diff, "\n-"); got != 30000-30000/7-1 {
+		t.Errorf("%d lines removed, want %d", got, 30000-30000/7-1)
+	}
+}
+
+func TestFmtFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		// the exit code, and what the file has afterwards
+		code int
+		want string
+	}{
+		{[]string{"-w"}, exitOK, "{\n  \"a\": 1\n}\n"},
+		{[]string{"--check"}, exitFailure, `{"a":1}`},
+		{[]string{"--diff"}, exitOK, `{"a":1}`},
+		{[]string{"--dry-run"}, exitOK, `{"a":1}`},
+		{[]string{"--check", "-w"}, exitUsage, `{"a":1}`},
+		{[]string{"-w", "--diff"}, exitUsage, `{"a":1}`},
+		{[]string{"--check", "--dry-run"}, exitUsage, `{"a":1}`},
+	}
+
+	for _, test := range tests {
+		dir := writeFiles(t, map[string]string{"a.json": `{"a":1}`})
+		path := filepath.Join(dir, "a.json")
+		_, err := runCLI(t, append(append([]string{"fmt"}, test.args...), path)...)
+		if got := exitCode(err); got != test.code {
+			t.Errorf("fmt %s: exit code %d (%v), want %d", strings.Join(test.args, " "), got, err, test.code)
+		}
+
+		if got := readFile(t, path); got != test.want {
+			t.Errorf("fmt %s left %q, want %q", strings.Join(test.args, " "), got, test.want)
+		}
+	}
+}
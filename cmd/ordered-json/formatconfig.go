@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+// fmt picks up formatter settings from the directories above each file, so a monorepo can mix
+// conventions. two kinds of files are read:
+//
+//   - .editorconfig (https://editorconfig.org): indent_style, indent_size, tab_width,
+//     insert_final_newline and max_line_length from the sections matching the file. the search
+//     stops at a file with root = true.
+//   - .orderedjsonrc: a json object with the same keys, applying to every file below it, e.g.
+//     {"indent_style": "space", "indent_size": 4}
+//
+// settings from a closer directory win, and within one directory .orderedjsonrc wins over
+// .editorconfig.
+
+type formatSettings map[string]string
+
+// reads and caches config files, fmt formats files in parallel
+type formatConfigLoader struct {
+	mu    sync.Mutex
+	files map[string]*configFile
+}
+
+type configFile struct {
+	// for .orderedjsonrc, which has no sections
+	settings formatSettings
+	root     bool
+	sections []editorconfigSection
+}
+
+type editorconfigSection struct {
+	patterns []*regexp.Regexp
+	settings formatSettings
+}
+
+func newFormatConfigLoader() *formatConfigLoader {
+	return &formatConfigLoader{files: make(map[string]*configFile)}
+}
+
+// the options for formatting the file at path, starting from base
+func (loader *formatConfigLoader) optionsFor(path string, base orderedjson.FormatOptions) (orderedjson.FormatOptions, error) {
+	if path == "" || path == "-" {
+		return base, nil
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return base, err
+	}
+
+	// walk up, so settings that are already set came from a closer directory
+	settings := formatSettings{}
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		rc, err := loader.load(filepath.Join(dir, ".orderedjsonrc"), parseOrderedJSONRC)
+		if err != nil {
+			return base, err
+		}
+
+		if rc != nil {
+			settings.addMissing(rc.settings)
+		}
+
+		editorconfig, err := loader.load(filepath.Join(dir, ".editorconfig"), parseEditorconfig)
+		if err != nil {
+			return base, err
+		}
+
+		if editorconfig != nil {
+			relative := filepath.ToSlash(strings.TrimPrefix(abs, dir))
+			relative = strings.TrimPrefix(relative, "/")
+
+			// later sections in a file win
+			for i := len(editorconfig.sections) - 1; i >= 0; i-- {
+				section := editorconfig.sections[i]
+				for _, re := range section.patterns {
+					if re.MatchString(relative) {
+						settings.addMissing(section.settings)
+						break
+					}
+				}
+			}
+
+			if editorconfig.root {
+				break
+			}
+		}
+
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	return settings.apply(base, path)
+}
+
+func (loader *formatConfigLoader) load(path string, parse func(data []byte) (*configFile, error)) (*configFile, error) {
+	loader.mu.Lock()
+	defer loader.mu.Unlock()
+
+	if file, ok := loader.files[path]; ok {
+		return file, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		loader.files[path] = nil
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, withExitCode(exitIO, err)
+	}
+
+	file, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	loader.files[path] = file
+	return file, nil
+}
+
+func (settings formatSettings) addMissing(other formatSettings) {
+	for key, value := range other {
+		if _, ok := settings[key]; !ok {
+			settings[key] = value
+		}
+	}
+}
+
+func (settings formatSettings) apply(opts orderedjson.FormatOptions, path string) (orderedjson.FormatOptions, error) {
+	style := settings["indent_style"]
+	size := settings["indent_size"]
+	if size == "tab" {
+		size = settings["tab_width"]
+	}
+
+	switch style {
+	case "tab":
+		opts.Indent = "\t"
+	case "space":
+		opts.Indent = "  "
+	case "", "unset":
+	default:
+		return opts, fmt.Errorf("%s: unsupported indent_style %q", path, style)
+	}
+
+	if style != "tab" && size != "" && size != "unset" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("%s: invalid indent_size %q", path, size)
+		}
+
+		opts.Indent = strings.Repeat(" ", n)
+	}
+
+	switch width := settings["max_line_length"]; width {
+	case "", "unset":
+	case "off":
+		opts.MaxWidth = 0
+	default:
+		n, err := strconv.Atoi(width)
+		if err != nil || n < 0 {
+			return opts, fmt.Errorf("%s: invalid max_line_length %q", path, width)
+		}
+
+		opts.MaxWidth = n
+	}
+
+	switch settings["insert_final_newline"] {
+	case "true":
+		opts.NoFinalNewline = false
+	case "false":
+		opts.NoFinalNewline = true
+	}
+
+	return opts, nil
+}
+
+func parseOrderedJSONRC(data []byte) (*configFile, error) {
+	tree, err := orderedjson.FromStdJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := formatSettings{}
+	for pair := tree.Oldest(); pair != nil; pair = pair.Next() {
+		switch value := pair.Value.(type) {
+		case string:
+			settings[pair.Key] = strings.ToLower(value)
+		case float64, bool:
+			settings[pair.Key] = fmt.Sprint(value)
+		default:
+			return nil, fmt.Errorf("unsupported value for %s", pair.Key)
+		}
+	}
+
+	return &configFile{settings: settings}, nil
+}
+
+func parseEditorconfig(data []byte) (*configFile, error) {
+	file := &configFile{}
+	var section *editorconfigSection
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			file.sections = append(file.sections, editorconfigSection{
+				patterns: editorconfigPatterns(line[1 : len(line)-1]),
+				settings: formatSettings{},
+			})
+			section = &file.sections[len(file.sections)-1]
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.ToLower(strings.TrimSpace(value))
+		if section == nil {
+			// the preamble only has root
+			if key == "root" {
+				file.root = value == "true"
+			}
+
+			continue
+		}
+
+		section.settings[key] = value
+	}
+
+	return file, scanner.Err()
+}
+
+// a section name is a glob with {a,b} alternatives. without a slash it matches the file name in any
+// directory, otherwise the path relative to the .editorconfig.
+func editorconfigPatterns(glob string) []*regexp.Regexp {
+	var patterns []*regexp.Regexp
+	for _, pattern := range expandBraces(glob) {
+		if strings.Contains(pattern, "/") {
+			pattern = strings.TrimPrefix(pattern, "/")
+		} else {
+			pattern = "**/" + pattern
+		}
+
+		patterns = append(patterns, globRegexp(pattern))
+	}
+
+	return patterns
+}
+
+// expands the first {a,b,c} in pattern, and then the ones in what that gave
+func expandBraces(pattern string) []string {
+	start := strings.IndexByte(pattern, '{')
+	if start < 0 {
+		return []string{pattern}
+	}
+
+	depth := 0
+	end := -1
+	alternatives := []string{}
+	last := start + 1
+	for i := start; i < len(pattern) && end < 0; i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				alternatives = append(alternatives, pattern[last:i])
+				end = i
+			}
+		case ',':
+			if depth == 1 {
+				alternatives = append(alternatives, pattern[last:i])
+				last = i + 1
+			}
+		}
+	}
+
+	if end < 0 || len(alternatives) < 2 {
+		// not a set of alternatives, match the braces literally
+		return []string{pattern}
+	}
+
+	var result []string
+	for _, alternative := range alternatives {
+		result = append(result, expandBraces(pattern[:start]+alternative+pattern[end+1:])...)
+	}
+
+	return result
+}
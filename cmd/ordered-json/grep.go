@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"sync/atomic"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+// grep finds keys and values in documents, by what they are instead of by their text: `grep
+// '"react"'` finds the key react and the string "react" wherever they are, but not a description
+// that happens to mention react. every match is printed as file:path:line, with the line it's on.
+
+func runGrep(flags *flag.FlagSet) func(args []string) error {
+	useRegex := flags.Bool("regex", false, "the pattern is a regular expression for keys and values, which are matched by their json text when they aren't strings")
+	typeName := flags.String("type", "", "only find values of this type: object, array, string, number, boolean or null")
+	pathPattern := flags.String("path", "", "only look at these paths: keys with dots between them, where * is any key or index, like dependencies.*")
+	keysOnly := flags.Bool("keys", false, "only find keys")
+	valuesOnly := flags.Bool("values", false, "only find values")
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		args, err := parseInterspersed(flags, args)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return usageError("grep needs a pattern, or '' to match anything and go by --type or --path alone")
+		}
+
+		switch *typeName {
+		case "", "object", "array", "string", "number", "boolean", "null":
+		default:
+			return usageError("unknown type %q, expected object, array, string, number, boolean or null", *typeName)
+		}
+
+		if *keysOnly && *valuesOnly {
+			return usageError("--keys and --values can't be used together")
+		}
+
+		grepper := &grepper{path: *pathPattern, typeName: *typeName, keys: !*valuesOnly && *typeName == "", values: !*keysOnly}
+		switch pattern := args[0]; {
+		case pattern == "":
+			grepper.match = func(value interface{}) bool { return true }
+		case *useRegex:
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return usageError("bad pattern: %v", err)
+			}
+
+			grepper.match = func(value interface{}) bool {
+				switch v := value.(type) {
+				case string:
+					return re.MatchString(v)
+				case *orderedjson.JsonObject, []interface{}:
+					return false
+				}
+
+				text, _ := marshalValue(value)
+				return re.MatchString(text)
+			}
+		default:
+			// json, or a string without its quotes
+			var literal interface{} = pattern
+			if value, err := orderedjson.FromStdJSONValue([]byte(pattern)); err == nil {
+				literal = value
+			}
+
+			grepper.match = func(value interface{}) bool {
+				return orderedjson.Equal(value, literal)
+			}
+		}
+
+		paths, err := files.expandPaths(args[1:])
+		if err != nil {
+			return err
+		}
+
+		var found atomic.Int64
+		err = files.run(paths, func(path string, out io.Writer) error {
+			raw, err := readInput(path)
+			if err != nil {
+				return err
+			}
+
+			doc, err := parseDocumentSource(path, raw)
+			if err != nil {
+				return err
+			}
+
+			matches, err := grepper.find(doc)
+			if err != nil {
+				return err
+			}
+
+			for _, match := range matches {
+				found.Add(1)
+				line := bytes.Count(doc.Text[:match.offset], []byte("\n")) + 1
+				fmt.Fprintf(out, "%s:%s:%d: %s\n", displayName(path), match.path, line, lineAt(doc.Text, match.offset))
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if found.Load() == 0 {
+			return fmt.Errorf("no matches in %d files", len(paths))
+		}
+
+		return nil
+	}
+}
+
+type grepper struct {
+	match    func(value interface{}) bool
+	path     string
+	typeName string
+	// whether to look at keys, values, or both
+	keys, values bool
+}
+
+type grepMatch struct {
+	path orderedjson.Path
+	// where the key or value starts in the text
+	offset int
+}
+
+// the keys and values in doc that match, in document order (a key before its value)
+func (grepper *grepper) find(doc *orderedjson.Document) ([]grepMatch, error) {
+	var matches []grepMatch
+	err := orderedjson.Accept(doc.Tree, orderedjson.VisitorFunc(func(path orderedjson.Path, value interface{}) bool {
+		if grepper.path != "" && !path.Matches(grepper.path) {
+			return true
+		}
+
+		span, _ := doc.Span(path)
+		if len(path) > 0 && grepper.keys {
+			if key, ok := path[len(path)-1].(string); ok && grepper.match(key) {
+				matches = append(matches, grepMatch{path: path, offset: span.KeyStart})
+			}
+		}
+
+		if grepper.values && (grepper.typeName == "" || orderedjson.TypeName(value) == grepper.typeName) && grepper.match(value) {
+			matches = append(matches, grepMatch{path: path, offset: span.ValueStart})
+		}
+
+		return true
+	}))
+
+	return matches, err
+}
+
+// the line offset is on, without the indentation
+func lineAt(text []byte, offset int) []byte {
+	start := bytes.LastIndexByte(text[:offset], '\n') + 1
+	end := bytes.IndexByte(text[offset:], '\n')
+	if end < 0 {
+		end = len(text) - offset
+	}
+
+	return bytes.TrimSpace(text[start : offset+end])
+}
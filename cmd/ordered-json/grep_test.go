@@ -0,0 +1,42 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGrepCommand(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.json": "{\n  \"dependencies\": {\"react\": \"^18\"},\n  \"files\": [\"react\", 1],\n  \"description\": \"a react thing\"\n}\n"})
+	path := filepath.Join(dir, "a.json")
+
+	tests := []struct {
+		args []string
+		// the paths of the matches
+		want []string
+	}{
+		{[]string{"react"}, []string{".dependencies.react", ".files[0]"}},
+		{[]string{"--keys", "react"}, []string{".dependencies.react"}},
+		{[]string{"--values", "react"}, []string{".files[0]"}},
+		{[]string{"--type", "number", ""}, []string{".files[1]"}},
+		{[]string{"--path", "files.*", ""}, []string{".files[0]", ".files[1]"}},
+		{[]string{"--regex", "^a "}, []string{".description"}},
+	}
+
+	for _, test := range tests {
+		out, err := runCLI(t, append(append([]string{"grep"}, test.args...), path)...)
+		if err != nil {
+			t.Errorf("grep %v: %v", test.args, err)
+			continue
+		}
+
+		var got []string
+		for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+			got = append(got, strings.Split(strings.TrimPrefix(line, path+":"), ":")[0])
+		}
+
+		if strings.Join(got, " ") != strings.Join(test.want, " ") {
+			t.Errorf("grep %v found %q, want %q", test.args, got, test.want)
+		}
+	}
+}
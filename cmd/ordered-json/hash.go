@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+var hashAlgorithms = map[string]crypto.Hash{
+	"sha224":     crypto.SHA224,
+	"sha256":     crypto.SHA256,
+	"sha384":     crypto.SHA384,
+	"sha512":     crypto.SHA512,
+	"sha512/256": crypto.SHA512_256,
+}
+
+func runHash(flags *flag.FlagSet) func(args []string) error {
+	algo := flags.String("algo", "sha256", "the hash function: sha224, sha256, sha384, sha512 or sha512/256")
+	etag := flags.Bool("etag", false, "print the hash as a quoted ETag header value")
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		hash, ok := hashAlgorithms[strings.ToLower(*algo)]
+		if !ok {
+			return usageError("unknown hash function %q", *algo)
+		}
+
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			tree, err := parseInput(path)
+			if err != nil {
+				return err
+			}
+
+			sum, err := orderedjson.Hash(tree, hash)
+			if err != nil {
+				return err
+			}
+
+			if *etag {
+				fmt.Fprintf(out, "%q\n", hex.EncodeToString(sum))
+				return nil
+			}
+
+			// the same layout as sha256sum
+			fmt.Fprintf(out, "%s  %s\n", hex.EncodeToString(sum), displayName(path))
+			return nil
+		})
+	}
+}
@@ -0,0 +1,167 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"syscall/js"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// built for js, the program doesn't run commands, it puts the parser and the marshaler on
+// globalThis.orderedJSON for js/orderedjson.js (which is what to use from js) and waits:
+//
+//	orderedJSON.parse(text, {comments, maxDepth})        -> {value} or {error, line, column}
+//	orderedJSON.stringify(value, {indent, maxWidth, ...}) -> {value} or {error}
+//
+// objects come out of parse as Maps, because plain js objects put keys like "2" before the others
+// whatever order they were added in. stringify takes Maps and plain objects both.
+func runDefault() {
+	js.Global().Set("orderedJSON", js.ValueOf(map[string]interface{}{
+		"parse":     js.FuncOf(jsParse),
+		"stringify": js.FuncOf(jsStringify),
+	}))
+
+	select {}
+}
+
+func jsParse(this js.Value, args []js.Value) interface{} {
+	if len(args) == 0 || args[0].Type() != js.TypeString {
+		return jsError(errors.New("parse needs the text to parse"))
+	}
+
+	var options []orderedjson.DecodeOption
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		if comments := args[1].Get("comments"); comments.Truthy() {
+			options = append(options, orderedjson.WithComments())
+		}
+		if depth := args[1].Get("maxDepth"); depth.Type() == js.TypeNumber {
+			options = append(options, orderedjson.WithMaxDepth(depth.Int()))
+		}
+	}
+
+	tree, err := orderedjson.Parse([]byte(args[0].String()), options...)
+	if err != nil {
+		return jsError(err)
+	}
+
+	return js.ValueOf(map[string]interface{}{"value": toJSValue(tree)})
+}
+
+func jsStringify(this js.Value, args []js.Value) interface{} {
+	if len(args) == 0 {
+		return jsError(errors.New("stringify needs a value"))
+	}
+
+	value, err := fromJSValue(args[0])
+	if err != nil {
+		return jsError(err)
+	}
+
+	opts := orderedjson.MarshalOptions{}
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		if indent := args[1].Get("indent"); indent.Type() == js.TypeString {
+			opts.Indent = indent.String()
+		} else if indent.Type() == js.TypeNumber {
+			opts.Indent = fmt.Sprintf("%*s", indent.Int(), "")
+		}
+		if width := args[1].Get("maxWidth"); width.Type() == js.TypeNumber {
+			opts.MaxWidth = width.Int()
+		}
+		opts.Compact = args[1].Get("compact").Truthy()
+		if args[1].Get("sortKeys").Truthy() {
+			opts.SortKeys = orderedjson.LexicalOrder
+		}
+	}
+
+	text, err := opts.Marshal(value)
+	if err != nil {
+		return jsError(err)
+	}
+
+	return js.ValueOf(map[string]interface{}{"value": text})
+}
+
+// {error}, with the line and column for syntax errors
+func jsError(err error) js.Value {
+	result := map[string]interface{}{"error": err.Error()}
+	var syntax *orderedjson.SyntaxError
+	if errors.As(err, &syntax) {
+		result["error"] = syntax.Msg
+		result["line"] = syntax.Line
+		result["column"] = syntax.Column
+	}
+
+	return js.ValueOf(result)
+}
+
+func toJSValue(value interface{}) js.Value {
+	switch v := value.(type) {
+	case *orderedjson.JsonObject:
+		object := js.Global().Get("Map").New()
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			object.Call("set", pair.Key, toJSValue(pair.Value))
+		}
+		return object
+	case []interface{}:
+		array := js.Global().Get("Array").New(len(v))
+		for i, item := range v {
+			array.SetIndex(i, toJSValue(item))
+		}
+		return array
+	}
+
+	return js.ValueOf(value)
+}
+
+func fromJSValue(value js.Value) (interface{}, error) {
+	switch value.Type() {
+	case js.TypeNull, js.TypeUndefined:
+		return nil, nil
+	case js.TypeBoolean:
+		return value.Bool(), nil
+	case js.TypeNumber:
+		return value.Float(), nil
+	case js.TypeString:
+		return value.String(), nil
+	case js.TypeObject:
+		if js.Global().Get("Array").Call("isArray", value).Bool() {
+			array := make([]interface{}, value.Length())
+			for i := range array {
+				item, err := fromJSValue(value.Index(i))
+				if err != nil {
+					return nil, err
+				}
+				array[i] = item
+			}
+			return array, nil
+		}
+
+		// a Map's entries, or a plain object's
+		entries := value
+		if !value.InstanceOf(js.Global().Get("Map")) {
+			entries = js.Global().Get("Object").Call("entries", value)
+		}
+
+		pairs := js.Global().Get("Array").Call("from", entries)
+		object := orderedmap.New[string, interface{}]()
+		for i := 0; i < pairs.Length(); i++ {
+			key := pairs.Index(i).Index(0)
+			if key.Type() != js.TypeString {
+				return nil, fmt.Errorf("object keys have to be strings, got %s", key.Type())
+			}
+
+			item, err := fromJSValue(pairs.Index(i).Index(1))
+			if err != nil {
+				return nil, err
+			}
+			object.Set(key.String(), item)
+		}
+		return object, nil
+	}
+
+	return nil, fmt.Errorf("%s can't be written as json", value.Type())
+}
@@ -0,0 +1,712 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+// a minimal language server over stdio: diagnostics (syntax errors, and schema errors when the
+// document has a "$schema" pointing at a local file), completion of keys and values from that
+// schema, formatting, and document symbols in key order. documents are synced incrementally and
+// reparsed with Document.Edit.
+
+type lspServer struct {
+	in    *bufio.Reader
+	out   io.Writer
+	files map[string]*lspFile
+	// the schema of each document from the last time it parsed, so completion still works while
+	// the document is half written
+	schemas map[string]*orderedjson.JsonObject
+}
+
+type lspFile struct {
+	text []byte
+	// nil while the text doesn't parse
+	doc *orderedjson.Document
+	err error
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspSymbol struct {
+	Name           string      `json:"name"`
+	Detail         string      `json:"detail,omitempty"`
+	Kind           int         `json:"kind"`
+	Range          lspRange    `json:"range"`
+	SelectionRange lspRange    `json:"selectionRange"`
+	Children       []lspSymbol `json:"children,omitempty"`
+}
+
+type lspCompletionItem struct {
+	Label         string `json:"label"`
+	Kind          int    `json:"kind"`
+	Detail        string `json:"detail,omitempty"`
+	Documentation string `json:"documentation,omitempty"`
+	InsertText    string `json:"insertText"`
+	// clients sort by this, and the schema's order is the one to keep
+	SortText string `json:"sortText"`
+}
+
+type lspRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type lspTextDocument struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+const (
+	lspSeverityError   = 1
+	lspSeverityWarning = 2
+)
+
+// completion item kinds from the LSP spec
+const (
+	lspCompletionValue    = 12
+	lspCompletionProperty = 10
+)
+
+func runLSP(*flag.FlagSet) func(args []string) error {
+	return func(args []string) error {
+		return newLSPServer(os.Stdin, os.Stdout).serve()
+	}
+}
+
+func newLSPServer(in io.Reader, out io.Writer) *lspServer {
+	return &lspServer{
+		in:      bufio.NewReader(in),
+		out:     out,
+		files:   make(map[string]*lspFile),
+		schemas: make(map[string]*orderedjson.JsonObject),
+	}
+}
+
+func (server *lspServer) serve() error {
+	for {
+		request, err := server.read()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if request.Method == "exit" {
+			return nil
+		}
+
+		result, err := server.handle(request)
+		if len(request.ID) == 0 {
+			// a notification, there's nobody to answer
+			continue
+		}
+
+		response := map[string]interface{}{"jsonrpc": "2.0", "id": request.ID}
+		if err != nil {
+			response["error"] = map[string]interface{}{"code": -32603, "message": err.Error()}
+		} else {
+			response["result"] = result
+		}
+
+		if err := server.write(response); err != nil {
+			return err
+		}
+	}
+}
+
+func (server *lspServer) read() (*lspRequest, error) {
+	length := -1
+	for {
+		line, err := server.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		name, value, _ := strings.Cut(line, ":")
+		if strings.EqualFold(name, "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("invalid Content-Length header %q", value)
+			}
+		}
+	}
+
+	if length < 0 {
+		return nil, fmt.Errorf("message without a Content-Length header")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(server.in, body); err != nil {
+		return nil, err
+	}
+
+	var request lspRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+func (server *lspServer) write(message interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(server.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (server *lspServer) notify(method string, params interface{}) error {
+	return server.write(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (server *lspServer) handle(request *lspRequest) (interface{}, error) {
+	switch request.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				// 2 is incremental sync
+				"textDocumentSync":           map[string]interface{}{"openClose": true, "change": 2},
+				"documentFormattingProvider": true,
+				"documentSymbolProvider":     true,
+				"completionProvider":         map[string]interface{}{"triggerCharacters": []string{`"`, ":"}},
+			},
+			"serverInfo": map[string]interface{}{"name": "ordered-json"},
+		}, nil
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, err
+		}
+
+		server.files[params.TextDocument.URI] = newLSPFile([]byte(params.TextDocument.Text))
+		return nil, server.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument   lspTextDocument `json:"textDocument"`
+			ContentChanges []struct {
+				Range *lspRange `json:"range"`
+				Text  string    `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, err
+		}
+
+		file, ok := server.files[params.TextDocument.URI]
+		if !ok {
+			return nil, nil
+		}
+
+		for _, change := range params.ContentChanges {
+			if change.Range == nil {
+				file = newLSPFile([]byte(change.Text))
+				continue
+			}
+
+			start := positionToOffset(file.text, change.Range.Start)
+			end := positionToOffset(file.text, change.Range.End)
+			file = file.edit(start, end-start, []byte(change.Text))
+		}
+
+		server.files[params.TextDocument.URI] = file
+		return nil, server.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, err
+		}
+
+		delete(server.files, params.TextDocument.URI)
+		delete(server.schemas, params.TextDocument.URI)
+		return nil, server.notify("textDocument/publishDiagnostics", map[string]interface{}{
+			"uri":         params.TextDocument.URI,
+			"diagnostics": []lspDiagnostic{},
+		})
+	case "textDocument/formatting":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+			Options      struct {
+				TabSize      int  `json:"tabSize"`
+				InsertSpaces bool `json:"insertSpaces"`
+			} `json:"options"`
+		}
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, err
+		}
+
+		file, ok := server.files[params.TextDocument.URI]
+		if !ok || file.doc == nil {
+			return nil, nil
+		}
+
+		indent := "\t"
+		if params.Options.InsertSpaces {
+			indent = strings.Repeat(" ", params.Options.TabSize)
+		}
+
+		formatted, err := orderedjson.MarshalOptions{Indent: indent}.Marshal(file.doc.Tree)
+		if err != nil {
+			return nil, err
+		}
+
+		return []lspTextEdit{{
+			Range:   lspRange{End: offsetToPosition(file.text, len(file.text))},
+			NewText: formatted + "\n",
+		}}, nil
+	case "textDocument/documentSymbol":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, err
+		}
+
+		file, ok := server.files[params.TextDocument.URI]
+		if !ok || file.doc == nil {
+			return []lspSymbol{}, nil
+		}
+
+		return file.symbols(orderedjson.Path{}, file.doc.Tree), nil
+	case "textDocument/completion":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+			Position     lspPosition     `json:"position"`
+		}
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, err
+		}
+
+		file, ok := server.files[params.TextDocument.URI]
+		schema := server.schemas[params.TextDocument.URI]
+		if !ok || schema == nil {
+			return []lspCompletionItem{}, nil
+		}
+
+		return file.completions(schema, positionToOffset(file.text, params.Position)), nil
+	}
+
+	if len(request.ID) > 0 && !strings.HasPrefix(request.Method, "$/") {
+		return nil, fmt.Errorf("method %s is not supported", request.Method)
+	}
+
+	return nil, nil
+}
+
+func newLSPFile(text []byte) *lspFile {
+	doc, err := orderedjson.ParseDocument(text)
+	return &lspFile{text: text, doc: doc, err: err}
+}
+
+func (file *lspFile) edit(offset, length int, replacement []byte) *lspFile {
+	if file.doc == nil {
+		text := append(append(append([]byte{}, file.text[:offset]...), replacement...), file.text[offset+length:]...)
+		return newLSPFile(text)
+	}
+
+	doc, err := file.doc.Edit(offset, length, replacement)
+	if err != nil {
+		text := append(append(append([]byte{}, file.text[:offset]...), replacement...), file.text[offset+length:]...)
+		return &lspFile{text: text, err: err}
+	}
+
+	return &lspFile{text: doc.Text, doc: doc}
+}
+
+func (server *lspServer) publishDiagnostics(uri string) error {
+	file := server.files[uri]
+	diagnostics := []lspDiagnostic{}
+
+	if file.err != nil {
+		offset := len(file.text)
+		var syntaxErr *orderedjson.SyntaxError
+		if errors.As(file.err, &syntaxErr) {
+			offset = syntaxErr.Offset
+		}
+
+		position := offsetToPosition(file.text, offset)
+		diagnostics = append(diagnostics, lspDiagnostic{
+			Range:    lspRange{Start: position, End: position},
+			Severity: lspSeverityError,
+			Source:   "ordered-json",
+			Message:  file.err.Error(),
+		})
+	} else if schemaRef, ok := file.doc.Tree.Get("$schema"); ok {
+		schema, err := loadLSPSchema(uri, schemaRef)
+		server.schemas[uri] = schema
+		diagnostics = append(diagnostics, file.schemaDiagnostics(schema, err)...)
+	} else {
+		delete(server.schemas, uri)
+	}
+
+	return server.notify("textDocument/publishDiagnostics", map[string]interface{}{
+		"uri":         uri,
+		"diagnostics": diagnostics,
+	})
+}
+
+// the schema a document's "$schema" points at. it's nil without an error for schemas that aren't
+// local files, since a language server shouldn't go and fetch things.
+func loadLSPSchema(uri string, schemaRef interface{}) (*orderedjson.JsonObject, error) {
+	location, ok := schemaRef.(string)
+	if !ok || strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return nil, nil
+	}
+
+	schemaPath := strings.TrimPrefix(location, "file://")
+	if !filepath.IsAbs(schemaPath) {
+		if documentURL, err := url.Parse(uri); err == nil && documentURL.Scheme == "file" {
+			schemaPath = filepath.Join(filepath.Dir(documentURL.Path), schemaPath)
+		}
+	}
+
+	raw, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return orderedjson.FromStdJSON(raw)
+}
+
+func (file *lspFile) schemaDiagnostics(schema *orderedjson.JsonObject, err error) []lspDiagnostic {
+	if err != nil {
+		return []lspDiagnostic{{
+			Range:    file.rangeOf(orderedjson.Path{"$schema"}),
+			Severity: lspSeverityWarning,
+			Source:   "ordered-json",
+			Message:  fmt.Sprintf("could not load schema: %v", err),
+		}}
+	}
+
+	diagnostics := []lspDiagnostic{}
+	if schema == nil {
+		return diagnostics
+	}
+
+	for _, schemaErr := range orderedjson.ValidateSchema(schema, file.doc.Tree) {
+		diagnostics = append(diagnostics, lspDiagnostic{
+			Range:    file.rangeOf(schemaErr.Path),
+			Severity: lspSeverityError,
+			Source:   "ordered-json",
+			Message:  schemaErr.Error(),
+		})
+	}
+
+	return diagnostics
+}
+
+func (file *lspFile) rangeOf(path orderedjson.Path) lspRange {
+	span, ok := file.doc.Span(path)
+	if !ok {
+		return lspRange{}
+	}
+
+	return lspRange{Start: offsetToPosition(file.text, span.ValueStart), End: offsetToPosition(file.text, span.ValueEnd)}
+}
+
+// symbol kinds from the LSP spec
+func lspSymbolKind(value interface{}) int {
+	switch value.(type) {
+	case *orderedjson.JsonObject:
+		return 19
+	case []interface{}:
+		return 18
+	case string:
+		return 15
+	case float64:
+		return 16
+	case bool:
+		return 17
+	}
+
+	return 21
+}
+
+func (file *lspFile) symbols(path orderedjson.Path, value interface{}) []lspSymbol {
+	symbols := []lspSymbol{}
+	add := func(name string, child orderedjson.Path, childValue interface{}) {
+		symbolRange := file.rangeOf(child)
+		symbol := lspSymbol{
+			Name:           name,
+			Detail:         orderedjson.TypeName(childValue),
+			Kind:           lspSymbolKind(childValue),
+			Range:          symbolRange,
+			SelectionRange: symbolRange,
+		}
+
+		switch childValue.(type) {
+		case *orderedjson.JsonObject, []interface{}:
+			symbol.Children = file.symbols(child, childValue)
+		}
+
+		symbols = append(symbols, symbol)
+	}
+
+	switch v := value.(type) {
+	case *orderedjson.JsonObject:
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			add(pair.Key, path.Child(pair.Key), pair.Value)
+		}
+	case []interface{}:
+		for i, item := range v {
+			add(strconv.Itoa(i), path.Child(i), item)
+		}
+	}
+
+	return symbols
+}
+
+// what schema allows at offset: keys where a key goes, values after a colon or in an array
+func (file *lspFile) completions(schema *orderedjson.JsonObject, offset int) []lspCompletionItem {
+	items := []lspCompletionItem{}
+	cursor, ok := cursorAt(file.text, offset)
+	if !ok {
+		return items
+	}
+
+	// the document usually doesn't parse while it's being typed, then keys that are there already
+	// aren't known
+	var tree *orderedjson.JsonObject
+	if file.doc != nil {
+		tree = file.doc.Tree
+	}
+
+	completions := orderedjson.CompleteSchema(schema, tree, cursor.path)
+	if cursor.key {
+		for _, completion := range completions.Keys {
+			if cursor.present[completion.Key] {
+				continue
+			}
+
+			item := lspCompletionItem{
+				Label:         completion.Key,
+				Kind:          lspCompletionProperty,
+				Documentation: completion.Description,
+				InsertText:    cursor.insertText(completion.Key),
+				SortText:      fmt.Sprintf("%04d", len(items)),
+			}
+			if completion.Required {
+				item.Detail = "required"
+			}
+
+			items = append(items, item)
+		}
+
+		return items
+	}
+
+	for _, completion := range completions.Values {
+		text, err := orderedjson.MarshalCompact(completion.Value)
+		if err != nil {
+			continue
+		}
+
+		insert := text
+		if value, ok := completion.Value.(string); ok {
+			insert = cursor.insertText(value)
+		}
+
+		items = append(items, lspCompletionItem{
+			Label:         text,
+			Kind:          lspCompletionValue,
+			Documentation: completion.Description,
+			InsertText:    insert,
+			SortText:      fmt.Sprintf("%04d", len(items)),
+		})
+	}
+
+	return items
+}
+
+// where the cursor is in a document, which doesn't have to parse
+type lspCursor struct {
+	// of the key being written, or of the value
+	path orderedjson.Path
+	// whether a key goes there, rather than a value
+	key bool
+	// whether the cursor is inside a string that's been opened already
+	quoted bool
+	// the keys before the cursor in the object it's in, for a key
+	present map[string]bool
+}
+
+// strings are inserted without their quotes when the cursor is after one already
+func (cursor lspCursor) insertText(s string) string {
+	quoted, _ := orderedjson.MarshalCompact(s)
+	if cursor.quoted {
+		return quoted[1 : len(quoted)-1]
+	}
+
+	return quoted
+}
+
+// goes through text up to offset, keeping track of the objects and arrays it's in and the key or
+// index it's at in each. ok is false outside of the top level object.
+func cursorAt(text []byte, offset int) (lspCursor, bool) {
+	type container struct {
+		object bool
+		// the key or index of the value being written
+		key   string
+		index int
+		// whether the key is done and the value comes next
+		colon bool
+		// the keys so far, for objects
+		keys map[string]bool
+	}
+
+	var cursor lspCursor
+	var stack []*container
+	for i := 0; i < offset && i < len(text); i++ {
+		var top *container
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		switch text[i] {
+		case '"':
+			end := i + 1
+			for end < offset && end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+
+			if end >= offset || end >= len(text) {
+				cursor.quoted = true
+				i = offset
+				break
+			}
+
+			if top != nil && top.object && !top.colon {
+				json.Unmarshal(text[i:end+1], &top.key)
+				top.keys[top.key] = true
+			}
+			i = end
+		case '{':
+			stack = append(stack, &container{object: true, keys: make(map[string]bool)})
+		case '[':
+			stack = append(stack, &container{})
+		case '}', ']':
+			if top != nil {
+				stack = stack[:len(stack)-1]
+			}
+		case ':':
+			if top != nil {
+				top.colon = true
+			}
+		case ',':
+			if top != nil && top.object {
+				*top = container{object: true, keys: top.keys}
+			} else if top != nil {
+				top.index++
+			}
+		}
+	}
+
+	if len(stack) == 0 {
+		return cursor, false
+	}
+
+	cursor.path = orderedjson.Path{}
+	for i, current := range stack {
+		last := i == len(stack)-1
+		if last && current.object && !current.colon {
+			cursor.key = true
+			cursor.present = current.keys
+			break
+		}
+
+		if current.object {
+			cursor.path = append(cursor.path, current.key)
+		} else {
+			cursor.path = append(cursor.path, current.index)
+		}
+	}
+
+	return cursor, true
+}
+
+// LSP positions count UTF-16 code units within a line
+func offsetToPosition(text []byte, offset int) lspPosition {
+	var position lspPosition
+	for i := 0; i < offset && i < len(text); {
+		char, size := utf8.DecodeRune(text[i:])
+		if char == '\n' {
+			position.Line++
+			position.Character = 0
+		} else {
+			position.Character += len(utf16.Encode([]rune{char}))
+		}
+
+		i += size
+	}
+
+	return position
+}
+
+func positionToOffset(text []byte, position lspPosition) int {
+	offset := 0
+	for line := 0; line < position.Line && offset < len(text); offset++ {
+		if text[offset] == '\n' {
+			line++
+		}
+	}
+
+	for units := 0; units < position.Character && offset < len(text) && text[offset] != '\n'; {
+		char, size := utf8.DecodeRune(text[offset:])
+		units += len(utf16.Encode([]rune{char}))
+		offset += size
+	}
+
+	return offset
+}
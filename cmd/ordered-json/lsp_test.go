@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLSPCompletion(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"schema.json": `{
+		"properties": {
+			"name": {"type": "string", "description": "the package name"},
+			"type": {"enum": ["module", "commonjs"], "enumDescriptions": ["esm", "require"]},
+			"private": {"type": "boolean"},
+			"engines": {"properties": {"node": {"type": "string"}, "npm": {"type": "string"}}},
+			"files": {"type": "array", "items": {"enum": ["dist", "src"]}}
+		},
+		"required": ["name"]
+	}`})
+	uri := "file://" + filepath.Join(dir, "package.json")
+
+	tests := []struct {
+		// | is the cursor
+		text string
+		// the labels, and the insert text of the first one
+		labels []string
+		insert string
+	}{
+		{`{"$schema": "./schema.json", |}`, []string{"name", "type", "private", "engines", "files"}, `"name"`},
+		{`{"$schema": "./schema.json", "na|`, []string{"name", "type", "private", "engines", "files"}, `name`},
+		{`{"$schema": "./schema.json", "name": "x", |}`, []string{"type", "private", "engines", "files"}, `"type"`},
+		{`{"$schema": "./schema.json", "type": |`, []string{`"module"`, `"commonjs"`}, `"module"`},
+		{`{"$schema": "./schema.json", "type": "m|`, []string{`"module"`, `"commonjs"`}, `module`},
+		{`{"$schema": "./schema.json", "private": |`, []string{"true", "false"}, `true`},
+		{`{"$schema": "./schema.json", "engines": {"node": "20", |`, []string{"npm"}, `"npm"`},
+		{`{"$schema": "./schema.json", "engines": {"node": "20"}, "files": ["src", |`, []string{`"dist"`, `"src"`}, `"dist"`},
+		{`{"$schema": "./schema.json", "name": "a, \"b\": {", |`, []string{"type", "private", "engines", "files"}, `"type"`},
+		{`|{"$schema": "./schema.json"}`, []string{}, ""},
+	}
+
+	for _, test := range tests {
+		server := newLSPServer(strings.NewReader(""), &bytes.Buffer{})
+		text := strings.Replace(test.text, "|", "", 1)
+		before, _, _ := strings.Cut(test.text, "|")
+
+		// the schema comes from the last version of the document that parsed
+		document := map[string]interface{}{"uri": uri, "text": `{"$schema": "./schema.json"}`}
+		requests := []*lspRequest{
+			lspTestRequest(t, "textDocument/didOpen", map[string]interface{}{"textDocument": document}),
+			lspTestRequest(t, "textDocument/didChange", map[string]interface{}{
+				"textDocument":   map[string]interface{}{"uri": uri},
+				"contentChanges": []interface{}{map[string]interface{}{"text": text}},
+			}),
+		}
+		for _, request := range requests {
+			if _, err := server.handle(request); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		request := lspTestRequest(t, "textDocument/completion", map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": uri},
+			"position":     offsetToPosition([]byte(text), len(before)),
+		})
+		result, err := server.handle(request)
+		if err != nil {
+			t.Errorf("completion in %s: %v", test.text, err)
+			continue
+		}
+
+		items := result.([]lspCompletionItem)
+		labels := []string{}
+		for _, item := range items {
+			labels = append(labels, item.Label)
+		}
+
+		if !reflect.DeepEqual(labels, test.labels) {
+			t.Errorf("completion in %s = %q, want %q", test.text, labels, test.labels)
+			continue
+		}
+
+		if len(items) > 0 && items[0].InsertText != test.insert {
+			t.Errorf("completion in %s inserts %s, want %s", test.text, items[0].InsertText, test.insert)
+		}
+	}
+}
+
+func lspTestRequest(t *testing.T, method string, params interface{}) *lspRequest {
+	t.Helper()
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &lspRequest{ID: json.RawMessage("1"), Method: method, Params: encoded}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		err := runCommand(os.Args[1:])
+		// with --output json, the error is in the report
+		var reported *reportedError
+		if err != nil && exitCode(err) != exitOK && !errors.As(err, &reported) {
+			fmt.Fprintf(os.Stderr, "error: %s\n", errorText(err))
+		}
+
+		os.Exit(exitCode(err))
+
+		return
+	}
+
+	runDefault()
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+	"github.com/michaelhelvey/orderedjson/v2/compat"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// with --output json before the command, everything a command prints goes in one json object on
+// stdout when it's done, so scripts don't have to parse text meant for people:
+//
+//	{"command": "validate", "ok": false, "exitCode": 3, "files": [
+//	  {"path": "a.json", "ok": true},
+//	  {"path": "b.json", "ok": false, "error": {"message": "...", "path": "b.json", "line": 2, "column": 5}}
+//	], "error": {"message": "1 of 2 files failed"}}
+//
+// each file has what the command printed for it: "diff" for the diffs it printed with printDiff,
+// and the rest as "values" when that was json and "output" otherwise. the commands that reformat or
+// rewrite files (fmt, fix, rewrite) also say whether the file is different afterwards, or would be
+// without --check or --diff, with "changed", so a file fmt --check fails on isn't just "ok". what
+// the command printed besides that (like the files rewrite changed) is at the top, the same way.
+
+// nil unless the output is json
+var report *commandReport
+
+type commandReport struct {
+	mu    sync.Mutex
+	files []*orderedjson.JsonObject
+	// what was printed that isn't part of a file
+	output commandOutput
+}
+
+func (report *commandReport) Write(p []byte) (int, error) {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	return report.output.Write(p)
+}
+
+func (report *commandReport) writeDiff(diff string) {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	report.output.writeDiff(diff)
+}
+
+// what a command printed, with the diffs apart from the rest
+type commandOutput struct {
+	// all of it in order, for text output
+	all  bytes.Buffer
+	text bytes.Buffer
+	diff bytes.Buffer
+	// what reportChanged said, nil when it wasn't called
+	changed *bool
+}
+
+func (output *commandOutput) Write(p []byte) (int, error) {
+	output.all.Write(p)
+	return output.text.Write(p)
+}
+
+func (output *commandOutput) writeDiff(diff string) {
+	output.all.WriteString(diff)
+	output.diff.WriteString(diff)
+}
+
+func (output *commandOutput) setChanged(changed bool) {
+	output.changed = &changed
+}
+
+// what diffs are printed to, so they can go under "diff" in a report
+type diffWriter interface {
+	writeDiff(diff string)
+}
+
+// says whether the file whose output goes to out is different after the command, for the "changed"
+// of its report. it's only kept when there's a report.
+func reportChanged(out io.Writer, changed bool) {
+	if output, ok := out.(*commandOutput); ok && report != nil {
+		output.setChanged(changed)
+	}
+}
+
+// prints a diff, like unifiedDiff's, to out
+func printDiff(out io.Writer, diff string) error {
+	if writer, ok := out.(diffWriter); ok {
+		writer.writeDiff(diff)
+		return nil
+	}
+
+	_, err := io.WriteString(out, diff)
+	return err
+}
+
+func (report *commandReport) addFile(path string, output *commandOutput, err error) {
+	file := orderedmap.New[string, interface{}]()
+	file.Set("path", displayName(path))
+	file.Set("ok", err == nil)
+	if output.changed != nil {
+		file.Set("changed", *output.changed)
+	}
+	addOutput(file, output)
+	if err != nil {
+		file.Set("error", errorObject(err))
+	}
+
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	report.files = append(report.files, file)
+}
+
+// the report of a command that finished with err, as json
+func (report *commandReport) marshal(name string, err error) (string, error) {
+	result := orderedmap.New[string, interface{}]()
+	result.Set("command", name)
+	result.Set("ok", exitCode(err) == exitOK)
+	result.Set("exitCode", float64(exitCode(err)))
+
+	files := make([]interface{}, 0, len(report.files))
+	for _, file := range report.files {
+		files = append(files, file)
+	}
+	result.Set("files", files)
+
+	addOutput(result, &report.output)
+	if exitCode(err) != exitOK {
+		result.Set("error", errorObject(err))
+	}
+
+	return marshalValue(result)
+}
+
+// sets diff, and values or output, to what was printed, if anything was
+func addOutput(object *orderedjson.JsonObject, output *commandOutput) {
+	if output.diff.Len() > 0 {
+		object.Set("diff", output.diff.String())
+	}
+
+	text := output.text.Bytes()
+	switch {
+	case len(bytes.TrimSpace(text)) == 0:
+	default:
+		if values, ok := jsonValues(text); ok {
+			object.Set("values", values)
+		} else {
+			object.Set("output", string(text))
+		}
+	}
+}
+
+// the json values in output, one after another, in order. numbers are kept as they were printed,
+// so integers past 2^53 don't lose digits.
+func jsonValues(output []byte) ([]interface{}, bool) {
+	decoder := compat.NewDecoder(bytes.NewReader(output))
+	decoder.UseNumber()
+	var values []interface{}
+	for {
+		var value interface{}
+		if err := decoder.Decode(&value); err == io.EOF {
+			return values, true
+		} else if err != nil {
+			return nil, false
+		}
+
+		values = append(values, value)
+	}
+}
+
+// an error as json, with where it is for syntax errors
+func errorObject(err error) *orderedjson.JsonObject {
+	result := orderedmap.New[string, interface{}]()
+	var syntax *syntaxError
+	if errors.As(err, &syntax) {
+		result.Set("message", syntax.err.Msg)
+		result.Set("path", displayName(syntax.path))
+		result.Set("line", float64(syntax.err.Line))
+		result.Set("column", float64(syntax.err.Column))
+		return result
+	}
+
+	result.Set("message", err.Error())
+	return result
+}
+
+// an error that's in a report that was printed, so it isn't printed again
+type reportedError struct {
+	err error
+}
+
+func (err *reportedError) Error() string {
+	return err.err.Error()
+}
+
+func (err *reportedError) Unwrap() error {
+	return err.err
+}
+
+// runs the command in args with its output in a report, and prints the report. the error is
+// returned for the exit code, as a reportedError since it's already in the report.
+func runReported(name string, args []string) error {
+	report = &commandReport{}
+	defer func() { report = nil }()
+	err := runCommand(args)
+
+	text, marshalErr := report.marshal(name, err)
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	if _, writeErr := io.WriteString(os.Stdout, text+"\n"); writeErr != nil {
+		return withExitCode(exitIO, writeErr)
+	}
+
+	if err != nil {
+		return &reportedError{err}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReportDiffs(t *testing.T) {
+	tests := []struct {
+		args  []string
+		input string
+		// what each file in the report has besides path and ok
+		want []string
+	}{
+		{[]string{"fmt", "--diff"}, `{"a":1}`, []string{"diff"}},
+		{[]string{"fmt", "--dry-run"}, `{"a":1}`, []string{"diff"}},
+		{[]string{"fmt", "--check", "--diff"}, `{"a":1}`, []string{"diff", "output"}},
+		{[]string{"fmt", "--check"}, `{"a":1}`, []string{"output"}},
+		{[]string{"fix", "--diff"}, `{"a":1,}`, []string{"diff"}},
+		{[]string{"minify"}, "{\n  \"a\": 1\n}\n", []string{"values"}},
+	}
+
+	for _, test := range tests {
+		dir := writeFiles(t, map[string]string{"a.json": test.input})
+		args := append(append([]string{"--output", "json"}, test.args...), filepath.Join(dir, "a.json"))
+		printed, _ := runCLI(t, args...)
+
+		var report struct {
+			Files []map[string]interface{} `json:"files"`
+		}
+		if err := json.Unmarshal([]byte(printed), &report); err != nil || len(report.Files) != 1 {
+			t.Errorf("%s printed %q, %v", strings.Join(test.args, " "), printed, err)
+			continue
+		}
+
+		var got []string
+		for _, key := range []string{"diff", "values", "output"} {
+			if _, ok := report.Files[0][key]; ok {
+				got = append(got, key)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(test.want, ",") {
+			t.Errorf("%s reported %v, want %v", strings.Join(test.args, " "), got, test.want)
+		}
+
+		if diff, ok := report.Files[0]["diff"].(string); ok && !strings.HasPrefix(diff, "--- ") {
+			t.Errorf("%s reported the diff %q", strings.Join(test.args, " "), diff)
+		}
+	}
+}
+
+func TestReportChanged(t *testing.T) {
+	tests := []struct {
+		args  []string
+		input string
+		// the "changed" of the file, nil when there isn't one
+		want interface{}
+	}{
+		{[]string{"fmt", "--check"}, `{"a":1}`, true},
+		{[]string{"fmt", "--check"}, "{\n  \"a\": 1\n}\n", false},
+		{[]string{"fmt", "--dry-run"}, `{"a":1}`, true},
+		{[]string{"fix"}, `{"a":1,}`, true},
+		{[]string{"rewrite", "--expr", "set(.a, 2)"}, `{"a": 1}`, true},
+		{[]string{"rewrite", "--expr", "set(.a, 1)"}, `{"a": 1}`, false},
+		{[]string{"validate"}, `{"a":1}`, nil},
+	}
+
+	for _, test := range tests {
+		dir := writeFiles(t, map[string]string{"a.json": test.input})
+		args := append(append([]string{"--output", "json"}, test.args...), filepath.Join(dir, "a.json"))
+		printed, _ := runCLI(t, args...)
+
+		var report struct {
+			Files []map[string]interface{} `json:"files"`
+		}
+		if err := json.Unmarshal([]byte(printed), &report); err != nil || len(report.Files) != 1 {
+			t.Errorf("%s printed %q, %v", strings.Join(test.args, " "), printed, err)
+			continue
+		}
+
+		if got := report.Files[0]["changed"]; got != test.want {
+			t.Errorf("%s on %q reported changed: %v, want %v", strings.Join(test.args, " "), test.input, got, test.want)
+		}
+	}
+}
+
+// the error is in the report, and main doesn't print it a second time
+func TestReportError(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.json": `{"a":`})
+	printed, err := runCLI(t, "--output", "json", "validate", filepath.Join(dir, "a.json"))
+
+	var reported *reportedError
+	if !errors.As(err, &reported) || exitCode(err) != exitSyntax {
+		t.Errorf("err = %v, want a reportedError with exit code %d", err, exitSyntax)
+	}
+
+	if !strings.Contains(printed, `"error": {"message": `) {
+		t.Errorf("printed %q, want the error", printed)
+	}
+
+	// errors of commands without a report are printed by main
+	if _, err := runCLI(t, "validate", filepath.Join(dir, "a.json")); err == nil || errors.As(err, &reported) {
+		t.Errorf("err = %#v, want one that isn't reported", err)
+	}
+}
+
+// numbers in values are what the command printed, not float64s
+func TestReportNumbers(t *testing.T) {
+	values, ok := jsonValues([]byte("{\"id\": 12345678901234567891, \"f\": 1.50}\n[-0, 1e400]\n"))
+	if !ok || len(values) != 2 {
+		t.Fatalf("jsonValues = %v, %v", values, ok)
+	}
+
+	if got, err := marshalValue(values); err != nil || got != `[{"id": 12345678901234567891, "f": 1.50}, [-0, 1e400]]` {
+		t.Errorf("values = %s, %v", got, err)
+	}
+
+	if values, ok := jsonValues([]byte("{\"a\": 1} not json")); ok {
+		t.Errorf("jsonValues(text) = %v, want it not to be json", values)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+func runToDotenv(flags *flag.FlagSet) func(args []string) error {
+	sep := flags.String("sep", "__", "join the keys of nested values with this")
+	return runFlatFormat(flags, func(tree *orderedjson.JsonObject) ([]byte, error) {
+		return orderedjson.ToDotenv(tree, *sep)
+	})
+}
+
+func runToProperties(flags *flag.FlagSet) func(args []string) error {
+	sep := flags.String("sep", ".", "join the keys of nested values with this")
+	return runFlatFormat(flags, func(tree *orderedjson.JsonObject) ([]byte, error) {
+		return orderedjson.ToProperties(tree, *sep)
+	})
+}
+
+func runFlatFormat(flags *flag.FlagSet, encode func(tree *orderedjson.JsonObject) ([]byte, error)) func(args []string) error {
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			tree, err := parseInput(path)
+			if err != nil {
+				return err
+			}
+
+			data, err := encode(tree)
+			if err != nil {
+				return err
+			}
+
+			_, err = out.Write(data)
+			return err
+		})
+	}
+}
+
+func runFromDotenv(flags *flag.FlagSet) func(args []string) error {
+	return runFromFlatFormat(flags, orderedjson.FromDotenv, ".env")
+}
+
+func runFromProperties(flags *flag.FlagSet) func(args []string) error {
+	return runFromFlatFormat(flags, orderedjson.FromProperties, ".properties")
+}
+
+func runFromFlatFormat(flags *flag.FlagSet, decode func(data []byte, sep string) (*orderedjson.JsonObject, error), extension string) func(args []string) error {
+	unflatten := flags.String("unflatten", "", "nest keys joined by this, instead of keeping them flat")
+	files := addFileFlags(flags, extension)
+
+	return func(args []string) error {
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			raw, err := readInput(path)
+			if err != nil {
+				return err
+			}
+
+			tree, err := decode(raw, *unflatten)
+			if err != nil {
+				return withExitCode(exitSyntax, fmt.Errorf("%s: %w", displayName(path), err))
+			}
+
+			data, err := marshalValue(tree)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(out, data)
+			return nil
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRedactCommand(t *testing.T) {
+	input := "{\n  \"name\": \"x\",\n  \"price\": 1.50,\n  \"db\": {\"Password\": \"hunter2\", \"port\": 5432},\n  \"token\": {\"a\": [1, 2]}\n}\n"
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "{\n  \"name\": \"x\",\n  \"price\": 1.50,\n  \"db\": {\"Password\": \"***\", \"port\": 5432},\n  \"token\": \"***\"\n}\n"},
+		{[]string{"--remove"}, "{\n  \"name\": \"x\",\n  \"price\": 1.50,\n  \"db\": {\"port\": 5432}\n}\n"},
+		{[]string{"--keys", "", "--pattern", "^n"}, "{\n  \"name\": \"***\",\n  \"price\": 1.50,\n  \"db\": {\"Password\": \"hunter2\", \"port\": 5432},\n  \"token\": {\"a\": [1, 2]}\n}\n"},
+		{[]string{"--keys", "nothing"}, input},
+	}
+
+	dir := writeFiles(t, map[string]string{"a.json": input})
+	for _, test := range tests {
+		args := append(append([]string{"redact"}, test.args...), filepath.Join(dir, "a.json"))
+		got, err := runCLI(t, args...)
+		if err != nil {
+			t.Errorf("%v: %v", args, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("%v printed\n%s\nwant\n%s", args, got, test.want)
+		}
+	}
+}
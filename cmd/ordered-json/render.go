@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// the environment as an object, for .env in templates
+func environmentObject() *orderedjson.JsonObject {
+	variables := os.Environ()
+	sort.Strings(variables)
+
+	env := orderedmap.New[string, interface{}]()
+	for _, variable := range variables {
+		name, value, _ := strings.Cut(variable, "=")
+		env.Set(name, value)
+	}
+
+	return env
+}
+
+func runRender(flags *flag.FlagSet) func(args []string) error {
+	var valueFiles []string
+	flags.Func("values", "a json or yaml file of values, later ones override the top level keys of earlier ones (can be repeated)", func(path string) error {
+		valueFiles = append(valueFiles, path)
+		return nil
+	})
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		// .env is there unless the values have one of their own
+		values := orderedmap.New[string, interface{}]()
+		values.Set("env", environmentObject())
+
+		for _, path := range valueFiles {
+			raw, err := readInput(path)
+			if err != nil {
+				return err
+			}
+
+			var layer *orderedjson.JsonObject
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".yaml", ".yml":
+				if layer, err = orderedjson.FromYAML(raw); err != nil {
+					return withExitCode(exitSyntax, fmt.Errorf("%s: %v", path, err))
+				}
+			default:
+				if layer, err = parseSource(path, raw, orderedjson.ParseOptions{}); err != nil {
+					return err
+				}
+			}
+
+			for pair := layer.Oldest(); pair != nil; pair = pair.Next() {
+				values.Set(pair.Key, pair.Value)
+			}
+		}
+
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			tmpl, err := parseInput(path)
+			if err != nil {
+				return err
+			}
+
+			rendered, err := orderedjson.Render(tmpl, values)
+			if err != nil {
+				return err
+			}
+
+			formatted, err := orderedjson.FormatOptions{}.FormatTree(rendered)
+			if err != nil {
+				return err
+			}
+
+			_, err = out.Write(formatted)
+			return err
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+func runFix(flags *flag.FlagSet) func(args []string) error {
+	edits := addEditFlags(flags, "write the result back to the files instead of stdout")
+	diff := flags.Bool("diff", false, "print a unified diff of what would change")
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		if err := edits.check(); err != nil {
+			return err
+		}
+
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			raw, err := readInput(path)
+			if err != nil {
+				return err
+			}
+
+			repaired, err := orderedjson.Repair(raw)
+			var syntax *orderedjson.SyntaxError
+			if errors.As(err, &syntax) {
+				return &syntaxError{path: path, err: syntax, source: raw}
+			}
+
+			if err != nil {
+				return withExitCode(exitSyntax, err)
+			}
+
+			reportChanged(out, !bytes.Equal(raw, repaired))
+			switch {
+			case *diff:
+				if !bytes.Equal(raw, repaired) {
+					return printDiff(out, unifiedDiff(displayName(path), raw, repaired))
+				}
+			case edits.enabled(path):
+				return edits.apply(path, raw, repaired, out)
+			default:
+				_, err = out.Write(repaired)
+				return err
+			}
+
+			return nil
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"sync"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+	"github.com/michaelhelvey/orderedjson/v2/query"
+)
+
+func runRewrite(flags *flag.FlagSet) func(args []string) error {
+	script := flags.String("expr", "", `the rewrite script, like 'set(.engines.node, ">=20") | del(.scripts.test)'`)
+	edits := addEditFlags(flags, "write the changes back to the files and list the ones that changed, instead of printing the documents")
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		args, err := parseInterspersed(flags, args)
+		if err != nil {
+			return err
+		}
+
+		if err := edits.check(); err != nil {
+			return err
+		}
+
+		if *script == "" {
+			return usageError("rewrite needs a script in --expr")
+		}
+
+		compiled, err := query.CompileRewrite(*script)
+		if err != nil {
+			return usageError("%v", err)
+		}
+
+		return rewriteFiles(files, edits, compiled, args)
+	}
+}
+
+// set is rewrite with one set step, for the common case and for pipelines:
+// `cat a.json | ordered-json set .x 1 | ordered-json fmt`
+func runSet(flags *flag.FlagSet) func(args []string) error {
+	edits := addEditFlags(flags, "write the changes back to the files and list the ones that changed, instead of printing the documents")
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		args, err := parseInterspersed(flags, args)
+		if err != nil {
+			return err
+		}
+
+		if err := edits.check(); err != nil {
+			return err
+		}
+
+		if len(args) < 2 {
+			return usageError("set needs a path like .a.b and a value")
+		}
+
+		// json, or a string without its quotes
+		var value interface{} = args[1]
+		if parsed, err := orderedjson.FromStdJSONValue([]byte(args[1])); err == nil {
+			value = parsed
+		}
+
+		compiled, err := query.SetScript(args[0], value)
+		if err != nil {
+			return usageError("%v", err)
+		}
+
+		return rewriteFiles(files, edits, compiled, args[2:])
+	}
+}
+
+// runs script on every file in args, printing the results or editing the files
+func rewriteFiles(files *fileFlags, edits *editFlags, script *query.RewriteScript, args []string) error {
+	paths, err := files.expandPaths(args)
+	if err != nil {
+		return err
+	}
+
+	// with -w, nothing is written until every file was rewritten, so a script that fails on one of
+	// them leaves all of them as they were
+	var mu sync.Mutex
+	type rewrite struct{ old, new []byte }
+	rewritten := make(map[string]rewrite)
+	err = files.run(paths, func(path string, out io.Writer) error {
+		raw, err := readInput(path)
+		if err != nil {
+			return err
+		}
+
+		doc, err := parseDocumentSource(path, raw)
+		if err != nil {
+			return err
+		}
+
+		tree, err := script.Apply(doc.Tree)
+		if err != nil {
+			return err
+		}
+
+		text, err := doc.Patch(tree)
+		if err != nil {
+			return err
+		}
+
+		if bytes.HasPrefix(raw, utf8BOM) {
+			text = append(append([]byte{}, utf8BOM...), text...)
+		}
+
+		reportChanged(out, !bytes.Equal(raw, text))
+		if !edits.enabled(path) {
+			_, err = out.Write(text)
+			return err
+		}
+
+		if !bytes.Equal(raw, text) {
+			mu.Lock()
+			rewritten[path] = rewrite{old: raw, new: text}
+			mu.Unlock()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		change, changed := rewritten[path]
+		if !changed {
+			continue
+		}
+
+		if err := edits.apply(path, change.old, change.new, files.stdout()); err != nil {
+			return err
+		}
+
+		if !edits.dryRun {
+			fmt.Fprintln(files.stdout(), displayName(path))
+		}
+	}
+
+	return nil
+}
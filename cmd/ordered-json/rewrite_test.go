@@ -0,0 +1,64 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRewriteCommand(t *testing.T) {
+	files := map[string]string{
+		"a.json": `{"a": [1], "b": true}`,
+		"b.json": `{"a": "s"}`,
+	}
+
+	tests := []struct {
+		expr string
+		code int
+		// what the files have afterwards, a.json and b.json
+		wantA, wantB string
+	}{
+		{"del(.b)", exitOK, `{"a": [1]}`, files["b.json"]},
+		{"set(.c, 2) | del(.a)", exitOK, `{"b": true, "c": 2}`, `{"c": 2}`},
+		{`set(.engines.node, ">=20")`, exitOK, `{"a": [1], "b": true, "engines": {"node": ">=20"}}`, `{"a": "s", "engines": {"node": ">=20"}}`},
+		{`rename(.a, "z")`, exitOK, `{"z": [1], "b": true}`, `{"z": "s"}`},
+		// b.json has no array, so neither file is written
+		{"set(.a[0], 2)", exitFailure, files["a.json"], files["b.json"]},
+		{"", exitUsage, files["a.json"], files["b.json"]},
+		{"set(.a +", exitUsage, files["a.json"], files["b.json"]},
+	}
+
+	for _, test := range tests {
+		dir := writeFiles(t, files)
+		a, b := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")
+		args := []string{"rewrite", "-w", a, b}
+		if test.expr != "" {
+			args = []string{"rewrite", "--expr", test.expr, "-w", a, b}
+		}
+
+		printed, err := runCLI(t, args...)
+		if got := exitCode(err); got != test.code {
+			t.Errorf("rewrite %q: exit code %d (%v), want %d", test.expr, got, err, test.code)
+			continue
+		}
+
+		if got := readFile(t, a); got != test.wantA {
+			t.Errorf("rewrite %q left a.json %q, want %q", test.expr, got, test.wantA)
+		}
+		if got := readFile(t, b); got != test.wantB {
+			t.Errorf("rewrite %q left b.json %q, want %q", test.expr, got, test.wantB)
+		}
+
+		// -w lists the files it changed
+		var changed []string
+		if test.wantA != files["a.json"] {
+			changed = append(changed, a+"\n")
+		}
+		if test.wantB != files["b.json"] {
+			changed = append(changed, b+"\n")
+		}
+		if test.code == exitOK && printed != strings.Join(changed, "") {
+			t.Errorf("rewrite %q printed %q, want %q", test.expr, printed, strings.Join(changed, ""))
+		}
+	}
+}
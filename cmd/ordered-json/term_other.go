@@ -0,0 +1,27 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package main
+
+import (
+	"errors"
+)
+
+var errNoTerminal = errors.New("interactive mode is not supported on this platform")
+
+type terminalState struct{}
+
+func makeRaw(fd int) (*terminalState, error) {
+	return nil, errNoTerminal
+}
+
+func (state *terminalState) restore() error {
+	return nil
+}
+
+func terminalSize(fd int) (width, height int, err error) {
+	return 0, 0, errNoTerminal
+}
+
+func isTerminal(fd int) bool {
+	return false
+}
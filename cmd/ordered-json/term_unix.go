@@ -0,0 +1,66 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// just enough of a terminal library for explore: raw mode and the window size
+
+type terminalState struct {
+	fd      int
+	termios syscall.Termios
+}
+
+// puts the terminal into raw mode, so keys are read one at a time without being echoed
+func makeRaw(fd int) (*terminalState, error) {
+	state := &terminalState{fd: fd}
+	if err := ioctl(fd, ioctlGetTermios, unsafe.Pointer(&state.termios)); err != nil {
+		return nil, err
+	}
+
+	raw := state.termios
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(fd, ioctlSetTermios, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func (state *terminalState) restore() error {
+	return ioctl(state.fd, ioctlSetTermios, unsafe.Pointer(&state.termios))
+}
+
+func terminalSize(fd int) (width, height int, err error) {
+	var size struct {
+		rows, columns, xPixels, yPixels uint16
+	}
+
+	if err := ioctl(fd, syscall.TIOCGWINSZ, unsafe.Pointer(&size)); err != nil {
+		return 0, 0, err
+	}
+
+	return int(size.columns), int(size.rows), nil
+}
+
+func ioctl(fd int, request uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+func isTerminal(fd int) bool {
+	var termios syscall.Termios
+	return ioctl(fd, ioctlGetTermios, unsafe.Pointer(&termios)) == nil
+}
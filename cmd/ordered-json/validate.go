@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+func runValidate(flags *flag.FlagSet) func(args []string) error {
+	schemaPath := flags.String("schema", "", "the schema to check against, instead of the local file in each document's \"$schema\"")
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		var schema *orderedjson.JsonObject
+		if *schemaPath != "" {
+			var err error
+			if schema, err = loadSchema(*schemaPath); err != nil {
+				return err
+			}
+		}
+
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			tree, err := parseInput(path)
+			if err != nil {
+				return err
+			}
+
+			documentSchema := schema
+			if documentSchema == nil {
+				location, _ := tree.Get("$schema")
+				source, ok := location.(string)
+				if !ok || strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+					return usageError("no --schema given and no local \"$schema\" in the document")
+				}
+
+				source = strings.TrimPrefix(source, "file://")
+				if !filepath.IsAbs(source) && path != "-" {
+					source = filepath.Join(filepath.Dir(path), source)
+				}
+
+				if documentSchema, err = loadSchema(source); err != nil {
+					return err
+				}
+			}
+
+			errs := orderedjson.ValidateSchema(documentSchema, tree)
+			for _, schemaErr := range errs {
+				fmt.Fprintf(out, "%s: %v\n", displayName(path), schemaErr)
+			}
+
+			if len(errs) > 0 {
+				return withExitCode(exitSchema, fmt.Errorf("%d schema errors", len(errs)))
+			}
+
+			return nil
+		})
+	}
+}
+
+// schemas are parsed as standard json, since they are full of true and false
+func loadSchema(path string) (*orderedjson.JsonObject, error) {
+	raw, err := readInput(path)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := orderedjson.FromStdJSON(raw)
+	if err != nil {
+		return nil, withExitCode(exitSyntax, fmt.Errorf("invalid schema %s: %v", path, err))
+	}
+
+	return schema, nil
+}
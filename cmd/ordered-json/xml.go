@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+func addXMLFlags(flags *flag.FlagSet) *orderedjson.XMLOptions {
+	var opts orderedjson.XMLOptions
+	flags.StringVar(&opts.AttributePrefix, "attr-prefix", "@", "what keys for attributes start with")
+	flags.StringVar(&opts.TextKey, "text-key", "#text", "the key for the text of elements that also have attributes or children")
+	flags.StringVar(&opts.ChildrenKey, "children-key", "", "keep children in document order in an array under this key, instead of grouping them by name")
+	return &opts
+}
+
+func runToXML(flags *flag.FlagSet) func(args []string) error {
+	opts := addXMLFlags(flags)
+	indent := flags.Bool("indent", false, "put every element on its own line")
+	flags.StringVar(&opts.Root, "root", "", "wrap the document in a root element with this name")
+	files := addFileFlags(flags)
+
+	return func(args []string) error {
+		if *indent {
+			opts.Indent = "  "
+		}
+
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			tree, err := parseInput(path)
+			if err != nil {
+				return err
+			}
+
+			data, err := orderedjson.ToXML(tree, *opts)
+			if err != nil {
+				return err
+			}
+
+			_, err = out.Write(data)
+			return err
+		})
+	}
+}
+
+func runFromXML(flags *flag.FlagSet) func(args []string) error {
+	opts := addXMLFlags(flags)
+	files := addFileFlags(flags, ".xml")
+
+	return func(args []string) error {
+		paths, err := files.expandPaths(args)
+		if err != nil {
+			return err
+		}
+
+		return files.run(paths, func(path string, out io.Writer) error {
+			raw, err := readInput(path)
+			if err != nil {
+				return err
+			}
+
+			tree, err := orderedjson.FromXML(raw, *opts)
+			if err != nil {
+				return withExitCode(exitSyntax, fmt.Errorf("%s: %w", displayName(path), err))
+			}
+
+			data, err := marshalValue(tree)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(out, data)
+			return nil
+		})
+	}
+}
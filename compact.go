@@ -0,0 +1,107 @@
+package orderedjson
+
+import (
+	"bytes"
+)
+
+// Compact appends src to dst without the whitespace between tokens, like json.Compact. it works on
+// the bytes as they come, so keys stay in their order and the size of src doesn't matter. dst is left
+// as it was when src isn't json.
+func Compact(dst *bytes.Buffer, src []byte) error {
+	return reformat(dst, src, &tokenFormatter{})
+}
+
+// Indent appends src to dst with every item of an object or array on a line of its own, starting
+// with prefix and indent once per level, like json.Indent. empty objects and arrays stay {} and [].
+func Indent(dst *bytes.Buffer, src []byte, prefix, indent string) error {
+	return reformat(dst, src, &tokenFormatter{indent: true, prefix: prefix, step: indent})
+}
+
+func reformat(dst *bytes.Buffer, src []byte, format *tokenFormatter) error {
+	start := dst.Len()
+	format.out = dst
+
+	v := newValidator()
+	v.format = format
+	err := v.write(src)
+	if err == nil {
+		err = v.close()
+	}
+
+	if err != nil {
+		dst.Truncate(start)
+	}
+
+	return err
+}
+
+// writes the bytes the validator accepts, with its own whitespace in place of the original
+type tokenFormatter struct {
+	out *bytes.Buffer
+
+	indent       bool
+	prefix, step string
+	depth        int
+	// an object or array was just opened, and needs a line break unless it's closed right away
+	opened bool
+}
+
+// handles c, which the validator read in state
+func (f *tokenFormatter) write(state validatorState, c byte) {
+	switch state {
+	case expectValue, expectFirstItem, expectKey, expectFirstKey, expectColon, afterValue:
+	default:
+		// inside a string, number or literal
+		f.out.WriteByte(c)
+		return
+	}
+
+	switch c {
+	case ' ', '\t', '\n', '\r':
+	case '{', '[':
+		f.breakLine()
+		f.out.WriteByte(c)
+		f.depth++
+		f.opened = true
+	case '}', ']':
+		f.depth--
+		if f.opened {
+			f.opened = false
+		} else {
+			f.newline()
+		}
+
+		f.out.WriteByte(c)
+	case ',':
+		f.out.WriteByte(c)
+		f.newline()
+	case ':':
+		f.out.WriteByte(c)
+		if f.indent {
+			f.out.WriteByte(' ')
+		}
+	default:
+		f.breakLine()
+		f.out.WriteByte(c)
+	}
+}
+
+// the line break after an opening bracket, once it's clear it isn't empty
+func (f *tokenFormatter) breakLine() {
+	if f.opened {
+		f.opened = false
+		f.newline()
+	}
+}
+
+func (f *tokenFormatter) newline() {
+	if !f.indent {
+		return
+	}
+
+	f.out.WriteByte('\n')
+	f.out.WriteString(f.prefix)
+	for i := 0; i < f.depth; i++ {
+		f.out.WriteString(f.step)
+	}
+}
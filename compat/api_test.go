@@ -0,0 +1,112 @@
+package compat
+
+import (
+	"bufio"
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestAPI checks the package against api/v2.txt, the frozen surface: nothing listed there may change
+// or go away during v2, and anything new has to be added to it.
+func TestAPI(t *testing.T) {
+	file, err := os.Open("../api/v2.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	frozen := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			frozen[line] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	current := map[string]bool{}
+	for _, line := range apiLines(t) {
+		current[line] = true
+		if !frozen[line] {
+			t.Errorf("not in api/v2.txt: %s", line)
+		}
+	}
+
+	for line := range frozen {
+		if !current[line] {
+			t.Errorf("changed or removed from the frozen api: %s", line)
+		}
+	}
+}
+
+// one line for every exported declaration of the package, like the files in go's api directory
+func apiLines(t *testing.T) []string {
+	fset := token.NewFileSet()
+	packages, err := parser.ParseDir(fset, ".", func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	print := func(node interface{}) string {
+		var buf bytes.Buffer
+		printer.Fprint(&buf, fset, node)
+		return buf.String()
+	}
+
+	var lines []string
+	for _, file := range packages["compat"].Files {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if !decl.Name.IsExported() {
+					continue
+				}
+
+				signature := strings.TrimPrefix(print(decl.Type), "func")
+				if decl.Recv == nil {
+					lines = append(lines, "pkg compat, func "+decl.Name.Name+signature)
+				} else {
+					receiver := print(decl.Recv.List[0].Type)
+					lines = append(lines, "pkg compat, method ("+receiver+") "+decl.Name.Name+signature)
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						if !spec.Name.IsExported() {
+							continue
+						}
+
+						if spec.Assign.IsValid() {
+							lines = append(lines, "pkg compat, type "+spec.Name.Name+" = "+print(spec.Type))
+						} else if _, ok := spec.Type.(*ast.StructType); ok {
+							lines = append(lines, "pkg compat, type "+spec.Name.Name+" struct")
+						} else {
+							lines = append(lines, "pkg compat, type "+spec.Name.Name+" "+print(spec.Type))
+						}
+					case *ast.ValueSpec:
+						for _, name := range spec.Names {
+							if name.IsExported() {
+								lines = append(lines, "pkg compat, "+decl.Tok.String()+" "+name.Name)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	sort.Strings(lines)
+	return lines
+}
@@ -0,0 +1,191 @@
+// Package compat mirrors the encoding/json API so code can switch imports and keep the key order of
+// dynamic objects.
+//
+// decoding into an interface{} (or directly into a *JsonObject) produces ordered objects instead of
+// map[string]interface{}. everything else, like decoding into structs, is handed straight to
+// encoding/json. marshaling already keeps the order of ordered objects, wherever they are.
+//
+// this package is the library's stable surface. every declaration listed in api/v2.txt at the root
+// of the module keeps working the way it does for every v2 release, and TestAPI fails when one of
+// them changes or goes away. anything that has to break goes to a /v3 module path. new things can be
+// added here (and to api/v2.txt). the implementation is in internal/stdjson, so it isn't part of the
+// surface, and the other packages of the module (the root package, bson, cbor, formats, transform and
+// so on) aren't covered by this and can change in any release.
+package compat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/michaelhelvey/orderedjson/v2/internal/stdjson"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type JsonObject = orderedmap.OrderedMap[string, interface{}]
+
+type (
+	RawMessage  = json.RawMessage
+	Number      = json.Number
+	Token       = json.Token
+	Delim       = json.Delim
+	Marshaler   = json.Marshaler
+	Unmarshaler = json.Unmarshaler
+)
+
+// the errors are encoding/json's, so code that checks for them keeps working after switching imports
+type (
+	SyntaxError           = json.SyntaxError
+	UnmarshalTypeError    = json.UnmarshalTypeError
+	InvalidUnmarshalError = json.InvalidUnmarshalError
+	UnsupportedTypeError  = json.UnsupportedTypeError
+	UnsupportedValueError = json.UnsupportedValueError
+	MarshalerError        = json.MarshalerError
+)
+
+var (
+	// ErrUnexpectedEOF is what Decode returns when the input ends in the middle of a value, like
+	// encoding/json's Decoder does. Unmarshal returns a *SyntaxError for that instead.
+	ErrUnexpectedEOF = stdjson.ErrUnexpectedEOF
+	// ErrInvalidUTF8 is wrapped by the error of a Decoder with RejectInvalidUTF8 when the input isn't
+	// valid UTF-8, with the offset of the bad byte.
+	ErrInvalidUTF8 = stdjson.ErrInvalidUTF8
+)
+
+func Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	return json.MarshalIndent(v, prefix, indent)
+}
+
+func Valid(data []byte) bool {
+	return json.Valid(data)
+}
+
+func Compact(dst *bytes.Buffer, src []byte) error {
+	return json.Compact(dst, src)
+}
+
+func Indent(dst *bytes.Buffer, src []byte, prefix, indent string) error {
+	return json.Indent(dst, src, prefix, indent)
+}
+
+func Unmarshal(data []byte, v interface{}) error {
+	return stdjson.Unmarshal(data, v)
+}
+
+type Decoder struct {
+	decoder *stdjson.Decoder
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{decoder: stdjson.NewDecoder(r)}
+}
+
+// SkipBOM makes the decoder ignore a UTF-8 byte order mark at the start of the input, which
+// encoding/json would reject. it has to be called before the first Decode.
+func (dec *Decoder) SkipBOM() {
+	dec.decoder.SkipBOM()
+}
+
+// by default UTF-16 and UTF-32 input is detected and transcoded to UTF-8. DisableTranscoding turns
+// that off, so only UTF-8 is accepted like encoding/json does. it has to be called before the first
+// Decode.
+func (dec *Decoder) DisableTranscoding() {
+	dec.decoder.DisableTranscoding()
+}
+
+// RejectInvalidUTF8 makes Decode fail on malformed UTF-8 instead of silently replacing it with
+// U+FFFD like encoding/json does. it has to be called before the first Decode.
+func (dec *Decoder) RejectInvalidUTF8() {
+	dec.decoder.RejectInvalidUTF8()
+}
+
+// Decompress makes the decoder read gzipped input (or input compressed with another codec from the
+// compression package), recognized by how it starts. other input is read as it is. it has to be
+// called before the first Decode.
+func (dec *Decoder) Decompress() {
+	dec.decoder.Decompress()
+}
+
+// Decode reads the next json value from the input into v.
+func (dec *Decoder) Decode(v interface{}) error {
+	return dec.decoder.Decode(v)
+}
+
+// DecodeContext is Decode, giving up with ctx.Err() once ctx is done, e.g. to stop working on a
+// request whose deadline has passed. ctx is checked whenever more input is read and while building
+// ordered objects. reading from the input itself isn't interrupted, so a slow reader should have
+// its own deadline. after an error from ctx the decoder can't be used any more.
+func (dec *Decoder) DecodeContext(ctx context.Context, v interface{}) error {
+	return dec.decoder.DecodeContext(ctx, v)
+}
+
+// DecodeArrayFunc reads the next value from the input, which has to be an array, and calls fn with
+// each element as soon as it has been decoded (objects as ordered objects), instead of building the
+// whole array. so memory use only depends on the size of the largest element. an error from fn stops
+// decoding and is returned as is.
+func (dec *Decoder) DecodeArrayFunc(fn func(index int, value interface{}) error) error {
+	return dec.decoder.DecodeArrayFunc(fn)
+}
+
+func (dec *Decoder) UseNumber() {
+	dec.decoder.UseNumber()
+}
+
+func (dec *Decoder) DisallowUnknownFields() {
+	dec.decoder.DisallowUnknownFields()
+}
+
+// Skip discards the next value in the input, however deeply nested, without building it. inside an
+// object it has to be called after reading the key with Token.
+func (dec *Decoder) Skip() error {
+	return dec.decoder.Skip()
+}
+
+// More reports whether there is another element in the array or object being read, or another
+// value in the input at the top level.
+func (dec *Decoder) More() bool {
+	return dec.decoder.More()
+}
+
+// Buffered returns the data that has been read from the input but not decoded yet. it's only valid
+// until the next call to Decode, Token or Skip.
+func (dec *Decoder) Buffered() io.Reader {
+	return dec.decoder.Buffered()
+}
+
+func (dec *Decoder) InputOffset() int64 {
+	return dec.decoder.InputOffset()
+}
+
+// Token returns the next token in the input, exactly like encoding/json: Delim for [ ] { }, string,
+// float64 (or Number after UseNumber), bool and nil. it can be mixed with Decode and
+// DecodeArrayFunc, e.g. to step into a top level object with Token and Decode each of its values
+// into an ordered object.
+func (dec *Decoder) Token() (Token, error) {
+	return dec.decoder.Token()
+}
+
+type Encoder struct {
+	encoder *json.Encoder
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{encoder: json.NewEncoder(w)}
+}
+
+func (enc *Encoder) Encode(v interface{}) error {
+	return enc.encoder.Encode(v)
+}
+
+func (enc *Encoder) SetIndent(prefix, indent string) {
+	enc.encoder.SetIndent(prefix, indent)
+}
+
+func (enc *Encoder) SetEscapeHTML(on bool) {
+	enc.encoder.SetEscapeHTML(on)
+}
@@ -0,0 +1,42 @@
+package compat
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	var syntax *SyntaxError
+	var mismatch *UnmarshalTypeError
+	var invalid *InvalidUnmarshalError
+
+	var value interface{}
+	var object JsonObject
+	tests := []struct {
+		name   string
+		decode func() error
+		is     func(error) bool
+	}{
+		{"syntax", func() error { return Unmarshal([]byte(`{"a" 1}`), &value) }, func(err error) bool { return errors.As(err, &syntax) }},
+		{"truncated", func() error { return NewDecoder(strings.NewReader(`{"a": [1`)).Decode(&value) }, func(err error) bool { return errors.Is(err, ErrUnexpectedEOF) }},
+		{"not an object", func() error { return Unmarshal([]byte(`[1]`), &object) }, func(err error) bool { return errors.As(err, &mismatch) && mismatch.Value == "array" }},
+		{"wrong type", func() error { var n int; return Unmarshal([]byte(`"a"`), &n) }, func(err error) bool { return errors.As(err, &mismatch) }},
+		{"trailing data", func() error { return Unmarshal([]byte(`{"a": 1} x`), &value) }, func(err error) bool {
+			return errors.As(err, &syntax) && err.Error() == json.Unmarshal([]byte(`{"a": 1} x`), new(interface{})).Error()
+		}},
+		{"not a pointer", func() error { return Unmarshal([]byte(`1`), value) }, func(err error) bool { return errors.As(err, &invalid) }},
+		{"invalid utf-8", func() error {
+			dec := NewDecoder(strings.NewReader("\"\xff\""))
+			dec.RejectInvalidUTF8()
+			return dec.Decode(&value)
+		}, func(err error) bool { return errors.Is(err, ErrInvalidUTF8) }},
+	}
+
+	for _, test := range tests {
+		if err := test.decode(); !test.is(err) {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+	}
+}
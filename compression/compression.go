@@ -0,0 +1,150 @@
+// Package compression reads and writes compressed json, like exports in .json.gz files. compressed
+// input is recognized by its first bytes, which json can't start with, and output is compressed
+// when the file name ends in the extension of a codec.
+//
+// gzip is built in. zstd is recognized, but needs a decoder and encoder from elsewhere since the
+// standard library doesn't have one, e.g. with github.com/klauspost/compress/zstd:
+//
+//	compression.Register(compression.Codec{
+//		Name:       "zstd",
+//		Extensions: []string{".zst"},
+//		Magic:      []byte{0x28, 0xb5, 0x2f, 0xfd},
+//		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+//			decoder, err := zstd.NewReader(r)
+//			return decoder.IOReadCloser(), err
+//		},
+//		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+//			return zstd.NewWriter(w)
+//		},
+//	})
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+type Codec struct {
+	Name string
+	// file name extensions, with the dot
+	Extensions []string
+	// the bytes compressed data starts with
+	Magic []byte
+	// nil when the format is only recognized, to say it isn't supported
+	NewReader func(r io.Reader) (io.ReadCloser, error)
+	NewWriter func(w io.Writer) (io.WriteCloser, error)
+}
+
+var ErrUnsupported = errors.New("unsupported compression")
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = []Codec{
+		{
+			Name:       "gzip",
+			Extensions: []string{".gz", ".gzip"},
+			Magic:      []byte{0x1f, 0x8b},
+			NewReader: func(r io.Reader) (io.ReadCloser, error) {
+				return gzip.NewReader(r)
+			},
+			NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+				return gzip.NewWriter(w), nil
+			},
+		},
+		{
+			Name:       "zstd",
+			Extensions: []string{".zst", ".zstd"},
+			Magic:      []byte{0x28, 0xb5, 0x2f, 0xfd},
+		},
+	}
+)
+
+// Register adds codec, or replaces the one with the same name.
+func Register(codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	for i := range codecs {
+		if codecs[i].Name == codec.Name {
+			codecs[i] = codec
+			return
+		}
+	}
+
+	codecs = append(codecs, codec)
+}
+
+// ForPath returns the codec for the extension of path, if there is one.
+func ForPath(path string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	for _, codec := range codecs {
+		for _, extension := range codec.Extensions {
+			if strings.HasSuffix(path, extension) {
+				return codec, true
+			}
+		}
+	}
+
+	return Codec{}, false
+}
+
+// NewReader returns a reader of the decompressed contents of r when it starts like the output of a
+// codec, or of r as it is otherwise.
+func NewReader(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	for _, codec := range codecs {
+		start, _ := buffered.Peek(len(codec.Magic))
+		if len(codec.Magic) == 0 || !bytes.Equal(start, codec.Magic) {
+			continue
+		}
+
+		if codec.NewReader == nil {
+			return nil, fmt.Errorf("%w: %s input needs a codec, see compression.Register", ErrUnsupported, codec.Name)
+		}
+
+		decompressed, err := codec.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", codec.Name, err)
+		}
+
+		return decompressed, nil
+	}
+
+	return io.NopCloser(buffered), nil
+}
+
+// NewWriter returns a writer that compresses to w with the codec for the extension of path, or w
+// itself when the extension isn't one. closing it writes the end of the compressed data, and doesn't
+// close w.
+func NewWriter(w io.Writer, path string) (io.WriteCloser, error) {
+	codec, ok := ForPath(path)
+	if !ok {
+		return nopWriteCloser{w}, nil
+	}
+
+	if codec.NewWriter == nil {
+		return nil, fmt.Errorf("%w: writing %s needs a codec, see compression.Register", ErrUnsupported, codec.Name)
+	}
+
+	return codec.NewWriter(w)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,187 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+)
+
+func compress(t *testing.T, path, text string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, path)
+	if err != nil {
+		t.Fatalf("NewWriter(%s): %v", path, err)
+	}
+
+	if _, err := io.WriteString(w, text); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func decompress(data []byte) (string, error) {
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	text, err := io.ReadAll(r)
+	return string(text), err
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, path := range []string{"out.json.gz", "out.gzip", "out.json", "out", ""} {
+		for _, text := range []string{`{"z":1,"a":[true,null]}`, "", `"x"`, "\x1f"} {
+			data := compress(t, path, text)
+			got, err := decompress(data)
+			if err != nil || got != text {
+				t.Errorf("decompress(compress(%q, %q)) = %q, %v", path, text, got, err)
+			}
+		}
+	}
+}
+
+func TestGzip(t *testing.T) {
+	data := compress(t, "out.json.gz", `{"a":1}`)
+	if !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		t.Errorf("compress(.gz) = %x, want gzip", data)
+	}
+
+	// gzip from elsewhere, with more than one member like concatenated files have
+	var buf bytes.Buffer
+	for _, part := range []string{`{"a":`, `1}`} {
+		w := gzip.NewWriter(&buf)
+		io.WriteString(w, part)
+		w.Close()
+	}
+
+	if got, err := decompress(buf.Bytes()); err != nil || got != `{"a":1}` {
+		t.Errorf("decompress(two members) = %q, %v", got, err)
+	}
+
+	// plain json is passed through, whatever it starts with
+	if got, err := decompress([]byte(" \n[1]")); err != nil || got != " \n[1]" {
+		t.Errorf("decompress(plain) = %q, %v", got, err)
+	}
+
+	if _, err := decompress(data[:len(data)-4]); err == nil {
+		t.Errorf("decompress(cut off gzip): want an error")
+	}
+
+	if _, err := decompress([]byte{0x1f, 0x8b, 0xff}); err == nil {
+		t.Errorf("decompress(broken gzip header): want an error")
+	}
+}
+
+func TestForPath(t *testing.T) {
+	tests := []struct {
+		path string
+		name string
+	}{
+		{"a.json.gz", "gzip"},
+		{"dir.gz/a.gzip", "gzip"},
+		{"a.zst", "zstd"},
+		{"a.json.zstd", "zstd"},
+		{"a.json", ""},
+		{"a.gz.json", ""},
+		{"a.GZ", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		codec, ok := ForPath(test.path)
+		if ok != (test.name != "") || codec.Name != test.name {
+			t.Errorf("ForPath(%q) = %q, %v, want %q", test.path, codec.Name, ok, test.name)
+		}
+	}
+}
+
+// zstd is recognized, but can't be read or written until a codec for it is registered
+func TestUnsupported(t *testing.T) {
+	zstd := []byte{0x28, 0xb5, 0x2f, 0xfd, 0, 0, 0}
+	if _, err := decompress(zstd); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("decompress(zstd) = %v, want %v", err, ErrUnsupported)
+	}
+
+	if _, err := NewWriter(io.Discard, "a.json.zst"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("NewWriter(.zst) = %v, want %v", err, ErrUnsupported)
+	}
+}
+
+type closer struct {
+	io.Writer
+}
+
+func (closer) Close() error {
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	original, _ := ForPath(".zst")
+	t.Cleanup(func() { Register(original) })
+
+	// a stand in for zstd, and a codec of its own
+	hexCodec := func(name string, magic []byte, extensions ...string) Codec {
+		return Codec{
+			Name:       name,
+			Extensions: extensions,
+			Magic:      magic,
+			NewReader: func(r io.Reader) (io.ReadCloser, error) {
+				if _, err := io.ReadFull(r, make([]byte, len(magic))); err != nil {
+					return nil, err
+				}
+
+				return io.NopCloser(hex.NewDecoder(r)), nil
+			},
+			NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+				_, err := w.Write(magic)
+				return closer{hex.NewEncoder(w)}, err
+			},
+		}
+	}
+
+	Register(hexCodec("zstd", original.Magic, original.Extensions...))
+	Register(hexCodec("hex", []byte("HEX:"), ".hex"))
+
+	for path, prefix := range map[string]string{"a.zst": "\x28\xb5\x2f\xfd7b7d", "a.hex": "HEX:7b7d"} {
+		data := compress(t, path, `{}`)
+		if string(data) != prefix {
+			t.Errorf("compress(%s) = %q, want %q", path, data, prefix)
+		}
+
+		if got, err := decompress(data); err != nil || got != `{}` {
+			t.Errorf("decompress(compress(%s)) = %q, %v", path, got, err)
+		}
+	}
+
+	if codec, ok := ForPath("a.zstd"); !ok || codec.NewReader == nil {
+		t.Errorf("ForPath(.zstd) after replacing zstd = %+v, %v", codec, ok)
+	}
+
+	if _, err := decompress([]byte("HEX:zz")); err == nil {
+		t.Errorf("decompress(bad hex): want an error")
+	}
+
+	// too short to be the codec's, so it's passed through
+	if got, err := decompress([]byte("HEX")); err != nil || got != "HEX" {
+		t.Errorf("decompress(HEX) = %q, %v", got, err)
+	}
+
+	// an error from the codec comes back with its name
+	Register(Codec{Name: "broken", Magic: []byte("BRK"), NewReader: func(r io.Reader) (io.ReadCloser, error) {
+		return nil, errors.New("no")
+	}})
+	if _, err := decompress([]byte("BRK")); err == nil || err.Error() != "broken: no" {
+		t.Errorf("decompress(BRK) = %v, want broken: no", err)
+	}
+}
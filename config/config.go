@@ -0,0 +1,351 @@
+// Package config loads layered configuration: an ordered base file, optional overlay files on top of
+// it (e.g. config.production.json) and finally environment variable overrides. the merged tree keeps
+// the key order of the base file, and remembers which layer set every value.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/michaelhelvey/orderedjson/v2/compat"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type JsonObject = orderedmap.OrderedMap[string, interface{}]
+
+type Options struct {
+	// the base file, which has to exist
+	Base string
+	// applied in order on top of the base file. missing files are skipped.
+	Overlays []string
+	// variables starting with EnvPrefix + "_" override keys, with "__" between the levels:
+	// APP_SERVER__PORT=8080 sets server.port. empty disables env overrides.
+	EnvPrefix string
+	// defaults to os.Environ()
+	Environ []string
+}
+
+type Config struct {
+	Tree *JsonObject
+	// dotted path of every leaf value -> where it was set
+	origins map[string]Provenance
+}
+
+// Provenance says where a value came from.
+type Provenance struct {
+	// the file name, or env:NAME for environment overrides
+	Layer string
+	// where the value starts in the file, 1 based (the column in characters). 0 for env overrides.
+	Line, Column int
+	// the values it replaced, the most recent first
+	Overrides []Provenance
+}
+
+func (provenance Provenance) String() string {
+	if provenance.Line == 0 {
+		return provenance.Layer
+	}
+
+	return fmt.Sprintf("%s:%d:%d", provenance.Layer, provenance.Line, provenance.Column)
+}
+
+// Load reads and merges all the layers. objects are merged key by key, everything else (including
+// arrays) is replaced. keys that only exist in later layers are added after the existing ones.
+func Load(opts Options) (*Config, error) {
+	config := &Config{origins: make(map[string]Provenance)}
+
+	base, err := readFile(opts.Base)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Tree = orderedmap.New[string, interface{}]()
+	config.merge(config.Tree, base.tree, base, "")
+
+	for _, path := range opts.Overlays {
+		overlay, err := readFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		config.merge(config.Tree, overlay.tree, overlay, "")
+	}
+
+	if opts.EnvPrefix != "" {
+		environ := opts.Environ
+		if environ == nil {
+			environ = os.Environ()
+		}
+
+		if err := config.applyEnv(opts.EnvPrefix+"_", environ); err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// a parsed layer file
+type layerFile struct {
+	path string
+	tree *JsonObject
+	// dotted path -> line and column of the value
+	positions map[string][2]int
+}
+
+func readFile(path string) (*layerFile, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree *JsonObject
+	if err := compat.Unmarshal(raw, &tree); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if tree == nil {
+		return nil, fmt.Errorf("%s: expected an object, got null", path)
+	}
+
+	return &layerFile{path: path, tree: tree, positions: valuePositions(raw)}, nil
+}
+
+// the line and column of every value under an object key. the file has already been parsed, so this
+// gives up quietly on anything it doesn't expect.
+func valuePositions(raw []byte) map[string][2]int {
+	positions := make(map[string][2]int)
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+
+	var object func(prefix string) error
+	object = func(prefix string) error {
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+
+			key, _ := token.(string)
+			path := joinPath(prefix, key)
+
+			start := int(decoder.InputOffset())
+			for start < len(raw) && bytes.IndexByte([]byte(" \t\r\n:"), raw[start]) >= 0 {
+				start++
+			}
+
+			positions[path] = lineColumn(raw, start)
+			if start == len(raw) || raw[start] != '{' {
+				var skipped json.RawMessage
+				if err := decoder.Decode(&skipped); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			if _, err := decoder.Token(); err != nil {
+				return err
+			}
+
+			if err := object(path); err != nil {
+				return err
+			}
+
+			if _, err := decoder.Token(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if token, err := decoder.Token(); err == nil && token == json.Delim('{') {
+		object("")
+	}
+
+	return positions
+}
+
+// the 1 based line and column (in characters) of a byte offset into data
+func lineColumn(data []byte, offset int) [2]int {
+	line, column := 1, 1
+	for i := 0; i < offset; {
+		char, size := utf8.DecodeRune(data[i:])
+		if char == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+
+		i += size
+	}
+
+	return [2]int{line, column}
+}
+
+func (config *Config) merge(dst, src *JsonObject, file *layerFile, prefix string) {
+	for pair := src.Oldest(); pair != nil; pair = pair.Next() {
+		path := joinPath(prefix, pair.Key)
+
+		srcObject, srcIsObject := pair.Value.(*JsonObject)
+		existing, _ := dst.Get(pair.Key)
+		dstObject, dstIsObject := existing.(*JsonObject)
+		if srcIsObject && dstIsObject {
+			config.merge(dstObject, srcObject, file, path)
+			continue
+		}
+
+		position := file.positions[path]
+		provenance := Provenance{Layer: file.path, Line: position[0], Column: position[1]}
+		provenance.Overrides = config.forget(path)
+		if srcIsObject {
+			// copy it so later layers don't write into the source tree
+			dstObject = orderedmap.New[string, interface{}]()
+			dst.Set(pair.Key, dstObject)
+			config.merge(dstObject, srcObject, file, path)
+			if srcObject.Len() == 0 {
+				config.origins[path] = provenance
+			}
+
+			continue
+		}
+
+		dst.Set(pair.Key, pair.Value)
+		config.origins[path] = provenance
+	}
+}
+
+// drops the origins of a value that is being replaced, including everything under it. it returns
+// the history of the value at exactly path, if there was one, to be kept by what replaces it.
+func (config *Config) forget(path string) []Provenance {
+	var history []Provenance
+	for key, provenance := range config.origins {
+		if key == path {
+			history = append([]Provenance{{Layer: provenance.Layer, Line: provenance.Line, Column: provenance.Column}}, provenance.Overrides...)
+		}
+
+		if key == path || strings.HasPrefix(key, path+".") {
+			delete(config.origins, key)
+		}
+	}
+
+	return history
+}
+
+func (config *Config) applyEnv(prefix string, environ []string) error {
+	// sorted so that the result doesn't depend on the order of the environment
+	sorted := append([]string{}, environ...)
+	sort.Strings(sorted)
+
+	for _, entry := range sorted {
+		name, raw, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+			continue
+		}
+
+		// values that are valid json (numbers, booleans, arrays...) keep their type, anything else is a
+		// string
+		var value interface{}
+		if err := compat.Unmarshal([]byte(raw), &value); err != nil {
+			value = raw
+		}
+
+		object := config.Tree
+		path := ""
+		parts := strings.Split(name[len(prefix):], "__")
+		for i, part := range parts {
+			key := matchKey(object, part)
+			path = joinPath(path, key)
+			if i == len(parts)-1 {
+				overrides := config.forget(path)
+				object.Set(key, value)
+				config.origins[path] = Provenance{Layer: "env:" + name, Overrides: overrides}
+				break
+			}
+
+			existing, _ := object.Get(key)
+			child, isObject := existing.(*JsonObject)
+			if !isObject {
+				if existing != nil {
+					return fmt.Errorf("%s: %s is not an object", name, path)
+				}
+
+				child = orderedmap.New[string, interface{}]()
+				object.Set(key, child)
+			}
+
+			object = child
+		}
+	}
+
+	return nil
+}
+
+// env var names are usually upper case, so match existing keys ignoring case and lower case new ones
+func matchKey(object *JsonObject, name string) string {
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		if strings.EqualFold(pair.Key, name) {
+			return pair.Key
+		}
+	}
+
+	return strings.ToLower(name)
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + "." + key
+}
+
+// Origin returns the layer that set the value at a dotted path like server.port: a file name, or
+// env:NAME for environment overrides. objects merged from several layers don't have a single
+// origin, ask about their keys instead.
+func (config *Config) Origin(path string) (string, bool) {
+	provenance, ok := config.origins[path]
+	return provenance.Layer, ok
+}
+
+// Origins returns the origin of every leaf value, keyed by dotted path.
+func (config *Config) Origins() map[string]string {
+	result := make(map[string]string, len(config.origins))
+	for path, provenance := range config.origins {
+		result[path] = provenance.Layer
+	}
+
+	return result
+}
+
+// Provenance is like Origin, but also says where in the file the value is, and which layers set it
+// before it was overridden.
+func (config *Config) Provenance(path string) (Provenance, bool) {
+	provenance, ok := config.origins[path]
+	return provenance, ok
+}
+
+// Explain returns the provenance of the value at path and of every leaf value under it, keyed by
+// dotted path, so an object merged from several layers can still be explained key by key.
+func (config *Config) Explain(path string) map[string]Provenance {
+	result := make(map[string]Provenance)
+	for key, provenance := range config.origins {
+		if path == "" || key == path || strings.HasPrefix(key, path+".") {
+			result[key] = provenance
+		}
+	}
+
+	return result
+}
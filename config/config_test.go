@@ -0,0 +1,208 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/michaelhelvey/orderedjson/v2/internal/testtree"
+)
+
+// writes the files into a temporary directory and returns their paths, in the order given
+func files(t *testing.T, contents ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	var paths []string
+	for i, content := range contents {
+		path := filepath.Join(dir, string(rune('a'+i))+".json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		layers  []string
+		environ []string
+		want    string
+	}{
+		{[]string{`{"z":1,"a":{"y":2,"b":3}}`}, nil, `{"z":1,"a":{"y":2,"b":3}}`},
+		{[]string{`{}`}, nil, `{}`},
+		{[]string{`{"z":1,"a":{"y":2,"b":3}}`, `{"a":{"b":4,"new":5},"added":6,"z":7}`}, nil, `{"z":7,"a":{"y":2,"b":4,"new":5},"added":6}`},
+		{[]string{`{"list":[1,2,3],"o":{"a":1}}`, `{"list":[4],"o":"replaced"}`}, nil, `{"list":[4],"o":"replaced"}`},
+		{[]string{`{"o":"x"}`, `{"o":{"a":1}}`, `{"o":{"b":2}}`}, nil, `{"o":{"a":1,"b":2}}`},
+		{[]string{`{"a":1}`, `{"a":2}`, `{"a":3}`}, nil, `{"a":3}`},
+		{[]string{`{"a":1}`, `{"a":{}}`}, nil, `{"a":{}}`},
+
+		{[]string{`{"server":{"host":"x","port":80}}`}, []string{"APP_SERVER__PORT=8080"}, `{"server":{"host":"x","port":8080}}`},
+		{[]string{`{"server":{"Port":80}}`}, []string{"APP_SERVER__PORT=8080"}, `{"server":{"Port":8080}}`},
+		{[]string{`{"a":1}`}, []string{"APP_NEW__DEEP__KEY=x", "APP_FLAG=true", "APP_LIST=[1,\"b\"]", "APP_NULL=null"}, `{"a":1,"flag":true,"list":[1,"b"],"new":{"deep":{"key":"x"}},"null":null}`},
+		{[]string{`{"a":1}`}, []string{"APP_A=not json", "APP_B=", "APP_C={\"x\":1}", "APP_D=a=b"}, `{"a":"not json","b":"","c":{"x":1},"d":"a=b"}`},
+		{[]string{`{"a":1}`}, []string{"OTHER_A=2", "APP=3", "APP_=4", "APPA=5", "NOEQUALS"}, `{"a":1}`},
+		{[]string{`{"a":{"b":1}}`}, []string{"APP_A=2"}, `{"a":2}`},
+	}
+
+	for _, test := range tests {
+		paths := files(t, test.layers...)
+		config, err := Load(Options{Base: paths[0], Overlays: paths[1:], EnvPrefix: "APP", Environ: append([]string{}, test.environ...)})
+		if err != nil {
+			t.Errorf("Load(%v, %v): %v", test.layers, test.environ, err)
+			continue
+		}
+
+		if got := testtree.Marshal(t, config.Tree); got != test.want {
+			t.Errorf("Load(%v, %v) = %s, want %s", test.layers, test.environ, got, test.want)
+		}
+	}
+}
+
+// later layers must not write into the trees of earlier ones
+func TestLoadCopies(t *testing.T) {
+	paths := files(t, `{"a":1}`, `{"o":{"x":1}}`, `{"o":{"y":2}}`)
+	config, err := Load(Options{Base: paths[0], Overlays: paths[1:]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	overlay, err := readFile(paths[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := testtree.Marshal(t, overlay.tree); got != `{"o":{"x":1}}` {
+		t.Errorf("overlay = %s", got)
+	}
+
+	if got := testtree.Marshal(t, config.Tree); got != `{"a":1,"o":{"x":1,"y":2}}` {
+		t.Errorf("Tree = %s", got)
+	}
+}
+
+func TestMissingOverlay(t *testing.T) {
+	paths := files(t, `{"a":1}`, `{"a":2}`)
+	config, err := Load(Options{Base: paths[0], Overlays: []string{filepath.Join(filepath.Dir(paths[0]), "missing.json"), paths[1]}})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if got := testtree.Marshal(t, config.Tree); got != `{"a":2}` {
+		t.Errorf("Tree = %s", got)
+	}
+
+	if _, err := Load(Options{Base: filepath.Join(filepath.Dir(paths[0]), "missing.json")}); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Load(missing base) = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+func TestProvenance(t *testing.T) {
+	paths := files(t,
+		"{\n  \"server\": {\n    \"host\": \"x\",\n    \"port\": 80\n  },\n  \"name\": \"é\", \"list\": [1]\n}\n",
+		"{\"server\": {\"port\": 81}, \"empty\": {}}",
+	)
+	base, overlay := paths[0], paths[1]
+	config, err := Load(Options{Base: base, Overlays: []string{overlay}, EnvPrefix: "APP", Environ: []string{"APP_SERVER__PORT=82"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want Provenance
+	}{
+		{"server.host", Provenance{Layer: base, Line: 3, Column: 13}},
+		{"name", Provenance{Layer: base, Line: 6, Column: 11}},
+		{"list", Provenance{Layer: base, Line: 6, Column: 24}},
+		{"empty", Provenance{Layer: overlay, Line: 1, Column: 35}},
+		{"server.port", Provenance{Layer: "env:APP_SERVER__PORT", Overrides: []Provenance{
+			{Layer: overlay, Line: 1, Column: 21},
+			{Layer: base, Line: 4, Column: 13},
+		}}},
+	}
+
+	for _, test := range tests {
+		got, ok := config.Provenance(test.path)
+		if !ok || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Provenance(%s) = %+v, %v, want %+v", test.path, got, ok, test.want)
+		}
+	}
+
+	if got, ok := config.Origin("server.port"); !ok || got != "env:APP_SERVER__PORT" {
+		t.Errorf("Origin(server.port) = %s, %v", got, ok)
+	}
+
+	// objects merged from several layers don't have an origin of their own
+	if got, ok := config.Origin("server"); ok {
+		t.Errorf("Origin(server) = %s, want none", got)
+	}
+
+	if got := (Provenance{Layer: base, Line: 3, Column: 13}).String(); got != base+":3:13" {
+		t.Errorf("String() = %s", got)
+	}
+
+	if got := (Provenance{Layer: "env:A"}).String(); got != "env:A" {
+		t.Errorf("String() = %s", got)
+	}
+
+	want := map[string]string{"server.host": base, "server.port": "env:APP_SERVER__PORT", "name": base, "list": base, "empty": overlay}
+	if got := config.Origins(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Origins() = %v, want %v", got, want)
+	}
+
+	explained := config.Explain("server")
+	if len(explained) != 2 || explained["server.host"].Layer != base || explained["server.port"].Layer != "env:APP_SERVER__PORT" {
+		t.Errorf("Explain(server) = %+v", explained)
+	}
+
+	if explained := config.Explain(""); len(explained) != 5 {
+		t.Errorf("Explain() = %+v", explained)
+	}
+}
+
+// replacing an object forgets where the values under it came from
+func TestProvenanceReplaced(t *testing.T) {
+	paths := files(t, `{"o":{"a":1,"b":{"c":2}},"other":3}`, `{"o":"x"}`)
+	config, err := Load(Options{Base: paths[0], Overlays: paths[1:]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"o": paths[1], "other": paths[0]}
+	if got := config.Origins(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Origins() = %v, want %v", got, want)
+	}
+
+	if provenance, _ := config.Provenance("o"); len(provenance.Overrides) != 0 {
+		t.Errorf("Provenance(o).Overrides = %+v, want none", provenance.Overrides)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, layers := range [][]string{
+		{`{"a":`},
+		{`null`},
+		{`[1]`},
+		{`"a"`},
+		{`{"a":1}`, `{"a":}`},
+		{`{"a":1}`, `null`},
+	} {
+		paths := files(t, layers...)
+		if config, err := Load(Options{Base: paths[0], Overlays: paths[1:]}); err == nil {
+			t.Errorf("Load(%v) = %s, want an error", layers, testtree.Marshal(t, config.Tree))
+		}
+	}
+
+	paths := files(t, `{"a":1,"b":[1]}`)
+	for _, entry := range []string{"APP_A__B=1", "APP_B__C=1"} {
+		if config, err := Load(Options{Base: paths[0], EnvPrefix: "APP", Environ: []string{entry}}); err == nil {
+			t.Errorf("Load(%s) = %s, want an error", entry, testtree.Marshal(t, config.Tree))
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package orderedjson
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// CSVColumns says what to do with rows whose keys differ from the first row's.
+type CSVColumns int
+
+const (
+	// the first object's keys, then keys that only later objects have, in the order they first show
+	// up. rows without a key get an empty cell.
+	UnionColumns CSVColumns = iota
+	// the first object's keys. a row with other keys (or missing some) is an error.
+	StrictColumns
+)
+
+// CSVOptions is used by both ToCSV and FromCSV. Columns only matters for ToCSV, and the Infer
+// options only for FromCSV.
+type CSVOptions struct {
+	Columns CSVColumns
+	// the field separator, ',' when zero. '\t' gives TSV.
+	Comma rune
+	// leaves out the header row. FromCSV then names the columns 1, 2, 3...
+	NoHeader bool
+	// when set, nested objects and arrays are flattened into columns with keys joined by this (see
+	// Flatten) instead of being written as json in one cell. FromCSV nests them back (see Unflatten).
+	Flatten string
+	// cells that are json numbers become numbers. ones that json doesn't allow, like 007 (a zip
+	// code, most likely), stay strings.
+	InferNumbers bool
+	// true and false become bools
+	InferBools bool
+	// empty cells become null, which is how ToCSV writes null
+	EmptyNull bool
+}
+
+// ToCSV writes arr, an array of objects, as CSV with one row per object. the columns are in the key
+// order of the first object (see CSVColumns for the others), so the same input always gives the
+// same columns. strings are written as they are, null as an empty cell, and nested values as
+// compact json.
+func ToCSV(arr []interface{}, opts CSVOptions) ([]byte, error) {
+	rows := make([]*JsonObject, 0, len(arr))
+	for i, item := range arr {
+		row, ok := item.(*JsonObject)
+		if !ok {
+			return nil, fmt.Errorf("element %d is %s, expected an object", i, TypeName(item))
+		}
+
+		if opts.Flatten != "" {
+			row = Flatten(row, opts.Flatten)
+		}
+
+		rows = append(rows, row)
+	}
+
+	columns, err := opts.columns(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var result bytes.Buffer
+	writer := csv.NewWriter(&result)
+	if opts.Comma != 0 {
+		writer.Comma = opts.Comma
+	}
+
+	if !opts.NoHeader && len(columns) > 0 {
+		writer.Write(columns)
+	}
+
+	record := make([]string, len(columns))
+	for i, row := range rows {
+		for j, column := range columns {
+			value, _ := row.Get(column)
+			if record[j], err = csvCell(value); err != nil {
+				return nil, fmt.Errorf("element %d: %q: %w", i, column, err)
+			}
+		}
+
+		writer.Write(record)
+	}
+
+	writer.Flush()
+	return result.Bytes(), writer.Error()
+}
+
+func (opts CSVOptions) columns(rows []*JsonObject) ([]string, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	columns := make([]string, 0, rows[0].Len())
+	seen := make(map[string]bool, rows[0].Len())
+	for pair := rows[0].Oldest(); pair != nil; pair = pair.Next() {
+		columns = append(columns, pair.Key)
+		seen[pair.Key] = true
+	}
+
+	for i, row := range rows[1:] {
+		for pair := row.Oldest(); pair != nil; pair = pair.Next() {
+			if seen[pair.Key] {
+				continue
+			}
+
+			if opts.Columns == StrictColumns {
+				return nil, fmt.Errorf("element %d has key %q, which the first element doesn't", i+1, pair.Key)
+			}
+
+			columns = append(columns, pair.Key)
+			seen[pair.Key] = true
+		}
+
+		if opts.Columns == StrictColumns && row.Len() != len(columns) {
+			for _, column := range columns {
+				if _, ok := row.Get(column); !ok {
+					return nil, fmt.Errorf("element %d is missing key %q", i+1, column)
+				}
+			}
+		}
+	}
+
+	return columns, nil
+}
+
+func csvCell(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+
+	return MarshalCompact(value)
+}
+
+// FromCSV reads CSV into an array of objects, one per row, with their keys in the order of the
+// header row. cells are strings unless opts says to infer types. every row needs as many fields as
+// the header.
+func FromCSV(data []byte, opts CSVOptions) ([]interface{}, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	if opts.Comma != 0 {
+		reader.Comma = opts.Comma
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil || len(records) == 0 {
+		return []interface{}{}, err
+	}
+
+	var header []string
+	if opts.NoHeader {
+		for i := range records[0] {
+			header = append(header, strconv.Itoa(i+1))
+		}
+	} else {
+		header, records = records[0], records[1:]
+		seen := make(map[string]bool, len(header))
+		for _, key := range header {
+			if seen[key] {
+				return nil, fmt.Errorf("column %q is in the header twice", key)
+			}
+			seen[key] = true
+		}
+	}
+
+	rows := make([]interface{}, 0, len(records))
+	for i, record := range records {
+		row := orderedmap.New[string, interface{}]()
+		for j, cell := range record {
+			row.Set(header[j], opts.inferCell(cell))
+		}
+
+		if opts.Flatten != "" {
+			if row, err = Unflatten(row, opts.Flatten); err != nil {
+				return nil, fmt.Errorf("row %d: %w", i+1, err)
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func (opts CSVOptions) inferCell(cell string) interface{} {
+	switch {
+	case opts.EmptyNull && cell == "":
+		return nil
+	case opts.InferBools && (cell == "true" || cell == "false"):
+		return cell == "true"
+	case opts.InferNumbers && isJSONNumber(cell):
+		if number, err := strconv.ParseFloat(cell, 64); err == nil {
+			return number
+		}
+	}
+
+	return cell
+}
+
+// whether text is a number the way json writes them. strconv also takes things like Inf, 0x1p3
+// and 1_000 that shouldn't turn into numbers.
+func isJSONNumber(text string) bool {
+	digits := strings.TrimPrefix(text, "-")
+	if digits == "" || digits[0] < '0' || digits[0] > '9' {
+		return false
+	}
+
+	for i := 0; i < len(digits); i++ {
+		if strings.IndexByte("0123456789.eE+-", digits[i]) < 0 {
+			return false
+		}
+	}
+
+	_, err := strconv.ParseFloat(text, 64)
+	return err == nil && numberProblem(text) == ""
+}
@@ -0,0 +1,95 @@
+package orderedjson
+
+import (
+	"testing"
+)
+
+func TestToCSV(t *testing.T) {
+	tests := []struct {
+		input string
+		opts  CSVOptions
+		want  string
+		// a bad input gives an error instead
+		fails bool
+	}{
+		{`{"rows": [{"b": 1, "a": "x"}, {"a": "y", "b": 2}]}`, CSVOptions{}, "b,a\n1,x\n2,y\n", false},
+		{`{"rows": [{"a": 1}, {"c": true, "a": null}]}`, CSVOptions{}, "a,c\n1,\n,true\n", false},
+		{`{"rows": [{"a": 1}, {"c": true, "a": null}]}`, CSVOptions{Columns: StrictColumns}, "", true},
+		{`{"rows": [{"a": 1, "b": 2}, {"a": 3}]}`, CSVOptions{Columns: StrictColumns}, "", true},
+		{`{"rows": [{"a": "x,y", "b": "say \"hi\""}]}`, CSVOptions{}, "a,b\n\"x,y\",\"say \"\"hi\"\"\"\n", false},
+		{`{"rows": [{"a": {"y": 1, "x": [2]}}]}`, CSVOptions{}, "a\n\"{\"\"y\"\":1,\"\"x\"\":[2]}\"\n", false},
+		{`{"rows": [{"a": {"y": 1, "x": [2]}}]}`, CSVOptions{Flatten: "."}, "a.y,a.x.0\n1,2\n", false},
+		{`{"rows": [{"a": 1, "b": 2}]}`, CSVOptions{Comma: '\t', NoHeader: true}, "1\t2\n", false},
+		{`{"rows": [{"a": 1}, 2]}`, CSVOptions{}, "", true},
+		{`{"rows": []}`, CSVOptions{}, "", false},
+	}
+
+	for _, test := range tests {
+		tree, err := ParseOptions{}.Parse([]byte(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rows, _ := tree.Get("rows")
+		got, err := ToCSV(rows.([]interface{}), test.opts)
+		if (err != nil) != test.fails {
+			t.Errorf("ToCSV(%s, %+v): %v", test.input, test.opts, err)
+			continue
+		}
+
+		if string(got) != test.want {
+			t.Errorf("ToCSV(%s, %+v) = %q, want %q", test.input, test.opts, got, test.want)
+		}
+	}
+}
+
+func TestFromCSV(t *testing.T) {
+	tests := []struct {
+		input string
+		opts  CSVOptions
+		// the rows, with MarshalCompact
+		want  string
+		fails bool
+	}{
+		{"b,a\n1,x\n", CSVOptions{}, `[{"b":"1","a":"x"}]`, false},
+		{"n,zip,ok,e\n1.5,007,true,\n", CSVOptions{InferNumbers: true, InferBools: true, EmptyNull: true}, `[{"n":1.5,"zip":"007","ok":true,"e":null}]`, false},
+		{"n,x\nInf,0x10\n", CSVOptions{InferNumbers: true}, `[{"n":"Inf","x":"0x10"}]`, false},
+		{"1,2\n3,4\n", CSVOptions{NoHeader: true}, `[{"1":"1","2":"2"},{"1":"3","2":"4"}]`, false},
+		{"a.y,a.x\n1,2\n", CSVOptions{Flatten: "."}, `[{"a":{"y":"1","x":"2"}}]`, false},
+		{"a\tb\nx y\tz\n", CSVOptions{Comma: '\t'}, `[{"a":"x y","b":"z"}]`, false},
+		{"a,a\n1,2\n", CSVOptions{}, "", true},
+		{"a,b\n1\n", CSVOptions{}, "", true},
+		{"", CSVOptions{}, `[]`, false},
+	}
+
+	for _, test := range tests {
+		rows, err := FromCSV([]byte(test.input), test.opts)
+		if (err != nil) != test.fails {
+			t.Errorf("FromCSV(%q, %+v): %v", test.input, test.opts, err)
+			continue
+		}
+
+		if test.fails {
+			continue
+		}
+
+		got, err := MarshalCompact(rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != test.want {
+			t.Errorf("FromCSV(%q, %+v) = %s, want %s", test.input, test.opts, got, test.want)
+		}
+
+		// and back, when nothing was inferred
+		if test.opts.InferNumbers || test.opts.EmptyNull || test.input == "" {
+			continue
+		}
+
+		back, err := ToCSV(rows, test.opts)
+		if err != nil || string(back) != test.input {
+			t.Errorf("ToCSV(FromCSV(%q)) = %q, %v", test.input, back, err)
+		}
+	}
+}
@@ -0,0 +1,683 @@
+package orderedjson
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// DecodeHook converts a value of the tree before it's decoded into a go value of type to, e.g. a
+// string into a time.Duration. it returns value unchanged when it has nothing to do. the decoder
+// takes whatever it returns, so a hook can return a value of type to, or another tree value for the
+// decoder to carry on with.
+type DecodeHook func(value interface{}, to reflect.Type) (interface{}, error)
+
+// WithHook runs hook on every value before decoding it, after the hooks added before it.
+func WithHook(hook DecodeHook) DecodeOption {
+	return decodeOption(func(config *decodeConfig) {
+		config.hooks = append(config.hooks, hook)
+	})
+}
+
+// DecodeInto converts a tree (or any value in one) into target, which has to be a non-nil pointer.
+// it works like json.Unmarshal: struct fields are matched by their json tag or name, ignoring case,
+// and keys without a field are skipped. the difference is that there's no json text in between, and
+// hooks can convert values on the way like mapstructure does for viper.
+//
+// fields of type *JsonObject, interface{} or Typed keep the order of their keys, so only maps and
+// structs lose it. a *JsonObject field tagged `json:",remain"` gets the keys that no other field
+// took, in their order, so they can be written back with EncodeTree. options for parsing are an
+// error, the tree has been parsed already.
+func DecodeInto(tree interface{}, target interface{}, opts ...DecodeOption) error {
+	hooks, err := decodeHooks("DecodeInto", opts)
+	if err != nil {
+		return err
+	}
+
+	return decodeInto(tree, target, hooks)
+}
+
+// the hooks of opts, for decoding a tree that's already parsed
+func decodeHooks(name string, opts []DecodeOption) ([]DecodeHook, error) {
+	config := newDecodeConfig(ParseOptions{}, opts)
+	if config.parsing {
+		return nil, fmt.Errorf("%s: options for parsing don't apply to a tree that's already parsed", name)
+	}
+
+	return config.hooks, nil
+}
+
+func decodeInto(tree interface{}, target interface{}, hooks []DecodeHook) error {
+	pointer := reflect.ValueOf(target)
+	if pointer.Kind() != reflect.Pointer || pointer.IsNil() {
+		return fmt.Errorf("DecodeInto: target must be a non-nil pointer, got %T", target)
+	}
+
+	decoder := &treeDecoder{hooks: hooks}
+
+	return decoder.decode(nil, tree, pointer.Elem())
+}
+
+type treeDecoder struct {
+	hooks []DecodeHook
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func (decoder *treeDecoder) decode(path Path, value interface{}, target reflect.Value) error {
+	for _, hook := range decoder.hooks {
+		converted, err := hook(value, target.Type())
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+
+		value = converted
+	}
+
+	if target.CanAddr() {
+		if optional, ok := target.Addr().Interface().(optionalDecoder); ok {
+			return optional.decodeOptional(value, func(value interface{}, target reflect.Value) error {
+				return decoder.decode(path, value, target)
+			})
+		}
+	}
+
+	if value != nil && reflect.TypeOf(value).AssignableTo(target.Type()) {
+		target.Set(reflect.ValueOf(value))
+		return nil
+	}
+
+	if target.Kind() != reflect.Pointer && target.Kind() != reflect.Interface && target.CanAddr() {
+		handled, err := decoder.unmarshaler(path, value, target.Addr())
+		if handled {
+			return err
+		}
+	}
+
+	mismatch := func() error {
+		return &UnmarshalTypeError{Value: TypeName(value), Type: target.Type(), Path: path}
+	}
+
+	switch target.Kind() {
+	case reflect.Interface:
+		// values that could be assigned were above
+		if value != nil {
+			return mismatch()
+		}
+
+		target.SetZero()
+	case reflect.Pointer:
+		if value == nil {
+			target.SetZero()
+			return nil
+		}
+
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+
+		return decoder.decode(path, value, target.Elem())
+	case reflect.Struct:
+		object, ok := value.(*JsonObject)
+		if !ok {
+			return mismatch()
+		}
+
+		return decoder.decodeStruct(path, object, target)
+	case reflect.Map:
+		object, ok := value.(*JsonObject)
+		if value == nil {
+			target.SetZero()
+			return nil
+		}
+
+		if !ok || target.Type().Key().Kind() != reflect.String {
+			return mismatch()
+		}
+
+		if target.IsNil() {
+			target.Set(reflect.MakeMapWithSize(target.Type(), object.Len()))
+		}
+
+		for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+			element := reflect.New(target.Type().Elem()).Elem()
+			if err := decoder.decode(path.Child(pair.Key), pair.Value, element); err != nil {
+				return err
+			}
+
+			target.SetMapIndex(reflect.ValueOf(pair.Key).Convert(target.Type().Key()), element)
+		}
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if value == nil {
+			target.SetZero()
+			return nil
+		}
+
+		if !ok {
+			return mismatch()
+		}
+
+		slice := reflect.MakeSlice(target.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decoder.decode(path.Child(i), item, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+
+		target.Set(slice)
+	case reflect.Array:
+		items, ok := value.([]interface{})
+		if !ok || len(items) > target.Len() {
+			return mismatch()
+		}
+
+		target.SetZero()
+		for i, item := range items {
+			if err := decoder.decode(path.Child(i), item, target.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			return mismatch()
+		}
+
+		target.SetString(s)
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return mismatch()
+		}
+
+		target.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := intValue(value)
+		if !ok || target.OverflowInt(n) {
+			return mismatch()
+		}
+
+		target.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := uintValue(value)
+		if !ok || target.OverflowUint(n) {
+			return mismatch()
+		}
+
+		target.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, ok := floatValue(value)
+		if !ok || target.OverflowFloat(n) {
+			return mismatch()
+		}
+
+		target.SetFloat(n)
+	default:
+		return mismatch()
+	}
+
+	return nil
+}
+
+// a number of the tree (a float64, or a json.Number with NumberJSONNumber) as an int64, if it's a
+// whole number that fits in one
+func intValue(value interface{}) (int64, bool) {
+	switch n := value.(type) {
+	case float64:
+		// -2^63 is the smallest int64, 2^63 is one past the biggest
+		if n != math.Trunc(n) || n < math.MinInt64 || n >= -math.MinInt64 {
+			return 0, false
+		}
+
+		return int64(n), true
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+
+	return 0, false
+}
+
+// a number of the tree as a uint64, if it's a whole number that fits in one
+func uintValue(value interface{}) (uint64, bool) {
+	switch n := value.(type) {
+	case float64:
+		if n != math.Trunc(n) || n < 0 || n >= math.MaxUint64 {
+			return 0, false
+		}
+
+		return uint64(n), true
+	case json.Number:
+		u, err := strconv.ParseUint(string(n), 10, 64)
+		return u, err == nil
+	}
+
+	return 0, false
+}
+
+// a number of the tree as a float64, if it's in range of one
+func floatValue(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+// whether value is a whole number, like json schema's integer. json.Numbers past the range of a
+// float64 are too, as long as they're written without a fraction or exponent.
+func isInteger(value interface{}) bool {
+	if n, ok := value.(json.Number); ok && !strings.ContainsAny(string(n), ".eE") {
+		return true
+	}
+
+	n, ok := floatValue(value)
+	return ok && n == math.Trunc(n) && !math.IsInf(n, 0)
+}
+
+// decodes with the target's own UnmarshalJSON or UnmarshalText, if it has one
+func (decoder *treeDecoder) unmarshaler(path Path, value interface{}, pointer reflect.Value) (bool, error) {
+	if pointer.Type().Implements(jsonUnmarshalerType) {
+		data, err := marshalValue(value)
+		if err != nil {
+			return true, fmt.Errorf("%s: %v", path, err)
+		}
+
+		if err := pointer.Interface().(json.Unmarshaler).UnmarshalJSON([]byte(data)); err != nil {
+			return true, fmt.Errorf("%s: %v", path, err)
+		}
+
+		return true, nil
+	}
+
+	if s, ok := value.(string); ok && pointer.Type().Implements(textUnmarshalerType) {
+		if err := pointer.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return true, fmt.Errorf("%s: %v", path, err)
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (decoder *treeDecoder) decodeStruct(path Path, object *JsonObject, target reflect.Value) error {
+	fields := structFields(target.Type())
+	var remain *JsonObject
+	if index, ok := remainField(target.Type()); ok {
+		remain = orderedmap.New[string, interface{}]()
+		target.Field(index).Set(reflect.ValueOf(remain))
+	}
+
+	present := make(map[string]bool, object.Len())
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		field, ok := fields.lookup(pair.Key)
+		if ok {
+			present[field.name] = true
+		}
+
+		if !ok {
+			if remain != nil {
+				remain.Set(pair.Key, pair.Value)
+			}
+
+			continue
+		}
+
+		value := pair.Value
+		if field.quoted && value != nil {
+			s, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("%s: ,string field needs a string, got %s", path.Child(pair.Key), TypeName(value))
+			}
+
+			unquoted, err := FromStdJSONValue([]byte(s))
+			if err != nil {
+				return fmt.Errorf("%s: invalid ,string value %q", path.Child(pair.Key), s)
+			}
+
+			value = unquoted
+		}
+
+		if err := decoder.decode(path.Child(pair.Key), value, fieldByIndex(target, field.index)); err != nil {
+			return err
+		}
+	}
+
+	for _, field := range fields {
+		if present[field.name] {
+			continue
+		}
+
+		fieldValue := fieldByIndex(target, field.index)
+		if field.hasDefault {
+			if err := decoder.decodeDefault(path.Child(field.name), field.defaultValue, fieldValue); err != nil {
+				return err
+			}
+		} else if fieldValue.Kind() == reflect.Struct && hasDefaults(fieldValue.Type()) {
+			// the defaults of a nested struct apply even when its key is missing
+			if err := decoder.decodeStruct(path.Child(field.name), orderedmap.New[string, interface{}](), fieldValue); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// whether t or a struct in it has a `default:"..."` field
+func hasDefaults(t reflect.Type) bool {
+	for _, field := range structFields(t) {
+		fieldType := t.FieldByIndex(field.index).Type
+		if field.hasDefault || fieldType.Kind() == reflect.Struct && fieldType != t && hasDefaults(fieldType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decodes the `default:"..."` of a missing key: as json if it is json and that fits the field, so
+// default:"8080" is a number and default:"[1, 2]" an array, and as a string otherwise, so
+// default:"8080" works for a string too and default:"30s" for a time.Duration with
+// StringToDurationHook. the default of an Optional makes it set, and a pointer gets allocated for it.
+func (decoder *treeDecoder) decodeDefault(path Path, text string, target reflect.Value) error {
+	candidate := reflect.New(target.Type()).Elem()
+	if value, err := FromStdJSONValue([]byte(text)); err == nil && decoder.decode(path, value, candidate) == nil {
+		target.Set(candidate)
+		return nil
+	}
+
+	candidate = reflect.New(target.Type()).Elem()
+	if err := decoder.decode(path, text, candidate); err != nil {
+		return fmt.Errorf("invalid default %q: %w", text, err)
+	}
+
+	target.Set(candidate)
+	return nil
+}
+
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	// tagged ",string": a number, boolean or string written as a json string
+	quoted bool
+	// the `default:"..."` tag, for when the key is missing
+	defaultValue string
+	hasDefault   bool
+}
+
+type fieldList []structField
+
+// the field for key: an exact match, or else one that only differs in case like encoding/json does
+func (fields fieldList) lookup(key string) (structField, bool) {
+	for _, field := range fields {
+		if field.name == key {
+			return field, true
+		}
+	}
+
+	for _, field := range fields {
+		if strings.EqualFold(field.name, key) {
+			return field, true
+		}
+	}
+
+	return structField{}, false
+}
+
+// the exported fields of a struct type and their json names, with the fields of embedded structs
+// without a tag, and of struct fields tagged `json:",inline"` like yaml.v3 has it, in place of the
+// struct. like encoding/json, a name at a shallower depth hides the same name further in, and names
+// repeated at the same depth are all left out.
+func structFields(t reflect.Type) fieldList {
+	var fields fieldList
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || hasTagOption(tag, "remain") || field.Type == jsonObjectType && hasTagOption(tag, "inline") {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		embedded := field.Type
+		if embedded.Kind() == reflect.Pointer {
+			embedded = embedded.Elem()
+		}
+
+		inline := field.Anonymous && name == "" || field.IsExported() && hasTagOption(tag, "inline")
+		if inline && embedded.Kind() == reflect.Struct {
+			for _, inner := range structFields(embedded) {
+				inner.index = append([]int{i}, inner.index...)
+				fields = append(fields, inner)
+			}
+
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		quoted := false
+		if hasTagOption(tag, "string") {
+			switch embedded.Kind() {
+			case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+				reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+				quoted = true
+			}
+		}
+
+		defaultValue, hasDefault := field.Tag.Lookup("default")
+		fields = append(fields, structField{
+			name:         name,
+			index:        []int{i},
+			omitEmpty:    hasTagOption(tag, "omitempty"),
+			quoted:       quoted,
+			defaultValue: defaultValue,
+			hasDefault:   hasDefault,
+		})
+	}
+
+	return dominantFields(fields)
+}
+
+func dominantFields(fields fieldList) fieldList {
+	depths := make(map[string][]int)
+	for _, field := range fields {
+		depths[field.name] = append(depths[field.name], len(field.index))
+	}
+
+	result := fields[:0]
+	for _, field := range fields {
+		shallowest, count := len(field.index), 0
+		for _, depth := range depths[field.name] {
+			if depth < shallowest {
+				shallowest, count = depth, 0
+			}
+
+			if depth == shallowest {
+				count++
+			}
+		}
+
+		if shallowest == len(field.index) && count == 1 {
+			result = append(result, field)
+		}
+	}
+
+	return result
+}
+
+var jsonObjectType = reflect.TypeOf((*JsonObject)(nil))
+
+// the *JsonObject field of a struct type tagged `json:",remain"`, for the keys without a field.
+// ",inline" works too, since that's how yaml.v3 spells it for maps.
+func remainField(t reflect.Type) (int, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if field.IsExported() && field.Type == jsonObjectType && (hasTagOption(tag, "remain") || hasTagOption(tag, "inline")) {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// whether a json tag like "name,omitempty" has option after its name
+func hasTagOption(tag, option string) bool {
+	_, options, _ := strings.Cut(tag, ",")
+	for options != "" {
+		var current string
+		current, options, _ = strings.Cut(options, ",")
+		if current == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+// the field at index, allocating the embedded pointers on the way
+func fieldByIndex(target reflect.Value, index []int) reflect.Value {
+	for i, position := range index {
+		if i > 0 && target.Kind() == reflect.Pointer {
+			if target.IsNil() {
+				target.Set(reflect.New(target.Type().Elem()))
+			}
+
+			target = target.Elem()
+		}
+
+		target = target.Field(position)
+	}
+
+	return target
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	ipType       = reflect.TypeOf(net.IP{})
+)
+
+// StringToDurationHook decodes strings like "1m30s" into time.Duration fields.
+func StringToDurationHook(value interface{}, to reflect.Type) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok || to != durationType {
+		return value, nil
+	}
+
+	return time.ParseDuration(s)
+}
+
+// StringToIPHook decodes strings like "10.0.0.1" or "::1" into net.IP fields.
+func StringToIPHook(value interface{}, to reflect.Type) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok || to != ipType {
+		return value, nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", s)
+	}
+
+	return ip, nil
+}
+
+// StringToTimeHook decodes strings in layout into time.Time fields. without it they have to be
+// RFC 3339.
+func StringToTimeHook(layout string) DecodeHook {
+	timeType := reflect.TypeOf(time.Time{})
+	return func(value interface{}, to reflect.Type) (interface{}, error) {
+		s, ok := value.(string)
+		if !ok || to != timeType {
+			return value, nil
+		}
+
+		return time.Parse(layout, s)
+	}
+}
+
+// WeaklyTyped is the opt-in weak decoding mode: WithHook(WeaklyTypedHook), for APIs that aren't
+// careful about their types.
+func WeaklyTyped() DecodeOption {
+	return WithHook(WeaklyTypedHook)
+}
+
+// WeaklyTypedHook converts between strings, numbers and booleans when that's what the field wants,
+// e.g. "42" into an int, 1 into a bool or 8080 into a string, and wraps a single value in an array
+// for slice fields. it's for config written by hand, and for env variables which are all strings.
+func WeaklyTypedHook(value interface{}, to reflect.Type) (interface{}, error) {
+	switch to.Kind() {
+	case reflect.String:
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case json.Number:
+			return string(v), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+	case reflect.Bool:
+		switch v := value.(type) {
+		case float64:
+			return v != 0, nil
+		case json.Number:
+			f, err := v.Float64()
+			return f != 0, err
+		case string:
+			if v == "" {
+				return false, nil
+			}
+
+			return strconv.ParseBool(v)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		switch v := value.(type) {
+		case bool:
+			if v {
+				return 1.0, nil
+			}
+
+			return 0.0, nil
+		case string:
+			if v == "" {
+				return 0.0, nil
+			}
+
+			return strconv.ParseFloat(strings.TrimSpace(v), 64)
+		}
+	case reflect.Slice:
+		if _, ok := value.([]interface{}); !ok && value != nil && to.Elem().Kind() != reflect.Uint8 {
+			return []interface{}{value}, nil
+		}
+	}
+
+	return value, nil
+}
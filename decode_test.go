@@ -0,0 +1,127 @@
+package orderedjson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeIntoNumbers(t *testing.T) {
+	type numbers struct {
+		Int   int64
+		Small int8
+		Uint  uint64
+		Float float32
+	}
+
+	tests := []struct {
+		input string
+		want  numbers
+		// whether it doesn't fit
+		mismatch bool
+	}{
+		{`{"Int": 9007199254740993}`, numbers{Int: 9007199254740993}, false},
+		{`{"Int": -9223372036854775808}`, numbers{Int: -9223372036854775808}, false},
+		{`{"Int": 9223372036854775808}`, numbers{}, true},
+		{`{"Int": 1.5}`, numbers{}, true},
+		{`{"Small": 127}`, numbers{Small: 127}, false},
+		{`{"Small": 128}`, numbers{}, true},
+		{`{"Uint": 18446744073709551615}`, numbers{Uint: 18446744073709551615}, false},
+		{`{"Uint": -1}`, numbers{}, true},
+		{`{"Float": 1.5}`, numbers{Float: 1.5}, false},
+		{`{"Float": 1e39}`, numbers{}, true},
+	}
+
+	for _, mode := range []NumberMode{NumberFloat64, NumberJSONNumber} {
+		for _, test := range tests {
+			// float64 can't hold these exactly
+			if mode == NumberFloat64 && (test.want.Int == 9007199254740993 || test.want.Uint != 0) {
+				continue
+			}
+
+			tree, err := Parse([]byte(test.input), WithNumberMode(mode))
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", test.input, err)
+			}
+
+			var got numbers
+			err = DecodeInto(tree, &got)
+			var mismatch *UnmarshalTypeError
+			if errors.As(err, &mismatch) != test.mismatch {
+				t.Errorf("number mode %d: DecodeInto(%s) = %v, want a mismatch: %v", mode, test.input, err, test.mismatch)
+				continue
+			}
+
+			if !test.mismatch && got != test.want {
+				t.Errorf("number mode %d: DecodeInto(%s) = %+v, want %+v", mode, test.input, got, test.want)
+			}
+		}
+	}
+}
+
+func TestDecodeIntoParseOptions(t *testing.T) {
+	tree, err := Parse([]byte(`{"a": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var target map[string]int
+	for _, opt := range []DecodeOption{WithMaxDepth(3), WithNumberMode(NumberJSONNumber), WithComments(), WithLenient(nil)} {
+		if err := DecodeInto(tree, &target, opt); err == nil {
+			t.Errorf("DecodeInto with %T: no error for an option for parsing", opt)
+		}
+	}
+
+	if err := DecodeInto(tree, &target, WeaklyTyped()); err != nil || !reflect.DeepEqual(target, map[string]int{"a": 1}) {
+		t.Errorf("DecodeInto with a hook = %v, %v", target, err)
+	}
+
+	// UnmarshalTagged parses and decodes, it takes both
+	type shape struct{ Type string }
+	types := map[string]func() interface{}{"circle": func() interface{} { return &shape{} }}
+	value, _, err := UnmarshalTagged([]byte(`{"type": "circle"}`), "type", types, WithMaxDepth(3), WeaklyTyped())
+	if err != nil || value.(*shape).Type != "circle" {
+		t.Errorf("UnmarshalTagged = %v, %v", value, err)
+	}
+}
+
+func TestEncodeTreeNumbers(t *testing.T) {
+	type numbers struct {
+		Int   int64
+		Small int8
+		Uint  uint64
+		Float float64
+	}
+
+	tests := []struct {
+		value numbers
+		want  string
+	}{
+		{numbers{Int: 9007199254740993}, `{"Int":9007199254740993,"Small":0,"Uint":0,"Float":0}`},
+		{numbers{Int: -9223372036854775808, Small: -128}, `{"Int":-9223372036854775808,"Small":-128,"Uint":0,"Float":0}`},
+		{numbers{Uint: 18446744073709551615, Float: 1.5}, `{"Int":0,"Small":0,"Uint":18446744073709551615,"Float":1.5}`},
+	}
+
+	for _, test := range tests {
+		tree, err := EncodeTree(test.value)
+		if err != nil {
+			t.Errorf("EncodeTree(%+v): %v", test.value, err)
+			continue
+		}
+
+		got, err := MarshalCompact(tree)
+		if err != nil {
+			t.Errorf("MarshalCompact(EncodeTree(%+v)): %v", test.value, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("EncodeTree(%+v) = %s, want %s", test.value, got, test.want)
+		}
+
+		var back numbers
+		if err := DecodeInto(tree.(*JsonObject), &back); err != nil || back != test.value {
+			t.Errorf("DecodeInto(EncodeTree(%+v)) = %+v (%v)", test.value, back, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package orderedjson
+
+// ChangeKind says what happened to a value between two versions of a tree.
+type ChangeKind string
+
+const (
+	ChangeAdd     ChangeKind = "add"
+	ChangeRemove  ChangeKind = "remove"
+	ChangeReplace ChangeKind = "replace"
+	// the key is still there, but somewhere else in its object
+	ChangeMove ChangeKind = "move"
+)
+
+type Change struct {
+	Kind ChangeKind
+	Path Path
+	// Old is nil for additions and New is nil for removals
+	Old interface{}
+	New interface{}
+}
+
+// Diff compares two trees and returns what changed, in document order. unlike comparing the trees
+// as maps, it also reports keys that moved within their object.
+func Diff(old, new interface{}) []Change {
+	return diff(Path{}, old, new, nil)
+}
+
+func diff(path Path, old, new interface{}, changes []Change) []Change {
+	oldObject, oldIsObject := old.(*JsonObject)
+	newObject, newIsObject := new.(*JsonObject)
+	if oldIsObject && newIsObject {
+		return diffObjects(path, oldObject, newObject, changes)
+	}
+
+	oldArray, oldIsArray := old.([]interface{})
+	newArray, newIsArray := new.([]interface{})
+	if oldIsArray && newIsArray {
+		for i := 0; i < len(oldArray) || i < len(newArray); i++ {
+			switch {
+			case i >= len(newArray):
+				changes = append(changes, Change{Kind: ChangeRemove, Path: path.Child(i), Old: oldArray[i]})
+			case i >= len(oldArray):
+				changes = append(changes, Change{Kind: ChangeAdd, Path: path.Child(i), New: newArray[i]})
+			default:
+				changes = diff(path.Child(i), oldArray[i], newArray[i], changes)
+			}
+		}
+
+		return changes
+	}
+
+	if oldIsObject || newIsObject || oldIsArray || newIsArray || old != new {
+		changes = append(changes, Change{Kind: ChangeReplace, Path: path, Old: old, New: new})
+	}
+
+	return changes
+}
+
+func diffObjects(path Path, old, new *JsonObject, changes []Change) []Change {
+	// the keys both versions have, in each version's order
+	var oldCommon, newCommon []string
+	for pair := old.Oldest(); pair != nil; pair = pair.Next() {
+		if _, present := new.Get(pair.Key); present {
+			oldCommon = append(oldCommon, pair.Key)
+		}
+	}
+
+	for pair := new.Oldest(); pair != nil; pair = pair.Next() {
+		if _, present := old.Get(pair.Key); present {
+			newCommon = append(newCommon, pair.Key)
+		}
+	}
+
+	// the longest run of keys that kept their relative order stays put, the rest moved
+	stayed := longestCommonSubsequence(oldCommon, newCommon)
+
+	for pair := old.Oldest(); pair != nil; pair = pair.Next() {
+		if _, present := new.Get(pair.Key); !present {
+			changes = append(changes, Change{Kind: ChangeRemove, Path: path.Child(pair.Key), Old: pair.Value})
+		}
+	}
+
+	for pair := new.Oldest(); pair != nil; pair = pair.Next() {
+		oldValue, present := old.Get(pair.Key)
+		if !present {
+			changes = append(changes, Change{Kind: ChangeAdd, Path: path.Child(pair.Key), New: pair.Value})
+			continue
+		}
+
+		if !stayed[pair.Key] {
+			changes = append(changes, Change{Kind: ChangeMove, Path: path.Child(pair.Key), Old: oldValue, New: pair.Value})
+		}
+
+		changes = diff(path.Child(pair.Key), oldValue, pair.Value, changes)
+	}
+
+	return changes
+}
+
+func longestCommonSubsequence(a, b []string) map[string]bool {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	result := make(map[string]bool)
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			result[a[i]] = true
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,76 @@
+package orderedjson
+
+import (
+	"strings"
+	"testing"
+)
+
+// the changes as "kind path" pairs, with the values of replacements
+func describeChanges(changes []Change) string {
+	var result []string
+	for _, change := range changes {
+		description := string(change.Kind) + " " + change.Path.String()
+		if change.Kind == ChangeReplace {
+			old, _ := MarshalCompact(change.Old)
+			new, _ := MarshalCompact(change.New)
+			description += " " + old + " -> " + new
+		}
+
+		result = append(result, description)
+	}
+
+	return strings.Join(result, ", ")
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		old  string
+		new  string
+		want string
+	}{
+		{`{"a":1,"b":[1,2]}`, `{"a":1,"b":[1,2]}`, ``},
+		{`{"a":1}`, `{"a":2}`, `replace .a 1 -> 2`},
+		{`{"a":1,"b":2}`, `{"b":2,"c":3}`, `remove .a, add .c`},
+		{`{"l":[1,2,3]}`, `{"l":[1,5]}`, `replace .l[1] 2 -> 5, remove .l[2]`},
+		{`{"l":[1]}`, `{"l":[1,{}]}`, `add .l[1]`},
+		{`{"o":{"x":1}}`, `{"o":[1]}`, `replace .o {"x":1} -> [1]`},
+		{`{"o":{}}`, `{"o":{}}`, ``},
+		{`{"o":null}`, `{"o":{}}`, `replace .o null -> {}`},
+
+		// moves: the longest run of keys that kept their order stays, the others moved
+		{`{"a":1,"b":2}`, `{"b":2,"a":1}`, `move .a`},
+		{`{"a":1,"b":2,"c":3}`, `{"c":3,"a":1,"b":2}`, `move .c`},
+		{`{"a":1,"b":2,"c":3,"d":4}`, `{"d":4,"c":3,"b":2,"a":1}`, `move .c, move .b, move .a`},
+		{`{"a":1,"b":2,"c":3}`, `{"b":2,"a":5,"c":3}`, `move .a, replace .a 1 -> 5`},
+		{`{"a":1,"x":0,"b":2}`, `{"b":2,"y":0,"a":1}`, `remove .x, add .y, move .a`},
+		{`{"o":{"p":1,"q":2}}`, `{"o":{"q":2,"p":1}}`, `move .o.p`},
+		{`{"l":[{"p":1,"q":2}]}`, `{"l":[{"q":2,"p":1}]}`, `move .l[0].p`},
+	}
+
+	for _, test := range tests {
+		old, err := ParseOptions{}.Parse([]byte(test.old))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		new, err := ParseOptions{}.Parse([]byte(test.new))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := describeChanges(Diff(old, new)); got != test.want {
+			t.Errorf("Diff(%s, %s) = %s, want %s", test.old, test.new, got, test.want)
+		}
+	}
+
+	// a move carries both values
+	old, _ := ParseOptions{}.Parse([]byte(`{"a":1,"b":2}`))
+	new, _ := ParseOptions{}.Parse([]byte(`{"b":2,"a":1}`))
+	if changes := Diff(old, new); len(changes) != 1 || changes[0].Old != 1.0 || changes[0].New != 1.0 || changes[0].Path.String() != ".a" {
+		t.Errorf("Diff = %+v", changes)
+	}
+
+	if got := describeChanges(Diff(1.0, "x")); got != `replace . 1 -> "x"` {
+		t.Errorf("Diff(1, x) = %s", got)
+	}
+}
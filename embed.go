@@ -0,0 +1,126 @@
+package orderedjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GenerateEmbed returns go source declaring varName as the ordered tree, so a default config can be
+// compiled into a binary with its key order. the source builds the tree when the package is
+// initialized, without anything to parse at run time.
+//
+// with lazy, the source holds the document as compact json instead, and varName is a function that
+// parses it the first time it's called. that keeps big documents out of the init time and the
+// binary's code, since it's just a string.
+func GenerateEmbed(packageName, varName string, tree *JsonObject, lazy bool) ([]byte, error) {
+	if !token.IsIdentifier(packageName) {
+		return nil, fmt.Errorf("invalid package name %q", packageName)
+	}
+
+	if !token.IsIdentifier(varName) {
+		return nil, fmt.Errorf("invalid variable name %q", varName)
+	}
+
+	var source strings.Builder
+	fmt.Fprintf(&source, "// Code generated by ordered-json embed. DO NOT EDIT.\n\npackage %s\n\n", packageName)
+
+	if lazy {
+		compact, err := MarshalCompact(tree)
+		if err != nil {
+			return nil, err
+		}
+
+		first, size := utf8.DecodeRuneInString(varName)
+		rawName := string(unicode.ToLower(first)) + varName[size:] + "JSON"
+
+		source.WriteString("import (\n\"sync\"\n\n\"github.com/michaelhelvey/orderedjson/v2/compat\"\norderedmap \"github.com/wk8/go-ordered-map/v2\"\n)\n\n")
+		fmt.Fprintf(&source, "const %s = %s\n\n", rawName, strconv.Quote(compact))
+		fmt.Fprintf(&source, "// %s parses the embedded document the first time it's called, and returns the same\n// tree after that.\n", varName)
+		fmt.Fprintf(&source, "var %s = sync.OnceValue(func() *orderedmap.OrderedMap[string, interface{}] {\n", varName)
+		source.WriteString("tree := orderedmap.New[string, interface{}]()\n")
+		fmt.Fprintf(&source, "if err := compat.Unmarshal([]byte(%s), tree); err != nil {\n", rawName)
+		source.WriteString("// it was checked when it was generated\npanic(err)\n}\n\nreturn tree\n})\n")
+	} else {
+		var value strings.Builder
+		numbers := false
+		if err := writeGoValue(&value, tree, &numbers); err != nil {
+			return nil, err
+		}
+
+		if numbers {
+			source.WriteString("import (\n\"encoding/json\"\n\norderedmap \"github.com/wk8/go-ordered-map/v2\"\n)\n\n")
+		} else {
+			source.WriteString("import orderedmap \"github.com/wk8/go-ordered-map/v2\"\n\n")
+		}
+
+		fmt.Fprintf(&source, "var %s = %s\n", varName, value.String())
+	}
+
+	return format.Source([]byte(source.String()))
+}
+
+// writes value as a go expression of the same tree value. numbers is set when it has a json.Number,
+// which needs encoding/json.
+func writeGoValue(source *strings.Builder, value interface{}, numbers *bool) error {
+	switch v := value.(type) {
+	case *JsonObject:
+		source.WriteString("orderedmap.New[string, interface{}](")
+		if v.Len() > 0 {
+			source.WriteString("orderedmap.WithInitialData(\n")
+			for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+				fmt.Fprintf(source, "orderedmap.Pair[string, interface{}]{Key: %s, Value: ", strconv.Quote(pair.Key))
+				if err := writeGoValue(source, pair.Value, numbers); err != nil {
+					return err
+				}
+
+				source.WriteString("},\n")
+			}
+
+			source.WriteString(")")
+		}
+
+		source.WriteString(")")
+	case []interface{}:
+		source.WriteString("[]interface{}{")
+		for _, item := range v {
+			source.WriteString("\n")
+			if err := writeGoValue(source, item, numbers); err != nil {
+				return err
+			}
+
+			source.WriteString(",")
+		}
+
+		if len(v) > 0 {
+			source.WriteString("\n")
+		}
+
+		source.WriteString("}")
+	case string:
+		source.WriteString(strconv.Quote(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("can't embed %v", v)
+		}
+
+		fmt.Fprintf(source, "float64(%s)", strconv.FormatFloat(v, 'g', -1, 64))
+	case json.Number:
+		*numbers = true
+		fmt.Fprintf(source, "json.Number(%s)", strconv.Quote(string(v)))
+	case bool:
+		source.WriteString(strconv.FormatBool(v))
+	case nil:
+		source.WriteString("nil")
+	default:
+		return fmt.Errorf("can't embed a %T", value)
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package orderedjson
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGenerateEmbed(t *testing.T) {
+	tests := []struct {
+		input   string
+		numbers NumberMode
+		imports []string
+	}{
+		{`{"z":1,"a":{"y":[true,null,"é\n\"q\""],"b":{},"c":[]},"f":-0.5e-10}`, NumberFloat64, []string{"github.com/wk8/go-ordered-map/v2"}},
+		{`{"big":12345678901234567891,"list":[1.50e2,[{}]]}`, NumberJSONNumber, []string{"encoding/json", "github.com/wk8/go-ordered-map/v2"}},
+		{`{}`, NumberFloat64, []string{"github.com/wk8/go-ordered-map/v2"}},
+	}
+
+	for _, test := range tests {
+		tree, err := ParseOptions{Numbers: test.numbers}.Parse([]byte(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, lazy := range []bool{false, true} {
+			source, err := GenerateEmbed("config", "Defaults", tree, lazy)
+			if err != nil {
+				t.Errorf("GenerateEmbed(%s, lazy %v): %v", test.input, lazy, err)
+				continue
+			}
+
+			// gofmt wouldn't change it
+			if formatted, err := format.Source(source); err != nil || string(formatted) != string(source) {
+				t.Errorf("GenerateEmbed(%s, lazy %v) isn't gofmt'ed:\n%s", test.input, lazy, source)
+			}
+
+			file, err := parser.ParseFile(token.NewFileSet(), "embed.go", source, parser.ParseComments)
+			if err != nil {
+				t.Errorf("GenerateEmbed(%s, lazy %v) doesn't parse: %v\n%s", test.input, lazy, err, source)
+				continue
+			}
+
+			if file.Name.Name != "config" || !strings.HasPrefix(string(source), "// Code generated by ordered-json embed. DO NOT EDIT.\n") {
+				t.Errorf("GenerateEmbed(%s, lazy %v) = package %s\n%s", test.input, lazy, file.Name.Name, source)
+			}
+
+			var imports []string
+			for _, spec := range file.Imports {
+				path, _ := strconv.Unquote(spec.Path.Value)
+				imports = append(imports, path)
+			}
+
+			want := test.imports
+			if lazy {
+				want = []string{"sync", "github.com/michaelhelvey/orderedjson/v2/compat", "github.com/wk8/go-ordered-map/v2"}
+			}
+
+			if strings.Join(imports, " ") != strings.Join(want, " ") {
+				t.Errorf("GenerateEmbed(%s, lazy %v) imports %v, want %v", test.input, lazy, imports, want)
+			}
+
+			// the declarations, and with lazy the document as a constant
+			var names []string
+			for _, decl := range file.Decls {
+				if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok != token.IMPORT {
+					for _, spec := range decl.Specs {
+						value := spec.(*ast.ValueSpec)
+						names = append(names, decl.Tok.String()+" "+value.Names[0].Name)
+						if decl.Tok == token.CONST {
+							document, _ := strconv.Unquote(value.Values[0].(*ast.BasicLit).Value)
+							if compact, _ := MarshalCompact(tree); document != compact {
+								t.Errorf("GenerateEmbed(%s, lazy) embeds %s", test.input, document)
+							}
+						}
+					}
+				}
+			}
+
+			wantNames := "var Defaults"
+			if lazy {
+				wantNames = "const defaultsJSON var Defaults"
+			}
+
+			if strings.Join(names, " ") != wantNames {
+				t.Errorf("GenerateEmbed(%s, lazy %v) declares %v, want %s", test.input, lazy, names, wantNames)
+			}
+		}
+	}
+
+	tree, _ := ParseOptions{}.Parse([]byte(`{"a":1}`))
+	for _, names := range [][2]string{{"", "V"}, {"my-pkg", "V"}, {"config", "1v"}, {"config", "a b"}, {"func", "V"}} {
+		if source, err := GenerateEmbed(names[0], names[1], tree, false); err == nil {
+			t.Errorf("GenerateEmbed(%q, %q) =\n%s\nwant an error", names[0], names[1], source)
+		}
+	}
+
+	tree.Set("nan", math.NaN())
+	if source, err := GenerateEmbed("config", "Defaults", tree, false); err == nil {
+		t.Errorf("GenerateEmbed(NaN) =\n%s\nwant an error", source)
+	}
+}
@@ -0,0 +1,203 @@
+package orderedjson
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// EncodeTree converts a go value into a tree value, the other way around from DecodeInto. it works
+// like json.Marshal: struct fields are written in the order they're declared under their json tag
+// or name, omitempty leaves out empty ones, and map keys are sorted. values with their own
+// MarshalJSON or MarshalText use it. integers become json.Numbers with all their digits, floats
+// float64s.
+//
+// the keys of a *JsonObject field tagged `json:",remain"` come after the other fields in their own
+// order, so a struct decoded by DecodeInto gets the keys it doesn't know about back where they were
+// relative to each other.
+func EncodeTree(value interface{}) (interface{}, error) {
+	return encodeValue(nil, reflect.ValueOf(value))
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func encodeValue(path Path, value reflect.Value) (interface{}, error) {
+	if !value.IsValid() {
+		return nil, nil
+	}
+
+	if (value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface) && value.IsNil() {
+		return nil, nil
+	}
+
+	switch v := value.Interface().(type) {
+	case optionalEncoder:
+		inner, _ := v.encodeOptional()
+		return encodeValue(path, inner)
+	case *JsonObject:
+		return v, nil
+	case json.Marshaler:
+		raw, err := v.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+
+		return FromStdJSONValue(raw)
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+
+		return string(text), nil
+	}
+
+	// values whose pointer has the method, like encoding/json does for addressable ones
+	if value.CanAddr() && (value.Addr().Type().Implements(jsonMarshalerType) || value.Addr().Type().Implements(textMarshalerType)) {
+		return encodeValue(path, value.Addr())
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return encodeValue(path, value.Elem())
+	case reflect.Bool:
+		return value.Bool(), nil
+	case reflect.String:
+		return value.String(), nil
+	// integers are json.Numbers, since a float64 can't hold the ones above 2^53
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Number(strconv.FormatInt(value.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return json.Number(strconv.FormatUint(value.Uint(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), nil
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil, nil
+		}
+
+		if value.Type().Elem().Kind() == reflect.Uint8 && value.Kind() == reflect.Slice {
+			return base64.StdEncoding.EncodeToString(value.Bytes()), nil
+		}
+
+		result := make([]interface{}, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			item, err := encodeValue(path.Child(i), value.Index(i))
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, item)
+		}
+
+		return result, nil
+	case reflect.Map:
+		if value.IsNil() {
+			return nil, nil
+		}
+
+		if value.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%s: can't encode a map with %s keys", path, value.Type().Key())
+		}
+
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+		result := orderedmap.New[string, interface{}]()
+		for _, key := range keys {
+			item, err := encodeValue(path.Child(key.String()), value.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+
+			result.Set(key.String(), item)
+		}
+
+		return result, nil
+	case reflect.Struct:
+		return encodeStruct(path, value)
+	}
+
+	return nil, fmt.Errorf("%s: can't encode a %s", path, value.Type())
+}
+
+func encodeStruct(path Path, value reflect.Value) (interface{}, error) {
+	result := orderedmap.New[string, interface{}]()
+	for _, field := range structFields(value.Type()) {
+		fieldValue, ok := existingField(value, field.index)
+		if !ok || field.omitEmpty && isEmptyValue(fieldValue) {
+			continue
+		}
+
+		if optional, ok := fieldValue.Interface().(optionalEncoder); ok {
+			if _, present := optional.encodeOptional(); !present {
+				continue
+			}
+		}
+
+		item, err := encodeValue(path.Child(field.name), fieldValue)
+		if err != nil {
+			return nil, err
+		}
+
+		if field.quoted && item != nil {
+			if item, err = marshalValue(item); err != nil {
+				return nil, err
+			}
+		}
+
+		result.Set(field.name, item)
+	}
+
+	if index, ok := remainField(value.Type()); ok {
+		if remain, _ := value.Field(index).Interface().(*JsonObject); remain != nil {
+			for pair := remain.Oldest(); pair != nil; pair = pair.Next() {
+				// a field wins over a key it has taken over
+				if _, taken := result.Get(pair.Key); !taken {
+					result.Set(pair.Key, pair.Value)
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// what omitempty leaves out: false, 0, nil, and empty strings, slices, arrays and maps. structs are
+// never empty, like in encoding/json.
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Struct:
+		return false
+	}
+
+	return value.IsZero()
+}
+
+// the field at index, if the embedded pointers on the way to it aren't nil
+func existingField(value reflect.Value, index []int) (reflect.Value, bool) {
+	for i, position := range index {
+		if i > 0 && value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				return reflect.Value{}, false
+			}
+
+			value = value.Elem()
+		}
+
+		value = value.Field(position)
+	}
+
+	return value, true
+}
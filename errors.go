@@ -0,0 +1,86 @@
+package orderedjson
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+)
+
+// the kinds of syntax errors, for errors.Is. the errors themselves are *SyntaxError, which also say
+// where the problem is.
+var (
+	// the document ends in the middle of a value
+	ErrUnexpectedEOF = errors.New("unexpected end of input")
+	// something other than what json allows at that point
+	ErrInvalidToken = errors.New("invalid token")
+	// a backslash escape in a string that json doesn't have, or a lone surrogate
+	ErrInvalidEscape = errors.New("invalid escape")
+	// malformed UTF-8 with InvalidUTF8Error
+	ErrInvalidUTF8 = errors.New("invalid UTF-8")
+	// objects and arrays nested deeper than ParseOptions.MaxDepth
+	ErrTooDeep = errors.New("nested too deeply")
+)
+
+// SyntaxError is a problem with the json text, and where it is. errors.Is matches it against the
+// Err* kind it is.
+type SyntaxError struct {
+	// what's wrong, without the position
+	Msg string
+	// the byte offset (after any byte order mark), and the 1 based line and column in characters
+	Offset, Line, Column int
+
+	kind error
+}
+
+func (err *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at line %d, column %d", err.Msg, err.Line, err.Column)
+}
+
+func (err *SyntaxError) Unwrap() error {
+	return err.kind
+}
+
+func newSyntaxError(data []byte, offset int, kind error, format string, args ...interface{}) *SyntaxError {
+	line, column := lineColumn(data, offset)
+	return &SyntaxError{Msg: fmt.Sprintf(format, args...), Offset: offset, Line: line, Column: column, kind: kind}
+}
+
+// UnmarshalTypeError is a json value that doesn't fit the go type it's decoded into.
+type UnmarshalTypeError struct {
+	// the kind of json value: object, array, string, number, boolean or null
+	Value string
+	Type  reflect.Type
+	// where the value is, empty for the whole document
+	Path Path
+}
+
+func (err *UnmarshalTypeError) Error() string {
+	if len(err.Path) == 0 {
+		return fmt.Sprintf("cannot unmarshal %s into %s", err.Value, err.Type)
+	}
+
+	return fmt.Sprintf("cannot unmarshal %s at %s into %s", err.Value, err.Path, err.Type)
+}
+
+// the 1 based line and column (in characters) of a byte offset into data
+func lineColumn(data []byte, offset int) (int, int) {
+	if offset > len(data) {
+		offset = len(data)
+	}
+
+	line, column := 1, 1
+	for i := 0; i < offset; {
+		char, size := utf8.DecodeRune(data[i:])
+		if char == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+
+		i += size
+	}
+
+	return line, column
+}
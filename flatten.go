@@ -0,0 +1,119 @@
+package orderedjson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// Flatten returns a single level object with a key for every leaf value in tree, made by joining the
+// keys on the way down with sep, e.g. {"scripts": {"build": "x"}} becomes {"scripts.build": "x"}.
+// array elements use their index as the key. empty objects and arrays are kept as they are, since
+// there's nothing in them to flatten.
+func Flatten(tree *JsonObject, sep string) *JsonObject {
+	result := orderedmap.New[string, interface{}]()
+	flatten(result, "", tree, sep)
+	return result
+}
+
+func flatten(result *JsonObject, prefix string, value interface{}, sep string) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+
+		return prefix + sep + key
+	}
+
+	switch v := value.(type) {
+	case *JsonObject:
+		if v.Len() == 0 && prefix != "" {
+			result.Set(prefix, v)
+			return
+		}
+
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			flatten(result, join(pair.Key), pair.Value, sep)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			result.Set(prefix, v)
+			return
+		}
+
+		for i, item := range v {
+			flatten(result, join(strconv.Itoa(i)), item, sep)
+		}
+	default:
+		result.Set(prefix, value)
+	}
+}
+
+// Unflatten reverses Flatten, splitting every key on sep and nesting the values. objects whose keys
+// are exactly 0, 1, 2... turn back into arrays. it fails if a key is used both for a value and as
+// the prefix of other keys, like "a" and "a.b".
+func Unflatten(flat *JsonObject, sep string) (*JsonObject, error) {
+	result := orderedmap.New[string, interface{}]()
+	for pair := flat.Oldest(); pair != nil; pair = pair.Next() {
+		parts := strings.Split(pair.Key, sep)
+		object := result
+		for i, part := range parts[:len(parts)-1] {
+			existing, present := object.Get(part)
+			if !present {
+				child := orderedmap.New[string, interface{}]()
+				object.Set(part, child)
+				object = child
+				continue
+			}
+
+			child, ok := existing.(*JsonObject)
+			if !ok {
+				return nil, fmt.Errorf("key %q conflicts with %q", pair.Key, strings.Join(parts[:i+1], sep))
+			}
+
+			object = child
+		}
+
+		last := parts[len(parts)-1]
+		if _, present := object.Get(last); present {
+			return nil, fmt.Errorf("key %q conflicts with another key", pair.Key)
+		}
+
+		object.Set(last, pair.Value)
+	}
+
+	// the top level stays an object even if its keys look like indices
+	for pair := result.Oldest(); pair != nil; pair = pair.Next() {
+		pair.Value = restoreArrays(pair.Value)
+	}
+
+	return result, nil
+}
+
+func restoreArrays(value interface{}) interface{} {
+	object, ok := value.(*JsonObject)
+	if !ok {
+		return value
+	}
+
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		pair.Value = restoreArrays(pair.Value)
+	}
+
+	if object.Len() == 0 {
+		return object
+	}
+
+	array := make([]interface{}, 0, object.Len())
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		if pair.Key != strconv.Itoa(len(array)) {
+			return object
+		}
+
+		array = append(array, pair.Value)
+	}
+
+	return array
+}
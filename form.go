@@ -0,0 +1,183 @@
+package orderedjson
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// ToForm encodes tree as application/x-www-form-urlencoded, the way PHP and Rails nest values:
+// {"a": {"b": ["x"]}} becomes a[b][0]=x. parameters are written in key order. null is written as
+// an empty value, and empty objects and arrays are left out, since there's no way to write them.
+func ToForm(tree *JsonObject) (string, error) {
+	var parts []string
+	for pair := tree.Oldest(); pair != nil; pair = pair.Next() {
+		var err error
+		if parts, err = formPairs(parts, pair.Key, pair.Value); err != nil {
+			return "", err
+		}
+	}
+
+	return strings.Join(parts, "&"), nil
+}
+
+func formPairs(parts []string, key string, value interface{}) ([]string, error) {
+	var text string
+	switch v := value.(type) {
+	case *JsonObject:
+		var err error
+		for pair := v.Oldest(); pair != nil && err == nil; pair = pair.Next() {
+			parts, err = formPairs(parts, key+"["+pair.Key+"]", pair.Value)
+		}
+
+		return parts, err
+	case []interface{}:
+		var err error
+		for i := 0; i < len(v) && err == nil; i++ {
+			parts, err = formPairs(parts, key+"["+strconv.Itoa(i)+"]", v[i])
+		}
+
+		return parts, err
+	case nil:
+	case string:
+		text = v
+	default:
+		var err error
+		if text, err = marshalValue(value); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	return append(parts, url.QueryEscape(key)+"="+url.QueryEscape(text)), nil
+}
+
+// FromForm decodes an application/x-www-form-urlencoded query (with or without the leading ?) into
+// an object with the parameters in the order they came in. bracketed keys nest: a[b]=x makes an
+// object, a[0]=x and a[]=x make arrays. a key without brackets that shows up more than once
+// becomes an array of its values. every value is a string.
+func FromForm(query string) (*JsonObject, error) {
+	result := orderedmap.New[string, interface{}]()
+	for _, part := range strings.Split(strings.TrimPrefix(query, "?"), "&") {
+		if part == "" {
+			continue
+		}
+
+		rawKey, rawValue, _ := strings.Cut(part, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+
+		path := formKeyPath(key)
+		existing, ok := result.Get(path[0])
+		placed, err := placeFormValue(existing, ok, path[1:], value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+
+		result.Set(path[0], placed)
+	}
+
+	return result, nil
+}
+
+// splits a[b][0] into a, b and 0. a key that isn't a name followed by brackets is just a name.
+func formKeyPath(key string) []string {
+	open := strings.IndexByte(key, '[')
+	if open <= 0 {
+		return []string{key}
+	}
+
+	path := []string{key[:open]}
+	for rest := key[open:]; rest != ""; {
+		end := strings.IndexByte(rest, ']')
+		if rest[0] != '[' || end < 0 {
+			return []string{key}
+		}
+
+		path = append(path, rest[1:end])
+		rest = rest[end+1:]
+	}
+
+	return path
+}
+
+// the value for a slot holding current (when exists) after putting value at path under it
+func placeFormValue(current interface{}, exists bool, path []string, value string) (interface{}, error) {
+	if len(path) == 0 {
+		switch v := current.(type) {
+		case nil:
+			if !exists {
+				return value, nil
+			}
+		case string:
+			return []interface{}{v, value}, nil
+		case []interface{}:
+			return append(v, value), nil
+		}
+
+		return nil, fmt.Errorf("is %s, and also has a value", TypeName(current))
+	}
+
+	segment, rest := path[0], path[1:]
+	if index, isIndex := formIndex(segment); isIndex {
+		var array []interface{}
+		if exists {
+			var ok bool
+			if array, ok = current.([]interface{}); !ok {
+				return nil, fmt.Errorf("[%s] needs an array, but it's %s", segment, TypeName(current))
+			}
+		}
+
+		if segment == "" {
+			index = len(array)
+		}
+
+		switch {
+		case index > len(array):
+			return nil, fmt.Errorf("[%d] skips elements, there are only %d", index, len(array))
+		case index == len(array):
+			placed, err := placeFormValue(nil, false, rest, value)
+			return append(array, placed), err
+		}
+
+		placed, err := placeFormValue(array[index], true, rest, value)
+		array[index] = placed
+		return array, err
+	}
+
+	object := orderedmap.New[string, interface{}]()
+	if exists {
+		var ok bool
+		if object, ok = current.(*JsonObject); !ok {
+			return nil, fmt.Errorf("[%s] needs an object, but it's %s", segment, TypeName(current))
+		}
+	}
+
+	existing, ok := object.Get(segment)
+	placed, err := placeFormValue(existing, ok, rest, value)
+	object.Set(segment, placed)
+	return object, err
+}
+
+// whether a bracketed segment is an array index: empty (append) or a number without leading zeros
+func formIndex(segment string) (int, bool) {
+	if segment == "" {
+		return 0, true
+	}
+
+	if len(segment) > 9 || (segment[0] == '0' && len(segment) > 1) {
+		return 0, false
+	}
+
+	index, err := strconv.Atoi(segment)
+	return index, err == nil && index >= 0 && segment[0] != '+'
+}
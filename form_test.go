@@ -0,0 +1,107 @@
+package orderedjson
+
+import (
+	"testing"
+)
+
+func TestToForm(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{"z":"1","a":"x y&z=é"}`, `z=1&a=x+y%26z%3D%C3%A9`},
+		{`{"user":{"name":"a","tags":["x","y"]},"n":1.5,"t":true,"none":null}`, `user%5Bname%5D=a&user%5Btags%5D%5B0%5D=x&user%5Btags%5D%5B1%5D=y&n=1.5&t=true&none=`},
+		{`{"l":[{"a":"1"},{"a":"2","b":"3"}]}`, `l%5B0%5D%5Ba%5D=1&l%5B1%5D%5Ba%5D=2&l%5B1%5D%5Bb%5D=3`},
+		{`{"empty":{},"list":[],"a":"1"}`, `a=1`},
+		{`{}`, ``},
+	}
+
+	for _, test := range tests {
+		tree, err := ParseOptions{}.Parse([]byte(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got, err := ToForm(tree); err != nil || got != test.want {
+			t.Errorf("ToForm(%s) = %s, %v, want %s", test.input, got, err, test.want)
+		}
+	}
+}
+
+func TestFromForm(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`z=1&a=x+y%26z%3D%C3%A9`, `{"z":"1","a":"x y&z=é"}`},
+		{`?a=1&&b=&c`, `{"a":"1","b":"","c":""}`},
+		{`user[name]=a&user[tags][0]=x&user[tags][1]=y`, `{"user":{"name":"a","tags":["x","y"]}}`},
+		{`user%5Bname%5D=a&user%5Bage%5D=3`, `{"user":{"name":"a","age":"3"}}`},
+		{`a[]=x&a[]=y&b[z]=1&b[y]=2&a[]=w`, `{"a":["x","y","w"],"b":{"z":"1","y":"2"}}`},
+		{`l[0][a]=1&l[1][a]=2&l[1][b]=3&l[0][c]=4`, `{"l":[{"a":"1","c":"4"},{"a":"2","b":"3"}]}`},
+		{`a[b][c][d]=deep`, `{"a":{"b":{"c":{"d":"deep"}}}}`},
+
+		// repeated keys without brackets collect their values
+		{`tag=a&other=1&tag=b&tag=c`, `{"tag":["a","b","c"],"other":"1"}`},
+		{`a[x]=1&a[x]=2`, `{"a":{"x":["1","2"]}}`},
+
+		// keys that only look like brackets, and segments that aren't indexes
+		{`[a]=1&a]=2&a[b=3&a[b]c=4`, `{"[a]":"1","a]":"2","a[b":"3","a[b]c":"4"}`},
+		{`a[01]=x&a[-1]=y&a[ 1]=z`, `{"a":{"01":"x","-1":"y"," 1":"z"}}`},
+		{``, `{}`},
+	}
+
+	for _, test := range tests {
+		tree, err := FromForm(test.query)
+		if err != nil {
+			t.Errorf("FromForm(%s): %v", test.query, err)
+			continue
+		}
+
+		if got, _ := MarshalCompact(tree); got != test.want {
+			t.Errorf("FromForm(%s) = %s, want %s", test.query, got, test.want)
+		}
+	}
+
+	for _, query := range []string{`a=%zz`, `%zz=1`, `a[2]=x`, `a[0]=x&a[2]=y`, `a=1&a[b]=2`, `a[b]=1&a[0]=2`, `a[0]=1&a[b]=2`, `a[b]=1&a=2`, `a[]=1&a[0][b]=2`} {
+		if tree, err := FromForm(query); err == nil {
+			got, _ := MarshalCompact(tree)
+			t.Errorf("FromForm(%s) = %s, want an error", query, got)
+		}
+	}
+}
+
+func TestFormRoundTrip(t *testing.T) {
+	for _, input := range []string{
+		`{"z":"1","a":"x y&z=é"}`,
+		`{"user":{"name":"a","tags":["x","y"]},"l":[{"a":"1"},{"a":"2","b":"3"}]}`,
+		`{"a":{"b":{"c":["1",["2","3"]]}}}`,
+	} {
+		tree, err := ParseOptions{}.Parse([]byte(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		query, err := ToForm(tree)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		back, err := FromForm(query)
+		if err != nil {
+			t.Errorf("FromForm(ToForm(%s)): %v", input, err)
+			continue
+		}
+
+		if got, _ := MarshalCompact(back); got != input {
+			t.Errorf("FromForm(ToForm(%s)) = %s", input, got)
+		}
+	}
+
+	// brackets in a key can't be told apart from nesting
+	tree, _ := ParseOptions{}.Parse([]byte(`{"k[x]":"v"}`))
+	query, _ := ToForm(tree)
+	if back, err := FromForm(query); err != nil || back.Value("k").(*JsonObject).Value("x") != "v" {
+		t.Errorf("FromForm(%s) = %v, %v", query, back, err)
+	}
+}
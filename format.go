@@ -0,0 +1,95 @@
+package orderedjson
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/michaelhelvey/orderedjson/v2/compression"
+)
+
+// FormatOptions are the settings of the formatter. the zero value is the standard profile: two space
+// indentation, one key or array element per line, ": " after keys, empty objects and arrays as {}
+// and [], strings written the way encoding/json writes them (without its html escaping), numbers
+// kept exactly as they were written, no trailing spaces and a newline at the end. its output won't
+// change between versions, so like gofmt, formatting with it gives the same bytes on every machine.
+type FormatOptions struct {
+	// indentation per level, two spaces when empty
+	Indent string
+	// leaves out the newline at the end of the document
+	NoFinalNewline bool
+	// keeps objects and arrays that fit in this many characters on one line, see
+	// MarshalOptions.MaxWidth. the standard profile breaks all of them.
+	MaxWidth int
+	// writes the comments in Meta, see MarshalOptions.Comments
+	Comments bool
+	// the metadata of the tree being formatted, like Document.Meta
+	Meta *Metadata
+}
+
+// Format parses data and returns it pretty printed with indent, ending in a newline.
+func Format(data []byte, indent string) ([]byte, error) {
+	return FormatOptions{Indent: indent}.Format(data)
+}
+
+// FormatStandard formats data with the standard profile, see FormatOptions.
+func FormatStandard(data []byte) ([]byte, error) {
+	return FormatOptions{}.Format(data)
+}
+
+func (opts FormatOptions) Format(data []byte) ([]byte, error) {
+	tree, err := ParseOptions{Numbers: NumberJSONNumber}.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return opts.FormatTree(tree)
+}
+
+// FormatTree is Format for a tree that's already parsed.
+func (opts FormatOptions) FormatTree(tree *JsonObject) ([]byte, error) {
+	indent := opts.Indent
+	if indent == "" {
+		indent = "  "
+	}
+
+	formatted, err := MarshalOptions{Indent: indent, MaxWidth: opts.MaxWidth, Comments: opts.Comments, Meta: opts.Meta}.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.NoFinalNewline {
+		formatted += "\n"
+	}
+
+	return []byte(formatted), nil
+}
+
+// WriteFile writes tree to the file at path with the standard profile, compressed when path ends in
+// .gz, or the extension of a codec added with compression.Register. .zst needs one, it isn't built
+// in.
+func WriteFile(path string, tree *JsonObject) error {
+	return FormatOptions{}.WriteFile(path, tree)
+}
+
+func (opts FormatOptions) WriteFile(path string, tree *JsonObject) error {
+	formatted, err := opts.FormatTree(tree)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	w, err := compression.NewWriter(&out, path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(formatted); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, out.Bytes(), 0644)
+}
@@ -0,0 +1,27 @@
+package orderedjson
+
+import "testing"
+
+func TestFormatStandard(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{`{"a":1.0}`, "{\n  \"a\": 1.0\n}\n"},
+		{`{"a":1E2,"b":-0.50e-3}`, "{\n  \"a\": 1E2,\n  \"b\": -0.50e-3\n}\n"},
+		{`{"id":100000000000000000000000}`, "{\n  \"id\": 100000000000000000000000\n}\n"},
+		{`{"a":[1.10,{"b":2.000}]}`, "{\n  \"a\": [\n    1.10,\n    {\n      \"b\": 2.000\n    }\n  ]\n}\n"},
+		{`{"engines":{"node":">=20 <22"},"s":"a & b"}`, "{\n  \"engines\": {\n    \"node\": \">=20 <22\"\n  },\n  \"s\": \"a & b\"\n}\n"},
+	}
+
+	for _, test := range tests {
+		got, err := FormatStandard([]byte(test.input))
+		if err != nil {
+			t.Errorf("FormatStandard(%q): %v", test.input, err)
+			continue
+		}
+
+		if string(got) != test.want {
+			t.Errorf("FormatStandard(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/michaelhelvey/orderedjson/v2/bson"
+	"github.com/michaelhelvey/orderedjson/v2/cbor"
+	"github.com/michaelhelvey/orderedjson/v2/hcl"
+	"github.com/michaelhelvey/orderedjson/v2/msgpack"
+	"github.com/michaelhelvey/orderedjson/v2/smile"
+	"github.com/michaelhelvey/orderedjson/v2/toml"
+)
+
+func init() {
+	// binary formats go first, their first bytes are never the start of a text format
+	smileCodec := OneDocument(smile.Decode, smile.Encode)
+	smileCodec.Extensions = []string{".sml", ".smile"}
+	smileCodec.Sniff = func(head []byte) bool {
+		return bytes.HasPrefix(head, []byte(":)\n"))
+	}
+	Register("smile", smileCodec)
+
+	Register("bson", Codec{
+		Extensions: []string{".bson"},
+		Sniff:      isBSONHead,
+		Decode:     decodeBSON,
+		Encode: func(w io.Writer, value interface{}, index int) error {
+			tree, err := Object(value)
+			if err != nil {
+				return err
+			}
+
+			data, err := bson.Encode(tree)
+			if err == nil {
+				_, err = w.Write(data)
+			}
+			return err
+		},
+	})
+
+	msgpackCodec := OneDocument(msgpack.Decode, msgpack.Encode)
+	msgpackCodec.Extensions = []string{".msgpack", ".mpk"}
+	msgpackCodec.Sniff = func(head []byte) bool {
+		// maps
+		return len(head) > 0 && (head[0] >= 0x80 && head[0] <= 0x8f || head[0] == 0xde || head[0] == 0xdf)
+	}
+	Register("msgpack", msgpackCodec)
+
+	cborCodec := OneDocument(cbor.Decode, cbor.Encode)
+	cborCodec.Extensions = []string{".cbor"}
+	cborCodec.Sniff = func(head []byte) bool {
+		// maps, or the self-described cbor tag
+		return len(head) > 0 && (head[0] >= 0xa0 && head[0] <= 0xbb || head[0] == 0xbf) || bytes.HasPrefix(head, []byte{0xd9, 0xd9, 0xf7})
+	}
+	Register("cbor", cborCodec)
+
+	tomlCodec := OneDocument(decodeObject(toml.Decode), encodeObject(toml.Encode))
+	tomlCodec.Extensions = []string{".toml"}
+	Register("toml", tomlCodec)
+
+	hclCodec := OneDocument(decodeObject(hcl.Decode), encodeObject(func(tree *JsonObject) ([]byte, error) {
+		return hcl.Encode(tree, hcl.EncodeOptions{})
+	}))
+	hclCodec.Extensions = []string{".hcl", ".tf"}
+	Register("hcl", hclCodec)
+
+	registerText()
+}
+
+// adapts the decoder of a format that always gives an object for OneDocument
+func decodeObject(decode func(data []byte) (*JsonObject, error)) func(data []byte) (interface{}, error) {
+	return func(data []byte) (interface{}, error) {
+		return decode(data)
+	}
+}
+
+func encodeObject(encode func(tree *JsonObject) ([]byte, error)) func(value interface{}) ([]byte, error) {
+	return func(value interface{}) ([]byte, error) {
+		tree, err := Object(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return encode(tree)
+	}
+}
+
+// whether head starts like a bson document: a length that isn't more than what's there (or what
+// a document can be), ending in the document's 0 byte
+func isBSONHead(head []byte) bool {
+	if len(head) < 5 {
+		return false
+	}
+
+	length := int(int32(binary.LittleEndian.Uint32(head)))
+	return length >= 5 && length <= 16<<20 && (length > len(head) || head[length-1] == 0) && head[4] != 0
+}
+
+// bson documents start with their length, so they can be read one at a time
+func decodeBSON(r io.Reader, each func(value interface{}) error) error {
+	for count := 1; ; count++ {
+		var length [4]byte
+		if _, err := io.ReadFull(r, length[:]); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("document %d: %w", count, err)
+		}
+
+		size := int32(binary.LittleEndian.Uint32(length[:]))
+		if size < 5 {
+			return fmt.Errorf("document %d: invalid document length %d", count, size)
+		}
+
+		document := make([]byte, size)
+		copy(document, length[:])
+		if _, err := io.ReadFull(r, document[4:]); err != nil {
+			return fmt.Errorf("document %d: %w", count, err)
+		}
+
+		tree, err := bson.Decode(document)
+		if err != nil {
+			return fmt.Errorf("document %d: %w", count, err)
+		}
+
+		if err := each(tree); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Package formats is the registry of the formats documents can be converted between. the convert
+// command reads and writes every format in it, and finds the format of a file from its extension or
+// its first bytes, so a program can add its own formats with Register without changing either.
+//
+// the formats of the orderedjson package (json, json streams, yaml, xml, csv and the others) and
+// the ones from the interop packages (bson, cbor, msgpack, smile, toml and hcl) are registered by
+// this package.
+package formats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type JsonObject = orderedmap.OrderedMap[string, interface{}]
+
+// Codec reads and writes one format. documents are the values of a tree: *JsonObject,
+// []interface{}, string, float64, json.Number (for integers past 2^53), bool or nil.
+type Codec struct {
+	// the file extensions of the format, with the dot, like ".yaml"
+	Extensions []string
+	// whether the start of an input (up to 512 bytes) is in this format, for inputs without an
+	// extension to go by, like stdin. nil doesn't match anything.
+	Sniff func(head []byte) bool
+	// calls each with every document in r, as they're read
+	Decode func(r io.Reader, each func(value interface{}) error) error
+	// writes the document at index, for formats where any number of documents can follow each
+	// other. formats that hold exactly one document, or need all of them to write any (like csv),
+	// set EncodeAll instead.
+	Encode    func(w io.Writer, value interface{}, index int) error
+	EncodeAll func(w io.Writer, values []interface{}) error
+}
+
+// OneDocument is a Codec for a format with one document in a file, from functions that convert the
+// whole file at once. the Extensions and Sniff still have to be set.
+func OneDocument(decode func(data []byte) (interface{}, error), encode func(value interface{}) ([]byte, error)) Codec {
+	return Codec{
+		Decode: func(r io.Reader, each func(value interface{}) error) error {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+
+			value, err := decode(data)
+			if err != nil {
+				return err
+			}
+
+			return each(value)
+		},
+		EncodeAll: func(w io.Writer, values []interface{}) error {
+			if len(values) != 1 {
+				return fmt.Errorf("can only hold one document, found %d", len(values))
+			}
+
+			data, err := encode(values[0])
+			if err == nil {
+				_, err = w.Write(data)
+			}
+			return err
+		},
+	}
+}
+
+// Object is the object in value, or an error saying the format needs one.
+func Object(value interface{}) (*JsonObject, error) {
+	tree, ok := value.(*JsonObject)
+	if !ok {
+		return nil, fmt.Errorf("needs an object, found %s", typeName(value))
+	}
+
+	return tree, nil
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case *JsonObject:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case float64, json.Number:
+		return "a number"
+	case bool:
+		return "a bool"
+	}
+
+	return "null"
+}
+
+var (
+	mu     sync.RWMutex
+	codecs = make(map[string]Codec)
+	// the names in the order they were registered, which is the order they're sniffed in
+	order []string
+)
+
+// Register adds a format, or replaces the one with the same name. it panics when the codec can't
+// read or write anything, since that's a mistake in the program.
+func Register(name string, codec Codec) {
+	if name == "" || codec.Decode == nil || codec.Encode == nil && codec.EncodeAll == nil {
+		panic(fmt.Sprintf("formats: %q needs a name, Decode and Encode or EncodeAll", name))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := codecs[name]; !ok {
+		order = append(order, name)
+	}
+	codecs[name] = codec
+}
+
+// Lookup returns the format called name.
+func Lookup(name string) (Codec, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	codec, ok := codecs[name]
+	return codec, ok
+}
+
+// Names returns the names of every format, sorted.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, len(order))
+	copy(names, order)
+	sort.Strings(names)
+
+	return names
+}
+
+// ForPath returns the format of the file at path from its extension.
+func ForPath(path string) (string, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return "", false
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, name := range order {
+		for _, extension := range codecs[name].Extensions {
+			if ext == extension {
+				return name, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// Sniff returns the first format (in the order they were registered) whose Sniff matches head.
+func Sniff(head []byte) (string, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, name := range order {
+		if sniff := codecs[name].Sniff; sniff != nil && sniff(head) {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// Detect returns the format of the file at path from its extension, or from head, the start of
+// its contents, when the extension doesn't say.
+func Detect(path string, head []byte) (string, bool) {
+	if name, ok := ForPath(path); ok {
+		return name, true
+	}
+
+	return Sniff(head)
+}
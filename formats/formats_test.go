@@ -0,0 +1,384 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/michaelhelvey/orderedjson/v2/internal/testtree"
+)
+
+func encode(codec Codec, values ...interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if codec.EncodeAll != nil {
+		err := codec.EncodeAll(&buf, values)
+		return buf.Bytes(), err
+	}
+
+	for i, value := range values {
+		if err := codec.Encode(&buf, value, i); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decode(codec Codec, data []byte) ([]interface{}, error) {
+	var values []interface{}
+	err := codec.Decode(bytes.NewReader(data), func(value interface{}) error {
+		values = append(values, value)
+		return nil
+	})
+
+	return values, err
+}
+
+func TestBuiltin(t *testing.T) {
+	names := Names()
+	for _, name := range []string{"bson", "cbor", "csv", "env", "form", "hcl", "json", "json-seq", "msgpack", "ndjson", "properties", "smile", "toml", "tsv", "xml", "yaml"} {
+		if _, ok := Lookup(name); !ok {
+			t.Errorf("Lookup(%s): not registered", name)
+		}
+	}
+
+	if !sortedStrings(names) {
+		t.Errorf("Names() = %v, want them sorted", names)
+	}
+
+}
+
+// a document converted from each format to the next, the way the convert command chains them,
+// comes back the same, with what the binary formats write recognized as them
+func TestConvert(t *testing.T) {
+	original := testtree.Object(t, `{"name":"ü水","tags":["a","b"],"nested":{"empty":{},"n":-0.5,"ok":false,"list":[[],{}]}}`)
+	original.Set("id", json.Number("9007199254740993"))
+	want := testtree.Marshal(t, original)
+
+	var value interface{} = original
+	previous := "json"
+	for _, name := range []string{"yaml", "toml", "hcl", "cbor", "msgpack", "smile", "json-seq", "ndjson", "json"} {
+		codec, _ := Lookup(name)
+		data, err := encode(codec, value)
+		if err != nil {
+			t.Fatalf("%s to %s: %v", previous, name, err)
+		}
+
+		if sniffed, ok := Sniff(data); codec.Sniff != nil && name != "yaml" && (!ok || sniffed != name) {
+			t.Errorf("Sniff(%s) = %s, %v", name, sniffed, ok)
+		}
+
+		values, err := decode(codec, data)
+		if err != nil || len(values) != 1 {
+			t.Fatalf("%s to %s: decode = %v, %v\n%s", previous, name, values, err, data)
+		}
+
+		if got := testtree.Marshal(t, values[0]); got != want {
+			t.Errorf("%s to %s = %s, want %s", previous, name, got, want)
+		}
+
+		value, previous = values[0], name
+	}
+
+	// relaxed extended json keeps integers that a float64 can't hold exactly as $numberLong, and
+	// toml has no null
+	bson, _ := Lookup("bson")
+	data, err := encode(bson, value)
+	if err != nil {
+		t.Fatalf("json to bson: %v", err)
+	}
+
+	if values, err := decode(bson, data); err != nil || len(values) != 1 || testtree.Marshal(t, values[0]) != strings.Replace(want, `9007199254740993`, `{"$numberLong":"9007199254740993"}`, 1) {
+		t.Errorf("json to bson = %v, %v", values, err)
+	}
+
+	toml, _ := Lookup("toml")
+	if data, err := encode(toml, testtree.Parse(t, `{"null":null}`)); err == nil {
+		t.Errorf("toml: encode(null) = %q, want an error", data)
+	}
+}
+
+func sortedStrings(names []string) bool {
+	for i := 1; i < len(names); i++ {
+		if names[i-1] > names[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestForPath(t *testing.T) {
+	tests := []struct {
+		path string
+		name string
+	}{
+		{"a.bson", "bson"},
+		{"a.cbor", "cbor"},
+		{"dir.toml/a.MSGPACK", "msgpack"},
+		{"a.mpk", "msgpack"},
+		{"a.sml", "smile"},
+		{"a.smile", "smile"},
+		{"a.toml", "toml"},
+		{"main.tf", "hcl"},
+		{"a.hcl", "hcl"},
+		{"a.json", "json"},
+		{"a.jsonl", "ndjson"},
+		{"a.json-seq", "json-seq"},
+		{"a.yml", "yaml"},
+		{"a.xml", "xml"},
+		{".env", "env"},
+		{"a.properties", "properties"},
+		{"a.csv", "csv"},
+		{"a.tsv", "tsv"},
+		{"a.unknown", ""},
+		{"toml", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		name, ok := ForPath(test.path)
+		if ok != (test.name != "") || name != test.name {
+			t.Errorf("ForPath(%q) = %q, %v, want %q", test.path, name, ok, test.name)
+		}
+
+		if name, ok := Detect(test.path, []byte(":)\n\x00")); test.name != "" && name != test.name || test.name == "" && (!ok || name != "smile") {
+			t.Errorf("Detect(%q, smile) = %q, %v", test.path, name, ok)
+		}
+	}
+}
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		head string
+		name string
+	}{
+		{":)\n\x00\xfa", "smile"},
+		{"\x05\x00\x00\x00\x00", ""},
+		{"\x0c\x00\x00\x00\x08a\x00\x01\x00", "bson"},
+		{"\x00\x10\x00\x00\x08", "bson"},
+		{"\x81\xa1a\x01", "msgpack"},
+		{"\xde\x00\x01", "msgpack"},
+		{"\xa1\x61a\x01", "cbor"},
+		{"\xbf\xff", "cbor"},
+		{"\xd9\xd9\xf7\xa0", "cbor"},
+		{`{"a": 1}`, "json"},
+		{"\ufeff \n{}", "json"},
+		{"\x1e{}", "json-seq"},
+		{"---\na: 1", "yaml"},
+		{"%YAML 1.2", "yaml"},
+		{"<a/>", "xml"},
+		{"[1]", ""},
+		{"a = 1", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		name, ok := Sniff([]byte(test.head))
+		if ok != (test.name != "") || name != test.name {
+			t.Errorf("Sniff(%q) = %q, %v, want %q", test.head, name, ok, test.name)
+		}
+	}
+}
+
+// the text formats, with more than one document where the format can hold them
+func TestText(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs []string
+		want   string
+		// what decoding gives back, when it isn't the inputs
+		decoded []string
+	}{
+		{"json", []string{`{"z":1,"a":[true,null]}`, `{"b":"x"}`}, "{\"z\": 1, \"a\": [true, null]}\n{\"b\": \"x\"}\n", nil},
+		{"ndjson", []string{`{"z":1,"a":[true,null]}`, `{"b":"x"}`}, "{\"z\":1,\"a\":[true,null]}\n{\"b\":\"x\"}\n", nil},
+		{"json-seq", []string{`{"z":1}`, `{"a":[2]}`}, "\x1e{\"z\":1}\n\x1e{\"a\":[2]}\n", nil},
+		{"yaml", []string{`{"z":1,"a":"x"}`, `{"b":[2]}`}, "z: 1\na: x\n---\nb:\n  - 2\n", nil},
+		{"xml", []string{`{"root":{"b":"1","a":"2"}}`}, "<root>\n  <b>1</b>\n  <a>2</a>\n</root>\n", nil},
+		{"form", []string{`{"b":"1","a":["x","y"]}`}, "b=1&a%5B0%5D=x&a%5B1%5D=y\n", nil},
+		// nested keys are written with the separator, and read back as they are
+		{"env", []string{`{"b":"1","a":{"c":"x y"}}`}, "b=1\na__c=\"x y\"\n", []string{`{"b":"1","a__c":"x y"}`}},
+		{"properties", []string{`{"b":"1","a":{"c":"x"}}`}, "b=1\na.c=x\n", []string{`{"b":"1","a.c":"x"}`}},
+		// csv comes back as one array of all the rows
+		{"csv", []string{`[{"b":"1","a":"x,y"}]`, `{"b":"2","a":"z"}`}, "b,a\n1,\"x,y\"\n2,z\n", []string{`[{"b":"1","a":"x,y"},{"b":"2","a":"z"}]`}},
+		{"tsv", []string{`[{"b":"1","a":"x"},{"b":"2","a":"z"}]`}, "b\ta\n1\tx\n2\tz\n", nil},
+	}
+
+	for _, test := range tests {
+		codec, _ := Lookup(test.name)
+		var values []interface{}
+		for _, input := range test.inputs {
+			values = append(values, testtree.Parse(t, input))
+		}
+
+		data, err := encode(codec, values...)
+		if err != nil || string(data) != test.want {
+			t.Errorf("%s: encode(%s) = %q, %v, want %q", test.name, test.inputs, data, err, test.want)
+			continue
+		}
+
+		decoded, err := decode(codec, data)
+		if err != nil {
+			t.Errorf("%s: decode(%q): %v", test.name, data, err)
+			continue
+		}
+
+		var got []string
+		for _, value := range decoded {
+			got = append(got, testtree.Marshal(t, value))
+		}
+
+		want := test.inputs
+		if test.decoded != nil {
+			want = test.decoded
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: decode(%q) = %s, want %s", test.name, data, got, want)
+		}
+	}
+}
+
+// bson documents are read one after the other
+func TestBSONStream(t *testing.T) {
+	codec, _ := Lookup("bson")
+	data, err := encode(codec, testtree.Parse(t, `{"a":1}`), testtree.Parse(t, `{"b":{"c":"d"}}`))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	values, err := decode(codec, data)
+	if err != nil || len(values) != 2 || testtree.Marshal(t, values[0]) != `{"a":1}` || testtree.Marshal(t, values[1]) != `{"b":{"c":"d"}}` {
+		t.Errorf("decode = %v, %v", values, err)
+	}
+
+	for _, input := range [][]byte{data[:3], data[:len(data)-1], {1, 0, 0, 0}, append(data[:12:12], 0xff, 0, 0, 0, 0)} {
+		if values, err := decode(codec, input); err == nil {
+			t.Errorf("decode(%x) = %v, want an error", input, values)
+		}
+	}
+
+	if _, err := decode(codec, data[:3]); err == nil || !strings.HasPrefix(err.Error(), "document 1: ") {
+		t.Errorf("decode(cut off) = %v, want the document number", err)
+	}
+}
+
+func TestOneDocument(t *testing.T) {
+	for _, name := range []string{"msgpack", "toml"} {
+		codec, _ := Lookup(name)
+		if data, err := encode(codec, testtree.Parse(t, `{"a":1}`), testtree.Parse(t, `{"b":2}`)); err == nil {
+			t.Errorf("%s: encode(two documents) = %q, want an error", name, data)
+		}
+
+		if data, err := encode(codec); err == nil {
+			t.Errorf("%s: encode(nothing) = %q, want an error", name, data)
+		}
+	}
+
+	for _, name := range []string{"bson", "toml", "hcl"} {
+		codec, _ := Lookup(name)
+		for _, value := range []interface{}{[]interface{}{1.0}, "s", 1.0, json.Number("1"), true, nil} {
+			if data, err := encode(codec, value); err == nil || !strings.HasPrefix(err.Error(), "needs an object, found ") {
+				t.Errorf("%s: encode(%#v) = %q, %v, want it to need an object", name, value, data, err)
+			}
+		}
+	}
+
+	if _, err := Object(json.Number("1")); err == nil || err.Error() != "needs an object, found a number" {
+		t.Errorf("Object(json.Number) = %v", err)
+	}
+
+	failing := OneDocument(func(data []byte) (interface{}, error) {
+		return nil, errors.New("no")
+	}, nil)
+	if _, err := decode(failing, nil); err == nil || err.Error() != "no" {
+		t.Errorf("decode(failing) = %v", err)
+	}
+}
+
+// a codec of a program's own, from outside of this package
+func TestRegister(t *testing.T) {
+	lines := Codec{
+		Extensions: []string{".lines"},
+		Sniff: func(head []byte) bool {
+			return bytes.HasPrefix(head, []byte("LINES\n"))
+		},
+		Decode: func(r io.Reader, each func(value interface{}) error) error {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+
+			for _, line := range strings.Split(strings.TrimPrefix(strings.TrimSuffix(string(data), "\n"), "LINES\n"), "\n") {
+				if err := each(line); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+		Encode: func(w io.Writer, value interface{}, index int) error {
+			if index == 0 {
+				io.WriteString(w, "LINES\n")
+			}
+
+			_, err := io.WriteString(w, value.(string)+"\n")
+			return err
+		},
+	}
+
+	Register("lines", lines)
+	data, err := encode(lines, "a", "b")
+	if err != nil || string(data) != "LINES\na\nb\n" {
+		t.Fatalf("encode = %q, %v", data, err)
+	}
+
+	if values, err := decode(lines, data); err != nil || !reflect.DeepEqual(values, []interface{}{"a", "b"}) {
+		t.Errorf("decode = %v, %v", values, err)
+	}
+
+	if name, ok := Detect("-", data); !ok || name != "lines" {
+		t.Errorf("Detect(-) = %s, %v", name, ok)
+	}
+
+	if name, ok := ForPath("a.LINES"); !ok || name != "lines" {
+		t.Errorf("ForPath(a.LINES) = %s, %v", name, ok)
+	}
+
+	// registering the name again replaces it, without adding it twice
+	count := len(Names())
+	lines.Extensions = []string{".txt"}
+	Register("lines", lines)
+	if len(Names()) != count {
+		t.Errorf("Names() = %v after replacing lines", Names())
+	}
+
+	if name, ok := ForPath("a.lines"); ok {
+		t.Errorf("ForPath(a.lines) = %s after replacing lines", name)
+	}
+
+	if name, ok := ForPath("a.txt"); !ok || name != "lines" {
+		t.Errorf("ForPath(a.txt) = %s, %v", name, ok)
+	}
+
+	for name, codec := range map[string]Codec{
+		"":           lines,
+		"no decode":  {Encode: lines.Encode},
+		"no encode":  {Decode: lines.Decode},
+		"only sniff": {Sniff: lines.Sniff},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%q): want a panic", name)
+				}
+			}()
+
+			Register(name, codec)
+		}()
+	}
+}
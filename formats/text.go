@@ -0,0 +1,181 @@
+package formats
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+// the text formats of the orderedjson package itself. formats that can hold more than one document
+// (json streams, yaml) are read and written a document at a time, so big files go through in
+// constant memory.
+func registerText() {
+	Register("json", Codec{
+		Extensions: []string{".json"},
+		Sniff:      textStartsWith("{"),
+		Decode:     decodeJSONStream(orderedjson.ConcatenatedStream),
+		Encode: func(w io.Writer, value interface{}, index int) error {
+			data, err := orderedjson.MarshalOptions{}.Marshal(value)
+			if err == nil {
+				_, err = fmt.Fprintln(w, data)
+			}
+			return err
+		},
+	})
+
+	Register("ndjson", Codec{
+		Extensions: []string{".ndjson", ".jsonl"},
+		Decode:     decodeJSONStream(orderedjson.ConcatenatedStream),
+		Encode: func(w io.Writer, value interface{}, index int) error {
+			data, err := orderedjson.MarshalCompact(value)
+			if err == nil {
+				_, err = fmt.Fprintln(w, data)
+			}
+			return err
+		},
+	})
+
+	Register("json-seq", Codec{
+		Extensions: []string{".json-seq"},
+		Sniff:      textStartsWith(orderedjson.SequenceStream.Delimiter),
+		Decode:     decodeJSONStream(orderedjson.SequenceStream),
+		Encode: func(w io.Writer, value interface{}, index int) error {
+			data, err := orderedjson.MarshalCompact(value)
+			if err == nil {
+				_, err = fmt.Fprintf(w, "%s%s\n", orderedjson.SequenceStream.Delimiter, data)
+			}
+			return err
+		},
+	})
+
+	Register("yaml", Codec{
+		Extensions: []string{".yaml", ".yml"},
+		Sniff:      textStartsWith("---", "%YAML"),
+		Decode:     orderedjson.FromYAMLStream,
+		Encode: func(w io.Writer, value interface{}, index int) error {
+			data, err := orderedjson.ToYAML(value)
+			if err != nil {
+				return err
+			}
+
+			if index > 0 {
+				if _, err := io.WriteString(w, "---\n"); err != nil {
+					return err
+				}
+			}
+
+			_, err = w.Write(data)
+			return err
+		},
+	})
+
+	registerObject("xml", []string{".xml"}, textStartsWith("<"), func(data []byte) (*JsonObject, error) {
+		return orderedjson.FromXML(data, orderedjson.XMLOptions{})
+	}, func(tree *JsonObject) ([]byte, error) {
+		return orderedjson.ToXML(tree, orderedjson.XMLOptions{Indent: "  "})
+	})
+
+	registerObject("form", nil, nil, func(data []byte) (*JsonObject, error) {
+		return orderedjson.FromForm(string(bytes.TrimSpace(data)))
+	}, func(tree *JsonObject) ([]byte, error) {
+		text, err := orderedjson.ToForm(tree)
+		return []byte(text + "\n"), err
+	})
+
+	registerObject("env", []string{".env"}, nil, func(data []byte) (*JsonObject, error) {
+		return orderedjson.FromDotenv(data, "")
+	}, func(tree *JsonObject) ([]byte, error) {
+		return orderedjson.ToDotenv(tree, "__")
+	})
+
+	registerObject("properties", []string{".properties"}, nil, func(data []byte) (*JsonObject, error) {
+		return orderedjson.FromProperties(data, "")
+	}, func(tree *JsonObject) ([]byte, error) {
+		return orderedjson.ToProperties(tree, ".")
+	})
+
+	Register("csv", csvCodec(".csv", ','))
+	Register("tsv", csvCodec(".tsv", '\t'))
+}
+
+// a sniffer for text formats that start with one of prefixes, after any whitespace and BOM
+func textStartsWith(prefixes ...string) func(head []byte) bool {
+	return func(head []byte) bool {
+		text := bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\ufeff")), " \t\r\n")
+		for _, prefix := range prefixes {
+			if bytes.HasPrefix(text, []byte(prefix)) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+func decodeJSONStream(format orderedjson.StreamFormat) func(r io.Reader, each func(value interface{}) error) error {
+	return func(r io.Reader, each func(value interface{}) error) error {
+		reader := orderedjson.ParseOptions{Numbers: orderedjson.NumberJSONNumber}.NewStreamReader(r, format)
+		for {
+			tree, err := reader.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := each(tree); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// registers a format with one object in a file
+func registerObject(name string, extensions []string, sniff func(head []byte) bool, decode func(data []byte) (*JsonObject, error), encode func(tree *JsonObject) ([]byte, error)) {
+	codec := OneDocument(decodeObject(decode), encodeObject(encode))
+	codec.Extensions = extensions
+	codec.Sniff = sniff
+
+	Register(name, codec)
+}
+
+// csv reads as an array of rows, and is written from every object in the input: documents that are
+// arrays give a row for each item, and objects are a row themselves (so a stream of objects works)
+func csvCodec(extension string, comma rune) Codec {
+	opts := orderedjson.CSVOptions{Comma: comma}
+	return Codec{
+		Extensions: []string{extension},
+		Decode: func(r io.Reader, each func(value interface{}) error) error {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+
+			rows, err := orderedjson.FromCSV(data, opts)
+			if err != nil {
+				return err
+			}
+
+			return each(rows)
+		},
+		EncodeAll: func(w io.Writer, values []interface{}) error {
+			rows := make([]interface{}, 0, len(values))
+			for _, value := range values {
+				if items, ok := value.([]interface{}); ok {
+					rows = append(rows, items...)
+				} else {
+					rows = append(rows, value)
+				}
+			}
+
+			data, err := orderedjson.ToCSV(rows, opts)
+			if err == nil {
+				_, err = w.Write(data)
+			}
+			return err
+		},
+	}
+}
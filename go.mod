@@ -2,11 +2,15 @@ module github.com/michaelhelvey/orderedjson/v2
 
 go 1.22.0
 
-require github.com/wk8/go-ordered-map/v2 v2.1.8
+require (
+	github.com/fsnotify/fsnotify v1.9.0
+	github.com/wk8/go-ordered-map/v2 v2.1.8
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/bahlo/generic-list-go v0.2.0 // indirect
 	github.com/buger/jsonparser v1.1.1 // indirect
 	github.com/mailru/easyjson v0.7.7 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	golang.org/x/sys v0.13.0 // indirect
 )
@@ -0,0 +1,693 @@
+package hcl
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// Decode parses an HCL2 document (native syntax, like Terraform's .tf files) into the shape of
+// HCL's JSON syntax: attributes are keys, a block is a key for its type with an object per label
+// nested inside it (resource "a" "b" {} becomes {"resource": {"a": {"b": {}}}}), and blocks that
+// show up more than once with the same labels become an array. everything is in the order it first
+// appears in the document.
+//
+// literals (strings, numbers, bools, null, tuples and objects) become json values, with integers
+// too big for a float64 to hold exactly as json.Numbers. any other expression (a reference, a
+// function call, a conditional...) becomes a "${...}" string with its source text, the way the
+// JSON syntax writes expressions.
+func Decode(data []byte) (*JsonObject, error) {
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("hcl: document is not valid utf-8")
+	}
+
+	root := orderedmap.New[string, interface{}]()
+	decoder := &decoder{runes: []rune(string(data)), line: 1, bodies: map[*JsonObject]bool{}}
+	if err := decoder.decodeBody(root, false); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+type decoder struct {
+	runes []rune
+	idx   int
+	line  int
+	// the objects that are block bodies, as opposed to the levels for their labels
+	bodies map[*JsonObject]bool
+}
+
+func (decoder *decoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("hcl: line %d: %s", decoder.line, fmt.Sprintf(format, args...))
+}
+
+func (decoder *decoder) peek() rune {
+	if decoder.idx < len(decoder.runes) {
+		return decoder.runes[decoder.idx]
+	}
+
+	return 0
+}
+
+func (decoder *decoder) hasPrefix(s string) bool {
+	return strings.HasPrefix(string(decoder.runes[decoder.idx:min(len(decoder.runes), decoder.idx+len(s))]), s)
+}
+
+func (decoder *decoder) skipSpace() {
+	for decoder.peek() == ' ' || decoder.peek() == '\t' || decoder.peek() == '\r' {
+		decoder.idx++
+	}
+}
+
+// skips a comment if there's one. # and // comments stop before the newline.
+func (decoder *decoder) skipComment() error {
+	switch {
+	case decoder.peek() == '#' || decoder.hasPrefix("//"):
+		for decoder.idx < len(decoder.runes) && decoder.peek() != '\n' {
+			decoder.idx++
+		}
+	case decoder.hasPrefix("/*"):
+		for decoder.idx += 2; !decoder.hasPrefix("*/"); decoder.idx++ {
+			if decoder.idx >= len(decoder.runes) {
+				return decoder.errorf("unterminated comment")
+			}
+
+			if decoder.peek() == '\n' {
+				decoder.line++
+			}
+		}
+		decoder.idx += 2
+	}
+
+	return nil
+}
+
+// skips whitespace, newlines and comments
+func (decoder *decoder) skipBlank() error {
+	for {
+		start := decoder.idx
+		decoder.skipSpace()
+		if err := decoder.skipComment(); err != nil {
+			return err
+		}
+
+		if decoder.peek() == '\n' {
+			decoder.idx++
+			decoder.line++
+		} else if decoder.idx == start {
+			return nil
+		}
+	}
+}
+
+func isIdentifierStart(char rune) bool {
+	return char == '_' || unicode.IsLetter(char)
+}
+
+func isIdentifierPart(char rune) bool {
+	return isIdentifierStart(char) || char == '-' || unicode.IsDigit(char)
+}
+
+func (decoder *decoder) identifier() string {
+	start := decoder.idx
+	if isIdentifierStart(decoder.peek()) {
+		for decoder.idx < len(decoder.runes) && isIdentifierPart(decoder.peek()) {
+			decoder.idx++
+		}
+	}
+
+	return string(decoder.runes[start:decoder.idx])
+}
+
+// after an attribute or block, which has to be followed by a newline (or the } of a one line block)
+func (decoder *decoder) endOfItem() error {
+	decoder.skipSpace()
+	if err := decoder.skipComment(); err != nil {
+		return err
+	}
+
+	// after a /* */ comment
+	decoder.skipSpace()
+
+	switch decoder.peek() {
+	case 0, '\n', '}':
+		return nil
+	}
+
+	return decoder.errorf("expected a newline, got %q", decoder.peek())
+}
+
+func (decoder *decoder) decodeBody(body *JsonObject, nested bool) error {
+	for {
+		if err := decoder.skipBlank(); err != nil {
+			return err
+		}
+
+		switch {
+		case decoder.idx >= len(decoder.runes):
+			if nested {
+				return decoder.errorf("unexpected end of input, expected }")
+			}
+
+			return nil
+		case decoder.peek() == '}':
+			if !nested {
+				return decoder.errorf("unexpected }")
+			}
+
+			decoder.idx++
+			return nil
+		}
+
+		name := decoder.identifier()
+		if name == "" {
+			return decoder.errorf("expected an attribute or block, got %q", decoder.peek())
+		}
+
+		decoder.skipSpace()
+		if decoder.peek() == '=' && !decoder.hasPrefix("==") {
+			decoder.idx++
+			value, err := decoder.decodeExpression()
+			if err != nil {
+				return err
+			}
+
+			if _, exists := body.Get(name); exists {
+				return decoder.errorf("duplicate %s", name)
+			}
+
+			body.Set(name, value)
+		} else if err := decoder.decodeBlock(body, name); err != nil {
+			return err
+		}
+
+		if err := decoder.endOfItem(); err != nil {
+			return err
+		}
+	}
+}
+
+func (decoder *decoder) decodeBlock(body *JsonObject, blockType string) error {
+	path := []string{blockType}
+	for decoder.peek() != '{' {
+		switch {
+		case decoder.peek() == '"':
+			label, err := decoder.decodeString()
+			if err != nil {
+				return err
+			}
+
+			path = append(path, label)
+		case isIdentifierStart(decoder.peek()):
+			path = append(path, decoder.identifier())
+		default:
+			return decoder.errorf("expected = or { after %s", strings.Join(path, " "))
+		}
+
+		decoder.skipSpace()
+	}
+	decoder.idx++
+
+	block := orderedmap.New[string, interface{}]()
+	decoder.bodies[block] = true
+	if err := decoder.decodeBody(block, true); err != nil {
+		return err
+	}
+
+	// walk (and make) the levels for the labels
+	parent := body
+	for _, key := range path[:len(path)-1] {
+		existing, exists := parent.Get(key)
+		if !exists {
+			level := orderedmap.New[string, interface{}]()
+			parent.Set(key, level)
+			parent = level
+			continue
+		}
+
+		level, ok := existing.(*JsonObject)
+		if !ok || decoder.bodies[level] || parent == body && !decoder.isBlock(existing) {
+			return decoder.errorf("%s is used for more than one kind of thing", strings.Join(path, " "))
+		}
+
+		parent = level
+	}
+
+	last := path[len(path)-1]
+	switch existing, exists := parent.Get(last); v := existing.(type) {
+	case nil:
+		if !exists {
+			parent.Set(last, block)
+			return nil
+		}
+	case *JsonObject:
+		if decoder.bodies[v] {
+			parent.Set(last, []interface{}{v, block})
+			return nil
+		}
+	case []interface{}:
+		if decoder.isBlock(v) {
+			parent.Set(last, append(v, block))
+			return nil
+		}
+	}
+
+	return decoder.errorf("%s is used for more than one kind of thing", strings.Join(path, " "))
+}
+
+// whether value came from blocks (a body, a level of labels, or an array of bodies), rather than
+// an attribute
+func (decoder *decoder) isBlock(value interface{}) bool {
+	switch v := value.(type) {
+	case *JsonObject:
+		if decoder.bodies[v] {
+			return true
+		}
+
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			if !decoder.isBlock(pair.Value) {
+				return false
+			}
+		}
+
+		return v.Len() > 0
+	case []interface{}:
+		for _, item := range v {
+			if body, ok := item.(*JsonObject); !ok || !decoder.bodies[body] {
+				return false
+			}
+		}
+
+		return len(v) > 0
+	}
+
+	return false
+}
+
+// whether the expression ends here, at the end of the line or of the collection it's in
+func (decoder *decoder) atExpressionEnd() bool {
+	decoder.skipSpace()
+	switch decoder.peek() {
+	case 0, '\n', ',', ']', '}', ')', '#':
+		return true
+	}
+
+	return decoder.hasPrefix("//") || decoder.hasPrefix("/*")
+}
+
+func (decoder *decoder) decodeExpression() (interface{}, error) {
+	decoder.skipSpace()
+	start, line := decoder.idx, decoder.line
+
+	var value interface{}
+	var err error
+	switch {
+	case decoder.peek() == '"':
+		value, err = decoder.decodeString()
+	case decoder.hasPrefix("<<"):
+		value, err = decoder.decodeHeredoc()
+	case decoder.peek() == '[' && !decoder.isForExpression():
+		value, err = decoder.decodeTuple()
+	case decoder.peek() == '{' && !decoder.isForExpression():
+		value, err = decoder.decodeObject()
+	default:
+		return decoder.decodeRaw()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if decoder.atExpressionEnd() {
+		return value, nil
+	}
+
+	// the literal is only the start of something bigger, like "a" == var.b or [1, 2][0]
+	decoder.idx, decoder.line = start, line
+	return decoder.decodeRaw()
+}
+
+// whether the [ or { here starts a for expression
+func (decoder *decoder) isForExpression() bool {
+	start, line := decoder.idx, decoder.line
+	defer func() { decoder.idx, decoder.line = start, line }()
+
+	decoder.idx++
+	if decoder.skipBlank() != nil {
+		return false
+	}
+
+	return decoder.identifier() == "for" && (decoder.peek() == ' ' || decoder.peek() == '\t')
+}
+
+// an expression that isn't a literal, up to where it ends. true, false, null and numbers are
+// literals; the rest becomes "${...}".
+func (decoder *decoder) decodeRaw() (interface{}, error) {
+	start := decoder.idx
+	depth := 0
+	for decoder.idx < len(decoder.runes) {
+		if depth == 0 && decoder.atExpressionEnd() {
+			break
+		}
+
+		switch decoder.peek() {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case '\n':
+			decoder.line++
+		case '"':
+			if _, err := decoder.decodeString(); err != nil {
+				return nil, err
+			}
+			continue
+		case '#':
+			if err := decoder.skipComment(); err != nil {
+				return nil, err
+			}
+			continue
+		case '/':
+			if decoder.hasPrefix("//") || decoder.hasPrefix("/*") {
+				if err := decoder.skipComment(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+
+		decoder.idx++
+	}
+
+	text := strings.TrimSpace(string(decoder.runes[start:decoder.idx]))
+	switch text {
+	case "":
+		return nil, decoder.errorf("expected an expression")
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+
+	if isNumber(text) {
+		// hcl numbers have arbitrary precision, so integers past 2^53 keep all their digits
+		if n, ok := new(big.Int).SetString(text, 10); ok && n.CmpAbs(big.NewInt(1<<53)) > 0 {
+			return json.Number(n.String()), nil
+		}
+
+		if number, err := strconv.ParseFloat(text, 64); err == nil {
+			return number, nil
+		}
+	}
+
+	return "${" + text + "}", nil
+}
+
+func isNumber(text string) bool {
+	digits := strings.TrimPrefix(text, "-")
+	if digits == "" || digits[0] < '0' || digits[0] > '9' {
+		return false
+	}
+
+	return strings.Trim(digits, "0123456789.eE+-") == ""
+}
+
+func (decoder *decoder) decodeTuple() (interface{}, error) {
+	decoder.idx++
+	result := make([]interface{}, 0)
+	for {
+		if err := decoder.skipBlank(); err != nil {
+			return nil, err
+		}
+
+		if decoder.peek() == ']' {
+			decoder.idx++
+			return result, nil
+		}
+
+		value, err := decoder.decodeExpression()
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, value)
+		if err := decoder.skipBlank(); err != nil {
+			return nil, err
+		}
+
+		switch decoder.peek() {
+		case ',':
+			decoder.idx++
+		case ']':
+			decoder.idx++
+			return result, nil
+		default:
+			return nil, decoder.errorf("expected , or ] in tuple")
+		}
+	}
+}
+
+func (decoder *decoder) decodeObject() (interface{}, error) {
+	decoder.idx++
+	result := orderedmap.New[string, interface{}]()
+	for {
+		if err := decoder.skipBlank(); err != nil {
+			return nil, err
+		}
+
+		if decoder.peek() == '}' {
+			decoder.idx++
+			return result, nil
+		}
+
+		var key string
+		switch {
+		case decoder.peek() == '"':
+			var err error
+			if key, err = decoder.decodeString(); err != nil {
+				return nil, err
+			}
+		case decoder.peek() == '(':
+			// a key that's an expression
+			start := decoder.idx
+			for depth := 0; depth > 0 || decoder.idx == start; decoder.idx++ {
+				switch decoder.peek() {
+				case 0:
+					return nil, decoder.errorf("unterminated (")
+				case '(':
+					depth++
+				case ')':
+					depth--
+				}
+			}
+
+			key = "${" + string(decoder.runes[start+1:decoder.idx-1]) + "}"
+		default:
+			if key = decoder.identifier(); key == "" {
+				return nil, decoder.errorf("expected a key in object, got %q", decoder.peek())
+			}
+		}
+
+		decoder.skipSpace()
+		if decoder.peek() != '=' && decoder.peek() != ':' {
+			return nil, decoder.errorf("expected = or : after %s", key)
+		}
+		decoder.idx++
+
+		value, err := decoder.decodeExpression()
+		if err != nil {
+			return nil, err
+		}
+
+		result.Set(key, value)
+
+		decoder.skipSpace()
+		if decoder.peek() == ',' {
+			decoder.idx++
+		} else if err := decoder.skipComment(); err != nil {
+			return nil, err
+		} else if decoder.peek() != '\n' && decoder.peek() != '}' {
+			return nil, decoder.errorf("expected a newline, , or } in object")
+		}
+	}
+}
+
+// a quoted string. interpolations (${...}) and directives (%{...}) are kept as they're written.
+func (decoder *decoder) decodeString() (string, error) {
+	decoder.idx++
+
+	var result strings.Builder
+	for decoder.idx < len(decoder.runes) {
+		char := decoder.peek()
+		switch {
+		case char == '"':
+			decoder.idx++
+			return result.String(), nil
+		case char == '\n':
+			return "", decoder.errorf("newline in string")
+		case char == '\\':
+			if err := decoder.decodeEscape(&result); err != nil {
+				return "", err
+			}
+			continue
+		case decoder.hasPrefix("$${") || decoder.hasPrefix("%%{"):
+			result.WriteString(string(decoder.runes[decoder.idx : decoder.idx+3]))
+			decoder.idx += 3
+			continue
+		case decoder.hasPrefix("${") || decoder.hasPrefix("%{"):
+			template, err := decoder.template()
+			if err != nil {
+				return "", err
+			}
+
+			result.WriteString(template)
+			continue
+		}
+
+		result.WriteRune(char)
+		decoder.idx++
+	}
+
+	return "", decoder.errorf("unterminated string")
+}
+
+// the text of a ${...} or %{...} in a string, which can have strings (and braces) of its own
+func (decoder *decoder) template() (string, error) {
+	start := decoder.idx
+	decoder.idx += 2
+	for depth := 1; depth > 0; {
+		switch decoder.peek() {
+		case 0:
+			return "", decoder.errorf("unterminated interpolation")
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '"':
+			if _, err := decoder.decodeString(); err != nil {
+				return "", err
+			}
+			continue
+		case '\n':
+			decoder.line++
+		}
+
+		decoder.idx++
+	}
+
+	return string(decoder.runes[start:decoder.idx]), nil
+}
+
+func (decoder *decoder) decodeEscape(result *strings.Builder) error {
+	decoder.idx++
+	escaped := decoder.peek()
+	decoder.idx++
+
+	switch escaped {
+	case 'n':
+		result.WriteRune('\n')
+	case 'r':
+		result.WriteRune('\r')
+	case 't':
+		result.WriteRune('\t')
+	case '"':
+		result.WriteRune('"')
+	case '\\':
+		result.WriteRune('\\')
+	case 'u', 'U':
+		length := 4
+		if escaped == 'U' {
+			length = 8
+		}
+
+		if decoder.idx+length > len(decoder.runes) {
+			return decoder.errorf("invalid unicode escape")
+		}
+
+		code, err := strconv.ParseUint(string(decoder.runes[decoder.idx:decoder.idx+length]), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return decoder.errorf("invalid unicode escape")
+		}
+
+		decoder.idx += length
+		result.WriteRune(rune(code))
+	default:
+		return decoder.errorf("invalid escape \\%c", escaped)
+	}
+
+	return nil
+}
+
+// <<EOF or <<-EOF, which takes out the indentation all the lines have
+func (decoder *decoder) decodeHeredoc() (string, error) {
+	decoder.idx += 2
+	strip := decoder.peek() == '-'
+	if strip {
+		decoder.idx++
+	}
+
+	marker := decoder.identifier()
+	if marker == "" {
+		return "", decoder.errorf("expected a heredoc marker after <<")
+	}
+
+	decoder.skipSpace()
+	if decoder.peek() != '\n' {
+		return "", decoder.errorf("expected a newline after <<%s", marker)
+	}
+	decoder.idx++
+	decoder.line++
+
+	var lines []string
+	for {
+		if decoder.idx >= len(decoder.runes) {
+			return "", decoder.errorf("unterminated heredoc, expected %s", marker)
+		}
+
+		start := decoder.idx
+		for decoder.idx < len(decoder.runes) && decoder.peek() != '\n' {
+			decoder.idx++
+		}
+
+		line := strings.TrimSuffix(string(decoder.runes[start:decoder.idx]), "\r")
+		if strings.TrimSpace(line) == marker {
+			break
+		}
+
+		lines = append(lines, line)
+		decoder.idx++
+		decoder.line++
+	}
+
+	if strip {
+		indent := -1
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			if width := len(line) - len(strings.TrimLeft(line, " \t")); indent < 0 || width < indent {
+				indent = width
+			}
+		}
+
+		for i, line := range lines {
+			lines[i] = line[min(max(indent, 0), len(line)):]
+		}
+	}
+
+	if len(lines) == 0 {
+		return "", nil
+	}
+
+	return strings.Join(lines, "\n") + "\n", nil
+}
@@ -0,0 +1,336 @@
+// Package hcl converts between ordered json trees and HCL2 native syntax (the syntax of Terraform's
+// .tf files), keeping the order of attributes and blocks in both directions. the json side is
+// shaped like HCL's JSON syntax (.tf.json), so converted documents work with tools that read that.
+package hcl
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type JsonObject = orderedmap.OrderedMap[string, interface{}]
+
+// EncodeOptions say which objects are blocks, since json doesn't tell a block from an attribute
+// that's an object (tags = {...}) the way HCL does.
+type EncodeOptions struct {
+	// how many labels blocks of each type have, e.g. 2 for resource "aws_instance" "web". types that
+	// aren't in here have none.
+	Labels map[string]int
+	// the keys that are blocks, at any depth. objects (and arrays of objects) under other keys are
+	// attributes. nil makes every one of them a block.
+	Blocks map[string]bool
+}
+
+// Terraform has the block types of the Terraform language. nested blocks that belong to a
+// provider's resources (like ingress for aws_security_group) aren't in it, so they're written as
+// attributes unless they're added to a copy.
+var Terraform = EncodeOptions{
+	Labels: map[string]int{
+		"resource": 2, "data": 2, "module": 1, "variable": 1, "output": 1, "provider": 1, "backend": 1,
+		"provisioner": 1, "dynamic": 1, "check": 1,
+	},
+	Blocks: map[string]bool{
+		"resource": true, "data": true, "module": true, "variable": true, "output": true, "provider": true,
+		"locals": true, "terraform": true, "required_providers": true, "backend": true, "cloud": true,
+		"lifecycle": true, "provisioner": true, "connection": true, "dynamic": true, "content": true,
+		"moved": true, "import": true, "removed": true, "check": true, "validation": true,
+		"precondition": true, "postcondition": true,
+	},
+}
+
+// Encode converts tree, shaped like HCL's JSON syntax, to HCL native syntax. strings that are
+// exactly one "${...}" are written as the expression inside it, so a decoded document comes back
+// the way it was written (besides comments and formatting).
+func Encode(tree *JsonObject, opts EncodeOptions) ([]byte, error) {
+	encoder := &encoder{opts: opts}
+	if err := encoder.encodeBody(tree, 0); err != nil {
+		return nil, err
+	}
+
+	return []byte(encoder.String()), nil
+}
+
+type encoder struct {
+	strings.Builder
+	opts EncodeOptions
+}
+
+func (encoder *encoder) isBlock(key string, value interface{}) bool {
+	if encoder.opts.Blocks != nil && !encoder.opts.Blocks[key] {
+		return false
+	}
+
+	if _, ok := value.(*JsonObject); ok {
+		return true
+	}
+
+	items, ok := value.([]interface{})
+	for _, item := range items {
+		if _, ok := item.(*JsonObject); !ok {
+			return false
+		}
+	}
+
+	return ok && len(items) > 0
+}
+
+func (encoder *encoder) encodeBody(body *JsonObject, depth int) error {
+	indent := strings.Repeat("  ", depth)
+	previousBlock := false
+	for pair := body.Oldest(); pair != nil; pair = pair.Next() {
+		block := encoder.isBlock(pair.Key, pair.Value)
+		if pair != body.Oldest() && (block || previousBlock) {
+			encoder.WriteString("\n")
+		}
+		previousBlock = block
+
+		if block {
+			if err := encoder.encodeBlocks(pair.Key, nil, pair.Value, encoder.opts.Labels[pair.Key], depth); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if !isIdentifier(pair.Key) {
+			return fmt.Errorf("hcl: %q can't be an attribute name", pair.Key)
+		}
+
+		value, err := encoder.encodeValue(pair.Value, depth)
+		if err != nil {
+			return fmt.Errorf("hcl: %s: %v", pair.Key, err)
+		}
+
+		encoder.WriteString(indent + pair.Key + " = " + value + "\n")
+	}
+
+	return nil
+}
+
+// writes the blocks of blockType in value, with labels levels of labels still to go
+func (encoder *encoder) encodeBlocks(blockType string, labels []string, value interface{}, remaining, depth int) error {
+	if remaining > 0 {
+		level, ok := value.(*JsonObject)
+		if !ok {
+			return fmt.Errorf("hcl: %s %s needs %d more labels, found %s", blockType, strings.Join(labels, " "), remaining, typeName(value))
+		}
+
+		for pair := level.Oldest(); pair != nil; pair = pair.Next() {
+			if pair != level.Oldest() {
+				encoder.WriteString("\n")
+			}
+
+			if err := encoder.encodeBlocks(blockType, append(labels, pair.Key), pair.Value, remaining-1, depth); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	var bodies []interface{}
+	switch v := value.(type) {
+	case *JsonObject:
+		bodies = []interface{}{v}
+	case []interface{}:
+		bodies = v
+	}
+
+	indent := strings.Repeat("  ", depth)
+	for i, item := range bodies {
+		body, ok := item.(*JsonObject)
+		if !ok {
+			return fmt.Errorf("hcl: the body of a %s block is %s", blockType, typeName(item))
+		}
+
+		if i > 0 {
+			encoder.WriteString("\n")
+		}
+
+		encoder.WriteString(indent + blockType)
+		for _, label := range labels {
+			encoder.WriteString(" " + encodeString(label))
+		}
+
+		if body.Len() == 0 {
+			encoder.WriteString(" {}\n")
+			continue
+		}
+
+		encoder.WriteString(" {\n")
+		if err := encoder.encodeBody(body, depth+1); err != nil {
+			return err
+		}
+		encoder.WriteString(indent + "}\n")
+	}
+
+	return nil
+}
+
+func (encoder *encoder) encodeValue(value interface{}, depth int) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "null", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case string:
+		if expression, ok := unwrapExpression(v); ok {
+			return expression, nil
+		}
+
+		return encodeString(v), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", fmt.Errorf("hcl has no way to write %v", v)
+		}
+
+		if v == math.Trunc(v) && math.Abs(v) <= 1<<53 {
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case json.Number:
+		if n, ok := new(big.Int).SetString(string(v), 10); ok {
+			return n.String(), nil
+		}
+
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return "", fmt.Errorf("%q isn't a number", string(v))
+		}
+
+		return encoder.encodeValue(f, depth)
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		multiline := false
+		for _, item := range v {
+			encoded, err := encoder.encodeValue(item, depth+1)
+			if err != nil {
+				return "", err
+			}
+
+			items = append(items, encoded)
+			multiline = multiline || strings.Contains(encoded, "\n")
+		}
+
+		if !multiline {
+			return "[" + strings.Join(items, ", ") + "]", nil
+		}
+
+		inner := strings.Repeat("  ", depth+1)
+		return "[\n" + inner + strings.Join(items, ",\n"+inner) + ",\n" + strings.Repeat("  ", depth) + "]", nil
+	case *JsonObject:
+		if v.Len() == 0 {
+			return "{}", nil
+		}
+
+		var result strings.Builder
+		result.WriteString("{\n")
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			encoded, err := encoder.encodeValue(pair.Value, depth+1)
+			if err != nil {
+				return "", err
+			}
+
+			key := pair.Key
+			if !isIdentifier(key) {
+				key = encodeString(key)
+			}
+
+			result.WriteString(strings.Repeat("  ", depth+1) + key + " = " + encoded + "\n")
+		}
+		result.WriteString(strings.Repeat("  ", depth) + "}")
+
+		return result.String(), nil
+	}
+
+	return "", fmt.Errorf("cannot convert %T to hcl", value)
+}
+
+// the expression in a string that's nothing but one ${...}
+func unwrapExpression(s string) (string, bool) {
+	if !strings.HasPrefix(s, "${") || !strings.HasSuffix(s, "}") {
+		return "", false
+	}
+
+	// the } at the end has to close the ${ at the start, not a later one ("${a}-${b}")
+	depth := 0
+	inString := false
+	for i := 2; i < len(s)-1; i++ {
+		switch {
+		case inString && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			inString = !inString
+		case inString:
+		case s[i] == '{':
+			depth++
+		case s[i] == '}':
+			if depth--; depth < 0 {
+				return "", false
+			}
+		}
+	}
+
+	expression := strings.TrimSpace(s[2 : len(s)-1])
+	return expression, depth == 0 && expression != ""
+}
+
+func isIdentifier(key string) bool {
+	for i, char := range key {
+		if i == 0 && !isIdentifierStart(char) || !isIdentifierPart(char) {
+			return false
+		}
+	}
+
+	return key != ""
+}
+
+func encodeString(s string) string {
+	var result strings.Builder
+	result.WriteRune('"')
+	for _, char := range s {
+		switch {
+		case char == '"':
+			result.WriteString(`\"`)
+		case char == '\\':
+			result.WriteString(`\\`)
+		case char == '\n':
+			result.WriteString(`\n`)
+		case char == '\r':
+			result.WriteString(`\r`)
+		case char == '\t':
+			result.WriteString(`\t`)
+		case char < 0x20 || char == 0x7f:
+			result.WriteString(fmt.Sprintf(`\u%04X`, char))
+		default:
+			result.WriteRune(char)
+		}
+	}
+	result.WriteRune('"')
+
+	return result.String()
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case *JsonObject:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case float64, json.Number:
+		return "a number"
+	case bool:
+		return "a bool"
+	}
+
+	return "null"
+}
@@ -0,0 +1,213 @@
+package hcl
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/michaelhelvey/orderedjson/v2/internal/testtree"
+)
+
+// blocks with their labels come back as they were written, in order, with attributes between them
+func TestRoundTrip(t *testing.T) {
+	service := EncodeOptions{Labels: map[string]int{"service": 2, "process": 1}}
+	tests := []struct {
+		hcl  string
+		opts EncodeOptions
+	}{
+		{"resource \"aws_instance\" \"web\" {\n  ami = var.ami\n  count = 2\n\n  lifecycle {\n    create_before_destroy = true\n  }\n}\n", Terraform},
+		{"variable \"b\" {}\n\nvariable \"a\" {\n  default = \"x\"\n}\n", Terraform},
+		{"a = 1\n\nprovider \"aws\" {\n  region = \"us-east-1\"\n}\n\nb = \"${var.x}-y\"\n", Terraform},
+		{"module \"net\" {\n  source = \"./net\"\n}\n\nresource \"aws_subnet\" \"a\" {}\n\nresource \"aws_subnet\" \"b\" {\n  tags = {\n    Name = \"b\"\n  }\n}\n\nresource \"aws_vpc\" \"main\" {}\n", Terraform},
+		// blocks with the same labels are an array
+		{"provisioner \"local-exec\" {\n  command = \"a\"\n}\n\nprovisioner \"local-exec\" {\n  command = \"b\"\n}\n", Terraform},
+		// the labels of block types that aren't terraform's come from the options
+		{"service \"http\" \"web\" {\n  process \"main\" {\n    command = [\"app\", \"server\"]\n  }\n\n  process \"mgmt\" {}\n}\n", service},
+		{"service \"http\" \"web\" {}\n\nservice \"http\" \"api\" {}\n\nservice \"tcp\" \"db\" {}\n", service},
+	}
+
+	for _, test := range tests {
+		decoded, err := Decode([]byte(test.hcl))
+		if err != nil {
+			t.Errorf("Decode(%q): %v", test.hcl, err)
+			continue
+		}
+
+		data, err := Encode(decoded, test.opts)
+		if err != nil || string(data) != test.hcl {
+			t.Errorf("Encode(Decode(%q)) = %q, %v (from %s)", test.hcl, data, err, testtree.Marshal(t, decoded))
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		json string
+		opts EncodeOptions
+		hcl  string
+	}{
+		{`{}`, Terraform, ``},
+		{`{"a":1,"b":"x","c":true,"d":null,"e":[1,2],"f":{}}`, Terraform, "a = 1\nb = \"x\"\nc = true\nd = null\ne = [1, 2]\nf = {}\n"},
+		{`{"tags":{"Name":"web","a b":1}}`, Terraform, "tags = {\n  Name = \"web\"\n  \"a b\" = 1\n}\n"},
+		{`{"list":[{"a":1},{"b":2}]}`, Terraform, "list = [\n  {\n    a = 1\n  },\n  {\n    b = 2\n  },\n]\n"},
+		{`{"resource":{"aws_instance":{"web":{"ami":"${var.ami}"}}}}`, Terraform, "resource \"aws_instance\" \"web\" {\n  ami = var.ami\n}\n"},
+		{`{"a":1,"terraform":{},"b":2}`, Terraform, "a = 1\n\nterraform {}\n\nb = 2\n"},
+		{`{"variable":{"a":{},"b":{"default":"${1 + 2}"}}}`, Terraform, "variable \"a\" {}\n\nvariable \"b\" {\n  default = 1 + 2\n}\n"},
+		{`{"dynamic":{"x":{"content":[{"a":1},{"a":2}]}}}`, Terraform, "dynamic \"x\" {\n  content {\n    a = 1\n  }\n\n  content {\n    a = 2\n  }\n}\n"},
+		{`{"ingress":{"from_port":80}}`, Terraform, "ingress = {\n  from_port = 80\n}\n"},
+		{`{"ingress":{"from_port":80}}`, EncodeOptions{}, "ingress {\n  from_port = 80\n}\n"},
+		{`{"s":"${a}-${b}","t":"${ \"}\" }","u":"${}"}`, Terraform, "s = \"${a}-${b}\"\nt = \"}\"\nu = \"${}\"\n"},
+		{`{"exact":9007199254740992,"big":18014398509481984,"n":-0.5}`, Terraform, "exact = 9007199254740992\nbig = 1.8014398509481984e+16\nn = -0.5\n"},
+	}
+
+	for _, test := range tests {
+		data, err := Encode(testtree.Object(t, test.json), test.opts)
+		if err != nil {
+			t.Errorf("Encode(%s): %v", test.json, err)
+			continue
+		}
+
+		if string(data) != test.hcl {
+			t.Errorf("Encode(%s) = %q, want %q", test.json, data, test.hcl)
+		}
+	}
+}
+
+// the examples of the HCL native syntax spec and the Terraform language docs
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		hcl  string
+		json string
+	}{
+		{"io_mode = \"async\"\n\nservice \"http\" \"web_proxy\" {\n  listen_addr = \"127.0.0.1:8080\"\n\n  process \"main\" {\n    command = [\"/usr/local/bin/awesome-app\", \"server\"]\n  }\n\n  process \"mgmt\" {\n    command = [\"/usr/local/bin/awesome-app\", \"mgmt\"]\n  }\n}\n",
+			`{"io_mode":"async","service":{"http":{"web_proxy":{"listen_addr":"127.0.0.1:8080","process":{"main":{"command":["/usr/local/bin/awesome-app","server"]},"mgmt":{"command":["/usr/local/bin/awesome-app","mgmt"]}}}}}}`},
+		{"# line\n// line\n/* block\ncomment */ a = 1 # trailing\nb = 2 /* inline */\n", `{"a":1,"b":2}`},
+		{"a = 1\nb = 1.5\nc = 1e3\nd = -2\ne = true\nf = false\ng = null\n", `{"a":1,"b":1.5,"c":1000,"d":-2,"e":true,"f":false,"g":null}`},
+		{"a = var.x\nb = upper(\"x\")\nc = a ? b : c\nd = [for s in var.list : upper(s)]\ne = {for k, v in var.map : k => v}\nf = \"a\" == var.b\ng = [1, 2][0]\nh = local.a[\"b\"].c\n",
+			`{"a":"${var.x}","b":"${upper(\"x\")}","c":"${a ? b : c}","d":"${[for s in var.list : upper(s)]}","e":"${{for k, v in var.map : k =\u003e v}}","f":"${\"a\" == var.b}","g":"${[1, 2][0]}","h":"${local.a[\"b\"].c}"}`},
+		{"a = \"x${var.y}z\"\nb = \"%{ if var.c }yes%{ endif }\"\nc = \"$${not} %%{this}\"\nd = \"${ \"}\" }\"\n", `{"a":"x${var.y}z","b":"%{ if var.c }yes%{ endif }","c":"$${not} %%{this}","d":"${ \"}\" }"}`},
+		{"a = \"\\n\\r\\t\\\"\\\\\\u00e9\\U0001F600\"\n", `{"a":"\n\r\t\"\\é😀"}`},
+		{"a = <<EOT\nhello\n  world\nEOT\nb = <<-EOT\n    hello\n      world\n    EOT\nc = <<EOT\nEOT\n", `{"a":"hello\n  world\n","b":"hello\n  world\n","c":""}`},
+		{"t = [\n  1,\n  \"two\", # comment\n  [3],\n]\no = {\n  a = 1,\n  \"b\" = 2\n  c: 3\n  (var.k) = 4\n}\n", `{"t":[1,"two",[3]],"o":{"a":1,"b":2,"c":3,"${var.k}":4}}`},
+		{"block {}\nblock {\n  a = 1\n}\nlabeled \"x\" { a = 1 }\nlabeled \"y\" {}\nbare ident {}\n", `{"block":[{},{"a":1}],"labeled":{"x":{"a":1},"y":{}},"bare":{"ident":{}}}`},
+		{"resource \"aws_instance\" \"web\" {\n  ami           = \"ami-a1b2c3d4\"\n  instance_type = \"t2.micro\"\n\n  lifecycle {\n    create_before_destroy = true\n  }\n}\n",
+			`{"resource":{"aws_instance":{"web":{"ami":"ami-a1b2c3d4","instance_type":"t2.micro","lifecycle":{"create_before_destroy":true}}}}}`},
+		{"a = 1\r\nb = 2\r\n", `{"a":1,"b":2}`},
+	}
+
+	for _, test := range tests {
+		decoded, err := Decode([]byte(test.hcl))
+		if err != nil {
+			t.Errorf("Decode(%q): %v", test.hcl, err)
+			continue
+		}
+
+		if got := testtree.Marshal(t, decoded); got != test.json {
+			t.Errorf("Decode(%q) = %s, want %s", test.hcl, got, test.json)
+		}
+	}
+}
+
+// hcl numbers have arbitrary precision, so integers past 2^53 are json.Numbers
+func TestBigIntegers(t *testing.T) {
+	decoded, err := Decode([]byte("a = 9007199254740993\nb = -123456789012345678901234567890\nc = 9007199254740992\n"))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if got := testtree.Marshal(t, decoded); got != `{"a":9007199254740993,"b":-123456789012345678901234567890,"c":9007199254740992}` {
+		t.Errorf("Decode = %s", got)
+	}
+
+	if _, ok := decoded.Value("b").(json.Number); !ok {
+		t.Errorf("b = %#v, want a json.Number", decoded.Value("b"))
+	}
+
+	data, err := Encode(decoded, Terraform)
+	if err != nil || string(data) != "a = 9007199254740993\nb = -123456789012345678901234567890\nc = 9007199254740992\n" {
+		t.Errorf("Encode = %q, %v", data, err)
+	}
+
+	tree := testtree.Object(t, `{}`)
+	tree.Set("n", json.Number("1.5e3"))
+	if data, err := Encode(tree, Terraform); err != nil || string(data) != "n = 1500\n" {
+		t.Errorf("Encode(1.5e3) = %q, %v", data, err)
+	}
+}
+
+func TestSpecialFloats(t *testing.T) {
+	for _, value := range []float64{math.Inf(1), math.Inf(-1), math.NaN()} {
+		tree := testtree.Object(t, `{}`)
+		tree.Set("a", value)
+		if data, err := Encode(tree, Terraform); err == nil {
+			t.Errorf("Encode(%v) = %q, want an error", value, data)
+		}
+	}
+
+	tree := testtree.Object(t, `{}`)
+	tree.Set("a", math.Copysign(0, -1))
+	data, err := Encode(tree, Terraform)
+	if err != nil {
+		t.Fatalf("Encode(-0): %v", err)
+	}
+
+	decoded, err := Decode(data)
+	if f, _ := decoded.Value("a").(float64); err != nil || f != 0 || !math.Signbit(f) {
+		t.Errorf("Decode(%q) = %v, %v", data, decoded.Value("a"), err)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, input := range []string{
+		"a",
+		"a =",
+		"= 1",
+		"1a = 1",
+		"a = 1\na = 2",
+		"a = 1\na {}",
+		"a {}\na = 1",
+		"block \"x\" {}\nblock {}",
+		"block {\n",
+		"block {\na = 1",
+		"}",
+		"block x y",
+		"block 1 {}",
+		"a = \"unterminated",
+		"a = \"new\nline\"",
+		"a = \"\\x\"",
+		"a = \"\\uD800\"",
+		"a = \"${unterminated\"",
+		"a = <<EOT\nno end\n",
+		"a = <<\nEOT\n",
+		"a = <<EOT x\nEOT\n",
+		"a = [1, 2",
+		"a = { b = 1",
+		"a = { b 1 }",
+		"a = { (b = 1 }",
+		"/* unterminated",
+		"a = 1 /* unterminated",
+		"a = \xff",
+	} {
+		if value, err := Decode([]byte(input)); err == nil {
+			t.Errorf("Decode(%q) = %s, want an error", input, testtree.Marshal(t, value))
+		}
+	}
+
+	for _, input := range []string{
+		`{"not an identifier":1}`,
+		`{"resource":{"aws_instance":"x"}}`,
+		`{"resource":{"aws_instance":{"web":[1]}}}`,
+	} {
+		if data, err := Encode(testtree.Object(t, input), Terraform); err == nil {
+			t.Errorf("Encode(%s) = %q, want an error", input, data)
+		}
+	}
+
+	for _, value := range []interface{}{json.Number("1x"), 1, map[string]interface{}{}} {
+		tree := testtree.Object(t, `{}`)
+		tree.Set("a", value)
+		if data, err := Encode(tree, Terraform); err == nil {
+			t.Errorf("Encode(%#v) = %q, want an error", value, data)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Package httpjson reads and writes ordered json in http handlers, so responses come out with their
+// keys in the order they were built in.
+package httpjson
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/michaelhelvey/orderedjson/v2/compat"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type JsonObject = orderedmap.OrderedMap[string, interface{}]
+
+// DefaultLimit is the body size limit Read uses when it's given a limit of 0.
+const DefaultLimit = 1 << 20
+
+var (
+	ErrBodyTooLarge       = errors.New("request body too large")
+	ErrUnsupportedContent = errors.New("unsupported content type, expected application/json")
+)
+
+// Write marshals v (keeping the order of ordered objects anywhere inside it) and writes it with the
+// given status code and a json content type.
+func Write(w http.ResponseWriter, status int, v interface{}) error {
+	data, err := compat.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Read decodes the body of r, which has to be a json object, into an ordered tree. bodies over limit
+// bytes fail with ErrBodyTooLarge, and a Content-Type other than application/json (or a +json type)
+// fails with ErrUnsupportedContent. a missing Content-Type is accepted.
+func Read(r *http.Request, limit int64) (*JsonObject, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+			return nil, ErrUnsupportedContent
+		}
+	}
+
+	if r.Body == nil {
+		return nil, fmt.Errorf("empty request body")
+	}
+
+	// read one byte past the limit to tell a body of exactly limit bytes from a longer one
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(body)) > limit {
+		return nil, ErrBodyTooLarge
+	}
+
+	var tree *JsonObject
+	if err := compat.Unmarshal(body, &tree); err != nil {
+		return nil, err
+	}
+
+	if tree == nil {
+		return nil, fmt.Errorf("expected a json object, got null")
+	}
+
+	return tree, nil
+}
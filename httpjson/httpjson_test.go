@@ -0,0 +1,127 @@
+package httpjson
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/michaelhelvey/orderedjson/v2/compat"
+)
+
+func TestWrite(t *testing.T) {
+	var tree *JsonObject
+	if err := compat.Unmarshal([]byte(`{"z":1,"a":{"y":[true,null],"b":"<&>"}}`), &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	// html characters are escaped like encoding/json does, so the body is safe to embed in a page
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{tree, `{"z":1,"a":{"y":[true,null],"b":"\u003c\u0026\u003e"}}` + "\n"},
+		{[]interface{}{tree, "x"}, `[{"z":1,"a":{"y":[true,null],"b":"\u003c\u0026\u003e"}},"x"]` + "\n"},
+		{map[string]interface{}{"b": 1, "a": tree}, `{"a":{"z":1,"a":{"y":[true,null],"b":"\u003c\u0026\u003e"}},"b":1}` + "\n"},
+		{nil, "null\n"},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		if err := Write(recorder, http.StatusCreated, test.value); err != nil {
+			t.Errorf("Write(%v): %v", test.value, err)
+			continue
+		}
+
+		if recorder.Code != http.StatusCreated || recorder.Body.String() != test.want {
+			t.Errorf("Write(%v) = %d %q, want %q", test.value, recorder.Code, recorder.Body.String(), test.want)
+		}
+
+		if got := recorder.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+			t.Errorf("Write(%v): Content-Type %q", test.value, got)
+		}
+	}
+
+	// nothing is written when the value can't be marshaled, so the handler can still send an error
+	recorder := httptest.NewRecorder()
+	if err := Write(recorder, http.StatusOK, func() {}); err == nil {
+		t.Errorf("Write(func): want an error")
+	}
+
+	if recorder.Body.Len() != 0 || recorder.Header().Get("Content-Type") != "" {
+		t.Errorf("Write(func) wrote %q, %v", recorder.Body.String(), recorder.Header())
+	}
+}
+
+func request(body, contentType string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+
+	return r
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		body        string
+		contentType string
+		want        string
+	}{
+		{`{"z":1,"a":{"y":2,"b":3}}`, "application/json", `{"z":1,"a":{"y":2,"b":3}}`},
+		{`{"a":[{}, []]}`, "application/json; charset=utf-8", `{"a":[{},[]]}`},
+		{`{}`, "application/merge-patch+json", `{}`},
+		{`{"a":1}`, "Application/JSON", `{"a":1}`},
+		{` {"a":1} `, "", `{"a":1}`},
+	}
+
+	for _, test := range tests {
+		tree, err := Read(request(test.body, test.contentType), 0)
+		if err != nil {
+			t.Errorf("Read(%s, %s): %v", test.body, test.contentType, err)
+			continue
+		}
+
+		text, _ := compat.Marshal(tree)
+		if string(text) != test.want {
+			t.Errorf("Read(%s, %s) = %s, want %s", test.body, test.contentType, text, test.want)
+		}
+	}
+}
+
+func TestReadLimit(t *testing.T) {
+	body := `{"a":"` + strings.Repeat("x", 10) + `"}`
+	if _, err := Read(request(body, ""), int64(len(body))); err != nil {
+		t.Errorf("Read(body of exactly the limit): %v", err)
+	}
+
+	if _, err := Read(request(body, ""), int64(len(body)-1)); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Read(body past the limit) = %v, want %v", err, ErrBodyTooLarge)
+	}
+
+	large := `{"a":"` + strings.Repeat("x", DefaultLimit) + `"}`
+	if _, err := Read(request(large, ""), 0); !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("Read(body past the default limit) = %v, want %v", err, ErrBodyTooLarge)
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	for _, contentType := range []string{"text/plain", "application/xml", "application/jsonx", "json", "application/json; charset"} {
+		if _, err := Read(request(`{}`, contentType), 0); !errors.Is(err, ErrUnsupportedContent) {
+			t.Errorf("Read(%s) = %v, want %v", contentType, err, ErrUnsupportedContent)
+		}
+	}
+
+	for _, body := range []string{``, `null`, `[1]`, `"a"`, `1`, `{"a":`, `{"a":1} {"b":2}`, `{"a":1,}`} {
+		if tree, err := Read(request(body, "application/json"), 0); err == nil {
+			t.Errorf("Read(%s) = %v, want an error", body, tree)
+		}
+	}
+
+	r := request(``, "")
+	r.Body = nil
+	if _, err := Read(r, 0); err == nil {
+		t.Errorf("Read(nil body): want an error")
+	}
+}
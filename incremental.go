@@ -0,0 +1,244 @@
+package orderedjson
+
+import (
+	"bytes"
+	"fmt"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// Document is a parse result that remembers where every value is in the text, so that an edit only
+// has to reparse the value it falls into instead of the whole document. this is what an editor
+// integration wants, since it reparses on every keystroke. Span says where each key and value is.
+type Document struct {
+	// the UTF-8 text without a byte order mark. offsets given to Edit are byte offsets into it.
+	Text []byte
+	Tree *JsonObject
+	// metadata of the keys of Tree, with the comments of a JSONC document
+	Meta *Metadata
+
+	opts  ParseOptions
+	spans []valueSpan
+}
+
+// where a value (and its key, for object members) is in the text, ends are exclusive. keyStart
+// and keyEnd are -1 for array items and the top level object.
+type valueSpan struct {
+	path             Path
+	start, end       int
+	keyStart, keyEnd int
+}
+
+// Span is where a value is in the text of a Document, as byte offsets with exclusive ends, so a tool
+// can change the text of one value (or rename a key) without writing the rest of the document out
+// again.
+type Span struct {
+	// the key, quotes included. both are -1 for array items and the top level object.
+	KeyStart, KeyEnd int
+	// the value, from its first byte to its last: the quotes of a string, the braces of an object
+	ValueStart, ValueEnd int
+}
+
+// Span returns where the value at path is. with duplicate keys it's the last one, the one that's
+// in the tree.
+func (doc *Document) Span(path Path) (Span, bool) {
+	for i := len(doc.spans) - 1; i >= 0; i-- {
+		if span := doc.spans[i]; span.path.Equal(path) {
+			return Span{KeyStart: span.keyStart, KeyEnd: span.keyEnd, ValueStart: span.start, ValueEnd: span.end}, true
+		}
+	}
+
+	return Span{}, false
+}
+
+func ParseDocument(data []byte, options ...DecodeOption) (*Document, error) {
+	return ParseOptions{}.With(options...).ParseDocument(data)
+}
+
+func (opts ParseOptions) ParseDocument(data []byte) (*Document, error) {
+	if opts.Comments {
+		return opts.parseJSONCDocument(data)
+	}
+
+	data, err := opts.prepare(data)
+	if err != nil {
+		return nil, err
+	}
+
+	text := append([]byte{}, bytes.TrimPrefix(data, utf8BOM)...)
+	parser := opts.newParser(text)
+	parser.recordSpans = true
+	tree, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Interpolate {
+		tree, err = Interpolate(tree, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Document{Text: text, Tree: tree, Meta: &Metadata{}, opts: opts, spans: parser.spans}, nil
+}
+
+// Edit replaces length bytes at offset with replacement and returns the updated document. only the
+// innermost value around the edit is reparsed, and the new tree shares everything else with the old
+// one. when that isn't possible (the edit touches the top level object, or the value doesn't parse
+// on its own any more) the whole text is reparsed. doc itself is left as it was.
+func (doc *Document) Edit(offset, length int, replacement []byte) (*Document, error) {
+	if offset < 0 || length < 0 || offset+length > len(doc.Text) {
+		return nil, fmt.Errorf("edit out of range: %d bytes at offset %d in a document of %d bytes", length, offset, len(doc.Text))
+	}
+
+	text := make([]byte, 0, len(doc.Text)-length+len(replacement))
+	text = append(text, doc.Text[:offset]...)
+	text = append(text, replacement...)
+	text = append(text, doc.Text[offset+length:]...)
+
+	if updated, ok := doc.reparse(text, offset, length, len(replacement)-length); ok {
+		return updated, nil
+	}
+
+	return doc.opts.ParseDocument(text)
+}
+
+func (doc *Document) reparse(text []byte, offset, length, delta int) (*Document, bool) {
+	// interpolated values depend on other parts of the document, and comments go with the keys
+	// around them, which can be outside the value that's reparsed
+	if doc.opts.Interpolate || doc.opts.Comments {
+		return nil, false
+	}
+
+	// the edit has to be strictly inside the value, so its opening and closing brace, bracket or quote
+	// are still there
+	var target *valueSpan
+	for i := range doc.spans {
+		span := &doc.spans[i]
+		if len(span.path) > 0 && span.start < offset && offset+length < span.end {
+			if target == nil || len(span.path) > len(target.path) {
+				target = span
+			}
+		}
+	}
+
+	if target == nil {
+		return nil, false
+	}
+
+	// with duplicate keys only the last value ends up in the tree, don't try to be clever
+	for i := range doc.spans {
+		if &doc.spans[i] != target && doc.spans[i].path.Equal(target.path) {
+			return nil, false
+		}
+	}
+
+	region := text[target.start : target.end+delta]
+	if doc.opts.InvalidUTF8 == InvalidUTF8Error && validateUTF8(region) != nil {
+		return nil, false
+	}
+
+	parser := doc.opts.newParser(region)
+	parser.recordSpans = true
+	parser.path = append(Path{}, target.path...)
+	parser.keyStart, parser.keyEnd = -1, -1
+	value, err := parser.parseValue()
+	if err != nil || parser.err != nil || parser.idx != len(parser.tokens) {
+		return nil, false
+	}
+
+	spans := make([]valueSpan, 0, len(doc.spans)+len(parser.spans))
+	for _, span := range doc.spans {
+		switch {
+		case span.start >= target.start && span.end <= target.end:
+			// replaced by the spans of the new value
+			continue
+		case span.start >= target.end:
+			span.start += delta
+			span.end += delta
+			if span.keyStart >= 0 {
+				span.keyStart += delta
+				span.keyEnd += delta
+			}
+		case span.end >= target.end:
+			// one of the values around the edit
+			span.end += delta
+		}
+
+		spans = append(spans, span)
+	}
+
+	for _, span := range parser.spans {
+		span.start += target.start
+		span.end += target.start
+		if span.keyStart >= 0 {
+			span.keyStart += target.start
+			span.keyEnd += target.start
+		}
+
+		// the key of the value itself is outside the reparsed text
+		if len(span.path) == len(target.path) {
+			span.keyStart, span.keyEnd = target.keyStart, target.keyEnd
+		}
+
+		spans = append(spans, span)
+	}
+
+	tree := replaceAtPath(doc.Tree, target.path, value).(*JsonObject)
+	return &Document{Text: text, Tree: tree, Meta: doc.Meta.clone(), opts: doc.opts, spans: spans}, true
+}
+
+// Equal reports whether path and other have the same keys and indexes.
+func (path Path) Equal(other Path) bool {
+	if len(path) != len(other) {
+		return false
+	}
+
+	for i := range path {
+		if path[i] != other[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ReplaceAt returns a copy of tree with the value at path replaced, sharing everything that's not
+// on the way to it. the objects and arrays on the way have to be there already.
+func ReplaceAt(tree *JsonObject, path Path, value interface{}) *JsonObject {
+	return replaceAtPath(tree, path, value).(*JsonObject)
+}
+
+// copies the objects and arrays along path, so the original tree isn't modified
+func replaceAtPath(value interface{}, path Path, replacement interface{}) interface{} {
+	if len(path) == 0 {
+		return replacement
+	}
+
+	switch v := value.(type) {
+	case *JsonObject:
+		key := path[0].(string)
+		child, _ := v.Get(key)
+		result := copyObject(v)
+		result.Set(key, replaceAtPath(child, path[1:], replacement))
+		return result
+	case []interface{}:
+		idx := path[0].(int)
+		result := append([]interface{}{}, v...)
+		result[idx] = replaceAtPath(v[idx], path[1:], replacement)
+		return result
+	}
+
+	return replacement
+}
+
+// shallow copy, keeping the key order
+func copyObject(object *JsonObject) *JsonObject {
+	result := orderedmap.New[string, interface{}]()
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		result.Set(pair.Key, pair.Value)
+	}
+
+	return result
+}
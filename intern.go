@@ -0,0 +1,59 @@
+package orderedjson
+
+import (
+	"sync"
+)
+
+// Interner hands out a single copy of equal strings, so a document that repeats the same keys in
+// every element of a big array (package-lock.json has "version", "resolved" and "integrity" for
+// every package) keeps one string for each instead of one per occurrence. see
+// ParseOptions.Interner.
+type Interner interface {
+	Intern(s string) string
+}
+
+// StringInterner is an Interner backed by a map. it's safe for concurrent use, so one interner can
+// be shared by every document a service parses.
+type StringInterner struct {
+	mu      sync.Mutex
+	strings map[string]string
+	limit   int
+}
+
+// NewInterner returns an interner that remembers up to limit strings, and hands out the ones it
+// hasn't seen as they are after that. a limit of 0 or less means no limit, which is fine for one
+// document but lets an interner shared between documents grow forever.
+func NewInterner(limit int) *StringInterner {
+	return &StringInterner{strings: make(map[string]string), limit: limit}
+}
+
+func (interner *StringInterner) Intern(s string) string {
+	interner.mu.Lock()
+	defer interner.mu.Unlock()
+
+	if interned, ok := interner.strings[s]; ok {
+		return interned
+	}
+
+	if interner.limit <= 0 || len(interner.strings) < interner.limit {
+		interner.strings[s] = s
+	}
+
+	return s
+}
+
+// Len is the number of strings the interner remembers.
+func (interner *StringInterner) Len() int {
+	interner.mu.Lock()
+	defer interner.mu.Unlock()
+
+	return len(interner.strings)
+}
+
+func (parser *BtreeJsonParser) intern(s string) string {
+	if parser.Interner == nil {
+		return s
+	}
+
+	return parser.Interner.Intern(s)
+}
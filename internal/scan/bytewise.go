@@ -0,0 +1,23 @@
+//go:build purego
+
+package scan
+
+func indexPair(b []byte, c1, c2 byte) int {
+	for i, c := range b {
+		if c == c1 || c == c2 {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func indexStringEnd(b []byte) int {
+	for i, c := range b {
+		if c == '"' || c == '\\' || c < 0x20 {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,18 @@
+// Package scan finds the bytes the tokenizer cares about in long runs of bytes it doesn't, the way
+// the first stage of simdjson does. the default implementation looks at 8 bytes at a time (SWAR,
+// SIMD within a register); building with the purego tag swaps in a plain loop over bytes, which is
+// easier to check against and what the fast path has to agree with.
+package scan
+
+// IndexQuote returns the index of the first quote or backslash in b, or -1 if there is none. it's
+// how the tokenizer finds the end of a string, or the next escape in it.
+func IndexQuote(b []byte, quote byte) int {
+	return indexPair(b, quote, '\\')
+}
+
+// IndexStringEnd returns the index of the first byte in b that ends a run of plain string content:
+// a double quote, a backslash or a control character, which json doesn't allow in strings. it's -1
+// if there is none.
+func IndexStringEnd(b []byte) int {
+	return indexStringEnd(b)
+}
@@ -0,0 +1,64 @@
+//go:build !purego
+
+package scan
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	ones  = 0x0101010101010101
+	highs = 0x8080808080808080
+)
+
+// the high bit of every byte of word that's zero, and maybe of some bytes above the first zero one,
+// which is why only the lowest bit counts
+func zeroBytes(word uint64) uint64 {
+	return (word - ones) & ^word & highs
+}
+
+func indexPair(b []byte, c1, c2 byte) int {
+	pattern1 := ones * uint64(c1)
+	pattern2 := ones * uint64(c2)
+
+	i := 0
+	for ; i+8 <= len(b); i += 8 {
+		// little endian so the first byte in memory is the lowest one in the word
+		word := binary.LittleEndian.Uint64(b[i:])
+		if found := zeroBytes(word^pattern1) | zeroBytes(word^pattern2); found != 0 {
+			return i + bits.TrailingZeros64(found)/8
+		}
+	}
+
+	for ; i < len(b); i++ {
+		if b[i] == c1 || b[i] == c2 {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func indexStringEnd(b []byte) int {
+	quotes := ones * uint64('"')
+	backslashes := ones * uint64('\\')
+
+	i := 0
+	for ; i+8 <= len(b); i += 8 {
+		word := binary.LittleEndian.Uint64(b[i:])
+		// bytes below 0x20 borrow when 0x20 is taken away from them, like zero bytes do for 1
+		controls := (word - ones*0x20) & ^word & highs
+		if found := zeroBytes(word^quotes) | zeroBytes(word^backslashes) | controls; found != 0 {
+			return i + bits.TrailingZeros64(found)/8
+		}
+	}
+
+	for ; i < len(b); i++ {
+		if b[i] == '"' || b[i] == '\\' || b[i] < 0x20 {
+			return i
+		}
+	}
+
+	return -1
+}
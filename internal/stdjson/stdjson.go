@@ -0,0 +1,373 @@
+// Package stdjson is the implementation of the compat package. compat forwards its frozen API to
+// it, so what's here can change freely as long as compat keeps behaving the same.
+package stdjson
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"unicode/utf8"
+
+	"github.com/michaelhelvey/orderedjson/v2/compression"
+	"github.com/michaelhelvey/orderedjson/v2/internal/textenc"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type JsonObject = orderedmap.OrderedMap[string, interface{}]
+
+type (
+	Token              = json.Token
+	UnmarshalTypeError = json.UnmarshalTypeError
+)
+
+var (
+	ErrUnexpectedEOF = io.ErrUnexpectedEOF
+	ErrInvalidUTF8   = errors.New("json: invalid UTF-8")
+)
+
+func Unmarshal(data []byte, v interface{}) error {
+	return unmarshal(data, v, false)
+}
+
+func unmarshal(data []byte, v interface{}, useNumber bool) error {
+	return unmarshalContext(context.Background(), data, v, useNumber)
+}
+
+func unmarshalContext(ctx context.Context, data []byte, v interface{}, useNumber bool) error {
+	switch target := v.(type) {
+	case *interface{}:
+		value, err := decodeOrdered(ctx, data, useNumber)
+		if err != nil {
+			return err
+		}
+
+		*target = value
+		return nil
+	case *JsonObject:
+		value, err := decodeOrdered(ctx, data, useNumber)
+		if err != nil {
+			return err
+		}
+
+		object, ok := value.(*JsonObject)
+		if !ok {
+			return &UnmarshalTypeError{Value: describe(value), Type: reflect.TypeOf(target).Elem()}
+		}
+
+		*target = *object
+		return nil
+	case **JsonObject:
+		value, err := decodeOrdered(ctx, data, useNumber)
+		if err != nil {
+			return err
+		}
+
+		if value == nil {
+			*target = nil
+			return nil
+		}
+
+		object, ok := value.(*JsonObject)
+		if !ok {
+			return &UnmarshalTypeError{Value: describe(value), Type: reflect.TypeOf(target).Elem()}
+		}
+
+		*target = object
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if useNumber {
+		decoder.UseNumber()
+	}
+
+	return decoder.Decode(v)
+}
+
+func describe(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case *JsonObject:
+		return "object"
+	}
+
+	return "number"
+}
+
+func decodeOrdered(ctx context.Context, data []byte, useNumber bool) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if useNumber {
+		decoder.UseNumber()
+	}
+
+	value, err := decodeValue(ctx, decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		// encoding/json checks the whole input first, so this gets its *SyntaxError and offset
+		var raw json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("json: invalid character after top-level value")
+	}
+
+	return value, nil
+}
+
+// ctx is checked before every member of an object or array, so a huge document can be abandoned
+func decodeValue(ctx context.Context, decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+
+	switch delim {
+	case '{':
+		result := orderedmap.New[string, interface{}]()
+		for decoder.More() {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := decodeValue(ctx, decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			result.Set(key.(string), value)
+		}
+
+		_, err := decoder.Token()
+		return result, err
+	case '[':
+		result := make([]interface{}, 0)
+		for decoder.More() {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			value, err := decodeValue(ctx, decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, value)
+		}
+
+		_, err := decoder.Token()
+		return result, err
+	}
+
+	return nil, fmt.Errorf("invalid character %q", rune(delim))
+}
+
+type Decoder struct {
+	decoder   *json.Decoder
+	input     *inputReader
+	useNumber bool
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	input := &inputReader{source: r}
+	return &Decoder{decoder: json.NewDecoder(input), input: input}
+}
+
+func (dec *Decoder) SkipBOM() {
+	dec.input.skipBOM = true
+}
+
+func (dec *Decoder) DisableTranscoding() {
+	dec.input.noTranscode = true
+}
+
+func (dec *Decoder) RejectInvalidUTF8() {
+	dec.input.strictUTF8 = true
+}
+
+func (dec *Decoder) Decompress() {
+	dec.input.decompress = true
+}
+
+// sets up the decompression, transcoding and BOM skipping on the first read, once the options are
+// known
+type inputReader struct {
+	source      io.Reader
+	reader      *bufio.Reader
+	decompress  bool
+	skipBOM     bool
+	noTranscode bool
+	strictUTF8  bool
+	// set during DecodeContext
+	ctx    context.Context
+	offset int64
+	err    error
+}
+
+func (r *inputReader) Read(p []byte) (int, error) {
+	if r.ctx != nil {
+		if err := r.ctx.Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.reader == nil {
+		if r.decompress {
+			decompressed, err := compression.NewReader(r.source)
+			if err != nil {
+				return 0, err
+			}
+
+			r.source = decompressed
+		}
+
+		if r.noTranscode {
+			r.reader = bufio.NewReader(r.source)
+		} else {
+			r.reader = bufio.NewReader(textenc.NewReader(r.source))
+		}
+
+		if r.skipBOM {
+			if prefix, _ := r.reader.Peek(3); bytes.Equal(prefix, []byte{0xef, 0xbb, 0xbf}) {
+				r.reader.Discard(3)
+			}
+		}
+	}
+
+	if !r.strictUTF8 {
+		return r.reader.Read(p)
+	}
+
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	n := 0
+	for n+utf8.UTFMax <= len(p) {
+		char, size, err := r.reader.ReadRune()
+		if err != nil {
+			if n > 0 && err == io.EOF {
+				break
+			}
+
+			return n, err
+		}
+
+		if char == utf8.RuneError && size == 1 {
+			// encoding/json ignores errors from reads that also returned data, so hand back what we
+			// have and fail on the next read
+			r.err = fmt.Errorf("%w at offset %d", ErrInvalidUTF8, r.offset)
+			return n, nil
+		}
+
+		n += utf8.EncodeRune(p[n:], char)
+		r.offset += int64(size)
+
+		if r.reader.Buffered() == 0 {
+			// don't block waiting for more input if we already have something to return
+			break
+		}
+	}
+
+	return n, nil
+}
+
+func (dec *Decoder) Decode(v interface{}) error {
+	return dec.DecodeContext(context.Background(), v)
+}
+
+func (dec *Decoder) DecodeContext(ctx context.Context, v interface{}) error {
+	dec.input.ctx = ctx
+	defer func() { dec.input.ctx = nil }()
+
+	switch v.(type) {
+	case *interface{}, *JsonObject, **JsonObject:
+		var raw json.RawMessage
+		if err := dec.decoder.Decode(&raw); err != nil {
+			return err
+		}
+
+		return unmarshalContext(ctx, raw, v, dec.useNumber)
+	}
+
+	return dec.decoder.Decode(v)
+}
+
+func (dec *Decoder) DecodeArrayFunc(fn func(index int, value interface{}) error) error {
+	token, err := dec.decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("json: expected an array, got %v", token)
+	}
+
+	for index := 0; dec.decoder.More(); index++ {
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+
+		if err := fn(index, value); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.decoder.Token()
+	return err
+}
+
+func (dec *Decoder) UseNumber() {
+	dec.useNumber = true
+	dec.decoder.UseNumber()
+}
+
+func (dec *Decoder) DisallowUnknownFields() {
+	dec.decoder.DisallowUnknownFields()
+}
+
+func (dec *Decoder) Skip() error {
+	var raw json.RawMessage
+	return dec.decoder.Decode(&raw)
+}
+
+func (dec *Decoder) More() bool {
+	return dec.decoder.More()
+}
+
+func (dec *Decoder) Buffered() io.Reader {
+	return dec.decoder.Buffered()
+}
+
+func (dec *Decoder) InputOffset() int64 {
+	return dec.decoder.InputOffset()
+}
+
+func (dec *Decoder) Token() (Token, error) {
+	return dec.decoder.Token()
+}
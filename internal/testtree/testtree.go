@@ -0,0 +1,46 @@
+// Package testtree has the helpers the tests of the format packages share, which can't use the
+// orderedjson package itself without an import cycle. trees are read and written with compat, so
+// objects keep their key order.
+package testtree
+
+import (
+	"testing"
+
+	"github.com/michaelhelvey/orderedjson/v2/compat"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type JsonObject = orderedmap.OrderedMap[string, interface{}]
+
+// Parse is the tree of input, which has to be json.
+func Parse(t testing.TB, input string) interface{} {
+	t.Helper()
+	var tree interface{}
+	if err := compat.Unmarshal([]byte(input), &tree); err != nil {
+		t.Fatalf("Unmarshal(%s): %v", input, err)
+	}
+
+	return tree
+}
+
+// Marshal is value as compact json.
+func Marshal(t testing.TB, value interface{}) string {
+	t.Helper()
+	text, err := compat.Marshal(value)
+	if err != nil {
+		t.Fatalf("Marshal(%v): %v", value, err)
+	}
+
+	return string(text)
+}
+
+// Object is Parse for input that has to be an object.
+func Object(t testing.TB, input string) *JsonObject {
+	t.Helper()
+	tree, ok := Parse(t, input).(*JsonObject)
+	if !ok {
+		t.Fatalf("Unmarshal(%s): not an object", input)
+	}
+
+	return tree
+}
@@ -0,0 +1,208 @@
+// Package textenc detects UTF-16 and UTF-32 encoded json (as allowed back in RFC 4627) and
+// transcodes it to UTF-8.
+package textenc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+type Encoding int
+
+const (
+	UTF8 Encoding = iota
+	UTF16BE
+	UTF16LE
+	UTF32BE
+	UTF32LE
+)
+
+func (enc Encoding) String() string {
+	return [...]string{"UTF-8", "UTF-16BE", "UTF-16LE", "UTF-32BE", "UTF-32LE"}[enc]
+}
+
+// Detect guesses the encoding from the first four bytes of a document, and returns the length of
+// its byte order mark (zero if there isn't one). without a BOM it uses the null byte pattern from
+// RFC 4627 section 3, which works because a json text always starts with an ascii character.
+func Detect(prefix []byte) (Encoding, int) {
+	switch {
+	case hasPrefix(prefix, 0x00, 0x00, 0xfe, 0xff):
+		return UTF32BE, 4
+	case hasPrefix(prefix, 0xff, 0xfe, 0x00, 0x00):
+		return UTF32LE, 4
+	case hasPrefix(prefix, 0xfe, 0xff):
+		return UTF16BE, 2
+	case hasPrefix(prefix, 0xff, 0xfe):
+		return UTF16LE, 2
+	case hasPrefix(prefix, 0xef, 0xbb, 0xbf):
+		return UTF8, 3
+	}
+
+	if len(prefix) >= 4 {
+		switch {
+		case prefix[0] == 0 && prefix[1] == 0 && prefix[2] == 0 && prefix[3] != 0:
+			return UTF32BE, 0
+		case prefix[0] != 0 && prefix[1] == 0 && prefix[2] == 0 && prefix[3] == 0:
+			return UTF32LE, 0
+		}
+	}
+
+	if len(prefix) >= 2 {
+		switch {
+		case prefix[0] == 0 && prefix[1] != 0:
+			return UTF16BE, 0
+		case prefix[0] != 0 && prefix[1] == 0:
+			return UTF16LE, 0
+		}
+	}
+
+	return UTF8, 0
+}
+
+func hasPrefix(data []byte, prefix ...byte) bool {
+	if len(data) < len(prefix) {
+		return false
+	}
+
+	for i, b := range prefix {
+		if data[i] != b {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ToUTF8 transcodes data to UTF-8 if it is UTF-16 or UTF-32, dropping the BOM. UTF-8 input is
+// returned as is.
+func ToUTF8(data []byte) ([]byte, error) {
+	enc, bom := Detect(data)
+	if enc == UTF8 {
+		return data, nil
+	}
+
+	return io.ReadAll(&reader{src: bufio.NewReader(bytes.NewReader(data[bom:])), enc: enc, detected: true})
+}
+
+// NewReader returns a reader that transcodes the input to UTF-8 if it turns out to be UTF-16 or
+// UTF-32. UTF-8 input (including its BOM) passes through untouched.
+func NewReader(r io.Reader) io.Reader {
+	return &reader{src: bufio.NewReader(r)}
+}
+
+type reader struct {
+	src      *bufio.Reader
+	enc      Encoding
+	detected bool
+	offset   int64
+	pending  []byte
+	// the error that ended the input, returned once pending is drained
+	err error
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if !r.detected {
+		r.detected = true
+		prefix, _ := r.src.Peek(4)
+		enc, bom := Detect(prefix)
+		r.enc = enc
+		if enc != UTF8 {
+			r.src.Discard(bom)
+			r.offset += int64(bom)
+		}
+	}
+
+	if r.enc == UTF8 {
+		return r.src.Read(p)
+	}
+
+	for len(r.pending) < len(p) && r.err == nil {
+		char, err := r.readRune()
+		if err != nil {
+			// kept, so it comes back after what's pending instead of getting lost in a caller's buffer
+			r.err = err
+			break
+		}
+
+		r.pending = utf8.AppendRune(r.pending, char)
+	}
+
+	if len(r.pending) == 0 && len(p) > 0 {
+		return 0, r.err
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *reader) readUnit(size int) (uint32, error) {
+	var buf [4]byte
+	n, err := io.ReadFull(r.src, buf[:size])
+	r.offset += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		return 0, fmt.Errorf("%s input ends in the middle of a character at offset %d", r.enc, r.offset)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	switch r.enc {
+	case UTF16BE:
+		return uint32(binary.BigEndian.Uint16(buf[:2])), nil
+	case UTF16LE:
+		return uint32(binary.LittleEndian.Uint16(buf[:2])), nil
+	case UTF32BE:
+		return binary.BigEndian.Uint32(buf[:4]), nil
+	}
+
+	return binary.LittleEndian.Uint32(buf[:4]), nil
+}
+
+func (r *reader) readRune() (rune, error) {
+	if r.enc == UTF32BE || r.enc == UTF32LE {
+		unit, err := r.readUnit(4)
+		if err != nil {
+			return 0, err
+		}
+
+		if !utf8.ValidRune(rune(unit)) {
+			return 0, fmt.Errorf("invalid %s code point 0x%x at offset %d", r.enc, unit, r.offset-4)
+		}
+
+		return rune(unit), nil
+	}
+
+	unit, err := r.readUnit(2)
+	if err != nil {
+		return 0, err
+	}
+
+	if !utf16.IsSurrogate(rune(unit)) {
+		return rune(unit), nil
+	}
+
+	// a lone surrogate turns into U+FFFD, same as encoding/json does for \u escapes. only consume the
+	// next unit if it actually is the second half of the pair.
+	next, _ := r.src.Peek(2)
+	if unit < 0xdc00 && len(next) == 2 {
+		low := binary.BigEndian.Uint16(next)
+		if r.enc == UTF16LE {
+			low = binary.LittleEndian.Uint16(next)
+		}
+
+		if char := utf16.DecodeRune(rune(unit), rune(low)); char != utf8.RuneError {
+			r.src.Discard(2)
+			r.offset += 2
+			return char, nil
+		}
+	}
+
+	return utf8.RuneError, nil
+}
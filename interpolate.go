@@ -0,0 +1,208 @@
+package orderedjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// Interpolate returns a copy of tree with ${...} references in string values expanded. a reference
+// is first looked up as a dotted path in the tree itself (${server.port}, ${hosts.0}) and otherwise
+// as an environment variable through lookupEnv (os.LookupEnv when nil). ${NAME:-default} falls back
+// to the string default when NAME is neither, or is an empty environment variable, like a shell
+// does. $${ is an escaped, literal ${.
+//
+// a string that is nothing but a single reference takes the referenced value as is, so
+// "${server.port}" can stay a number. references inside longer strings have to point at strings,
+// numbers, booleans or null. referenced values are expanded too, and reference cycles are an error.
+func Interpolate(tree *JsonObject, lookupEnv func(name string) (string, bool)) (*JsonObject, error) {
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+
+	interpolator := &interpolator{
+		root:      tree,
+		lookupEnv: lookupEnv,
+		resolved:  make(map[string]interface{}),
+	}
+
+	result, err := interpolator.expand(tree, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*JsonObject), nil
+}
+
+type interpolator struct {
+	root      *JsonObject
+	lookupEnv func(name string) (string, bool)
+	resolved  map[string]interface{}
+	// the references being resolved right now, to find cycles
+	stack []string
+}
+
+func (interpolator *interpolator) expand(value interface{}, path string) (interface{}, error) {
+	switch v := value.(type) {
+	case *JsonObject:
+		result := orderedmap.New[string, interface{}]()
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			child, err := interpolator.expand(pair.Value, joinReference(path, pair.Key))
+			if err != nil {
+				return nil, err
+			}
+
+			result.Set(pair.Key, child)
+		}
+
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for i, item := range v {
+			child, err := interpolator.expand(item, joinReference(path, strconv.Itoa(i)))
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, child)
+		}
+
+		return result, nil
+	case string:
+		return interpolator.expandString(v, path)
+	}
+
+	return value, nil
+}
+
+func joinReference(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func (interpolator *interpolator) expandString(s string, path string) (interface{}, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	// a lone reference keeps the type of whatever it points to
+	if strings.HasPrefix(s, "${") && strings.Index(s, "}") == len(s)-1 {
+		return interpolator.resolve(s[2:len(s)-1], path)
+	}
+
+	var result strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			result.WriteString(s)
+			return result.String(), nil
+		}
+
+		if start > 0 && s[start-1] == '$' {
+			result.WriteString(s[:start-1] + "${")
+			s = s[start+2:]
+			continue
+		}
+
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("%s: unterminated reference in %q", path, s)
+		}
+
+		value, err := interpolator.resolve(s[start+2:start+end], path)
+		if err != nil {
+			return nil, err
+		}
+
+		result.WriteString(s[:start])
+		switch v := value.(type) {
+		case string:
+			result.WriteString(v)
+		case float64:
+			result.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+		case json.Number:
+			result.WriteString(string(v))
+		case bool:
+			result.WriteString(strconv.FormatBool(v))
+		case nil:
+			result.WriteString("null")
+		default:
+			return nil, fmt.Errorf("%s: cannot interpolate %s ${%s} into a string", path, TypeName(value), s[start+2:start+end])
+		}
+
+		s = s[start+end+1:]
+	}
+}
+
+func (interpolator *interpolator) resolve(reference string, from string) (interface{}, error) {
+	if value, done := interpolator.resolved[reference]; done {
+		return value, nil
+	}
+
+	for i, ref := range interpolator.stack {
+		if ref == reference {
+			cycle := append(append([]string{}, interpolator.stack[i:]...), reference)
+			return nil, fmt.Errorf("%s: reference cycle %s", from, strings.Join(cycle, " -> "))
+		}
+	}
+
+	name, fallback, hasDefault := strings.Cut(reference, ":-")
+	raw, found := lookupReference(interpolator.root, name)
+	if !found {
+		env, ok := interpolator.lookupEnv(name)
+		if hasDefault && (!ok || env == "") {
+			env, ok = fallback, true
+		}
+
+		if !ok {
+			return nil, fmt.Errorf("%s: ${%s} is neither a key in the document nor an environment variable", from, reference)
+		}
+
+		interpolator.resolved[reference] = env
+		return env, nil
+	}
+
+	interpolator.stack = append(interpolator.stack, reference)
+	value, err := interpolator.expand(raw, reference)
+	interpolator.stack = interpolator.stack[:len(interpolator.stack)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	interpolator.resolved[reference] = value
+	return value, nil
+}
+
+// finds the value at a dotted path like server.hosts.0
+func lookupReference(tree *JsonObject, reference string) (interface{}, bool) {
+	var current interface{} = tree
+	for _, part := range strings.Split(reference, ".") {
+		switch v := current.(type) {
+		case *JsonObject:
+			value, present := v.Get(part)
+			if !present {
+				return nil, false
+			}
+
+			current = value
+		case []interface{}:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, false
+			}
+
+			current = v[idx]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
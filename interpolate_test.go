@@ -0,0 +1,125 @@
+package orderedjson
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	env := map[string]string{"HOST": "example.com", "PORT": "80", "EMPTY": ""}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{"url":"http://${HOST}:${PORT}/","host":"${HOST}"}`, `{"url":"http://example.com:80/","host":"example.com"}`},
+		// keys of the document come before the environment, and a lone reference keeps its type
+		{`{"HOST":"local","port":8080,"url":"${HOST}:${port}","copy":"${port}"}`, `{"HOST":"local","port":8080,"url":"local:8080","copy":8080}`},
+		{`{"hosts":["a",{"name":"b"}],"first":"${hosts.0}","second":"${hosts.1.name}","all":"${hosts}"}`, `{"hosts":["a",{"name":"b"}],"first":"a","second":"b","all":["a",{"name":"b"}]}`},
+		{`{"a":"${b}!","b":"${c}?","c":"${HOST}"}`, `{"a":"example.com?!","b":"example.com?","c":"example.com"}`},
+		{`{"s":"${t} ${f} ${n} ${x}","t":true,"f":false,"n":null,"x":0.5}`, `{"s":"true false null 0.5","t":true,"f":false,"n":null,"x":0.5}`},
+		{`{"lone":"${n}","n":null}`, `{"lone":null,"n":null}`},
+
+		// defaults
+		{`{"a":"${MISSING:-fallback}","b":"${EMPTY:-fallback}","c":"${HOST:-fallback}","d":"${MISSING:-}","e":"${EMPTY}"}`, `{"a":"fallback","b":"fallback","c":"example.com","d":"","e":""}`},
+		{`{"port":8080,"a":"${port:-1}","b":"${nope:-1}","c":"at ${MISSING:-a b:-c}"}`, `{"port":8080,"a":8080,"b":"1","c":"at a b:-c"}`},
+
+		// escapes
+		{`{"a":"$${HOST}","b":"cost: $${HOST} is ${HOST}","c":"$5 and $ {HOST}","d":"$${"}`, `{"a":"${HOST}","b":"cost: ${HOST} is example.com","c":"$5 and $ {HOST}","d":"${"}`},
+
+		// everything but strings is left alone
+		{`{"n":1.5,"b":true,"z":null,"l":[1,[{}],"${PORT}"],"o":{"$":"$"}}`, `{"n":1.5,"b":true,"z":null,"l":[1,[{}],"80"],"o":{"$":"$"}}`},
+		{`{}`, `{}`},
+	}
+
+	for _, test := range tests {
+		tree, err := ParseOptions{}.Parse([]byte(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := Interpolate(tree, lookupEnv)
+		if err != nil {
+			t.Errorf("Interpolate(%s): %v", test.input, err)
+			continue
+		}
+
+		if got, _ := MarshalCompact(result); got != test.want {
+			t.Errorf("Interpolate(%s) = %s, want %s", test.input, got, test.want)
+		}
+
+		// the tree itself isn't changed
+		if got, _ := MarshalCompact(tree); got != test.input {
+			t.Errorf("Interpolate(%s) changed the tree to %s", test.input, got)
+		}
+	}
+}
+
+func TestInterpolateNumbers(t *testing.T) {
+	tree, err := ParseOptions{Numbers: NumberJSONNumber}.Parse([]byte(`{"id":12345678901234567891,"name":"item-${id}","copy":"${id}","e":1.50e2,"s":"e=${e}"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Interpolate(tree, func(string) (string, bool) { return "", false })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := MarshalCompact(result); got != `{"id":12345678901234567891,"name":"item-12345678901234567891","copy":12345678901234567891,"e":1.50e2,"s":"e=1.50e2"}` {
+		t.Errorf("Interpolate = %s", got)
+	}
+
+	if copied, _ := result.Get("copy"); copied != json.Number("12345678901234567891") {
+		t.Errorf("copy = %#v, want a json.Number", copied)
+	}
+}
+
+func TestInterpolateEnviron(t *testing.T) {
+	t.Setenv("ORDEREDJSON_TEST_HOST", "from-env")
+	tree, _ := ParseOptions{}.Parse([]byte(`{"host":"${ORDEREDJSON_TEST_HOST}"}`))
+	result, err := Interpolate(tree, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := MarshalCompact(result); got != `{"host":"from-env"}` {
+		t.Errorf("Interpolate = %s", got)
+	}
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	lookupEnv := func(name string) (string, bool) {
+		return "x", name == "SET"
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{"a":"${MISSING}"}`, "a: ${MISSING} is neither a key in the document nor an environment variable"},
+		{`{"o":{"a":"at ${MISSING}"}}`, "o.a: ${MISSING} is neither"},
+		{`{"a":"${hosts.5}","hosts":[]}`, "a: ${hosts.5} is neither"},
+		{`{"a":"${b}","b":"${a}"}`, "a: reference cycle b -> a -> b"},
+		{`{"a":"${a}"}`, "a: reference cycle a -> a"},
+		{`{"a":"x${o}","o":{}}`, "a: cannot interpolate object ${o} into a string"},
+		{`{"a":"x${l}","l":[]}`, "a: cannot interpolate array ${l} into a string"},
+		{`{"a":"${SET} ${unterminated"}`, "a: unterminated reference"},
+	}
+
+	for _, test := range tests {
+		tree, err := ParseOptions{}.Parse([]byte(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result, err := Interpolate(tree, lookupEnv); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Interpolate(%s) = %v, %v, want %q", test.input, result, err, test.want)
+		}
+	}
+}
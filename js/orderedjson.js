@@ -0,0 +1,47 @@
+// parse and stringify from the go code, built with `just wasm`, for browsers and node. objects are
+// Maps so they keep their key order, even for keys like "2" that plain objects move to the front:
+//
+//	import { load } from "./orderedjson.js";
+//
+//	const json = await load(fetch("orderedjson.wasm"));
+//	const tree = json.parse(text);
+//	tree.get("dependencies").set("react", "^19.0.0");
+//	const updated = json.stringify(tree, { indent: 2 });
+//
+// wasm_exec.js (copied from the go install by `just wasm`) has to be loaded first, it's what
+// defines globalThis.Go.
+
+// source is the wasm module: a Response (or a promise of one, like fetch returns), or its bytes
+export async function load(source) {
+	const go = new globalThis.Go();
+	source = await source;
+	const { instance } =
+		typeof Response !== "undefined" && source instanceof Response
+			? await WebAssembly.instantiateStreaming(source, go.importObject)
+			: await WebAssembly.instantiate(source, go.importObject);
+
+	// runs until the program exits, which it doesn't, it waits for calls
+	go.run(instance);
+	const exports = globalThis.orderedJSON;
+
+	return {
+		// options: comments (allow comments and trailing commas), maxDepth
+		parse(text, options = {}) {
+			const result = exports.parse(String(text), options);
+			if (result.error !== undefined) {
+				const where = result.line ? ` at line ${result.line}, column ${result.column}` : "";
+				throw new SyntaxError(result.error + where);
+			}
+			return result.value;
+		},
+
+		// options: indent (a string or a number of spaces), maxWidth, compact, sortKeys
+		stringify(value, options = {}) {
+			const result = exports.stringify(value, options);
+			if (result.error !== undefined) {
+				throw new TypeError(result.error);
+			}
+			return result.value;
+		},
+	};
+}
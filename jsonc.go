@@ -0,0 +1,220 @@
+package orderedjson
+
+import (
+	"bytes"
+	"strings"
+)
+
+// ParseJSONC parses JSON with comments, like tsconfig.json or VS Code's settings.json: // and /* */
+// comments are allowed anywhere json allows whitespace, and so are trailing commas. each comment is
+// kept in the document's Meta, as metadata of the key it belongs to: CommentMeta for the ones on the
+// lines before a key, TrailingCommentMeta for the ones after its value. since metadata goes by the
+// key, setting and sorting keys keeps their comments with them, and writing the tree with
+// MarshalOptions.Comments and Meta (or the same in FormatOptions) gives the file back with its
+// comments.
+//
+// comments without a key of their own, around array elements or before the closing brace of an
+// object, go with the nearest key around them. they're written back as // comments.
+func ParseJSONC(data []byte) (*Document, error) {
+	return ParseOptions{}.parseJSONCDocument(data)
+}
+
+type jsoncComment struct {
+	start, end int
+	text       string
+}
+
+// parses data as a document with its comments and trailing commas blanked out, so the spans still
+// point into the original text, and attaches the comments to the tree. the document keeps the
+// original text, comments and all.
+func (opts ParseOptions) parseJSONCDocument(data []byte) (*Document, error) {
+	original := append([]byte{}, bytes.TrimPrefix(data, utf8BOM)...)
+
+	// the document itself is plain json once they're blanked out
+	plain := opts
+	plain.Comments = false
+	text := append([]byte{}, original...)
+	comments, err := blankComments(text)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := plain.ParseDocument(text)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, comment := range comments {
+		doc.attachComment(comment)
+	}
+
+	doc.Text, doc.opts = original, opts
+	doc.opts.Comments = true
+	return doc, nil
+}
+
+// replaces comments and trailing commas in text with spaces (keeping line breaks), and returns the
+// comments
+func blankComments(text []byte) ([]jsoncComment, error) {
+	var comments []jsoncComment
+	// the last character that isn't whitespace or a comment
+	last := -1
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '"':
+			for i++; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' {
+					i++
+				}
+			}
+
+			last = i
+		case bytes.HasPrefix(text[i:], []byte("//")):
+			end := bytes.IndexByte(text[i:], '\n')
+			if end < 0 {
+				end = len(text) - i
+			}
+
+			comments = append(comments, jsoncComment{start: i, end: i + end, text: commentText(text[i : i+end])})
+			blank(text[i : i+end])
+			i += end - 1
+		case bytes.HasPrefix(text[i:], []byte("/*")):
+			end := bytes.Index(text[i+2:], []byte("*/"))
+			if end < 0 {
+				return nil, newSyntaxError(text, i, ErrUnexpectedEOF, "unterminated /* comment")
+			}
+
+			end += 4
+			comments = append(comments, jsoncComment{start: i, end: i + end, text: commentText(text[i : i+end])})
+			blank(text[i : i+end])
+			i += end - 1
+		case isSpace(text[i]):
+		default:
+			if (text[i] == '}' || text[i] == ']') && last >= 0 && text[last] == ',' {
+				text[last] = ' '
+			}
+
+			last = i
+		}
+	}
+
+	return comments, nil
+}
+
+func blank(text []byte) {
+	for i, c := range text {
+		if c != '\n' && c != '\r' {
+			text[i] = ' '
+		}
+	}
+}
+
+// the text of a comment without its // or /* */, and without the * that block comments often have
+// at the start of each line
+func commentText(comment []byte) string {
+	if bytes.HasPrefix(comment, []byte("//")) {
+		text := strings.TrimSuffix(string(comment[2:]), "\r")
+		return strings.TrimRight(strings.TrimPrefix(text, " "), " \t")
+	}
+
+	lines := strings.Split(string(comment[2:len(comment)-2]), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if i > 0 {
+			line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+		}
+
+		lines[i] = line
+	}
+
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+func (doc *Document) attachComment(comment jsoncComment) {
+	// after a value on the same line
+	before := comment.start - 1
+	for before >= 0 && (doc.Text[before] == ' ' || doc.Text[before] == '\t') {
+		before--
+	}
+
+	if before >= 0 && doc.Text[before] == ',' {
+		before--
+		for before >= 0 && (doc.Text[before] == ' ' || doc.Text[before] == '\t') {
+			before--
+		}
+	}
+
+	if before >= 0 && doc.Text[before] != '\n' && doc.Text[before] != '\r' {
+		for _, span := range doc.spans {
+			if span.end == before+1 && isMember(span.path) {
+				doc.addComment(span.path, TrailingCommentMeta, comment.text, " ")
+				return
+			}
+		}
+	}
+
+	next := comment.end
+	for next < len(doc.Text) && isSpace(doc.Text[next]) {
+		next++
+	}
+
+	// on its own line at the end of an object or array, after the value before it
+	if next == len(doc.Text) || doc.Text[next] == '}' || doc.Text[next] == ']' {
+		var previous Path
+		end := -1
+		for _, span := range doc.spans {
+			if span.end <= comment.start && span.end > end && enclosingMember(span.path) != nil {
+				previous, end = enclosingMember(span.path), span.end
+			}
+		}
+
+		if previous != nil {
+			doc.addComment(previous, TrailingCommentMeta, "\n"+comment.text, "")
+		}
+
+		return
+	}
+
+	// before the next key, or the key of the value it's in front of
+	var following Path
+	start := len(doc.Text)
+	for _, span := range doc.spans {
+		if span.start >= next && span.start < start && enclosingMember(span.path) != nil {
+			following, start = enclosingMember(span.path), span.start
+		}
+	}
+
+	if following != nil {
+		doc.addComment(following, CommentMeta, comment.text, "\n")
+	}
+}
+
+// adds text to the comment called name of the key at path, after sep if there's one already
+func (doc *Document) addComment(path Path, name, text, sep string) {
+	if existing := doc.Meta.comment(path, name); existing != "" {
+		text = existing + sep + text
+	}
+
+	doc.Meta.Set(path, name, text)
+}
+
+// whether path is of a value in an object, which can have comments
+func isMember(path Path) bool {
+	if len(path) == 0 {
+		return false
+	}
+
+	_, ok := path[len(path)-1].(string)
+	return ok
+}
+
+// the path of the innermost key that path is at or under, nil for the top level object
+func enclosingMember(path Path) Path {
+	for i := len(path); i > 0; i-- {
+		if isMember(path[:i]) {
+			return path[:i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,913 @@
+// Package jsonpath runs JSONPath (RFC 9535) queries on ordered json trees. everything in the RFC is
+// here except for function extensions other than the five standard ones (length, count, match,
+// search, value).
+package jsonpath
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type JsonObject = orderedmap.OrderedMap[string, interface{}]
+
+// Match is a node a query matched.
+type Match struct {
+	// normalized path of the match, e.g. $['store']['book'][0]['title']
+	Path  string
+	Value interface{}
+}
+
+// Query returns every node in tree matched by the JSONPath expression, in document order.
+func Query(tree interface{}, expr string) ([]Match, error) {
+	parser := &jsonPathParser{runes: []rune(expr)}
+	query, err := parser.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	parser.skipSpace()
+	if parser.idx < len(parser.runes) {
+		return nil, parser.errorf("unexpected %q", parser.runes[parser.idx])
+	}
+
+	if query.relative {
+		return nil, fmt.Errorf("jsonpath: query must start with $")
+	}
+
+	return query.eval(tree, tree), nil
+}
+
+// ast:
+
+type jsonPathQuery struct {
+	relative bool // starts with @ instead of $
+	segments []jsonPathSegment
+}
+
+type jsonPathSegment struct {
+	descendant bool
+	selectors  []jsonPathSelector
+}
+
+type jsonPathSelectorKind int
+
+const (
+	nameSelector jsonPathSelectorKind = iota
+	wildcardSelector
+	indexSelector
+	sliceSelector
+	filterSelector
+)
+
+type jsonPathSelector struct {
+	kind   jsonPathSelectorKind
+	name   string
+	index  int
+	slice  [3]*int
+	filter jsonPathExpr
+}
+
+type jsonPathExpr interface {
+	// filter expressions evaluate to either a logical value, a single value, or a list of nodes
+	evalFilter(root, current interface{}) interface{}
+}
+
+// returned when a singular query doesn't match anything
+type jsonPathNothing struct{}
+
+type jsonPathNodes []Match
+
+type jsonPathLiteral struct {
+	value interface{}
+}
+
+type jsonPathLogical struct {
+	op       string // "&&", "||", "!"
+	lhs, rhs jsonPathExpr
+}
+
+type jsonPathComparison struct {
+	op       string
+	lhs, rhs jsonPathExpr
+}
+
+type jsonPathFunction struct {
+	name string
+	args []jsonPathExpr
+}
+
+// parsing:
+
+type jsonPathParser struct {
+	runes []rune
+	idx   int
+}
+
+func (parser *jsonPathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("jsonpath: %s at %d", fmt.Sprintf(format, args...), parser.idx)
+}
+
+func (parser *jsonPathParser) skipSpace() {
+	for parser.idx < len(parser.runes) && strings.ContainsRune(" \t\n\r", parser.runes[parser.idx]) {
+		parser.idx++
+	}
+}
+
+func (parser *jsonPathParser) peekString(s string) bool {
+	return strings.HasPrefix(string(parser.runes[parser.idx:]), s)
+}
+
+func (parser *jsonPathParser) consume(s string) bool {
+	if parser.peekString(s) {
+		parser.idx += len([]rune(s))
+		return true
+	}
+
+	return false
+}
+
+func (parser *jsonPathParser) parseQuery() (*jsonPathQuery, error) {
+	query := &jsonPathQuery{}
+	if parser.consume("@") {
+		query.relative = true
+	} else if !parser.consume("$") {
+		return nil, parser.errorf("expected $ or @")
+	}
+
+	for {
+		// whitespace is allowed between segments, but we have to be careful not to eat the space
+		// in front of an operator in a filter
+		start := parser.idx
+		parser.skipSpace()
+		if !parser.peekString(".") && !parser.peekString("[") {
+			parser.idx = start
+			return query, nil
+		}
+
+		segment, err := parser.parseSegment()
+		if err != nil {
+			return nil, err
+		}
+
+		query.segments = append(query.segments, segment)
+	}
+}
+
+func (parser *jsonPathParser) parseSegment() (jsonPathSegment, error) {
+	segment := jsonPathSegment{}
+
+	if parser.consume("..") {
+		segment.descendant = true
+		if !parser.peekString("[") {
+			selector, err := parser.parseShorthand()
+			if err != nil {
+				return segment, err
+			}
+
+			segment.selectors = []jsonPathSelector{selector}
+			return segment, nil
+		}
+	} else if parser.consume(".") {
+		selector, err := parser.parseShorthand()
+		if err != nil {
+			return segment, err
+		}
+
+		segment.selectors = []jsonPathSelector{selector}
+		return segment, nil
+	}
+
+	if !parser.consume("[") {
+		return segment, parser.errorf("expected [")
+	}
+
+	for {
+		parser.skipSpace()
+		selector, err := parser.parseSelector()
+		if err != nil {
+			return segment, err
+		}
+
+		segment.selectors = append(segment.selectors, selector)
+
+		parser.skipSpace()
+		if parser.consume("]") {
+			return segment, nil
+		}
+
+		if !parser.consume(",") {
+			return segment, parser.errorf("expected , or ]")
+		}
+	}
+}
+
+// the bit after a `.` or `..`: either * or a member name
+func (parser *jsonPathParser) parseShorthand() (jsonPathSelector, error) {
+	if parser.consume("*") {
+		return jsonPathSelector{kind: wildcardSelector}, nil
+	}
+
+	name := parser.parseMemberName()
+	if name == "" {
+		return jsonPathSelector{}, parser.errorf("expected member name")
+	}
+
+	return jsonPathSelector{kind: nameSelector, name: name}, nil
+}
+
+func (parser *jsonPathParser) parseMemberName() string {
+	start := parser.idx
+	for parser.idx < len(parser.runes) {
+		char := parser.runes[parser.idx]
+		isFirst := parser.idx == start
+		if !(unicode.IsLetter(char) || char == '_' || char >= 0x80 || (!isFirst && unicode.IsDigit(char))) {
+			break
+		}
+
+		parser.idx++
+	}
+
+	return string(parser.runes[start:parser.idx])
+}
+
+func (parser *jsonPathParser) parseSelector() (jsonPathSelector, error) {
+	if parser.idx >= len(parser.runes) {
+		return jsonPathSelector{}, parser.errorf("unexpected end of query")
+	}
+
+	char := parser.runes[parser.idx]
+	switch {
+	case char == '\'' || char == '"':
+		name, err := parser.parseStringLiteral()
+		return jsonPathSelector{kind: nameSelector, name: name}, err
+	case char == '*':
+		parser.idx++
+		return jsonPathSelector{kind: wildcardSelector}, nil
+	case char == '?':
+		parser.idx++
+		parser.skipSpace()
+		filter, err := parser.parseOr()
+		return jsonPathSelector{kind: filterSelector, filter: filter}, err
+	}
+
+	// index or slice
+	var parts [3]*int
+	part := 0
+	for {
+		parser.skipSpace()
+		if parser.idx < len(parser.runes) && (parser.runes[parser.idx] == '-' || unicode.IsDigit(parser.runes[parser.idx])) {
+			n, err := parser.parseInt()
+			if err != nil {
+				return jsonPathSelector{}, err
+			}
+
+			parts[part] = &n
+		}
+
+		parser.skipSpace()
+		if part < 2 && parser.consume(":") {
+			part++
+			continue
+		}
+
+		break
+	}
+
+	if part == 0 {
+		if parts[0] == nil {
+			return jsonPathSelector{}, parser.errorf("invalid selector")
+		}
+
+		return jsonPathSelector{kind: indexSelector, index: *parts[0]}, nil
+	}
+
+	return jsonPathSelector{kind: sliceSelector, slice: parts}, nil
+}
+
+func (parser *jsonPathParser) parseInt() (int, error) {
+	start := parser.idx
+	if parser.peekString("-") {
+		parser.idx++
+	}
+
+	for parser.idx < len(parser.runes) && unicode.IsDigit(parser.runes[parser.idx]) {
+		parser.idx++
+	}
+
+	text := string(parser.runes[start:parser.idx])
+	if text == "-0" || (len(text) > 1 && text[0] == '0') || (len(text) > 2 && text[:2] == "-0") {
+		return 0, parser.errorf("invalid integer %q", text)
+	}
+
+	return strconv.Atoi(text)
+}
+
+func (parser *jsonPathParser) parseStringLiteral() (string, error) {
+	quote := parser.runes[parser.idx]
+	parser.idx++
+
+	var result strings.Builder
+	for parser.idx < len(parser.runes) {
+		char := parser.runes[parser.idx]
+		parser.idx++
+
+		if char == quote {
+			return result.String(), nil
+		}
+
+		if char != '\\' {
+			result.WriteRune(char)
+			continue
+		}
+
+		if parser.idx >= len(parser.runes) {
+			break
+		}
+
+		escaped := parser.runes[parser.idx]
+		parser.idx++
+		switch escaped {
+		case 'b':
+			result.WriteRune('\b')
+		case 'f':
+			result.WriteRune('\f')
+		case 'n':
+			result.WriteRune('\n')
+		case 'r':
+			result.WriteRune('\r')
+		case 't':
+			result.WriteRune('\t')
+		case 'u':
+			if parser.idx+4 > len(parser.runes) {
+				return "", parser.errorf("invalid unicode escape")
+			}
+
+			code, err := strconv.ParseUint(string(parser.runes[parser.idx:parser.idx+4]), 16, 32)
+			if err != nil {
+				return "", parser.errorf("invalid unicode escape")
+			}
+
+			parser.idx += 4
+			result.WriteRune(rune(code))
+		default:
+			result.WriteRune(escaped)
+		}
+	}
+
+	return "", parser.errorf("unterminated string")
+}
+
+func (parser *jsonPathParser) parseOr() (jsonPathExpr, error) {
+	lhs, err := parser.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		parser.skipSpace()
+		if !parser.consume("||") {
+			return lhs, nil
+		}
+
+		parser.skipSpace()
+		rhs, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		lhs = &jsonPathLogical{op: "||", lhs: lhs, rhs: rhs}
+	}
+}
+
+func (parser *jsonPathParser) parseAnd() (jsonPathExpr, error) {
+	lhs, err := parser.parseBasic()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		parser.skipSpace()
+		if !parser.consume("&&") {
+			return lhs, nil
+		}
+
+		parser.skipSpace()
+		rhs, err := parser.parseBasic()
+		if err != nil {
+			return nil, err
+		}
+
+		lhs = &jsonPathLogical{op: "&&", lhs: lhs, rhs: rhs}
+	}
+}
+
+func (parser *jsonPathParser) parseBasic() (jsonPathExpr, error) {
+	if parser.consume("!") {
+		parser.skipSpace()
+		inner, err := parser.parseBasic()
+		if err != nil {
+			return nil, err
+		}
+
+		return &jsonPathLogical{op: "!", lhs: inner}, nil
+	}
+
+	if parser.consume("(") {
+		parser.skipSpace()
+		inner, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		parser.skipSpace()
+		if !parser.consume(")") {
+			return nil, parser.errorf("expected )")
+		}
+
+		return inner, nil
+	}
+
+	lhs, err := parser.parseComparable()
+	if err != nil {
+		return nil, err
+	}
+
+	parser.skipSpace()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if parser.consume(op) {
+			parser.skipSpace()
+			rhs, err := parser.parseComparable()
+			if err != nil {
+				return nil, err
+			}
+
+			return &jsonPathComparison{op: op, lhs: lhs, rhs: rhs}, nil
+		}
+	}
+
+	if _, isLiteral := lhs.(*jsonPathLiteral); isLiteral {
+		return nil, parser.errorf("literal must be compared")
+	}
+
+	return lhs, nil
+}
+
+func (parser *jsonPathParser) parseComparable() (jsonPathExpr, error) {
+	if parser.idx >= len(parser.runes) {
+		return nil, parser.errorf("unexpected end of query")
+	}
+
+	char := parser.runes[parser.idx]
+	switch {
+	case char == '$' || char == '@':
+		return parser.parseQuery()
+	case char == '\'' || char == '"':
+		value, err := parser.parseStringLiteral()
+		return &jsonPathLiteral{value: value}, err
+	case char == '-' || unicode.IsDigit(char):
+		start := parser.idx
+		parser.idx++
+		for parser.idx < len(parser.runes) && strings.ContainsRune("0123456789.eE+-", parser.runes[parser.idx]) {
+			parser.idx++
+		}
+
+		value, err := strconv.ParseFloat(string(parser.runes[start:parser.idx]), 64)
+		if err != nil {
+			return nil, parser.errorf("invalid number")
+		}
+
+		return &jsonPathLiteral{value: value}, nil
+	case parser.consume("true"):
+		return &jsonPathLiteral{value: true}, nil
+	case parser.consume("false"):
+		return &jsonPathLiteral{value: false}, nil
+	case parser.consume("null"):
+		return &jsonPathLiteral{value: nil}, nil
+	}
+
+	name := parser.parseMemberName()
+	switch name {
+	case "length", "count", "match", "search", "value":
+	case "":
+		return nil, parser.errorf("unexpected %q", char)
+	default:
+		return nil, parser.errorf("unknown function %q", name)
+	}
+
+	if !parser.consume("(") {
+		return nil, parser.errorf("expected (")
+	}
+
+	function := &jsonPathFunction{name: name}
+	for {
+		parser.skipSpace()
+		arg, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		function.args = append(function.args, arg)
+
+		parser.skipSpace()
+		if parser.consume(")") {
+			break
+		}
+
+		if !parser.consume(",") {
+			return nil, parser.errorf("expected , or )")
+		}
+	}
+
+	expected := map[string]int{"length": 1, "count": 1, "match": 2, "search": 2, "value": 1}[name]
+	if len(function.args) != expected {
+		return nil, parser.errorf("%s() takes %d arguments", name, expected)
+	}
+
+	return function, nil
+}
+
+// evaluation:
+
+func (query *jsonPathQuery) eval(root, current interface{}) jsonPathNodes {
+	start := root
+	if query.relative {
+		start = current
+	}
+
+	nodes := jsonPathNodes{{Path: "$", Value: start}}
+	if query.relative {
+		nodes[0].Path = "@"
+	}
+
+	for _, segment := range query.segments {
+		next := jsonPathNodes{}
+		for _, node := range nodes {
+			targets := jsonPathNodes{node}
+			if segment.descendant {
+				targets = descendants(node)
+			}
+
+			for _, target := range targets {
+				for _, selector := range segment.selectors {
+					next = append(next, selector.apply(root, target)...)
+				}
+			}
+		}
+
+		nodes = next
+	}
+
+	return nodes
+}
+
+func (query *jsonPathQuery) evalFilter(root, current interface{}) interface{} {
+	return query.eval(root, current)
+}
+
+func (query *jsonPathQuery) isSingular() bool {
+	for _, segment := range query.segments {
+		if segment.descendant || len(segment.selectors) != 1 {
+			return false
+		}
+
+		if kind := segment.selectors[0].kind; kind != nameSelector && kind != indexSelector {
+			return false
+		}
+	}
+
+	return true
+}
+
+// the node itself followed by all of its descendants, in document order
+func descendants(node Match) jsonPathNodes {
+	result := jsonPathNodes{node}
+	for _, child := range children(node) {
+		result = append(result, descendants(child)...)
+	}
+
+	return result
+}
+
+func children(node Match) jsonPathNodes {
+	result := jsonPathNodes{}
+	switch container := node.Value.(type) {
+	case *JsonObject:
+		for pair := container.Oldest(); pair != nil; pair = pair.Next() {
+			result = append(result, Match{Path: node.Path + jsonPathName(pair.Key), Value: pair.Value})
+		}
+	case []interface{}:
+		for i, value := range container {
+			result = append(result, Match{Path: fmt.Sprintf("%s[%d]", node.Path, i), Value: value})
+		}
+	}
+
+	return result
+}
+
+func jsonPathName(name string) string {
+	var result strings.Builder
+	result.WriteString("['")
+	for _, char := range name {
+		switch {
+		case char == '\'':
+			result.WriteString(`\'`)
+		case char == '\\':
+			result.WriteString(`\\`)
+		case char == '\b':
+			result.WriteString(`\b`)
+		case char == '\f':
+			result.WriteString(`\f`)
+		case char == '\n':
+			result.WriteString(`\n`)
+		case char == '\r':
+			result.WriteString(`\r`)
+		case char == '\t':
+			result.WriteString(`\t`)
+		case char < 0x20:
+			result.WriteString(fmt.Sprintf(`\u%04x`, char))
+		default:
+			result.WriteRune(char)
+		}
+	}
+	result.WriteString("']")
+
+	return result.String()
+}
+
+func (selector jsonPathSelector) apply(root interface{}, node Match) jsonPathNodes {
+	switch selector.kind {
+	case nameSelector:
+		if object, ok := node.Value.(*JsonObject); ok {
+			if value, present := object.Get(selector.name); present {
+				return jsonPathNodes{{Path: node.Path + jsonPathName(selector.name), Value: value}}
+			}
+		}
+	case wildcardSelector:
+		return children(node)
+	case indexSelector:
+		if array, ok := node.Value.([]interface{}); ok {
+			i := selector.index
+			if i < 0 {
+				i += len(array)
+			}
+
+			if i >= 0 && i < len(array) {
+				return jsonPathNodes{{Path: fmt.Sprintf("%s[%d]", node.Path, i), Value: array[i]}}
+			}
+		}
+	case sliceSelector:
+		if array, ok := node.Value.([]interface{}); ok {
+			result := jsonPathNodes{}
+			for _, i := range sliceIndices(selector.slice, len(array)) {
+				result = append(result, Match{Path: fmt.Sprintf("%s[%d]", node.Path, i), Value: array[i]})
+			}
+
+			return result
+		}
+	case filterSelector:
+		result := jsonPathNodes{}
+		for _, child := range children(node) {
+			if isLogicalTrue(selector.filter.evalFilter(root, child.Value)) {
+				result = append(result, child)
+			}
+		}
+
+		return result
+	}
+
+	return jsonPathNodes{}
+}
+
+// section 2.3.4.2.2 of the RFC
+func sliceIndices(slice [3]*int, length int) []int {
+	step := 1
+	if slice[2] != nil {
+		step = *slice[2]
+	}
+
+	if step == 0 {
+		return nil
+	}
+
+	normalize := func(i int) int {
+		if i < 0 {
+			return length + i
+		}
+
+		return i
+	}
+
+	clamp := func(i, lower, upper int) int {
+		return min(max(i, lower), upper)
+	}
+
+	indices := make([]int, 0)
+	if step > 0 {
+		start, end := 0, length
+		if slice[0] != nil {
+			start = normalize(*slice[0])
+		}
+
+		if slice[1] != nil {
+			end = normalize(*slice[1])
+		}
+
+		for i := clamp(start, 0, length); i < clamp(end, 0, length); i += step {
+			indices = append(indices, i)
+		}
+	} else {
+		start, end := length-1, -length-1
+		if slice[0] != nil {
+			start = normalize(*slice[0])
+		}
+
+		if slice[1] != nil {
+			end = normalize(*slice[1])
+		}
+
+		for i := clamp(start, -1, length-1); i > clamp(end, -1, length-1); i += step {
+			indices = append(indices, i)
+		}
+	}
+
+	return indices
+}
+
+func isLogicalTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case jsonPathNodes:
+		// existence test
+		return len(v) > 0
+	}
+
+	return false
+}
+
+func (literal *jsonPathLiteral) evalFilter(root, current interface{}) interface{} {
+	return literal.value
+}
+
+func (logical *jsonPathLogical) evalFilter(root, current interface{}) interface{} {
+	lhs := isLogicalTrue(logical.lhs.evalFilter(root, current))
+	switch logical.op {
+	case "!":
+		return !lhs
+	case "&&":
+		return lhs && isLogicalTrue(logical.rhs.evalFilter(root, current))
+	}
+
+	return lhs || isLogicalTrue(logical.rhs.evalFilter(root, current))
+}
+
+// turns the result of a comparable expression into a single value (or nothing)
+func comparableValue(expr jsonPathExpr, root, current interface{}) interface{} {
+	value := expr.evalFilter(root, current)
+	if nodes, ok := value.(jsonPathNodes); ok {
+		if query, isQuery := expr.(*jsonPathQuery); isQuery && !query.isSingular() {
+			return jsonPathNothing{}
+		}
+
+		if len(nodes) != 1 {
+			return jsonPathNothing{}
+		}
+
+		return nodes[0].Value
+	}
+
+	return value
+}
+
+func (comparison *jsonPathComparison) evalFilter(root, current interface{}) interface{} {
+	lhs := comparableValue(comparison.lhs, root, current)
+	rhs := comparableValue(comparison.rhs, root, current)
+
+	switch comparison.op {
+	case "==":
+		return jsonPathEqual(lhs, rhs)
+	case "!=":
+		return !jsonPathEqual(lhs, rhs)
+	case "<":
+		return jsonPathLess(lhs, rhs)
+	case ">":
+		return jsonPathLess(rhs, lhs)
+	case "<=":
+		return jsonPathLess(lhs, rhs) || jsonPathEqual(lhs, rhs)
+	}
+
+	return jsonPathLess(rhs, lhs) || jsonPathEqual(lhs, rhs)
+}
+
+func jsonPathEqual(lhs, rhs interface{}) bool {
+	_, lhsNothing := lhs.(jsonPathNothing)
+	_, rhsNothing := rhs.(jsonPathNothing)
+	if lhsNothing || rhsNothing {
+		return lhsNothing && rhsNothing
+	}
+
+	return valuesEqual(lhs, rhs)
+}
+
+func jsonPathLess(lhs, rhs interface{}) bool {
+	switch l := lhs.(type) {
+	case float64:
+		r, ok := rhs.(float64)
+		return ok && l < r
+	case string:
+		r, ok := rhs.(string)
+		return ok && l < r
+	}
+
+	return false
+}
+
+func (function *jsonPathFunction) evalFilter(root, current interface{}) interface{} {
+	switch function.name {
+	case "length":
+		switch value := comparableValue(function.args[0], root, current).(type) {
+		case string:
+			return float64(len([]rune(value)))
+		case []interface{}:
+			return float64(len(value))
+		case *JsonObject:
+			return float64(value.Len())
+		}
+
+		return jsonPathNothing{}
+	case "count":
+		if nodes, ok := function.args[0].evalFilter(root, current).(jsonPathNodes); ok {
+			return float64(len(nodes))
+		}
+
+		return jsonPathNothing{}
+	case "value":
+		if nodes, ok := function.args[0].evalFilter(root, current).(jsonPathNodes); ok && len(nodes) == 1 {
+			return nodes[0].Value
+		}
+
+		return jsonPathNothing{}
+	}
+
+	// match and search
+	value, ok := comparableValue(function.args[0], root, current).(string)
+	if !ok {
+		return false
+	}
+
+	pattern, ok := comparableValue(function.args[1], root, current).(string)
+	if !ok {
+		return false
+	}
+
+	if function.name == "match" {
+		pattern = "^(?:" + pattern + ")$"
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false
+	}
+
+	return re.MatchString(value)
+}
+
+// objects are equal with their keys in any order, as the RFC compares them
+func valuesEqual(left, right interface{}) bool {
+	switch l := left.(type) {
+	case *JsonObject:
+		r, ok := right.(*JsonObject)
+		if !ok || l.Len() != r.Len() {
+			return false
+		}
+
+		for pair := l.Oldest(); pair != nil; pair = pair.Next() {
+			value, present := r.Get(pair.Key)
+			if !present || !valuesEqual(pair.Value, value) {
+				return false
+			}
+		}
+
+		return true
+	case []interface{}:
+		r, ok := right.([]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+
+		for i := range l {
+			if !valuesEqual(l[i], r[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return left == right
+}
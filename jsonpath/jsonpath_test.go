@@ -0,0 +1,61 @@
+package jsonpath
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/michaelhelvey/orderedjson/v2/compat"
+)
+
+const store = `{"store": {
+	"book": [
+		{"category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95},
+		{"category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99},
+		{"category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99}
+	],
+	"bicycle": {"color": "red", "price": 399}
+}}`
+
+func TestQuery(t *testing.T) {
+	var tree interface{}
+	if err := compat.Unmarshal([]byte(store), &tree); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr  string
+		paths []string
+	}{
+		{`$.store.book[?(@.price<10)].title`, []string{"$['store']['book'][0]['title']", "$['store']['book'][2]['title']"}},
+		{`$.store.book[-1].author`, []string{"$['store']['book'][2]['author']"}},
+		{`$.store.book[0:2].price`, []string{"$['store']['book'][0]['price']", "$['store']['book'][1]['price']"}},
+		{`$.store.bicycle.*`, []string{"$['store']['bicycle']['color']", "$['store']['bicycle']['price']"}},
+		{`$..isbn`, []string{"$['store']['book'][2]['isbn']"}},
+		{`$.store.book[?length(@.author) > 12].title`, []string{"$['store']['book'][2]['title']"}},
+		{`$.store.book[?@.isbn]`, []string{"$['store']['book'][2]"}},
+		{`$.nothing`, []string{}},
+	}
+
+	for _, test := range tests {
+		matches, err := Query(tree, test.expr)
+		if err != nil {
+			t.Errorf("Query(%q): %v", test.expr, err)
+			continue
+		}
+
+		paths := []string{}
+		for _, match := range matches {
+			paths = append(paths, match.Path)
+		}
+
+		if !reflect.DeepEqual(paths, test.paths) {
+			t.Errorf("Query(%q) matched %q, want %q", test.expr, paths, test.paths)
+		}
+	}
+
+	for _, expr := range []string{`@.a`, `$.store[`, `$.a b`} {
+		if _, err := Query(tree, expr); err == nil {
+			t.Errorf("Query(%q) didn't fail", expr)
+		}
+	}
+}
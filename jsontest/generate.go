@@ -0,0 +1,175 @@
+package jsontest
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// Generator makes random json documents for property tests, like checking that a transformation
+// keeps key order or that parse(marshal(x)) == x. the zero value is usable, and the same seed
+// always gives the same documents.
+type Generator struct {
+	// how deep objects and arrays nest, 4 when zero
+	MaxDepth int
+	// keys per object and items per array, 5 when zero
+	MaxKeys, MaxItems int
+	// also make empty objects and arrays
+	Empty bool
+	// how often each kind of value shows up, relative to the others. all zero means equally often.
+	Weights Weights
+	// only whole numbers, and only printable ascii in strings and keys
+	IntegersOnly, ASCIIOnly bool
+}
+
+// Weights are the relative frequencies of kinds of values. objects and arrays aren't made once the
+// maximum depth is reached.
+type Weights struct {
+	Object, Array, String, Number, Bool, Null int
+}
+
+// Object returns a random object.
+func (g Generator) Object(r *rand.Rand) *JsonObject {
+	return g.object(r, 1)
+}
+
+// Value returns a random value of any kind the weights allow.
+func (g Generator) Value(r *rand.Rand) interface{} {
+	return g.value(r, 1)
+}
+
+func (g Generator) value(r *rand.Rand, depth int) interface{} {
+	weights := g.Weights
+	if weights == (Weights{}) {
+		weights = Weights{1, 1, 1, 1, 1, 1}
+	}
+
+	if depth >= g.maxDepth() {
+		weights.Object, weights.Array = 0, 0
+		if weights == (Weights{}) {
+			// only containers are allowed, end with a string
+			weights.String = 1
+		}
+	}
+
+	kinds := []int{weights.Object, weights.Array, weights.String, weights.Number, weights.Bool, weights.Null}
+	total := 0
+	for _, weight := range kinds {
+		total += max(weight, 0)
+	}
+
+	if total == 0 {
+		return g.string(r)
+	}
+
+	pick := r.Intn(total)
+	kind := 0
+	for ; pick >= max(kinds[kind], 0); kind++ {
+		pick -= max(kinds[kind], 0)
+	}
+
+	switch kind {
+	case 0:
+		return g.object(r, depth+1)
+	case 1:
+		items := make([]interface{}, g.count(r, g.MaxItems))
+		for i := range items {
+			items[i] = g.value(r, depth+1)
+		}
+
+		return items
+	case 2:
+		return g.string(r)
+	case 3:
+		return g.number(r)
+	case 4:
+		return r.Intn(2) == 0
+	}
+
+	return nil
+}
+
+func (g Generator) object(r *rand.Rand, depth int) *JsonObject {
+	object := orderedmap.New[string, interface{}]()
+	for n := g.count(r, g.MaxKeys); object.Len() < n; {
+		key := g.string(r)
+		if _, exists := object.Get(key); !exists {
+			object.Set(key, g.value(r, depth))
+		}
+	}
+
+	return object
+}
+
+func (g Generator) maxDepth() int {
+	if g.MaxDepth <= 0 {
+		return 4
+	}
+
+	return g.MaxDepth
+}
+
+func (g Generator) count(r *rand.Rand, limit int) int {
+	if limit <= 0 {
+		limit = 5
+	}
+
+	if g.Empty {
+		return r.Intn(limit + 1)
+	}
+
+	return 1 + r.Intn(limit)
+}
+
+// strings that trip up encoders: quotes, backslashes, control characters, html and characters
+// outside the basic multilingual plane
+var awkwardRunes = []rune{'"', '\\', '/', '\n', '\t', '\x00', '\x1f', '<', '>', '&', 'é', 'ß', '中', ' ', '😀'}
+
+func (g Generator) string(r *rand.Rand) string {
+	var result strings.Builder
+	length := r.Intn(12)
+	for i := 0; i < length; i++ {
+		switch {
+		case g.ASCIIOnly:
+			result.WriteByte(byte(' ' + r.Intn('~'-' '+1)))
+		case r.Intn(4) == 0:
+			result.WriteRune(awkwardRunes[r.Intn(len(awkwardRunes))])
+		default:
+			result.WriteByte(byte('a' + r.Intn(26)))
+		}
+	}
+
+	return result.String()
+}
+
+func (g Generator) number(r *rand.Rand) float64 {
+	if g.IntegersOnly || r.Intn(2) == 0 {
+		// within the range where float64 holds every integer
+		return float64(r.Int63n(1<<53)) * float64(1-2*r.Intn(2))
+	}
+
+	switch r.Intn(3) {
+	case 0:
+		return r.NormFloat64() * 1000
+	case 1:
+		// very large or very small
+		return math.Ldexp(r.Float64(), r.Intn(2000)-1000)
+	}
+
+	return float64(r.Intn(100000)) / 100
+}
+
+// Document is a random object that testing/quick can generate, e.g.
+//
+//	quick.Check(func(doc jsontest.Document) bool { ... }, nil)
+type Document struct {
+	*JsonObject
+}
+
+func (Document) Generate(r *rand.Rand, size int) reflect.Value {
+	generator := Generator{MaxKeys: max(size/10, 1), MaxItems: max(size/10, 1), Empty: true}
+	return reflect.ValueOf(Document{generator.Object(r)})
+}
@@ -0,0 +1,358 @@
+// Package jsontest has test helpers for checking ordered json, including the order of its keys, with
+// a diff of the two documents when they don't match.
+package jsontest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/michaelhelvey/orderedjson/v2/compat"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type JsonObject = orderedmap.OrderedMap[string, interface{}]
+
+// Options change what AssertEqualJSON treats as equal.
+type Options struct {
+	// compares objects as sets of keys
+	IgnoreKeyOrder bool
+	// paths like .metadata.updatedAt or .items[0].id whose values aren't compared, for timestamps,
+	// generated ids and such
+	IgnorePaths []string
+}
+
+// AssertKeyOrder checks that tree has all of keys, in that order. other keys may come before, after or
+// in between them.
+func AssertKeyOrder(t testing.TB, tree *JsonObject, keys ...string) bool {
+	t.Helper()
+
+	if tree == nil {
+		t.Errorf("expected keys %s in order, got a nil object", strings.Join(quoteAll(keys), ", "))
+		return false
+	}
+
+	var actual []string
+	for pair := tree.Oldest(); pair != nil; pair = pair.Next() {
+		actual = append(actual, pair.Key)
+	}
+
+	next := 0
+	for _, key := range actual {
+		if next < len(keys) && key == keys[next] {
+			next++
+		}
+	}
+
+	if next == len(keys) {
+		return true
+	}
+
+	if _, ok := tree.Get(keys[next]); !ok {
+		t.Errorf("missing key %q\nwant order: %s\ngot keys:   %s", keys[next], strings.Join(quoteAll(keys), ", "), strings.Join(quoteAll(actual), ", "))
+	} else {
+		t.Errorf("key %q is out of order\nwant order: %s\ngot keys:   %s", keys[next], strings.Join(quoteAll(keys), ", "), strings.Join(quoteAll(actual), ", "))
+	}
+
+	return false
+}
+
+// AssertEqualJSON checks that want and got are the same json, including the order of object keys
+// unless opts say otherwise. both can be json text ([]byte, string or json.RawMessage) or any value
+// that marshals to json, like a tree. on failure it reports the first difference and a diff of the
+// two documents.
+func AssertEqualJSON(t testing.TB, want, got interface{}, opts Options) bool {
+	t.Helper()
+
+	wantValue, err := normalize(want)
+	if err != nil {
+		t.Errorf("invalid want: %v", err)
+		return false
+	}
+
+	gotValue, err := normalize(got)
+	if err != nil {
+		t.Errorf("invalid got: %v", err)
+		return false
+	}
+
+	ignored := make(map[string]bool, len(opts.IgnorePaths))
+	for _, path := range opts.IgnorePaths {
+		ignored[path] = true
+	}
+
+	comparison := &comparison{opts: opts, ignored: ignored}
+	if comparison.equal("", wantValue, gotValue) {
+		return true
+	}
+
+	if opts.IgnoreKeyOrder {
+		// so the diff only shows differences that count
+		gotValue = alignKeys(wantValue, gotValue)
+	}
+
+	wantText, _ := compat.MarshalIndent(wantValue, "", "  ")
+	gotText, _ := compat.MarshalIndent(gotValue, "", "  ")
+	t.Errorf("json differs at %s: %s\n\n%s", displayPath(comparison.path), comparison.reason, lineDiff(string(wantText), string(gotText)))
+	return false
+}
+
+// parses json text, or round trips anything else through json so both sides have the same types
+func normalize(value interface{}) (interface{}, error) {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case json.RawMessage:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		marshaled, err := compat.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		data = marshaled
+	}
+
+	var result interface{}
+	err := compat.Unmarshal(data, &result)
+	return result, err
+}
+
+type comparison struct {
+	opts    Options
+	ignored map[string]bool
+	// where the first difference is, and what it is
+	path   string
+	reason string
+}
+
+func (c *comparison) fail(path, format string, args ...interface{}) bool {
+	c.path = path
+	c.reason = fmt.Sprintf(format, args...)
+	return false
+}
+
+func (c *comparison) equal(path string, want, got interface{}) bool {
+	if c.ignored[displayPath(path)] {
+		return true
+	}
+
+	switch w := want.(type) {
+	case *JsonObject:
+		g, ok := got.(*JsonObject)
+		if !ok {
+			return c.fail(path, "want an object, got %s", describe(got))
+		}
+
+		return c.equalObjects(path, w, g)
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			return c.fail(path, "want an array, got %s", describe(got))
+		}
+
+		for i := 0; i < len(w) && i < len(g); i++ {
+			if !c.equal(fmt.Sprintf("%s[%d]", path, i), w[i], g[i]) {
+				return false
+			}
+		}
+
+		if len(w) != len(g) {
+			return c.fail(path, "want %d items, got %d", len(w), len(g))
+		}
+
+		return true
+	}
+
+	if want != got {
+		wantText, _ := json.Marshal(want)
+		gotText, _ := json.Marshal(got)
+		return c.fail(path, "want %s, got %s", wantText, gotText)
+	}
+
+	return true
+}
+
+func (c *comparison) equalObjects(path string, want, got *JsonObject) bool {
+	for pair := want.Oldest(); pair != nil; pair = pair.Next() {
+		child := path + pathKey(pair.Key)
+		value, ok := got.Get(pair.Key)
+		if !ok {
+			if c.ignored[displayPath(child)] {
+				continue
+			}
+
+			return c.fail(path, "missing key %q", pair.Key)
+		}
+
+		if !c.equal(child, pair.Value, value) {
+			return false
+		}
+	}
+
+	for pair := got.Oldest(); pair != nil; pair = pair.Next() {
+		if _, ok := want.Get(pair.Key); !ok && !c.ignored[displayPath(path+pathKey(pair.Key))] {
+			return c.fail(path, "unexpected key %q", pair.Key)
+		}
+	}
+
+	if c.opts.IgnoreKeyOrder {
+		return true
+	}
+
+	wantKeys := c.comparedKeys(path, want)
+	gotKeys := c.comparedKeys(path, got)
+	for i := range wantKeys {
+		if wantKeys[i] != gotKeys[i] {
+			return c.fail(path, "keys in a different order\nwant: %s\ngot:  %s", strings.Join(quoteAll(wantKeys), ", "), strings.Join(quoteAll(gotKeys), ", "))
+		}
+	}
+
+	return true
+}
+
+// a copy of got with the keys of its objects in the order they have in want, where want has them
+func alignKeys(want, got interface{}) interface{} {
+	switch g := got.(type) {
+	case *JsonObject:
+		w, _ := want.(*JsonObject)
+		result := orderedmap.New[string, interface{}]()
+		if w != nil {
+			for pair := w.Oldest(); pair != nil; pair = pair.Next() {
+				if value, ok := g.Get(pair.Key); ok {
+					result.Set(pair.Key, alignKeys(pair.Value, value))
+				}
+			}
+		}
+
+		for pair := g.Oldest(); pair != nil; pair = pair.Next() {
+			if _, ok := result.Get(pair.Key); !ok {
+				result.Set(pair.Key, pair.Value)
+			}
+		}
+
+		return result
+	case []interface{}:
+		w, _ := want.([]interface{})
+		result := make([]interface{}, len(g))
+		for i, item := range g {
+			var wantItem interface{}
+			if i < len(w) {
+				wantItem = w[i]
+			}
+
+			result[i] = alignKeys(wantItem, item)
+		}
+
+		return result
+	}
+
+	return got
+}
+
+// the keys of object that are compared, in order
+func (c *comparison) comparedKeys(path string, object *JsonObject) []string {
+	var keys []string
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		if !c.ignored[displayPath(path+pathKey(pair.Key))] {
+			keys = append(keys, pair.Key)
+		}
+	}
+
+	return keys
+}
+
+func pathKey(key string) string {
+	for i, char := range key {
+		if !(char == '_' || char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || i > 0 && char >= '0' && char <= '9') {
+			quoted, _ := json.Marshal(key)
+			return "[" + string(quoted) + "]"
+		}
+	}
+
+	if key == "" {
+		return `[""]`
+	}
+
+	return "." + key
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "."
+	}
+
+	return path
+}
+
+func describe(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a bool"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case []interface{}:
+		return "an array"
+	}
+
+	return "an object"
+}
+
+func quoteAll(keys []string) []string {
+	quoted := make([]string, len(keys))
+	for i, key := range keys {
+		quoted[i] = fmt.Sprintf("%q", key)
+	}
+
+	return quoted
+}
+
+// every line of both texts, marked with - for want only and + for got only
+func lineDiff(want, got string) string {
+	a := strings.Split(want, "\n")
+	b := strings.Split(got, "\n")
+
+	// lengths[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	var result strings.Builder
+	result.WriteString("--- want\n+++ got\n")
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			result.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lengths[i+1][j] >= lengths[i][j+1]):
+			result.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			result.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+
+	return result.String()
+}
@@ -0,0 +1,329 @@
+package jsontest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/michaelhelvey/orderedjson/v2/compat"
+)
+
+// a testing.TB that records the errors instead of failing the test
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) output() string {
+	return strings.Join(r.errors, "\n")
+}
+
+func parse(t *testing.T, input string) *JsonObject {
+	t.Helper()
+	var tree *JsonObject
+	if err := compat.Unmarshal([]byte(input), &tree); err != nil {
+		t.Fatalf("Unmarshal(%s): %v", input, err)
+	}
+
+	return tree
+}
+
+func TestAssertKeyOrder(t *testing.T) {
+	tree := parse(t, `{"name":"a","description":"b","version":"1","scripts":{}}`)
+	tests := []struct {
+		keys []string
+		want string
+	}{
+		{[]string{"name", "version"}, ""},
+		{[]string{"name", "description", "version", "scripts"}, ""},
+		{[]string{"scripts"}, ""},
+		{nil, ""},
+		{[]string{"version", "name"}, `key "name" is out of order` + "\nwant order: \"version\", \"name\"\ngot keys:   \"name\", \"description\", \"version\", \"scripts\""},
+		{[]string{"name", "license"}, `missing key "license"` + "\nwant order: \"name\", \"license\"\ngot keys:   \"name\", \"description\", \"version\", \"scripts\""},
+		{[]string{"name", "name"}, `key "name" is out of order`},
+	}
+
+	for _, test := range tests {
+		r := &recorder{}
+		ok := AssertKeyOrder(r, tree, test.keys...)
+		if ok != (test.want == "") || !strings.HasPrefix(r.output(), test.want) || test.want == "" && len(r.errors) > 0 {
+			t.Errorf("AssertKeyOrder(%v) = %v, reported %q, want %q", test.keys, ok, r.output(), test.want)
+		}
+	}
+
+	r := &recorder{}
+	if AssertKeyOrder(r, nil, "a") || r.output() != `expected keys "a" in order, got a nil object` {
+		t.Errorf("AssertKeyOrder(nil) reported %q", r.output())
+	}
+}
+
+func TestAssertEqualJSON(t *testing.T) {
+	tests := []struct {
+		want, got interface{}
+		opts      Options
+		reason    string
+	}{
+		{`{"a":1,"b":[true,null]}`, `{ "a": 1.0, "b": [true, null] }`, Options{}, ""},
+		{`{"a":1}`, []byte(`{"a":1}`), Options{}, ""},
+		{json.RawMessage(`[1]`), []interface{}{1}, Options{}, ""},
+		{`{"a":{"x":1,"y":2}}`, map[string]interface{}{"a": map[string]interface{}{"x": 1, "y": 2}}, Options{}, ""},
+		{`"s"`, "\"s\"", Options{}, ""},
+
+		{`{"a":1,"b":2}`, `{"b":2,"a":1}`, Options{}, "json differs at .: keys in a different order\nwant: \"a\", \"b\"\ngot:  \"b\", \"a\""},
+		{`{"a":1,"b":2}`, `{"b":2,"a":1}`, Options{IgnoreKeyOrder: true}, ""},
+		{`{"o":{"a":1,"b":2}}`, `{"o":{"b":2,"a":1}}`, Options{IgnoreKeyOrder: true}, ""},
+		{`{"a":1}`, `{"a":2}`, Options{}, "json differs at .a: want 1, got 2"},
+		{`{"a":1}`, `{"a":"1"}`, Options{}, `json differs at .a: want 1, got "1"`},
+		{`{"a":1}`, `{}`, Options{}, `json differs at .: missing key "a"`},
+		{`{}`, `{"a":1}`, Options{}, `json differs at .: unexpected key "a"`},
+		{`{"a":[1,2]}`, `{"a":[1]}`, Options{}, "json differs at .a: want 2 items, got 1"},
+		{`{"a":[1,{"b":2}]}`, `{"a":[1,{"b":3}]}`, Options{}, "json differs at .a[1].b: want 2, got 3"},
+		{`{"a":{}}`, `{"a":[]}`, Options{}, "json differs at .a: want an object, got an array"},
+		{`[]`, `{}`, Options{}, "json differs at .: want an array, got an object"},
+		{`{"a b":1,"":2,"1":3}`, `{"a b":0,"":2,"1":3}`, Options{}, `json differs at ["a b"]: want 1, got 0`},
+		{`{"":1}`, `{"":null}`, Options{}, `json differs at [""]: want 1, got null`},
+		{`{"1":true}`, `{"1":"x"}`, Options{}, `json differs at ["1"]: want true, got "x"`},
+
+		{`{"id":1,"meta":{"at":"x","n":1}}`, `{"id":2,"meta":{"at":"y","n":1}}`, Options{IgnorePaths: []string{".id", ".meta.at"}}, ""},
+		{`{"id":1,"a":1}`, `{"a":1}`, Options{IgnorePaths: []string{".id"}}, ""},
+		{`{"a":1}`, `{"a":1,"id":1}`, Options{IgnorePaths: []string{".id"}}, ""},
+		{`{"id":1,"a":1,"b":2}`, `{"a":1,"id":1,"b":2}`, Options{IgnorePaths: []string{".id"}}, ""},
+		{`{"items":[{"id":1,"x":1}]}`, `{"items":[{"id":2,"x":1}]}`, Options{IgnorePaths: []string{".items[0].id"}}, ""},
+		{`{"a":1}`, `{"b":1}`, Options{IgnorePaths: []string{"."}}, ""},
+		{`{"a":1,"b":2}`, `{"a":1,"b":3}`, Options{IgnorePaths: []string{".a"}}, "json differs at .b: want 2, got 3"},
+	}
+
+	for _, test := range tests {
+		r := &recorder{}
+		ok := AssertEqualJSON(r, test.want, test.got, test.opts)
+		if ok != (test.reason == "") || len(r.errors) != map[bool]int{true: 0, false: 1}[ok] || !strings.HasPrefix(r.output(), test.reason) {
+			t.Errorf("AssertEqualJSON(%s, %s, %+v) = %v, reported %q, want %q", test.want, test.got, test.opts, ok, r.output(), test.reason)
+		}
+	}
+
+	for _, pair := range [][2]interface{}{{`{"a":`, `{}`}, {`{}`, `nul`}, {`{}`, func() {}}} {
+		r := &recorder{}
+		if AssertEqualJSON(r, pair[0], pair[1], Options{}) || !strings.HasPrefix(r.output(), "invalid ") {
+			t.Errorf("AssertEqualJSON(%v, %v) reported %q, want it to be invalid", pair[0], pair[1], r.output())
+		}
+	}
+}
+
+// the diff has every line of both documents, and only what differs is marked
+func TestDiff(t *testing.T) {
+	r := &recorder{}
+	AssertEqualJSON(r, `{"a":1,"b":[1,2],"c":3}`, `{"a":1,"b":[1,3],"c":3}`, Options{})
+	want := `json differs at .b[1]: want 2, got 3
+
+--- want
++++ got
+  {
+    "a": 1,
+    "b": [
+      1,
+-     2
++     3
+    ],
+    "c": 3
+  }
+`
+	if r.output() != want {
+		t.Errorf("reported\n%s\nwant\n%s", r.output(), want)
+	}
+
+	// with IgnoreKeyOrder the keys in the diff are lined up, so only the value shows
+	r = &recorder{}
+	AssertEqualJSON(r, `{"a":1,"b":2}`, `{"b":3,"a":1}`, Options{IgnoreKeyOrder: true})
+	if !strings.HasSuffix(r.output(), "  {\n    \"a\": 1,\n-   \"b\": 2\n+   \"b\": 3\n  }\n") {
+		t.Errorf("reported\n%s", r.output())
+	}
+
+	if got := lineDiff("a\nb\nc", "a\nc\nd"); got != "--- want\n+++ got\n  a\n- b\n  c\n+ d\n" {
+		t.Errorf("lineDiff = %q", got)
+	}
+
+	if got := lineDiff("", ""); got != "--- want\n+++ got\n  \n" {
+		t.Errorf("lineDiff(empty) = %q", got)
+	}
+}
+
+// runs f in a temporary directory, with -update set to updating
+func inTempDir(t *testing.T, updating bool, f func()) {
+	t.Helper()
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Chdir(dir)
+	defer func(previous bool) { *update = previous }(*update)
+	*update = updating
+
+	f()
+}
+
+func TestSnapshot(t *testing.T) {
+	value := parse(t, `{"z":1,"a":{"<":"&"}}`)
+	want := "{\n  \"z\": 1,\n  \"a\": {\n    \"\\u003c\": \"\\u0026\"\n  }\n}\n"
+
+	inTempDir(t, false, func() {
+		r := &recorder{}
+		if Snapshot(r, "group/name", value) || !strings.HasPrefix(r.output(), "snapshot group/name doesn't exist yet") {
+			t.Errorf("Snapshot(missing) reported %q", r.output())
+		}
+	})
+
+	inTempDir(t, true, func() {
+		r := &recorder{}
+		if !Snapshot(r, "group/name", value) || len(r.errors) > 0 {
+			t.Fatalf("Snapshot(-update) reported %q", r.output())
+		}
+
+		data, err := os.ReadFile(filepath.Join("testdata", "group", "name.json"))
+		if err != nil || !strings.Contains(string(data), `"z": 1`) {
+			t.Fatalf("testdata/group/name.json = %q, %v", data, err)
+		}
+
+		*update = false
+		if !Snapshot(r, "group/name", value) || len(r.errors) > 0 {
+			t.Errorf("Snapshot(same value) reported %q", r.output())
+		}
+
+		// line endings from a windows checkout
+		os.WriteFile(filepath.Join("testdata", "group", "name.json"), []byte(strings.ReplaceAll(want, "\n", "\r\n")), 0644)
+		if !Snapshot(r, "group/name", value) || len(r.errors) > 0 {
+			t.Errorf("Snapshot(\\r\\n) reported %q", r.output())
+		}
+
+		value.Set("z", 2.0)
+		if Snapshot(r, "group/name", value) || !strings.Contains(r.output(), "-   \"z\": 1,\n+   \"z\": 2,\n") {
+			t.Errorf("Snapshot(changed value) reported %q", r.output())
+		}
+
+		r = &recorder{}
+		if Snapshot(r, "unmarshalable", func() {}) || len(r.errors) != 1 {
+			t.Errorf("Snapshot(func) reported %q", r.output())
+		}
+	})
+}
+
+func TestGenerator(t *testing.T) {
+	tests := []Generator{
+		{},
+		{MaxDepth: 1},
+		{MaxDepth: 6, MaxKeys: 2, MaxItems: 2, Empty: true},
+		{IntegersOnly: true, ASCIIOnly: true},
+		{Weights: Weights{Object: 1}},
+		{Weights: Weights{Array: 3, Number: 1}},
+		{Weights: Weights{Null: 1, Object: -1}},
+	}
+
+	for _, g := range tests {
+		for seed := int64(0); seed < 20; seed++ {
+			object := g.Object(rand.New(rand.NewSource(seed)))
+			data, err := compat.Marshal(object)
+			if err != nil {
+				t.Fatalf("%+v: Marshal: %v", g, err)
+			}
+
+			// the same seed gives the same document
+			again, _ := compat.Marshal(g.Object(rand.New(rand.NewSource(seed))))
+			if string(again) != string(data) {
+				t.Errorf("%+v: seed %d gave %s and then %s", g, seed, data, again)
+			}
+
+			if depth := depthOf(object); depth > max(g.MaxDepth, 0) && !(g.MaxDepth == 0 && depth <= 4) {
+				t.Errorf("%+v: %s is %d deep", g, data, depth)
+			}
+
+			if !g.Empty && strings.Contains(string(data), "{}") {
+				t.Errorf("%+v: %s has an empty object", g, data)
+			}
+
+			if g.ASCIIOnly && strings.ContainsFunc(string(data), func(r rune) bool { return r > '~' }) {
+				t.Errorf("%+v: %s isn't ascii", g, data)
+			}
+
+			if g.IntegersOnly && !walk(object, func(value interface{}) bool { f, ok := value.(float64); return !ok || f == math.Trunc(f) }) {
+				t.Errorf("%+v: %s has a fraction", g, data)
+			}
+
+			AssertEqualJSON(t, data, object, Options{})
+		}
+	}
+
+	if value := (Generator{Weights: Weights{Bool: 1}}).Value(rand.New(rand.NewSource(1))); value != true && value != false {
+		t.Errorf("Value(bools only) = %v", value)
+	}
+}
+
+// whether check holds for every value in value, including itself
+func walk(value interface{}, check func(value interface{}) bool) bool {
+	switch v := value.(type) {
+	case *JsonObject:
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			if !walk(pair.Value, check) {
+				return false
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if !walk(item, check) {
+				return false
+			}
+		}
+	}
+
+	return check(value)
+}
+
+// how deeply objects and arrays nest in value, 1 for an object of scalars
+func depthOf(value interface{}) int {
+	deepest := 0
+	switch v := value.(type) {
+	case *JsonObject:
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			deepest = max(deepest, depthOf(pair.Value))
+		}
+	case []interface{}:
+		for _, item := range v {
+			deepest = max(deepest, depthOf(item))
+		}
+	default:
+		return 0
+	}
+
+	return deepest + 1
+}
+
+// the documents round trip through text with their keys in order
+func TestDocument(t *testing.T) {
+	err := quick.Check(func(doc Document) bool {
+		data, err := compat.Marshal(doc.JsonObject)
+		if err != nil {
+			return false
+		}
+
+		return AssertEqualJSON(t, doc.JsonObject, data, Options{})
+	}, &quick.Config{Rand: rand.New(rand.NewSource(1))})
+
+	if err != nil {
+		t.Error(err)
+	}
+}
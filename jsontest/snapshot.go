@@ -0,0 +1,65 @@
+package jsontest
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/michaelhelvey/orderedjson/v2/compat"
+)
+
+// run the tests with -update to write the snapshots instead of comparing against them. a test
+// package that defines its own -update flag can't use Snapshot.
+var update = flag.Bool("update", false, "write jsontest snapshots instead of comparing against them")
+
+// Snapshot compares value, pretty printed with its keys in order, with testdata/<name>.json, and
+// writes that file instead when the tests run with -update. name may contain slashes to group
+// snapshots in directories.
+func Snapshot(t testing.TB, name string, value interface{}) bool {
+	t.Helper()
+
+	data, err := compat.MarshalIndent(value, "", "  ")
+	if err != nil {
+		t.Errorf("snapshot %s: %v", name, err)
+		return false
+	}
+	data = append(data, '\n')
+
+	path := filepath.Join("testdata", filepath.FromSlash(name)+".json")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Errorf("snapshot %s: %v", name, err)
+			return false
+		}
+
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Errorf("snapshot %s: %v", name, err)
+			return false
+		}
+
+		return true
+	}
+
+	want, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Errorf("snapshot %s doesn't exist yet, run the tests with -update to create %s", name, path)
+		return false
+	}
+
+	if err != nil {
+		t.Errorf("snapshot %s: %v", name, err)
+		return false
+	}
+
+	// checkouts on windows may have turned the line endings into \r\n
+	wantText := strings.ReplaceAll(string(want), "\r\n", "\n")
+	if wantText == string(data) {
+		return true
+	}
+
+	t.Errorf("snapshot %s doesn't match %s, run the tests with -update if the change is intended\n\n%s", name, path, lineDiff(strings.TrimSuffix(wantText, "\n"), strings.TrimSuffix(string(data), "\n")))
+	return false
+}
@@ -1,15 +1,27 @@
-package main
+// Package orderedjson parses json into trees that keep object keys in the order they were written,
+// and writes them back out that way. the ordered-json command in cmd/ordered-json is built on it.
+package orderedjson
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/big"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 
+	"github.com/michaelhelvey/orderedjson/v2/compression"
+	"github.com/michaelhelvey/orderedjson/v2/internal/scan"
+	"github.com/michaelhelvey/orderedjson/v2/internal/textenc"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
 )
 
@@ -53,86 +65,352 @@ func tokenTypeToString(t TokenType) string {
 	panic(fmt.Sprintf("tokenTypeToString: unhandled token type: %v", t))
 }
 
+// how a token type reads in an error message: the character itself for punctuation, like repair
+// says it
+func expectedToken(t TokenType) string {
+	switch t {
+	case OpenBrace:
+		return "'{'"
+	case CloseBrace:
+		return "'}'"
+	case OpenBracket:
+		return "'['"
+	case CloseBracket:
+		return "']'"
+	case Quote:
+		return `'"'`
+	case Colon:
+		return "':'"
+	case Comma:
+		return "','"
+	case NumberLiteral:
+		return "a number"
+	}
+
+	return "a string"
+}
+
 type Token struct {
 	TokenType TokenType `json:"type"`
 	Lexeme    string    `json:"lexeme"`
+	// byte offset of the token in the input
+	Offset int `json:"offset"`
+}
+
+// how the token reads in an error message: the character for punctuation, the text itself for
+// numbers and words
+func (token *Token) describe() string {
+	if token.TokenType == NumberLiteral || token.TokenType == StringLiteral {
+		return token.Lexeme
+	}
+
+	return fmt.Sprintf("%q", []rune(token.Lexeme)[0])
 }
 
 // I'm probably supposed to use some cool go json tokenizer here or something here so this is actually correct
 func tokenize(data []byte) []Token {
-	tokens := make([]Token, 0)
+	tokens, _ := tokenizeContext(context.Background(), data, nil)
+	return tokens
+}
+
+// how many characters or tokens go by between checks whether a ParseContext was cancelled
+const cancelCheckInterval = 4096
+
+func tokenizeContext(ctx context.Context, data []byte, arena *Arena) ([]Token, error) {
+	tokens := arena.tokenBuffer()
+	runes := arena.runesOf(data)
+
+	// byte offset of every rune. invalid bytes decode to one U+FFFD each, so this can't just add up
+	// utf8.RuneLen of the runes.
+	offsets := arena.offsetBuffer(len(runes))
+	// lexemes are built here and then copied to a string of their own
+	var scratch []byte
+	for offset := 0; offset < len(data); {
+		offsets = append(offsets, offset)
+		_, size := utf8.DecodeRune(data[offset:])
+		offset += size
+	}
+
+	emit := func(tokenType TokenType, lexeme string, at int) {
+		tokens = append(tokens, Token{TokenType: tokenType, Lexeme: lexeme, Offset: offsets[at]})
+	}
 
-	runes := []rune(string(data))
 	for i := 0; i < len(runes); i++ {
+		if i%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
 		char := runes[i]
 
 		if char == '{' {
-			tokens = append(tokens, Token{TokenType: OpenBrace, Lexeme: string(char)})
+			emit(OpenBrace, string(char), i)
 		} else if char == '}' {
-			tokens = append(tokens, Token{TokenType: CloseBrace, Lexeme: string(char)})
+			emit(CloseBrace, string(char), i)
 		} else if char == ']' {
-			tokens = append(tokens, Token{TokenType: CloseBracket, Lexeme: string(char)})
+			emit(CloseBracket, string(char), i)
 		} else if char == '[' {
-			tokens = append(tokens, Token{TokenType: OpenBracket, Lexeme: string(char)})
-		} else if char == '"' {
-			tokens = append(tokens, Token{TokenType: Quote, Lexeme: string(char)})
-		} else if char == ':' {
-			tokens = append(tokens, Token{TokenType: Colon, Lexeme: string(char)})
+			emit(OpenBracket, string(char), i)
+		} else if char == '"' || char == '\'' {
+			// single quotes aren't json, but they're tokenized like double quotes so the parser can
+			// say what's wrong, or accept them when it's lenient
+			emit(Quote, string(char), i)
+
+			// everything up to the closing quote is the string, escapes and all. the parser decodes
+			// the escapes since how it does that depends on its options.
+			start := i + 1
+			lexeme := scratch[:0]
+			for i+1 < len(runes) && runes[i+1] != char {
+				// take everything up to the next quote, backslash or control character at once
+				from := offsets[i+1]
+				var n int
+				if char == '"' {
+					n = scan.IndexStringEnd(data[from:])
+				} else {
+					n = scan.IndexQuote(data[from:], byte(char))
+				}
+				if n < 0 {
+					n = len(data) - from
+				}
+				if char != '"' {
+					n = indexControl(data[from : from+n])
+				}
+
+				if n > 0 {
+					segment := data[from : from+n]
+					count := utf8.RuneCount(segment)
+					if utf8.Valid(segment) {
+						lexeme = append(lexeme, segment...)
+					} else {
+						// invalid bytes are U+FFFD in runes
+						for _, char := range runes[i+1 : i+1+count] {
+							lexeme = utf8.AppendRune(lexeme, char)
+						}
+					}
+
+					i += count
+					continue
+				}
+
+				i++
+				if runes[i] < 0x20 {
+					return nil, newSyntaxError(data, offsets[i], ErrInvalidToken, "invalid character %q in string", runes[i])
+				}
+
+				lexeme = utf8.AppendRune(lexeme, runes[i])
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					lexeme = utf8.AppendRune(lexeme, runes[i])
+				}
+			}
+
+			if start < len(runes) {
+				emit(StringLiteral, arena.string(lexeme), start)
+			} else {
+				tokens = append(tokens, Token{TokenType: StringLiteral, Offset: len(data)})
+			}
+
+			scratch = lexeme
+
+			if i+1 < len(runes) {
+				i++
+				emit(Quote, string(runes[i]), i)
+			}
+		} else if char == ':' || char == '=' {
+			// = is a common mistake for :, see Lenient
+			emit(Colon, string(char), i)
 		} else if char == ',' {
-			tokens = append(tokens, Token{TokenType: Comma, Lexeme: string(char)})
-		} else if unicode.IsLetter(char) {
-			var lexeme strings.Builder
-			for unicode.IsLetter(char) {
-				lexeme.WriteRune(char)
+			emit(Comma, string(char), i)
+		} else if unicode.IsLetter(char) || (char == '-' && i+1 < len(runes) && unicode.IsLetter(runes[i+1])) {
+			// -Infinity ends up here too, it's only valid with AllowNonFinite anyway
+			start := i
+			lexeme := scratch[:0]
+			lexeme = utf8.AppendRune(lexeme, char)
+			for i+1 < len(runes) && isWordRune(runes[i+1]) {
 				i++
-				char = runes[i]
+				lexeme = utf8.AppendRune(lexeme, runes[i])
 			}
 
-			i--
-			tokens = append(tokens, Token{TokenType: StringLiteral, Lexeme: lexeme.String()})
-		} else if unicode.IsNumber(char) {
-			var lexeme strings.Builder
-			// imagine actually supporting JSON number spec KEKW
-			for unicode.IsNumber(char) {
-				lexeme.WriteRune(char)
+			emit(StringLiteral, arena.string(lexeme), start)
+			scratch = lexeme
+		} else if unicode.IsNumber(char) || char == '-' || char == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1]) {
+			start := i
+			lexeme := scratch[:0]
+			lexeme = utf8.AppendRune(lexeme, char)
+			// the parser checks the number against the spec, this only finds where it ends
+			for i+1 < len(runes) && isNumberRune(runes[i+1], runes[i]) {
 				i++
-				char = runes[i]
+				lexeme = utf8.AppendRune(lexeme, runes[i])
 			}
 
-			i--
-			tokens = append(tokens, Token{TokenType: NumberLiteral, Lexeme: lexeme.String()})
+			emit(NumberLiteral, arena.string(lexeme), start)
+			scratch = lexeme
+		} else if char != ' ' && char != '\t' && char != '\n' && char != '\r' {
+			return nil, newSyntaxError(data, offsets[i], ErrInvalidToken, "invalid character %q", char)
 		}
 	}
 
-	return tokens
+	arena.keepTokens(tokens)
+	return tokens, nil
+}
+
+// the index of the first control character in b (which json doesn't allow in strings), or len(b)
+func indexControl(b []byte) int {
+	for i, c := range b {
+		if c < 0x20 {
+			return i
+		}
+	}
+
+	return len(b)
+}
+
+// letters, digits, _ and $ make up unquoted keys like javascript has
+func isWordRune(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsDigit(char) || char == '_' || char == '$'
+}
+
+func isNumberRune(char, previous rune) bool {
+	switch char {
+	case '.', 'e', 'E':
+		return true
+	case '+', '-':
+		return previous == 'e' || previous == 'E'
+	}
+
+	return unicode.IsNumber(char)
+}
+
+// the parser is pretty chatty, so only print its debug output when DEBUG is set. this keeps the
+// output of the CLI commands clean.
+var debug = os.Getenv("DEBUG") != ""
+
+func debugf(format string, args ...interface{}) {
+	if debug {
+		fmt.Printf(format, args...)
+	}
 }
 
 type BtreeJsonParser struct {
+	ctx context.Context
+	// the input, for the positions of errors
+	data   []byte
 	tokens []Token
 	idx    int
+	empty  bool
+	// what was wrong with the text, when tokenizing it failed. Parse returns it.
+	err error
+
+	// accept the NaN, Infinity and -Infinity literals that python and JSON5 like to produce
+	AllowNonFinite bool
+	LoneSurrogates LoneSurrogatePolicy
+	// accept the mistakes that ParseOptions.Lenient lists, instead of failing with a hint
+	Lenient bool
+	Warn    func(err *SyntaxError)
+
+	// deduplicates keys, and string values up to InternValues bytes long
+	Interner     Interner
+	InternValues int
+
+	// for Stats
+	depth, maxDepth, values int
+	// see ParseOptions
+	depthLimit int
+	numbers    NumberMode
+
+	arena *Arena
+
+	// converts the values at matching paths as they're parsed, see ParseOptions.Decoders
+	decoders []PathDecoder
+
+	// where every value is, for incremental reparsing. only tracked when recordSpans is set.
+	recordSpans bool
+	path        Path
+	spans       []valueSpan
+	// where the key of the value being parsed is, -1 when it isn't in an object
+	keyStart, keyEnd int
+}
+
+var ErrEmptyDocument = errors.New("empty document")
+
+// windows tools love to put one of these at the start of files
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+func NewParser(data []byte, options ...DecodeOption) *BtreeJsonParser {
+	opts := ParseOptions{}.With(options...)
+	parser, _ := newParserContext(context.Background(), data, opts.Arena)
+	return opts.configure(parser)
 }
 
-func NewParser(data []byte) *BtreeJsonParser {
-	tokens := tokenize(data)
-	fmt.Printf("[DEBUG]: tokens=%+v", tokens)
-	return &BtreeJsonParser{tokens: tokens, idx: 0}
+// a parser that stops with ctx.Err() once ctx is done, the tokenizer as well as the parser. when
+// tokenizing fails the error is returned, and the parser returns it too.
+func newParserContext(ctx context.Context, data []byte, arena *Arena) (*BtreeJsonParser, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	tokens, err := tokenizeContext(ctx, data, arena)
+
+	if debug {
+		debugf("[DEBUG]: tokens=%+v", tokens)
+	}
+	return &BtreeJsonParser{ctx: ctx, data: data, tokens: tokens, idx: 0, empty: len(bytes.TrimSpace(data)) == 0, err: err, arena: arena}, err
 }
 
 func (parser *BtreeJsonParser) match(tokenType TokenType) (*Token, error) {
 	token := parser.peek()
-	fmt.Printf("[DEBUG]: match(%s): idx=%d, current_token=%+v\n", tokenTypeToString(tokenType), parser.idx, token)
+	if debug {
+		// checked here too, boxing the arguments would allocate for every token
+		debugf("[DEBUG]: match(%s): idx=%d, current_token=%+v\n", tokenTypeToString(tokenType), parser.idx, token)
+	}
 	if token == nil {
-		return nil, fmt.Errorf("unexpected EOF at %d, expected %s", parser.idx, tokenTypeToString(tokenType))
+		return nil, parser.errorf(ErrUnexpectedEOF, "unexpected end of input, expected %s", expectedToken(tokenType))
 	}
 
 	if token.TokenType != tokenType {
-		return nil, fmt.Errorf("invalid token %s at %d, expected %s", token.Lexeme, parser.idx, tokenTypeToString(tokenType))
+		return nil, parser.errorf(ErrInvalidToken, "invalid token %s, expected %s", token.describe(), expectedToken(tokenType))
+	}
+
+	if parser.idx%cancelCheckInterval == 0 {
+		if err := parser.ctx.Err(); err != nil {
+			return nil, err
+		}
 	}
 
 	parser.idx += 1
 	return token, nil
 }
 
+// byte offset of the current token, or of the end of the input once all of them are used up
+func (parser *BtreeJsonParser) offset() int {
+	if token := parser.peek(); token != nil {
+		return token.Offset
+	}
+
+	if len(parser.tokens) == 0 {
+		return 0
+	}
+
+	last := parser.tokens[len(parser.tokens)-1]
+	return last.Offset + len(last.Lexeme)
+}
+
+// a syntax error at the current token
+func (parser *BtreeJsonParser) errorf(kind error, format string, args ...interface{}) *SyntaxError {
+	return newSyntaxError(parser.data, parser.offset(), kind, format, args...)
+}
+
+// a mistake that's only an error when the parser isn't lenient
+func (parser *BtreeJsonParser) tolerate(mistake *SyntaxError) error {
+	if !parser.Lenient {
+		return mistake
+	}
+
+	if parser.Warn != nil {
+		parser.Warn(mistake)
+	}
+
+	return nil
+}
+
 func (parser *BtreeJsonParser) peek() *Token {
 	if len(parser.tokens) > parser.idx {
 		return &parser.tokens[parser.idx]
@@ -144,13 +422,45 @@ func (parser *BtreeJsonParser) peek() *Token {
 type JsonObject = orderedmap.OrderedMap[string, interface{}]
 
 func (parser *BtreeJsonParser) parseKeyValuePair() (string, interface{}, error) {
-	key, err := parser.parseString()
+	var key string
+	keyStart := parser.idx
+	if token := parser.peek(); token != nil && token.TokenType == StringLiteral {
+		if err := parser.tolerate(parser.errorf(ErrInvalidToken, "unquoted key %s, did you mean \"%s\"?", token.Lexeme, token.Lexeme)); err != nil {
+			return "", nil, err
+		}
+
+		parser.idx += 1
+		key = token.Lexeme
+	} else {
+		var err error
+		key, err = parser.parseString()
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	key = parser.intern(key)
+	keyEnd := parser.idx
+	colon, err := parser.match(Colon)
 	if err != nil {
 		return "", nil, err
 	}
 
-	if _, err := parser.match(Colon); err != nil {
-		return "", nil, err
+	if colon.Lexeme == "=" {
+		if err := parser.tolerate(newSyntaxError(parser.data, colon.Offset, ErrInvalidToken, "= after key %q, did you mean :?", key)); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if parser.tracksPath() {
+		parser.path = append(parser.path, key)
+		defer func() { parser.path = parser.path[:len(parser.path)-1] }()
+	}
+
+	if parser.recordSpans {
+		// the quotes are tokens of their own
+		last := parser.tokens[keyEnd-1]
+		parser.keyStart, parser.keyEnd = parser.tokens[keyStart].Offset, last.Offset+len(last.Lexeme)
 	}
 
 	value, err := parser.parseValue()
@@ -159,12 +469,22 @@ func (parser *BtreeJsonParser) parseKeyValuePair() (string, interface{}, error)
 }
 
 func (parser *BtreeJsonParser) parseObject() (*JsonObject, error) {
+	defer parser.leave()
+	if err := parser.enter(); err != nil {
+		return nil, err
+	}
+
 	tree := orderedmap.New[string, interface{}]()
 
 	if _, err := parser.match(OpenBrace); err != nil {
 		return nil, err
 	}
 
+	if nextToken := parser.peek(); nextToken != nil && nextToken.TokenType == CloseBrace {
+		_, err := parser.match(CloseBrace)
+		return tree, err
+	}
+
 	lhs, rhs, err := parser.parseKeyValuePair()
 	if err != nil {
 		return nil, err
@@ -199,15 +519,46 @@ func (parser *BtreeJsonParser) parseObject() (*JsonObject, error) {
 func (parser *BtreeJsonParser) parseNumber() (float64, error) {
 	token, err := parser.match(NumberLiteral)
 	if err != nil {
-		return 0.0, nil
+		return 0.0, err
+	}
+
+	if problem := numberProblem(token.Lexeme); problem != "" {
+		if err := parser.tolerate(newSyntaxError(parser.data, token.Offset, ErrInvalidToken, "invalid number %s: %s", token.Lexeme, problem)); err != nil {
+			return 0.0, err
+		}
 	}
 
 	value, err := strconv.ParseFloat(token.Lexeme, 64)
-	return value, err
+	if errors.Is(err, strconv.ErrRange) {
+		return 0.0, newSyntaxError(parser.data, token.Offset, ErrInvalidToken, "number %s is out of range", token.Lexeme)
+	}
+
+	if err != nil {
+		return 0.0, newSyntaxError(parser.data, token.Offset, ErrInvalidToken, "invalid number %s", token.Lexeme)
+	}
+
+	return value, nil
+}
+
+// what's wrong with a number that go can parse but json doesn't allow, or "" for nothing
+func numberProblem(lexeme string) string {
+	digits := strings.TrimPrefix(lexeme, "-")
+	mantissa, _, _ := strings.Cut(strings.ToLower(digits), "e")
+	switch {
+	case strings.HasPrefix(mantissa, "."):
+		return "missing digits before the decimal point"
+	case strings.HasSuffix(mantissa, "."):
+		return "missing digits after the decimal point"
+	case len(mantissa) > 1 && mantissa[0] == '0' && mantissa[1] != '.':
+		return "leading zeros aren't allowed"
+	}
+
+	return ""
 }
 
 func (parser *BtreeJsonParser) parseString() (string, error) {
-	if _, err := parser.match(Quote); err != nil {
+	quote, err := parser.match(Quote)
+	if err != nil {
 		return "", err
 	}
 
@@ -220,21 +571,151 @@ func (parser *BtreeJsonParser) parseString() (string, error) {
 		return "", err
 	}
 
-	return token.Lexeme, nil
+	lexeme := token.Lexeme
+	if quote.Lexeme == "'" {
+		lexeme = doubleQuoted(lexeme)
+		if err := parser.tolerate(newSyntaxError(parser.data, quote.Offset, ErrInvalidToken, "single quoted string '%s', did you mean \"%s\"?", token.Lexeme, lexeme)); err != nil {
+			return "", err
+		}
+	}
+
+	value, err := parser.unescape(lexeme)
+	if err != nil {
+		return "", newSyntaxError(parser.data, token.Offset, ErrInvalidEscape, "%v", err)
+	}
+
+	return value, nil
+}
+
+// what to do with a \uXXXX escape of half a surrogate pair that isn't part of a pair
+type LoneSurrogatePolicy int
+
+const (
+	LoneSurrogateError LoneSurrogatePolicy = iota
+	LoneSurrogateReplace
+	// keep the surrogate as WTF-8, like JavaScript strings allow. it's written back out as the same
+	// \uXXXX escape when marshalling.
+	LoneSurrogatePassThrough
+)
+
+func (parser *BtreeJsonParser) unescape(lexeme string) (string, error) {
+	if !strings.ContainsRune(lexeme, '\\') {
+		return lexeme, nil
+	}
+
+	var result strings.Builder
+	runes := []rune(lexeme)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '\\' {
+			result.WriteRune(runes[i])
+			continue
+		}
+
+		i++
+		if i >= len(runes) {
+			return "", fmt.Errorf("invalid escape at end of string")
+		}
+
+		switch runes[i] {
+		case '"', '\\', '/':
+			result.WriteRune(runes[i])
+		case 'b':
+			result.WriteRune('\b')
+		case 'f':
+			result.WriteRune('\f')
+		case 'n':
+			result.WriteRune('\n')
+		case 'r':
+			result.WriteRune('\r')
+		case 't':
+			result.WriteRune('\t')
+		case 'u':
+			char, ok := parseHexEscape(runes, i+1)
+			if !ok {
+				return "", fmt.Errorf("invalid unicode escape in string")
+			}
+			i += 4
+
+			if !utf16.IsSurrogate(char) {
+				result.WriteRune(char)
+				continue
+			}
+
+			// a high surrogate directly followed by an escaped low surrogate is one character
+			if char < 0xdc00 && i+2 < len(runes) && runes[i+1] == '\\' && runes[i+2] == 'u' {
+				if low, ok := parseHexEscape(runes, i+3); ok {
+					if combined := utf16.DecodeRune(char, low); combined != utf8.RuneError {
+						result.WriteRune(combined)
+						i += 6
+						continue
+					}
+				}
+			}
+
+			switch parser.LoneSurrogates {
+			case LoneSurrogateReplace:
+				result.WriteRune(utf8.RuneError)
+			case LoneSurrogatePassThrough:
+				result.WriteString(encodeWTF8(char))
+			default:
+				return "", fmt.Errorf("lone surrogate \\u%04x in string", char)
+			}
+		default:
+			return "", fmt.Errorf("invalid escape \\%c in string", runes[i])
+		}
+	}
+
+	return result.String(), nil
+}
+
+// reads the 4 hex digits starting at runes[start]
+func parseHexEscape(runes []rune, start int) (rune, bool) {
+	if start+4 > len(runes) {
+		return 0, false
+	}
+
+	value, err := strconv.ParseUint(string(runes[start:start+4]), 16, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	return rune(value), true
+}
+
+// the generalized UTF-8 encoding of a surrogate, which go's utf8 package refuses to produce
+func encodeWTF8(char rune) string {
+	return string([]byte{0xe0 | byte(char>>12), 0x80 | byte(char>>6)&0x3f, 0x80 | byte(char)&0x3f})
 }
 
 func (parser *BtreeJsonParser) parseArray() ([]interface{}, error) {
-	result := make([]interface{}, 0)
+	defer parser.leave()
+	if err := parser.enter(); err != nil {
+		return nil, err
+	}
+
+	if parser.arena == nil {
+		return parser.parseItems(make([]interface{}, 0))
+	}
+
+	// the items are collected in a buffer for this depth, and copied to the arena once there's no
+	// more of them
+	items, err := parser.parseItems(parser.arena.itemBuffer(parser.depth))
+	parser.arena.keepItems(parser.depth, items)
+	return parser.arena.array(items), err
+}
+
+func (parser *BtreeJsonParser) parseItems(result []interface{}) ([]interface{}, error) {
 	if _, err := parser.match(OpenBracket); err != nil {
 		return result, err
 	}
 
 	nextToken := parser.peek()
 	if nextToken != nil && nextToken.TokenType == CloseBracket {
-		return result, nil
+		_, err := parser.match(CloseBracket)
+		return result, err
 	}
 
-	value, err := parser.parseValue()
+	value, err := parser.parseElement(len(result))
 	if err != nil {
 		return result, err
 	}
@@ -247,7 +728,7 @@ func (parser *BtreeJsonParser) parseArray() ([]interface{}, error) {
 			return result, err
 		}
 
-		value, err = parser.parseValue()
+		value, err = parser.parseElement(len(result))
 		if err != nil {
 			return result, err
 		}
@@ -263,81 +744,520 @@ func (parser *BtreeJsonParser) parseArray() ([]interface{}, error) {
 	return result, nil
 }
 
+func (parser *BtreeJsonParser) parseElement(index int) (interface{}, error) {
+	if parser.tracksPath() {
+		parser.path = append(parser.path, index)
+		defer func() { parser.path = parser.path[:len(parser.path)-1] }()
+	}
+
+	return parser.parseValue()
+}
+
 func (parser *BtreeJsonParser) parseValue() (interface{}, error) {
+	if !parser.tracksPath() {
+		return parser.parseBareValue()
+	}
+
+	start := parser.idx
+	keyStart, keyEnd := parser.keyStart, parser.keyEnd
+	parser.keyStart, parser.keyEnd = -1, -1
+
+	value, err := parser.parseBareValue()
+	if err == nil && len(parser.decoders) > 0 {
+		value, err = parser.decode(parser.tokens[start], value)
+	}
+
+	if err == nil && parser.recordSpans && parser.idx > start {
+		last := parser.tokens[parser.idx-1]
+		parser.spans = append(parser.spans, valueSpan{
+			path:     append(Path{}, parser.path...),
+			start:    parser.tokens[start].Offset,
+			end:      last.Offset + len(last.Lexeme),
+			keyStart: keyStart,
+			keyEnd:   keyEnd,
+		})
+	}
+
+	return value, err
+}
+
+func (parser *BtreeJsonParser) parseBareValue() (interface{}, error) {
 	token := parser.peek()
 
 	if token == nil {
-		return nil, nil
+		return nil, parser.errorf(ErrUnexpectedEOF, "unexpected end of input, expected a value")
 	}
 
+	parser.values++
+
 	switch token.TokenType {
 	case OpenBrace:
 		return parser.parseObject()
 	case OpenBracket:
 		return parser.parseArray()
 	case NumberLiteral:
-		return parser.parseNumber()
+		number, err := parser.parseNumber()
+		if err != nil || parser.numbers != NumberJSONNumber {
+			return number, err
+		}
+
+		// the digits as written, unless Lenient let through ones json doesn't allow
+		if numberProblem(token.Lexeme) != "" {
+			return json.Number(strconv.FormatFloat(number, 'g', -1, 64)), nil
+		}
+
+		return json.Number(token.Lexeme), nil
 	case Quote:
-		return parser.parseString()
+		s, err := parser.parseString()
+		if len(s) <= parser.InternValues {
+			s = parser.intern(s)
+		}
+
+		return s, err
+	case StringLiteral:
+		if value, ok := jsonLiterals[token.Lexeme]; ok {
+			parser.idx += 1
+			return value, nil
+		}
+
+		if literal, ok := pythonLiterals[token.Lexeme]; ok {
+			if err := parser.tolerate(parser.errorf(ErrInvalidToken, "invalid token %s, did you mean %s?", token.Lexeme, literal)); err != nil {
+				return nil, err
+			}
+
+			parser.idx += 1
+			return jsonLiterals[literal], nil
+		}
+
+		if parser.AllowNonFinite && isNonFiniteLiteral(token.Lexeme) {
+			parser.idx += 1
+			return strconv.ParseFloat(token.Lexeme, 64)
+		}
 	}
 
-	return nil, fmt.Errorf("invalid token at position %d: %s", parser.idx, token.Lexeme)
+	return nil, parser.errorf(ErrInvalidToken, "invalid token %s, expected a value", token.describe())
+}
+
+type ParseOptions struct {
+	AllowNonFinite bool
+	// accept some common mistakes instead of failing with a hint about them: single quoted strings,
+	// unquoted keys, = instead of :, python's True, False and None, and numbers with leading zeros
+	// (012) or without digits on one side of the decimal point (.5 or 5.)
+	Lenient bool
+	// called with each mistake Lenient lets through, e.g. to print warnings
+	Warn func(err *SyntaxError)
+	// UTF-16 and UTF-32 input is transcoded to UTF-8 unless this is set
+	NoTranscode    bool
+	InvalidUTF8    InvalidUTF8Policy
+	LoneSurrogates LoneSurrogatePolicy
+	// expand ${...} references in strings after parsing, see Interpolate
+	Interpolate bool
+	// convert the values at some paths into other types while parsing, see PathDecoder
+	Decoders []PathDecoder
+	// deduplicates keys, and string values of up to InternValues bytes, for big documents that repeat
+	// the same ones over and over. nil doesn't intern anything.
+	Interner     Interner
+	InternValues int
+	// where the tree's strings and arrays are allocated, see Arena. nil uses the heap.
+	Arena *Arena
+	// filled in with what parsing cost, see Stats
+	Stats *Stats
+	// called with the stats of every parse, failed or not, e.g. to update metrics with ExpvarMetrics
+	Metrics func(stats Stats)
+	// how deeply objects and arrays can nest, so an untrusted document can't make the parser recurse
+	// until it runs out of stack. zero is DefaultMaxDepth, and a negative number is no limit.
+	MaxDepth int
+	// what numbers become in the tree
+	Numbers NumberMode
+	// allow comments and trailing commas. ParseDocument keeps the comments in Document.Meta, like
+	// ParseJSONC, Parse leaves them out.
+	Comments bool
+}
+
+// DefaultMaxDepth is how deeply objects and arrays can nest when ParseOptions.MaxDepth isn't set,
+// which is what encoding/json allows too
+const DefaultMaxDepth = 10000
+
+type NumberMode int
+
+const (
+	// numbers are float64s
+	NumberFloat64 NumberMode = iota
+	// numbers are json.Numbers with the digits as they were written, for ids and amounts that a
+	// float64 can't hold exactly. the rest of the package treats them as opaque, like PathDecoder's
+	// types, except for marshaling, which writes the digits back.
+	NumberJSONNumber
+)
+
+type InvalidUTF8Policy int
+
+const (
+	// malformed sequences become U+FFFD
+	InvalidUTF8Replace InvalidUTF8Policy = iota
+	// malformed sequences are an error, for untrusted input
+	InvalidUTF8Error
+)
+
+// ParseFile parses the file at path, decompressing it first when it's gzipped, or compressed with a
+// codec added with compression.Register. zstd is one of those, it isn't built in.
+func ParseFile(path string, options ...DecodeOption) (*JsonObject, error) {
+	return ParseOptions{}.With(options...).ParseFile(path)
+}
+
+func (opts ParseOptions) ParseFile(path string) (*JsonObject, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r, err := compression.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	defer r.Close()
+
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return opts.Parse(raw)
+}
+
+func (opts ParseOptions) Parse(data []byte) (*JsonObject, error) {
+	return opts.parse(context.Background(), data)
+}
+
+// ParseContext reads a document from r and parses it, giving up with ctx.Err() once ctx is done, so
+// a server can stop working on a huge document when the request's deadline passes. ctx is checked
+// between reads and every few thousand characters and tokens.
+func ParseContext(ctx context.Context, r io.Reader, options ...DecodeOption) (*JsonObject, error) {
+	return ParseOptions{}.With(options...).ParseContext(ctx, r)
+}
+
+func (opts ParseOptions) ParseContext(ctx context.Context, r io.Reader) (*JsonObject, error) {
+	data, err := io.ReadAll(contextReader{ctx: ctx, r: r})
+	if err != nil {
+		return nil, err
+	}
+
+	return opts.parse(ctx, data)
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (reader contextReader) Read(p []byte) (int, error) {
+	if err := reader.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return reader.r.Read(p)
+}
+
+func (opts ParseOptions) parse(ctx context.Context, data []byte) (*JsonObject, error) {
+	if opts.Stats == nil && opts.Metrics == nil {
+		return opts.parseDocument(ctx, data, nil)
+	}
+
+	stats := Stats{Bytes: len(data)}
+	start := time.Now()
+	tree, err := opts.parseDocument(ctx, data, &stats)
+	stats.Duration = time.Since(start)
+	stats.Failed = err != nil
+
+	if opts.Stats != nil {
+		*opts.Stats = stats
+	}
+
+	if opts.Metrics != nil {
+		opts.Metrics(stats)
+	}
+
+	return tree, err
+}
+
+func (opts ParseOptions) parseDocument(ctx context.Context, data []byte, stats *Stats) (*JsonObject, error) {
+	if opts.Comments {
+		// the comments are attached to keys with the spans of a Document
+		doc, err := opts.parseJSONCDocument(data)
+		if err != nil {
+			return nil, err
+		}
+
+		return doc.Tree, nil
+	}
+
+	data, err := opts.prepare(data)
+	if err != nil {
+		return nil, err
+	}
+
+	parser, err := newParserContext(ctx, data, opts.Arena)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := opts.configure(parser).Parse()
+	if stats != nil {
+		parser.recordStats(stats)
+	}
+
+	if err != nil || !opts.Interpolate {
+		return tree, err
+	}
+
+	return Interpolate(tree, nil)
+}
+
+// transcodes and validates the input as the options say
+func (opts ParseOptions) prepare(data []byte) ([]byte, error) {
+	if !opts.NoTranscode {
+		transcoded, err := textenc.ToUTF8(data)
+		if err != nil {
+			return nil, err
+		}
+
+		data = transcoded
+	}
+
+	if opts.InvalidUTF8 == InvalidUTF8Error {
+		if err := validateUTF8(data); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+func (opts ParseOptions) newParser(data []byte) *BtreeJsonParser {
+	parser, _ := newParserContext(context.Background(), data, nil)
+	return opts.configure(parser)
+}
+
+func (opts ParseOptions) configure(parser *BtreeJsonParser) *BtreeJsonParser {
+	parser.AllowNonFinite = opts.AllowNonFinite
+	parser.LoneSurrogates = opts.LoneSurrogates
+	parser.Lenient = opts.Lenient
+	parser.Warn = opts.Warn
+	parser.Interner = opts.Interner
+	parser.InternValues = opts.InternValues
+	parser.decoders = opts.Decoders
+	parser.depthLimit = opts.MaxDepth
+	parser.numbers = opts.Numbers
+	return parser
+}
+
+func validateUTF8(data []byte) error {
+	line, column := 1, 1
+	for offset := 0; offset < len(data); {
+		char, size := utf8.DecodeRune(data[offset:])
+		if char == utf8.RuneError && size <= 1 {
+			return &SyntaxError{Msg: "invalid UTF-8", Offset: offset, Line: line, Column: column, kind: ErrInvalidUTF8}
+		}
+
+		if char == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+
+		offset += size
+	}
+
+	return nil
+}
+
+var jsonLiterals = map[string]interface{}{"true": true, "false": false, "null": nil}
+
+// what python's repr gives instead of json
+var pythonLiterals = map[string]string{"True": "true", "False": "false", "None": "null"}
+
+// the contents of a single quoted string as they'd be between double quotes
+func doubleQuoted(lexeme string) string {
+	var result strings.Builder
+	for i := 0; i < len(lexeme); i++ {
+		switch {
+		case lexeme[i] == '\\' && i+1 < len(lexeme):
+			i++
+			if lexeme[i] != '\'' {
+				result.WriteByte('\\')
+			}
+
+			result.WriteByte(lexeme[i])
+		case lexeme[i] == '"':
+			result.WriteString(`\"`)
+		default:
+			result.WriteByte(lexeme[i])
+		}
+	}
+
+	return result.String()
+}
+
+func isNonFiniteLiteral(lexeme string) bool {
+	return lexeme == "NaN" || lexeme == "Infinity" || lexeme == "-Infinity"
 }
 
 func (parser *BtreeJsonParser) Parse() (*JsonObject, error) {
+	if parser.empty {
+		return nil, ErrEmptyDocument
+	}
+
+	if parser.err != nil {
+		return nil, parser.err
+	}
+
 	if len(parser.tokens) == 0 {
-		return nil, nil
+		return nil, parser.errorf(ErrUnexpectedEOF, "no json tokens found")
 	}
 
+	// a document is an object, that's what has keys to keep in order. arrays and other values are
+	// json too, but not documents.
 	firstToken := parser.tokens[0]
 	if firstToken.TokenType != OpenBrace {
-		// in the real world we would want a marshal/unmarshal thing that we can reflect on in order to
-		// figure out what the "top level object" is supposed to be:
-		return nil, fmt.Errorf("invalid opening token for object: %s", tokenTypeToString(firstToken.TokenType))
+		return nil, parser.errorf(ErrInvalidToken, "expected an object at the top level, found %s", firstToken.describe())
 	}
 
 	tree, err := parser.parseObject()
-	return tree, err
+	if err != nil {
+		return nil, err
+	}
+
+	// one document is all there can be, anything after it would be lost
+	if token := parser.peek(); token != nil {
+		return nil, parser.errorf(ErrInvalidToken, "unexpected %s after the document", token.describe())
+	}
+
+	if parser.recordSpans {
+		last := parser.tokens[parser.idx-1]
+		parser.spans = append(parser.spans, valueSpan{path: Path{}, start: firstToken.Offset, end: last.Offset + 1, keyStart: -1, keyEnd: -1})
+	}
+
+	return tree, nil
+}
+
+// what to do with NaN and +/-Inf when marshalling, since JSON can't represent them
+type NonFinitePolicy int
+
+const (
+	NonFiniteError NonFinitePolicy = iota
+	NonFiniteNull
+	// writes "NaN", "Infinity" and "-Infinity" strings
+	NonFiniteString
+)
+
+type MarshalOptions struct {
+	NonFinite NonFinitePolicy
+	// when set, every key and array element goes on its own line, indented this much per level
+	Indent string
+	// leaves out the spaces after colons and commas. ignored when there is an Indent.
+	Compact bool
+	// with an Indent, objects and arrays that fit in this many characters (counting the indentation,
+	// the key before them and the comma after them) stay on one line, like prettier does it. zero
+	// breaks every non-empty one.
+	MaxWidth int
+	// when set, object keys are written in this order instead of the order they were added in
+	SortKeys KeyOrder
+	// writes the CommentMeta and TrailingCommentMeta of keys in Meta as comments, which makes the
+	// output JSONC instead of JSON
+	Comments bool
+	// the metadata of the tree being written, like Document.Meta
+	Meta *Metadata
+
+	depth int
+	// how many characters come before and after the value on its line, besides the indentation
+	before, after int
+	// of the value being written, only kept track of for comments
+	path Path
 }
 
-func bTreeMarshall(tree *JsonObject) (string, error) {
+// marshals any value that can show up in the tree, keeping the key order of nested objects (including
+// ones inside of arrays)
+func marshalValue(value interface{}) (string, error) {
+	return MarshalOptions{}.Marshal(value)
+}
+
+// MarshalCompact is like marshalling with the default options, but without any whitespace, for when
+// every byte counts.
+func MarshalCompact(value interface{}) (string, error) {
+	return MarshalOptions{Compact: true}.Marshal(value)
+}
+
+func (opts MarshalOptions) marshalObject(tree *JsonObject) (string, error) {
 	var result strings.Builder
 	result.WriteRune('{')
 
+	child := opts.nested()
 	errors := make([]error, 0)
-	i := 0
-	for pair := tree.Oldest(); pair != nil; pair = pair.Next() {
-		result.WriteString(fmt.Sprintf("\"%s\": ", pair.Key))
-		switch v := pair.Value.(type) {
-		case *JsonObject:
-			{
-				nextResult, err := bTreeMarshall(v)
-				if err != nil {
-					errors = append(errors, err)
-				}
+	for i, pair := range opts.pairs(tree) {
+		var comment, trailing string
+		if opts.commenting() {
+			child.path = opts.path.Child(pair.Key)
+			comment = opts.Meta.comment(child.path, CommentMeta)
+			trailing = opts.Meta.comment(child.path, TrailingCommentMeta)
+		}
 
-				result.WriteString(nextResult)
+		result.WriteString(opts.newline(1))
+		if comment != "" {
+			result.WriteString(opts.comment(comment, 1))
+			if opts.Indent == "" {
+				result.WriteString(" ")
+			} else {
+				result.WriteString(opts.newline(1))
 			}
-		default:
-			{
-				nextResult, err := json.Marshal(v)
+		}
 
-				if err != nil {
-					errors = append(errors, err)
-				}
+		key, err := opts.Marshal(pair.Key)
+		if err != nil {
+			errors = append(errors, err)
+		}
 
-				result.WriteString(string(nextResult))
-			}
+		result.WriteString(key + opts.colon())
+		child.before = utf8.RuneCountInString(key + opts.colon())
+		child.after = len(opts.separator())
+		if i == tree.Len()-1 {
+			child.after = 0
+		}
+
+		nextResult, err := child.Marshal(pair.Value)
+		if err != nil {
+			errors = append(errors, err)
+		}
+
+		result.WriteString(nextResult)
+
+		// a // comment has to go after the comma, a /* */ one looks better before it
+		if trailing != "" && opts.Indent == "" {
+			result.WriteString(" " + opts.comment(trailing, 1))
 		}
 
 		// write a comma if we are in the last item in the object:
 		if i != tree.Len()-1 {
-			result.WriteString(", ")
+			result.WriteString(opts.separator())
 		}
 
-		i++
+		// the lines after the first one go on lines of their own, they were on their own lines
+		// before the closing brace in JSONC
+		if trailing != "" && opts.Indent != "" {
+			first, rest, _ := strings.Cut(trailing, "\n")
+			if first != "" {
+				result.WriteString(" " + opts.comment(first, 1))
+			}
+
+			if rest != "" {
+				result.WriteString(opts.newline(1) + opts.comment(rest, 1))
+			}
+		}
 	}
 
+	if tree.Len() > 0 {
+		result.WriteString(opts.newline(0))
+	}
 	result.WriteString("}")
 
 	// idk
@@ -348,43 +1268,272 @@ func bTreeMarshall(tree *JsonObject) (string, error) {
 	return result.String(), nil
 }
 
-func main() {
-	file, err := os.Open("package.json")
+func (opts MarshalOptions) Marshal(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case *JsonObject:
+		if flat, ok := opts.fitsOnLine(v); ok {
+			return flat, nil
+		}
 
-	if err != nil {
-		panic(fmt.Errorf("could not open file package.json: %v", err))
+		return opts.marshalObject(v)
+	case []interface{}:
+		if flat, ok := opts.fitsOnLine(v); ok {
+			return flat, nil
+		}
+
+		var result strings.Builder
+		result.WriteRune('[')
+
+		child := opts.nested()
+		child.before = 0
+		for i, item := range v {
+			if i > 0 {
+				result.WriteString(opts.separator())
+			}
+
+			result.WriteString(opts.newline(1))
+			child.after = len(opts.separator())
+			if i == len(v)-1 {
+				child.after = 0
+			}
+
+			if opts.commenting() {
+				child.path = opts.path.Child(i)
+			}
+
+			nextResult, err := child.Marshal(item)
+			if err != nil {
+				return "", err
+			}
+
+			result.WriteString(nextResult)
+		}
+
+		if len(v) > 0 {
+			result.WriteString(opts.newline(0))
+		}
+		result.WriteRune(']')
+		return result.String(), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return opts.marshalNonFinite(v)
+		}
+	case string:
+		if !utf8.ValidString(v) {
+			return marshalWTF8String(v)
+		}
 	}
-	defer file.Close()
 
-	raw, err := io.ReadAll(file)
+	nextResult, err := marshalJSON(value)
 	if err != nil {
-		panic(fmt.Errorf("could not read from file package.json: %v", err))
+		return "", err
 	}
 
-	parser := NewParser(raw)
-	result, err := parser.Parse()
+	return string(nextResult), nil
+}
 
-	if err != nil {
-		panic(fmt.Errorf("could not parse to btree: %v", err))
+// json.Marshal without turning <, > and & into \u003c, \u003e and \u0026, which is only there for
+// json pasted into html and makes a mess of ">=20" in a package.json
+func marshalJSON(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
 	}
 
-	result.Set("custom_key", "some value")
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
 
-	data, err := bTreeMarshall(result)
-	if err != nil {
-		panic(fmt.Errorf("could not marshsall btree: %v", err))
+// the one line form of an object or array, if it's within MaxWidth
+func (opts MarshalOptions) fitsOnLine(value interface{}) (string, bool) {
+	if opts.Indent == "" || opts.MaxWidth <= 0 || opts.commenting() && opts.Meta.hasComments(opts.path, value) {
+		return "", false
 	}
 
-	fmt.Printf("[DEBUG]: marshaled final result to: %s\n", string(data))
-	err = os.WriteFile("package-new.json", []byte(data), 0644)
+	flat, err := MarshalOptions{NonFinite: opts.NonFinite, SortKeys: opts.SortKeys}.Marshal(value)
 	if err != nil {
-		panic(fmt.Errorf("could not write to file: %+v", err))
+		// let the usual path report it
+		return "", false
 	}
 
-	cmd := exec.Command("prettier", "-w", "package-new.json")
-	_, err = cmd.Output()
+	width := utf8.RuneCountInString(strings.Repeat(opts.Indent, opts.depth)) + opts.before + utf8.RuneCountInString(flat) + opts.after
+	return flat, width <= opts.MaxWidth
+}
 
-	if err != nil {
-		panic(fmt.Errorf("could not execute prettier on result: %v", err))
+// whether there are comments to write
+func (opts MarshalOptions) commenting() bool {
+	return opts.Comments && opts.Meta != nil
+}
+
+func (opts MarshalOptions) nested() MarshalOptions {
+	opts.depth++
+	return opts
+}
+
+// the line break and indentation before an item (extra = 1) or a closing brace (extra = 0). without
+// an Indent everything stays on one line.
+func (opts MarshalOptions) newline(extra int) string {
+	if opts.Indent == "" {
+		return ""
+	}
+
+	return "\n" + strings.Repeat(opts.Indent, opts.depth+extra)
+}
+
+func (opts MarshalOptions) separator() string {
+	if opts.Indent == "" && !opts.Compact {
+		return ", "
+	}
+
+	return ","
+}
+
+func (opts MarshalOptions) colon() string {
+	if opts.Compact && opts.Indent == "" {
+		return ":"
 	}
+
+	return ": "
+}
+
+// strings holding lone surrogates (from LoneSurrogatePassThrough) get them written back as \uXXXX
+// escapes instead of having encoding/json turn them into U+FFFD
+func marshalWTF8String(s string) (string, error) {
+	var result strings.Builder
+	result.WriteRune('"')
+
+	start := 0
+	flush := func(end int) error {
+		if start == end {
+			return nil
+		}
+
+		quoted, err := marshalJSON(s[start:end])
+		if err != nil {
+			return err
+		}
+
+		result.Write(quoted[1 : len(quoted)-1])
+		return nil
+	}
+
+	for i := 0; i < len(s); {
+		if i+2 < len(s) && s[i] == 0xed && s[i+1] >= 0xa0 && s[i+1] <= 0xbf && s[i+2]&0xc0 == 0x80 {
+			if err := flush(i); err != nil {
+				return "", err
+			}
+
+			char := rune(s[i]&0x0f)<<12 | rune(s[i+1]&0x3f)<<6 | rune(s[i+2]&0x3f)
+			result.WriteString(fmt.Sprintf("\\u%04x", char))
+			i += 3
+			start = i
+			continue
+		}
+
+		i++
+	}
+
+	if err := flush(len(s)); err != nil {
+		return "", err
+	}
+
+	result.WriteRune('"')
+	return result.String(), nil
+}
+
+func (opts MarshalOptions) marshalNonFinite(v float64) (string, error) {
+	switch opts.NonFinite {
+	case NonFiniteNull:
+		return "null", nil
+	case NonFiniteString:
+		if math.IsNaN(v) {
+			return `"NaN"`, nil
+		} else if v > 0 {
+			return `"Infinity"`, nil
+		}
+
+		return `"-Infinity"`, nil
+	}
+
+	return "", fmt.Errorf("cannot marshal %v: json has no NaN or Infinity", v)
+}
+
+// Equal reports whether two values of a tree are the same json. objects are equal when they have
+// the same entries, regardless of order, same as jq.
+func Equal(left, right interface{}) bool {
+	switch l := left.(type) {
+	case *JsonObject:
+		r, ok := right.(*JsonObject)
+		if !ok || l.Len() != r.Len() {
+			return false
+		}
+
+		for pair := l.Oldest(); pair != nil; pair = pair.Next() {
+			value, present := r.Get(pair.Key)
+			if !present || !Equal(pair.Value, value) {
+				return false
+			}
+		}
+
+		return true
+	case []interface{}:
+		r, ok := right.([]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+
+		for i := range l {
+			if !Equal(l[i], r[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if l, ok := bigNumber(left); ok {
+		r, ok := bigNumber(right)
+		return ok && l.Cmp(r) == 0
+	}
+
+	return left == right
+}
+
+// a number of the tree (a float64, or a json.Number with NumberJSONNumber) exactly, so numbers
+// compare by value whichever they are
+func bigNumber(value interface{}) (*big.Float, bool) {
+	switch n := value.(type) {
+	case float64:
+		if math.IsNaN(n) {
+			return nil, false
+		}
+
+		return new(big.Float).SetFloat64(n), true
+	case json.Number:
+		f, _, err := big.ParseFloat(string(n), 10, 1024, big.ToNearestEven)
+		return f, err == nil
+	}
+
+	return nil, false
+}
+
+// TypeName is the json type of a value of a tree: null, boolean, number, string, array or object.
+func TypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case *JsonObject:
+		return "object"
+	}
+
+	return fmt.Sprintf("%T", value)
 }
@@ -0,0 +1,202 @@
+package orderedjson
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		// the tree written back with MarshalCompact
+		want string
+	}{
+		{`{}`, `{}`},
+		{` { } `, `{}`},
+		{`{"a":{}}`, `{"a":{}}`},
+		{`{"a":[]}`, `{"a":[]}`},
+		{`{"a": [ ]}`, `{"a":[]}`},
+		{`{"a":[[],{}],"b":{"c":[]}}`, `{"a":[[],{}],"b":{"c":[]}}`},
+		{`{"b":1,"a":2}`, `{"b":1,"a":2}`},
+		{`{"a":[1,"x",true,false,null]}`, `{"a":[1,"x",true,false,null]}`},
+		{"{\"devDependencies\": {},\n \"files\": []\n}\n", `{"devDependencies":{},"files":[]}`},
+		{`{"node":">=20","q":"a & b <c>"}`, `{"node":">=20","q":"a & b <c>"}`},
+	}
+
+	for _, test := range tests {
+		tree, err := ParseOptions{}.Parse([]byte(test.input))
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.input, err)
+			continue
+		}
+
+		got, err := MarshalCompact(tree)
+		if err != nil {
+			t.Errorf("MarshalCompact(Parse(%q)): %v", test.input, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("Parse(%q) = %s, want %s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		kind  error
+		msg   string
+		// where the error is
+		line, column int
+	}{
+		{"{\"a\":1}\n{\"b\":2}\n", ErrInvalidToken, "unexpected '{' after the document", 2, 1},
+		{`{"a":1}}`, ErrInvalidToken, "unexpected '}' after the document", 1, 8},
+		{`{"a":1} 2`, ErrInvalidToken, "unexpected 2 after the document", 1, 9},
+		{`{"a":1}@`, ErrInvalidToken, "invalid character '@'", 1, 8},
+		{`{"a":#1}`, ErrInvalidToken, "invalid character '#'", 1, 6},
+		{"{\"a\":\"x\ty\"}", ErrInvalidToken, `invalid character '\t' in string`, 1, 8},
+		{"{\"a\":\"x\ny\"}", ErrInvalidToken, `invalid character '\n' in string`, 1, 8},
+		{"{'a':'x\ny'}", ErrInvalidToken, `invalid character '\n' in string`, 1, 8},
+		{"{\"a\":\"x\u0000\"}", ErrInvalidToken, `invalid character '\x00' in string`, 1, 8},
+		{`[]`, ErrInvalidToken, "expected an object at the top level, found '['", 1, 1},
+		{` null`, ErrInvalidToken, "expected an object at the top level, found null", 1, 2},
+		{`"a"`, ErrInvalidToken, `expected an object at the top level, found '"'`, 1, 1},
+		{`{"a":`, ErrUnexpectedEOF, "unexpected end of input, expected a value", 1, 6},
+		{`{"a":1`, ErrUnexpectedEOF, "unexpected end of input, expected '}'", 1, 7},
+		{`{"a" 1}`, ErrInvalidToken, "invalid token 1, expected ':'", 1, 6},
+		{`{"a":1,}`, ErrInvalidToken, `invalid token '}', expected '"'`, 1, 8},
+		{`{"a":[1}`, ErrInvalidToken, "invalid token '}', expected ']'", 1, 8},
+		{`{"a":1 "b":2}`, ErrInvalidToken, `invalid token '"', expected '}'`, 1, 8},
+		{`{"a":}`, ErrInvalidToken, "invalid token '}', expected a value", 1, 6},
+	}
+
+	for _, test := range tests {
+		_, err := ParseOptions{}.Parse([]byte(test.input))
+		var syntax *SyntaxError
+		if !errors.As(err, &syntax) {
+			t.Errorf("Parse(%q) = %v, want a *SyntaxError", test.input, err)
+			continue
+		}
+
+		if !errors.Is(err, test.kind) {
+			t.Errorf("Parse(%q) = %v, want %v", test.input, err, test.kind)
+		}
+
+		if test.msg != "" && syntax.Msg != test.msg {
+			t.Errorf("Parse(%q) says %q, want %q", test.input, syntax.Msg, test.msg)
+		}
+
+		if syntax.Line != test.line || syntax.Column != test.column {
+			t.Errorf("Parse(%q) is at %d:%d, want %d:%d", test.input, syntax.Line, syntax.Column, test.line, test.column)
+		}
+	}
+}
+
+func TestParseMaxDepth(t *testing.T) {
+	nested := func(depth int) []byte {
+		return []byte(strings.Repeat(`{"a":`, depth) + "1" + strings.Repeat("}", depth))
+	}
+
+	tests := []struct {
+		depth, maxDepth int
+		tooDeep         bool
+	}{
+		{DefaultMaxDepth, 0, false},
+		{DefaultMaxDepth + 1, 0, true},
+		{1000000, 0, true},
+		{3, 3, false},
+		{4, 3, true},
+		{DefaultMaxDepth + 1, -1, false},
+	}
+
+	for _, test := range tests {
+		_, err := ParseOptions{MaxDepth: test.maxDepth}.Parse(nested(test.depth))
+		if got := errors.Is(err, ErrTooDeep); got != test.tooDeep {
+			t.Errorf("parsing %d objects deep with MaxDepth %d: %v", test.depth, test.maxDepth, err)
+		}
+
+		if !test.tooDeep && err != nil {
+			t.Errorf("parsing %d objects deep with MaxDepth %d: %v", test.depth, test.maxDepth, err)
+		}
+	}
+}
+
+// with NumberJSONNumber the leaves are json.Numbers, which everything that takes a tree has to
+// handle like float64s
+func TestNumberJSONNumber(t *testing.T) {
+	tree, err := Parse([]byte(`{"id":12345678901234567890,"port":8080,"ratio":0.5,"s":"${port}:${id}"}`), WithNumberMode(NumberJSONNumber))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := tree.Value("port").(json.Number); !ok {
+		t.Fatalf("port = %#v, want a json.Number", tree.Value("port"))
+	}
+
+	if yaml, err := ToYAML(tree); err != nil || !strings.Contains(string(yaml), "id: 12345678901234567890\nport: 8080\nratio: 0.5\n") {
+		t.Errorf("ToYAML = %q, %v", yaml, err)
+	}
+
+	schema := InferSchema(tree)
+	if text, _ := MarshalCompact(schema.Value("properties")); text != `{"id":{"type":"integer"},"port":{"type":"integer"},"ratio":{"type":"number"},"s":{"type":"string"}}` {
+		t.Errorf("InferSchema properties = %s", text)
+	}
+
+	if errs := ValidateSchema(schema, tree); len(errs) != 0 {
+		t.Errorf("ValidateSchema = %v", errs)
+	}
+
+	limits, _ := Parse([]byte(`{"properties":{"port":{"type":"integer","maximum":1024},"ratio":{"type":"integer"}}}`))
+	if errs := ValidateSchema(limits, tree); len(errs) != 2 || errs[0].Message != "must be at most 1024" {
+		t.Errorf("ValidateSchema(limits) = %v", errs)
+	}
+
+	interpolated, err := Interpolate(tree, func(string) (string, bool) { return "", false })
+	if err != nil || interpolated.Value("s") != "8080:12345678901234567890" {
+		t.Errorf("Interpolate = %v, %v", interpolated.Value("s"), err)
+	}
+
+	tests := []struct {
+		left, right interface{}
+		want        bool
+	}{
+		{json.Number("1"), 1.0, true},
+		{1.0, json.Number("1.0"), true},
+		{json.Number("1e2"), json.Number("100"), true},
+		{json.Number("12345678901234567890"), json.Number("12345678901234567891"), false},
+		{json.Number("1"), "1", false},
+		{json.Number("1"), 2.0, false},
+	}
+
+	for _, test := range tests {
+		if got := Equal(test.left, test.right); got != test.want {
+			t.Errorf("Equal(%#v, %#v) = %v, want %v", test.left, test.right, got, test.want)
+		}
+	}
+
+	if canonical, err := Canonical(tree); err != nil || string(canonical) != `{"id":12345678901234567000,"port":8080,"ratio":0.5,"s":"${port}:${id}"}` {
+		t.Errorf("Canonical = %s, %v", canonical, err)
+	}
+
+	var visited []float64
+	Accept(tree, VisitorFunc(func(path Path, value interface{}) bool {
+		if n, ok := value.(float64); ok {
+			visited = append(visited, n)
+		}
+		return true
+	}))
+	if len(visited) != 3 || visited[1] != 8080 {
+		t.Errorf("Accept visited numbers %v", visited)
+	}
+
+	if source, err := GenerateEmbed("config", "Default", tree, false); err != nil || !strings.Contains(string(source), `json.Number("12345678901234567890")`) {
+		t.Errorf("GenerateEmbed = %s, %v", source, err)
+	}
+
+	if TypeName(json.Number("1")) != "number" {
+		t.Errorf("TypeName(json.Number) = %s", TypeName(json.Number("1")))
+	}
+}
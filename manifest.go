@@ -0,0 +1,255 @@
+package orderedjson
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestProfile says which parts of a well known kind of file (package.json, composer.json,
+// tsconfig.json) TidyManifest keeps in order.
+type ManifestProfile struct {
+	Name string
+	// objects of package names to versions, sorted by name
+	Sections []Path
+	// arrays of strings to remove repeated entries from, and whether to sort them too
+	Lists []manifestList
+	// sections or lists a name shouldn't be in more than one of, like dependencies and
+	// devDependencies
+	Exclusive [][2]Path
+	// the order of names in Sections, by default strings.Compare
+	Order KeyOrder
+}
+
+type manifestList struct {
+	path Path
+	sort bool
+}
+
+var manifestProfiles = map[string]ManifestProfile{
+	"package-json": {
+		Name:     "package-json",
+		Sections: []Path{{"dependencies"}, {"devDependencies"}, {"peerDependencies"}, {"optionalDependencies"}},
+		Lists:    []manifestList{{Path{"bundleDependencies"}, true}, {Path{"bundledDependencies"}, true}},
+		Exclusive: [][2]Path{
+			{{"dependencies"}, {"devDependencies"}},
+			{{"dependencies"}, {"optionalDependencies"}},
+		},
+	},
+	"composer": {
+		Name:      "composer",
+		Sections:  []Path{{"require"}, {"require-dev"}, {"suggest"}, {"provide"}, {"replace"}, {"conflict"}},
+		Exclusive: [][2]Path{{{"require"}, {"require-dev"}}},
+		Order:     composerOrder,
+	},
+	"tsconfig": {
+		Name:     "tsconfig",
+		Sections: []Path{{"compilerOptions", "paths"}},
+		// the order of files is the order they're compiled in, so only dedupe
+		Lists: []manifestList{
+			{Path{"files"}, false},
+			{Path{"include"}, false},
+			{Path{"exclude"}, false},
+			{Path{"compilerOptions", "lib"}, true},
+			{Path{"compilerOptions", "types"}, true},
+		},
+		Exclusive: [][2]Path{{{"include"}, {"exclude"}}},
+	},
+}
+
+// LookupManifestProfile returns the profile with the given name: package-json, composer or
+// tsconfig.
+func LookupManifestProfile(name string) (ManifestProfile, bool) {
+	profile, ok := manifestProfiles[name]
+	return profile, ok
+}
+
+// ManifestProfileFor picks the profile for a file by its name, like fmt --profile auto does.
+func ManifestProfileFor(path string) (ManifestProfile, bool) {
+	base := filepath.Base(path)
+	switch {
+	case base == "package.json":
+		return manifestProfiles["package-json"], true
+	case base == "composer.json":
+		return manifestProfiles["composer"], true
+	case strings.HasPrefix(base, "tsconfig") && strings.HasSuffix(base, ".json"), base == "jsconfig.json":
+		return manifestProfiles["tsconfig"], true
+	}
+
+	return ManifestProfile{}, false
+}
+
+// composer puts platform packages (php and its extensions) before the others
+func composerOrder(a, b string) int {
+	platform := func(name string) int {
+		if name == "php" || name == "hhvm" || !strings.Contains(name, "/") {
+			return 0
+		}
+
+		return 1
+	}
+
+	if platform(a) != platform(b) {
+		return platform(a) - platform(b)
+	}
+
+	return strings.Compare(a, b)
+}
+
+// TidyManifest sorts the dependency sections of doc's tree and removes repeated entries, as profile
+// says. it returns what it found that needs a person to look at: names repeated with different
+// versions (the last one is kept, like every json parser does) and names in sections they shouldn't
+// both be in.
+func TidyManifest(doc *Document, profile ManifestProfile) []string {
+	var problems []string
+	order := profile.Order
+	if order == nil {
+		order = strings.Compare
+	}
+
+	for _, section := range profile.Sections {
+		object, ok := objectValueAt(doc.Tree, section).(*JsonObject)
+		if !ok {
+			continue
+		}
+
+		problems = append(problems, doc.repeatedKeys(section)...)
+
+		keys := make([]string, 0, object.Len())
+		for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+			keys = append(keys, pair.Key)
+		}
+
+		sort.SliceStable(keys, func(i, j int) bool {
+			return order(keys[i], keys[j]) < 0
+		})
+
+		for _, key := range keys {
+			object.MoveToBack(key)
+		}
+	}
+
+	for _, list := range profile.Lists {
+		items, ok := objectValueAt(doc.Tree, list.path).([]interface{})
+		if !ok {
+			continue
+		}
+
+		seen := make(map[string]bool)
+		kept := items[:0]
+		for _, item := range items {
+			s, ok := item.(string)
+			if ok && seen[s] {
+				problems = append(problems, fmt.Sprintf("%s: removed repeated %q", list.path, s))
+				continue
+			}
+
+			seen[s] = true
+			kept = append(kept, item)
+		}
+
+		if list.sort {
+			sort.SliceStable(kept, func(i, j int) bool {
+				a, _ := kept[i].(string)
+				b, _ := kept[j].(string)
+				return a < b
+			})
+		}
+
+		setObjectValueAt(doc.Tree, list.path, kept)
+	}
+
+	for _, pair := range profile.Exclusive {
+		first, second := manifestNames(doc.Tree, pair[0]), manifestNames(doc.Tree, pair[1])
+		for _, name := range first {
+			for _, other := range second {
+				if name == other {
+					problems = append(problems, fmt.Sprintf("%q is in both %s and %s", name, pair[0], pair[1]))
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+// keys that show up more than once in the object at path, which only the text still knows about
+func (doc *Document) repeatedKeys(path Path) []string {
+	var problems []string
+	values := make(map[string][]string)
+	var names []string
+	for _, span := range doc.spans {
+		if len(span.path) != len(path)+1 || !span.path[:len(path)].Equal(path) {
+			continue
+		}
+
+		name, _ := span.path[len(path)].(string)
+		if values[name] == nil {
+			names = append(names, name)
+		}
+
+		values[name] = append(values[name], string(doc.Text[span.start:span.end]))
+	}
+
+	for _, name := range names {
+		texts := values[name]
+		if len(texts) < 2 {
+			continue
+		}
+
+		different := false
+		for _, text := range texts[1:] {
+			different = different || text != texts[0]
+		}
+
+		if different {
+			problems = append(problems, fmt.Sprintf("%s: %q is there %d times with different values %s, kept the last one", path, name, len(texts), strings.Join(texts, ", ")))
+		} else {
+			problems = append(problems, fmt.Sprintf("%s: removed repeated %q", path, name))
+		}
+	}
+
+	return problems
+}
+
+// the keys of the object, or the strings in the array, at path
+func manifestNames(tree *JsonObject, path Path) []string {
+	var names []string
+	switch v := objectValueAt(tree, path).(type) {
+	case *JsonObject:
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			names = append(names, pair.Key)
+		}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+
+	return names
+}
+
+// the value at a path of object keys, nil if there isn't one
+func objectValueAt(tree *JsonObject, path Path) interface{} {
+	var value interface{} = tree
+	for _, key := range path {
+		object, ok := value.(*JsonObject)
+		if !ok {
+			return nil
+		}
+
+		value, _ = object.Get(key.(string))
+	}
+
+	return value
+}
+
+func setObjectValueAt(tree *JsonObject, path Path, value interface{}) {
+	parent, ok := objectValueAt(tree, path[:len(path)-1]).(*JsonObject)
+	if ok {
+		parent.Set(path[len(path)-1].(string), value)
+	}
+}
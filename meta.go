@@ -0,0 +1,174 @@
+package orderedjson
+
+import (
+	"strings"
+)
+
+// names of key metadata that MarshalOptions.Comments writes as comments
+const (
+	// written on the line(s) before the key
+	CommentMeta = "comment"
+	// written after the value, on the same line
+	TrailingCommentMeta = "trailingComment"
+)
+
+// Metadata is what's attached to the keys of a tree, like the comments of a JSONC file or a note
+// for a generated field, kept next to the tree instead of in it. a Document has one for its tree.
+//
+// it goes by the path of the key, so it stays when the key is set again or the keys are sorted, and
+// it applies just as well to the copies of the tree that Redact, Interpolate, Render or query.Rewrite
+// return. a key that moves somewhere else leaves its metadata behind, unless whatever moves it
+// calls Move and Forget, like query.RewriteScript.ApplyMeta does. the zero value is empty and ready
+// to use.
+type Metadata struct {
+	// Path.String() of the key -> name -> value
+	keys map[string]map[string]interface{}
+}
+
+// Set attaches metadata called name to the key at path. nothing reads it besides Get and the
+// writer, which turns CommentMeta and TrailingCommentMeta into comments when asked to (see
+// MarshalOptions.Comments).
+func (meta *Metadata) Set(path Path, name string, value interface{}) {
+	if meta.keys == nil {
+		meta.keys = make(map[string]map[string]interface{})
+	}
+
+	key := path.String()
+	if meta.keys[key] == nil {
+		meta.keys[key] = make(map[string]interface{})
+	}
+
+	meta.keys[key][name] = value
+}
+
+// Get returns the metadata called name of the key at path. meta can be nil.
+func (meta *Metadata) Get(path Path, name string) (interface{}, bool) {
+	if meta == nil {
+		return nil, false
+	}
+
+	value, ok := meta.keys[path.String()][name]
+	return value, ok
+}
+
+// Delete removes the metadata called name from the key at path.
+func (meta *Metadata) Delete(path Path, name string) {
+	delete(meta.keys[path.String()], name)
+}
+
+// Forget removes all the metadata of the key at path and of everything under it, for a key that's
+// deleted, so a key added later in its place doesn't get the old one's comments.
+func (meta *Metadata) Forget(path Path) {
+	if len(path) == 0 {
+		meta.keys = nil
+		return
+	}
+
+	prefix := path.String()
+	for key := range meta.keys {
+		if isUnder(key, prefix) {
+			delete(meta.keys, key)
+		}
+	}
+}
+
+// Move gives the metadata of the key at from, and of everything under it, to the key at to, for a
+// key that's renamed or an array item whose index changed. whatever to had before is forgotten.
+// neither path can be the top level object.
+func (meta *Metadata) Move(from, to Path) {
+	if len(from) == 0 || len(to) == 0 || from.Equal(to) {
+		return
+	}
+
+	prefix := from.String()
+	moved := make(map[string]map[string]interface{})
+	for key, values := range meta.keys {
+		if isUnder(key, prefix) {
+			moved[key] = values
+			delete(meta.keys, key)
+		}
+	}
+
+	meta.Forget(to)
+	if len(moved) == 0 {
+		return
+	}
+
+	if meta.keys == nil {
+		meta.keys = make(map[string]map[string]interface{})
+	}
+
+	target := to.String()
+	for key, values := range moved {
+		meta.keys[target+key[len(prefix):]] = values
+	}
+}
+
+// Clone returns a copy of meta that can be changed without changing meta. meta can be nil.
+func (meta *Metadata) Clone() *Metadata {
+	if meta == nil {
+		return &Metadata{}
+	}
+
+	return meta.clone()
+}
+
+// whether the key of the path key is prefix or something under it
+func isUnder(key, prefix string) bool {
+	return key == prefix || strings.HasPrefix(key, prefix) && (key[len(prefix)] == '.' || key[len(prefix)] == '[')
+}
+
+func (meta *Metadata) clone() *Metadata {
+	copied := &Metadata{keys: make(map[string]map[string]interface{}, len(meta.keys))}
+	for key, values := range meta.keys {
+		copied.keys[key] = make(map[string]interface{}, len(values))
+		for name, value := range values {
+			copied.keys[key][name] = value
+		}
+	}
+
+	return copied
+}
+
+// the comment metadata of the key at path as text, "" without any
+func (meta *Metadata) comment(path Path, name string) string {
+	value, _ := meta.Get(path, name)
+	comment, _ := value.(string)
+	return comment
+}
+
+// whether writing value, which is at path, with comments needs more than one line
+func (meta *Metadata) hasComments(path Path, value interface{}) bool {
+	switch v := value.(type) {
+	case *JsonObject:
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			child := path.Child(pair.Key)
+			if meta.comment(child, CommentMeta) != "" || meta.comment(child, TrailingCommentMeta) != "" || meta.hasComments(child, pair.Value) {
+				return true
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if meta.hasComments(path.Child(i), item) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// comment as // lines, or as a /* */ block when everything is on one line
+func (opts MarshalOptions) comment(comment string, extra int) string {
+	if opts.Indent == "" {
+		comment = strings.TrimSpace(strings.ReplaceAll(strings.ReplaceAll(comment, "\n", " "), "*/", "* /"))
+		return "/* " + comment + " */"
+	}
+
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("// "+line, " ")
+	}
+
+	return strings.Join(lines, opts.newline(extra))
+}
@@ -0,0 +1,106 @@
+package orderedjson
+
+import (
+	"strings"
+	"testing"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+func TestMetadataComments(t *testing.T) {
+	doc, err := ParseJSONC([]byte("{\n  // the name\n  \"name\": \"x\", // trailing\n  \"db\": {\"password\": \"p\" /* secret */}\n}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	generated := orderedmap.New[string, interface{}]()
+	generated.Set("port", 8080.0)
+	generated.Set("items", []interface{}{orderedmap.New[string, interface{}]()})
+	generated.Value("items").([]interface{})[0].(*JsonObject).Set("id", 1.0)
+	var meta Metadata
+	meta.Set(Path{"port"}, CommentMeta, "generated\ndon't edit")
+	meta.Set(Path{"items", 0, "id"}, TrailingCommentMeta, "first")
+
+	forgotten := &Metadata{}
+	forgotten.Set(Path{"port"}, CommentMeta, "gone")
+	forgotten.Set(Path{"items", 0, "id"}, CommentMeta, "gone")
+	forgotten.Set(Path{"portable"}, CommentMeta, "kept")
+	forgotten.Forget(Path{"items"})
+	forgotten.Delete(Path{"port"}, CommentMeta)
+
+	tests := []struct {
+		name string
+		tree *JsonObject
+		opts MarshalOptions
+		want string
+	}{
+		{"jsonc", doc.Tree, MarshalOptions{Indent: "  ", Comments: true, Meta: doc.Meta},
+			"{\n  // the name\n  \"name\": \"x\", // trailing\n  \"db\": {\n    \"password\": \"p\" // secret\n  }\n}"},
+		{"on one line", doc.Tree, MarshalOptions{Comments: true, Meta: doc.Meta},
+			`{/* the name */ "name": "x" /* trailing */, "db": {"password": "p" /* secret */}}`},
+		{"without Comments", doc.Tree, MarshalOptions{Indent: "  ", Meta: doc.Meta},
+			"{\n  \"name\": \"x\",\n  \"db\": {\n    \"password\": \"p\"\n  }\n}"},
+		{"copied by Redact", Redact(doc.Tree, MatchKeys("password")), MarshalOptions{Indent: "  ", MaxWidth: 80, Comments: true, Meta: doc.Meta},
+			"{\n  // the name\n  \"name\": \"x\", // trailing\n  \"db\": {\n    \"password\": \"***\" // secret\n  }\n}"},
+		{"generated", generated, MarshalOptions{Indent: "  ", Comments: true, Meta: &meta},
+			"{\n  // generated\n  // don't edit\n  \"port\": 8080,\n  \"items\": [\n    {\n      \"id\": 1 // first\n    }\n  ]\n}"},
+		{"forgotten", generated, MarshalOptions{Compact: true, Comments: true, Meta: forgotten},
+			`{"port":8080,"items":[{"id":1}]}`},
+	}
+
+	for _, test := range tests {
+		got, err := test.opts.Marshal(test.tree)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+
+	if value, ok := forgotten.Get(Path{"portable"}, CommentMeta); !ok || value != "kept" {
+		t.Errorf("Forget(.items) took .portable's comment too")
+	}
+}
+
+func TestEditComments(t *testing.T) {
+	text := "{\n  // note\n  \"a\": 1,\n  \"b\": {\n    // inner\n    \"c\": 2\n  }\n}"
+	doc, err := ParseJSONC([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		old, new string
+		// the comments of .a and .b.c afterwards
+		wantA, wantC interface{}
+	}{
+		{"removing a comment", "// inner\n    ", "", "note", nil},
+		{"removing a comment at the top level", "// note\n  ", "", nil, "inner"},
+		{"changing a comment", "inner", "outer", "note", "outer"},
+		{"changing a value", "2", "3", "note", "inner"},
+	}
+
+	for _, test := range tests {
+		edited, err := doc.Edit(strings.Index(text, test.old), len(test.old), []byte(test.new))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if comment, _ := edited.Meta.Get(Path{"a"}, CommentMeta); comment != test.wantA {
+			t.Errorf("%s: the comment of .a is %v, want %v", test.name, comment, test.wantA)
+		}
+
+		if comment, _ := edited.Meta.Get(Path{"b", "c"}, CommentMeta); comment != test.wantC {
+			t.Errorf("%s: the comment of .b.c is %v, want %v", test.name, comment, test.wantC)
+		}
+	}
+
+	if comment, _ := doc.Meta.Get(Path{"b", "c"}, CommentMeta); comment != "inner" {
+		t.Errorf("Edit changed the metadata of the document it was called on")
+	}
+}
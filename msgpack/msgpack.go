@@ -0,0 +1,400 @@
+// Package msgpack converts between ordered json trees and MessagePack. maps are written and read in
+// insertion order, so converting json -> msgpack -> json gives back the same document.
+//
+// integers too big for a float64 to hold exactly come out of Decode as json.Numbers with all their
+// digits, and Encode writes json.Numbers that are integers as msgpack ints when they fit in one.
+package msgpack
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type JsonObject = orderedmap.OrderedMap[string, interface{}]
+
+// Encode encodes any value from the tree (*JsonObject, []interface{}, string, float64, json.Number,
+// bool or nil). integral numbers use the smallest msgpack int that holds them, everything else is a
+// float 64.
+func Encode(value interface{}) ([]byte, error) {
+	return appendValue(make([]byte, 0, 64), value)
+}
+
+func appendValue(buf []byte, value interface{}) ([]byte, error) {
+	var err error
+
+	switch v := value.(type) {
+	case nil:
+		return append(buf, 0xc0), nil
+	case bool:
+		if v {
+			return append(buf, 0xc3), nil
+		}
+
+		return append(buf, 0xc2), nil
+	case float64:
+		return appendNumber(buf, v), nil
+	case json.Number:
+		if n, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return appendInt(buf, n), nil
+		}
+
+		if n, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return appendUint(buf, n), nil
+		}
+
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("msgpack: cannot encode number %s", v)
+		}
+
+		return appendNumber(buf, f), nil
+	case string:
+		length := len(v)
+		switch {
+		case length < 32:
+			buf = append(buf, 0xa0|byte(length))
+		case length <= math.MaxUint8:
+			buf = append(buf, 0xd9, byte(length))
+		case length <= math.MaxUint16:
+			buf = binary.BigEndian.AppendUint16(append(buf, 0xda), uint16(length))
+		default:
+			buf = binary.BigEndian.AppendUint32(append(buf, 0xdb), uint32(length))
+		}
+
+		return append(buf, v...), nil
+	case []interface{}:
+		buf = appendContainerHead(buf, len(v), 0x90, 0xdc, 0xdd)
+		for _, item := range v {
+			if buf, err = appendValue(buf, item); err != nil {
+				return nil, err
+			}
+		}
+
+		return buf, nil
+	case *JsonObject:
+		buf = appendContainerHead(buf, v.Len(), 0x80, 0xde, 0xdf)
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			if buf, err = appendValue(buf, pair.Key); err != nil {
+				return nil, err
+			}
+
+			if buf, err = appendValue(buf, pair.Value); err != nil {
+				return nil, err
+			}
+		}
+
+		return buf, nil
+	}
+
+	return nil, fmt.Errorf("msgpack: cannot encode %T", value)
+}
+
+func appendContainerHead(buf []byte, length int, fix, head16, head32 byte) []byte {
+	switch {
+	case length < 16:
+		return append(buf, fix|byte(length))
+	case length <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, head16), uint16(length))
+	}
+
+	return binary.BigEndian.AppendUint32(append(buf, head32), uint32(length))
+}
+
+func appendNumber(buf []byte, v float64) []byte {
+	// -0 is a float too, an int would lose the sign
+	if v != math.Trunc(v) || math.IsInf(v, 0) || math.Abs(v) > 1<<53 || v == 0 && math.Signbit(v) {
+		return binary.BigEndian.AppendUint64(append(buf, 0xcb), math.Float64bits(v))
+	}
+
+	if v >= 0 {
+		return appendUint(buf, uint64(v))
+	}
+
+	return appendInt(buf, int64(v))
+}
+
+func appendUint(buf []byte, n uint64) []byte {
+	switch {
+	case n <= 0x7f:
+		return append(buf, byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xcd), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xce), uint32(n))
+	}
+
+	return binary.BigEndian.AppendUint64(append(buf, 0xcf), n)
+}
+
+func appendInt(buf []byte, n int64) []byte {
+	if n >= 0 {
+		return appendUint(buf, uint64(n))
+	}
+
+	switch {
+	case n >= -32:
+		return append(buf, byte(n))
+	case n >= math.MinInt8:
+		return append(buf, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xd1), uint16(n))
+	case n >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xd2), uint32(n))
+	}
+
+	return binary.BigEndian.AppendUint64(append(buf, 0xd3), uint64(n))
+}
+
+// Decode decodes a single msgpack value. binary values become base64 strings and timestamps (ext
+// type -1) become RFC 3339 strings, since json has neither. ints are float64s, or json.Numbers past
+// 2^53.
+func Decode(data []byte) (interface{}, error) {
+	decoder := &decoder{data: data}
+	value, err := decoder.decodeValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if decoder.idx != len(data) {
+		return nil, fmt.Errorf("msgpack: %d trailing bytes after value", len(data)-decoder.idx)
+	}
+
+	return value, nil
+}
+
+type decoder struct {
+	data  []byte
+	idx   int
+	depth int
+}
+
+func (decoder *decoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("msgpack: %s at offset %d", fmt.Sprintf(format, args...), decoder.idx)
+}
+
+func (decoder *decoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(decoder.data)-decoder.idx) {
+		return nil, decoder.errorf("unexpected end of data")
+	}
+
+	result := decoder.data[decoder.idx : decoder.idx+int(n)]
+	decoder.idx += int(n)
+	return result, nil
+}
+
+// reads a big endian unsigned int of size bytes
+func (decoder *decoder) readUint(size int) (uint64, error) {
+	b, err := decoder.read(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	}
+
+	return binary.BigEndian.Uint64(b), nil
+}
+
+func (decoder *decoder) decodeValue() (interface{}, error) {
+	decoder.depth++
+	defer func() { decoder.depth-- }()
+
+	if decoder.depth > 10000 {
+		return nil, decoder.errorf("nesting too deep")
+	}
+
+	head, err := decoder.read(1)
+	if err != nil {
+		return nil, err
+	}
+
+	b := head[0]
+	switch {
+	case b <= 0x7f:
+		return float64(b), nil
+	case b >= 0xe0:
+		return float64(int8(b)), nil
+	case b&0xf0 == 0x80:
+		return decoder.decodeMap(uint64(b & 0x0f))
+	case b&0xf0 == 0x90:
+		return decoder.decodeArray(uint64(b & 0x0f))
+	case b&0xe0 == 0xa0:
+		raw, err := decoder.read(uint64(b & 0x1f))
+		return string(raw), err
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		length, err := decoder.readUint(1 << (b - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+
+		raw, err := decoder.read(length)
+		if err != nil {
+			return nil, err
+		}
+
+		return base64.StdEncoding.EncodeToString(raw), nil
+	case 0xc7, 0xc8, 0xc9:
+		length, err := decoder.readUint(1 << (b - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.decodeExt(length)
+	case 0xca:
+		n, err := decoder.readUint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := decoder.readUint(8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := decoder.readUint(1 << (b - 0xcc))
+		if n > 1<<53 {
+			return json.Number(strconv.FormatUint(n, 10)), err
+		}
+
+		return float64(n), err
+	case 0xd0:
+		n, err := decoder.readUint(1)
+		return float64(int8(n)), err
+	case 0xd1:
+		n, err := decoder.readUint(2)
+		return float64(int16(n)), err
+	case 0xd2:
+		n, err := decoder.readUint(4)
+		return float64(int32(n)), err
+	case 0xd3:
+		n, err := decoder.readUint(8)
+		if int64(n) < -1<<53 || int64(n) > 1<<53 {
+			return json.Number(strconv.FormatInt(int64(n), 10)), err
+		}
+
+		return float64(int64(n)), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return decoder.decodeExt(1 << (b - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		length, err := decoder.readUint(1 << (b - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+
+		raw, err := decoder.read(length)
+		return string(raw), err
+	case 0xdc, 0xdd:
+		length, err := decoder.readUint(2 << (b - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.decodeArray(length)
+	case 0xde, 0xdf:
+		length, err := decoder.readUint(2 << (b - 0xde))
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.decodeMap(length)
+	}
+
+	return nil, decoder.errorf("invalid type byte 0x%02x", b)
+}
+
+func (decoder *decoder) decodeArray(length uint64) (interface{}, error) {
+	if length > uint64(len(decoder.data)) {
+		return nil, decoder.errorf("array length %d is too large", length)
+	}
+
+	result := make([]interface{}, 0, length)
+	for i := uint64(0); i < length; i++ {
+		item, err := decoder.decodeValue()
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, item)
+	}
+
+	return result, nil
+}
+
+func (decoder *decoder) decodeMap(length uint64) (interface{}, error) {
+	if length > uint64(len(decoder.data)) {
+		return nil, decoder.errorf("map length %d is too large", length)
+	}
+
+	result := orderedmap.New[string, interface{}]()
+	for i := uint64(0); i < length; i++ {
+		key, err := decoder.decodeValue()
+		if err != nil {
+			return nil, err
+		}
+
+		name, ok := key.(string)
+		if !ok {
+			return nil, decoder.errorf("map keys must be strings to convert to json, got %T", key)
+		}
+
+		value, err := decoder.decodeValue()
+		if err != nil {
+			return nil, err
+		}
+
+		result.Set(name, value)
+	}
+
+	return result, nil
+}
+
+func (decoder *decoder) decodeExt(length uint64) (interface{}, error) {
+	extType, err := decoder.read(1)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := decoder.read(length)
+	if err != nil {
+		return nil, err
+	}
+
+	if int8(extType[0]) != -1 {
+		return nil, decoder.errorf("unsupported extension type %d", int8(extType[0]))
+	}
+
+	var t time.Time
+	switch len(data) {
+	case 4:
+		t = time.Unix(int64(binary.BigEndian.Uint32(data)), 0)
+	case 8:
+		n := binary.BigEndian.Uint64(data)
+		t = time.Unix(int64(n&0x3ffffffff), int64(n>>34))
+	case 12:
+		t = time.Unix(int64(binary.BigEndian.Uint64(data[4:])), int64(binary.BigEndian.Uint32(data[:4])))
+	default:
+		return nil, decoder.errorf("invalid timestamp length %d", len(data))
+	}
+
+	return t.UTC().Format(time.RFC3339Nano), nil
+}
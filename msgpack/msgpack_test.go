@@ -0,0 +1,251 @@
+package msgpack
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/michaelhelvey/orderedjson/v2/internal/testtree"
+)
+
+// every format Decode reads is written back in the smallest one that holds the value: integers at
+// the edges of each width, strings and binary data, and the timestamp extension
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		msgpack string
+		json    string
+		encoded string
+	}{
+		{"7f", `127`, "7f"},
+		{"d07f", `127`, "7f"},
+		{"cf000000000000007f", `127`, "7f"},
+		{"cc80", `128`, "cc80"},
+		{"ccff", `255`, "ccff"},
+		{"cd0100", `256`, "cd0100"},
+		{"cdffff", `65535`, "cdffff"},
+		{"ceffffffff", `4294967295`, "ceffffffff"},
+		{"cf0000000100000000", `4294967296`, "cf0000000100000000"},
+		{"cfffffffffffffffff", `18446744073709551615`, "cfffffffffffffffff"},
+		{"e0", `-32`, "e0"},
+		{"d3ffffffffffffffe0", `-32`, "e0"},
+		{"d0df", `-33`, "d0df"},
+		{"d080", `-128`, "d080"},
+		{"d1ff7f", `-129`, "d1ff7f"},
+		{"d2ffff8000", `-32768`, "d18000"},
+		{"d38000000000000000", `-9223372036854775808`, "d38000000000000000"},
+		{"ca3fc00000", `1.5`, "cb3ff8000000000000"},
+		// str 8 is a string, bin 8 is binary data that becomes base64
+		{"d90161", `"a"`, "a161"},
+		{"d920" + strings.Repeat("61", 32), `"` + strings.Repeat("a", 32) + `"`, "d920" + strings.Repeat("61", 32)},
+		{"c40161", `"YQ=="`, "a459513d3d"},
+		{"c400", `""`, "a0"},
+		// timestamps of each width are strings
+		{"d6ff5e0be100", `"2020-01-01T00:00:00Z"`, "b4323032302d30312d30315430303a30303a30305a"},
+		{"c70cff000000000000000000000000", `"1970-01-01T00:00:00Z"`, "b4313937302d30312d30315430303a30303a30305a"},
+		{"81a161d6ff00000000", `{"a":"1970-01-01T00:00:00Z"}`, "81a161b4313937302d30312d30315430303a30303a30305a"},
+	}
+
+	for _, test := range tests {
+		raw, _ := hex.DecodeString(test.msgpack)
+		decoded, err := Decode(raw)
+		if err != nil || testtree.Marshal(t, decoded) != test.json {
+			t.Errorf("Decode(%.40s) = %v, %v, want %.40s", test.msgpack, decoded, err, test.json)
+			continue
+		}
+
+		data, err := Encode(decoded)
+		if got := hex.EncodeToString(data); err != nil || got != test.encoded {
+			t.Errorf("Encode(Decode(%.40s)) = %.40s, %v, want %.40s", test.msgpack, got, err, test.encoded)
+			continue
+		}
+
+		if again, err := Decode(data); err != nil || testtree.Marshal(t, again) != test.json {
+			t.Errorf("Decode(%.40s) = %v, %v, want %.40s", test.encoded, again, err, test.json)
+		}
+	}
+
+	// extensions other than timestamps can't be json
+	for _, input := range []string{"d40100", "d501" + "0000", "c70101" + "00", "c70005"} {
+		raw, _ := hex.DecodeString(input)
+		if value, err := Decode(raw); err == nil || !strings.Contains(err.Error(), "extension type") {
+			t.Errorf("Decode(%s) = %v, %v, want an unsupported extension", input, value, err)
+		}
+	}
+}
+
+// the encodings of the msgpack spec, with the smallest format that holds each value
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		json    string
+		msgpack string
+	}{
+		{`null`, "c0"},
+		{`false`, "c2"},
+		{`true`, "c3"},
+		{`0`, "00"},
+		{`127`, "7f"},
+		{`128`, "cc80"},
+		{`256`, "cd0100"},
+		{`65536`, "ce00010000"},
+		{`4294967296`, "cf0000000100000000"},
+		{`-1`, "ff"},
+		{`-32`, "e0"},
+		{`-33`, "d0df"},
+		{`-129`, "d1ff7f"},
+		{`-32769`, "d2ffff7fff"},
+		{`-2147483649`, "d3ffffffff7fffffff"},
+		{`1.5`, "cb3ff8000000000000"},
+		{`""`, "a0"},
+		{`"a"`, "a161"},
+		{`"` + strings.Repeat("a", 31) + `"`, "bf" + strings.Repeat("61", 31)},
+		{`"` + strings.Repeat("a", 32) + `"`, "d920" + strings.Repeat("61", 32)},
+		{`"` + strings.Repeat("a", 256) + `"`, "da0100" + strings.Repeat("61", 256)},
+		{`[]`, "90"},
+		{`[1,[2]]`, "92019102"},
+		{`[` + strings.Repeat("0,", 15) + `0]`, "dc0010" + strings.Repeat("00", 16)},
+		{`{}`, "80"},
+		{`{"b":1,"a":{}}`, "82a16201a16180"},
+	}
+
+	for _, test := range tests {
+		data, err := Encode(testtree.Parse(t, test.json))
+		if err != nil {
+			t.Errorf("Encode(%.40s): %v", test.json, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(data); got != test.msgpack {
+			t.Errorf("Encode(%.40s) = %.40s, want %.40s", test.json, got, test.msgpack)
+		}
+
+		raw, _ := hex.DecodeString(test.msgpack)
+		decoded, err := Decode(raw)
+		if err != nil {
+			t.Errorf("Decode(%.40s): %v", test.msgpack, err)
+			continue
+		}
+
+		if got := testtree.Marshal(t, decoded); got != test.json {
+			t.Errorf("Decode(%.40s) = %.40s, want %.40s", test.msgpack, got, test.json)
+		}
+	}
+}
+
+// what Decode makes of the formats Encode doesn't write
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		msgpack string
+		json    string
+	}{
+		{"ca3fc00000", `1.5`},
+		{"d001", `1`},
+		{"cc01", `1`},
+		{"dd00000001a0", `[""]`},
+		{"df00000001a161c0", `{"a":null}`},
+		{"db0000000162", `"b"`},
+		{"c403010203", `"AQID"`},
+		{"c50000", `""`},
+		{"d6ff00000000", `"1970-01-01T00:00:00Z"`},
+		{"d7ff" + "0000000400000001", `"1970-01-01T00:00:01.000000001Z"`},
+		{"c70cff" + "00000001" + "ffffffffffffffff", `"1969-12-31T23:59:59.000000001Z"`},
+	}
+
+	for _, test := range tests {
+		raw, _ := hex.DecodeString(test.msgpack)
+		decoded, err := Decode(raw)
+		if err != nil {
+			t.Errorf("Decode(%s): %v", test.msgpack, err)
+			continue
+		}
+
+		if got := testtree.Marshal(t, decoded); got != test.json {
+			t.Errorf("Decode(%s) = %s, want %s", test.msgpack, got, test.json)
+		}
+	}
+}
+
+// integers past 2^53 are json.Numbers, since a float64 would lose digits
+func TestBigIntegers(t *testing.T) {
+	tests := []struct {
+		number  json.Number
+		msgpack string
+	}{
+		{"9007199254740993", "cf0020000000000001"},
+		{"18446744073709551615", "cfffffffffffffffff"},
+		{"-9007199254740993", "d3ffdfffffffffffff"},
+		{"-9223372036854775808", "d38000000000000000"},
+	}
+
+	for _, test := range tests {
+		data, err := Encode(test.number)
+		if err != nil {
+			t.Errorf("Encode(%s): %v", test.number, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(data); got != test.msgpack {
+			t.Errorf("Encode(%s) = %s, want %s", test.number, got, test.msgpack)
+		}
+
+		if decoded, err := Decode(data); err != nil || decoded != test.number {
+			t.Errorf("Decode(Encode(%s)) = %#v, %v", test.number, decoded, err)
+		}
+	}
+
+	// past 64 bits they're floats
+	if data, err := Encode(json.Number("18446744073709551616")); err != nil || hex.EncodeToString(data) != "cb43f0000000000000" {
+		t.Errorf("Encode(18446744073709551616) = %x, %v", data, err)
+	}
+}
+
+func TestSpecialFloats(t *testing.T) {
+	for _, value := range []float64{math.Inf(1), math.Inf(-1), math.NaN(), math.Copysign(0, -1)} {
+		data, err := Encode(value)
+		if err != nil {
+			t.Errorf("Encode(%v): %v", value, err)
+			continue
+		}
+
+		if data[0] != 0xcb {
+			t.Errorf("Encode(%v) = %x, want a float 64", value, data)
+		}
+
+		decoded, err := Decode(data)
+		f, ok := decoded.(float64)
+		if err != nil || !ok || !(f == value || math.IsNaN(f) && math.IsNaN(value)) || math.Signbit(f) != math.Signbit(value) {
+			t.Errorf("Decode(Encode(%v)) = %v, %v", value, decoded, err)
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"c1",
+		"cc",
+		"cd01",
+		"a261",
+		"0000",
+		"8101c0",
+		"81a161",
+		"92c0",
+		"dcffff",
+		"c406",
+		"d40100",
+		"d6ff000000",
+		"c703ff000000",
+	} {
+		raw, _ := hex.DecodeString(input)
+		if value, err := Decode(raw); err == nil {
+			t.Errorf("Decode(%s) = %v, want an error", input, value)
+		}
+	}
+
+	for _, value := range []interface{}{json.Number("1x"), 1, map[string]interface{}{}} {
+		if data, err := Encode(value); err == nil {
+			t.Errorf("Encode(%#v) = %x, want an error", value, data)
+		}
+	}
+}
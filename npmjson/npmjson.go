@@ -0,0 +1,370 @@
+// Package npmjson reads and edits package.json files. everything works on the ordered tree, so a
+// file that's parsed, changed and written again only differs where it was changed, with the
+// indentation it had.
+//
+//	pkg, err := npmjson.Parse(data)
+//	pkg.SetDependency("left-pad", "^1.3.0")
+//	pkg.BumpVersion(npmjson.Minor)
+//	data, err = pkg.Marshal()
+package npmjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/michaelhelvey/orderedjson/v2/compat"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type JsonObject = orderedmap.OrderedMap[string, interface{}]
+
+// the sections of package.json that map package names to version ranges
+const (
+	Dependencies         = "dependencies"
+	DevDependencies      = "devDependencies"
+	PeerDependencies     = "peerDependencies"
+	OptionalDependencies = "optionalDependencies"
+)
+
+// FieldOrder is the order SortFields puts the fields of a package.json in, the one the npm docs
+// describe them in. fields that aren't in it go after these.
+var FieldOrder = []string{
+	"name", "version", "private", "description", "keywords", "homepage", "bugs", "repository",
+	"funding", "license", "author", "contributors", "maintainers", "type", "exports", "main",
+	"module", "browser", "types", "typings", "bin", "man", "files", "directories", "workspaces",
+	"scripts", "config", "dependencies", "devDependencies", "peerDependencies",
+	"peerDependenciesMeta", "optionalDependencies", "bundleDependencies", "bundledDependencies",
+	"overrides", "engines", "os", "cpu", "packageManager", "publishConfig",
+}
+
+type Package struct {
+	Tree *JsonObject
+
+	// how the file was laid out, to write it back the same way
+	indent       string
+	finalNewline bool
+}
+
+// Parse parses a package.json, remembering its indentation.
+func Parse(data []byte) (*Package, error) {
+	tree := orderedmap.New[string, interface{}]()
+	if err := compat.Unmarshal(data, tree); err != nil {
+		return nil, err
+	}
+
+	return &Package{Tree: tree, indent: detectIndent(data), finalNewline: bytes.HasSuffix(data, []byte("\n"))}, nil
+}
+
+// New is an empty package.json, written with two space indentation like npm does.
+func New() *Package {
+	return &Package{Tree: orderedmap.New[string, interface{}](), indent: "  ", finalNewline: true}
+}
+
+// the indentation of the first indented line, two spaces if there isn't one
+func detectIndent(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n")[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+
+	return "  "
+}
+
+// Marshal writes the package.json with the indentation it was parsed with. unlike encoding/json it
+// doesn't escape &, < and >, which show up in scripts all the time.
+func (pkg *Package) Marshal() ([]byte, error) {
+	var out bytes.Buffer
+	if err := writeValue(&out, pkg.Tree, pkg.indent, 0); err != nil {
+		return nil, err
+	}
+
+	if pkg.finalNewline {
+		out.WriteByte('\n')
+	}
+
+	return out.Bytes(), nil
+}
+
+func writeValue(out *bytes.Buffer, value interface{}, indent string, depth int) error {
+	newline := func(depth int) {
+		out.WriteByte('\n')
+		out.WriteString(strings.Repeat(indent, depth))
+	}
+
+	switch v := value.(type) {
+	case *JsonObject:
+		if v.Len() == 0 {
+			out.WriteString("{}")
+			return nil
+		}
+
+		out.WriteByte('{')
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			newline(depth + 1)
+			if err := writeScalar(out, pair.Key); err != nil {
+				return err
+			}
+
+			out.WriteString(": ")
+			if err := writeValue(out, pair.Value, indent, depth+1); err != nil {
+				return err
+			}
+
+			if pair.Next() != nil {
+				out.WriteByte(',')
+			}
+		}
+
+		newline(depth)
+		out.WriteByte('}')
+	case []interface{}:
+		if len(v) == 0 {
+			out.WriteString("[]")
+			return nil
+		}
+
+		out.WriteByte('[')
+		for i, item := range v {
+			newline(depth + 1)
+			if err := writeValue(out, item, indent, depth+1); err != nil {
+				return err
+			}
+
+			if i < len(v)-1 {
+				out.WriteByte(',')
+			}
+		}
+
+		newline(depth)
+		out.WriteByte(']')
+	default:
+		return writeScalar(out, value)
+	}
+
+	return nil
+}
+
+func writeScalar(out *bytes.Buffer, value interface{}) error {
+	var scalar bytes.Buffer
+	encoder := json.NewEncoder(&scalar)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+
+	out.Write(bytes.TrimSuffix(scalar.Bytes(), []byte("\n")))
+	return nil
+}
+
+func (pkg *Package) str(key string) string {
+	value, _ := pkg.Tree.Get(key)
+	s, _ := value.(string)
+	return s
+}
+
+func (pkg *Package) Name() string {
+	return pkg.str("name")
+}
+
+// Version is the version field as it's written, "" if there isn't one.
+func (pkg *Package) Version() string {
+	return pkg.str("version")
+}
+
+// SetVersion sets the version field, which is added after the name if there isn't one.
+func (pkg *Package) SetVersion(version string) {
+	pkg.setField("version", version)
+}
+
+// BumpVersion increments part of the version, and returns the new one.
+func (pkg *Package) BumpVersion(part Part) (Version, error) {
+	current, err := ParseVersion(pkg.Version())
+	if err != nil {
+		return Version{}, fmt.Errorf("version: %w", err)
+	}
+
+	bumped, err := current.Bump(part)
+	if err != nil {
+		return Version{}, err
+	}
+
+	pkg.SetVersion(bumped.String())
+	return bumped, nil
+}
+
+// sets key, putting a new key where FieldOrder says it goes relative to the fields that are there
+func (pkg *Package) setField(key string, value interface{}) {
+	if _, ok := pkg.Tree.Get(key); ok {
+		pkg.Tree.Set(key, value)
+		return
+	}
+
+	pkg.Tree.Set(key, value)
+	rank := fieldRank(key)
+	if rank == len(FieldOrder) {
+		return
+	}
+
+	// after the last field that comes before it
+	var after string
+	for pair := pkg.Tree.Oldest(); pair != nil; pair = pair.Next() {
+		if pair.Key != key && fieldRank(pair.Key) < rank {
+			after = pair.Key
+		}
+	}
+
+	if after == "" {
+		pkg.Tree.MoveToFront(key)
+	} else {
+		pkg.Tree.MoveAfter(key, after)
+	}
+}
+
+// where key is in FieldOrder, after all of it if it isn't
+func fieldRank(key string) int {
+	for i, field := range FieldOrder {
+		if field == key {
+			return i
+		}
+	}
+
+	return len(FieldOrder)
+}
+
+// Scripts returns the scripts by name.
+func (pkg *Package) Scripts() (*orderedmap.OrderedMap[string, string], error) {
+	return pkg.stringMap("scripts")
+}
+
+// SetScript adds or replaces the script called name.
+func (pkg *Package) SetScript(name, command string) error {
+	return pkg.setIn("scripts", name, command, false)
+}
+
+// Dependencies returns the dependencies with their version ranges. like the other accessors it's a
+// copy, changes go through the setters.
+func (pkg *Package) Dependencies() (*orderedmap.OrderedMap[string, string], error) {
+	return pkg.stringMap(Dependencies)
+}
+
+func (pkg *Package) DevDependencies() (*orderedmap.OrderedMap[string, string], error) {
+	return pkg.stringMap(DevDependencies)
+}
+
+func (pkg *Package) PeerDependencies() (*orderedmap.OrderedMap[string, string], error) {
+	return pkg.stringMap(PeerDependencies)
+}
+
+// SetDependency adds name to the dependencies with the version range semver, or changes its range.
+// a new one is put in alphabetical order like npm install does, when the others are in it.
+func (pkg *Package) SetDependency(name, semver string) error {
+	return pkg.setIn(Dependencies, name, semver, true)
+}
+
+func (pkg *Package) SetDevDependency(name, semver string) error {
+	return pkg.setIn(DevDependencies, name, semver, true)
+}
+
+// RemoveDependency removes name from every dependency section, and returns whether it was in one.
+func (pkg *Package) RemoveDependency(name string) bool {
+	removed := false
+	for _, section := range []string{Dependencies, DevDependencies, PeerDependencies, OptionalDependencies} {
+		value, _ := pkg.Tree.Get(section)
+		if object, ok := value.(*JsonObject); ok {
+			if _, ok := object.Delete(name); ok {
+				removed = true
+			}
+		}
+	}
+
+	return removed
+}
+
+func (pkg *Package) stringMap(key string) (*orderedmap.OrderedMap[string, string], error) {
+	result := orderedmap.New[string, string]()
+	value, ok := pkg.Tree.Get(key)
+	if !ok {
+		return result, nil
+	}
+
+	object, ok := value.(*JsonObject)
+	if !ok {
+		return nil, fmt.Errorf("%s is a %T, not an object", key, value)
+	}
+
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		s, ok := pair.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s is a %T, not a string", key, pair.Key, pair.Value)
+		}
+
+		result.Set(pair.Key, s)
+	}
+
+	return result, nil
+}
+
+// sets name in the object at section, creating it if it's missing
+func (pkg *Package) setIn(section, name, value string, sorted bool) error {
+	existing, ok := pkg.Tree.Get(section)
+	if !ok {
+		existing = orderedmap.New[string, interface{}]()
+		pkg.setField(section, existing)
+	}
+
+	object, ok := existing.(*JsonObject)
+	if !ok {
+		return fmt.Errorf("%s is a %T, not an object", section, existing)
+	}
+
+	if _, ok := object.Get(name); ok || !sorted || !isSorted(object) {
+		object.Set(name, value)
+		return nil
+	}
+
+	object.Set(name, value)
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		if pair.Key > name {
+			object.MoveBefore(name, pair.Key)
+			break
+		}
+	}
+
+	return nil
+}
+
+func isSorted(object *JsonObject) bool {
+	previous := ""
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		if pair.Key < previous {
+			return false
+		}
+
+		previous = pair.Key
+	}
+
+	return true
+}
+
+// SortFields puts the top level fields in the order of FieldOrder. other fields go after them, in
+// the order they were in.
+func (pkg *Package) SortFields() {
+	var unknown []string
+	for pair := pkg.Tree.Oldest(); pair != nil; pair = pair.Next() {
+		if fieldRank(pair.Key) == len(FieldOrder) {
+			unknown = append(unknown, pair.Key)
+		}
+	}
+
+	for _, field := range FieldOrder {
+		pkg.Tree.MoveToBack(field)
+	}
+
+	for _, field := range unknown {
+		pkg.Tree.MoveToBack(field)
+	}
+}
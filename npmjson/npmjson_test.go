@@ -0,0 +1,275 @@
+package npmjson
+
+import (
+	"strings"
+	"testing"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+const packageJSON = `{
+    "name": "demo",
+    "version": "1.2.3",
+    "scripts": {
+        "test": "jest && eslint <src>"
+    },
+    "dependencies": {
+        "b": "^1.0.0",
+        "d": "~2.0.0"
+    },
+    "zzz": {"custom": [1, 2.5, true, null, []]}
+}
+`
+
+func mustParse(t *testing.T, data string) *Package {
+	t.Helper()
+	pkg, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	return pkg
+}
+
+func mustMarshal(t *testing.T, pkg *Package) string {
+	t.Helper()
+	data, err := pkg.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	return string(data)
+}
+
+// the keys of object, space separated
+func keys[V any](object *orderedmap.OrderedMap[string, V]) string {
+	var names []string
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		names = append(names, pair.Key)
+	}
+
+	return strings.Join(names, " ")
+}
+
+// a file that isn't changed comes back the same, apart from the layout of what's on one line
+func TestRoundTrip(t *testing.T) {
+	want := strings.Replace(packageJSON, `{"custom": [1, 2.5, true, null, []]}`, "{\n        \"custom\": [\n            1,\n            2.5,\n            true,\n            null,\n            []\n        ]\n    }", 1)
+	if got := mustMarshal(t, mustParse(t, packageJSON)); got != want {
+		t.Errorf("Marshal =\n%s\nwant\n%s", got, want)
+	}
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"{\n\t\"a\": {}\n}", "{\n\t\"a\": {}\n}"},
+		{`{"a":1,"b":[]}`, "{\n  \"a\": 1,\n  \"b\": []\n}"},
+		{"{}\n", "{}\n"},
+		{"{\n\n   \"a\": \"<&>\"\n}\n", "{\n   \"a\": \"<&>\"\n}\n"},
+	}
+
+	for _, test := range tests {
+		if got := mustMarshal(t, mustParse(t, test.input)); got != test.want {
+			t.Errorf("Marshal(Parse(%q)) = %q, want %q", test.input, got, test.want)
+		}
+	}
+
+	if got := mustMarshal(t, New()); got != "{}\n" {
+		t.Errorf("Marshal(New()) = %q", got)
+	}
+
+	for _, input := range []string{``, `[1]`, `"a"`, `{"a":`, `{"a":1} x`} {
+		if _, err := Parse([]byte(input)); err == nil {
+			t.Errorf("Parse(%q): want an error", input)
+		}
+	}
+}
+
+func TestAccessors(t *testing.T) {
+	pkg := mustParse(t, packageJSON)
+	if pkg.Name() != "demo" || pkg.Version() != "1.2.3" {
+		t.Errorf("Name, Version = %q, %q", pkg.Name(), pkg.Version())
+	}
+
+	scripts, err := pkg.Scripts()
+	if err != nil || scripts.Len() != 1 || scripts.Value("test") != "jest && eslint <src>" {
+		t.Errorf("Scripts() = %v, %v", scripts, err)
+	}
+
+	dependencies, err := pkg.Dependencies()
+	if err != nil || dependencies.Len() != 2 || dependencies.Oldest().Key != "b" || dependencies.Value("d") != "~2.0.0" {
+		t.Errorf("Dependencies() = %v, %v", dependencies, err)
+	}
+
+	// a copy, so changing it doesn't change the file
+	dependencies.Set("x", "1")
+	if again, _ := pkg.Dependencies(); again.Len() != 2 {
+		t.Errorf("Dependencies() changed through its result")
+	}
+
+	if dev, err := pkg.DevDependencies(); err != nil || dev.Len() != 0 {
+		t.Errorf("DevDependencies() = %v, %v", dev, err)
+	}
+
+	for _, input := range []string{`{"dependencies": []}`, `{"dependencies": {"a": 1}}`, `{"peerDependencies": "a"}`} {
+		pkg := mustParse(t, input)
+		_, err1 := pkg.Dependencies()
+		_, err2 := pkg.PeerDependencies()
+		if err1 == nil && err2 == nil {
+			t.Errorf("Dependencies(%s): want an error", input)
+		}
+	}
+}
+
+func TestSetDependency(t *testing.T) {
+	pkg := mustParse(t, packageJSON)
+	for _, name := range []string{"c", "a", "e", "d"} {
+		if err := pkg.SetDependency(name, "^3.0.0"); err != nil {
+			t.Fatalf("SetDependency(%s): %v", name, err)
+		}
+	}
+
+	dependencies, _ := pkg.Dependencies()
+	if got := keys(dependencies); got != "a b c d e" || dependencies.Value("d") != "^3.0.0" {
+		t.Errorf("dependencies = %v", dependencies)
+	}
+
+	// unsorted sections are left in their order
+	pkg = mustParse(t, `{"dependencies": {"z": "1", "a": "1"}}`)
+	pkg.SetDependency("m", "2")
+	if dependencies, _ := pkg.Dependencies(); keys(dependencies) != "z a m" {
+		t.Errorf("dependencies = %v", keys(dependencies))
+	}
+
+	// a new section goes where FieldOrder puts it
+	pkg = mustParse(t, packageJSON)
+	if err := pkg.SetDevDependency("jest", "^29.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pkg.SetScript("build", "tsc"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := keys(pkg.Tree); got != "name version scripts dependencies devDependencies zzz" {
+		t.Errorf("fields = %s", got)
+	}
+
+	if scripts, _ := pkg.Scripts(); keys(scripts) != "test build" {
+		t.Errorf("scripts = %v", keys(scripts))
+	}
+
+	if err := mustParse(t, `{"dependencies": "x"}`).SetDependency("a", "1"); err == nil {
+		t.Errorf("SetDependency(dependencies that aren't an object): want an error")
+	}
+
+	pkg = mustParse(t, `{"dependencies": {"a": "1"}, "devDependencies": {"a": "1", "b": "2"}, "optionalDependencies": {"a": "1"}}`)
+	if !pkg.RemoveDependency("a") || pkg.RemoveDependency("a") || pkg.RemoveDependency("missing") {
+		t.Errorf("RemoveDependency(a) twice didn't remove it once")
+	}
+
+	if got := mustMarshal(t, pkg); got != "{\n  \"dependencies\": {},\n  \"devDependencies\": {\n    \"b\": \"2\"\n  },\n  \"optionalDependencies\": {}\n}" {
+		t.Errorf("Marshal = %q", got)
+	}
+}
+
+func TestFieldOrder(t *testing.T) {
+	pkg := mustParse(t, `{"custom": 1, "dependencies": {}, "name": "a"}`)
+	pkg.SetVersion("1.0.0")
+	if got := keys(pkg.Tree); got != "custom dependencies name version" {
+		t.Errorf("fields = %s", got)
+	}
+
+	pkg = New()
+	pkg.SetVersion("1.0.0")
+	pkg.Tree.Set("name", "a")
+	pkg.Tree.Set("other", true)
+	pkg.Tree.Set("license", "MIT")
+	pkg.SortFields()
+	if got := keys(pkg.Tree); got != "name version license other" {
+		t.Errorf("SortFields() = %s", got)
+	}
+
+	pkg = mustParse(t, `{"b": 1, "scripts": {}, "a": 2, "name": "x"}`)
+	pkg.SortFields()
+	if got := keys(pkg.Tree); got != "name scripts b a" {
+		t.Errorf("SortFields() = %s", got)
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	for _, input := range []string{"0.0.0", "1.2.3", "10.20.30", "1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-0.3.7", "1.0.0-x.7.z.92", "1.0.0-x-y-z.--", "1.0.0+20130313144700", "1.0.0-beta+exp.sha.5114f85", "1.0.0+21AF26D3---117B344092BD", "1.0.0+001"} {
+		version, err := ParseVersion(input)
+		if err != nil || version.String() != input {
+			t.Errorf("ParseVersion(%s) = %v, %v", input, version, err)
+		}
+	}
+
+	if version, err := ParseVersion("v1.2.3"); err != nil || version.String() != "1.2.3" {
+		t.Errorf("ParseVersion(v1.2.3) = %v, %v", version, err)
+	}
+
+	for _, input := range []string{"", "1", "1.2", "1.2.3.4", "01.2.3", "1.02.3", "1.2.03", "-1.2.3", "1.+2.3", "1.2.x", "1.2.3-", "1.2.3+", "1.2.3-a..b", "1.2.3-01", "1.2.3-a_b", "1.2.3+é", "99999999999999999999.0.0", "V1.2.3"} {
+		if version, err := ParseVersion(input); err == nil {
+			t.Errorf("ParseVersion(%q) = %v, want an error", input, version)
+		}
+	}
+}
+
+func TestBump(t *testing.T) {
+	tests := []struct {
+		version string
+		part    Part
+		want    string
+	}{
+		{"1.2.3", Major, "2.0.0"},
+		{"1.2.3", Minor, "1.3.0"},
+		{"1.2.3", Patch, "1.2.4"},
+		{"1.2.3", Prerelease, "1.2.4-0"},
+		{"1.2.3+build", Patch, "1.2.4"},
+		{"2.0.0-rc.1", Major, "2.0.0"},
+		{"2.1.0-rc.1", Major, "3.0.0"},
+		{"1.3.0-rc.1", Minor, "1.3.0"},
+		{"1.3.1-rc.1", Minor, "1.4.0"},
+		{"1.2.4-0", Patch, "1.2.4"},
+		{"1.2.4-beta.1", Prerelease, "1.2.4-beta.2"},
+		{"1.2.4-beta.9", Prerelease, "1.2.4-beta.10"},
+		{"1.2.4-beta", Prerelease, "1.2.4-beta.0"},
+		{"1.2.4-beta.-1", Prerelease, "1.2.4-beta.-1.0"},
+	}
+
+	for _, test := range tests {
+		version, err := ParseVersion(test.version)
+		if err != nil {
+			t.Fatalf("ParseVersion(%s): %v", test.version, err)
+		}
+
+		bumped, err := version.Bump(test.part)
+		if err != nil || bumped.String() != test.want {
+			t.Errorf("%s.Bump(%s) = %s, %v, want %s", test.version, test.part, bumped, err, test.want)
+		}
+
+		if version.String() != test.version {
+			t.Errorf("%s.Bump(%s) changed the version to %s", test.version, test.part, version)
+		}
+	}
+
+	if _, err := (Version{}).Bump("huge"); err == nil {
+		t.Errorf("Bump(huge): want an error")
+	}
+
+	pkg := mustParse(t, packageJSON)
+	if bumped, err := pkg.BumpVersion(Minor); err != nil || bumped.String() != "1.3.0" || pkg.Version() != "1.3.0" {
+		t.Errorf("BumpVersion(Minor) = %v, %v, version %s", bumped, err, pkg.Version())
+	}
+
+	if !strings.Contains(mustMarshal(t, pkg), "\n    \"version\": \"1.3.0\",\n") {
+		t.Errorf("Marshal after BumpVersion = %s", mustMarshal(t, pkg))
+	}
+
+	for _, input := range []string{`{}`, `{"version": "latest"}`, `{"version": 1}`} {
+		if bumped, err := mustParse(t, input).BumpVersion(Patch); err == nil {
+			t.Errorf("BumpVersion(%s) = %v, want an error", input, bumped)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package npmjson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version (https://semver.org), like 1.4.2-beta.1+build.5.
+type Version struct {
+	Major, Minor, Patch int
+	// the dot separated parts after the -, and after the +
+	Prerelease []string
+	Build      []string
+}
+
+// ParseVersion parses a version, with or without a v in front.
+func ParseVersion(s string) (Version, error) {
+	var version Version
+	rest := strings.TrimPrefix(s, "v")
+
+	rest, build, hasBuild := strings.Cut(rest, "+")
+	if hasBuild {
+		version.Build = strings.Split(build, ".")
+	}
+
+	core, prerelease, hasPrerelease := strings.Cut(rest, "-")
+	if hasPrerelease {
+		version.Prerelease = strings.Split(prerelease, ".")
+	}
+
+	numbers := strings.Split(core, ".")
+	if len(numbers) != 3 {
+		return Version{}, fmt.Errorf("invalid version %q: expected major.minor.patch", s)
+	}
+
+	for i, target := range []*int{&version.Major, &version.Minor, &version.Patch} {
+		n, err := strconv.Atoi(numbers[i])
+		if err != nil || !isNumeric(numbers[i]) || len(numbers[i]) > 1 && numbers[i][0] == '0' {
+			return Version{}, fmt.Errorf("invalid version %q: %q isn't a number", s, numbers[i])
+		}
+
+		*target = n
+	}
+
+	for i, identifier := range append(version.Prerelease, version.Build...) {
+		if identifier == "" || strings.Trim(identifier, "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-") != "" {
+			return Version{}, fmt.Errorf("invalid version %q: bad identifier %q", s, identifier)
+		}
+
+		// numbers in the prerelease can't have leading zeros, in the build they can
+		if i < len(version.Prerelease) && isNumeric(identifier) && len(identifier) > 1 && identifier[0] == '0' {
+			return Version{}, fmt.Errorf("invalid version %q: %q has a leading zero", s, identifier)
+		}
+	}
+
+	return version, nil
+}
+
+// strconv.Atoi also takes a sign, which versions can't have
+func isNumeric(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+func (version Version) String() string {
+	result := fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Patch)
+	if len(version.Prerelease) > 0 {
+		result += "-" + strings.Join(version.Prerelease, ".")
+	}
+
+	if len(version.Build) > 0 {
+		result += "+" + strings.Join(version.Build, ".")
+	}
+
+	return result
+}
+
+// Part is what Bump increments.
+type Part string
+
+const (
+	Major Part = "major"
+	Minor Part = "minor"
+	Patch Part = "patch"
+	// the last number of the prerelease, e.g. 1.0.0-beta.1 to 1.0.0-beta.2. a release becomes the
+	// first prerelease of the next patch, 1.0.0 to 1.0.1-0.
+	Prerelease Part = "prerelease"
+)
+
+// Bump increments part the way npm version does: the parts after it go back to 0, and the
+// prerelease and build are dropped. a prerelease of the version being bumped to is released
+// instead, so 2.0.0-rc.1 bumps to 2.0.0 with Major.
+func (version Version) Bump(part Part) (Version, error) {
+	pre := len(version.Prerelease) > 0
+	bumped := Version{Major: version.Major, Minor: version.Minor, Patch: version.Patch}
+
+	switch part {
+	case Major:
+		if !pre || version.Minor != 0 || version.Patch != 0 {
+			bumped = Version{Major: version.Major + 1}
+		}
+	case Minor:
+		if !pre || version.Patch != 0 {
+			bumped = Version{Major: version.Major, Minor: version.Minor + 1}
+		}
+	case Patch:
+		if !pre {
+			bumped.Patch++
+		}
+	case Prerelease:
+		if !pre {
+			bumped.Patch++
+			bumped.Prerelease = []string{"0"}
+			break
+		}
+
+		bumped.Prerelease = append([]string(nil), version.Prerelease...)
+		last := len(bumped.Prerelease) - 1
+		if n, err := strconv.Atoi(bumped.Prerelease[last]); err == nil && isNumeric(bumped.Prerelease[last]) {
+			bumped.Prerelease[last] = strconv.Itoa(n + 1)
+		} else {
+			bumped.Prerelease = append(bumped.Prerelease, "0")
+		}
+	default:
+		return Version{}, fmt.Errorf("unknown version part %q, expected major, minor, patch or prerelease", part)
+	}
+
+	return bumped, nil
+}
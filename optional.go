@@ -0,0 +1,111 @@
+package orderedjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// Optional is a field that tells apart a key that's missing, a key that's null and a key with a
+// value, for PATCH style updates where leaving a key out means "don't change it" and null means
+// "clear it". the zero value is absent.
+//
+// DecodeInto fills it in (leaving it absent when the key isn't there), and EncodeTree leaves the key
+// out when it's absent. with encoding/json, absent is written as null, since only the struct can
+// leave a key out.
+type Optional[T any] struct {
+	value T
+	state optionalState
+}
+
+type optionalState uint8
+
+const (
+	optionalAbsent optionalState = iota
+	optionalNull
+	optionalSet
+)
+
+// Some is an Optional set to value.
+func Some[T any](value T) Optional[T] {
+	return Optional[T]{value: value, state: optionalSet}
+}
+
+// Null is an Optional that's there, as null.
+func Null[T any]() Optional[T] {
+	return Optional[T]{state: optionalNull}
+}
+
+// Get returns the value, and whether there's one (the key wasn't missing or null).
+func (optional Optional[T]) Get() (T, bool) {
+	return optional.value, optional.state == optionalSet
+}
+
+func (optional Optional[T]) IsAbsent() bool {
+	return optional.state == optionalAbsent
+}
+
+func (optional Optional[T]) IsNull() bool {
+	return optional.state == optionalNull
+}
+
+func (optional Optional[T]) IsSet() bool {
+	return optional.state == optionalSet
+}
+
+func (optional Optional[T]) MarshalJSON() ([]byte, error) {
+	if optional.state != optionalSet {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(optional.value)
+}
+
+func (optional *Optional[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*optional = Null[T]()
+		return nil
+	}
+
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	*optional = Some(value)
+	return nil
+}
+
+// the methods of Optional that DecodeInto and EncodeTree use instead of the json ones, so the value
+// goes through their rules (hooks, key order) too
+type optionalDecoder interface {
+	decodeOptional(value interface{}, decode func(value interface{}, target reflect.Value) error) error
+}
+
+type optionalEncoder interface {
+	encodeOptional() (value reflect.Value, present bool)
+}
+
+func (optional *Optional[T]) decodeOptional(value interface{}, decode func(value interface{}, target reflect.Value) error) error {
+	if value == nil {
+		*optional = Null[T]()
+		return nil
+	}
+
+	var result T
+	if err := decode(value, reflect.ValueOf(&result).Elem()); err != nil {
+		return err
+	}
+
+	*optional = Some(result)
+	return nil
+}
+
+// the value to encode (invalid for null), and whether there's a key at all
+func (optional Optional[T]) encodeOptional() (reflect.Value, bool) {
+	if optional.state != optionalSet {
+		return reflect.Value{}, optional.state == optionalNull
+	}
+
+	return reflect.ValueOf(&optional.value).Elem(), true
+}
@@ -0,0 +1,178 @@
+package orderedjson
+
+// the entry points take their settings as options too, so a call only names what it changes and new
+// settings don't change any signature:
+//
+//	doc, err := ParseDocument(data, WithMaxDepth(64), WithComments())
+//	text, err := Marshal(doc.Tree, WithIndent("  "), WithComments(), WithMeta(doc.Meta))
+//
+// every option sets a field of ParseOptions or MarshalOptions (With applies them to one), so the
+// two ways of configuring can't drift apart. options that mean something both ways, like
+// WithComments, work as a DecodeOption and as an EncodeOption.
+
+// DecodeOption configures parsing, and DecodeInto.
+type DecodeOption interface {
+	applyDecode(config *decodeConfig)
+}
+
+// EncodeOption configures marshaling.
+type EncodeOption interface {
+	applyEncode(opts *MarshalOptions)
+}
+
+type decodeConfig struct {
+	parse ParseOptions
+	// whether any option was for parsing, which DecodeInto can't do anything with
+	parsing bool
+	// for DecodeInto
+	hooks []DecodeHook
+}
+
+type decodeOption func(config *decodeConfig)
+
+func (opt decodeOption) applyDecode(config *decodeConfig) {
+	opt(config)
+}
+
+// an option for parsing
+type parseOption func(opts *ParseOptions)
+
+func (opt parseOption) applyDecode(config *decodeConfig) {
+	opt(&config.parse)
+	config.parsing = true
+}
+
+type encodeOption func(opts *MarshalOptions)
+
+func (opt encodeOption) applyEncode(opts *MarshalOptions) {
+	opt(opts)
+}
+
+// an option for both ways
+type codecOption struct {
+	decode parseOption
+	encode encodeOption
+}
+
+func (opt codecOption) applyDecode(config *decodeConfig) {
+	opt.decode.applyDecode(config)
+}
+
+func (opt codecOption) applyEncode(opts *MarshalOptions) {
+	opt.encode(opts)
+}
+
+func newDecodeConfig(parse ParseOptions, options []DecodeOption) decodeConfig {
+	config := decodeConfig{parse: parse}
+	for _, opt := range options {
+		opt.applyDecode(&config)
+	}
+
+	return config
+}
+
+// With returns opts with options applied.
+func (opts ParseOptions) With(options ...DecodeOption) ParseOptions {
+	return newDecodeConfig(opts, options).parse
+}
+
+// With returns opts with options applied.
+func (opts MarshalOptions) With(options ...EncodeOption) MarshalOptions {
+	for _, opt := range options {
+		opt.applyEncode(&opts)
+	}
+
+	return opts
+}
+
+// WithParseOptions replaces every parse setting with opts, for code that already has a
+// ParseOptions. the options after it change it further.
+func WithParseOptions(opts ParseOptions) DecodeOption {
+	return parseOption(func(parse *ParseOptions) {
+		*parse = opts
+	})
+}
+
+// WithMaxDepth is ParseOptions.MaxDepth.
+func WithMaxDepth(depth int) DecodeOption {
+	return parseOption(func(opts *ParseOptions) {
+		opts.MaxDepth = depth
+	})
+}
+
+// WithNumberMode is ParseOptions.Numbers.
+func WithNumberMode(mode NumberMode) DecodeOption {
+	return parseOption(func(opts *ParseOptions) {
+		opts.Numbers = mode
+	})
+}
+
+// WithLenient is ParseOptions.Lenient, with warn (which can be nil) as ParseOptions.Warn.
+func WithLenient(warn func(err *SyntaxError)) DecodeOption {
+	return parseOption(func(opts *ParseOptions) {
+		opts.Lenient = true
+		opts.Warn = warn
+	})
+}
+
+// WithComments allows comments (and trailing commas) when parsing, which ParseDocument keeps in
+// Document.Meta like ParseJSONC, and writes them back when marshaling, like MarshalOptions.Comments.
+func WithComments() interface {
+	DecodeOption
+	EncodeOption
+} {
+	return codecOption{
+		decode: func(opts *ParseOptions) {
+			opts.Comments = true
+		},
+		encode: func(opts *MarshalOptions) {
+			opts.Comments = true
+		},
+	}
+}
+
+// WithIndent is MarshalOptions.Indent.
+func WithIndent(indent string) EncodeOption {
+	return encodeOption(func(opts *MarshalOptions) {
+		opts.Indent = indent
+	})
+}
+
+// WithCompact is MarshalOptions.Compact.
+func WithCompact() EncodeOption {
+	return encodeOption(func(opts *MarshalOptions) {
+		opts.Compact = true
+	})
+}
+
+// WithMaxWidth is MarshalOptions.MaxWidth.
+func WithMaxWidth(width int) EncodeOption {
+	return encodeOption(func(opts *MarshalOptions) {
+		opts.MaxWidth = width
+	})
+}
+
+// WithSortKeys is MarshalOptions.SortKeys.
+func WithSortKeys(order KeyOrder) EncodeOption {
+	return encodeOption(func(opts *MarshalOptions) {
+		opts.SortKeys = order
+	})
+}
+
+// WithMeta is MarshalOptions.Meta.
+func WithMeta(meta *Metadata) EncodeOption {
+	return encodeOption(func(opts *MarshalOptions) {
+		opts.Meta = meta
+	})
+}
+
+// Parse parses a document, which is an object at the top level. an array or a lone value at the top
+// is a syntax error, and Valid says false for it.
+func Parse(data []byte, options ...DecodeOption) (*JsonObject, error) {
+	return ParseOptions{}.With(options...).Parse(data)
+}
+
+// Marshal writes value as json, keeping the order of its keys.
+func Marshal(value interface{}, options ...EncodeOption) (string, error) {
+	return MarshalOptions{}.With(options...).Marshal(value)
+}
@@ -0,0 +1,275 @@
+package orderedjson
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// Patch returns the text of doc changed to hold tree instead of doc.Tree, by rewriting only the
+// values that changed, adding and removing just the keys and array items that come and go. every
+// other byte (whitespace, key order, how numbers are written) stays as it was, so the diff of the
+// file is no bigger than the change. objects whose keys were reordered are written out again whole,
+// in the indentation of the file. arrays go item by item, by index: items at the same index are
+// patched like any other value, and the array is cut or added to at the end, so an item removed from
+// the middle shows up as the items after it changing and the last one going.
+func (doc *Document) Patch(tree *JsonObject) ([]byte, error) {
+	patcher := &patcher{doc: doc, spans: make(map[string]valueSpan), duplicates: make(map[string]bool)}
+	for _, span := range doc.spans {
+		key := span.path.String()
+		if _, seen := patcher.spans[key]; seen {
+			patcher.duplicates[key] = true
+		}
+		patcher.spans[key] = span
+	}
+
+	patcher.indent = guessIndent(doc.Text)
+
+	if err := patcher.diff(Path{}, doc.Tree, tree); err != nil {
+		return nil, err
+	}
+
+	// insertions go before a removal that starts at the same place, and keep the order they were
+	// made in
+	sort.SliceStable(patcher.edits, func(i, j int) bool {
+		a, b := patcher.edits[i], patcher.edits[j]
+		if a.start != b.start {
+			return a.start < b.start
+		}
+
+		return a.end == a.start && b.end != b.start
+	})
+
+	var result bytes.Buffer
+	offset := 0
+	for _, edit := range patcher.edits {
+		result.Write(doc.Text[offset:edit.start])
+		result.WriteString(edit.text)
+		offset = edit.end
+	}
+	result.Write(doc.Text[offset:])
+
+	return result.Bytes(), nil
+}
+
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+type patcher struct {
+	doc   *Document
+	spans map[string]valueSpan
+	// paths that are in the text more than once, because of duplicate keys
+	duplicates map[string]bool
+	// what the document is indented with
+	indent string
+	edits  []textEdit
+}
+
+// the indentation of the first indented line, two spaces if there isn't one
+func guessIndent(text []byte) string {
+	for _, line := range bytes.Split(text, []byte("\n"))[1:] {
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) > 0 && len(trimmed) < len(line) {
+			return string(line[:len(line)-len(trimmed)])
+		}
+	}
+
+	return "  "
+}
+
+// the whitespace at the start of the line offset is on
+func (patcher *patcher) lineIndent(offset int) string {
+	start := bytes.LastIndexByte(patcher.doc.Text[:offset], '\n') + 1
+	line := patcher.doc.Text[start:offset]
+	return string(line[:len(line)-len(bytes.TrimLeft(line, " \t"))])
+}
+
+// whether the value at span is spread over lines
+func (patcher *patcher) multiline(span valueSpan) bool {
+	return bytes.ContainsRune(patcher.doc.Text[span.start:span.end], '\n')
+}
+
+// value written for a place on a line that starts with indent, inside a container that's on one
+// line or not
+func (patcher *patcher) format(value interface{}, indent string, multiline bool) (string, error) {
+	if !multiline {
+		return marshalValue(value)
+	}
+
+	text, err := MarshalOptions{Indent: patcher.indent}.Marshal(value)
+	return strings.ReplaceAll(text, "\n", "\n"+indent), err
+}
+
+func (patcher *patcher) replace(path Path, value interface{}) error {
+	span := patcher.spans[path.String()]
+	container := span
+	if len(path) > 0 {
+		container = patcher.spans[path[:len(path)-1].String()]
+	}
+
+	text, err := patcher.format(value, patcher.lineIndent(span.start), patcher.multiline(container))
+	if err != nil {
+		return err
+	}
+
+	patcher.edits = append(patcher.edits, textEdit{start: span.start, end: span.end, text: text})
+	return nil
+}
+
+func (patcher *patcher) diff(path Path, old, new interface{}) error {
+	span := patcher.spans[path.String()]
+	switch o := old.(type) {
+	case *JsonObject:
+		if n, ok := new.(*JsonObject); ok && n.Len() > 0 {
+			return patcher.diffObject(path, span, o, n)
+		}
+	case []interface{}:
+		// there are no items to put new ones next to in an empty array
+		if n, ok := new.([]interface{}); ok && len(o) > 0 && len(n) > 0 {
+			return patcher.diffArray(path, span, o, n)
+		}
+	default:
+		switch new.(type) {
+		case *JsonObject, []interface{}:
+		default:
+			if old == new {
+				return nil
+			}
+		}
+	}
+
+	return patcher.replace(path, new)
+}
+
+func (patcher *patcher) member(path Path, key string) (valueSpan, bool) {
+	child := append(append(Path{}, path...), key).String()
+	return patcher.spans[child], !patcher.duplicates[child]
+}
+
+func (patcher *patcher) diffObject(path Path, span valueSpan, old, new *JsonObject) error {
+	// the keys both have must still be in the same order, and there has to be one left to put the
+	// new keys next to
+	var kept []string
+	for pair := old.Oldest(); pair != nil; pair = pair.Next() {
+		if _, ok := new.Get(pair.Key); ok {
+			kept = append(kept, pair.Key)
+		}
+		if _, unique := patcher.member(path, pair.Key); !unique {
+			return patcher.replace(path, new)
+		}
+	}
+
+	i := 0
+	for pair := new.Oldest(); pair != nil && i < len(kept); pair = pair.Next() {
+		if pair.Key == kept[i] {
+			i++
+		}
+	}
+
+	if len(kept) == 0 || i < len(kept) {
+		return patcher.replace(path, new)
+	}
+
+	multiline := patcher.multiline(span)
+	first, _ := patcher.member(path, old.Oldest().Key)
+	indent := patcher.lineIndent(first.keyStart)
+	separator := ", "
+	if multiline {
+		separator = ",\n" + indent
+	}
+
+	// removed keys: the ones before the first kept key up to it, the others from the end of the
+	// value before them, which takes their comma along
+	firstKept, _ := patcher.member(path, kept[0])
+	if first.keyStart < firstKept.keyStart {
+		patcher.edits = append(patcher.edits, textEdit{start: first.keyStart, end: firstKept.keyStart})
+	}
+
+	previousEnd := -1
+	for pair := old.Oldest(); pair != nil; pair = pair.Next() {
+		member, _ := patcher.member(path, pair.Key)
+		if _, ok := new.Get(pair.Key); !ok {
+			if previousEnd >= 0 {
+				patcher.edits = append(patcher.edits, textEdit{start: previousEnd, end: member.end})
+			}
+		} else if err := patcher.diff(append(append(Path{}, path...), pair.Key), pair.Value, mustGet(new, pair.Key)); err != nil {
+			return err
+		}
+
+		if member.keyStart >= firstKept.keyStart {
+			previousEnd = member.end
+		}
+	}
+
+	// added keys go after the kept key before them, or in front of the first kept key
+	var anchor *valueSpan
+	for pair := new.Oldest(); pair != nil; pair = pair.Next() {
+		if _, ok := old.Get(pair.Key); ok {
+			member, _ := patcher.member(path, pair.Key)
+			anchor = &member
+			continue
+		}
+
+		key, err := marshalValue(pair.Key)
+		if err != nil {
+			return err
+		}
+
+		value, err := patcher.format(pair.Value, indent, multiline)
+		if err != nil {
+			return err
+		}
+
+		if anchor == nil {
+			patcher.edits = append(patcher.edits, textEdit{start: firstKept.keyStart, end: firstKept.keyStart, text: key + ": " + value + separator})
+		} else {
+			patcher.edits = append(patcher.edits, textEdit{start: anchor.end, end: anchor.end, text: separator + key + ": " + value})
+		}
+	}
+
+	return nil
+}
+
+func mustGet(object *JsonObject, key string) interface{} {
+	value, _ := object.Get(key)
+	return value
+}
+
+func (patcher *patcher) diffArray(path Path, span valueSpan, old, new []interface{}) error {
+	items := make([]valueSpan, len(old))
+	for i := range old {
+		items[i] = patcher.spans[append(append(Path{}, path...), i).String()]
+	}
+
+	for i := 0; i < min(len(old), len(new)); i++ {
+		if err := patcher.diff(append(append(Path{}, path...), i), old[i], new[i]); err != nil {
+			return err
+		}
+	}
+
+	last := items[len(old)-1]
+	if len(new) < len(old) {
+		// from the end of the last item that stays to the end of the array's last item
+		patcher.edits = append(patcher.edits, textEdit{start: items[len(new)-1].end, end: last.end})
+		return nil
+	}
+
+	multiline := patcher.multiline(span)
+	separator := ", "
+	if multiline {
+		separator = ",\n" + patcher.lineIndent(items[0].start)
+	}
+
+	for _, item := range new[len(old):] {
+		text, err := patcher.format(item, patcher.lineIndent(items[0].start), multiline)
+		if err != nil {
+			return err
+		}
+
+		patcher.edits = append(patcher.edits, textEdit{start: last.end, end: last.end, text: separator + text})
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package orderedjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PathDecoder converts the values at the paths matching Pattern while a document is parsed, so the
+// tree holds domain types (a time.Time, a decimal) instead of the strings and numbers they were
+// written as, without another pass over the tree afterwards.
+//
+// Pattern is a dot separated path from the top level object where * is any key or index, like
+// "amount" or "*.createdAt". numbers match indexes as well as keys. with more than one decoder for a
+// path, the first one wins.
+//
+// Decode gets the value after the values inside of it were decoded. numbers come as a json.Number,
+// so decimal types can have all their digits. what it returns goes in the tree instead, and errors
+// stop the parse.
+//
+// the rest of the package only knows the usual tree types: marshalling works for anything
+// encoding/json can marshal, but queries, diffs and the like treat other types as opaque.
+type PathDecoder struct {
+	Pattern string
+	Decode  func(value interface{}) (interface{}, error)
+}
+
+// DecodeTime is a Decode for strings in layout, e.g. time.RFC3339.
+func DecodeTime(layout string) func(value interface{}) (interface{}, error) {
+	return func(value interface{}) (interface{}, error) {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a time, got %s", TypeName(value))
+		}
+
+		return time.Parse(layout, s)
+	}
+}
+
+// DecodeDuration is a Decode for strings like "1m30s".
+func DecodeDuration(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a duration, got %s", TypeName(value))
+	}
+
+	return time.ParseDuration(s)
+}
+
+func (parser *BtreeJsonParser) tracksPath() bool {
+	return parser.recordSpans || len(parser.decoders) > 0
+}
+
+// runs the first decoder matching the current path on value, which started at token
+func (parser *BtreeJsonParser) decode(token Token, value interface{}) (interface{}, error) {
+	for _, decoder := range parser.decoders {
+		if !parser.path.Matches(decoder.Pattern) {
+			continue
+		}
+
+		if number, ok := value.(float64); ok {
+			// the digits as written, unless Lenient fixed them up
+			if token.TokenType == NumberLiteral && numberProblem(token.Lexeme) == "" {
+				value = json.Number(token.Lexeme)
+			} else {
+				value = json.Number(strconv.FormatFloat(number, 'g', -1, 64))
+			}
+		}
+
+		decoded, err := decoder.Decode(value)
+		if err != nil {
+			line, column := lineColumn(parser.data, token.Offset)
+			return nil, fmt.Errorf("%s at line %d, column %d: %w", parser.path, line, column, err)
+		}
+
+		return decoded, nil
+	}
+
+	return value, nil
+}
+
+// Matches reports whether path matches a dot separated pattern, where * is any key or index, like
+// the Pattern of a PathDecoder.
+func (path Path) Matches(pattern string) bool {
+	segments := strings.Split(pattern, ".")
+	if len(segments) != len(path) {
+		return false
+	}
+
+	for i, segment := range segments {
+		switch key := path[i].(type) {
+		case string:
+			if segment != "*" && segment != key {
+				return false
+			}
+		case int:
+			if segment != "*" && segment != strconv.Itoa(key) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,342 @@
+package orderedjson
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// .env and java .properties files, which are flat lists of string values. nested objects are
+// flattened into keys joined by a separator on the way out (see Flatten), and can be nested again
+// on the way in (see Unflatten). keys are in line order both ways.
+
+// FromDotenv reads a .env file: KEY=value lines (optionally starting with export), # comments, and
+// values that are unquoted (with a # comment after them), 'single quoted' (as written) or "double
+// quoted" (with \n, \t, \" and \\ escapes, and newlines). when sep isn't empty, keys are split on
+// it and nested. a key that's set twice keeps its first place with the last value.
+func FromDotenv(data []byte, sep string) (*JsonObject, error) {
+	result := orderedmap.New[string, interface{}]()
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		number := i + 1
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || !isEnvName(key) {
+			return nil, fmt.Errorf("line %d: expected KEY=value", number)
+		}
+
+		value = strings.TrimLeft(value, " \t")
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.IndexByte(value[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated string", number)
+			}
+
+			value = value[1 : end+1]
+		case strings.HasPrefix(value, `"`):
+			// the value goes on until the closing quote, on this line or a later one
+			text := value[1:]
+			for ; ; text += "\n" + lines[i] {
+				if end := closingQuote(text); end >= 0 {
+					text = text[:end]
+					break
+				}
+
+				if i++; i >= len(lines) {
+					return nil, fmt.Errorf("line %d: unterminated string", number)
+				}
+			}
+
+			value = unescapeDotenv(text)
+		default:
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				value = value[:comment]
+			}
+
+			value = strings.TrimSpace(value)
+		}
+
+		result.Set(key, value)
+	}
+
+	return unflattenIf(result, sep)
+}
+
+func isEnvName(key string) bool {
+	for i, char := range key {
+		if !(char == '_' || char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || i > 0 && char >= '0' && char <= '9' || i > 0 && char == '.') {
+			return false
+		}
+	}
+
+	return key != ""
+}
+
+// the index of the first " in text that isn't escaped, or -1
+func closingQuote(text string) int {
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+
+	return -1
+}
+
+func unescapeDotenv(text string) string {
+	var result strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i+1 == len(text) {
+			result.WriteByte(text[i])
+			continue
+		}
+
+		i++
+		switch text[i] {
+		case 'n':
+			result.WriteByte('\n')
+		case 'r':
+			result.WriteByte('\r')
+		case 't':
+			result.WriteByte('\t')
+		case '"', '\\', '$':
+			result.WriteByte(text[i])
+		default:
+			result.WriteByte('\\')
+			result.WriteByte(text[i])
+		}
+	}
+
+	return result.String()
+}
+
+func unflattenIf(flat *JsonObject, sep string) (*JsonObject, error) {
+	if sep == "" {
+		return flat, nil
+	}
+
+	return Unflatten(flat, sep)
+}
+
+// ToDotenv writes tree as a .env file, with nested keys joined by sep. values are quoted when
+// they'd be read back differently otherwise. null is an empty value, and empty objects and arrays
+// are written as json.
+func ToDotenv(tree *JsonObject, sep string) ([]byte, error) {
+	var result bytes.Buffer
+	for pair := Flatten(tree, sep).Oldest(); pair != nil; pair = pair.Next() {
+		if !isEnvName(pair.Key) {
+			return nil, fmt.Errorf("%q isn't a valid variable name", pair.Key)
+		}
+
+		value, err := flatText(pair.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pair.Key, err)
+		}
+
+		if value != strings.TrimSpace(value) || strings.ContainsAny(value, "\"'#\\\n\r\t$ ") {
+			replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "$", `\$`)
+			value = `"` + replacer.Replace(value) + `"`
+		}
+
+		fmt.Fprintf(&result, "%s=%s\n", pair.Key, value)
+	}
+
+	return result.Bytes(), nil
+}
+
+// the text of a leaf value from Flatten
+func flatText(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+
+	return MarshalCompact(value)
+}
+
+// FromProperties reads a java .properties file, the way java.util.Properties does: # and !
+// comments, keys separated from values by =, : or whitespace, backslash escapes (including
+// \uXXXX) and lines continued with a trailing backslash. when sep isn't empty, keys are split on it
+// and nested.
+func FromProperties(data []byte, sep string) (*JsonObject, error) {
+	result := orderedmap.New[string, interface{}]()
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64<<10), len(data)+1)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimLeft(strings.TrimSuffix(scanner.Text(), "\r"), " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		start := number
+		for continued(line) && scanner.Scan() {
+			number++
+			line = line[:len(line)-1] + strings.TrimLeft(strings.TrimSuffix(scanner.Text(), "\r"), " \t\f")
+		}
+
+		key, value, err := splitProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", start, err)
+		}
+
+		result.Set(key, value)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return unflattenIf(result, sep)
+}
+
+// whether line ends with an odd number of backslashes
+func continued(line string) bool {
+	count := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		count++
+	}
+
+	return count%2 == 1
+}
+
+func splitProperty(line string) (string, string, error) {
+	end := 0
+	for end < len(line) && !strings.ContainsRune("=: \t\f", rune(line[end])) {
+		if line[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	end = min(end, len(line))
+
+	key, err := unescapeProperty(line[:end])
+	if err != nil {
+		return "", "", err
+	}
+
+	// whitespace around the separator, and one = or : in it
+	rest := strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	value, err := unescapeProperty(rest)
+	return key, value, err
+}
+
+func unescapeProperty(text string) (string, error) {
+	var result strings.Builder
+	var surrogate rune
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' {
+			result.WriteByte(text[i])
+			continue
+		}
+
+		if i++; i == len(text) {
+			break
+		}
+
+		switch text[i] {
+		case 't':
+			result.WriteByte('\t')
+		case 'n':
+			result.WriteByte('\n')
+		case 'r':
+			result.WriteByte('\r')
+		case 'f':
+			result.WriteByte('\f')
+		case 'u':
+			if i+5 > len(text) {
+				return "", fmt.Errorf("invalid \\u escape")
+			}
+
+			code, err := strconv.ParseUint(text[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid \\u escape %q", text[i-1:i+5])
+			}
+			i += 4
+
+			// characters outside the BMP are written as two escapes
+			switch char := rune(code); {
+			case utf16.IsSurrogate(char) && surrogate == 0:
+				surrogate = char
+				continue
+			case surrogate != 0:
+				result.WriteRune(utf16.DecodeRune(surrogate, char))
+			default:
+				result.WriteRune(char)
+			}
+		default:
+			result.WriteByte(text[i])
+		}
+
+		surrogate = 0
+	}
+
+	return result.String(), nil
+}
+
+// ToProperties writes tree as a java .properties file, with nested keys joined by sep. characters
+// outside ASCII are written as \u escapes, since java reads .properties files as ISO-8859-1. null
+// is an empty value, and empty objects and arrays are written as json.
+func ToProperties(tree *JsonObject, sep string) ([]byte, error) {
+	var result bytes.Buffer
+	for pair := Flatten(tree, sep).Oldest(); pair != nil; pair = pair.Next() {
+		value, err := flatText(pair.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pair.Key, err)
+		}
+
+		fmt.Fprintf(&result, "%s=%s\n", escapeProperty(pair.Key, true), escapeProperty(value, false))
+	}
+
+	return result.Bytes(), nil
+}
+
+func escapeProperty(text string, key bool) string {
+	var result strings.Builder
+	for i, char := range text {
+		switch {
+		case char == '\\':
+			result.WriteString(`\\`)
+		case char == '\t':
+			result.WriteString(`\t`)
+		case char == '\n':
+			result.WriteString(`\n`)
+		case char == '\r':
+			result.WriteString(`\r`)
+		case char == '\f':
+			result.WriteString(`\f`)
+		case char == ' ' && (key || i == 0):
+			result.WriteString(`\ `)
+		case strings.ContainsRune("=:#!", char) && (key || i == 0):
+			result.WriteByte('\\')
+			result.WriteRune(char)
+		case char < 0x20 || char > 0x7e:
+			for _, unit := range utf16.Encode([]rune{char}) {
+				fmt.Fprintf(&result, `\u%04X`, unit)
+			}
+		default:
+			result.WriteRune(char)
+		}
+	}
+
+	return result.String()
+}
@@ -0,0 +1,179 @@
+package orderedjson
+
+import (
+	"testing"
+)
+
+func TestFromDotenv(t *testing.T) {
+	tests := []struct {
+		input string
+		sep   string
+		want  string
+	}{
+		{"B=1\nA=two words # comment\n", "", `{"B":"1","A":"two words"}`},
+		{"# comment\n\nexport KEY = value\r\nEMPTY=\nHASH=a#b\n", "", `{"KEY":"value","EMPTY":"","HASH":"a#b"}`},
+		{`S='as \n written # too'` + "\n" + `D="tab\tquote\" slash\\ dollar\$ other\q"`, "", `{"S":"as \\n written # too","D":"tab\tquote\" slash\\ dollar$ other\\q"}`},
+		{"M=\"first\nsecond\"\nN=1\n", "", `{"M":"first\nsecond","N":"1"}`},
+		{"U=ü水😀\n", "", `{"U":"ü水😀"}`},
+		{"A=1\nB=2\nA=3\n", "", `{"A":"3","B":"2"}`},
+		{"DB__HOST=x\nDB__PORT=5432\nNAME=n\n", "__", `{"DB":{"HOST":"x","PORT":"5432"},"NAME":"n"}`},
+		{"a.b=1\na.c=2\n", ".", `{"a":{"b":"1","c":"2"}}`},
+		{"", "", `{}`},
+	}
+
+	for _, test := range tests {
+		tree, err := FromDotenv([]byte(test.input), test.sep)
+		if err != nil {
+			t.Errorf("FromDotenv(%q): %v", test.input, err)
+			continue
+		}
+
+		if got, _ := MarshalCompact(tree); got != test.want {
+			t.Errorf("FromDotenv(%q) = %s, want %s", test.input, got, test.want)
+		}
+	}
+
+	for _, input := range []string{"novalue\n", "1A=x\n", "A B=x\n", "=x\n", "A='open\n", "A=\"open\nstill open\n", "A-B=1\n"} {
+		if tree, err := FromDotenv([]byte(input), ""); err == nil {
+			t.Errorf("FromDotenv(%q) = %v, want an error", input, tree)
+		}
+	}
+}
+
+func TestToDotenv(t *testing.T) {
+	tests := []struct {
+		input string
+		sep   string
+		want  string
+	}{
+		{`{"B":"1","A":"plain"}`, "", "B=1\nA=plain\n"},
+		{`{"S":"two words","Q":"a\"b","H":"#x","N":"l1\nl2","D":"$HOME","T":" pad ","E":"","U":"ü水"}`, "", "S=\"two words\"\nQ=\"a\\\"b\"\nH=\"#x\"\nN=\"l1\\nl2\"\nD=\"\\$HOME\"\nT=\" pad \"\nE=\nU=ü水\n"},
+		{`{"n":1.5,"b":true,"z":null,"e":{},"l":[]}`, "", "n=1.5\nb=true\nz=\ne={}\nl=[]\n"},
+		{`{"db":{"host":"x","port":5432},"list":["a","b"]}`, "_", "db_host=x\ndb_port=5432\nlist_0=a\nlist_1=b\n"},
+	}
+
+	for _, test := range tests {
+		tree, err := ParseOptions{}.Parse([]byte(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got, err := ToDotenv(tree, test.sep); err != nil || string(got) != test.want {
+			t.Errorf("ToDotenv(%s) = %q, %v, want %q", test.input, got, err, test.want)
+		}
+	}
+
+	for _, input := range []string{`{"a b":"1"}`, `{"1a":"1"}`, `{"a":{"b-c":1}}`} {
+		tree, _ := ParseOptions{}.Parse([]byte(input))
+		if got, err := ToDotenv(tree, "_"); err == nil {
+			t.Errorf("ToDotenv(%s) = %q, want an error", input, got)
+		}
+	}
+}
+
+func TestFromProperties(t *testing.T) {
+	tests := []struct {
+		input string
+		sep   string
+		want  string
+	}{
+		{"b=1\na = two words \n", "", `{"b":"1","a":"two words "}`},
+		{"# comment\n! also a comment\n\n  key:value\nspaced   value\ntab\tvalue\nempty\n", "", `{"key":"value","spaced":"value","tab":"value","empty":""}`},
+		{`key\ with\=odd\:chars = \=value:with=seps`, "", `{"key with=odd:chars":"=value:with=seps"}`},
+		{`esc=\t\n\r\f\\\q`, "", `{"esc":"\t\n\r\f\\q"}`},
+		{`u=\u00FC\u6C34\uD83D\uDE00 é`, "", `{"u":"ü水😀 é"}`},
+		{"long=first, \\\n    second, \\\r\n    third\nnext=1\n", "", `{"long":"first, second, third","next":"1"}`},
+		{"even=ends with a slash\\\\\nnext=1\n", "", `{"even":"ends with a slash\\","next":"1"}`},
+		{"last=continued at the end\\", "", `{"last":"continued at the end"}`},
+		{"a=1\nb=2\na=3\n", "", `{"a":"3","b":"2"}`},
+		{"app.db.host=x\napp.db.port=5432\napp.name=n\n", ".", `{"app":{"db":{"host":"x","port":"5432"},"name":"n"}}`},
+	}
+
+	for _, test := range tests {
+		tree, err := FromProperties([]byte(test.input), test.sep)
+		if err != nil {
+			t.Errorf("FromProperties(%q): %v", test.input, err)
+			continue
+		}
+
+		if got, _ := MarshalCompact(tree); got != test.want {
+			t.Errorf("FromProperties(%q) = %s, want %s", test.input, got, test.want)
+		}
+	}
+
+	for _, input := range []string{`a=\u12`, `a=\uZZZZ`, `\u00=1`} {
+		if tree, err := FromProperties([]byte(input), ""); err == nil {
+			t.Errorf("FromProperties(%q) = %v, want an error", input, tree)
+		}
+	}
+}
+
+func TestToProperties(t *testing.T) {
+	tests := []struct {
+		input string
+		sep   string
+		want  string
+	}{
+		{`{"b":"1","a":"two words"}`, "", "b=1\na=two words\n"},
+		{`{"key with=odd:chars#!":"=value:with=seps # !"}`, "", "key\\ with\\=odd\\:chars\\#\\!=\\=value:with=seps # !\n"},
+		{`{" lead":" lead","#":"!x"}`, "", "\\ lead=\\ lead\n\\#=\\!x\n"},
+		{`{"esc":"\t\n\r\f\\"}`, "", "esc=\\t\\n\\r\\f\\\\\n"},
+		{`{"ü":"ü水😀","ctl":"\u0001\u007f"}`, "", "\\u00FC=\\u00FC\\u6C34\\uD83D\\uDE00\nctl=\\u0001\\u007F\n"},
+		{`{"n":1.5,"b":false,"z":null,"e":{}}`, "", "n=1.5\nb=false\nz=\ne={}\n"},
+		{`{"app":{"db":{"host":"x"},"list":["a"]}}`, ".", "app.db.host=x\napp.list.0=a\n"},
+	}
+
+	for _, test := range tests {
+		tree, err := ParseOptions{}.Parse([]byte(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got, err := ToProperties(tree, test.sep); err != nil || string(got) != test.want {
+			t.Errorf("ToProperties(%s) = %q, %v, want %q", test.input, got, err, test.want)
+		}
+	}
+}
+
+func TestFlatRoundTrip(t *testing.T) {
+	for _, input := range []string{
+		`{"A":"1","B":"two words","C":"q\"uote 'single' #hash $dollar \\slash","D":"l1\nl2\tt","E":"","F":" pad ","G":"ü水😀"}`,
+		`{"DB":{"HOST":"x","PORT":"5432"},"NAME":"n"}`,
+	} {
+		tree, err := ParseOptions{}.Parse([]byte(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dotenv, err := ToDotenv(tree, "__")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if back, err := FromDotenv(dotenv, "__"); err != nil {
+			t.Errorf("FromDotenv(%q): %v", dotenv, err)
+		} else if got, _ := MarshalCompact(back); got != input {
+			t.Errorf("FromDotenv(ToDotenv(%s)) = %s, from %q", input, got, dotenv)
+		}
+
+		properties, err := ToProperties(tree, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if back, err := FromProperties(properties, "."); err != nil {
+			t.Errorf("FromProperties(%q): %v", properties, err)
+		} else if got, _ := MarshalCompact(back); got != input {
+			t.Errorf("FromProperties(ToProperties(%s)) = %s, from %q", input, got, properties)
+		}
+	}
+
+	// properties keys can be anything
+	tree, _ := ParseOptions{}.Parse([]byte(`{"a key=with:odd#chars!":"=:#! \\","ü":"\r\f","":"empty key"}`))
+	properties, _ := ToProperties(tree, "")
+	if back, err := FromProperties(properties, ""); err != nil {
+		t.Errorf("FromProperties(%q): %v", properties, err)
+	} else if got, _ := MarshalCompact(back); got != `{"a key=with:odd#chars!":"=:#! \\","ü":"\r\f","":"empty key"}` {
+		t.Errorf("FromProperties(ToProperties) = %s, from %q", got, properties)
+	}
+}
@@ -0,0 +1,354 @@
+// Package protostruct converts between ordered json trees and the protobuf wire format of
+// google.protobuf.Struct, Value and ListValue, without depending on the protobuf module. to get a
+// *structpb.Struct, proto.Unmarshal the bytes EncodeStruct returns into one. to go the other way,
+// proto.Marshal it and pass the bytes to DecodeStruct.
+//
+// protobuf maps have no order, so EncodeStruct also writes the keys in order in a field that Struct
+// doesn't define (OrderField). protobuf implementations keep unknown fields when they marshal a
+// message again, so the order survives a trip through a service that passes the payload along.
+// DecodeStruct puts the keys in that order, and keys it doesn't list (set by someone that doesn't
+// know about it) after them, in the order they're on the wire.
+package protostruct
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type JsonObject = orderedmap.OrderedMap[string, interface{}]
+
+// OrderField is the field number of a Struct that EncodeStruct writes the key order in
+const OrderField = 2047
+
+// field numbers of struct.proto
+const (
+	structFields = 1
+
+	entryKey   = 1
+	entryValue = 2
+
+	valueNull   = 1
+	valueNumber = 2
+	valueString = 3
+	valueBool   = 4
+	valueStruct = 5
+	valueList   = 6
+
+	listValues = 1
+)
+
+// wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// EncodeStruct encodes object as a google.protobuf.Struct.
+func EncodeStruct(object *JsonObject) ([]byte, error) {
+	return appendStruct(make([]byte, 0, 64), object)
+}
+
+// EncodeValue encodes any value from the tree (*JsonObject, []interface{}, string, float64,
+// json.Number, bool or nil) as a google.protobuf.Value. Value's only number type is a double, so
+// integers past 2^53 lose digits on the way.
+func EncodeValue(value interface{}) ([]byte, error) {
+	return appendValue(make([]byte, 0, 16), value)
+}
+
+func appendTag(buf []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(buf, uint64(field<<3|wireType))
+}
+
+func appendBytes(buf []byte, field int, data []byte) []byte {
+	buf = appendTag(buf, field, wireBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+func appendStruct(buf []byte, object *JsonObject) ([]byte, error) {
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		value, err := EncodeValue(pair.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := appendBytes(nil, entryKey, []byte(pair.Key))
+		entry = appendBytes(entry, entryValue, value)
+		buf = appendBytes(buf, structFields, entry)
+	}
+
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		buf = appendBytes(buf, OrderField, []byte(pair.Key))
+	}
+
+	return buf, nil
+}
+
+func appendValue(buf []byte, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return append(appendTag(buf, valueNull, wireVarint), 0), nil
+	case bool:
+		buf = appendTag(buf, valueBool, wireVarint)
+		if v {
+			return append(buf, 1), nil
+		}
+
+		return append(buf, 0), nil
+	case float64:
+		buf = appendTag(buf, valueNumber, wireFixed64)
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v)), nil
+	case json.Number:
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return nil, fmt.Errorf("protostruct: %q isn't a number", string(v))
+		}
+
+		return appendValue(buf, f)
+	case string:
+		return appendBytes(buf, valueString, []byte(v)), nil
+	case []interface{}:
+		var list []byte
+		for _, item := range v {
+			encoded, err := EncodeValue(item)
+			if err != nil {
+				return nil, err
+			}
+
+			list = appendBytes(list, listValues, encoded)
+		}
+
+		return appendBytes(buf, valueList, list), nil
+	case *JsonObject:
+		encoded, err := EncodeStruct(v)
+		if err != nil {
+			return nil, err
+		}
+
+		return appendBytes(buf, valueStruct, encoded), nil
+	}
+
+	return nil, fmt.Errorf("protostruct: cannot encode %T", value)
+}
+
+var valueWireTypes = map[int]int{
+	valueNull:   wireVarint,
+	valueNumber: wireFixed64,
+	valueString: wireBytes,
+	valueBool:   wireVarint,
+	valueStruct: wireBytes,
+	valueList:   wireBytes,
+}
+
+// DecodeStruct decodes a google.protobuf.Struct.
+func DecodeStruct(data []byte) (*JsonObject, error) {
+	decoder := &decoder{}
+	return decoder.decodeStruct(data)
+}
+
+// DecodeValue decodes a google.protobuf.Value. a Value without a kind set decodes as null.
+func DecodeValue(data []byte) (interface{}, error) {
+	decoder := &decoder{}
+	return decoder.decodeValue(data)
+}
+
+type decoder struct {
+	depth int
+}
+
+// one field of a message
+type field struct {
+	number   int
+	wireType int
+	// the value of varint and fixed fields
+	n uint64
+	// the contents of length delimited fields
+	data []byte
+}
+
+// calls fn with every field in data, in order
+func (decoder *decoder) fields(data []byte, fn func(field) error) error {
+	for idx := 0; idx < len(data); {
+		tag, size := binary.Uvarint(data[idx:])
+		if size <= 0 {
+			return fmt.Errorf("protostruct: invalid tag at offset %d", idx)
+		}
+		idx += size
+
+		f := field{number: int(tag >> 3), wireType: int(tag & 7)}
+		switch f.wireType {
+		case wireVarint:
+			if f.n, size = binary.Uvarint(data[idx:]); size <= 0 {
+				return fmt.Errorf("protostruct: invalid varint at offset %d", idx)
+			}
+			idx += size
+		case wireFixed64, wireFixed32:
+			width := 8
+			if f.wireType == wireFixed32 {
+				width = 4
+			}
+
+			if len(data)-idx < width {
+				return fmt.Errorf("protostruct: unexpected end of data at offset %d", idx)
+			}
+
+			if width == 8 {
+				f.n = binary.LittleEndian.Uint64(data[idx:])
+			} else {
+				f.n = uint64(binary.LittleEndian.Uint32(data[idx:]))
+			}
+			idx += width
+		case wireBytes:
+			length, size := binary.Uvarint(data[idx:])
+			if size <= 0 || length > uint64(len(data)-idx-size) {
+				return fmt.Errorf("protostruct: invalid length at offset %d", idx)
+			}
+			idx += size
+
+			f.data = data[idx : idx+int(length)]
+			idx += int(length)
+		default:
+			return fmt.Errorf("protostruct: unsupported wire type %d at offset %d", f.wireType, idx)
+		}
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (decoder *decoder) nested() error {
+	decoder.depth++
+	if decoder.depth > 10000 {
+		return fmt.Errorf("protostruct: nesting too deep")
+	}
+
+	return nil
+}
+
+func (decoder *decoder) decodeStruct(data []byte) (*JsonObject, error) {
+	if err := decoder.nested(); err != nil {
+		return nil, err
+	}
+	defer func() { decoder.depth-- }()
+
+	entries := orderedmap.New[string, interface{}]()
+	var order []string
+	err := decoder.fields(data, func(f field) error {
+		switch {
+		case f.number == OrderField && f.wireType == wireBytes:
+			order = append(order, string(f.data))
+		case f.number == structFields && f.wireType == wireBytes:
+			var key string
+			var value interface{}
+			err := decoder.fields(f.data, func(f field) error {
+				var err error
+				switch {
+				case f.number == entryKey && f.wireType == wireBytes:
+					key, err = decodeString(f.data)
+				case f.number == entryValue && f.wireType == wireBytes:
+					value, err = decoder.decodeValue(f.data)
+				}
+
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			entries.Set(key, value)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(order) == 0 {
+		return entries, nil
+	}
+
+	result := orderedmap.New[string, interface{}](orderedmap.WithCapacity[string, interface{}](entries.Len()))
+	for _, key := range order {
+		if value, ok := entries.Get(key); ok {
+			result.Set(key, value)
+		}
+	}
+
+	for pair := entries.Oldest(); pair != nil; pair = pair.Next() {
+		if _, ok := result.Get(pair.Key); !ok {
+			result.Set(pair.Key, pair.Value)
+		}
+	}
+
+	return result, nil
+}
+
+func (decoder *decoder) decodeValue(data []byte) (interface{}, error) {
+	if err := decoder.nested(); err != nil {
+		return nil, err
+	}
+	defer func() { decoder.depth-- }()
+
+	var result interface{}
+	err := decoder.fields(data, func(f field) error {
+		if wireType, known := valueWireTypes[f.number]; !known {
+			return nil
+		} else if wireType != f.wireType {
+			return fmt.Errorf("protostruct: Value field %d has wire type %d, expected %d", f.number, f.wireType, wireType)
+		}
+
+		var err error
+		switch f.number {
+		case valueNull:
+			result = nil
+		case valueNumber:
+			result = math.Float64frombits(f.n)
+		case valueString:
+			result, err = decodeString(f.data)
+		case valueBool:
+			result = f.n != 0
+		case valueStruct:
+			result, err = decoder.decodeStruct(f.data)
+		case valueList:
+			list := []interface{}{}
+			err = decoder.fields(f.data, func(f field) error {
+				if f.number != listValues || f.wireType != wireBytes {
+					return nil
+				}
+
+				item, err := decoder.decodeValue(f.data)
+				list = append(list, item)
+				return err
+			})
+			result = list
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// protobuf strings are utf-8
+func decodeString(data []byte) (string, error) {
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("protostruct: string is not valid utf-8")
+	}
+
+	return string(data), nil
+}
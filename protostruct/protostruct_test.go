@@ -0,0 +1,262 @@
+package protostruct
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/michaelhelvey/orderedjson/v2/internal/testtree"
+)
+
+// structs, lists and nulls nested in each other come back as they were, from both EncodeStruct and
+// EncodeValue
+func TestRoundTrip(t *testing.T) {
+	for _, input := range []string{
+		`{"a":[null,[],{}],"b":{"c":[[null]],"":{}}}`,
+		`{"list":[{"z":null,"a":[]},[[],[{}]]],"null":null}`,
+		`{"empty":""}`,
+	} {
+		data, err := EncodeStruct(testtree.Object(t, input))
+		if err != nil {
+			t.Errorf("EncodeStruct(%s): %v", input, err)
+			continue
+		}
+
+		if decoded, err := DecodeStruct(data); err != nil || testtree.Marshal(t, decoded) != input {
+			t.Errorf("DecodeStruct(EncodeStruct(%s)) = %v, %v", input, decoded, err)
+		}
+
+		value, err := EncodeValue(testtree.Parse(t, input))
+		if err != nil {
+			t.Errorf("EncodeValue(%s): %v", input, err)
+			continue
+		}
+
+		if decoded, err := DecodeValue(value); err != nil || testtree.Marshal(t, decoded) != input {
+			t.Errorf("DecodeValue(EncodeValue(%s)) = %v, %v", input, decoded, err)
+		}
+	}
+
+	// what other encoders can write for the same values
+	tests := []struct {
+		proto string
+		json  string
+	}{
+		// NullValue has one value, anything else in the enum is still null
+		{"0801", `null`},
+		{"3200", `[]`},
+		{"32020a00", `[null]`},
+		{"3206" + "0a020800" + "0a00", `[null,null]`},
+		{"2a00", `{}`},
+		{"2a09" + "0a070a0161120208" + "00", `{"a":null}`},
+		// fields ListValue doesn't have are skipped
+		{"3206" + "0a022001" + "1001", `[true]`},
+	}
+
+	for _, test := range tests {
+		raw, _ := hex.DecodeString(test.proto)
+		if decoded, err := DecodeValue(raw); err != nil || testtree.Marshal(t, decoded) != test.json {
+			t.Errorf("DecodeValue(%s) = %v, %v, want %s", test.proto, decoded, err, test.json)
+		}
+	}
+}
+
+// Value only has doubles, so numbers come back as the nearest one
+func TestNumberPrecision(t *testing.T) {
+	tests := []struct {
+		number json.Number
+		want   string
+	}{
+		{"9007199254740992", `9007199254740992`},
+		{"9007199254740993", `9007199254740992`},
+		{"12345678901234567891", `12345678901234567000`},
+		{"-9223372036854775807", `-9223372036854776000`},
+		{"0.1", `0.1`},
+		{"1.00000000000000001", `1`},
+		{"1.50", `1.5`},
+		{"1e300", `1e+300`},
+	}
+
+	for _, test := range tests {
+		tree := testtree.Object(t, `{}`)
+		tree.Set("n", test.number)
+		data, err := EncodeStruct(tree)
+		if err != nil {
+			t.Errorf("EncodeStruct(%s): %v", test.number, err)
+			continue
+		}
+
+		decoded, err := DecodeStruct(data)
+		if err != nil {
+			t.Errorf("DecodeStruct(EncodeStruct(%s)): %v", test.number, err)
+			continue
+		}
+
+		if got, ok := decoded.Value("n").(float64); !ok || testtree.Marshal(t, got) != test.want {
+			t.Errorf("DecodeStruct(EncodeStruct(%s)) = %#v, want %s", test.number, decoded.Value("n"), test.want)
+		}
+	}
+}
+
+// Values encoded the way the protobuf encoding spec lays out struct.proto
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		json  string
+		proto string
+	}{
+		{`null`, "0800"},
+		{`1.5`, "11000000000000f83f"},
+		{`0`, "110000000000000000"},
+		{`""`, "1a00"},
+		{`"a"`, "1a0161"},
+		{`true`, "2001"},
+		{`false`, "2000"},
+		{`{}`, "2a00"},
+		{`[]`, "3200"},
+		{`[1]`, "320b0a0911000000000000f03f"},
+		{`{"a":true}`, "2a0d" + "0a070a01611202" + "2001" + "fa7f0161"},
+		{`{"b":null,"a":"x"}`, "2a1b" + "0a070a0162120208" + "00" + "0a080a016112031a0178" + "fa7f0162" + "fa7f0161"},
+	}
+
+	for _, test := range tests {
+		data, err := EncodeValue(testtree.Parse(t, test.json))
+		if err != nil {
+			t.Errorf("EncodeValue(%s): %v", test.json, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(data); got != test.proto {
+			t.Errorf("EncodeValue(%s) = %s, want %s", test.json, got, test.proto)
+		}
+
+		raw, _ := hex.DecodeString(test.proto)
+		decoded, err := DecodeValue(raw)
+		if err != nil {
+			t.Errorf("DecodeValue(%s): %v", test.proto, err)
+			continue
+		}
+
+		if got := testtree.Marshal(t, decoded); got != test.json {
+			t.Errorf("DecodeValue(%s) = %s, want %s", test.proto, got, test.json)
+		}
+	}
+}
+
+// what DecodeStruct makes of payloads from encoders that don't know about OrderField
+func TestDecodeOrder(t *testing.T) {
+	b := "0a070a0162120220" + "01"
+	a := "0a070a0161120220" + "00"
+	tests := []struct {
+		proto string
+		json  string
+	}{
+		{b + a, `{"b":true,"a":false}`},
+		{a + b, `{"a":false,"b":true}`},
+		{b + a + "fa7f0161" + "fa7f0162", `{"a":false,"b":true}`},
+		{b + a + "fa7f0161", `{"a":false,"b":true}`},
+		{b + a + "fa7f0163" + "fa7f0161", `{"a":false,"b":true}`},
+		{a + a, `{"a":false}`},
+		{"", `{}`},
+		{a + "7d01020304" + "8001" + "01", `{"a":false}`},
+		{"0a0412020800", `{"":null}`},
+		{"0a030a0161", `{"a":null}`},
+	}
+
+	for _, test := range tests {
+		raw, _ := hex.DecodeString(test.proto)
+		decoded, err := DecodeStruct(raw)
+		if err != nil {
+			t.Errorf("DecodeStruct(%s): %v", test.proto, err)
+			continue
+		}
+
+		if got := testtree.Marshal(t, decoded); got != test.json {
+			t.Errorf("DecodeStruct(%s) = %s, want %s", test.proto, got, test.json)
+		}
+	}
+
+	// a Value without a kind is null, and the last kind set wins like it does for a oneof
+	for proto, want := range map[string]string{"": `null`, "2001" + "1a0161": `"a"`, "980101" + "2001": `true`} {
+		raw, _ := hex.DecodeString(proto)
+		if decoded, err := DecodeValue(raw); err != nil || testtree.Marshal(t, decoded) != want {
+			t.Errorf("DecodeValue(%s) = %v, %v, want %s", proto, decoded, err, want)
+		}
+	}
+}
+
+// Value has only doubles, so json.Numbers are written as one and integers past 2^53 round
+func TestNumbers(t *testing.T) {
+	tests := []struct {
+		number json.Number
+		proto  string
+	}{
+		{"1.5", "11000000000000f83f"},
+		{"9007199254740993", "110000000000004043"},
+		{"-0", "110000000000000080"},
+	}
+
+	for _, test := range tests {
+		data, err := EncodeValue(test.number)
+		if err != nil {
+			t.Errorf("EncodeValue(%s): %v", test.number, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(data); got != test.proto {
+			t.Errorf("EncodeValue(%s) = %s, want %s", test.number, got, test.proto)
+		}
+	}
+}
+
+func TestSpecialFloats(t *testing.T) {
+	for _, value := range []float64{math.Inf(1), math.Inf(-1), math.NaN(), math.Copysign(0, -1)} {
+		data, err := EncodeValue(value)
+		if err != nil {
+			t.Errorf("EncodeValue(%v): %v", value, err)
+			continue
+		}
+
+		decoded, err := DecodeValue(data)
+		f, ok := decoded.(float64)
+		if err != nil || !ok || !(f == value || math.IsNaN(f) && math.IsNaN(value)) || math.Signbit(f) != math.Signbit(value) {
+			t.Errorf("DecodeValue(EncodeValue(%v)) = %v, %v", value, decoded, err)
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, input := range []string{
+		"0a",
+		"0a05",
+		"0a0712",
+		"0aff",
+		"0b",
+		"0affffffffffffffffffff01",
+		"0a021202",
+		"0a041202" + "1100",
+		"0a04120211" + "00",
+		"0a05120308" + "ff",
+		"0a0412021101",
+		"0a05120322" + "0161",
+		"0a0412021aff",
+		"0a0512031a01ff",
+		"0a0612042a020a" + "ff",
+		"0a06120432020a01",
+	} {
+		raw, _ := hex.DecodeString(input)
+		if value, err := DecodeStruct(raw); err == nil {
+			t.Errorf("DecodeStruct(%s) = %s, want an error", input, testtree.Marshal(t, value))
+		}
+	}
+
+	if value, err := DecodeStruct([]byte("\x0a\x04\x0a\x02\xc3\x28")); err == nil {
+		t.Errorf("DecodeStruct(invalid utf-8 key) = %v, want an error", value)
+	}
+
+	for _, value := range []interface{}{json.Number("1x"), json.Number("1e400"), 1, map[string]interface{}{}, []interface{}{1}} {
+		if data, err := EncodeValue(value); err == nil {
+			t.Errorf("EncodeValue(%#v) = %x, want an error", value, data)
+		}
+	}
+}
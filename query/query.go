@@ -0,0 +1,1053 @@
+// Package query runs jq-like expressions on ordered json trees, and rewrite scripts (set, del and
+// rename) that change them. it's what the query, rewrite and set commands use.
+package query
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// a (very) small subset of jq. supported:
+//
+//	.  .foo  .foo.bar  .["foo"]  .[0]  .[-1]  .[1:3]  .[]  a | b  a, b
+//	== != < <= > >= and or  literals  (parens)
+//	{a: .x, "b": .y, c, (.k): .v}
+//	select(f) map(f) keys length not type has(k) values
+//
+// the one real difference from jq is that `keys` returns keys in document order instead of sorting
+// them, since keeping the order is the whole point of this thing. object construction also keeps
+// the order the keys were written in.
+
+type queryNode interface {
+	eval(input interface{}) ([]interface{}, error)
+}
+
+// Query evaluates a jq-like expression against tree and returns every value it produces.
+func Query(tree interface{}, expr string) ([]interface{}, error) {
+	node, err := compileQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return node.eval(tree)
+}
+
+func compileQuery(expr string) (queryNode, error) {
+	lexer := &queryLexer{runes: []rune(expr)}
+	tokens, err := lexer.tokenize()
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &queryParser{tokens: tokens}
+	node, err := parser.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := parser.peek(); tok.kind != queryEOF {
+		return nil, fmt.Errorf("query: unexpected %q at %d", tok.text, tok.pos)
+	}
+
+	return node, nil
+}
+
+// lexing:
+
+type queryTokenKind int
+
+const (
+	queryEOF queryTokenKind = iota
+	queryDot
+	queryIdent
+	queryString
+	queryNumber
+	queryPunct
+)
+
+type queryToken struct {
+	kind queryTokenKind
+	text string
+	pos  int
+}
+
+type queryLexer struct {
+	runes []rune
+	idx   int
+}
+
+func (lexer *queryLexer) tokenize() ([]queryToken, error) {
+	tokens := make([]queryToken, 0)
+
+	for lexer.idx < len(lexer.runes) {
+		start := lexer.idx
+		char := lexer.runes[lexer.idx]
+
+		switch {
+		case unicode.IsSpace(char):
+			lexer.idx++
+		case char == '.':
+			lexer.idx++
+			tokens = append(tokens, queryToken{kind: queryDot, text: ".", pos: start})
+		case char == '"':
+			value, err := lexer.readString()
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, queryToken{kind: queryString, text: value, pos: start})
+		case unicode.IsDigit(char) || char == '-' && lexer.idx+1 < len(lexer.runes) && unicode.IsDigit(lexer.runes[lexer.idx+1]):
+			// there's no subtraction, so a - is always a sign
+			lexer.idx++
+			for lexer.idx < len(lexer.runes) && (unicode.IsDigit(lexer.runes[lexer.idx]) || lexer.runes[lexer.idx] == '.') {
+				lexer.idx++
+			}
+			tokens = append(tokens, queryToken{kind: queryNumber, text: string(lexer.runes[start:lexer.idx]), pos: start})
+		case unicode.IsLetter(char) || char == '_':
+			for lexer.idx < len(lexer.runes) && isQueryIdentRune(lexer.runes[lexer.idx]) {
+				lexer.idx++
+			}
+			tokens = append(tokens, queryToken{kind: queryIdent, text: string(lexer.runes[start:lexer.idx]), pos: start})
+		default:
+			text := string(char)
+			if lexer.idx+1 < len(lexer.runes) {
+				two := string(lexer.runes[lexer.idx : lexer.idx+2])
+				if two == "==" || two == "!=" || two == "<=" || two == ">=" {
+					text = two
+				}
+			}
+
+			switch text {
+			case "[", "]", "(", ")", "{", "}", "|", ",", ":", "<", ">", "==", "!=", "<=", ">=":
+			default:
+				return nil, fmt.Errorf("query: unexpected character %q at %d", char, start)
+			}
+
+			lexer.idx += len([]rune(text))
+			tokens = append(tokens, queryToken{kind: queryPunct, text: text, pos: start})
+		}
+	}
+
+	tokens = append(tokens, queryToken{kind: queryEOF, pos: len(lexer.runes)})
+	return tokens, nil
+}
+
+func isQueryIdentRune(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsDigit(char) || char == '_'
+}
+
+func (lexer *queryLexer) readString() (string, error) {
+	start := lexer.idx
+	lexer.idx++
+
+	var value strings.Builder
+	for lexer.idx < len(lexer.runes) {
+		char := lexer.runes[lexer.idx]
+		lexer.idx++
+
+		if char == '"' {
+			return value.String(), nil
+		}
+
+		if char == '\\' && lexer.idx < len(lexer.runes) {
+			char = lexer.runes[lexer.idx]
+			lexer.idx++
+		}
+
+		value.WriteRune(char)
+	}
+
+	return "", fmt.Errorf("query: unterminated string at %d", start)
+}
+
+// parsing:
+
+type queryParser struct {
+	tokens []queryToken
+	idx    int
+}
+
+func (parser *queryParser) peek() queryToken {
+	return parser.tokens[parser.idx]
+}
+
+func (parser *queryParser) next() queryToken {
+	token := parser.tokens[parser.idx]
+	if token.kind != queryEOF {
+		parser.idx++
+	}
+
+	return token
+}
+
+func (parser *queryParser) isPunct(text string) bool {
+	token := parser.peek()
+	return token.kind == queryPunct && token.text == text
+}
+
+func (parser *queryParser) isIdent(text string) bool {
+	token := parser.peek()
+	return token.kind == queryIdent && token.text == text
+}
+
+func (parser *queryParser) expect(text string) error {
+	if !parser.isPunct(text) {
+		token := parser.peek()
+		return fmt.Errorf("query: expected %q at %d, got %q", text, token.pos, token.text)
+	}
+
+	parser.next()
+	return nil
+}
+
+func (parser *queryParser) parsePipe() (queryNode, error) {
+	lhs, err := parser.parseComma()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.isPunct("|") {
+		parser.next()
+		rhs, err := parser.parseComma()
+		if err != nil {
+			return nil, err
+		}
+
+		lhs = &pipeNode{lhs: lhs, rhs: rhs}
+	}
+
+	return lhs, nil
+}
+
+func (parser *queryParser) parseComma() (queryNode, error) {
+	lhs, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.isPunct(",") {
+		parser.next()
+		rhs, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		lhs = &commaNode{lhs: lhs, rhs: rhs}
+	}
+
+	return lhs, nil
+}
+
+func (parser *queryParser) parseOr() (queryNode, error) {
+	lhs, err := parser.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.isIdent("or") {
+		parser.next()
+		rhs, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		lhs = &binaryNode{op: "or", lhs: lhs, rhs: rhs}
+	}
+
+	return lhs, nil
+}
+
+func (parser *queryParser) parseAnd() (queryNode, error) {
+	lhs, err := parser.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+
+	for parser.isIdent("and") {
+		parser.next()
+		rhs, err := parser.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+
+		lhs = &binaryNode{op: "and", lhs: lhs, rhs: rhs}
+	}
+
+	return lhs, nil
+}
+
+func (parser *queryParser) parseComparison() (queryNode, error) {
+	lhs, err := parser.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if parser.isPunct(op) {
+			parser.next()
+			rhs, err := parser.parsePostfix()
+			if err != nil {
+				return nil, err
+			}
+
+			return &binaryNode{op: op, lhs: lhs, rhs: rhs}, nil
+		}
+	}
+
+	return lhs, nil
+}
+
+func (parser *queryParser) parsePostfix() (queryNode, error) {
+	node, err := parser.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if parser.peek().kind == queryDot {
+			parser.next()
+			token := parser.peek()
+			switch {
+			case token.kind == queryIdent || token.kind == queryString:
+				parser.next()
+				node = &pipeNode{lhs: node, rhs: &indexNode{key: &literalNode{value: token.text}}}
+			case parser.isPunct("["):
+				// handled by the next iteration
+			default:
+				return nil, fmt.Errorf("query: expected field name at %d", token.pos)
+			}
+		} else if parser.isPunct("[") {
+			suffix, err := parser.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+
+			node = &pipeNode{lhs: node, rhs: suffix}
+		} else {
+			return node, nil
+		}
+	}
+}
+
+// parses `[]`, `[expr]` or a slice like `[from:to]`, where either end can be left out, the bracket
+// being the current token
+func (parser *queryParser) parseBracket() (queryNode, error) {
+	if err := parser.expect("["); err != nil {
+		return nil, err
+	}
+
+	if parser.isPunct("]") {
+		parser.next()
+		return &iterateNode{}, nil
+	}
+
+	var key queryNode
+	if !parser.isPunct(":") {
+		var err error
+		if key, err = parser.parsePipe(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !parser.isPunct(":") {
+		if err := parser.expect("]"); err != nil {
+			return nil, err
+		}
+
+		return &indexNode{key: key}, nil
+	}
+
+	parser.next()
+	slice := &sliceNode{from: key}
+	if !parser.isPunct("]") {
+		var err error
+		if slice.to, err = parser.parsePipe(); err != nil {
+			return nil, err
+		}
+	}
+
+	if slice.from == nil && slice.to == nil {
+		return nil, fmt.Errorf("query: a slice needs a start or an end at %d", parser.peek().pos)
+	}
+
+	if err := parser.expect("]"); err != nil {
+		return nil, err
+	}
+
+	return slice, nil
+}
+
+func (parser *queryParser) parsePrimary() (queryNode, error) {
+	token := parser.peek()
+
+	switch token.kind {
+	case queryDot:
+		parser.next()
+		next := parser.peek()
+		if next.kind == queryIdent || next.kind == queryString {
+			parser.next()
+			return &indexNode{key: &literalNode{value: next.text}}, nil
+		}
+
+		if parser.isPunct("[") {
+			return parser.parseBracket()
+		}
+
+		return &identityNode{}, nil
+	case queryString:
+		parser.next()
+		return &literalNode{value: token.text}, nil
+	case queryNumber:
+		parser.next()
+		value, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("query: invalid number %q at %d", token.text, token.pos)
+		}
+
+		return &literalNode{value: value}, nil
+	case queryIdent:
+		return parser.parseFunction()
+	case queryPunct:
+		switch token.text {
+		case "(":
+			parser.next()
+			node, err := parser.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := parser.expect(")"); err != nil {
+				return nil, err
+			}
+
+			return node, nil
+		case "{":
+			return parser.parseObjectConstruction()
+		case "[":
+			parser.next()
+			if parser.isPunct("]") {
+				parser.next()
+				return &collectNode{}, nil
+			}
+
+			inner, err := parser.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := parser.expect("]"); err != nil {
+				return nil, err
+			}
+
+			return &collectNode{inner: inner}, nil
+		}
+	}
+
+	if token.kind == queryEOF {
+		return nil, fmt.Errorf("query: unexpected end of expression")
+	}
+
+	return nil, fmt.Errorf("query: unexpected %q at %d", token.text, token.pos)
+}
+
+func (parser *queryParser) parseFunction() (queryNode, error) {
+	token := parser.next()
+
+	switch token.text {
+	case "true":
+		return &literalNode{value: true}, nil
+	case "false":
+		return &literalNode{value: false}, nil
+	case "null":
+		return &literalNode{value: nil}, nil
+	case "keys", "length", "not", "type", "values":
+		return &functionNode{name: token.text}, nil
+	case "select", "map", "has":
+		if err := parser.expect("("); err != nil {
+			return nil, err
+		}
+
+		arg, err := parser.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := parser.expect(")"); err != nil {
+			return nil, err
+		}
+
+		return &functionNode{name: token.text, arg: arg}, nil
+	}
+
+	return nil, fmt.Errorf("query: unknown function %q at %d", token.text, token.pos)
+}
+
+func (parser *queryParser) parseObjectConstruction() (queryNode, error) {
+	if err := parser.expect("{"); err != nil {
+		return nil, err
+	}
+
+	node := &objectNode{}
+	for !parser.isPunct("}") {
+		var entry objectEntry
+		token := parser.peek()
+
+		switch {
+		case token.kind == queryIdent || token.kind == queryString:
+			parser.next()
+			entry.key = &literalNode{value: token.text}
+			if token.kind == queryIdent && !parser.isPunct(":") {
+				// `{foo}` is shorthand for `{foo: .foo}`
+				entry.value = &indexNode{key: &literalNode{value: token.text}}
+			}
+		case parser.isPunct("("):
+			parser.next()
+			key, err := parser.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := parser.expect(")"); err != nil {
+				return nil, err
+			}
+
+			entry.key = key
+		default:
+			return nil, fmt.Errorf("query: invalid object key %q at %d", token.text, token.pos)
+		}
+
+		if entry.value == nil {
+			if err := parser.expect(":"); err != nil {
+				return nil, err
+			}
+
+			value, err := parser.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			entry.value = value
+		}
+
+		node.entries = append(node.entries, entry)
+
+		if !parser.isPunct(",") {
+			break
+		}
+
+		parser.next()
+	}
+
+	if err := parser.expect("}"); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+// evaluation:
+
+type identityNode struct{}
+
+func (node *identityNode) eval(input interface{}) ([]interface{}, error) {
+	return []interface{}{input}, nil
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (node *literalNode) eval(input interface{}) ([]interface{}, error) {
+	return []interface{}{node.value}, nil
+}
+
+type pipeNode struct {
+	lhs, rhs queryNode
+}
+
+func (node *pipeNode) eval(input interface{}) ([]interface{}, error) {
+	lhs, err := node.lhs.eval(input)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, 0)
+	for _, value := range lhs {
+		rhs, err := node.rhs.eval(value)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, rhs...)
+	}
+
+	return results, nil
+}
+
+type commaNode struct {
+	lhs, rhs queryNode
+}
+
+func (node *commaNode) eval(input interface{}) ([]interface{}, error) {
+	lhs, err := node.lhs.eval(input)
+	if err != nil {
+		return nil, err
+	}
+
+	rhs, err := node.rhs.eval(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(lhs, rhs...), nil
+}
+
+type indexNode struct {
+	key queryNode
+}
+
+func (node *indexNode) eval(input interface{}) ([]interface{}, error) {
+	keys, err := node.key.eval(input)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		value, err := indexValue(input, key)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, value)
+	}
+
+	return results, nil
+}
+
+func indexValue(input interface{}, key interface{}) (interface{}, error) {
+	if input == nil {
+		return nil, nil
+	}
+
+	switch container := input.(type) {
+	case *orderedjson.JsonObject:
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("query: cannot index object with %s", orderedjson.TypeName(key))
+		}
+
+		value, _ := container.Get(name)
+		return value, nil
+	case []interface{}:
+		idx, ok := key.(float64)
+		if !ok {
+			return nil, fmt.Errorf("query: cannot index array with %s", orderedjson.TypeName(key))
+		}
+
+		i := int(math.Floor(idx))
+		if i < 0 {
+			i += len(container)
+		}
+
+		if i < 0 || i >= len(container) {
+			return nil, nil
+		}
+
+		return container[i], nil
+	}
+
+	return nil, fmt.Errorf("query: cannot index %s", orderedjson.TypeName(input))
+}
+
+// .[from:to] of an array or a string, with negative ends counting from the end like in jq. a nil
+// end is the start or the end of the whole thing.
+type sliceNode struct {
+	from, to queryNode
+}
+
+func (node *sliceNode) eval(input interface{}) ([]interface{}, error) {
+	froms, err := sliceBounds(node.from, input)
+	if err != nil {
+		return nil, err
+	}
+
+	tos, err := sliceBounds(node.to, input)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, 0, len(froms)*len(tos))
+	for _, from := range froms {
+		for _, to := range tos {
+			value, err := sliceValue(input, from, to)
+			if err != nil {
+				return nil, err
+			}
+
+			results = append(results, value)
+		}
+	}
+
+	return results, nil
+}
+
+func sliceBounds(bound queryNode, input interface{}) ([]interface{}, error) {
+	if bound == nil {
+		return []interface{}{nil}, nil
+	}
+
+	return bound.eval(input)
+}
+
+func sliceValue(input, from, to interface{}) (interface{}, error) {
+	var length int
+	switch container := input.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		length = len(container)
+	case string:
+		length = len([]rune(container))
+	default:
+		return nil, fmt.Errorf("query: cannot slice %s", orderedjson.TypeName(input))
+	}
+
+	start, err := sliceIndex(from, 0, length, math.Floor)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := sliceIndex(to, length, length, math.Ceil)
+	if err != nil {
+		return nil, err
+	}
+
+	end = max(start, end)
+	if container, ok := input.([]interface{}); ok {
+		return append([]interface{}{}, container[start:end]...), nil
+	}
+
+	return string([]rune(input.(string))[start:end]), nil
+}
+
+// where bound is in something length long, missing being where a null bound is
+func sliceIndex(bound interface{}, missing, length int, round func(float64) float64) (int, error) {
+	if bound == nil {
+		return missing, nil
+	}
+
+	number, ok := bound.(float64)
+	if !ok {
+		return 0, fmt.Errorf("query: slice bounds have to be numbers, not %s", orderedjson.TypeName(bound))
+	}
+
+	index := int(round(number))
+	if index < 0 {
+		index += length
+	}
+
+	return min(max(index, 0), length), nil
+}
+
+type iterateNode struct{}
+
+func (node *iterateNode) eval(input interface{}) ([]interface{}, error) {
+	switch container := input.(type) {
+	case *orderedjson.JsonObject:
+		results := make([]interface{}, 0, container.Len())
+		for pair := container.Oldest(); pair != nil; pair = pair.Next() {
+			results = append(results, pair.Value)
+		}
+
+		return results, nil
+	case []interface{}:
+		return append([]interface{}{}, container...), nil
+	}
+
+	return nil, fmt.Errorf("query: cannot iterate over %s", orderedjson.TypeName(input))
+}
+
+type collectNode struct {
+	inner queryNode
+}
+
+func (node *collectNode) eval(input interface{}) ([]interface{}, error) {
+	if node.inner == nil {
+		return []interface{}{make([]interface{}, 0)}, nil
+	}
+
+	values, err := node.inner.eval(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return []interface{}{values}, nil
+}
+
+type objectEntry struct {
+	key, value queryNode
+}
+
+type objectNode struct {
+	entries []objectEntry
+}
+
+func (node *objectNode) eval(input interface{}) ([]interface{}, error) {
+	// every entry can produce several values, and like jq we produce the cartesian product of all
+	// of them. the entries are always set in the order they were written.
+	partials := []*orderedjson.JsonObject{orderedmap.New[string, interface{}]()}
+
+	for _, entry := range node.entries {
+		keys, err := entry.key.eval(input)
+		if err != nil {
+			return nil, err
+		}
+
+		values, err := entry.value.eval(input)
+		if err != nil {
+			return nil, err
+		}
+
+		next := make([]*orderedjson.JsonObject, 0, len(partials)*len(keys)*len(values))
+		for _, partial := range partials {
+			for _, key := range keys {
+				name, ok := key.(string)
+				if !ok {
+					return nil, fmt.Errorf("query: object keys must be strings, got %s", orderedjson.TypeName(key))
+				}
+
+				for _, value := range values {
+					object := copyObject(partial)
+					object.Set(name, value)
+					next = append(next, object)
+				}
+			}
+		}
+
+		partials = next
+	}
+
+	results := make([]interface{}, 0, len(partials))
+	for _, partial := range partials {
+		results = append(results, partial)
+	}
+
+	return results, nil
+}
+
+type binaryNode struct {
+	op       string
+	lhs, rhs queryNode
+}
+
+func (node *binaryNode) eval(input interface{}) ([]interface{}, error) {
+	lhs, err := node.lhs.eval(input)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, 0)
+	for _, left := range lhs {
+		if node.op == "and" && !isTruthy(left) {
+			results = append(results, false)
+			continue
+		}
+
+		if node.op == "or" && isTruthy(left) {
+			results = append(results, true)
+			continue
+		}
+
+		rhs, err := node.rhs.eval(input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, right := range rhs {
+			value, err := applyBinary(node.op, left, right)
+			if err != nil {
+				return nil, err
+			}
+
+			results = append(results, value)
+		}
+	}
+
+	return results, nil
+}
+
+func applyBinary(op string, left, right interface{}) (interface{}, error) {
+	switch op {
+	case "and", "or":
+		return isTruthy(right), nil
+	case "==":
+		return orderedjson.Equal(left, right), nil
+	case "!=":
+		return !orderedjson.Equal(left, right), nil
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, fmt.Errorf("query: cannot compare number with %s", orderedjson.TypeName(right))
+		}
+
+		if l < r {
+			cmp = -1
+		} else if l > r {
+			cmp = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("query: cannot compare string with %s", orderedjson.TypeName(right))
+		}
+
+		cmp = strings.Compare(l, r)
+	default:
+		return nil, fmt.Errorf("query: cannot compare %s", orderedjson.TypeName(left))
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	}
+
+	return cmp >= 0, nil
+}
+
+type functionNode struct {
+	name string
+	arg  queryNode
+}
+
+func (node *functionNode) eval(input interface{}) ([]interface{}, error) {
+	switch node.name {
+	case "keys":
+		switch container := input.(type) {
+		case *orderedjson.JsonObject:
+			keys := make([]interface{}, 0, container.Len())
+			for pair := container.Oldest(); pair != nil; pair = pair.Next() {
+				keys = append(keys, pair.Key)
+			}
+
+			return []interface{}{keys}, nil
+		case []interface{}:
+			keys := make([]interface{}, 0, len(container))
+			for i := range container {
+				keys = append(keys, float64(i))
+			}
+
+			return []interface{}{keys}, nil
+		}
+
+		return nil, fmt.Errorf("query: %s has no keys", orderedjson.TypeName(input))
+	case "length":
+		switch value := input.(type) {
+		case nil:
+			return []interface{}{0.0}, nil
+		case *orderedjson.JsonObject:
+			return []interface{}{float64(value.Len())}, nil
+		case []interface{}:
+			return []interface{}{float64(len(value))}, nil
+		case string:
+			return []interface{}{float64(len([]rune(value)))}, nil
+		case float64:
+			return []interface{}{math.Abs(value)}, nil
+		}
+
+		return nil, fmt.Errorf("query: %s has no length", orderedjson.TypeName(input))
+	case "not":
+		return []interface{}{!isTruthy(input)}, nil
+	case "type":
+		return []interface{}{orderedjson.TypeName(input)}, nil
+	case "values":
+		if input == nil {
+			return []interface{}{}, nil
+		}
+
+		return []interface{}{input}, nil
+	case "select":
+		conditions, err := node.arg.eval(input)
+		if err != nil {
+			return nil, err
+		}
+
+		results := make([]interface{}, 0)
+		for _, condition := range conditions {
+			if isTruthy(condition) {
+				results = append(results, input)
+			}
+		}
+
+		return results, nil
+	case "map":
+		return (&collectNode{inner: &pipeNode{lhs: &iterateNode{}, rhs: node.arg}}).eval(input)
+	case "has":
+		keys, err := node.arg.eval(input)
+		if err != nil {
+			return nil, err
+		}
+
+		results := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			switch container := input.(type) {
+			case *orderedjson.JsonObject:
+				name, ok := key.(string)
+				if !ok {
+					return nil, fmt.Errorf("query: cannot check object for %s key", orderedjson.TypeName(key))
+				}
+
+				_, present := container.Get(name)
+				results = append(results, present)
+			case []interface{}:
+				idx, ok := key.(float64)
+				if !ok {
+					return nil, fmt.Errorf("query: cannot check array for %s key", orderedjson.TypeName(key))
+				}
+
+				results = append(results, idx >= 0 && int(idx) < len(container))
+			default:
+				return nil, fmt.Errorf("query: cannot check whether %s has a key", orderedjson.TypeName(input))
+			}
+		}
+
+		return results, nil
+	}
+
+	return nil, fmt.Errorf("query: unknown function %q", node.name)
+}
+
+// shallow copy, keeping the key order
+func copyObject(object *orderedjson.JsonObject) *orderedjson.JsonObject {
+	result := orderedmap.New[string, interface{}]()
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		result.Set(pair.Key, pair.Value)
+	}
+
+	return result
+}
+
+func isTruthy(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+
+	if b, ok := value.(bool); ok {
+		return b
+	}
+
+	return true
+}
@@ -0,0 +1,64 @@
+package query
+
+import (
+	"strings"
+	"testing"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+func TestQuery(t *testing.T) {
+	tree, err := orderedjson.ParseOptions{}.Parse([]byte(`{"b": 1, "a": {"x": [1, 2, 3, 4]}, "s": "héllo", "n": null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr string
+		// the results, compact and one per line
+		want string
+	}{
+		{`.b`, `1`},
+		{`keys`, `["b","a","s","n"]`},
+		{`{a: .b, "z": .a.x[0], b}`, `{"a":1,"z":1,"b":1}`},
+		{`.a.x[] | select(. > 2)`, "3\n4"},
+		{`.a.x | map(select(. != 2))`, `[1,3,4]`},
+		{`.a.x[-1]`, `4`},
+		{`.a.x[-9]`, `null`},
+		{`.a.x[1:3]`, `[2,3]`},
+		{`.a.x[-2:]`, `[3,4]`},
+		{`.a.x[:1]`, `[1]`},
+		{`.a.x[3:1]`, `[]`},
+		{`.a.x[-9:9]`, `[1,2,3,4]`},
+		{`.s[1:3]`, `"él"`},
+		{`.n[1:]`, `null`},
+	}
+
+	for _, test := range tests {
+		results, err := Query(tree, test.expr)
+		if err != nil {
+			t.Errorf("Query(%q): %v", test.expr, err)
+			continue
+		}
+
+		var got []string
+		for _, result := range results {
+			data, err := orderedjson.MarshalCompact(result)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got = append(got, data)
+		}
+
+		if strings.Join(got, "\n") != test.want {
+			t.Errorf("Query(%q) = %s, want %s", test.expr, strings.Join(got, "\n"), test.want)
+		}
+	}
+
+	for _, expr := range []string{`.a.x[:]`, `.a.x["a":]`, `.b[1:]`, `.a.x[1:2`, `.a -1`} {
+		if _, err := Query(tree, expr); err == nil {
+			t.Errorf("Query(%q) didn't fail", expr)
+		}
+	}
+}
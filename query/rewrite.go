@@ -0,0 +1,420 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// a rewrite script is a list of steps with | between them, each one changing the document the step
+// before it left:
+//
+//	set(.engines.node, ">=20")  sets a key (or array item), making the objects on the way to it
+//	del(.scripts.test)          removes a key or array item, if it's there
+//	rename(.main, "module")     renames a key where it is
+//
+// paths are .key, .["key"] and .[index], and values are query expressions (see query.go) run on the
+// document as it is at that step, so set(.b, .a) copies a value.
+
+type rewriteStep struct {
+	name string
+	path orderedjson.Path
+	// the value for set, the new name for rename
+	arg queryNode
+}
+
+// Rewrite runs a rewrite script on tree and returns the result. tree itself isn't changed.
+func Rewrite(tree *orderedjson.JsonObject, script string) (*orderedjson.JsonObject, error) {
+	compiled, err := CompileRewrite(script)
+	if err != nil {
+		return nil, err
+	}
+
+	return compiled.Apply(tree)
+}
+
+// RewriteScript is a compiled rewrite script, for running the same one on many trees.
+type RewriteScript struct {
+	steps []rewriteStep
+}
+
+// CompileRewrite parses a rewrite script.
+func CompileRewrite(script string) (*RewriteScript, error) {
+	steps, err := compileRewrite(script)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RewriteScript{steps: steps}, nil
+}
+
+// SetScript is the script set(path, value) with value as it is, instead of an expression for it.
+func SetScript(path string, value interface{}) (*RewriteScript, error) {
+	lexer := &queryLexer{runes: []rune(path)}
+	tokens, err := lexer.tokenize()
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &queryParser{tokens: tokens}
+	parsed, err := parser.parseRewritePath()
+	if err != nil {
+		return nil, err
+	}
+
+	if token := parser.peek(); token.kind != queryEOF {
+		return nil, fmt.Errorf("set: unexpected %q at %d", token.text, token.pos)
+	}
+
+	return &RewriteScript{steps: []rewriteStep{{name: "set", path: parsed, arg: &literalNode{value: value}}}}, nil
+}
+
+// Apply runs the script on tree and returns the result. tree itself isn't changed.
+func (script *RewriteScript) Apply(tree *orderedjson.JsonObject) (*orderedjson.JsonObject, error) {
+	result, _, err := applyRewrite(tree, nil, script.steps)
+	return result, err
+}
+
+// ApplyMeta is Apply for a tree with metadata, like a Document's Tree and Meta. the metadata it
+// returns follows the keys the script changes: a renamed key keeps its comments, a deleted one
+// takes them with it, and the items after a deleted array item keep theirs. neither tree nor meta
+// is changed.
+func (script *RewriteScript) ApplyMeta(tree *orderedjson.JsonObject, meta *orderedjson.Metadata) (*orderedjson.JsonObject, *orderedjson.Metadata, error) {
+	return applyRewrite(tree, meta.Clone(), script.steps)
+}
+
+func compileRewrite(script string) ([]rewriteStep, error) {
+	lexer := &queryLexer{runes: []rune(script)}
+	tokens, err := lexer.tokenize()
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &queryParser{tokens: tokens}
+	var steps []rewriteStep
+	for {
+		step, err := parser.parseRewriteStep()
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+
+		if !parser.isPunct("|") {
+			break
+		}
+		parser.next()
+	}
+
+	if token := parser.peek(); token.kind != queryEOF {
+		return nil, fmt.Errorf("rewrite: unexpected %q at %d", token.text, token.pos)
+	}
+
+	return steps, nil
+}
+
+func (parser *queryParser) parseRewriteStep() (rewriteStep, error) {
+	token := parser.next()
+	step := rewriteStep{name: token.text}
+	switch {
+	case token.kind == queryEOF:
+		return step, fmt.Errorf("rewrite: unexpected end of script")
+	case token.kind != queryIdent || (token.text != "set" && token.text != "del" && token.text != "rename"):
+		return step, fmt.Errorf("rewrite: expected set, del or rename at %d, found %q", token.pos, token.text)
+	}
+
+	if err := parser.expect("("); err != nil {
+		return step, err
+	}
+
+	path, err := parser.parseRewritePath()
+	if err != nil {
+		return step, err
+	}
+	step.path = path
+
+	if step.name != "del" {
+		if err := parser.expect(","); err != nil {
+			return step, err
+		}
+
+		if step.arg, err = parser.parsePipe(); err != nil {
+			return step, err
+		}
+	}
+
+	return step, parser.expect(")")
+}
+
+// a path like .a.b, .["a b"] or .[0]. it has to lead somewhere, . alone isn't one.
+func (parser *queryParser) parseRewritePath() (orderedjson.Path, error) {
+	var path orderedjson.Path
+	for parser.peek().kind == queryDot || parser.isPunct("[") {
+		if parser.peek().kind == queryDot {
+			parser.next()
+			if token := parser.peek(); token.kind == queryIdent || token.kind == queryString {
+				parser.next()
+				path = append(path, token.text)
+				continue
+			}
+
+			if !parser.isPunct("[") {
+				break
+			}
+		}
+
+		parser.next()
+		token := parser.next()
+		switch token.kind {
+		case queryString:
+			path = append(path, token.text)
+		case queryNumber:
+			index, err := strconv.Atoi(token.text)
+			if err != nil {
+				return nil, fmt.Errorf("rewrite: invalid index %s at %d", token.text, token.pos)
+			}
+			path = append(path, index)
+		default:
+			return nil, fmt.Errorf("rewrite: expected a key or an index at %d", token.pos)
+		}
+
+		if err := parser.expect("]"); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(path) == 0 {
+		return nil, fmt.Errorf("rewrite: expected a path like .key at %d", parser.peek().pos)
+	}
+
+	return path, nil
+}
+
+// meta is changed along with the tree when it's not nil
+func applyRewrite(tree *orderedjson.JsonObject, meta *orderedjson.Metadata, steps []rewriteStep) (*orderedjson.JsonObject, *orderedjson.Metadata, error) {
+	var result interface{} = tree
+	for _, step := range steps {
+		var err error
+		switch step.name {
+		case "set":
+			var value interface{}
+			if value, err = step.value(result); err == nil {
+				result, err = setAtPath(result, step.path, value)
+			}
+		case "del":
+			if meta != nil {
+				forgetDeleted(meta, result, step.path)
+			}
+			result = deleteAtPath(result, step.path)
+		case "rename":
+			var name interface{}
+			if name, err = step.value(result); err == nil {
+				var renamed interface{}
+				if renamed, err = renameAtPath(result, step.path, name); err == nil && meta != nil && renamed != result {
+					meta.Move(step.path, step.path[:len(step.path)-1].Child(name))
+				}
+				result = renamed
+			}
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s(%s): %w", step.name, step.path, err)
+		}
+	}
+
+	return result.(*orderedjson.JsonObject), meta, nil
+}
+
+// forgets the metadata of what del(path) removes from tree, and moves that of the array items
+// after a removed one down by one
+func forgetDeleted(meta *orderedjson.Metadata, tree interface{}, path orderedjson.Path) {
+	parentPath := path[:len(path)-1]
+	switch parent := valueAt(tree, parentPath).(type) {
+	case *orderedjson.JsonObject:
+		key, ok := path[len(path)-1].(string)
+		if _, found := parent.Get(key); ok && found {
+			meta.Forget(path)
+		}
+	case []interface{}:
+		index, ok := path[len(path)-1].(int)
+		if !ok || index < 0 || index >= len(parent) {
+			return
+		}
+
+		meta.Forget(path)
+		for i := index + 1; i < len(parent); i++ {
+			meta.Move(parentPath.Child(i), parentPath.Child(i-1))
+		}
+	}
+}
+
+// the one value the step's argument gives for tree
+func (step rewriteStep) value(tree interface{}) (interface{}, error) {
+	values, err := step.arg.eval(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) != 1 {
+		return nil, fmt.Errorf("the value has to be one value, got %d", len(values))
+	}
+
+	return values[0], nil
+}
+
+// like ReplaceAt, but makes the objects that aren't there yet, and appends to arrays when the
+// index is their length
+func setAtPath(value interface{}, path orderedjson.Path, replacement interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return replacement, nil
+	}
+
+	switch key := path[0].(type) {
+	case string:
+		if value == nil {
+			value = orderedmap.New[string, interface{}]()
+		}
+
+		object, ok := value.(*orderedjson.JsonObject)
+		if !ok {
+			return nil, fmt.Errorf("can't set key %q of %s", key, orderedjson.TypeName(value))
+		}
+
+		child, _ := object.Get(key)
+		updated, err := setAtPath(child, path[1:], replacement)
+		if err != nil {
+			return nil, err
+		}
+
+		result := copyObject(object)
+		result.Set(key, updated)
+		return result, nil
+	case int:
+		array, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("can't set index %d of %s", key, orderedjson.TypeName(value))
+		}
+
+		if key < 0 || key > len(array) {
+			return nil, fmt.Errorf("index %d is out of range, the array has %d items", key, len(array))
+		}
+
+		result := append([]interface{}{}, array...)
+		if key == len(array) {
+			result = append(result, nil)
+		}
+
+		updated, err := setAtPath(result[key], path[1:], replacement)
+		if err != nil {
+			return nil, err
+		}
+
+		result[key] = updated
+		return result, nil
+	}
+
+	return value, nil
+}
+
+// value without what's at path, which doesn't have to be there
+func deleteAtPath(value interface{}, path orderedjson.Path) interface{} {
+	switch v := value.(type) {
+	case *orderedjson.JsonObject:
+		key, ok := path[0].(string)
+		child, found := v.Get(key)
+		if !ok || !found {
+			return value
+		}
+
+		result := copyObject(v)
+		if len(path) == 1 {
+			result.Delete(key)
+		} else {
+			result.Set(key, deleteAtPath(child, path[1:]))
+		}
+
+		return result
+	case []interface{}:
+		index, ok := path[0].(int)
+		if !ok || index < 0 || index >= len(v) {
+			return value
+		}
+
+		if len(path) == 1 {
+			return append(append([]interface{}{}, v[:index]...), v[index+1:]...)
+		}
+
+		result := append([]interface{}{}, v...)
+		result[index] = deleteAtPath(v[index], path[1:])
+		return result
+	}
+
+	return value
+}
+
+// value with the key at the end of path called name instead, in the same place
+func renameAtPath(value interface{}, path orderedjson.Path, name interface{}) (interface{}, error) {
+	newKey, ok := name.(string)
+	if !ok {
+		return nil, fmt.Errorf("the new name has to be a string, got %s", orderedjson.TypeName(name))
+	}
+
+	oldKey, ok := path[len(path)-1].(string)
+	if !ok {
+		return nil, fmt.Errorf("only keys can be renamed")
+	}
+
+	parentPath := path[:len(path)-1]
+	object := objectAt(value.(*orderedjson.JsonObject), parentPath)
+	if object == nil {
+		return value, nil
+	}
+
+	if _, found := object.Get(oldKey); !found || oldKey == newKey {
+		return value, nil
+	}
+
+	if _, taken := object.Get(newKey); taken {
+		return nil, fmt.Errorf("there's a %q already", newKey)
+	}
+
+	renamed := orderedmap.New[string, interface{}]()
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		if pair.Key == oldKey {
+			renamed.Set(newKey, pair.Value)
+		} else {
+			renamed.Set(pair.Key, pair.Value)
+		}
+	}
+
+	return orderedjson.ReplaceAt(value.(*orderedjson.JsonObject), parentPath, renamed), nil
+}
+
+// the object at path in tree, or nil when there isn't one there
+func objectAt(tree *orderedjson.JsonObject, path orderedjson.Path) *orderedjson.JsonObject {
+	object, _ := valueAt(tree, path).(*orderedjson.JsonObject)
+	return object
+}
+
+// the value at path in tree, or nil when there isn't one there
+func valueAt(tree interface{}, path orderedjson.Path) interface{} {
+	value := tree
+	for _, element := range path {
+		switch v := value.(type) {
+		case *orderedjson.JsonObject:
+			key, _ := element.(string)
+			value, _ = v.Get(key)
+		case []interface{}:
+			i, ok := element.(int)
+			if !ok || i < 0 || i >= len(v) {
+				return nil
+			}
+			value = v[i]
+		default:
+			return nil
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,92 @@
+package query
+
+import (
+	"testing"
+
+	orderedjson "github.com/michaelhelvey/orderedjson/v2"
+)
+
+func TestPatch(t *testing.T) {
+	tests := []struct {
+		input, script, want string
+	}{
+		{`{"a": [], "b": 1}`, `set(.a[0], 1)`, `{"a": [1], "b": 1}`},
+		{`{"a": {}, "b": 1}`, `set(.a.x, 1)`, `{"a": {"x": 1}, "b": 1}`},
+		{`{"a": [1], "b": 1}`, `del(.a[0])`, `{"a": [], "b": 1}`},
+		{`{"a": [1, 2], "b": 1.50}`, `set(.a[2], 3)`, `{"a": [1, 2, 3], "b": 1.50}`},
+		{"{\n  \"a\": [\n    1\n  ],\n  \"b\": 1.50\n}", `set(.a[1], 2)`, "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"b\": 1.50\n}"},
+		{"{\n  \"a\": 1,\n  \"b\": 2\n}", `del(.a)`, "{\n  \"b\": 2\n}"},
+		{"{\n  \"a\": 1,\n  \"b\": 2\n}", `set(.c, 3)`, "{\n  \"a\": 1,\n  \"b\": 2,\n  \"c\": 3\n}"},
+		{`{"a": 1, "b": 2}`, `rename(.a, "z")`, `{"z": 1, "b": 2}`},
+	}
+
+	for _, test := range tests {
+		doc, err := orderedjson.ParseDocument([]byte(test.input))
+		if err != nil {
+			t.Errorf("ParseDocument(%q): %v", test.input, err)
+			continue
+		}
+
+		tree, err := Rewrite(doc.Tree, test.script)
+		if err != nil {
+			t.Errorf("Rewrite(%q, %q): %v", test.input, test.script, err)
+			continue
+		}
+
+		got, err := doc.Patch(tree)
+		if err != nil {
+			t.Errorf("Patch(%q, %q): %v", test.input, test.script, err)
+			continue
+		}
+
+		if string(got) != test.want {
+			t.Errorf("Patch(%q, %q) = %q, want %q", test.input, test.script, got, test.want)
+		}
+	}
+}
+
+func TestRewriteMeta(t *testing.T) {
+	input := "{\n  // the name\n  \"name\": \"x\",\n  \"list\": [\n    {\n      \"id\": 1 // first\n    },\n    {\n      \"id\": 2 // second\n    }\n  ]\n}"
+	tests := []struct {
+		script, want string
+	}{
+		{`rename(.name, "title")`, "{\n  // the name\n  \"title\": \"x\",\n  \"list\": [\n    {\n      \"id\": 1 // first\n    },\n    {\n      \"id\": 2 // second\n    }\n  ]\n}"},
+		{`del(.list[0])`, "{\n  // the name\n  \"name\": \"x\",\n  \"list\": [\n    {\n      \"id\": 2 // second\n    }\n  ]\n}"},
+		{`del(.name) | set(.name, "y")`, "{\n  \"list\": [\n    {\n      \"id\": 1 // first\n    },\n    {\n      \"id\": 2 // second\n    }\n  ],\n  \"name\": \"y\"\n}"},
+		{`del(.list[1]) | set(.list[1], {id: 3})`, "{\n  // the name\n  \"name\": \"x\",\n  \"list\": [\n    {\n      \"id\": 1 // first\n    },\n    {\n      \"id\": 3\n    }\n  ]\n}"},
+		{`rename(.list, "items") | set(.list, [])`, "{\n  // the name\n  \"name\": \"x\",\n  \"items\": [\n    {\n      \"id\": 1 // first\n    },\n    {\n      \"id\": 2 // second\n    }\n  ],\n  \"list\": []\n}"},
+	}
+
+	doc, err := orderedjson.ParseJSONC([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		script, err := CompileRewrite(test.script)
+		if err != nil {
+			t.Errorf("CompileRewrite(%q): %v", test.script, err)
+			continue
+		}
+
+		tree, meta, err := script.ApplyMeta(doc.Tree, doc.Meta)
+		if err != nil {
+			t.Errorf("ApplyMeta(%q): %v", test.script, err)
+			continue
+		}
+
+		got, err := orderedjson.MarshalOptions{Indent: "  ", Comments: true, Meta: meta}.Marshal(tree)
+		if err != nil {
+			t.Errorf("Marshal(%q): %v", test.script, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.script, got, test.want)
+		}
+	}
+
+	if comment, _ := doc.Meta.Get(orderedjson.Path{"name"}, orderedjson.CommentMeta); comment != "the name" {
+		t.Errorf("ApplyMeta changed the metadata it was given")
+	}
+}
@@ -0,0 +1,78 @@
+package orderedjson
+
+import (
+	"regexp"
+	"strings"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// RedactedValue is what Redact puts in place of a sensitive value.
+const RedactedValue = "***"
+
+// KeyMatcher decides whether the value under an object key is sensitive.
+type KeyMatcher func(key string) bool
+
+// MatchKeys matches any of the given keys, ignoring case.
+func MatchKeys(keys ...string) KeyMatcher {
+	return func(key string) bool {
+		for _, k := range keys {
+			if strings.EqualFold(k, key) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// MatchPattern matches keys that re matches.
+func MatchPattern(re *regexp.Regexp) KeyMatcher {
+	return re.MatchString
+}
+
+// Redact returns a copy of tree where the value of every key (at any depth) that one of matchers
+// matches is replaced by RedactedValue. everything else, including the key order, stays as it was.
+func Redact(tree *JsonObject, matchers ...KeyMatcher) *JsonObject {
+	return redact(tree, matchers, false).(*JsonObject)
+}
+
+// RemoveKeys is Redact but drops the matched keys entirely.
+func RemoveKeys(tree *JsonObject, matchers ...KeyMatcher) *JsonObject {
+	return redact(tree, matchers, true).(*JsonObject)
+}
+
+func redact(value interface{}, matchers []KeyMatcher, remove bool) interface{} {
+	switch v := value.(type) {
+	case *JsonObject:
+		result := orderedmap.New[string, interface{}]()
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			if !matchesAny(matchers, pair.Key) {
+				result.Set(pair.Key, redact(pair.Value, matchers, remove))
+			} else if !remove {
+				result.Set(pair.Key, RedactedValue)
+			}
+		}
+
+		return result
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			result = append(result, redact(item, matchers, remove))
+		}
+
+		return result
+	}
+
+	return value
+}
+
+func matchesAny(matchers []KeyMatcher, key string) bool {
+	for _, matcher := range matchers {
+		if matcher(key) {
+			return true
+		}
+	}
+
+	return false
+}
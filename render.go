@@ -0,0 +1,169 @@
+package orderedjson
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// a string that's nothing but one {{ }} action
+var wholeAction = regexp.MustCompile(`^\{\{-?\s*(.*?)\s*-?\}\}$`)
+
+// Render fills in a template: a document whose strings (keys included) can hold text/template
+// actions like {{ .region }} or {{ .env.HOME }}, run against values. the result has the keys of the
+// template in the template's order.
+//
+// like with Interpolate, a string that's nothing but one action takes the value it gives as is, so
+// "{{ .replicas }}" can be a number and "{{ .labels }}" an object, in the order it has in values.
+// anything else is text. a key that's missing from values is an error instead of "<no value>".
+func Render(tmpl *JsonObject, values *JsonObject) (*JsonObject, error) {
+	renderer := &renderer{origins: make(map[uintptr]*JsonObject)}
+	renderer.data = renderer.templateData(values)
+
+	result, err := renderer.render(tmpl, Path{})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*JsonObject), nil
+}
+
+type renderer struct {
+	data interface{}
+	// the objects of values that the maps templates see came from, to put their keys back in order
+	origins map[uintptr]*JsonObject
+	// what the last whole string action gave
+	captured interface{}
+}
+
+// values as plain maps, since that's what templates can look into with .key
+func (renderer *renderer) templateData(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *JsonObject:
+		result := make(map[string]interface{}, v.Len())
+		renderer.origins[reflect.ValueOf(result).Pointer()] = v
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			result[pair.Key] = renderer.templateData(pair.Value)
+		}
+
+		return result
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			result = append(result, renderer.templateData(item))
+		}
+
+		return result
+	}
+
+	return value
+}
+
+// turns what a template gave back into a tree value
+func (renderer *renderer) treeValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if origin, ok := renderer.origins[reflect.ValueOf(v).Pointer()]; ok {
+			return origin, nil
+		}
+
+		return fromStdValue(v, strings.Compare)
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			converted, err := renderer.treeValue(item)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, converted)
+		}
+
+		return result, nil
+	}
+
+	return fromStdValue(value, strings.Compare)
+}
+
+func (renderer *renderer) render(value interface{}, path Path) (interface{}, error) {
+	switch v := value.(type) {
+	case *JsonObject:
+		result := orderedmap.New[string, interface{}]()
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			key, err := renderer.execute(pair.Key, path)
+			if err != nil {
+				return nil, err
+			}
+
+			child, err := renderer.render(pair.Value, path.Child(pair.Key))
+			if err != nil {
+				return nil, err
+			}
+
+			result.Set(key, child)
+		}
+
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for i, item := range v {
+			child, err := renderer.render(item, path.Child(i))
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, child)
+		}
+
+		return result, nil
+	case string:
+		match := wholeAction.FindStringSubmatch(v)
+		if match == nil || strings.Contains(match[1], "{{") {
+			return renderer.execute(v, path)
+		}
+
+		renderer.captured = nil
+		if _, err := renderer.execute("{{ capture ("+match[1]+") }}", path); err != nil {
+			return nil, err
+		}
+
+		converted, err := renderer.treeValue(renderer.captured)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+
+		return converted, nil
+	}
+
+	return value, nil
+}
+
+// runs the template in text, if there is one
+func (renderer *renderer) execute(text string, path Path) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	funcs := template.FuncMap{
+		"capture": func(value interface{}) string {
+			renderer.captured = value
+			return ""
+		},
+	}
+
+	tmpl, err := template.New(path.String()).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", path, err)
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, renderer.data); err != nil {
+		return "", err
+	}
+
+	return result.String(), nil
+}
@@ -0,0 +1,341 @@
+package orderedjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Repair turns almost-json into json, like what people write by hand or what comes out of a
+// language model. it fixes:
+//
+//   - // line, /* block */ and # comments, which are removed
+//   - trailing commas, and missing ones between values
+//   - single quoted strings, and keys and values without quotes
+//   - = instead of : after keys
+//   - python's True, False and None
+//   - numbers with leading zeros (012), a + sign or no digits on one side of the decimal point (.5)
+//   - raw control characters in strings, strings and objects or arrays that aren't closed
+//
+// everything else stays as it was, including the order of keys and the layout. it's an error when
+// the result still isn't json.
+func Repair(data []byte) ([]byte, error) {
+	r := &repairer{data: bytes.TrimPrefix(data, utf8BOM)}
+	r.out.Grow(len(data))
+
+	r.out.WriteString(r.space())
+	if r.pos == len(r.data) {
+		return nil, ErrEmptyDocument
+	}
+
+	if err := r.value(); err != nil {
+		return nil, err
+	}
+
+	r.out.WriteString(r.space())
+	if r.pos < len(r.data) {
+		return nil, r.errorf("unexpected %s after the document", r.describe())
+	}
+
+	var check json.RawMessage
+	if err := json.Unmarshal(r.out.Bytes(), &check); err != nil {
+		return nil, fmt.Errorf("could not repair the document: %v", err)
+	}
+
+	return r.out.Bytes(), nil
+}
+
+type repairer struct {
+	data []byte
+	pos  int
+	out  bytes.Buffer
+}
+
+func (r *repairer) errorf(format string, args ...interface{}) error {
+	return newSyntaxError(r.data, r.pos, ErrInvalidToken, format, args...)
+}
+
+func (r *repairer) peek() byte {
+	if r.pos < len(r.data) {
+		return r.data[r.pos]
+	}
+
+	return 0
+}
+
+func (r *repairer) describe() string {
+	if r.pos >= len(r.data) {
+		return "end of input"
+	}
+
+	char, _ := utf8.DecodeRune(r.data[r.pos:])
+	return fmt.Sprintf("%q", char)
+}
+
+// skips whitespace and comments, and returns the whitespace. a comment on a line of its own goes
+// away with its line.
+func (r *repairer) space() string {
+	var result strings.Builder
+	for r.pos < len(r.data) {
+		char := r.data[r.pos]
+		switch {
+		case char == ' ' || char == '\t' || char == '\n' || char == '\r':
+			result.WriteByte(char)
+			r.pos++
+		case char == '#' || bytes.HasPrefix(r.data[r.pos:], []byte("//")):
+			end := bytes.IndexByte(r.data[r.pos:], '\n')
+			if end < 0 {
+				end = len(r.data) - r.pos
+			}
+
+			r.pos += end
+			r.dropComment(&result)
+		case bytes.HasPrefix(r.data[r.pos:], []byte("/*")):
+			end := bytes.Index(r.data[r.pos+2:], []byte("*/"))
+			if end < 0 {
+				r.pos = len(r.data)
+			} else {
+				r.pos += end + 4
+			}
+
+			r.dropComment(&result)
+		default:
+			return result.String()
+		}
+	}
+
+	return result.String()
+}
+
+// removes the spaces before a comment that was just skipped, and the line break after it when
+// nothing else was on its line
+func (r *repairer) dropComment(result *strings.Builder) {
+	before := strings.TrimRight(result.String(), " \t")
+	result.Reset()
+	result.WriteString(before)
+
+	alone := strings.HasSuffix(before, "\n") || before == "" && r.out.Len() == 0
+	if !alone {
+		return
+	}
+
+	if bytes.HasPrefix(r.data[r.pos:], []byte("\r\n")) {
+		r.pos += 2
+	} else if r.peek() == '\n' {
+		r.pos++
+	}
+}
+
+func (r *repairer) value() error {
+	switch char := r.peek(); {
+	case char == '{':
+		return r.container('{', '}')
+	case char == '[':
+		return r.container('[', ']')
+	case char == '"' || char == '\'':
+		r.string()
+	case char == '-' || char == '+' || char == '.' || char >= '0' && char <= '9':
+		r.number()
+	case isWordStart(char):
+		word := r.word()
+		if literal, ok := pythonLiterals[word]; ok {
+			word = literal
+		}
+
+		if _, ok := jsonLiterals[word]; ok {
+			r.out.WriteString(word)
+		} else {
+			// a string someone forgot to quote
+			writeQuoted(&r.out, word)
+		}
+	default:
+		return r.errorf("expected a value, got %s", r.describe())
+	}
+
+	return nil
+}
+
+// an object or an array, closed at the end of the input if it isn't
+func (r *repairer) container(open, close byte) error {
+	r.out.WriteByte(open)
+	r.pos++
+
+	before := r.space()
+	for r.peek() == ',' {
+		// a leading comma
+		r.pos++
+		before += r.space()
+	}
+
+	for {
+		if r.pos == len(r.data) {
+			r.out.WriteString(strings.TrimRight(before, " \t\r\n"))
+			r.out.WriteByte(close)
+			return nil
+		}
+
+		// [1, 2} has the wrong closing bracket, keep what the opening one says
+		if r.peek() == '}' || r.peek() == ']' {
+			r.out.WriteString(before)
+			r.out.WriteByte(close)
+			r.pos++
+			return nil
+		}
+
+		r.out.WriteString(before)
+		if open == '{' {
+			if err := r.member(); err != nil {
+				return err
+			}
+		} else if err := r.value(); err != nil {
+			return err
+		}
+
+		after := r.space()
+		if r.peek() != ',' {
+			if next := r.peek(); next == '}' || next == ']' || r.pos == len(r.data) {
+				before = after
+				continue
+			}
+
+			// two values without a comma between them
+			r.out.WriteByte(',')
+			before = after
+			continue
+		}
+
+		for r.peek() == ',' {
+			r.pos++
+			after += r.space()
+		}
+
+		if next := r.peek(); next == '}' || next == ']' || r.pos == len(r.data) {
+			// a trailing comma
+			before = after
+			continue
+		}
+
+		r.out.WriteByte(',')
+		before = after
+	}
+}
+
+func (r *repairer) member() error {
+	switch char := r.peek(); {
+	case char == '"' || char == '\'':
+		r.string()
+	case isWordStart(char) || char >= '0' && char <= '9':
+		writeQuoted(&r.out, r.word())
+	default:
+		return r.errorf("expected a key, got %s", r.describe())
+	}
+
+	between := r.space()
+	// or a missing colon
+	if r.peek() == ':' || r.peek() == '=' {
+		r.pos++
+	}
+
+	r.out.WriteString(strings.TrimRight(between, " \t"))
+	r.out.WriteByte(':')
+	r.out.WriteString(r.space())
+	if r.pos == len(r.data) {
+		r.out.WriteString("null")
+		return nil
+	}
+
+	return r.value()
+}
+
+func isWordStart(char byte) bool {
+	return char == '_' || char == '$' || char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= utf8.RuneSelf
+}
+
+// an unquoted key or value, up to the next character json gives a meaning to
+func (r *repairer) word() string {
+	start := r.pos
+	for r.pos < len(r.data) && !strings.ContainsRune(" \t\r\n:=,{}[]\"'", rune(r.data[r.pos])) && !r.atComment() {
+		r.pos++
+	}
+
+	return string(r.data[start:r.pos])
+}
+
+func (r *repairer) atComment() bool {
+	return r.data[r.pos] == '#' || bytes.HasPrefix(r.data[r.pos:], []byte("//")) || bytes.HasPrefix(r.data[r.pos:], []byte("/*"))
+}
+
+func (r *repairer) number() {
+	start := r.pos
+	for r.pos < len(r.data) && strings.IndexByte("+-.0123456789eE", r.data[r.pos]) >= 0 {
+		r.pos++
+	}
+
+	number := strings.TrimPrefix(string(r.data[start:r.pos]), "+")
+	sign := ""
+	if strings.HasPrefix(number, "-") {
+		sign, number = "-", number[1:]
+	}
+
+	mantissa, exponent := number, ""
+	if i := strings.IndexAny(number, "eE"); i >= 0 {
+		mantissa, exponent = number[:i], number[i:]
+	}
+
+	if strings.HasSuffix(mantissa, ".") {
+		mantissa += "0"
+	}
+
+	mantissa = strings.TrimLeft(mantissa, "0")
+	if mantissa == "" || mantissa[0] == '.' {
+		mantissa = "0" + mantissa
+	}
+
+	r.out.WriteString(sign + mantissa + exponent)
+}
+
+// a string in single or double quotes, written in double quotes with control characters escaped
+func (r *repairer) string() {
+	quote := r.data[r.pos]
+	r.pos++
+
+	r.out.WriteByte('"')
+	for r.pos < len(r.data) && r.data[r.pos] != quote {
+		char := r.data[r.pos]
+		switch {
+		case char == '\\' && r.pos+1 < len(r.data):
+			if r.data[r.pos+1] != '\'' {
+				r.out.WriteByte('\\')
+			}
+
+			r.out.WriteByte(r.data[r.pos+1])
+			r.pos += 2
+			continue
+		case char == '"':
+			r.out.WriteString(`\"`)
+		case char == '\n':
+			r.out.WriteString(`\n`)
+		case char == '\r':
+			r.out.WriteString(`\r`)
+		case char == '\t':
+			r.out.WriteString(`\t`)
+		case char < 0x20:
+			fmt.Fprintf(&r.out, `\u%04x`, char)
+		default:
+			r.out.WriteByte(char)
+		}
+
+		r.pos++
+	}
+
+	// the closing quote, if there is one
+	r.pos = min(r.pos+1, len(r.data))
+	r.out.WriteByte('"')
+}
+
+func writeQuoted(out *bytes.Buffer, s string) {
+	quoted, _ := marshalJSON(s)
+	out.Write(quoted)
+}
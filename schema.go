@@ -0,0 +1,372 @@
+package orderedjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"strings"
+	"unicode"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// schema inference: merge any number of sample documents into a description of their shape, then
+// render it as JSON Schema or Go types. properties are always listed in the order they were first
+// seen in the samples.
+
+type inferredSchema struct {
+	// json schema type names, in the order they were seen
+	types []string
+
+	// objects
+	objects    int
+	properties *orderedmap.OrderedMap[string, *inferredSchema]
+	// how many of the sampled objects had each property
+	propertyCounts map[string]int
+
+	// arrays
+	items *inferredSchema
+}
+
+func newInferredSchema() *inferredSchema {
+	return &inferredSchema{
+		properties:     orderedmap.New[string, *inferredSchema](),
+		propertyCounts: make(map[string]int),
+	}
+}
+
+func (schema *inferredSchema) addType(name string) {
+	for _, t := range schema.types {
+		if t == name {
+			return
+		}
+	}
+
+	schema.types = append(schema.types, name)
+}
+
+func (schema *inferredSchema) hasType(name string) bool {
+	for _, t := range schema.types {
+		if t == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (schema *inferredSchema) add(value interface{}) {
+	switch v := value.(type) {
+	case *JsonObject:
+		schema.addType("object")
+		schema.objects++
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			property, present := schema.properties.Get(pair.Key)
+			if !present {
+				property = newInferredSchema()
+				schema.properties.Set(pair.Key, property)
+			}
+
+			property.add(pair.Value)
+			schema.propertyCounts[pair.Key]++
+		}
+	case []interface{}:
+		schema.addType("array")
+		if schema.items == nil {
+			schema.items = newInferredSchema()
+		}
+
+		for _, item := range v {
+			schema.items.add(item)
+		}
+	case string:
+		schema.addType("string")
+	case float64, json.Number:
+		if isInteger(v) {
+			schema.addType("integer")
+		} else {
+			schema.addType("number")
+		}
+	case bool:
+		schema.addType("boolean")
+	case nil:
+		schema.addType("null")
+	}
+}
+
+// the types to report, with integer folded into number when both were seen
+func (schema *inferredSchema) typeNames() []string {
+	names := make([]string, 0, len(schema.types))
+	for _, t := range schema.types {
+		if t == "integer" && schema.hasType("number") {
+			continue
+		}
+
+		names = append(names, t)
+	}
+
+	return names
+}
+
+func (schema *inferredSchema) required() []string {
+	required := make([]string, 0)
+	for pair := schema.properties.Oldest(); pair != nil; pair = pair.Next() {
+		if schema.propertyCounts[pair.Key] == schema.objects {
+			required = append(required, pair.Key)
+		}
+	}
+
+	return required
+}
+
+// InferSchema returns a JSON Schema (draft 2020-12) that all of the sample documents match.
+func InferSchema(docs ...interface{}) *JsonObject {
+	schema := newInferredSchema()
+	for _, doc := range docs {
+		schema.add(doc)
+	}
+
+	result := orderedmap.New[string, interface{}]()
+	result.Set("$schema", "https://json-schema.org/draft/2020-12/schema")
+	for pair := schema.toJSONSchema().Oldest(); pair != nil; pair = pair.Next() {
+		result.Set(pair.Key, pair.Value)
+	}
+
+	return result
+}
+
+func (schema *inferredSchema) toJSONSchema() *JsonObject {
+	result := orderedmap.New[string, interface{}]()
+
+	names := schema.typeNames()
+	switch len(names) {
+	case 0:
+		// an array that was always empty, anything goes
+		return result
+	case 1:
+		result.Set("type", names[0])
+	default:
+		types := make([]interface{}, 0, len(names))
+		for _, name := range names {
+			types = append(types, name)
+		}
+
+		result.Set("type", types)
+	}
+
+	if schema.hasType("object") {
+		properties := orderedmap.New[string, interface{}]()
+		for pair := schema.properties.Oldest(); pair != nil; pair = pair.Next() {
+			properties.Set(pair.Key, pair.Value.toJSONSchema())
+		}
+
+		result.Set("properties", properties)
+
+		if required := schema.required(); len(required) > 0 {
+			values := make([]interface{}, 0, len(required))
+			for _, name := range required {
+				values = append(values, name)
+			}
+
+			result.Set("required", values)
+		}
+	}
+
+	if schema.hasType("array") && schema.items != nil && len(schema.items.types) > 0 {
+		result.Set("items", schema.items.toJSONSchema())
+	}
+
+	return result
+}
+
+// InferGoStructs returns Go type declarations (without a package clause) that can hold all of the
+// sample documents. the top level type is called rootName and every nested object gets its own
+// named type, with fields in the same order as the keys.
+func InferGoStructs(rootName string, docs ...interface{}) (string, error) {
+	schema := newInferredSchema()
+	for _, doc := range docs {
+		schema.add(doc)
+	}
+
+	generator := &goTypeGenerator{names: make(map[string]bool)}
+	if schema.hasType("object") && len(schema.typeNames()) == 1 {
+		generator.declareStruct(exportedGoName(rootName), schema)
+	} else {
+		// the root type comes first here too, and nested structs can't take its name
+		name := generator.uniqueName(exportedGoName(rootName))
+		generator.decls = append(generator.decls, "")
+		generator.decls[0] = fmt.Sprintf("type %s %s", name, generator.goType(rootName, schema))
+	}
+
+	source, err := format.Source([]byte(strings.Join(generator.decls, "\n\n") + "\n"))
+	if err != nil {
+		return "", err
+	}
+
+	return string(source), nil
+}
+
+// GenerateGo returns a complete, gofmt'ed Go source file in package packageName with the types from
+// InferGoStructs.
+func GenerateGo(packageName, rootName string, docs ...interface{}) ([]byte, error) {
+	decls, err := InferGoStructs(rootName, docs...)
+	if err != nil {
+		return nil, err
+	}
+
+	source := fmt.Sprintf("// Code generated by ordered-json gen. DO NOT EDIT.\n\npackage %s\n\n%s", packageName, decls)
+	return format.Source([]byte(source))
+}
+
+type goTypeGenerator struct {
+	decls []string
+	names map[string]bool
+}
+
+// declares a struct type for an object schema, and returns the name it ended up with
+func (generator *goTypeGenerator) declareStruct(name string, schema *inferredSchema) string {
+	name = generator.uniqueName(name)
+
+	// reserve a slot so the parent type comes before its children
+	idx := len(generator.decls)
+	generator.decls = append(generator.decls, "")
+
+	required := make(map[string]bool)
+	for _, key := range schema.required() {
+		required[key] = true
+	}
+
+	fieldNames := make(map[string]bool)
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("type %s struct {\n", name))
+	for pair := schema.properties.Oldest(); pair != nil; pair = pair.Next() {
+		fieldName := exportedGoName(pair.Key)
+		for i := 2; fieldNames[fieldName]; i++ {
+			fieldName = fmt.Sprintf("%s%d", exportedGoName(pair.Key), i)
+		}
+		fieldNames[fieldName] = true
+
+		tag := pair.Key
+		if !required[pair.Key] {
+			tag += ",omitempty"
+		}
+
+		fieldType := generator.goType(pair.Key, pair.Value)
+		if !required[pair.Key] && pair.Value.isStruct() {
+			fieldType = "*" + fieldType
+		}
+
+		body.WriteString(fmt.Sprintf("\t%s %s `json:%q`\n", fieldName, fieldType, tag))
+	}
+	body.WriteString("}")
+
+	generator.decls[idx] = body.String()
+	return name
+}
+
+func (schema *inferredSchema) isStruct() bool {
+	names := schema.typeNames()
+	return len(names) == 1 && names[0] == "object"
+}
+
+func (generator *goTypeGenerator) goType(name string, schema *inferredSchema) string {
+	names := schema.typeNames()
+
+	nullable := false
+	if len(names) == 2 && schema.hasType("null") {
+		nullable = true
+		if names[0] == "null" {
+			names = names[1:]
+		} else {
+			names = names[:1]
+		}
+	}
+
+	if len(names) != 1 {
+		return "interface{}"
+	}
+
+	var goType string
+	switch names[0] {
+	case "string":
+		goType = "string"
+	case "integer":
+		goType = "int64"
+	case "number":
+		goType = "float64"
+	case "boolean":
+		goType = "bool"
+	case "null":
+		return "interface{}"
+	case "array":
+		if schema.items == nil || len(schema.items.types) == 0 {
+			return "[]interface{}"
+		}
+
+		return "[]" + generator.goType(singular(name), schema.items)
+	case "object":
+		goType = generator.declareStruct(exportedGoName(name), schema)
+	}
+
+	if nullable {
+		return "*" + goType
+	}
+
+	return goType
+}
+
+func (generator *goTypeGenerator) uniqueName(name string) string {
+	unique := name
+	for i := 2; generator.names[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+
+	generator.names[unique] = true
+	return unique
+}
+
+// turns a json key like "dev-dependencies" or "user_id" into DevDependencies and UserID
+func exportedGoName(key string) string {
+	var result strings.Builder
+	upperNext := true
+	for _, char := range key {
+		if !unicode.IsLetter(char) && !unicode.IsDigit(char) {
+			upperNext = true
+			continue
+		}
+
+		if upperNext {
+			result.WriteRune(unicode.ToUpper(char))
+			upperNext = false
+		} else {
+			result.WriteRune(char)
+		}
+	}
+
+	name := result.String()
+	for _, initialism := range []string{"Id", "Url", "Uri", "Api", "Http", "Json", "Html", "Ip", "Uuid"} {
+		if strings.HasSuffix(name, initialism) {
+			name = strings.TrimSuffix(name, initialism) + strings.ToUpper(initialism)
+		}
+	}
+
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "X" + name
+	}
+
+	return name
+}
+
+// good enough to name the element type of an array: "files" -> "file", "entries" -> "entry"
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 3:
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "ses") || strings.HasSuffix(name, "xes"):
+		return strings.TrimSuffix(name, "es")
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss") && len(name) > 1:
+		return strings.TrimSuffix(name, "s")
+	}
+
+	return name + "Item"
+}
@@ -0,0 +1,104 @@
+package orderedjson
+
+import (
+	"testing"
+)
+
+func parseAll(t *testing.T, inputs ...string) []interface{} {
+	t.Helper()
+	var docs []interface{}
+	for _, input := range inputs {
+		value, err := FromStdJSONValue([]byte(input))
+		if err != nil {
+			t.Fatalf("parsing %s: %v", input, err)
+		}
+
+		docs = append(docs, value)
+	}
+
+	return docs
+}
+
+func TestInferSchema(t *testing.T) {
+	tests := []struct {
+		docs []string
+		want string
+	}{
+		{[]string{`{"z":1,"a":"x","b":true,"n":null}`}, `{"type":"object","properties":{"z":{"type":"integer"},"a":{"type":"string"},"b":{"type":"boolean"},"n":{"type":"null"}},"required":["z","a","b","n"]}`},
+		// integers and other numbers are numbers, and properties that aren't everywhere aren't required
+		{[]string{`{"n":1,"a":1}`, `{"n":1.5,"b":2}`}, `{"type":"object","properties":{"n":{"type":"number"},"a":{"type":"integer"},"b":{"type":"integer"}},"required":["n"]}`},
+		{[]string{`{"v":"x"}`, `{"v":null}`, `{"v":1}`}, `{"type":"object","properties":{"v":{"type":["string","null","integer"]}},"required":["v"]}`},
+		{[]string{`[]`}, `{"type":"array"}`},
+		{[]string{`[[], [1]]`}, `{"type":"array","items":{"type":"array","items":{"type":"integer"}}}`},
+		{[]string{`[{"a":1,"b":{}}, {"b":{"c":"x"}}]`}, `{"type":"array","items":{"type":"object","properties":{"a":{"type":"integer"},"b":{"type":"object","properties":{"c":{"type":"string"}}}},"required":["b"]}}`},
+		{[]string{`{}`, `[]`, `"s"`}, `{"type":["object","array","string"],"properties":{}}`},
+		{[]string{`1e2`, `12345678901234567891`}, `{"type":"integer"}`},
+		{nil, `{}`},
+	}
+
+	for _, test := range tests {
+		schema := InferSchema(parseAll(t, test.docs...)...)
+		if schema.Oldest().Key != "$schema" || schema.Value("$schema") != "https://json-schema.org/draft/2020-12/schema" {
+			t.Errorf("InferSchema(%v) = %v, want $schema first", test.docs, schema)
+		}
+
+		schema.Delete("$schema")
+		if got, _ := MarshalCompact(schema); got != test.want {
+			t.Errorf("InferSchema(%v) = %s, want %s", test.docs, got, test.want)
+		}
+	}
+}
+
+const generatedUser = "// Code generated by ordered-json gen. DO NOT EDIT.\n\npackage models\n\n" + `type User struct {
+	UserID  int64         ` + "`json:\"user_id\"`" + `
+	Name    string        ` + "`json:\"name\"`" + `
+	HomeURL string        ` + "`json:\"home-url\"`" + `
+	Tags    []string      ` + "`json:\"tags\"`" + `
+	Address *Address      ` + "`json:\"address,omitempty\"`" + `
+	Entries []Entry       ` + "`json:\"entries\"`" + `
+	Score   float64       ` + "`json:\"score\"`" + `
+	Extra   *string       ` + "`json:\"extra\"`" + `
+	X2fa    bool          ` + "`json:\"2fa\"`" + `
+	Name2   string        ` + "`json:\"Name\"`" + `
+	Mixed   []interface{} ` + "`json:\"mixed,omitempty\"`" + `
+}
+
+type Address struct {
+	City string      ` + "`json:\"city\"`" + `
+	Zip  interface{} ` + "`json:\"zip\"`" + `
+}
+
+type Entry struct {
+	N int64  ` + "`json:\"n\"`" + `
+	M string ` + "`json:\"m,omitempty\"`" + `
+}
+`
+
+func TestGenerateGo(t *testing.T) {
+	docs := parseAll(t,
+		`{"user_id":1,"name":"a","home-url":"x","tags":["x"],"address":{"city":"c","zip":null},"entries":[{"n":1}],"score":1,"extra":null,"2fa":true,"Name":"dup"}`,
+		`{"user_id":2,"name":"b","home-url":"y","tags":[],"score":1.5,"entries":[{"n":2,"m":"x"}],"extra":"s","2fa":false,"Name":"d","mixed":[1,"a"]}`,
+	)
+
+	source, err := GenerateGo("models", "user", docs...)
+	if err != nil || string(source) != generatedUser {
+		t.Errorf("GenerateGo =\n%s\n%v\nwant\n%s", source, err, generatedUser)
+	}
+
+	tests := []struct {
+		rootName string
+		docs     []string
+		want     string
+	}{
+		{"rows", []string{`[{"id":1}]`}, "type Rows []Row\n\ntype Row struct {\n\tID int64 `json:\"id\"`\n}\n"},
+		{"entry", []string{`[{"entry":{"a":1}}]`}, "type Entry []EntryItem\n\ntype EntryItem struct {\n\tEntry Entry2 `json:\"entry\"`\n}\n\ntype Entry2 struct {\n\tA int64 `json:\"a\"`\n}\n"},
+		{"value", []string{`"s"`, `1`}, "type Value interface{}\n"},
+		{"doc", []string{`{"a":{"b":{}},"b":{"x":1}}`}, "type Doc struct {\n\tA A  `json:\"a\"`\n\tB B2 `json:\"b\"`\n}\n\ntype A struct {\n\tB B `json:\"b\"`\n}\n\ntype B struct {\n}\n\ntype B2 struct {\n\tX int64 `json:\"x\"`\n}\n"},
+	}
+
+	for _, test := range tests {
+		if got, err := InferGoStructs(test.rootName, parseAll(t, test.docs...)...); err != nil || got != test.want {
+			t.Errorf("InferGoStructs(%v) =\n%s\n%v\nwant\n%s", test.docs, got, err, test.want)
+		}
+	}
+}
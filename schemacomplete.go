@@ -0,0 +1,258 @@
+package orderedjson
+
+import (
+	"regexp"
+)
+
+// SchemaCompletion is a key or a value that the schema allows at some place in a document, for an
+// editor to offer.
+type SchemaCompletion struct {
+	// the property name, for keys
+	Key string
+	// the value, for values
+	Value interface{}
+	// the description of the property or value in the schema, or its title when it has no
+	// description
+	Description string
+	// whether the property is in the object's required list
+	Required bool
+}
+
+// SchemaCompletions are what can be written at a path: the keys the object there can have and the
+// values that can go there.
+type SchemaCompletions struct {
+	Keys   []SchemaCompletion
+	Values []SchemaCompletion
+}
+
+// CompleteSchema returns what schema allows at path in tree: the properties of the object at path
+// (leaving out the ones tree already has there), and the values from enum and const (with the
+// descriptions from enumDescriptions, which VS Code uses too), or true and false for booleans. both
+// are in the order the schema lists them. allOf, anyOf and oneOf are all followed, as are local
+// $refs. tree can be nil.
+func CompleteSchema(schema *JsonObject, tree *JsonObject, path Path) SchemaCompletions {
+	completer := &schemaCompleter{validator: &schemaValidator{root: schema}}
+	schemas := completer.expand(schema, nil)
+	for _, element := range path {
+		var next []*JsonObject
+		for _, current := range schemas {
+			next = append(next, completer.expand(completer.child(current, element), nil)...)
+		}
+		schemas = next
+	}
+
+	existing := objectAt(tree, path)
+
+	var result SchemaCompletions
+	seenKeys := make(map[string]bool)
+	for _, current := range schemas {
+		required := make(map[string]bool)
+		if names, ok := current.Get("required"); ok {
+			list, _ := names.([]interface{})
+			for _, name := range list {
+				if key, ok := name.(string); ok {
+					required[key] = true
+				}
+			}
+		}
+
+		properties, _ := current.Get("properties")
+		if object, ok := properties.(*JsonObject); ok {
+			for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+				if seenKeys[pair.Key] {
+					continue
+				}
+				seenKeys[pair.Key] = true
+
+				if existing != nil {
+					if _, present := existing.Get(pair.Key); present {
+						continue
+					}
+				}
+
+				result.Keys = append(result.Keys, SchemaCompletion{
+					Key:         pair.Key,
+					Description: completer.describe(pair.Value),
+					Required:    required[pair.Key],
+				})
+			}
+		}
+
+		result.Values = completer.values(current, result.Values)
+	}
+
+	return result
+}
+
+// the object at path in tree, or nil when tree doesn't have one there (yet, often, while it's being
+// written)
+func objectAt(tree *JsonObject, path Path) *JsonObject {
+	if tree == nil {
+		return nil
+	}
+
+	var value interface{} = tree
+	for _, element := range path {
+		switch v := value.(type) {
+		case *JsonObject:
+			key, _ := element.(string)
+			value, _ = v.Get(key)
+		case []interface{}:
+			i, ok := element.(int)
+			if !ok || i < 0 || i >= len(v) {
+				return nil
+			}
+			value = v[i]
+		default:
+			return nil
+		}
+	}
+
+	object, _ := value.(*JsonObject)
+	return object
+}
+
+type schemaCompleter struct {
+	validator *schemaValidator
+}
+
+// the schemas that apply where schema does: itself, what it $refs, and every allOf, anyOf and
+// oneOf branch. seen stops $ref cycles.
+func (completer *schemaCompleter) expand(schema interface{}, seen map[*JsonObject]bool) []*JsonObject {
+	object, ok := schema.(*JsonObject)
+	if !ok || seen[object] {
+		return nil
+	}
+
+	if seen == nil {
+		seen = make(map[*JsonObject]bool)
+	}
+	seen[object] = true
+
+	result := []*JsonObject{object}
+	if ref, ok := object.Get("$ref"); ok {
+		if target, err := completer.validator.resolve(ref); err == nil {
+			result = append(result, completer.expand(target, seen)...)
+		}
+	}
+
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		branches, _ := object.Get(keyword)
+		list, _ := branches.([]interface{})
+		for _, branch := range list {
+			result = append(result, completer.expand(branch, seen)...)
+		}
+	}
+
+	return result
+}
+
+// the schema of the property or item element in the values schema describes, or nil
+func (completer *schemaCompleter) child(schema *JsonObject, element interface{}) interface{} {
+	switch key := element.(type) {
+	case string:
+		properties, _ := schema.Get("properties")
+		if object, ok := properties.(*JsonObject); ok {
+			if property, ok := object.Get(key); ok {
+				return property
+			}
+		}
+
+		patterns, _ := schema.Get("patternProperties")
+		if object, ok := patterns.(*JsonObject); ok {
+			for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+				if re, err := regexp.Compile(pair.Key); err == nil && re.MatchString(key) {
+					return pair.Value
+				}
+			}
+		}
+
+		additional, _ := schema.Get("additionalProperties")
+		return additional
+	case int:
+		// prefixItems (2020-12), or items as an array like older drafts did it
+		for _, keyword := range []string{"prefixItems", "items"} {
+			tuple, _ := schema.Get(keyword)
+			if list, ok := tuple.([]interface{}); ok && key < len(list) {
+				return list[key]
+			}
+		}
+
+		items, _ := schema.Get("items")
+		if _, tuple := items.([]interface{}); tuple {
+			additional, _ := schema.Get("additionalItems")
+			return additional
+		}
+
+		return items
+	}
+
+	return nil
+}
+
+func (completer *schemaCompleter) describe(schema interface{}) string {
+	for _, current := range completer.expand(schema, nil) {
+		for _, keyword := range []string{"description", "title"} {
+			if text, ok := current.Get(keyword); ok {
+				if description, ok := text.(string); ok {
+					return description
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// adds the values schema allows to values, besides the ones already there
+func (completer *schemaCompleter) values(schema *JsonObject, values []SchemaCompletion) []SchemaCompletion {
+	add := func(value interface{}, description string) {
+		for _, existing := range values {
+			if Equal(existing.Value, value) {
+				return
+			}
+		}
+
+		values = append(values, SchemaCompletion{Value: value, Description: description})
+	}
+
+	if enum, ok := schema.Get("enum"); ok {
+		options, _ := enum.([]interface{})
+		descriptionList, _ := schema.Get("enumDescriptions")
+		descriptions, _ := descriptionList.([]interface{})
+		for i, option := range options {
+			description := ""
+			if i < len(descriptions) {
+				description, _ = descriptions[i].(string)
+			}
+
+			add(option, description)
+		}
+	}
+
+	if constant, ok := schema.Get("const"); ok {
+		add(constant, completer.describe(schema))
+	}
+
+	if types, ok := schema.Get("type"); ok && isBooleanType(types) {
+		add(true, "")
+		add(false, "")
+	}
+
+	return values
+}
+
+func isBooleanType(types interface{}) bool {
+	switch t := types.(type) {
+	case string:
+		return t == "boolean"
+	case []interface{}:
+		for _, name := range t {
+			if name == "boolean" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
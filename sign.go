@@ -0,0 +1,196 @@
+package orderedjson
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Sign signs the canonical form of tree (see Canonical) with key, and returns a detached JWS: the
+// header and signature of a compact JWS with the payload left out, "header..signature". the
+// document travels as it is, formatted however it likes, and Verify checks it against the
+// signature. the algorithm follows from the key:
+//
+//   - ed25519.PrivateKey: EdDSA
+//   - *ecdsa.PrivateKey on P-256, P-384 or P-521: ES256, ES384 or ES512
+//   - *rsa.PrivateKey: RS256
+func Sign(tree interface{}, key crypto.Signer) (string, error) {
+	alg, err := signatureAlgorithm(key.Public())
+	if err != nil {
+		return "", err
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": alg.name})
+	if err != nil {
+		return "", err
+	}
+
+	encodedHeader := base64.RawURLEncoding.EncodeToString(header)
+	input, err := signingInput(encodedHeader, tree)
+	if err != nil {
+		return "", err
+	}
+
+	digest := input
+	if alg.hash != 0 {
+		h := alg.hash.New()
+		h.Write(input)
+		digest = h.Sum(nil)
+	}
+
+	signature, err := key.Sign(rand.Reader, digest, alg.hash)
+	if err != nil {
+		return "", err
+	}
+
+	if ecKey, ok := key.Public().(*ecdsa.PublicKey); ok {
+		// JWS wants r and s next to each other instead of the ASN.1 that crypto.Signer gives
+		if signature, err = ecdsaRaw(signature, ecKey.Curve); err != nil {
+			return "", err
+		}
+	}
+
+	return encodedHeader + ".." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Verify checks a detached JWS from Sign against the canonical form of tree, so it doesn't matter how
+// the document was formatted or in which order its keys are since it was signed. the algorithm in
+// the header has to be the one for pub, a header can't pick a weaker one. it returns
+// ErrInvalidSignature when the signature doesn't match.
+func Verify(tree interface{}, signature string, pub crypto.PublicKey) error {
+	encodedHeader, encodedSignature, ok := strings.Cut(signature, "..")
+	if !ok {
+		return fmt.Errorf("%w: not a detached JWS, which looks like header..signature", ErrInvalidSignature)
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(encodedHeader)
+	if err != nil {
+		return fmt.Errorf("%w: header: %v", ErrInvalidSignature, err)
+	}
+
+	var header struct {
+		Alg  string   `json:"alg"`
+		Crit []string `json:"crit"`
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return fmt.Errorf("%w: header: %v", ErrInvalidSignature, err)
+	}
+
+	if len(header.Crit) > 0 {
+		return fmt.Errorf("%w: unsupported critical header parameters %v", ErrInvalidSignature, header.Crit)
+	}
+
+	alg, err := signatureAlgorithm(pub)
+	if err != nil {
+		return err
+	}
+
+	if header.Alg != alg.name {
+		return fmt.Errorf("%w: signed with %q, but the key is for %s", ErrInvalidSignature, header.Alg, alg.name)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	input, err := signingInput(encodedHeader, tree)
+	if err != nil {
+		return err
+	}
+
+	valid := false
+	switch key := pub.(type) {
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, input, sig)
+	case *ecdsa.PublicKey:
+		h := alg.hash.New()
+		h.Write(input)
+		size := curveBytes(key.Curve)
+		if len(sig) == 2*size {
+			r := new(big.Int).SetBytes(sig[:size])
+			s := new(big.Int).SetBytes(sig[size:])
+			valid = ecdsa.Verify(key, h.Sum(nil), r, s)
+		}
+	case *rsa.PublicKey:
+		h := alg.hash.New()
+		h.Write(input)
+		valid = rsa.VerifyPKCS1v15(key, alg.hash, h.Sum(nil), sig) == nil
+	}
+
+	if !valid {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+type jwsAlgorithm struct {
+	name string
+	// what the input is hashed with before signing, 0 for ed25519 which hashes on its own
+	hash crypto.Hash
+}
+
+func signatureAlgorithm(pub crypto.PublicKey) (jwsAlgorithm, error) {
+	switch key := pub.(type) {
+	case ed25519.PublicKey:
+		return jwsAlgorithm{name: "EdDSA"}, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return jwsAlgorithm{name: "ES256", hash: crypto.SHA256}, nil
+		case elliptic.P384():
+			return jwsAlgorithm{name: "ES384", hash: crypto.SHA384}, nil
+		case elliptic.P521():
+			return jwsAlgorithm{name: "ES512", hash: crypto.SHA512}, nil
+		}
+
+		return jwsAlgorithm{}, fmt.Errorf("unsupported curve %s", key.Curve.Params().Name)
+	case *rsa.PublicKey:
+		return jwsAlgorithm{name: "RS256", hash: crypto.SHA256}, nil
+	}
+
+	return jwsAlgorithm{}, fmt.Errorf("unsupported key type %T", pub)
+}
+
+// what's signed: the encoded header and the encoded canonical payload, with a dot in between
+func signingInput(encodedHeader string, tree interface{}) ([]byte, error) {
+	payload, err := Canonical(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(encodedHeader + "." + base64.RawURLEncoding.EncodeToString(payload)), nil
+}
+
+func curveBytes(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
+
+// converts an ASN.1 ECDSA signature to r and s as big endian numbers of the curve's size
+func ecdsaRaw(der []byte, curve elliptic.Curve) ([]byte, error) {
+	var parsed struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &parsed); err != nil {
+		return nil, fmt.Errorf("parsing ECDSA signature: %v", err)
+	}
+
+	size := curveBytes(curve)
+	raw := make([]byte, 2*size)
+	parsed.R.FillBytes(raw[:size])
+	parsed.S.FillBytes(raw[size:])
+	return raw, nil
+}
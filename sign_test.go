@@ -0,0 +1,133 @@
+package orderedjson
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func signingKeys(t *testing.T) []crypto.Signer {
+	t.Helper()
+	keys := []crypto.Signer{ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))}
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return append(keys, key)
+}
+
+func TestSignVerify(t *testing.T) {
+	tree, err := ParseOptions{}.Parse([]byte(`{"z":1,"a":{"y":[true,null],"b":"é"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same document, formatted differently and with its keys in another order
+	reordered, err := ParseOptions{}.Parse([]byte("{\n  \"a\": {\"b\": \"\\u00e9\", \"y\": [true, null]},\n  \"z\": 1.0\n}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := ParseOptions{}.Parse([]byte(`{"z":2,"a":{"y":[true,null],"b":"é"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range signingKeys(t) {
+		signature, err := Sign(tree, key)
+		if err != nil {
+			t.Fatalf("Sign(%T): %v", key, err)
+		}
+
+		header, encoded, ok := strings.Cut(signature, "..")
+		if !ok || strings.Contains(encoded, ".") {
+			t.Fatalf("Sign(%T) = %s, want header..signature", key, signature)
+		}
+
+		if err := Verify(tree, signature, key.Public()); err != nil {
+			t.Errorf("Verify(%T): %v", key, err)
+		}
+
+		if err := Verify(reordered, signature, key.Public()); err != nil {
+			t.Errorf("Verify(%T, reordered): %v", key, err)
+		}
+
+		if err := Verify(changed, signature, key.Public()); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Verify(%T, changed) = %v, want %v", key, err, ErrInvalidSignature)
+		}
+
+		// one byte of the signature changed
+		raw, _ := base64.RawURLEncoding.DecodeString(encoded)
+		raw[len(raw)/2] ^= 1
+		tampered := header + ".." + base64.RawURLEncoding.EncodeToString(raw)
+		if err := Verify(tree, tampered, key.Public()); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Verify(%T, tampered) = %v, want %v", key, err, ErrInvalidSignature)
+		}
+	}
+}
+
+func TestVerifyInvalid(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	signature, err := Sign("x", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, encoded, _ := strings.Cut(signature, "..")
+	if raw, _ := base64.RawURLEncoding.DecodeString(header); string(raw) != `{"alg":"EdDSA"}` {
+		t.Errorf("header = %s", raw)
+	}
+
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+	headerOf := func(json string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(json))
+	}
+
+	for _, signature := range []string{
+		"",
+		header + "." + encoded,
+		header + ".payload." + encoded,
+		"!!.." + encoded,
+		headerOf(`{"alg":"none"}`) + ".." + encoded,
+		headerOf(`{"alg":"ES256"}`) + ".." + encoded,
+		headerOf(`{"alg":"EdDSA","crit":["exp"]}`) + ".." + encoded,
+		headerOf(`[]`) + ".." + encoded,
+		header + "..!!",
+		header + "..",
+	} {
+		if err := Verify("x", signature, key.Public()); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Verify(%q) = %v, want %v", signature, err, ErrInvalidSignature)
+		}
+	}
+
+	if err := Verify("x", signature, other); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify(other key) = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	// keys that aren't supported are an error of their own, not a bad signature
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if _, err := Sign("x", ecKey); err == nil || errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Sign(P-224) = %v, want an unsupported curve", err)
+	}
+
+	if err := Verify("x", signature, "key"); err == nil || errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify(string key) = %v", err)
+	}
+}
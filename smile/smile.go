@@ -0,0 +1,818 @@
+// Package smile converts between ordered json trees and Smile, the binary json format Jackson (and
+// so Elasticsearch) speaks. objects are written and read in insertion order, so converting
+// json -> smile -> json gives back the same document.
+package smile
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"unicode/utf8"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type JsonObject = orderedmap.OrderedMap[string, interface{}]
+
+// the header is ":)\n" and a byte of version (always 0) and flags
+const (
+	flagSharedNames  = 0x01
+	flagSharedValues = 0x02
+	flagRawBinary    = 0x04
+)
+
+// value tokens
+const (
+	tokenEmptyString  = 0x20
+	tokenNull         = 0x21
+	tokenFalse        = 0x22
+	tokenTrue         = 0x23
+	tokenInt32        = 0x24
+	tokenInt64        = 0x25
+	tokenBigInteger   = 0x26
+	tokenFloat32      = 0x28
+	tokenFloat64      = 0x29
+	tokenBigDecimal   = 0x2a
+	tokenTinyASCII    = 0x40
+	tokenShortASCII   = 0x60
+	tokenTinyUnicode  = 0x80
+	tokenShortUnicode = 0xa0
+	tokenSmallInt     = 0xc0
+	tokenLongASCII    = 0xe0
+	tokenLongUnicode  = 0xe4
+	tokenBinary7Bit   = 0xe8
+	tokenLongShared   = 0xec
+	tokenStartArray   = 0xf8
+	tokenEndArray     = 0xf9
+	tokenStartObject  = 0xfa
+	tokenEndObject    = 0xfb
+	tokenEndString    = 0xfc
+	tokenRawBinary    = 0xfd
+	tokenEndContent   = 0xff
+)
+
+// key tokens
+const (
+	keyEmpty        = 0x20
+	keyLongShared   = 0x30
+	keyLongUnicode  = 0x34
+	keyShortShared  = 0x40
+	keyShortASCII   = 0x80
+	keyShortUnicode = 0xc0
+)
+
+// both shared string tables start over when they're full
+const maxShared = 1024
+
+// EncodeOptions are the header flags of the output.
+type EncodeOptions struct {
+	// refer back to object keys seen before instead of writing them again. Jackson does this by
+	// default, and it's what makes arrays of objects small.
+	SharedNames bool
+	// the same for string values of 64 bytes or less. Jackson leaves it off by default, since it
+	// costs time on the way out and most values don't repeat.
+	SharedValues bool
+}
+
+// Encode encodes any value from the tree (*JsonObject, []interface{}, string, float64, json.Number,
+// bool or nil) with Jackson's default settings: a header and shared key names.
+func Encode(value interface{}) ([]byte, error) {
+	return EncodeOptions{SharedNames: true}.Encode(value)
+}
+
+// Encode encodes value with these options. integral numbers become the smallest Smile int that
+// holds them (a big integer for json.Numbers past 64 bits), other numbers are a 32 bit float when
+// that's exact and a 64 bit one when it isn't.
+func (opts EncodeOptions) Encode(value interface{}) ([]byte, error) {
+	var flags byte
+	encoder := &encoder{}
+	if opts.SharedNames {
+		flags |= flagSharedNames
+		encoder.names = newSharedTable()
+	}
+	if opts.SharedValues {
+		flags |= flagSharedValues
+		encoder.values = newSharedTable()
+	}
+
+	encoder.buf = append(make([]byte, 0, 64), ':', ')', '\n', flags)
+	if err := encoder.appendValue(value); err != nil {
+		return nil, err
+	}
+
+	return encoder.buf, nil
+}
+
+// strings seen so far and their index, which both sides keep in step
+type sharedTable struct {
+	strings []string
+	index   map[string]int
+}
+
+func newSharedTable() *sharedTable {
+	return &sharedTable{index: make(map[string]int)}
+}
+
+func (table *sharedTable) add(s string) {
+	if len(table.strings) == maxShared {
+		table.strings = table.strings[:0]
+		clear(table.index)
+	}
+
+	table.index[s] = len(table.strings)
+	table.strings = append(table.strings, s)
+}
+
+type encoder struct {
+	buf    []byte
+	names  *sharedTable
+	values *sharedTable
+}
+
+func (encoder *encoder) appendValue(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		encoder.buf = append(encoder.buf, tokenNull)
+	case bool:
+		if v {
+			encoder.buf = append(encoder.buf, tokenTrue)
+		} else {
+			encoder.buf = append(encoder.buf, tokenFalse)
+		}
+	case string:
+		encoder.appendString(v)
+	case float64:
+		encoder.appendNumber(v)
+	case json.Number:
+		return encoder.appendJSONNumber(v)
+	case []interface{}:
+		encoder.buf = append(encoder.buf, tokenStartArray)
+		for _, item := range v {
+			if err := encoder.appendValue(item); err != nil {
+				return err
+			}
+		}
+		encoder.buf = append(encoder.buf, tokenEndArray)
+	case *JsonObject:
+		encoder.buf = append(encoder.buf, tokenStartObject)
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			encoder.appendKey(pair.Key)
+			if err := encoder.appendValue(pair.Value); err != nil {
+				return err
+			}
+		}
+		encoder.buf = append(encoder.buf, tokenEndObject)
+	default:
+		return fmt.Errorf("smile: cannot encode %T", value)
+	}
+
+	return nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (encoder *encoder) appendString(s string) {
+	if s == "" {
+		encoder.buf = append(encoder.buf, tokenEmptyString)
+		return
+	}
+
+	short := len(s) <= 64
+	if short && encoder.values != nil {
+		// a long reference whose second byte would be 0xfe or 0xff isn't allowed, so the string is
+		// written out again instead
+		if index, ok := encoder.values.index[s]; ok && index&0xff < 0xfe {
+			if index < 31 {
+				encoder.buf = append(encoder.buf, byte(index+1))
+			} else {
+				encoder.buf = append(encoder.buf, tokenLongShared|byte(index>>8), byte(index))
+			}
+
+			return
+		}
+
+		encoder.values.add(s)
+	}
+
+	ascii := isASCII(s)
+	switch {
+	case !short && ascii:
+		encoder.buf = append(append(append(encoder.buf, tokenLongASCII), s...), tokenEndString)
+	case !short:
+		encoder.buf = append(append(append(encoder.buf, tokenLongUnicode), s...), tokenEndString)
+	case ascii && len(s) <= 32:
+		encoder.buf = append(append(encoder.buf, tokenTinyASCII|byte(len(s)-1)), s...)
+	case ascii:
+		encoder.buf = append(append(encoder.buf, tokenShortASCII|byte(len(s)-33)), s...)
+	case len(s) <= 33:
+		encoder.buf = append(append(encoder.buf, tokenTinyUnicode|byte(len(s)-2)), s...)
+	default:
+		encoder.buf = append(append(encoder.buf, tokenShortUnicode|byte(len(s)-34)), s...)
+	}
+}
+
+func (encoder *encoder) appendKey(key string) {
+	if key == "" {
+		encoder.buf = append(encoder.buf, keyEmpty)
+		return
+	}
+
+	ascii := isASCII(key)
+	short := ascii && len(key) <= 64 || !ascii && len(key) <= 57
+	if short && encoder.names != nil {
+		if index, ok := encoder.names.index[key]; ok && index&0xff < 0xfe {
+			if index < 64 {
+				encoder.buf = append(encoder.buf, keyShortShared|byte(index))
+			} else {
+				encoder.buf = append(encoder.buf, keyLongShared|byte(index>>8), byte(index))
+			}
+
+			return
+		}
+
+		encoder.names.add(key)
+	}
+
+	switch {
+	case !short:
+		encoder.buf = append(append(append(encoder.buf, keyLongUnicode), key...), tokenEndString)
+	case ascii:
+		encoder.buf = append(append(encoder.buf, keyShortASCII|byte(len(key)-1)), key...)
+	default:
+		encoder.buf = append(append(encoder.buf, keyShortUnicode|byte(len(key)-2)), key...)
+	}
+}
+
+func (encoder *encoder) appendNumber(v float64) {
+	// -0 is left to the floats, since the ints don't have it
+	if v == math.Trunc(v) && !math.IsInf(v, 0) && math.Abs(v) <= 1<<53 && !(v == 0 && math.Signbit(v)) {
+		encoder.appendInt(int64(v))
+		return
+	}
+
+	if f32 := float32(v); float64(f32) == v || math.IsNaN(v) {
+		encoder.buf = append7Bit(append(encoder.buf, tokenFloat32), uint64(math.Float32bits(f32)), 5)
+		return
+	}
+
+	encoder.buf = append7Bit(append(encoder.buf, tokenFloat64), math.Float64bits(v), 10)
+}
+
+func (encoder *encoder) appendInt(n int64) {
+	switch {
+	case n >= -16 && n <= 15:
+		encoder.buf = append(encoder.buf, tokenSmallInt|byte(zigzag(n)))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		encoder.buf = appendVInt(append(encoder.buf, tokenInt32), zigzag(n))
+	default:
+		encoder.buf = appendVInt(append(encoder.buf, tokenInt64), zigzag(n))
+	}
+}
+
+// integers keep all their digits: an int when they fit in 64 bits and a big integer when they don't
+func (encoder *encoder) appendJSONNumber(v json.Number) error {
+	if n, err := v.Int64(); err == nil {
+		encoder.appendInt(n)
+		return nil
+	}
+
+	if n, ok := new(big.Int).SetString(string(v), 10); ok {
+		raw := bigIntBytes(n)
+		encoder.buf = append7BitBinary(appendVInt(append(encoder.buf, tokenBigInteger), uint64(len(raw))), raw)
+		return nil
+	}
+
+	f, err := v.Float64()
+	if err != nil {
+		return fmt.Errorf("smile: %q isn't a number", string(v))
+	}
+
+	encoder.appendNumber(f)
+	return nil
+}
+
+func zigzag(n int64) uint64 {
+	return uint64(n<<1) ^ uint64(n>>63)
+}
+
+func unzigzag(n uint64) int64 {
+	return int64(n>>1) ^ -int64(n&1)
+}
+
+// Smile's variable length ints: 7 bits a byte, most significant first, except the last byte which
+// has the high bit set and only 6 bits
+func appendVInt(buf []byte, n uint64) []byte {
+	var groups [10]byte
+	i := len(groups) - 1
+	groups[i] = 0x80 | byte(n&0x3f)
+	for n >>= 6; n > 0; n >>= 7 {
+		i--
+		groups[i] = byte(n & 0x7f)
+	}
+
+	return append(buf, groups[i:]...)
+}
+
+// the low bits of n in size bytes of 7 bits each, most significant first
+func append7Bit(buf []byte, n uint64, size int) []byte {
+	for i := size - 1; i >= 0; i-- {
+		buf = append(buf, byte(n>>(7*i))&0x7f)
+	}
+
+	return buf
+}
+
+// raw in 7 bits a byte, the way read7BitBinary reads it
+func append7BitBinary(buf []byte, raw []byte) []byte {
+	for len(raw) > 0 {
+		chunk := min(len(raw), 7)
+		var bits uint64
+		for _, b := range raw[:chunk] {
+			bits = bits<<8 | uint64(b)
+		}
+
+		if chunk == 7 {
+			buf = append7Bit(buf, bits, 8)
+		} else {
+			buf = append(append7Bit(buf, bits>>chunk, chunk), byte(bits&(1<<chunk-1)))
+		}
+
+		raw = raw[chunk:]
+	}
+
+	return buf
+}
+
+// Decode decodes a single Smile value. the header is optional; without one, keys may be shared and
+// values may not, like Jackson assumes. binary values become base64 strings, integers too big for a
+// float64 to hold exactly become json.Numbers with all their digits, and big decimals become
+// (possibly rounded) numbers.
+func Decode(data []byte) (interface{}, error) {
+	decoder := &decoder{data: data, names: newSharedTable()}
+	if bytes.HasPrefix(data, []byte(":)\n")) {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("smile: header is missing its flags")
+		}
+
+		flags := data[3]
+		if version := flags >> 4; version != 0 {
+			return nil, fmt.Errorf("smile: unsupported version %d", version)
+		}
+
+		if flags&flagSharedNames == 0 {
+			decoder.names = nil
+		}
+		if flags&flagSharedValues != 0 {
+			decoder.values = newSharedTable()
+		}
+
+		decoder.idx = 4
+	}
+
+	value, err := decoder.decodeValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if decoder.idx < len(data) && data[decoder.idx] == tokenEndContent {
+		decoder.idx++
+	}
+
+	if decoder.idx != len(data) {
+		return nil, fmt.Errorf("smile: %d trailing bytes after value", len(data)-decoder.idx)
+	}
+
+	return value, nil
+}
+
+type decoder struct {
+	data   []byte
+	idx    int
+	depth  int
+	names  *sharedTable
+	values *sharedTable
+}
+
+// returned by decodeValue for the end of an array
+type endArray struct{}
+
+func (decoder *decoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("smile: %s at offset %d", fmt.Sprintf(format, args...), decoder.idx)
+}
+
+func (decoder *decoder) read(n int) ([]byte, error) {
+	if n < 0 || decoder.idx+n > len(decoder.data) {
+		return nil, decoder.errorf("unexpected end of data")
+	}
+
+	result := decoder.data[decoder.idx : decoder.idx+n]
+	decoder.idx += n
+	return result, nil
+}
+
+func (decoder *decoder) readByte() (byte, error) {
+	b, err := decoder.read(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return b[0], nil
+}
+
+// reads text up to the end of string marker
+func (decoder *decoder) readLongString() (string, error) {
+	end := bytes.IndexByte(decoder.data[decoder.idx:], tokenEndString)
+	if end < 0 {
+		return "", decoder.errorf("unterminated string")
+	}
+
+	text, _ := decoder.read(end)
+	decoder.idx++
+	return decoder.checkUTF8(text)
+}
+
+func (decoder *decoder) checkUTF8(text []byte) (string, error) {
+	if !utf8.Valid(text) {
+		return "", decoder.errorf("invalid utf-8 in string")
+	}
+
+	return string(text), nil
+}
+
+func (decoder *decoder) readVInt() (uint64, error) {
+	var n uint64
+	for i := 0; i < 10; i++ {
+		b, err := decoder.readByte()
+		if err != nil {
+			return 0, err
+		}
+
+		if b&0x80 != 0 {
+			return n<<6 | uint64(b&0x3f), nil
+		}
+
+		n = n<<7 | uint64(b)
+	}
+
+	return 0, decoder.errorf("variable length int is too long")
+}
+
+func (decoder *decoder) read7Bit(size int) (uint64, error) {
+	raw, err := decoder.read(size)
+	if err != nil {
+		return 0, err
+	}
+
+	var n uint64
+	for _, b := range raw {
+		n = n<<7 | uint64(b&0x7f)
+	}
+
+	return n, nil
+}
+
+// reads a length and that many bytes, 7 bits to a byte: each 7 bytes take 8, and the last bits are
+// right aligned in the last byte
+func (decoder *decoder) read7BitBinary() ([]byte, error) {
+	length, err := decoder.readVInt()
+	if err != nil {
+		return nil, err
+	}
+
+	if length > uint64(len(decoder.data)) {
+		return nil, decoder.errorf("binary length %d is too large", length)
+	}
+
+	result := make([]byte, 0, length)
+	for remaining := int(length); remaining > 0; remaining -= 7 {
+		chunk := min(remaining, 7)
+		raw, err := decoder.read(chunk + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		// the last byte of a partial chunk only holds the chunk bits that are left over
+		var bits uint64
+		for i, b := range raw {
+			if i == chunk && chunk < 7 {
+				bits = bits<<chunk | uint64(b&(1<<chunk-1))
+				continue
+			}
+
+			bits = bits<<7 | uint64(b&0x7f)
+		}
+
+		for i := chunk - 1; i >= 0; i-- {
+			result = append(result, byte(bits>>(8*i)))
+		}
+	}
+
+	return result, nil
+}
+
+func (decoder *decoder) decodeValue() (interface{}, error) {
+	decoder.depth++
+	defer func() { decoder.depth-- }()
+
+	if decoder.depth > 10000 {
+		return nil, decoder.errorf("nesting too deep")
+	}
+
+	token, err := decoder.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case token >= 0x01 && token <= 0x1f:
+		return decoder.sharedValue(int(token) - 1)
+	case token >= tokenTinyASCII && token < tokenSmallInt:
+		var length int
+		switch token & 0xe0 {
+		case tokenTinyASCII:
+			length = int(token&0x1f) + 1
+		case tokenShortASCII:
+			length = int(token&0x1f) + 33
+		case tokenTinyUnicode:
+			length = int(token&0x1f) + 2
+		default:
+			length = int(token&0x1f) + 34
+		}
+
+		raw, err := decoder.read(length)
+		if err != nil {
+			return nil, err
+		}
+
+		text, err := decoder.checkUTF8(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		if decoder.values != nil {
+			decoder.values.add(text)
+		}
+
+		return text, nil
+	case token >= tokenSmallInt && token < tokenLongASCII:
+		return float64(unzigzag(uint64(token & 0x1f))), nil
+	case token >= tokenLongShared && token <= tokenLongShared|3:
+		low, err := decoder.readByte()
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.sharedValue(int(token&3)<<8 | int(low))
+	}
+
+	switch token {
+	case tokenEmptyString:
+		return "", nil
+	case tokenNull:
+		return nil, nil
+	case tokenFalse:
+		return false, nil
+	case tokenTrue:
+		return true, nil
+	case tokenInt32, tokenInt64:
+		n, err := decoder.readVInt()
+		if err != nil {
+			return nil, err
+		}
+
+		return integer(big.NewInt(unzigzag(n))), nil
+	case tokenBigInteger:
+		raw, err := decoder.read7BitBinary()
+		if err != nil {
+			return nil, err
+		}
+
+		return integer(signedBigInt(raw)), nil
+	case tokenBigDecimal:
+		scale, err := decoder.readVInt()
+		if err != nil {
+			return nil, err
+		}
+
+		raw, err := decoder.read7BitBinary()
+		if err != nil {
+			return nil, err
+		}
+
+		// unscaled * 10^-scale, through the decimal string so it rounds once
+		text := fmt.Sprintf("%se%d", signedBigInt(raw), -unzigzag(scale))
+		n, _, err := big.ParseFloat(text, 10, 53, big.ToNearestEven)
+		if err != nil {
+			return nil, decoder.errorf("invalid big decimal")
+		}
+
+		result, _ := n.Float64()
+		return result, nil
+	case tokenFloat32:
+		bits, err := decoder.read7Bit(5)
+		if err != nil {
+			return nil, err
+		}
+
+		return float64(math.Float32frombits(uint32(bits))), nil
+	case tokenFloat64:
+		bits, err := decoder.read7Bit(10)
+		if err != nil {
+			return nil, err
+		}
+
+		return math.Float64frombits(bits), nil
+	case tokenLongASCII, tokenLongUnicode:
+		return decoder.readLongString()
+	case tokenBinary7Bit:
+		raw, err := decoder.read7BitBinary()
+		if err != nil {
+			return nil, err
+		}
+
+		return base64.StdEncoding.EncodeToString(raw), nil
+	case tokenRawBinary:
+		length, err := decoder.readVInt()
+		if err != nil {
+			return nil, err
+		}
+
+		if length > uint64(len(decoder.data)) {
+			return nil, decoder.errorf("binary length %d is too large", length)
+		}
+
+		raw, err := decoder.read(int(length))
+		if err != nil {
+			return nil, err
+		}
+
+		return base64.StdEncoding.EncodeToString(raw), nil
+	case tokenStartArray:
+		result := make([]interface{}, 0)
+		for {
+			item, err := decoder.decodeValue()
+			if err != nil {
+				return nil, err
+			}
+
+			if _, done := item.(endArray); done {
+				return result, nil
+			}
+
+			result = append(result, item)
+		}
+	case tokenEndArray:
+		if decoder.depth == 1 {
+			return nil, decoder.errorf("unexpected end of array")
+		}
+
+		return endArray{}, nil
+	case tokenStartObject:
+		return decoder.decodeObject()
+	}
+
+	return nil, decoder.errorf("unexpected token 0x%02x", token)
+}
+
+func (decoder *decoder) sharedValue(index int) (interface{}, error) {
+	if decoder.values == nil {
+		return nil, decoder.errorf("shared value reference, but shared values are off")
+	}
+
+	if index >= len(decoder.values.strings) {
+		return nil, decoder.errorf("shared value reference %d is out of range", index)
+	}
+
+	return decoder.values.strings[index], nil
+}
+
+func (decoder *decoder) decodeObject() (interface{}, error) {
+	result := orderedmap.New[string, interface{}]()
+	for {
+		token, err := decoder.readByte()
+		if err != nil {
+			return nil, err
+		}
+
+		var key string
+		switch {
+		case token == tokenEndObject:
+			return result, nil
+		case token == keyEmpty:
+		case token >= keyLongShared && token <= keyLongShared|3:
+			low, err := decoder.readByte()
+			if err != nil {
+				return nil, err
+			}
+
+			if key, err = decoder.sharedName(int(token&3)<<8 | int(low)); err != nil {
+				return nil, err
+			}
+		case token == keyLongUnicode:
+			if key, err = decoder.readLongString(); err != nil {
+				return nil, err
+			}
+		case token >= keyShortShared && token < keyShortASCII:
+			if key, err = decoder.sharedName(int(token & 0x3f)); err != nil {
+				return nil, err
+			}
+		case token >= keyShortASCII && token <= 0xf7:
+			length := int(token&0x3f) + 1
+			if token >= keyShortUnicode {
+				length++
+			}
+
+			raw, err := decoder.read(length)
+			if err != nil {
+				return nil, err
+			}
+
+			if key, err = decoder.checkUTF8(raw); err != nil {
+				return nil, err
+			}
+
+			if decoder.names != nil {
+				decoder.names.add(key)
+			}
+		default:
+			return nil, decoder.errorf("unexpected key token 0x%02x", token)
+		}
+
+		value, err := decoder.decodeValue()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := value.(endArray); ok {
+			return nil, decoder.errorf("object is missing a value")
+		}
+
+		result.Set(key, value)
+	}
+}
+
+func (decoder *decoder) sharedName(index int) (string, error) {
+	if decoder.names == nil {
+		return "", decoder.errorf("shared key reference, but shared keys are off")
+	}
+
+	if index >= len(decoder.names.strings) {
+		return "", decoder.errorf("shared key reference %d is out of range", index)
+	}
+
+	return decoder.names.strings[index], nil
+}
+
+// n as a float64, or a json.Number past 2^53 where a float64 would lose digits
+func integer(n *big.Int) interface{} {
+	if n.IsInt64() && n.Int64() >= -(1<<53) && n.Int64() <= 1<<53 {
+		return float64(n.Int64())
+	}
+
+	return json.Number(n.String())
+}
+
+// a big endian two's complement integer, the way Java's BigInteger.toByteArray writes it
+func signedBigInt(raw []byte) *big.Int {
+	n := new(big.Int).SetBytes(raw)
+	if len(raw) > 0 && raw[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(8*len(raw))))
+	}
+
+	return n
+}
+
+// the other way around: the fewest bytes that hold n and its sign
+func bigIntBytes(n *big.Int) []byte {
+	if n.Sign() >= 0 {
+		raw := n.Bytes()
+		if len(raw) == 0 || raw[0]&0x80 != 0 {
+			raw = append([]byte{0}, raw...)
+		}
+
+		return raw
+	}
+
+	// -n-1 with every bit flipped is n
+	raw := new(big.Int).Sub(new(big.Int).Neg(n), big.NewInt(1)).Bytes()
+	if len(raw) == 0 || raw[0]&0x80 != 0 {
+		raw = append([]byte{0}, raw...)
+	}
+
+	for i := range raw {
+		raw[i] = ^raw[i]
+	}
+
+	return raw
+}
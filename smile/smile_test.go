@@ -0,0 +1,295 @@
+package smile
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/michaelhelvey/orderedjson/v2/internal/testtree"
+)
+
+// names and short values that repeat are written as references to where they were first, when
+// sharing them is on
+func TestRoundTrip(t *testing.T) {
+	long := strings.Repeat("x", 65)
+	tests := []struct {
+		json  string
+		opts  EncodeOptions
+		smile string
+	}{
+		{`[{"a":"x"},{"a":"x"}]`, EncodeOptions{}, "3a290a00" + "f8" + "fa806140" + "78fb" + "fa806140" + "78fb" + "f9"},
+		{`[{"a":"x"},{"a":"x"}]`, EncodeOptions{SharedNames: true}, "3a290a01" + "f8" + "fa806140" + "78fb" + "fa4040" + "78fb" + "f9"},
+		{`[{"a":"x"},{"a":"x"}]`, EncodeOptions{SharedNames: true, SharedValues: true}, "3a290a03" + "f8" + "fa806140" + "78fb" + "fa4001fb" + "f9"},
+		// each name and value gets the next index
+		{`{"a":"x","b":"y","c":{"b":"y","a":"x"}}`, EncodeOptions{SharedNames: true, SharedValues: true}, "3a290a03" + "fa" + "806140" + "78" + "806240" + "79" + "8063" + "fa" + "4102" + "4001" + "fb" + "fb"},
+		// values are only shared when they're short, and names that are the same as values aren't
+		// references to them
+		{`["` + long + `","` + long + `",{"x":"x"}]`, EncodeOptions{SharedNames: true, SharedValues: true}, "3a290a03" + "f8" + "e0" + strings.Repeat("78", 65) + "fc" + "e0" + strings.Repeat("78", 65) + "fc" + "fa807840" + "78fb" + "f9"},
+	}
+
+	for _, test := range tests {
+		data, err := test.opts.Encode(testtree.Parse(t, test.json))
+		if err != nil {
+			t.Errorf("%+v.Encode(%.40s): %v", test.opts, test.json, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(data); got != test.smile {
+			t.Errorf("%+v.Encode(%.40s) = %s, want %s", test.opts, test.json, got, test.smile)
+		}
+
+		if decoded, err := Decode(data); err != nil || testtree.Marshal(t, decoded) != test.json {
+			t.Errorf("Decode(%+v.Encode(%.40s)) = %.40s, %v", test.opts, test.json, testtree.Marshal(t, decoded), err)
+		}
+	}
+
+	// past 64 names, references take two bytes
+	var keys []string
+	for i := 0; i < 70; i++ {
+		keys = append(keys, fmt.Sprintf(`"k%d":%d`, i, i%16))
+	}
+
+	input := `[{` + strings.Join(keys, ",") + `},{"k0":0,"k65":1}]`
+	data, err := Encode(testtree.Parse(t, input))
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	if !strings.HasSuffix(hex.EncodeToString(data), "fa40c03041c2fbf9") {
+		t.Errorf("Encode(70 names) ends in %x, want the references 40 and 3041", data[len(data)-8:])
+	}
+
+	if decoded, err := Decode(data); err != nil || testtree.Marshal(t, decoded) != input {
+		t.Errorf("Decode(Encode(70 names)) = %.40s, %v", testtree.Marshal(t, decoded), err)
+	}
+}
+
+// more names and values than the shared tables hold, so they start over
+func TestSharedTableFull(t *testing.T) {
+	var items []string
+	for i := 0; i < 1500; i++ {
+		items = append(items, `{"key`+strings.Repeat("x", i%3)+hex.EncodeToString([]byte{byte(i >> 8), byte(i)})+`":"v`+hex.EncodeToString([]byte{byte(i % 700)})+`"}`)
+	}
+
+	input := `[` + strings.Join(items, ",") + `]`
+	data, err := EncodeOptions{SharedNames: true, SharedValues: true}.Encode(testtree.Parse(t, input))
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	decoded, err := Decode(data)
+	if err != nil || testtree.Marshal(t, decoded) != input {
+		t.Errorf("Decode(Encode(...)) = %.40s, %v", testtree.Marshal(t, decoded), err)
+	}
+}
+
+// the token layout of the Smile format specification, with the header Encode writes
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		json  string
+		smile string
+	}{
+		{`null`, "21"},
+		{`false`, "22"},
+		{`true`, "23"},
+		{`0`, "c0"},
+		{`-1`, "c1"},
+		{`1`, "c2"},
+		{`15`, "de"},
+		{`-16`, "df"},
+		{`16`, "24a0"},
+		{`-17`, "24a1"},
+		{`100`, "240388"},
+		{`2147483647`, "241f7f7f7fbe"},
+		{`-2147483648`, "241f7f7f7fbf"},
+		{`2147483648`, "252000000080"},
+		{`1.5`, "28037e000000"},
+		{`0.1`, "29003f5c6633194c66331a"},
+		{`""`, "20"},
+		{`"a"`, "4061"},
+		{`"` + strings.Repeat("a", 32) + `"`, "5f" + strings.Repeat("61", 32)},
+		{`"` + strings.Repeat("a", 33) + `"`, "60" + strings.Repeat("61", 33)},
+		{`"` + strings.Repeat("a", 65) + `"`, "e0" + strings.Repeat("61", 65) + "fc"},
+		{`"ü"`, "80c3bc"},
+		{`[]`, "f8f9"},
+		{`[1,[2]]`, "f8c2f8c4f9f9"},
+		{`{}`, "fafb"},
+		{`{"a":1,"":{}}`, "fa8061c220fafbfb"},
+		{`{"ü":"b"}`, "fac0c3bc4062fb"},
+		{`[{"a":1},{"a":2}]`, "f8fa8061c2fbfa40c4fbf9"},
+	}
+
+	for _, test := range tests {
+		data, err := Encode(testtree.Parse(t, test.json))
+		if err != nil {
+			t.Errorf("Encode(%.40s): %v", test.json, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(data); got != "3a290a01"+test.smile {
+			t.Errorf("Encode(%.40s) = %.40s, want 3a290a01%.40s", test.json, got, test.smile)
+		}
+
+		raw, _ := hex.DecodeString("3a290a01" + test.smile)
+		decoded, err := Decode(raw)
+		if err != nil {
+			t.Errorf("Decode(%.40s): %v", test.smile, err)
+			continue
+		}
+
+		if got := testtree.Marshal(t, decoded); got != test.json {
+			t.Errorf("Decode(%.40s) = %.40s, want %.40s", test.smile, got, test.json)
+		}
+	}
+}
+
+// what Decode makes of the things Encode doesn't write
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		smile string
+		json  string
+	}{
+		{"c2", `1`},
+		{"3a290a00c2ff", `1`},
+		{"3a290a00fa8061c28062c4fb", `{"a":1,"b":2}`},
+		{"3a290a03f841616201f9", `["ab","ab"]`},
+		{"f8416162e0616263fcf9", `["ab","abc"]`},
+		{"fa340102fcc2fb", `{"\u0001\u0002":1}`},
+		{"e88300404003", `"AQID"`},
+		{"fd83010203", `"AQID"`},
+		{"26810001", `1`},
+		{"26890040000000000000000000", `18446744073709551616`},
+		{"2a84810001", `0.01`},
+	}
+
+	for _, test := range tests {
+		raw, _ := hex.DecodeString(test.smile)
+		decoded, err := Decode(raw)
+		if err != nil {
+			t.Errorf("Decode(%s): %v", test.smile, err)
+			continue
+		}
+
+		if got := testtree.Marshal(t, decoded); got != test.json {
+			t.Errorf("Decode(%s) = %s, want %s", test.smile, got, test.json)
+		}
+	}
+}
+
+// integers past 2^53 are json.Numbers, since a float64 would lose digits, and past 64 bits they're
+// big integers
+func TestBigIntegers(t *testing.T) {
+	tests := []struct {
+		number json.Number
+		smile  string
+	}{
+		{"9007199254740993", "254000000000000082"},
+		{"-9223372036854775808", "25037f7f7f7f7f7f7f7fbf"},
+		{"9223372036854775808", "26890020000000000000000000"},
+		{"18446744073709551616", "26890040000000000000000000"},
+		{"-18446744073709551617", "26897f3f7f7f7f7f7f7f7f7f03"},
+	}
+
+	for _, test := range tests {
+		data, err := EncodeOptions{}.Encode(test.number)
+		if err != nil {
+			t.Errorf("Encode(%s): %v", test.number, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(data); got != "3a290a00"+test.smile {
+			t.Errorf("Encode(%s) = %s, want 3a290a00%s", test.number, got, test.smile)
+		}
+
+		if decoded, err := Decode(data); err != nil || decoded != test.number {
+			t.Errorf("Decode(Encode(%s)) = %#v, %v", test.number, decoded, err)
+		}
+	}
+
+	// the json.Numbers that aren't integers are written like float64s
+	if data, err := (EncodeOptions{}).Encode(json.Number("1.5")); err != nil || hex.EncodeToString(data) != "3a290a0028037e000000" {
+		t.Errorf("Encode(json.Number(1.5)) = %x, %v", data, err)
+	}
+}
+
+func TestSpecialFloats(t *testing.T) {
+	tests := []struct {
+		value float64
+		smile string
+	}{
+		{math.Inf(1), "28077c000000"},
+		{math.Inf(-1), "280f7c000000"},
+		{math.Copysign(0, -1), "280800000000"},
+	}
+
+	for _, test := range tests {
+		data, err := EncodeOptions{}.Encode(test.value)
+		if err != nil {
+			t.Errorf("Encode(%v): %v", test.value, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(data[4:]); got != test.smile {
+			t.Errorf("Encode(%v) = %s, want %s", test.value, got, test.smile)
+		}
+
+		decoded, err := Decode(data)
+		if f, ok := decoded.(float64); err != nil || !ok || f != test.value || math.Signbit(f) != math.Signbit(test.value) {
+			t.Errorf("Decode(Encode(%v)) = %v, %v", test.value, decoded, err)
+		}
+	}
+
+	data, err := Encode(math.NaN())
+	if err != nil || data[4] != tokenFloat32 {
+		t.Fatalf("Encode(NaN) = %x, %v, want a 32 bit float", data, err)
+	}
+
+	if decoded, err := Decode(data); err != nil || !math.IsNaN(decoded.(float64)) {
+		t.Errorf("Decode(Encode(NaN)) = %v, %v", decoded, err)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"3a290a",
+		"3a290a10c0",
+		"24",
+		"247f7f7f7f7f7f7f7f7f7f",
+		"29003f",
+		"41",
+		"4280",
+		"e06161",
+		"f8c2",
+		"f9",
+		"fa8061",
+		"fa8061f9",
+		"fa40c2fb",
+		"3a290a00fa40c2fb",
+		"fa30ffc2fb",
+		"01",
+		"3a290a03ec05",
+		"fac0c3fb",
+		"fa17c2fb",
+		"e883004040",
+		"e8ff7f7f7f7f7f7f7f7f80",
+		"fd8501",
+		"c2c2",
+		"2b",
+		"f0",
+	} {
+		raw, _ := hex.DecodeString(input)
+		if value, err := Decode(raw); err == nil {
+			t.Errorf("Decode(%s) = %v, want an error", input, value)
+		}
+	}
+
+	for _, value := range []interface{}{json.Number("1x"), 1, map[string]interface{}{}} {
+		if data, err := Encode(value); err == nil {
+			t.Errorf("Encode(%#v) = %x, want an error", value, data)
+		}
+	}
+}
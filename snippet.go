@@ -0,0 +1,123 @@
+package orderedjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// how many lines to show before and after the one with the error
+const snippetContext = 2
+
+// longer lines are cut down to the part around the error, minified documents are one long line
+const snippetWidth = 100
+
+// FormatError describes err like a compiler would. for a syntax error that's the message followed by
+// the lines of data around it, with a ^ under where the problem is:
+//
+//	invalid token x, expected a value at line 3, column 8
+//	  |
+//	1 | {
+//	2 |   "a": 1,
+//	3 |   "b": x
+//	  |        ^
+//	4 | }
+//
+// data is the text that was parsed. other errors are just their message.
+func FormatError(data []byte, err error) string {
+	var syntax *SyntaxError
+	if !errors.As(err, &syntax) {
+		return err.Error()
+	}
+
+	return err.Error() + "\n" + syntax.Snippet(data)
+}
+
+// Snippet returns the lines of data around the error with a ^ under the column it's at, each line
+// starting with its number.
+func (err *SyntaxError) Snippet(data []byte) string {
+	// offsets are counted after the byte order mark
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	lines := strings.Split(string(data), "\n")
+	index := min(max(err.Line-1, 0), len(lines)-1)
+	first := max(index-snippetContext, 0)
+	last := min(index+snippetContext, len(lines)-1)
+
+	// a newline at the end doesn't start another line worth showing
+	if last > index && last == len(lines)-1 && lines[last] == "" {
+		last--
+	}
+
+	gutter := len(strconv.Itoa(last + 1))
+	blank := strings.Repeat(" ", gutter) + " |"
+
+	var result strings.Builder
+	result.WriteString(blank + "\n")
+	for i := first; i <= last; i++ {
+		line := strings.TrimSuffix(lines[i], "\r")
+		column := err.Column
+		if i == index {
+			line, column = snippetWindow(line, column)
+		} else {
+			line, _ = snippetWindow(line, 1)
+		}
+
+		fmt.Fprintf(&result, "%*d | %s\n", gutter, i+1, line)
+		if i == index {
+			result.WriteString(blank + " " + caretPadding(line, column) + "^\n")
+		}
+	}
+
+	return result.String()
+}
+
+// cuts a long line down to about snippetWidth characters around column, and where column is in that
+func snippetWindow(line string, column int) (string, int) {
+	length := utf8.RuneCountInString(line)
+	if length <= snippetWidth {
+		return line, column
+	}
+
+	runes := []rune(line)
+	start := min(max(column-1-snippetWidth/2, 0), length-snippetWidth)
+	end := start + snippetWidth
+	window := string(runes[start:end])
+	column -= start
+	if start > 0 {
+		window = "..." + window
+		column += 3
+	}
+
+	if end < length {
+		window += "..."
+	}
+
+	return window, column
+}
+
+// spaces up to column, keeping tabs so the caret lines up with the line above
+func caretPadding(line string, column int) string {
+	var padding strings.Builder
+	n := 1
+	for _, char := range line {
+		if n >= column {
+			break
+		}
+
+		if char == '\t' {
+			padding.WriteByte('\t')
+		} else {
+			padding.WriteByte(' ')
+		}
+
+		n++
+	}
+
+	// past the end of the line, e.g. at the end of the document
+	padding.WriteString(strings.Repeat(" ", max(column-n, 0)))
+	return padding.String()
+}
@@ -0,0 +1,92 @@
+package orderedjson
+
+import (
+	"cmp"
+	"sort"
+	"strings"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// KeyOrder compares two object keys like strings.Compare does. it's for output that shouldn't depend
+// on the order keys were added in, like snapshots and fixtures. any function works, e.g. one doing
+// locale aware collation.
+type KeyOrder func(a, b string) int
+
+var (
+	// byte by byte, like encoding/json sorts map keys
+	LexicalOrder KeyOrder = strings.Compare
+	// compares runs of digits by their value, so item2 comes before item10
+	NaturalOrder KeyOrder = naturalCompare
+)
+
+// WithSortedKeys returns a copy of opts that writes object keys in order.
+func (opts MarshalOptions) WithSortedKeys(order KeyOrder) MarshalOptions {
+	opts.SortKeys = order
+	return opts
+}
+
+// the pairs of tree in the order they should be written
+func (opts MarshalOptions) pairs(tree *JsonObject) []*orderedmap.Pair[string, interface{}] {
+	pairs := make([]*orderedmap.Pair[string, interface{}], 0, tree.Len())
+	for pair := tree.Oldest(); pair != nil; pair = pair.Next() {
+		pairs = append(pairs, pair)
+	}
+
+	if opts.SortKeys != nil {
+		sort.SliceStable(pairs, func(i, j int) bool {
+			return opts.SortKeys(pairs[i].Key, pairs[j].Key) < 0
+		})
+	}
+
+	return pairs
+}
+
+func naturalCompare(a, b string) int {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			numberA, restA := splitDigits(a)
+			numberB, restB := splitDigits(b)
+
+			// compare the values without leading zeros by length first, so there's no overflow
+			trimmedA := strings.TrimLeft(numberA, "0")
+			trimmedB := strings.TrimLeft(numberB, "0")
+			if len(trimmedA) != len(trimmedB) {
+				return cmp.Compare(len(trimmedA), len(trimmedB))
+			}
+
+			if c := strings.Compare(trimmedA, trimmedB); c != 0 {
+				return c
+			}
+
+			// the same value, fewer leading zeros first
+			if len(numberA) != len(numberB) {
+				return cmp.Compare(len(numberA), len(numberB))
+			}
+
+			a, b = restA, restB
+			continue
+		}
+
+		if a[0] != b[0] {
+			return cmp.Compare(int(a[0]), int(b[0]))
+		}
+
+		a, b = a[1:], b[1:]
+	}
+
+	return cmp.Compare(len(a), len(b))
+}
+
+func isDigit(char byte) bool {
+	return char >= '0' && char <= '9'
+}
+
+func splitDigits(s string) (string, string) {
+	end := 0
+	for end < len(s) && isDigit(s[end]) {
+		end++
+	}
+
+	return s[:end], s[end:]
+}
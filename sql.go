@@ -0,0 +1,51 @@
+package orderedjson
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// SQLObject stores an ordered tree in a database column through database/sql. JsonObject is an
+// alias for the ordered map type, which we can't add methods to, so it needs this wrapper:
+//
+//	var settings SQLObject
+//	err := db.QueryRow("select settings from users where id = $1", id).Scan(&settings)
+//	_, err = db.Exec("update users set settings = $1", SQLObject{Tree: tree})
+//
+// a nil Tree is stored as NULL. the key order only survives a round trip through columns that keep
+// the text as is (TEXT, postgres json, mysql TEXT). postgres jsonb and mysql JSON normalize the key
+// order on their end.
+type SQLObject struct {
+	Tree *JsonObject
+}
+
+func (object *SQLObject) Scan(src interface{}) error {
+	var raw []byte
+	switch v := src.(type) {
+	case nil:
+		object.Tree = nil
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into an ordered json object", src)
+	}
+
+	tree, err := FromStdJSON(raw)
+	if err != nil {
+		return err
+	}
+
+	object.Tree = tree
+	return nil
+}
+
+func (object SQLObject) Value() (driver.Value, error) {
+	if object.Tree == nil {
+		return nil, nil
+	}
+
+	return marshalValue(object.Tree)
+}
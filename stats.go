@@ -0,0 +1,89 @@
+package orderedjson
+
+import (
+	"expvar"
+	"sync"
+	"time"
+)
+
+// Stats is what parsing one document cost, for services that want to keep an eye on how much time
+// and memory goes into json. set ParseOptions.Stats to get them, or ParseOptions.Metrics to feed
+// them to expvar, Prometheus or the like.
+type Stats struct {
+	// the size of the input
+	Bytes  int
+	Tokens int
+	// how deeply objects and arrays nest, 1 for an object of scalars
+	MaxDepth int
+	// a rough count of heap allocations: one per token and per value in the tree, which is what
+	// dominates. it's meant for comparing documents, not for matching a memory profile.
+	Allocations int
+	Duration    time.Duration
+	Failed      bool
+}
+
+func (parser *BtreeJsonParser) enter() error {
+	parser.depth++
+	parser.maxDepth = max(parser.maxDepth, parser.depth)
+	limit := parser.depthLimit
+	if limit == 0 {
+		limit = DefaultMaxDepth
+	}
+
+	if limit > 0 && parser.depth > limit {
+		return parser.errorf(ErrTooDeep, "objects and arrays are nested more than %d deep", limit)
+	}
+
+	return nil
+}
+
+func (parser *BtreeJsonParser) leave() {
+	parser.depth--
+}
+
+func (parser *BtreeJsonParser) recordStats(stats *Stats) {
+	stats.Tokens = len(parser.tokens)
+	stats.MaxDepth = parser.maxDepth
+	stats.Allocations = len(parser.tokens) + parser.values
+}
+
+var maxDepthMu sync.Mutex
+
+// ExpvarMetrics returns a ParseOptions.Metrics function that adds up the stats in an expvar.Map
+// published as name, so they show up on /debug/vars: documents, failed, bytes, tokens, allocations
+// and nanoseconds are totals, max_depth is the deepest document so far. calling it again with the
+// same name reuses the map.
+//
+// for Prometheus, a Metrics function can update counters and histograms the same way.
+func ExpvarMetrics(name string) func(stats Stats) {
+	metrics, ok := expvar.Get(name).(*expvar.Map)
+	if !ok {
+		metrics = expvar.NewMap(name)
+	}
+
+	maxDepth := new(expvar.Int)
+	if existing, ok := metrics.Get("max_depth").(*expvar.Int); ok {
+		maxDepth = existing
+	} else {
+		metrics.Set("max_depth", maxDepth)
+	}
+
+	return func(stats Stats) {
+		metrics.Add("documents", 1)
+		if stats.Failed {
+			metrics.Add("failed", 1)
+		}
+
+		metrics.Add("bytes", int64(stats.Bytes))
+		metrics.Add("tokens", int64(stats.Tokens))
+		metrics.Add("allocations", int64(stats.Allocations))
+		metrics.Add("nanoseconds", stats.Duration.Nanoseconds())
+
+		// expvar has no atomic max
+		maxDepthMu.Lock()
+		if int64(stats.MaxDepth) > maxDepth.Value() {
+			maxDepth.Set(int64(stats.MaxDepth))
+		}
+		maxDepthMu.Unlock()
+	}
+}
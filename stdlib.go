@@ -0,0 +1,203 @@
+package orderedjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/michaelhelvey/orderedjson/v2/compat"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// conversions between the ordered tree and the types everyone else uses
+
+// ToMap converts tree (recursively) to plain maps, dropping the key order.
+func ToMap(tree *JsonObject) map[string]interface{} {
+	return toStdValue(tree).(map[string]interface{})
+}
+
+func toStdValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *JsonObject:
+		result := make(map[string]interface{}, v.Len())
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			result[pair.Key] = toStdValue(pair.Value)
+		}
+
+		return result
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			result = append(result, toStdValue(item))
+		}
+
+		return result
+	}
+
+	return value
+}
+
+// FromMap converts m (recursively) to an ordered tree, with the keys of every object sorted by compare.
+// a nil compare sorts keys lexically. integers of any Go type become json.Numbers, like Encode
+// gives them, so the ones past 2^53 keep their digits, and other numbers become float64s.
+func FromMap(m map[string]interface{}, compare func(a, b string) int) (*JsonObject, error) {
+	if compare == nil {
+		compare = func(a, b string) int {
+			if a < b {
+				return -1
+			} else if a > b {
+				return 1
+			}
+
+			return 0
+		}
+	}
+
+	value, err := fromStdValue(m, compare)
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*JsonObject), nil
+}
+
+// FromMapKeys is FromMap with the top level keys in the order given by keys. keys of m that aren't
+// in the list come after them, sorted lexically, and so do the keys of nested objects.
+func FromMapKeys(m map[string]interface{}, keys []string) (*JsonObject, error) {
+	position := make(map[string]int, len(keys))
+	for i, key := range keys {
+		if _, seen := position[key]; !seen {
+			position[key] = i
+		}
+	}
+
+	tree, err := FromMap(m, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := orderedmap.New[string, interface{}]()
+	for _, key := range keys {
+		if value, present := tree.Get(key); present {
+			result.Set(key, value)
+		}
+	}
+
+	for pair := tree.Oldest(); pair != nil; pair = pair.Next() {
+		if _, listed := position[pair.Key]; !listed {
+			result.Set(pair.Key, pair.Value)
+		}
+	}
+
+	return result, nil
+}
+
+func fromStdValue(value interface{}, compare func(a, b string) int) (interface{}, error) {
+	switch v := value.(type) {
+	case nil, bool, string, float64, *JsonObject:
+		return v, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return compare(keys[i], keys[j]) < 0 })
+
+		result := orderedmap.New[string, interface{}]()
+		for _, key := range keys {
+			child, err := fromStdValue(v[key], compare)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+
+			result.Set(key, child)
+		}
+
+		return result, nil
+	case map[string]string:
+		converted := make(map[string]interface{}, len(v))
+		for key, s := range v {
+			converted[key] = s
+		}
+
+		return fromStdValue(converted, compare)
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			child, err := fromStdValue(item, compare)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, child)
+		}
+
+		return result, nil
+	case []string:
+		result := make([]interface{}, 0, len(v))
+		for _, s := range v {
+			result = append(result, s)
+		}
+
+		return result, nil
+	case json.Number:
+		if !isJSONNumber(string(v)) {
+			return nil, fmt.Errorf("%q is not a json number", string(v))
+		}
+
+		return v, nil
+	case json.RawMessage:
+		return FromStdJSONValue(v)
+	case float32:
+		return float64(v), nil
+	case int:
+		return json.Number(strconv.FormatInt(int64(v), 10)), nil
+	case int8:
+		return json.Number(strconv.FormatInt(int64(v), 10)), nil
+	case int16:
+		return json.Number(strconv.FormatInt(int64(v), 10)), nil
+	case int32:
+		return json.Number(strconv.FormatInt(int64(v), 10)), nil
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10)), nil
+	case uint:
+		return json.Number(strconv.FormatUint(uint64(v), 10)), nil
+	case uint8:
+		return json.Number(strconv.FormatUint(uint64(v), 10)), nil
+	case uint16:
+		return json.Number(strconv.FormatUint(uint64(v), 10)), nil
+	case uint32:
+		return json.Number(strconv.FormatUint(uint64(v), 10)), nil
+	case uint64:
+		return json.Number(strconv.FormatUint(v, 10)), nil
+	}
+
+	return nil, fmt.Errorf("cannot convert %T to a json value", value)
+}
+
+// FromStdJSON parses raw with encoding/json's tokenizer into an ordered tree. raw has to hold an object.
+func FromStdJSON(raw json.RawMessage) (*JsonObject, error) {
+	value, err := FromStdJSONValue(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, ok := value.(*JsonObject)
+	if !ok {
+		return nil, &UnmarshalTypeError{Value: TypeName(value), Type: reflect.TypeOf(tree)}
+	}
+
+	return tree, nil
+}
+
+// FromStdJSONValue is FromStdJSON for any json value, not just objects.
+func FromStdJSONValue(raw json.RawMessage) (interface{}, error) {
+	var value interface{}
+	if err := compat.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
@@ -0,0 +1,122 @@
+package orderedjson
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestToMap(t *testing.T) {
+	tree, err := ParseOptions{}.Parse([]byte(`{"z":1,"a":{"y":[true,null,{"b":"x"}]},"n":2.5}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"z": 1.0,
+		"a": map[string]interface{}{"y": []interface{}{true, nil, map[string]interface{}{"b": "x"}}},
+		"n": 2.5,
+	}
+	if got := ToMap(tree); !reflect.DeepEqual(got, want) {
+		t.Errorf("ToMap = %#v, want %#v", got, want)
+	}
+}
+
+func TestFromMap(t *testing.T) {
+	reverse := func(a, b string) int { return strings.Compare(b, a) }
+	tests := []struct {
+		input   map[string]interface{}
+		compare func(a, b string) int
+		want    string
+	}{
+		{map[string]interface{}{"b": 1, "a": map[string]interface{}{"d": true, "c": nil}}, nil, `{"a":{"c":null,"d":true},"b":1}`},
+		{map[string]interface{}{"b": 1, "a": map[string]interface{}{"c": 1, "d": 2}}, reverse, `{"b":1,"a":{"d":2,"c":1}}`},
+		{map[string]interface{}{"s": []string{"x"}, "m": map[string]string{"k": "v"}, "l": []interface{}{"y", 1.5}}, nil, `{"l":["y",1.5],"m":{"k":"v"},"s":["x"]}`},
+		// integers keep every digit, whatever their type
+		{map[string]interface{}{"i": int64(math.MaxInt64), "u": uint64(math.MaxUint64), "n": int8(-8), "f": float32(0.5)}, nil, `{"f":0.5,"i":9223372036854775807,"n":-8,"u":18446744073709551615}`},
+		{map[string]interface{}{"big": json.Number("12345678901234567891"), "e": json.Number("1.50e2")}, nil, `{"big":12345678901234567891,"e":1.50e2}`},
+		{map[string]interface{}{"raw": json.RawMessage(`{"z":1,"a":2}`)}, nil, `{"raw":{"z":1,"a":2}}`},
+		{map[string]interface{}{}, nil, `{}`},
+	}
+
+	for _, test := range tests {
+		tree, err := FromMap(test.input, test.compare)
+		if err != nil {
+			t.Errorf("FromMap(%v): %v", test.input, err)
+			continue
+		}
+
+		if got, _ := MarshalCompact(tree); got != test.want {
+			t.Errorf("FromMap(%v) = %s, want %s", test.input, got, test.want)
+		}
+	}
+
+	tree, _ := FromMap(map[string]interface{}{"i": 1, "u": uint(2)}, nil)
+	if i, _ := tree.Get("i"); i != json.Number("1") {
+		t.Errorf("FromMap(int) = %#v, want a json.Number", i)
+	}
+
+	for _, input := range []map[string]interface{}{
+		{"a": struct{}{}},
+		{"a": map[string]interface{}{"b": []interface{}{make(chan int)}}},
+		{"a": json.Number("0x10")},
+		{"a": json.Number("")},
+		{"a": json.Number("Inf")},
+		{"a": json.RawMessage(`{"a":`)},
+	} {
+		if tree, err := FromMap(input, nil); err == nil {
+			t.Errorf("FromMap(%v) = %v, want an error", input, tree)
+		}
+	}
+}
+
+func TestFromMapKeys(t *testing.T) {
+	m := map[string]interface{}{"d": 1, "c": 2, "b": map[string]interface{}{"z": 1, "y": 2}, "a": 3}
+	tests := []struct {
+		keys []string
+		want string
+	}{
+		{[]string{"c", "b"}, `{"c":2,"b":{"y":2,"z":1},"a":3,"d":1}`},
+		{[]string{"d", "missing", "d", "a"}, `{"d":1,"a":3,"b":{"y":2,"z":1},"c":2}`},
+		{nil, `{"a":3,"b":{"y":2,"z":1},"c":2,"d":1}`},
+	}
+
+	for _, test := range tests {
+		tree, err := FromMapKeys(m, test.keys)
+		if err != nil {
+			t.Errorf("FromMapKeys(%v): %v", test.keys, err)
+			continue
+		}
+
+		if got, _ := MarshalCompact(tree); got != test.want {
+			t.Errorf("FromMapKeys(%v) = %s, want %s", test.keys, got, test.want)
+		}
+	}
+
+	if _, err := FromMapKeys(map[string]interface{}{"a": func() {}}, []string{"a"}); err == nil {
+		t.Errorf("FromMapKeys(func): want an error")
+	}
+}
+
+func TestFromStdJSON(t *testing.T) {
+	tree, err := FromStdJSON(json.RawMessage(` {"z":1,"a":{"y":[true,null],"b":"ü"}} `))
+	if err != nil {
+		t.Fatalf("FromStdJSON: %v", err)
+	}
+
+	if got, _ := MarshalCompact(tree); got != `{"z":1,"a":{"y":[true,null],"b":"ü"}}` {
+		t.Errorf("FromStdJSON = %s", got)
+	}
+
+	for _, input := range []string{`[1]`, `"a"`, `null`, `{"a":`, `{"a":1} x`, ``} {
+		if tree, err := FromStdJSON(json.RawMessage(input)); err == nil {
+			t.Errorf("FromStdJSON(%s) = %v, want an error", input, tree)
+		}
+	}
+
+	if value, err := FromStdJSONValue(json.RawMessage(`[1,"a"]`)); err != nil || !reflect.DeepEqual(value, []interface{}{1.0, "a"}) {
+		t.Errorf("FromStdJSONValue([1,\"a\"]) = %#v, %v", value, err)
+	}
+}
@@ -0,0 +1,169 @@
+package orderedjson
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+)
+
+// StreamFormat says how the documents of a stream are separated.
+type StreamFormat struct {
+	// what goes between (or before) documents, like RS for json-seq. empty means documents just
+	// follow each other, with or without whitespace between them.
+	Delimiter string
+}
+
+var (
+	// documents one after another: NDJSON, {"a":1}{"b":2}, or pretty printed documents after each
+	// other
+	ConcatenatedStream = StreamFormat{}
+	// JSON text sequences (RFC 7464, application/json-seq): every document starts with RS (0x1E)
+	SequenceStream = StreamFormat{Delimiter: "\x1e"}
+)
+
+// StreamReader reads the documents of a stream one at a time, so a log or an export of any size
+// can be processed in constant memory (besides the biggest document).
+type StreamReader struct {
+	scanner *bufio.Scanner
+	format  StreamFormat
+	opts    ParseOptions
+	// how many documents were read, for errors
+	count int
+}
+
+// NewStreamReader reads documents separated the way format says from r.
+func NewStreamReader(r io.Reader, format StreamFormat, options ...DecodeOption) *StreamReader {
+	return ParseOptions{}.With(options...).NewStreamReader(r, format)
+}
+
+// NewStreamReader is NewStreamReader with the documents parsed with opts.
+func (opts ParseOptions) NewStreamReader(r io.Reader, format StreamFormat) *StreamReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), math.MaxInt)
+	if format.Delimiter == "" {
+		scanner.Split((&documentSplitter{}).split)
+	} else {
+		scanner.Split(delimiterSplitter([]byte(format.Delimiter)))
+	}
+
+	return &StreamReader{scanner: scanner, format: format, opts: opts}
+}
+
+// Next returns the next document, or io.EOF after the last one. with a Delimiter, a document that
+// doesn't parse is an error but the ones after it can still be read, like RFC 7464 asks for.
+// without one there's no telling where the next document starts, so the error ends the stream.
+func (reader *StreamReader) Next() (*JsonObject, error) {
+	for reader.scanner.Scan() {
+		document := reader.scanner.Bytes()
+		if len(bytes.TrimSpace(document)) == 0 {
+			// before the first delimiter, or between two of them
+			continue
+		}
+
+		reader.count++
+		tree, err := reader.opts.Parse(document)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", reader.count, err)
+		}
+
+		return tree, nil
+	}
+
+	if err := reader.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("document %d: %w", reader.count+1, err)
+	}
+
+	return nil, io.EOF
+}
+
+func delimiterSplitter(delimiter []byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.Index(data, delimiter); i >= 0 {
+			return i + len(delimiter), data[:i], nil
+		}
+
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+
+		return 0, nil, nil
+	}
+}
+
+// finds where each document ends with a validator, which keeps its place between calls so a big
+// document is only looked at once
+type documentSplitter struct {
+	validator *validator
+	// how much of the data the validator has seen
+	checked int
+}
+
+func (splitter *documentSplitter) split(data []byte, atEOF bool) (int, []byte, error) {
+	if splitter.validator == nil {
+		skip := 0
+		for skip < len(data) && isSpace(data[skip]) {
+			skip++
+		}
+
+		if skip > 0 || len(data) == 0 {
+			return skip, nil, nil
+		}
+
+		if data[0] != '{' {
+			return 0, nil, newSyntaxError(data, 0, ErrInvalidToken, "invalid character %q, expected the { of a document", data[0])
+		}
+
+		splitter.validator, splitter.checked = newValidator(), 0
+	}
+
+	for ; splitter.checked < len(data); splitter.checked++ {
+		if err := splitter.validator.write(data[splitter.checked : splitter.checked+1]); err != nil {
+			return 0, nil, err
+		}
+
+		if splitter.validator.state == afterValue && splitter.validator.depth == 0 {
+			end := splitter.checked + 1
+			splitter.validator = nil
+			return end, data[:end], nil
+		}
+	}
+
+	if atEOF {
+		return 0, nil, splitter.validator.close()
+	}
+
+	return 0, nil, nil
+}
+
+// StreamWriter writes documents the way a StreamReader with the same format reads them: each one
+// after the format's Delimiter and followed by a newline, so SequenceStream gives RS, the document,
+// LF (RFC 7464) and ConcatenatedStream gives NDJSON (as long as there's no Indent).
+type StreamWriter struct {
+	w      io.Writer
+	format StreamFormat
+	opts   MarshalOptions
+}
+
+// NewStreamWriter writes compact documents to w, unless options say otherwise.
+func NewStreamWriter(w io.Writer, format StreamFormat, options ...EncodeOption) *StreamWriter {
+	return MarshalOptions{Compact: true}.With(options...).NewStreamWriter(w, format)
+}
+
+// NewStreamWriter is NewStreamWriter with the documents marshaled with opts.
+func (opts MarshalOptions) NewStreamWriter(w io.Writer, format StreamFormat) *StreamWriter {
+	return &StreamWriter{w: w, format: format, opts: opts}
+}
+
+// Write writes one document. it's marshaled before anything is written, so a document that can't
+// be marshaled leaves the stream as it was.
+func (writer *StreamWriter) Write(tree *JsonObject) error {
+	text, err := writer.opts.Marshal(tree)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(writer.w, writer.format.Delimiter+text+"\n")
+	return err
+}
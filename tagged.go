@@ -0,0 +1,136 @@
+package orderedjson
+
+import (
+	"fmt"
+	"reflect"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// UnmarshalTagged decodes a tagged union: the string at the key tag (the discriminator, like "type")
+// picks the function in types that makes the go value to decode the rest of the object into, e.g.
+//
+//	shape, unknown, err := UnmarshalTagged(data, "type", map[string]func() interface{}{
+//		"circle": func() interface{} { return &Circle{} },
+//		"square": func() interface{} { return &Square{} },
+//	})
+//
+// the value is decoded with DecodeInto, and returned as the function made it. the keys that aren't
+// the tag and that a struct has no field for come back as an object, in their order in data, so a
+// newer producer's fields aren't lost.
+func UnmarshalTagged(data []byte, tag string, types map[string]func() interface{}, opts ...DecodeOption) (interface{}, *JsonObject, error) {
+	config := newDecodeConfig(ParseOptions{}, opts)
+	tree, err := config.parse.Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return decodeTagged(tree, tag, types, config.hooks)
+}
+
+// DecodeTagged is UnmarshalTagged for an object that has already been parsed.
+func DecodeTagged(object *JsonObject, tag string, types map[string]func() interface{}, opts ...DecodeOption) (interface{}, *JsonObject, error) {
+	hooks, err := decodeHooks("DecodeTagged", opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return decodeTagged(object, tag, types, hooks)
+}
+
+func decodeTagged(object *JsonObject, tag string, types map[string]func() interface{}, hooks []DecodeHook) (interface{}, *JsonObject, error) {
+	value, err := newTagged(object, tag, types)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	target := reflect.ValueOf(value)
+	if target.Kind() == reflect.Pointer && !target.IsNil() {
+		err = decodeInto(object, value, hooks)
+	} else {
+		// not a pointer, so decode into a copy and return that
+		pointer := reflect.New(target.Type())
+		pointer.Elem().Set(target)
+		err = decodeInto(object, pointer.Interface(), hooks)
+		value = pointer.Elem().Interface()
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return value, unknownFields(object, tag, reflect.TypeOf(value)), nil
+}
+
+// TaggedHook decodes tagged union objects into fields of interface types (besides interface{}), the
+// way DecodeTagged does, for unions nested in other values. the value types makes has to implement
+// the field's interface.
+func TaggedHook(tag string, types map[string]func() interface{}) DecodeHook {
+	return func(value interface{}, to reflect.Type) (interface{}, error) {
+		object, ok := value.(*JsonObject)
+		if !ok || to.Kind() != reflect.Interface || to.NumMethod() == 0 {
+			return value, nil
+		}
+
+		if _, tagged := object.Get(tag); !tagged {
+			return value, nil
+		}
+
+		decoded, _, err := DecodeTagged(object, tag, types, WithHook(TaggedHook(tag, types)))
+		if err != nil {
+			return nil, err
+		}
+
+		if !reflect.TypeOf(decoded).Implements(to) {
+			return nil, fmt.Errorf("%T doesn't implement %s", decoded, to)
+		}
+
+		return decoded, nil
+	}
+}
+
+// the value to decode object into, going by its tag
+func newTagged(object *JsonObject, tag string, types map[string]func() interface{}) (interface{}, error) {
+	discriminator, ok := object.Get(tag)
+	if !ok {
+		return nil, fmt.Errorf("missing %q key", tag)
+	}
+
+	name, ok := discriminator.(string)
+	if !ok {
+		return nil, fmt.Errorf("%q is %s, expected a string", tag, TypeName(discriminator))
+	}
+
+	newValue, ok := types[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown %s %q", tag, name)
+	}
+
+	value := newValue()
+	if value == nil {
+		return nil, fmt.Errorf("the function for %s %q returned nil", tag, name)
+	}
+
+	return value, nil
+}
+
+// the keys of object that t has no field for, besides tag
+func unknownFields(object *JsonObject, tag string, t reflect.Type) *JsonObject {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	unknown := orderedmap.New[string, interface{}]()
+	if t.Kind() != reflect.Struct {
+		return unknown
+	}
+
+	fields := structFields(t)
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		if _, known := fields.lookup(pair.Key); !known && pair.Key != tag {
+			unknown.Set(pair.Key, pair.Value)
+		}
+	}
+
+	return unknown
+}
@@ -0,0 +1,639 @@
+package toml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// Decode parses a TOML document. keys and tables end up in the order they first appear in the
+// document. dates and times are kept as their original text since json has no type for them, and
+// integers too big for a float64 to hold exactly are json.Numbers with all their digits.
+func Decode(data []byte) (*JsonObject, error) {
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("toml: document is not valid utf-8")
+	}
+
+	root := orderedmap.New[string, interface{}]()
+	decoder := &decoder{runes: []rune(string(data)), line: 1, root: root, current: root, closed: map[*JsonObject]bool{}}
+	if err := decoder.decode(); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+type decoder struct {
+	runes   []rune
+	idx     int
+	line    int
+	root    *JsonObject
+	current *JsonObject
+	// inline tables can't be extended after they are written
+	closed map[*JsonObject]bool
+}
+
+func (decoder *decoder) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("toml: line %d: %s", decoder.line, fmt.Sprintf(format, args...))
+}
+
+func (decoder *decoder) peek() rune {
+	if decoder.idx < len(decoder.runes) {
+		return decoder.runes[decoder.idx]
+	}
+
+	return 0
+}
+
+func (decoder *decoder) hasPrefix(s string) bool {
+	return strings.HasPrefix(string(decoder.runes[decoder.idx:min(len(decoder.runes), decoder.idx+len(s))]), s)
+}
+
+func (decoder *decoder) skipSpace() {
+	for decoder.peek() == ' ' || decoder.peek() == '\t' {
+		decoder.idx++
+	}
+}
+
+func (decoder *decoder) skipComment() {
+	if decoder.peek() == '#' {
+		for decoder.idx < len(decoder.runes) && decoder.peek() != '\n' {
+			decoder.idx++
+		}
+	}
+}
+
+// skips whitespace, newlines and comments (used between expressions and inside of arrays)
+func (decoder *decoder) skipBlank() {
+	for decoder.idx < len(decoder.runes) {
+		decoder.skipSpace()
+		decoder.skipComment()
+
+		switch {
+		case decoder.peek() == '\n':
+			decoder.idx++
+			decoder.line++
+		case decoder.hasPrefix("\r\n"):
+			decoder.idx += 2
+			decoder.line++
+		default:
+			return
+		}
+	}
+}
+
+func (decoder *decoder) endOfLine() error {
+	decoder.skipSpace()
+	decoder.skipComment()
+
+	if decoder.idx >= len(decoder.runes) || decoder.peek() == '\n' || decoder.hasPrefix("\r\n") {
+		return nil
+	}
+
+	return decoder.errorf("expected end of line, got %q", decoder.peek())
+}
+
+func (decoder *decoder) decode() error {
+	for {
+		decoder.skipBlank()
+		if decoder.idx >= len(decoder.runes) {
+			return nil
+		}
+
+		var err error
+		if decoder.hasPrefix("[[") {
+			err = decoder.decodeArrayTableHeader()
+		} else if decoder.peek() == '[' {
+			err = decoder.decodeTableHeader()
+		} else {
+			err = decoder.decodeKeyValue(decoder.current)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err := decoder.endOfLine(); err != nil {
+			return err
+		}
+	}
+}
+
+func (decoder *decoder) decodeTableHeader() error {
+	decoder.idx++
+	keys, err := decoder.decodeKey()
+	if err != nil {
+		return err
+	}
+
+	if decoder.peek() != ']' {
+		return decoder.errorf("expected ]")
+	}
+	decoder.idx++
+
+	table, err := decoder.descend(decoder.root, keys)
+	if err != nil {
+		return err
+	}
+
+	decoder.current = table
+	return nil
+}
+
+func (decoder *decoder) decodeArrayTableHeader() error {
+	decoder.idx += 2
+	keys, err := decoder.decodeKey()
+	if err != nil {
+		return err
+	}
+
+	if !decoder.hasPrefix("]]") {
+		return decoder.errorf("expected ]]")
+	}
+	decoder.idx += 2
+
+	parent, err := decoder.descend(decoder.root, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+
+	last := keys[len(keys)-1]
+	table := orderedmap.New[string, interface{}]()
+
+	existing, present := parent.Get(last)
+	if !present {
+		parent.Set(last, []interface{}{table})
+	} else if array, ok := existing.([]interface{}); ok && isArrayOfTables(array) {
+		parent.Set(last, append(array, table))
+	} else {
+		return decoder.errorf("%s is not an array of tables", last)
+	}
+
+	decoder.current = table
+	return nil
+}
+
+// walks (and creates) the tables along keys, starting from table
+func (decoder *decoder) descend(table *JsonObject, keys []string) (*JsonObject, error) {
+	for _, key := range keys {
+		existing, present := table.Get(key)
+		if !present {
+			child := orderedmap.New[string, interface{}]()
+			table.Set(key, child)
+			table = child
+			continue
+		}
+
+		switch v := existing.(type) {
+		case *JsonObject:
+			if decoder.closed[v] {
+				return nil, decoder.errorf("cannot extend inline table %s", key)
+			}
+
+			table = v
+		case []interface{}:
+			// the last table of an array of tables
+			if !isArrayOfTables(v) {
+				return nil, decoder.errorf("%s is not a table", key)
+			}
+
+			table = v[len(v)-1].(*JsonObject)
+		default:
+			return nil, decoder.errorf("%s is not a table", key)
+		}
+	}
+
+	return table, nil
+}
+
+func (decoder *decoder) decodeKeyValue(table *JsonObject) error {
+	keys, err := decoder.decodeKey()
+	if err != nil {
+		return err
+	}
+
+	if decoder.peek() != '=' {
+		return decoder.errorf("expected = after key")
+	}
+	decoder.idx++
+	decoder.skipSpace()
+
+	value, err := decoder.decodeValue()
+	if err != nil {
+		return err
+	}
+
+	parent, err := decoder.descend(table, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+
+	last := keys[len(keys)-1]
+	if _, present := parent.Get(last); present {
+		return decoder.errorf("duplicate key %s", last)
+	}
+
+	parent.Set(last, value)
+	return nil
+}
+
+// a possibly dotted key, leaving the decoder after any trailing whitespace
+func (decoder *decoder) decodeKey() ([]string, error) {
+	keys := make([]string, 0, 1)
+	for {
+		decoder.skipSpace()
+
+		var key string
+		switch decoder.peek() {
+		case '"':
+			s, err := decoder.decodeBasicString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		case '\'':
+			s, err := decoder.decodeLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		default:
+			start := decoder.idx
+			for decoder.idx < len(decoder.runes) {
+				char := decoder.peek()
+				if !(char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9' || char == '_' || char == '-') {
+					break
+				}
+				decoder.idx++
+			}
+
+			if start == decoder.idx {
+				return nil, decoder.errorf("expected a key")
+			}
+
+			key = string(decoder.runes[start:decoder.idx])
+		}
+
+		keys = append(keys, key)
+		decoder.skipSpace()
+		if decoder.peek() != '.' {
+			return keys, nil
+		}
+		decoder.idx++
+	}
+}
+
+func (decoder *decoder) decodeValue() (interface{}, error) {
+	switch {
+	case decoder.hasPrefix(`"""`):
+		return decoder.decodeMultilineBasicString()
+	case decoder.hasPrefix(`'''`):
+		return decoder.decodeMultilineLiteralString()
+	case decoder.peek() == '"':
+		return decoder.decodeBasicString()
+	case decoder.peek() == '\'':
+		return decoder.decodeLiteralString()
+	case decoder.peek() == '[':
+		return decoder.decodeArray()
+	case decoder.peek() == '{':
+		return decoder.decodeInlineTable()
+	case decoder.hasPrefix("true"):
+		decoder.idx += 4
+		return true, nil
+	case decoder.hasPrefix("false"):
+		decoder.idx += 5
+		return false, nil
+	}
+
+	return decoder.decodeNumberOrDate()
+}
+
+func (decoder *decoder) decodeArray() (interface{}, error) {
+	decoder.idx++
+	result := make([]interface{}, 0)
+
+	for {
+		decoder.skipBlank()
+		if decoder.peek() == ']' {
+			decoder.idx++
+			return result, nil
+		}
+
+		value, err := decoder.decodeValue()
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, value)
+
+		decoder.skipBlank()
+		switch decoder.peek() {
+		case ',':
+			decoder.idx++
+		case ']':
+			decoder.idx++
+			return result, nil
+		default:
+			return nil, decoder.errorf("expected , or ] in array")
+		}
+	}
+}
+
+func (decoder *decoder) decodeInlineTable() (interface{}, error) {
+	decoder.idx++
+	table := orderedmap.New[string, interface{}]()
+
+	decoder.skipSpace()
+	if decoder.peek() == '}' {
+		decoder.idx++
+		decoder.closed[table] = true
+		return table, nil
+	}
+
+	for {
+		if err := decoder.decodeKeyValue(table); err != nil {
+			return nil, err
+		}
+
+		decoder.skipSpace()
+		switch decoder.peek() {
+		case ',':
+			decoder.idx++
+		case '}':
+			decoder.idx++
+			decoder.closed[table] = true
+			return table, nil
+		default:
+			return nil, decoder.errorf("expected , or } in inline table")
+		}
+	}
+}
+
+func (decoder *decoder) decodeNumberOrDate() (interface{}, error) {
+	start := decoder.idx
+	for decoder.idx < len(decoder.runes) && !strings.ContainsRune(" \t\r\n,]}#", decoder.peek()) {
+		decoder.idx++
+	}
+
+	// "1979-05-27 07:32:00" is a single value
+	text := string(decoder.runes[start:decoder.idx])
+	if len(text) == 10 && text[4] == '-' && decoder.peek() == ' ' && decoder.idx+1 < len(decoder.runes) && decoder.runes[decoder.idx+1] >= '0' && decoder.runes[decoder.idx+1] <= '9' {
+		decoder.idx++
+		for decoder.idx < len(decoder.runes) && !strings.ContainsRune(" \t\r\n,]}#", decoder.peek()) {
+			decoder.idx++
+		}
+
+		text = string(decoder.runes[start:decoder.idx])
+	}
+
+	if text == "" {
+		return nil, decoder.errorf("expected a value")
+	}
+
+	if len(text) >= 8 && (text[2] == ':' || (len(text) >= 10 && text[4] == '-' && text[7] == '-')) {
+		return text, nil
+	}
+
+	switch strings.TrimLeft(text, "+-") {
+	case "inf":
+		if text[0] == '-' {
+			return math.Inf(-1), nil
+		}
+
+		return math.Inf(1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+
+	clean := strings.ReplaceAll(text, "_", "")
+	for prefix, base := range map[string]int{"0x": 16, "0o": 8, "0b": 2} {
+		if strings.HasPrefix(text, prefix) {
+			value, err := strconv.ParseInt(clean[2:], base, 64)
+			if err != nil || !prefixedNumber.MatchString(text[2:]) {
+				return nil, decoder.errorf("invalid number %s", text)
+			}
+
+			return integer(value), nil
+		}
+	}
+
+	if !decimalNumber.MatchString(text) {
+		return nil, decoder.errorf("invalid value %s", text)
+	}
+
+	if !strings.ContainsAny(clean, ".eE") {
+		value, err := strconv.ParseInt(clean, 10, 64)
+		if err != nil {
+			return nil, decoder.errorf("integer %s doesn't fit in 64 bits", text)
+		}
+
+		return integer(value), nil
+	}
+
+	value, err := strconv.ParseFloat(clean, 64)
+	if err != nil {
+		return nil, decoder.errorf("invalid value %s", text)
+	}
+
+	return value, nil
+}
+
+// underscores only go between digits, and decimal integers don't start with 0
+var (
+	decimalNumber  = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][+-]?[0-9](_?[0-9])*)?$`)
+	prefixedNumber = regexp.MustCompile(`^[0-9a-fA-F](_?[0-9a-fA-F])*$`)
+)
+
+// n as a float64, or a json.Number past 2^53 where a float64 would lose digits
+func integer(n int64) interface{} {
+	if n < -(1<<53) || n > 1<<53 {
+		return json.Number(strconv.FormatInt(n, 10))
+	}
+
+	return float64(n)
+}
+
+func (decoder *decoder) decodeLiteralString() (string, error) {
+	decoder.idx++
+	start := decoder.idx
+	for decoder.idx < len(decoder.runes) {
+		switch decoder.peek() {
+		case '\'':
+			value := string(decoder.runes[start:decoder.idx])
+			decoder.idx++
+			return value, nil
+		case '\n':
+			return "", decoder.errorf("newline in string")
+		}
+		decoder.idx++
+	}
+
+	return "", decoder.errorf("unterminated string")
+}
+
+func (decoder *decoder) decodeMultilineLiteralString() (string, error) {
+	decoder.idx += 3
+	decoder.skipFirstNewline()
+
+	start := decoder.idx
+	for decoder.idx < len(decoder.runes) {
+		if decoder.hasPrefix("'''") {
+			// up to two extra quotes are allowed right before the closing delimiter
+			for decoder.hasPrefix("''''") {
+				decoder.idx++
+			}
+
+			value := string(decoder.runes[start:decoder.idx])
+			decoder.idx += 3
+			return value, nil
+		}
+
+		if decoder.peek() == '\n' {
+			decoder.line++
+		}
+		decoder.idx++
+	}
+
+	return "", decoder.errorf("unterminated string")
+}
+
+func (decoder *decoder) skipFirstNewline() {
+	if decoder.peek() == '\n' {
+		decoder.idx++
+		decoder.line++
+	} else if decoder.hasPrefix("\r\n") {
+		decoder.idx += 2
+		decoder.line++
+	}
+}
+
+func (decoder *decoder) decodeBasicString() (string, error) {
+	decoder.idx++
+
+	var result strings.Builder
+	for decoder.idx < len(decoder.runes) {
+		char := decoder.peek()
+		switch char {
+		case '"':
+			decoder.idx++
+			return result.String(), nil
+		case '\n':
+			return "", decoder.errorf("newline in string")
+		case '\\':
+			if err := decoder.decodeEscape(&result); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		result.WriteRune(char)
+		decoder.idx++
+	}
+
+	return "", decoder.errorf("unterminated string")
+}
+
+func (decoder *decoder) decodeMultilineBasicString() (string, error) {
+	decoder.idx += 3
+	decoder.skipFirstNewline()
+
+	var result strings.Builder
+	for decoder.idx < len(decoder.runes) {
+		if decoder.hasPrefix(`"""`) {
+			for decoder.hasPrefix(`""""`) {
+				result.WriteRune('"')
+				decoder.idx++
+			}
+
+			decoder.idx += 3
+			return result.String(), nil
+		}
+
+		char := decoder.peek()
+		if char == '\\' {
+			// a backslash at the end of a line trims all the whitespace after it
+			next := decoder.idx + 1
+			for next < len(decoder.runes) && (decoder.runes[next] == ' ' || decoder.runes[next] == '\t') {
+				next++
+			}
+
+			if next < len(decoder.runes) && (decoder.runes[next] == '\n' || decoder.runes[next] == '\r') {
+				decoder.idx = next
+				for decoder.idx < len(decoder.runes) && strings.ContainsRune(" \t\r\n", decoder.peek()) {
+					if decoder.peek() == '\n' {
+						decoder.line++
+					}
+					decoder.idx++
+				}
+
+				continue
+			}
+
+			if err := decoder.decodeEscape(&result); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		if char == '\n' {
+			decoder.line++
+		}
+
+		result.WriteRune(char)
+		decoder.idx++
+	}
+
+	return "", decoder.errorf("unterminated string")
+}
+
+func (decoder *decoder) decodeEscape(result *strings.Builder) error {
+	decoder.idx++
+	escaped := decoder.peek()
+	decoder.idx++
+
+	switch escaped {
+	case 'b':
+		result.WriteRune('\b')
+	case 't':
+		result.WriteRune('\t')
+	case 'n':
+		result.WriteRune('\n')
+	case 'f':
+		result.WriteRune('\f')
+	case 'r':
+		result.WriteRune('\r')
+	case 'e':
+		result.WriteRune(0x1b)
+	case '"':
+		result.WriteRune('"')
+	case '\\':
+		result.WriteRune('\\')
+	case 'u', 'U':
+		length := 4
+		if escaped == 'U' {
+			length = 8
+		}
+
+		if decoder.idx+length > len(decoder.runes) {
+			return decoder.errorf("invalid unicode escape")
+		}
+
+		code, err := strconv.ParseUint(string(decoder.runes[decoder.idx:decoder.idx+length]), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return decoder.errorf("invalid unicode escape")
+		}
+
+		decoder.idx += length
+		result.WriteRune(rune(code))
+	default:
+		return decoder.errorf("invalid escape \\%c", escaped)
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+// Package toml converts between ordered json trees and TOML documents, keeping the order of keys and
+// tables in both directions where TOML can represent it.
+package toml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+type JsonObject = orderedmap.OrderedMap[string, interface{}]
+
+// Encode converts tree to a TOML document.
+//
+// TOML requires every plain key of a table to come before its sub-tables, so nested objects that
+// come before a plain key are written as inline tables. anything after the last plain key gets a
+// normal [table] header (or [[array.of.tables]] for arrays of objects). null has no TOML equivalent
+// and is an error.
+func Encode(tree *JsonObject) ([]byte, error) {
+	var result strings.Builder
+	if err := encodeTable(&result, nil, tree); err != nil {
+		return nil, err
+	}
+
+	return []byte(result.String()), nil
+}
+
+func isTable(value interface{}) bool {
+	_, ok := value.(*JsonObject)
+	return ok
+}
+
+func isArrayOfTables(value interface{}) bool {
+	array, ok := value.([]interface{})
+	if !ok || len(array) == 0 {
+		return false
+	}
+
+	for _, item := range array {
+		if !isTable(item) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func encodeTable(result *strings.Builder, path []string, table *JsonObject) error {
+	// everything up to and including the last value that can't be a section goes in the key/value
+	// part of this table
+	lastPlain := -1
+	i := 0
+	for pair := table.Oldest(); pair != nil; pair = pair.Next() {
+		if !isTable(pair.Value) && !isArrayOfTables(pair.Value) {
+			lastPlain = i
+		}
+		i++
+	}
+
+	i = 0
+	for pair := table.Oldest(); pair != nil; pair = pair.Next() {
+		if i > lastPlain {
+			break
+		}
+
+		value, err := encodeValue(pair.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", strings.Join(append(path, pair.Key), "."), err)
+		}
+
+		result.WriteString(fmt.Sprintf("%s = %s\n", encodeKey(pair.Key), value))
+		i++
+	}
+
+	i = 0
+	for pair := table.Oldest(); pair != nil; pair = pair.Next() {
+		if i <= lastPlain {
+			i++
+			continue
+		}
+
+		childPath := append(append([]string{}, path...), pair.Key)
+		if child, ok := pair.Value.(*JsonObject); ok {
+			if result.Len() > 0 {
+				result.WriteString("\n")
+			}
+
+			result.WriteString(fmt.Sprintf("[%s]\n", encodePath(childPath)))
+			if err := encodeTable(result, childPath, child); err != nil {
+				return err
+			}
+		} else {
+			for _, item := range pair.Value.([]interface{}) {
+				if result.Len() > 0 {
+					result.WriteString("\n")
+				}
+
+				result.WriteString(fmt.Sprintf("[[%s]]\n", encodePath(childPath)))
+				if err := encodeTable(result, childPath, item.(*JsonObject)); err != nil {
+					return err
+				}
+			}
+		}
+
+		i++
+	}
+
+	return nil
+}
+
+func encodePath(path []string) string {
+	keys := make([]string, 0, len(path))
+	for _, key := range path {
+		keys = append(keys, encodeKey(key))
+	}
+
+	return strings.Join(keys, ".")
+}
+
+func encodeKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+
+	for _, char := range key {
+		if !(char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9' || char == '_' || char == '-') {
+			return encodeString(key)
+		}
+	}
+
+	return key
+}
+
+func encodeValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return encodeString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "nan", nil
+		case math.IsInf(v, 1):
+			return "inf", nil
+		case math.IsInf(v, -1):
+			return "-inf", nil
+		case v == 0 && math.Signbit(v):
+			// integers don't have -0
+			return "-0.0", nil
+		case v == math.Trunc(v) && math.Abs(v) <= 1<<53:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+
+		formatted := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(formatted, ".e") {
+			formatted += ".0"
+		}
+
+		return formatted, nil
+	case json.Number:
+		if _, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return string(v), nil
+		}
+
+		// past 64 bits there are only floats
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return "", fmt.Errorf("%q isn't a number", string(v))
+		}
+
+		return encodeValue(f)
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			encoded, err := encodeValue(item)
+			if err != nil {
+				return "", err
+			}
+
+			items = append(items, encoded)
+		}
+
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case *JsonObject:
+		items := make([]string, 0, v.Len())
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			encoded, err := encodeValue(pair.Value)
+			if err != nil {
+				return "", err
+			}
+
+			items = append(items, fmt.Sprintf("%s = %s", encodeKey(pair.Key), encoded))
+		}
+
+		if len(items) == 0 {
+			return "{}", nil
+		}
+
+		return "{ " + strings.Join(items, ", ") + " }", nil
+	case nil:
+		return "", fmt.Errorf("toml has no null value")
+	}
+
+	return "", fmt.Errorf("cannot convert %T to toml", value)
+}
+
+func encodeString(s string) string {
+	var result strings.Builder
+	result.WriteRune('"')
+	for _, char := range s {
+		switch {
+		case char == '"':
+			result.WriteString(`\"`)
+		case char == '\\':
+			result.WriteString(`\\`)
+		case char == '\b':
+			result.WriteString(`\b`)
+		case char == '\t':
+			result.WriteString(`\t`)
+		case char == '\n':
+			result.WriteString(`\n`)
+		case char == '\f':
+			result.WriteString(`\f`)
+		case char == '\r':
+			result.WriteString(`\r`)
+		case char < 0x20 || char == 0x7f:
+			result.WriteString(fmt.Sprintf(`\u%04X`, char))
+		default:
+			result.WriteRune(char)
+		}
+	}
+	result.WriteRune('"')
+
+	return result.String()
+}
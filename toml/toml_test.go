@@ -0,0 +1,243 @@
+package toml
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/michaelhelvey/orderedjson/v2/internal/testtree"
+)
+
+// toml that Encode writes the same way comes back as it was, with datetimes as strings and the
+// order of keys, tables and arrays of tables kept
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		toml string
+		// what Encode writes, when it isn't the input
+		want string
+	}{
+		{"z = 1\na = \"x\"\n\n[m]\ny = true\nb = 1.5\n", ""},
+		// datetimes are read as strings, and written back as them
+		{"odt = 1979-05-27T07:32:00Z\nldt = 1979-05-27T07:32:00.999\nld = 1979-05-27\nlt = 07:32:00\n", "odt = \"1979-05-27T07:32:00Z\"\nldt = \"1979-05-27T07:32:00.999\"\nld = \"1979-05-27\"\nlt = \"07:32:00\"\n"},
+		{"[[fruits]]\nname = \"apple\"\n\n[fruits.physical]\ncolor = \"red\"\n\n[[fruits.varieties]]\nname = \"red delicious\"\n\n[[fruits.varieties]]\nname = \"granny smith\"\n\n[[fruits]]\nname = \"banana\"\n\n[[fruits.varieties]]\nname = \"plantain\"\n", ""},
+		{"[[products]]\nname = \"Hammer\"\n\n[[products]]\n\n[[products]]\nname = \"Nail\"\n", ""},
+		// a table that comes before a value stays where it is, as an inline table
+		{"a = { b = 1 }\nc = 2\n\n[d]\ne = [{ f = 1 }, 2]\n", ""},
+		{"b.y = 1\nb.x = 2\na = 3\n", "b = { y = 1, x = 2 }\na = 3\n"},
+		{"[z]\n[a]\nk = 1\n[z.sub]\n", "[z]\n\n[z.sub]\n\n[a]\nk = 1\n"},
+	}
+
+	for _, test := range tests {
+		decoded, err := Decode([]byte(test.toml))
+		if err != nil {
+			t.Errorf("Decode(%q): %v", test.toml, err)
+			continue
+		}
+
+		want := test.want
+		if want == "" {
+			want = test.toml
+		}
+
+		data, err := Encode(decoded)
+		if err != nil || string(data) != want {
+			t.Errorf("Encode(Decode(%q)) = %q, %v, want %q", test.toml, data, err, want)
+			continue
+		}
+
+		// and reading what was written gives the same tree
+		again, err := Decode(data)
+		if err != nil || testtree.Marshal(t, again) != testtree.Marshal(t, decoded) {
+			t.Errorf("Decode(%q) = %v, %v, want %s", data, again, err, testtree.Marshal(t, decoded))
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		json string
+		toml string
+	}{
+		{`{}`, ``},
+		{`{"a":1,"b":"x","c":true,"d":1.5,"e":[1,2]}`, "a = 1\nb = \"x\"\nc = true\nd = 1.5\ne = [1, 2]\n"},
+		{`{"a":{"b":1},"c":2}`, "a = { b = 1 }\nc = 2\n"},
+		{`{"c":2,"a":{"b":1,"d":{}}}`, "c = 2\n\n[a]\nb = 1\n\n[a.d]\n"},
+		{`{"fruits":[{"name":"apple"},{"name":"banana"}]}`, "[[fruits]]\nname = \"apple\"\n\n[[fruits]]\nname = \"banana\"\n"},
+		{`{"":1,"a b":2,"ü":3,"a-b_C9":4}`, "\"\" = 1\n\"a b\" = 2\n\"ü\" = 3\na-b_C9 = 4\n"},
+		{`{"e":{}}`, "[e]\n"},
+		{`{"s":"a\"b\\c\nd\u007f"}`, "s = \"a\\\"b\\\\c\\nd\\u007F\"\n"},
+		{`{"exact":9007199254740992,"n":-3,"big":18014398509481984}`, "exact = 9007199254740992\nn = -3\nbig = 1.8014398509481984e+16\n"},
+	}
+
+	for _, test := range tests {
+		data, err := Encode(testtree.Object(t, test.json))
+		if err != nil {
+			t.Errorf("Encode(%s): %v", test.json, err)
+			continue
+		}
+
+		if string(data) != test.toml {
+			t.Errorf("Encode(%s) = %q, want %q", test.json, data, test.toml)
+		}
+	}
+}
+
+// the examples of the TOML 1.0 spec
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		toml string
+		json string
+	}{
+		{"# comment\nkey = \"value\" # comment\n", `{"key":"value"}`},
+		{"bare_key = 1\nbare-key = 2\n1234 = 3\n\"quoted \\u00e9\" = 4\n'literal' = 5\n", `{"bare_key":1,"bare-key":2,"1234":3,"quoted é":4,"literal":5}`},
+		{"name = \"Orange\"\nphysical.color = \"orange\"\nphysical.shape = \"round\"\nsite.\"google.com\" = true\n", `{"name":"Orange","physical":{"color":"orange","shape":"round"},"site":{"google.com":true}}`},
+		{"[dog.\"tater.man\"]\ntype.name = \"pug\"\n", `{"dog":{"tater.man":{"type":{"name":"pug"}}}}`},
+		{"[ j . \"ʞ\" . 'l' ]\n", `{"j":{"ʞ":{"l":{}}}}`},
+		{"[x.y.z.w]\n[x]\n", `{"x":{"y":{"z":{"w":{}}}}}`},
+		{"str = \"I'm a string. \\\"You can quote me\\\". Name\\tJos\\u00E9\\nLocation\\tSF.\"\n", `{"str":"I'm a string. \"You can quote me\". Name\tJosé\nLocation\tSF."}`},
+		{"str1 = \"\"\"\nRoses are red\nViolets are blue\"\"\"\n", `{"str1":"Roses are red\nViolets are blue"}`},
+		{"str = \"\"\"\nThe quick brown \\\n\n\n  fox jumps over \\\n    the lazy dog.\"\"\"\n", `{"str":"The quick brown fox jumps over the lazy dog."}`},
+		{"str = \"\"\"Here are fifteen quotation marks: \"\"\\\"\"\"\\\"\"\"\\\"\"\"\\\"\"\"\\\".\"\"\"\nstr7 = \"\"\"\"This,\" she said, \"is just a pointless statement.\"\"\"\"\n", `{"str":"Here are fifteen quotation marks: \"\"\"\"\"\"\"\"\"\"\"\"\"\"\".","str7":"\"This,\" she said, \"is just a pointless statement.\""}`},
+		{"winpath = 'C:\\Users\\nodejs\\templates'\nregex = '<\\i\\c*\\s*>'\n", `{"winpath":"C:\\Users\\nodejs\\templates","regex":"\u003c\\i\\c*\\s*\u003e"}`},
+		{"lines = '''\nThe first newline is\ntrimmed in raw strings.\n'''\nquot15 = '''Here are fifteen quotation marks: \"\"\"\"\"\"\"\"\"\"\"\"\"\"\"'''\napos15 = \"Here are fifteen apostrophes: '''''''''''''''\"\n", `{"lines":"The first newline is\ntrimmed in raw strings.\n","quot15":"Here are fifteen quotation marks: \"\"\"\"\"\"\"\"\"\"\"\"\"\"\"","apos15":"Here are fifteen apostrophes: '''''''''''''''"}`},
+		{"int1 = +99\nint2 = 42\nint3 = 0\nint4 = -17\nint5 = 1_000\nint6 = 5_349_221\nint7 = 53_49_221\nint8 = 1_2_3_4_5\n", `{"int1":99,"int2":42,"int3":0,"int4":-17,"int5":1000,"int6":5349221,"int7":5349221,"int8":12345}`},
+		{"hex1 = 0xDEADBEEF\nhex2 = 0xdeadbeef\nhex3 = 0xdead_beef\noct1 = 0o01234567\noct2 = 0o755\nbin1 = 0b11010110\n", `{"hex1":3735928559,"hex2":3735928559,"hex3":3735928559,"oct1":342391,"oct2":493,"bin1":214}`},
+		{"flt1 = +1.0\nflt2 = 3.1415\nflt3 = -0.01\nflt4 = 5e+22\nflt5 = 1e06\nflt6 = -2E-2\nflt7 = 6.626e-34\nflt8 = 224_617.445_991_228\n", `{"flt1":1,"flt2":3.1415,"flt3":-0.01,"flt4":5e+22,"flt5":1000000,"flt6":-0.02,"flt7":6.626e-34,"flt8":224617.445991228}`},
+		{"bool1 = true\nbool2 = false\n", `{"bool1":true,"bool2":false}`},
+		{"odt1 = 1979-05-27T07:32:00Z\nodt2 = 1979-05-27T00:32:00.999999-07:00\nodt4 = 1979-05-27 07:32:00Z\nldt1 = 1979-05-27T07:32:00\nld1 = 1979-05-27\nlt1 = 07:32:00\nlt2 = 00:32:00.999999\n", `{"odt1":"1979-05-27T07:32:00Z","odt2":"1979-05-27T00:32:00.999999-07:00","odt4":"1979-05-27 07:32:00Z","ldt1":"1979-05-27T07:32:00","ld1":"1979-05-27","lt1":"07:32:00","lt2":"00:32:00.999999"}`},
+		{"integers = [ 1, 2, 3 ]\nnested_mixed_array = [ [ 1, 2 ], [\"a\", \"b\", \"c\"] ]\nnumbers = [ 0.1, 0.2, 0.5, 1, 2, 5 ]\ncontributors = [\n  \"Foo Bar <foo@example.com>\",\n  { name = \"Baz Qux\", email = \"bazqux@example.com\", url = \"https://example.com/bazqux\" }\n]\nintegers3 = [\n  1,\n  2, # this is ok\n]\n", `{"integers":[1,2,3],"nested_mixed_array":[[1,2],["a","b","c"]],"numbers":[0.1,0.2,0.5,1,2,5],"contributors":["Foo Bar \u003cfoo@example.com\u003e",{"name":"Baz Qux","email":"bazqux@example.com","url":"https://example.com/bazqux"}],"integers3":[1,2]}`},
+		{"[table-1]\nkey1 = \"some string\"\nkey2 = 123\n\n[table-2]\nkey1 = \"another string\"\nkey2 = 456\n", `{"table-1":{"key1":"some string","key2":123},"table-2":{"key1":"another string","key2":456}}`},
+		{"name = { first = \"Tom\", last = \"Preston-Werner\" }\npoint = { x = 1, y = 2 }\nanimal = { type.name = \"pug\" }\n", `{"name":{"first":"Tom","last":"Preston-Werner"},"point":{"x":1,"y":2},"animal":{"type":{"name":"pug"}}}`},
+		{"[[products]]\nname = \"Hammer\"\nsku = 738594937\n\n[[products]]  # empty table within the array\n\n[[products]]\nname = \"Nail\"\nsku = 284758393\n\ncolor = \"gray\"\n", `{"products":[{"name":"Hammer","sku":738594937},{},{"name":"Nail","sku":284758393,"color":"gray"}]}`},
+		{"[[fruits]]\nname = \"apple\"\n\n[fruits.physical]\ncolor = \"red\"\n\n[[fruits.varieties]]\nname = \"red delicious\"\n\n[[fruits.varieties]]\nname = \"granny smith\"\n\n[[fruits]]\nname = \"banana\"\n\n[[fruits.varieties]]\nname = \"plantain\"\n", `{"fruits":[{"name":"apple","physical":{"color":"red"},"varieties":[{"name":"red delicious"},{"name":"granny smith"}]},{"name":"banana","varieties":[{"name":"plantain"}]}]}`},
+		{"a = 1\r\nb = 2\r\n", `{"a":1,"b":2}`},
+	}
+
+	for _, test := range tests {
+		decoded, err := Decode([]byte(test.toml))
+		if err != nil {
+			t.Errorf("Decode(%q): %v", test.toml, err)
+			continue
+		}
+
+		if got := testtree.Marshal(t, decoded); got != test.json {
+			t.Errorf("Decode(%q) = %s, want %s", test.toml, got, test.json)
+		}
+	}
+}
+
+// integers past 2^53 are json.Numbers, since a float64 would lose digits
+func TestBigIntegers(t *testing.T) {
+	decoded, err := Decode([]byte("max = 9223372036854775807\nmin = -9223372036854775808\nhex = 0x7fff_ffff_ffff_ffff\nexact = 9007199254740992\n"))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if got := testtree.Marshal(t, decoded); got != `{"max":9223372036854775807,"min":-9223372036854775808,"hex":9223372036854775807,"exact":9007199254740992}` {
+		t.Errorf("Decode = %s", got)
+	}
+
+	if _, ok := decoded.Value("max").(json.Number); !ok {
+		t.Errorf("max = %#v, want a json.Number", decoded.Value("max"))
+	}
+
+	data, err := Encode(decoded)
+	if err != nil || string(data) != "max = 9223372036854775807\nmin = -9223372036854775808\nhex = 9223372036854775807\nexact = 9007199254740992\n" {
+		t.Errorf("Encode = %q, %v", data, err)
+	}
+
+	// past 64 bits there are only floats
+	tree := testtree.Object(t, `{}`)
+	tree.Set("n", json.Number("18446744073709551616"))
+	if data, err := Encode(tree); err != nil || string(data) != "n = 1.8446744073709552e+19\n" {
+		t.Errorf("Encode(18446744073709551616) = %q, %v", data, err)
+	}
+}
+
+func TestSpecialFloats(t *testing.T) {
+	decoded, err := Decode([]byte("sf1 = inf\nsf2 = +inf\nsf3 = -inf\nsf4 = nan\nsf5 = +nan\nsf6 = -nan\nz = -0.0\n"))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	for key, want := range map[string]float64{"sf1": math.Inf(1), "sf2": math.Inf(1), "sf3": math.Inf(-1), "z": math.Copysign(0, -1)} {
+		if got, _ := decoded.Value(key).(float64); got != want || math.Signbit(got) != math.Signbit(want) {
+			t.Errorf("%s = %v, want %v", key, decoded.Value(key), want)
+		}
+	}
+
+	for _, key := range []string{"sf4", "sf5", "sf6"} {
+		if got, _ := decoded.Value(key).(float64); !math.IsNaN(got) {
+			t.Errorf("%s = %v, want NaN", key, decoded.Value(key))
+		}
+	}
+
+	data, err := Encode(decoded)
+	if err != nil || string(data) != "sf1 = inf\nsf2 = inf\nsf3 = -inf\nsf4 = nan\nsf5 = nan\nsf6 = nan\nz = -0.0\n" {
+		t.Errorf("Encode = %q, %v", data, err)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, input := range []string{
+		"a",
+		"a =",
+		"a = 1 b = 2",
+		"= 1",
+		"a = 1\na = 2",
+		"a.b = 1\na.b.c = 2",
+		"a = \"unterminated",
+		"a = \"new\nline\"",
+		"a = 'unterminated",
+		"a = \"\"\"unterminated",
+		"a = '''unterminated",
+		"a = \"\\x\"",
+		"a = \"\\uD800\"",
+		"a = \"\\u12\"",
+		"a = [1, 2",
+		"a = [1 2]",
+		"a = { b = 1",
+		"a = { b = 1 c = 2 }",
+		"a = { b = 1 }\n[a]",
+		"a = { b = 1 }\na.c = 2",
+		"[a",
+		"[[a]",
+		"a = 1\n[a]",
+		"[a]\n[[a]]",
+		"a = 01",
+		"a = 1__0",
+		"a = _1",
+		"a = 1_",
+		"a = 1.",
+		"a = .1",
+		"a = 1e",
+		"a = infinity",
+		"a = 0x",
+		"a = 0x-1",
+		"a = 0x_1",
+		"a = 0b2",
+		"a = 9223372036854775808",
+		"a = 0x8000000000000000",
+		"a = 1.2.3",
+		"a = tru",
+		"a = \xff",
+	} {
+		if value, err := Decode([]byte(input)); err == nil {
+			t.Errorf("Decode(%q) = %s, want an error", input, testtree.Marshal(t, value))
+		}
+	}
+
+	for _, input := range []string{`{"a":null}`, `{"a":[1,null]}`, `{"a":{"b":null}}`, `{"a":{"b":[{"c":null}]}}`} {
+		if data, err := Encode(testtree.Object(t, input)); err == nil {
+			t.Errorf("Encode(%s) = %q, want an error", input, data)
+		}
+	}
+
+	for _, value := range []interface{}{json.Number("1x"), 1, map[string]interface{}{}} {
+		tree := testtree.Object(t, `{}`)
+		tree.Set("a", value)
+		if data, err := Encode(tree); err == nil {
+			t.Errorf("Encode(%#v) = %q, want an error", value, data)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+// Package transform rewrites json while it streams from a reader to a writer, for files too big to
+// load. the input is read as a stream of events (the start and end of objects and arrays, keys and
+// values) which go through a chain of processors before being written out again, so memory only
+// grows with how deeply the document nests. keys come out in the order they went in.
+//
+//	err := transform.Run(in, out,
+//		transform.Drop("users.*.password"),
+//		transform.RenameKey("users.*.mail", "email"),
+//		transform.MapValues("users.*.name", strings.ToUpper),
+//	)
+//
+// the input can be several documents in a row, like newline delimited json, and they come out one
+// per line. the output is compact.
+package transform
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type Kind int
+
+const (
+	ObjectStart Kind = iota
+	ObjectEnd
+	ArrayStart
+	ArrayEnd
+	// a key in an object, the event after it is its value
+	Key
+	// a string, json.Number, bool or nil
+	Value
+)
+
+func (kind Kind) String() string {
+	switch kind {
+	case ObjectStart:
+		return "object start"
+	case ObjectEnd:
+		return "object end"
+	case ArrayStart:
+		return "array start"
+	case ArrayEnd:
+		return "array end"
+	case Key:
+		return "key"
+	case Value:
+		return "value"
+	}
+
+	return "Kind(" + strconv.Itoa(int(kind)) + ")"
+}
+
+type Event struct {
+	Kind Kind
+	// where the event is in the input: the object or array for start and end events, and the value
+	// for keys and values. it's reused for the next event, so it has to be copied to be kept.
+	Path Path
+	// for Key events
+	Key string
+	// for Value events. processors can replace it with anything json.Marshal can write.
+	Value interface{}
+}
+
+// Path is the keys (strings) and array indexes (ints) from the top of a document to a value.
+type Path []interface{}
+
+func (path Path) String() string {
+	var result strings.Builder
+	for i, segment := range path {
+		if i > 0 {
+			result.WriteByte('.')
+		}
+
+		fmt.Fprint(&result, segment)
+	}
+
+	return result.String()
+}
+
+// Processor handles one event, and passes it on to the rest of the chain with emit: unchanged,
+// changed, not at all or as several events.
+type Processor func(event Event, emit func(Event) error) error
+
+// Run reads json from r, passes every event through processors in order, and writes the result to w.
+func Run(r io.Reader, w io.Writer, processors ...Processor) error {
+	out := bufio.NewWriter(w)
+	writer := &eventWriter{out: out}
+
+	emit := writer.write
+	for i := len(processors) - 1; i >= 0; i-- {
+		process, next := processors[i], emit
+		emit = func(event Event) error {
+			return process(event, next)
+		}
+	}
+
+	if err := read(r, emit); err != nil {
+		return err
+	}
+
+	if err := writer.finish(); err != nil {
+		return err
+	}
+
+	return out.Flush()
+}
+
+// Drop removes the values matching pattern, and their keys.
+func Drop(pattern string) Processor {
+	match := compilePattern(pattern)
+	return func(event Event, emit func(Event) error) error {
+		if match.prefixOf(event.Path) {
+			return nil
+		}
+
+		return emit(event)
+	}
+}
+
+// RenameKey renames the keys of the values matching pattern to name. paths in later events still
+// have the old key, since they're where things are in the input.
+func RenameKey(pattern, name string) Processor {
+	match := compilePattern(pattern)
+	return func(event Event, emit func(Event) error) error {
+		if event.Kind == Key && match.matches(event.Path) {
+			event.Key = name
+		}
+
+		return emit(event)
+	}
+}
+
+// MapValues replaces the strings matching pattern with what fn returns.
+func MapValues(pattern string, fn func(string) string) Processor {
+	return RewriteValues(pattern, func(path Path, value interface{}) (interface{}, error) {
+		if s, ok := value.(string); ok {
+			return fn(s), nil
+		}
+
+		return value, nil
+	})
+}
+
+// RewriteValues replaces the values (strings, numbers, booleans and nulls, not objects or arrays)
+// matching pattern with what fn returns.
+func RewriteValues(pattern string, fn func(path Path, value interface{}) (interface{}, error)) Processor {
+	match := compilePattern(pattern)
+	return func(event Event, emit func(Event) error) error {
+		if event.Kind == Value && match.matches(event.Path) {
+			value, err := fn(event.Path, event.Value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", event.Path, err)
+			}
+
+			event.Value = value
+		}
+
+		return emit(event)
+	}
+}
+
+// a dot separated path where * is any key or index, e.g. "users.*.name". numbers match indexes as
+// well as keys.
+type pathPattern []string
+
+func compilePattern(pattern string) pathPattern {
+	if pattern == "" {
+		return pathPattern{}
+	}
+
+	return strings.Split(pattern, ".")
+}
+
+func (pattern pathPattern) matches(path Path) bool {
+	return len(path) == len(pattern) && pattern.prefixOf(path)
+}
+
+// whether path is a value matching the pattern, or inside one
+func (pattern pathPattern) prefixOf(path Path) bool {
+	if len(path) < len(pattern) {
+		return false
+	}
+
+	for i, segment := range pattern {
+		if segment == "*" {
+			continue
+		}
+
+		switch key := path[i].(type) {
+		case string:
+			if key != segment {
+				return false
+			}
+		case int:
+			if strconv.Itoa(key) != segment {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// reads every document in r as events
+func read(r io.Reader, emit func(Event) error) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	type frame struct {
+		object bool
+		// the index of the next item of an array
+		next int
+	}
+
+	var (
+		stack []frame
+		path  Path
+		// whether the next string is a key, in an object
+		wantKey bool
+	)
+
+	// the path of the value that's starting, which moves on to the next index in arrays
+	enterValue := func() {
+		if len(stack) > 0 && !stack[len(stack)-1].object {
+			top := &stack[len(stack)-1]
+			path = append(path, top.next)
+			top.next++
+		}
+	}
+
+	// back out of the value that just ended, to its container
+	leaveValue := func() {
+		if len(stack) > 0 {
+			path = path[:len(path)-1]
+			wantKey = stack[len(stack)-1].object
+		}
+	}
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			// Token doesn't tell a cut off document from the end of the input
+			if len(stack) > 0 {
+				return io.ErrUnexpectedEOF
+			}
+
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if key, ok := token.(string); ok && wantKey {
+			path = append(path, key)
+			wantKey = false
+			if err := emit(Event{Kind: Key, Path: path, Key: key}); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			object := token == json.Delim('{')
+			enterValue()
+			kind := ArrayStart
+			if object {
+				kind = ObjectStart
+			}
+
+			if err := emit(Event{Kind: kind, Path: path}); err != nil {
+				return err
+			}
+
+			stack = append(stack, frame{object: object})
+			wantKey = object
+		case json.Delim('}'), json.Delim(']'):
+			kind := ArrayEnd
+			if token == json.Delim('}') {
+				kind = ObjectEnd
+			}
+
+			stack = stack[:len(stack)-1]
+			if err := emit(Event{Kind: kind, Path: path}); err != nil {
+				return err
+			}
+
+			leaveValue()
+		default:
+			enterValue()
+			if err := emit(Event{Kind: Value, Path: path, Value: token}); err != nil {
+				return err
+			}
+
+			leaveValue()
+		}
+	}
+}
+
+// writes events back out as compact json, one document per line
+type eventWriter struct {
+	out *bufio.Writer
+
+	stack []writerFrame
+	// whether a document was written already
+	started bool
+	scratch bytes.Buffer
+}
+
+type writerFrame struct {
+	object bool
+	// how many items or keys were written
+	count int
+	// a key was written, and its value is next
+	keyed bool
+}
+
+func (writer *eventWriter) write(event Event) error {
+	switch event.Kind {
+	case Key:
+		if len(writer.stack) == 0 || !writer.top().object || writer.top().keyed {
+			return fmt.Errorf("%s: key %q outside of an object", event.Path, event.Key)
+		}
+
+		top := writer.top()
+		if top.count > 0 {
+			writer.out.WriteByte(',')
+		}
+
+		top.count++
+		top.keyed = true
+		if err := writer.value(event.Key); err != nil {
+			return err
+		}
+
+		return writer.out.WriteByte(':')
+	case ObjectEnd, ArrayEnd:
+		if len(writer.stack) == 0 || writer.top().object != (event.Kind == ObjectEnd) || writer.top().keyed {
+			return fmt.Errorf("%s: unexpected %s", event.Path, event.Kind)
+		}
+
+		writer.stack = writer.stack[:len(writer.stack)-1]
+		if event.Kind == ObjectEnd {
+			return writer.out.WriteByte('}')
+		}
+
+		return writer.out.WriteByte(']')
+	}
+
+	if err := writer.beforeValue(event); err != nil {
+		return err
+	}
+
+	switch event.Kind {
+	case ObjectStart:
+		writer.stack = append(writer.stack, writerFrame{object: true})
+		return writer.out.WriteByte('{')
+	case ArrayStart:
+		writer.stack = append(writer.stack, writerFrame{})
+		return writer.out.WriteByte('[')
+	case Value:
+		return writer.value(event.Value)
+	}
+
+	return fmt.Errorf("%s: unknown event %s", event.Path, event.Kind)
+}
+
+// the comma or line break before a value, and checks that it can go where it is
+func (writer *eventWriter) beforeValue(event Event) error {
+	if len(writer.stack) == 0 {
+		if writer.started {
+			writer.out.WriteByte('\n')
+		}
+
+		writer.started = true
+		return nil
+	}
+
+	top := writer.top()
+	if top.object {
+		if !top.keyed {
+			return fmt.Errorf("%s: %s without a key in an object", event.Path, event.Kind)
+		}
+
+		top.keyed = false
+		return nil
+	}
+
+	if top.count > 0 {
+		writer.out.WriteByte(',')
+	}
+
+	top.count++
+	return nil
+}
+
+func (writer *eventWriter) top() *writerFrame {
+	return &writer.stack[len(writer.stack)-1]
+}
+
+func (writer *eventWriter) value(value interface{}) error {
+	if number, ok := value.(json.Number); ok {
+		_, err := writer.out.WriteString(number.String())
+		return err
+	}
+
+	writer.scratch.Reset()
+	encoder := json.NewEncoder(&writer.scratch)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+
+	_, err := writer.out.Write(bytes.TrimSuffix(writer.scratch.Bytes(), []byte("\n")))
+	return err
+}
+
+func (writer *eventWriter) finish() error {
+	if len(writer.stack) > 0 {
+		return errors.New("the output ended inside an object or array")
+	}
+
+	if writer.started {
+		return writer.out.WriteByte('\n')
+	}
+
+	return nil
+}
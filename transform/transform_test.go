@@ -0,0 +1,213 @@
+package transform
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func run(input string, processors ...Processor) (string, error) {
+	var out bytes.Buffer
+	err := Run(strings.NewReader(input), &out, processors...)
+	return out.String(), err
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		input      string
+		processors []Processor
+		want       string
+	}{
+		{`{"z": 1, "a": [true, null, "x"], "m": {}}`, nil, "{\"z\":1,\"a\":[true,null,\"x\"],\"m\":{}}\n"},
+		{`{"a":1} {"b":2}` + "\n" + `[3] "four" 5`, nil, "{\"a\":1}\n{\"b\":2}\n[3]\n\"four\"\n5\n"},
+		{``, nil, ``},
+		{` `, nil, ``},
+		{`{"n": 12345678901234567890, "f": 1.50, "e": 1E+2}`, nil, "{\"n\":12345678901234567890,\"f\":1.50,\"e\":1E+2}\n"},
+		{"{\"s\": \"<&>\u2028\\\"\\\\é\"}", nil, "{\"s\":\"<&>\\u2028\\\"\\\\é\"}\n"},
+		{`[[], {}, [[{}]]]`, nil, "[[],{},[[{}]]]\n"},
+
+		{`{"a": 1, "b": 2, "c": 3}`, []Processor{Drop("a")}, "{\"b\":2,\"c\":3}\n"},
+		{`{"a": 1, "b": 2, "c": 3}`, []Processor{Drop("b")}, "{\"a\":1,\"c\":3}\n"},
+		{`{"a": 1, "b": 2, "c": 3}`, []Processor{Drop("c")}, "{\"a\":1,\"b\":2}\n"},
+		{`{"a": {"deep": [1, {"x": 2}]}, "b": 2}`, []Processor{Drop("a")}, "{\"b\":2}\n"},
+		{`{"a": 1}`, []Processor{Drop("a"), Drop("missing")}, "{}\n"},
+		{`[0, 1, 2]`, []Processor{Drop("0")}, "[1,2]\n"},
+		{`[0, 1, 2]`, []Processor{Drop("1")}, "[0,2]\n"},
+		{`[0, [1, 2], 3]`, []Processor{Drop("1.0")}, "[0,[2],3]\n"},
+		{`{"0": "key", "list": ["item"]}`, []Processor{Drop("0"), Drop("list.0")}, "{\"list\":[]}\n"},
+		{`{"users": [{"name": "a", "password": "x"}, {"password": "y", "name": "b"}]}`, []Processor{Drop("users.*.password")}, "{\"users\":[{\"name\":\"a\"},{\"name\":\"b\"}]}\n"},
+		{`{"a": {"x": 1, "y": 2}, "b": {"x": 3}}`, []Processor{Drop("*.x")}, "{\"a\":{\"y\":2},\"b\":{}}\n"},
+		{`{"a": 1} [2] 3`, []Processor{Drop("a"), Drop("0")}, "{}\n[]\n3\n"},
+		{`{"a": 1} {"b": 2}`, []Processor{Drop("")}, ``},
+
+		{`{"mail": "a", "other": 1}`, []Processor{RenameKey("mail", "email")}, "{\"email\":\"a\",\"other\":1}\n"},
+		{`{"users": [{"mail": "a"}, {"mail": "b", "x": {"mail": "c"}}]}`, []Processor{RenameKey("users.*.mail", "email")}, "{\"users\":[{\"email\":\"a\"},{\"email\":\"b\",\"x\":{\"mail\":\"c\"}}]}\n"},
+		{`{"a": {"b": 1}}`, []Processor{RenameKey("a", "c"), Drop("a.b")}, "{\"c\":{}}\n"},
+
+		{`{"name": "a", "n": 1, "list": ["b", 2, null]}`, []Processor{MapValues("name", strings.ToUpper), MapValues("n", strings.ToUpper), MapValues("list.*", strings.ToUpper)}, "{\"name\":\"A\",\"n\":1,\"list\":[\"B\",2,null]}\n"},
+		{`{"obj": {"a": "x"}}`, []Processor{MapValues("obj", strings.ToUpper)}, "{\"obj\":{\"a\":\"x\"}}\n"},
+		{`{"n": 1, "s": "x"}`, []Processor{RewriteValues("*", func(path Path, value interface{}) (interface{}, error) {
+			return map[string]interface{}{"was": value, "at": path.String()}, nil
+		})}, "{\"n\":{\"at\":\"n\",\"was\":1},\"s\":{\"at\":\"s\",\"was\":\"x\"}}\n"},
+	}
+
+	for _, test := range tests {
+		got, err := run(test.input, test.processors...)
+		if err != nil {
+			t.Errorf("Run(%s): %v", test.input, err)
+			continue
+		}
+
+		if got != test.want {
+			t.Errorf("Run(%s) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	var events []string
+	record := func(event Event, emit func(Event) error) error {
+		text := event.Kind.String() + " " + event.Path.String()
+		if event.Kind == Key {
+			text += " " + event.Key
+		}
+		if event.Kind == Value {
+			text += fmt.Sprintf(" %v", event.Value)
+		}
+
+		events = append(events, text)
+		return emit(event)
+	}
+
+	if _, err := run(`{"a": [1, {"b": null}], "c": "d"} 2`, record); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	want := []string{
+		"object start ",
+		"key a a",
+		"array start a",
+		"value a.0 1",
+		"object start a.1",
+		"key a.1.b b",
+		"value a.1.b <nil>",
+		"object end a.1",
+		"array end a",
+		"key c c",
+		"value c d",
+		"object end ",
+		"value  2",
+	}
+
+	if strings.Join(events, "\n") != strings.Join(want, "\n") {
+		t.Errorf("events =\n%s\nwant\n%s", strings.Join(events, "\n"), strings.Join(want, "\n"))
+	}
+
+	if got := Kind(9).String(); got != "Kind(9)" {
+		t.Errorf("Kind(9).String() = %s", got)
+	}
+}
+
+// processors can emit several events for one, like wrapping every value in an array
+func TestEmitMore(t *testing.T) {
+	wrap := func(event Event, emit func(Event) error) error {
+		if event.Kind != Value || len(event.Path) == 0 {
+			return emit(event)
+		}
+
+		for _, e := range []Event{{Kind: ArrayStart, Path: event.Path}, event, event, {Kind: ArrayEnd, Path: event.Path}} {
+			if err := emit(e); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if got, err := run(`{"a": 1, "b": [2]}`, wrap); err != nil || got != "{\"a\":[1,1],\"b\":[[2,2]]}\n" {
+		t.Errorf("Run = %q, %v", got, err)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	for _, input := range []string{
+		`{"a": 1`,
+		`[1, 2`,
+		`{"a":`,
+		`{"a"`,
+		`{`,
+		`"abc`,
+		`{"a": 1}}`,
+		`{"a": 1} x`,
+		`[1,]`,
+		`{"a" 1}`,
+		`{1: 2}`,
+		`nul`,
+	} {
+		if got, err := run(input); err == nil {
+			t.Errorf("Run(%s) = %q, want an error", input, got)
+		}
+
+		// even when nothing of the document is written
+		if got, err := run(input, Drop("")); err == nil {
+			t.Errorf("Run(%s, Drop(\"\")) = %q, want an error", input, got)
+		}
+	}
+
+	if _, err := run(`{"a": 1`); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Run(cut off) = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	failing := RewriteValues("a.*", func(path Path, value interface{}) (interface{}, error) {
+		return nil, errors.New("no")
+	})
+	if _, err := run(`{"a": [1]}`, failing); err == nil || err.Error() != "a.0: no" {
+		t.Errorf("Run(failing) = %v, want a.0: no", err)
+	}
+
+	// events that don't make json
+	for name, processor := range map[string]Processor{
+		"key outside an object": func(event Event, emit func(Event) error) error {
+			if event.Kind == ArrayStart {
+				return emit(Event{Kind: Key, Key: "k"})
+			}
+
+			return emit(event)
+		},
+		"value without a key": func(event Event, emit func(Event) error) error {
+			if event.Kind == Key {
+				return nil
+			}
+
+			return emit(event)
+		},
+		"unclosed": func(event Event, emit func(Event) error) error {
+			if event.Kind == ArrayEnd {
+				return nil
+			}
+
+			return emit(event)
+		},
+		"wrong end": func(event Event, emit func(Event) error) error {
+			if event.Kind == ArrayEnd {
+				event.Kind = ObjectEnd
+			}
+
+			return emit(event)
+		},
+		"unmarshalable value": func(event Event, emit func(Event) error) error {
+			if event.Kind == Value {
+				event.Value = func() {}
+			}
+
+			return emit(event)
+		},
+	} {
+		if got, err := run(`{"a": [1]}`, processor); err == nil {
+			t.Errorf("Run with %s = %q, want an error", name, got)
+		}
+	}
+}
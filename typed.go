@@ -0,0 +1,117 @@
+package orderedjson
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// Typed is an object whose values all have the same type, like "scripts" (Typed[string]) or
+// "dependencies" in a package.json, so Get and Set don't need type assertions. it keeps the order
+// of its keys like JsonObject, and marshals to and from json the same way.
+type Typed[V any] struct {
+	*orderedmap.OrderedMap[string, V]
+}
+
+func NewTyped[V any]() Typed[V] {
+	return Typed[V]{orderedmap.New[string, V]()}
+}
+
+// TypedOf decodes the values of object into V. values that aren't a V already go through json, so
+// numbers can become ints and objects can become structs.
+func TypedOf[V any](object *JsonObject) (Typed[V], error) {
+	typed := NewTyped[V]()
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		value, err := decodeAs[V](pair.Value)
+		if err != nil {
+			return Typed[V]{}, &UnmarshalTypeError{Value: TypeName(pair.Value), Type: reflect.TypeOf((*V)(nil)).Elem(), Path: Path{pair.Key}}
+		}
+
+		typed.Set(pair.Key, value)
+	}
+
+	return typed, nil
+}
+
+// TypedAt is TypedOf for the object at key in tree. a missing key gives an empty object, since most
+// of package.json is optional.
+func TypedAt[V any](tree *JsonObject, key string) (Typed[V], error) {
+	value, ok := tree.Get(key)
+	if !ok {
+		return NewTyped[V](), nil
+	}
+
+	object, ok := value.(*JsonObject)
+	if !ok {
+		return Typed[V]{}, &UnmarshalTypeError{Value: TypeName(value), Type: reflect.TypeOf(Typed[V]{}), Path: Path{key}}
+	}
+
+	typed, err := TypedOf[V](object)
+	var typeErr *UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		typeErr.Path = append(Path{key}, typeErr.Path...)
+	}
+
+	return typed, err
+}
+
+// Object converts typed back to a tree, e.g. to Set it in a document.
+func (typed Typed[V]) Object() (*JsonObject, error) {
+	object := orderedmap.New[string, interface{}]()
+	if typed.OrderedMap == nil {
+		return object, nil
+	}
+
+	for pair := typed.Oldest(); pair != nil; pair = pair.Next() {
+		value, err := fromStdValue(pair.Value, strings.Compare)
+		if err != nil {
+			// structs and such
+			data, err := json.Marshal(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			if value, err = FromStdJSONValue(data); err != nil {
+				return nil, err
+			}
+		}
+
+		object.Set(pair.Key, value)
+	}
+
+	return object, nil
+}
+
+func (typed *Typed[V]) UnmarshalJSON(data []byte) error {
+	if typed.OrderedMap == nil {
+		typed.OrderedMap = orderedmap.New[string, V]()
+	}
+
+	return typed.OrderedMap.UnmarshalJSON(data)
+}
+
+func (typed Typed[V]) MarshalJSON() ([]byte, error) {
+	if typed.OrderedMap == nil {
+		return []byte("{}"), nil
+	}
+
+	return typed.OrderedMap.MarshalJSON()
+}
+
+func decodeAs[V any](value interface{}) (V, error) {
+	if v, ok := value.(V); ok {
+		return v, nil
+	}
+
+	var result V
+	data, err := marshalValue(value)
+	if err != nil {
+		return result, err
+	}
+
+	err = json.Unmarshal([]byte(data), &result)
+	return result, err
+}
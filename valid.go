@@ -0,0 +1,637 @@
+package orderedjson
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf8"
+
+	"github.com/michaelhelvey/orderedjson/v2/internal/scan"
+	"github.com/michaelhelvey/orderedjson/v2/internal/textenc"
+)
+
+// Valid reports whether data is a document, for services that only need to check a payload before
+// passing it on. it accepts what Parse with the default options does (an object at the top, nothing
+// after it, no numbers too big for a float64), but goes through the bytes with the same state
+// machine as Compact and Indent instead of building a tree, so it doesn't allocate for UTF-8 input.
+func Valid(data []byte) bool {
+	data, err := textenc.ToUTF8(data)
+	if err != nil {
+		return false
+	}
+
+	v := validator{line: 1, column: 1, document: true, maxDepth: DefaultMaxDepth}
+	if err := v.write(bytes.TrimPrefix(data, utf8BOM)); err != nil {
+		return false
+	}
+
+	return v.close() == nil
+}
+
+// ValidReader is Valid for what r reads, and says what's wrong where with a *SyntaxError. r is read
+// a piece at a time, so memory use only grows with how deeply the document nests.
+func ValidReader(r io.Reader) error {
+	input := bufio.NewReader(textenc.NewReader(r))
+	prefix, err := input.Peek(len(utf8BOM))
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	if bytes.Equal(prefix, utf8BOM) {
+		input.Discard(len(utf8BOM))
+	}
+
+	v := newValidator()
+	v.document, v.maxDepth = true, DefaultMaxDepth
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := input.Read(buf)
+		if n > 0 {
+			if err := v.write(buf[:n]); err != nil {
+				return err
+			}
+		}
+
+		if err == io.EOF {
+			return v.close()
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+type validatorState int
+
+const (
+	// before a value, and whether it's the first item of an array that can also be closed
+	expectValue validatorState = iota
+	expectFirstItem
+	// before a key, and whether the object can also be closed
+	expectKey
+	expectFirstKey
+	expectColon
+	// after a value: a comma, the end of the container or the end of the document
+	afterValue
+	inString
+	// after a backslash, and in the 4 hex digits of \u
+	inEscape
+	inUnicode
+	// the rest of true, false or null
+	inLiteral
+	// where in a number: after -, after a leading 0, in the integer part, after the point, in the
+	// fraction, after the e, after its sign, in the exponent
+	afterMinus
+	afterZero
+	inInteger
+	afterPoint
+	inFraction
+	afterE
+	afterExponentSign
+	inExponent
+)
+
+// checks json fed to it in pieces, one byte at a time except for the insides of strings. streams
+// use it to find where each document ends, and Compact and Indent to write json as it comes in,
+// without holding all of it. what a document is is up to the parser.
+type validator struct {
+	state validatorState
+	// the open objects and arrays, { or [. the first ones go in shallow, so a document that doesn't
+	// nest deeply is checked without allocating
+	shallow [32]byte
+	deep    []byte
+	depth   int
+	// whether the string being read is a key
+	key bool
+	// what's left of the literal, or how many hex digits are left of a \u escape
+	literal string
+	digits  int
+
+	offset, line, column int
+
+	// writes the document back out as it's checked, for Compact and Indent
+	format *tokenFormatter
+
+	// checks what Parse does on top of the json grammar, for Valid: an object at the top level, no
+	// deeper than maxDepth, and no lone surrogates in \u escapes
+	document bool
+	maxDepth int
+	// the value of the \u escape being read, and a high surrogate still waiting for its low one
+	unit, high rune
+	// the number being read, and where it started
+	number                              numberSize
+	numberOffset, numberLine, numberCol int
+}
+
+func newValidator() *validator {
+	return &validator{line: 1, column: 1}
+}
+
+func (v *validator) fail(kind error, format string, args ...interface{}) error {
+	err := newSyntaxError(nil, 0, kind, format, args...)
+	err.Offset, err.Line, err.Column = v.offset, v.line, v.column
+	return err
+}
+
+func (v *validator) unexpected(c byte) error {
+	return v.fail(ErrInvalidToken, "invalid character %q %s", c, v.expecting())
+}
+
+func (v *validator) expecting() string {
+	switch v.state {
+	case expectValue, expectFirstItem:
+		return "looking for a value"
+	case expectKey, expectFirstKey:
+		return "looking for a key"
+	case expectColon:
+		return "after a key"
+	case afterValue:
+		return "after a value"
+	case inString, inEscape, inUnicode:
+		return "in a string"
+	case inLiteral:
+		return "in a literal"
+	}
+
+	return "in a number"
+}
+
+func (v *validator) write(data []byte) error {
+	for i := 0; i < len(data); {
+		c := data[i]
+
+		if v.state == inString {
+			// plain string content goes by in one go
+			n := scan.IndexStringEnd(data[i:])
+			if n < 0 {
+				n = len(data) - i
+			}
+
+			if n > 0 {
+				if v.high != 0 {
+					return v.loneSurrogate()
+				}
+
+				if v.format != nil {
+					v.format.out.Write(data[i : i+n])
+				}
+
+				v.offset += n
+				v.column += utf8.RuneCount(data[i : i+n])
+				i += n
+				continue
+			}
+		}
+
+		before := v.state
+		reprocess, err := v.step(c)
+		if err != nil {
+			return err
+		}
+
+		if reprocess {
+			// the byte ended a number, and has to be looked at again after it
+			continue
+		}
+
+		if v.format != nil {
+			v.format.write(before, c)
+		}
+
+		v.offset++
+		if c == '\n' {
+			v.line++
+			v.column = 1
+		} else if c&0xc0 != 0x80 {
+			v.column++
+		}
+
+		i++
+	}
+
+	return nil
+}
+
+// handles one byte. it returns true when the byte wasn't used up because it ended a number.
+func (v *validator) step(c byte) (bool, error) {
+	switch v.state {
+	case expectValue, expectFirstItem:
+		if isSpace(c) {
+			return false, nil
+		}
+
+		if c == ']' && v.state == expectFirstItem {
+			return false, v.closeContainer(c)
+		}
+
+		return false, v.startValue(c)
+	case expectKey, expectFirstKey:
+		switch {
+		case isSpace(c):
+		case c == '"':
+			v.state, v.key = inString, true
+		case c == '}' && v.state == expectFirstKey:
+			return false, v.closeContainer(c)
+		default:
+			return false, v.unexpected(c)
+		}
+	case expectColon:
+		switch {
+		case isSpace(c):
+		case c == ':':
+			v.state = expectValue
+		default:
+			return false, v.unexpected(c)
+		}
+	case afterValue:
+		switch {
+		case isSpace(c):
+		case c == ',' && v.depth > 0:
+			if v.top() == '{' {
+				v.state = expectKey
+			} else {
+				v.state = expectValue
+			}
+		case c == '}' || c == ']':
+			return false, v.closeContainer(c)
+		default:
+			return false, v.unexpected(c)
+		}
+	case inString:
+		switch {
+		case v.high != 0 && c != '\\':
+			return false, v.loneSurrogate()
+		case c == '"':
+			v.endValue()
+		case c == '\\':
+			v.state = inEscape
+		default:
+			return false, v.fail(ErrInvalidToken, "invalid control character %q in a string", c)
+		}
+	case inEscape:
+		if v.high != 0 && c != 'u' {
+			return false, v.loneSurrogate()
+		}
+
+		switch c {
+		case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+			v.state = inString
+		case 'u':
+			v.state, v.digits, v.unit = inUnicode, 4, 0
+		default:
+			return false, v.fail(ErrInvalidEscape, "invalid escape \\%c", c)
+		}
+	case inUnicode:
+		if !isHexDigit(c) {
+			return false, v.fail(ErrInvalidEscape, "invalid character %q in a \\u escape", c)
+		}
+
+		v.unit = v.unit<<4 | hexValue(c)
+		if v.digits--; v.digits == 0 {
+			v.state = inString
+			if v.document {
+				return false, v.checkSurrogate()
+			}
+		}
+	case inLiteral:
+		if c != v.literal[0] {
+			return false, v.unexpected(c)
+		}
+
+		if v.literal = v.literal[1:]; v.literal == "" {
+			v.endValue()
+		}
+	default:
+		return v.stepNumber(c)
+	}
+
+	return false, nil
+}
+
+func (v *validator) startValue(c byte) error {
+	if v.document && v.depth == 0 && c != '{' {
+		return v.fail(ErrInvalidToken, "invalid character %q, expected an object at the top level", c)
+	}
+
+	if (c == '{' || c == '[') && v.maxDepth > 0 && v.depth >= v.maxDepth {
+		return v.fail(ErrTooDeep, "objects and arrays are nested more than %d deep", v.maxDepth)
+	}
+
+	switch {
+	case c == '{':
+		v.push(c)
+		v.state = expectFirstKey
+	case c == '[':
+		v.push(c)
+		v.state = expectFirstItem
+	case c == '"':
+		v.state, v.key = inString, false
+	case c == 't':
+		v.state, v.literal = inLiteral, "rue"
+	case c == 'f':
+		v.state, v.literal = inLiteral, "alse"
+	case c == 'n':
+		v.state, v.literal = inLiteral, "ull"
+	case c == '-':
+		v.state = afterMinus
+	case c == '0':
+		v.state = afterZero
+	case c >= '1' && c <= '9':
+		v.state = inInteger
+	default:
+		return v.unexpected(c)
+	}
+
+	if v.state == afterMinus || v.state == afterZero || v.state == inInteger {
+		v.number = numberSize{}
+		v.numberOffset, v.numberLine, v.numberCol = v.offset, v.line, v.column
+		v.number.integerDigit(c)
+	}
+
+	return nil
+}
+
+func (v *validator) stepNumber(c byte) (bool, error) {
+	digit := c >= '0' && c <= '9'
+	switch v.state {
+	case afterMinus:
+		switch {
+		case c == '0':
+			v.state = afterZero
+		case digit:
+			v.state = inInteger
+		default:
+			return false, v.unexpected(c)
+		}
+
+		v.number.integerDigit(c)
+		return false, nil
+	case afterZero, inInteger:
+		switch {
+		case digit && v.state == inInteger:
+			v.number.integerDigit(c)
+		case digit:
+			return false, v.fail(ErrInvalidToken, "invalid number: leading zeros aren't allowed")
+		case c == '.':
+			v.state = afterPoint
+		case c == 'e' || c == 'E':
+			v.state = afterE
+		default:
+			return true, v.endNumber()
+		}
+
+		return false, nil
+	case afterPoint:
+		if !digit {
+			return false, v.unexpected(c)
+		}
+
+		v.state = inFraction
+		v.number.fractionDigit(c)
+		return false, nil
+	case inFraction:
+		switch {
+		case digit:
+			v.number.fractionDigit(c)
+		case c == 'e' || c == 'E':
+			v.state = afterE
+		default:
+			return true, v.endNumber()
+		}
+
+		return false, nil
+	case afterE:
+		switch {
+		case c == '+' || c == '-':
+			v.state = afterExponentSign
+			v.number.negative = c == '-'
+		case digit:
+			v.state = inExponent
+			v.number.exponentDigit(c)
+		default:
+			return false, v.unexpected(c)
+		}
+
+		return false, nil
+	case afterExponentSign:
+		if !digit {
+			return false, v.unexpected(c)
+		}
+
+		v.state = inExponent
+		v.number.exponentDigit(c)
+		return false, nil
+	}
+
+	// in the exponent
+	if digit {
+		v.number.exponentDigit(c)
+		return false, nil
+	}
+
+	return true, v.endNumber()
+}
+
+// for Valid, a number has to fit in a float64 like it does for Parse
+func (v *validator) endNumber() error {
+	v.endValue()
+	if !v.document || !v.number.overflows() {
+		return nil
+	}
+
+	err := newSyntaxError(nil, 0, ErrInvalidToken, "number is out of range")
+	err.Offset, err.Line, err.Column = v.numberOffset, v.numberLine, v.numberCol
+	return err
+}
+
+// the digits of 2^1024 - 2^970, halfway between the largest float64 and the next one up, which is
+// the smallest number that parses to infinity
+const float64Limit = "179769313486231580793728971405303415079934132710037826936173778980444968292764750946649017977587207096330286416692887910946555547851940402630657488671505820681908902000708383676273854845817711531764475730270069855571366959622842914819860834936475292719074168444365510704342711559699508093042880177904174497792"
+
+// how big a number is, kept as its digits go by so they don't have to be: the number is 0.d × 10^n,
+// where d are its digits from the first one that isn't 0, and n is worked out from where the point
+// is and the exponent. d is only compared with the digits of float64Limit, as far as they're equal.
+type numberSize struct {
+	// whether a digit that isn't 0 has come yet
+	significant bool
+	// n without the exponent
+	point int
+	// how d compares with float64Limit so far, and how many digits of it have been compared
+	compared, digits int
+	exponent         int
+	negative         bool
+}
+
+func (size *numberSize) integerDigit(c byte) {
+	if c == '-' || c == '0' && !size.significant {
+		return
+	}
+
+	size.significant = true
+	size.point++
+	size.compare(c)
+}
+
+func (size *numberSize) fractionDigit(c byte) {
+	if c == '0' && !size.significant {
+		size.point--
+		return
+	}
+
+	size.significant = true
+	size.compare(c)
+}
+
+func (size *numberSize) exponentDigit(c byte) {
+	// far past anything a float64 holds either way
+	if size.exponent < 1<<20 {
+		size.exponent = size.exponent*10 + int(c-'0')
+	}
+}
+
+func (size *numberSize) compare(c byte) {
+	if size.compared == 0 {
+		switch {
+		case size.digits >= len(float64Limit):
+			if c != '0' {
+				size.compared = 1
+			}
+		case c > float64Limit[size.digits]:
+			size.compared = 1
+		case c < float64Limit[size.digits]:
+			size.compared = -1
+		}
+	}
+
+	size.digits++
+}
+
+// whether the number is too big for a float64. the ones too small for one are 0.
+func (size *numberSize) overflows() bool {
+	if !size.significant {
+		return false
+	}
+
+	n := size.point
+	if size.negative {
+		n -= size.exponent
+	} else {
+		n += size.exponent
+	}
+
+	// shorter than float64Limit and equal as far as it goes is smaller, since it doesn't end in 0
+	return n > len(float64Limit) || n == len(float64Limit) && (size.compared > 0 || size.compared == 0 && size.digits >= len(float64Limit))
+}
+
+// a high surrogate has to be followed right away by an escaped low one, and a low one can't come
+// on its own
+func (v *validator) checkSurrogate() error {
+	low := v.unit >= 0xdc00 && v.unit <= 0xdfff
+	if v.high != 0 && !low || v.high == 0 && low {
+		return v.loneSurrogate()
+	}
+
+	v.high = 0
+	if v.unit >= 0xd800 && v.unit <= 0xdbff && !low {
+		v.high = v.unit
+	}
+
+	return nil
+}
+
+func (v *validator) loneSurrogate() error {
+	if v.high != 0 {
+		return v.fail(ErrInvalidEscape, "lone surrogate \\u%04x in string", v.high)
+	}
+
+	return v.fail(ErrInvalidEscape, "lone surrogate \\u%04x in string", v.unit)
+}
+
+func (v *validator) endValue() {
+	if v.state == inString && v.key {
+		v.state = expectColon
+		return
+	}
+
+	v.state = afterValue
+}
+
+func (v *validator) closeContainer(c byte) error {
+	if v.depth == 0 {
+		return v.unexpected(c)
+	}
+
+	open := v.top()
+	if open == '{' && c != '}' || open == '[' && c != ']' {
+		return v.unexpected(c)
+	}
+
+	v.pop()
+	v.state = afterValue
+	return nil
+}
+
+func (v *validator) push(c byte) {
+	if v.depth < len(v.shallow) {
+		v.shallow[v.depth] = c
+	} else {
+		v.deep = append(v.deep, c)
+	}
+
+	v.depth++
+}
+
+// the innermost open object or array
+func (v *validator) top() byte {
+	if v.depth <= len(v.shallow) {
+		return v.shallow[v.depth-1]
+	}
+
+	return v.deep[v.depth-1-len(v.shallow)]
+}
+
+func (v *validator) pop() {
+	v.depth--
+	if v.depth >= len(v.shallow) {
+		v.deep = v.deep[:v.depth-len(v.shallow)]
+	}
+}
+
+// the end of the input, which can end a number but nothing else
+func (v *validator) close() error {
+	switch v.state {
+	case afterZero, inInteger, inFraction, inExponent:
+		v.state = afterValue
+	case expectValue:
+		// only whitespace, if anything
+		if v.depth == 0 {
+			return ErrEmptyDocument
+		}
+	}
+
+	if v.state != afterValue || v.depth > 0 {
+		return v.fail(ErrUnexpectedEOF, "unexpected end of input %s", v.expecting())
+	}
+
+	return nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func hexValue(c byte) rune {
+	switch {
+	case c >= 'a':
+		return rune(c-'a') + 10
+	case c >= 'A':
+		return rune(c-'A') + 10
+	}
+
+	return rune(c - '0')
+}
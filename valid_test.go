@@ -0,0 +1,201 @@
+package orderedjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{`{}`, true},
+		{`{"a":[]}`, true},
+		{`{"a":{"b":[1,"x",null]}}`, true},
+		{" {\"a\": 1}\n", true},
+		{`[]`, false},
+		{`1`, false},
+		{`null`, false},
+		{``, false},
+		{`{"a":1}{"b":2}`, false},
+		{`{"a":1}@`, false},
+		{`{"a":1,}`, false},
+		{`{"a":01}`, false},
+		{"{\"a\":\"\t\"}", false},
+		{"\xef\xbb\xbf{\"a\": 1}", true},
+		{"{\x00\"\x00a\x00\"\x00:\x001\x00}\x00", true},
+		{`{"a":"\ud83d\ude00"}`, true},
+		{`{"a":"\ud83d"}`, false},
+		{`{"a":"\ud83dx"}`, false},
+		{`{"a":"\ud83d\u0041"}`, false},
+		{`{"a":"\ude00"}`, false},
+		{`{"a":"\n\u00e9\/"}`, true},
+		// numbers a float64 can't hold, which Parse rejects
+		{`{"a":1e400}`, false},
+		{`{"a":[-1e400]}`, false},
+		{`{"a":1e99999999999999999999}`, false},
+		{`{"a":1e-400}`, true},
+		{`{"a":0e99999}`, true},
+		{`{"a":1.7976931348623157e308}`, true},
+		{`{"a":1.7976931348623158e308}`, true},
+		{`{"a":1.7976931348623159e308}`, false},
+		{`{"a":100e306}`, true},
+		{`{"a":1000e306}`, false},
+		{`{"a":` + float64Limit + `}`, false},
+		{`{"a":` + float64Limit[:len(float64Limit)-1] + "1" + `}`, true},
+		{`{"a":0.00` + float64Limit + `e311}`, false},
+		{`{"a":` + float64Limit + `.000}`, false},
+		{`{"a":` + float64Limit + `.0001e-1}`, true},
+		{`{"a":` + strings.Repeat("[", DefaultMaxDepth-1) + strings.Repeat("]", DefaultMaxDepth-1) + `}`, true},
+		{`{"a":` + strings.Repeat("[", DefaultMaxDepth) + strings.Repeat("]", DefaultMaxDepth) + `}`, false},
+		// utf-16 that ends in the middle of a character, and only whitespace
+		{"\x00{\x00}t", false},
+		{" \n\t", false},
+	}
+
+	for _, test := range tests {
+		if got := Valid([]byte(test.input)); got != test.want {
+			t.Errorf("Valid(%q) = %v, want %v", test.input, got, test.want)
+		}
+
+		if got := ValidReader(strings.NewReader(test.input)) == nil; got != test.want {
+			t.Errorf("ValidReader(%q) = %v, want %v", test.input, got, test.want)
+		}
+
+		if _, err := (ParseOptions{}).Parse([]byte(test.input)); (err == nil) != test.want {
+			t.Errorf("Parse(%q) = %v, which disagrees with Valid", test.input, err)
+		}
+	}
+}
+
+// a reader that hands out one byte at a time, so every state has to survive between reads
+type oneByteReader struct {
+	data []byte
+}
+
+func (r *oneByteReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+
+	p[0], r.data = r.data[0], r.data[1:]
+	return 1, nil
+}
+
+func TestValidReaderErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		kind  error
+		// where the error is
+		line, column int
+	}{
+		{"{\"a\": 1,\n \"b\": tru}", ErrInvalidToken, 2, 10},
+		{`{"a": [1, 2`, ErrUnexpectedEOF, 1, 12},
+		{`{"a": "\ud800 "}`, ErrInvalidEscape, 1, 14},
+		{`[1]`, ErrInvalidToken, 1, 1},
+		{"{\"a\": [1,\n  -2.5e400]}", ErrInvalidToken, 2, 3},
+	}
+
+	for _, test := range tests {
+		err := ValidReader(&oneByteReader{data: []byte(test.input)})
+		var syntax *SyntaxError
+		if !errors.As(err, &syntax) || !errors.Is(err, test.kind) {
+			t.Errorf("ValidReader(%q) = %v, want a %v", test.input, err, test.kind)
+			continue
+		}
+
+		if syntax.Line != test.line || syntax.Column != test.column {
+			t.Errorf("ValidReader(%q) failed at %d:%d, want %d:%d", test.input, syntax.Line, syntax.Column, test.line, test.column)
+		}
+	}
+}
+
+// a document with nothing but whitespace is empty to ValidReader like it is to Parse
+func TestValidReaderEmpty(t *testing.T) {
+	for _, input := range []string{"", " ", "\r\n\t ", "\xef\xbb\xbf "} {
+		if err := ValidReader(strings.NewReader(input)); !errors.Is(err, ErrEmptyDocument) {
+			t.Errorf("ValidReader(%q) = %v, want %v", input, err, ErrEmptyDocument)
+		}
+
+		if _, err := (ParseOptions{}).Parse([]byte(input)); !errors.Is(err, ErrEmptyDocument) {
+			t.Errorf("Parse(%q) = %v, want %v", input, err, ErrEmptyDocument)
+		}
+	}
+}
+
+// Valid, ValidReader and Parse have to agree on every input
+func FuzzValid(f *testing.F) {
+	for _, input := range []string{
+		`{"a":[1,"x",null]}`,
+		"{\x00\"\x00a\x00\"\x00:\x001\x00}\x00",
+		"\x00{\x00}t",
+		"\x00{\x00}",
+		" \n\t",
+		"",
+		`{"a":"\ud83d"}`,
+		`{"a":1e400}`,
+	} {
+		f.Add([]byte(input))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		valid := Valid(data)
+		if err := ValidReader(bytes.NewReader(data)); (err == nil) != valid {
+			t.Errorf("Valid(%q) = %v, but ValidReader = %v", data, valid, err)
+		}
+
+		if _, err := (ParseOptions{}).Parse(data); (err == nil) != valid {
+			t.Errorf("Valid(%q) = %v, but Parse = %v", data, valid, err)
+		}
+	})
+}
+
+func TestValidAllocations(t *testing.T) {
+	data := []byte(`{"name": "x", "list": [1, 2.5e3, true, null, {"nested": ["\u00e9"]}]}`)
+	if allocs := testing.AllocsPerRun(100, func() { Valid(data) }); allocs != 0 {
+		t.Errorf("Valid allocated %v times, want 0", allocs)
+	}
+}
+
+func BenchmarkValid(b *testing.B) {
+	var doc strings.Builder
+	doc.WriteString(`{"items": [`)
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			doc.WriteString(",")
+		}
+
+		fmt.Fprintf(&doc, `{"id": %d, "name": "item %d", "tags": ["a", "b"], "price": %d.5}`, i, i, i)
+	}
+	doc.WriteString("]}")
+	data := []byte(doc.String())
+
+	b.Run("Valid", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			Valid(data)
+		}
+	})
+
+	b.Run("ValidReader", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			ValidReader(bytes.NewReader(data))
+		}
+	})
+
+	b.Run("Parse", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			ParseOptions{}.Parse(data)
+		}
+	})
+}
@@ -0,0 +1,319 @@
+package orderedjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaError is a place where a value doesn't match its JSON Schema.
+type SchemaError struct {
+	Path    Path
+	Message string
+}
+
+func (err SchemaError) Error() string {
+	return fmt.Sprintf("%s: %s", err.Path, err.Message)
+}
+
+// ValidateSchema checks value against schema and returns every mismatch, in document order. it
+// covers the commonly used part of JSON Schema: type, enum, const, properties, required,
+// additionalProperties, items, the min/max keywords, pattern, allOf/anyOf/oneOf/not and local $refs
+// (#/$defs/... or #/definitions/...). anything else in the schema is ignored.
+func ValidateSchema(schema *JsonObject, value interface{}) []SchemaError {
+	validator := &schemaValidator{root: schema}
+	validator.validate(schema, Path{}, value)
+	return validator.errors
+}
+
+type schemaValidator struct {
+	root   *JsonObject
+	errors []SchemaError
+}
+
+func (validator *schemaValidator) errorf(path Path, format string, args ...interface{}) {
+	validator.errors = append(validator.errors, SchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// true and false are valid schemas too, matching everything and nothing
+func (validator *schemaValidator) validate(schema interface{}, path Path, value interface{}) {
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			validator.errorf(path, "no value is allowed here")
+		}
+	case *JsonObject:
+		validator.validateObject(s, path, value)
+	}
+}
+
+// whether value matches, without keeping the errors
+func (validator *schemaValidator) matches(schema interface{}, path Path, value interface{}) bool {
+	nested := &schemaValidator{root: validator.root}
+	nested.validate(schema, path, value)
+	return len(nested.errors) == 0
+}
+
+func (validator *schemaValidator) validateObject(schema *JsonObject, path Path, value interface{}) {
+	if ref, ok := schema.Get("$ref"); ok {
+		target, err := validator.resolve(ref)
+		if err != nil {
+			validator.errorf(path, "%v", err)
+			return
+		}
+
+		validator.validate(target, path, value)
+	}
+
+	if types, ok := schema.Get("type"); ok && !matchesType(types, value) {
+		validator.errorf(path, "expected %s, got %s", describeTypes(types), schemaTypeName(value))
+		// the other keywords would only produce noise
+		return
+	}
+
+	if enum, ok := schema.Get("enum"); ok {
+		if options, ok := enum.([]interface{}); ok {
+			found := false
+			for _, option := range options {
+				if Equal(option, value) {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				allowed := make([]string, 0, len(options))
+				for _, option := range options {
+					text, _ := marshalValue(option)
+					allowed = append(allowed, text)
+				}
+
+				validator.errorf(path, "must be one of %s", strings.Join(allowed, ", "))
+			}
+		}
+	}
+
+	if constant, ok := schema.Get("const"); ok && !Equal(constant, value) {
+		text, _ := marshalValue(constant)
+		validator.errorf(path, "must be %s", text)
+	}
+
+	switch v := value.(type) {
+	case *JsonObject:
+		validator.validateProperties(schema, path, v)
+	case []interface{}:
+		if min, ok := schemaNumber(schema, "minItems"); ok && float64(len(v)) < min {
+			validator.errorf(path, "must have at least %v items", min)
+		}
+
+		if max, ok := schemaNumber(schema, "maxItems"); ok && float64(len(v)) > max {
+			validator.errorf(path, "must have at most %v items", max)
+		}
+
+		if items, ok := schema.Get("items"); ok {
+			for i, item := range v {
+				validator.validate(items, path.Child(i), item)
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if min, ok := schemaNumber(schema, "minLength"); ok && length < min {
+			validator.errorf(path, "must be at least %v characters long", min)
+		}
+
+		if max, ok := schemaNumber(schema, "maxLength"); ok && length > max {
+			validator.errorf(path, "must be at most %v characters long", max)
+		}
+
+		if pattern, ok := schema.Get("pattern"); ok {
+			if source, ok := pattern.(string); ok {
+				re, err := regexp.Compile(source)
+				if err != nil {
+					validator.errorf(path, "invalid pattern %q in schema: %v", source, err)
+				} else if !re.MatchString(v) {
+					validator.errorf(path, "must match %q", source)
+				}
+			}
+		}
+	case float64, json.Number:
+		number, _ := floatValue(v)
+		if min, ok := schemaNumber(schema, "minimum"); ok && number < min {
+			validator.errorf(path, "must be at least %v", min)
+		}
+
+		if max, ok := schemaNumber(schema, "maximum"); ok && number > max {
+			validator.errorf(path, "must be at most %v", max)
+		}
+
+		if min, ok := schemaNumber(schema, "exclusiveMinimum"); ok && number <= min {
+			validator.errorf(path, "must be greater than %v", min)
+		}
+
+		if max, ok := schemaNumber(schema, "exclusiveMaximum"); ok && number >= max {
+			validator.errorf(path, "must be less than %v", max)
+		}
+	}
+
+	if allOf, ok := schema.Get("allOf"); ok {
+		if schemas, ok := allOf.([]interface{}); ok {
+			for _, s := range schemas {
+				validator.validate(s, path, value)
+			}
+		}
+	}
+
+	if anyOf, ok := schema.Get("anyOf"); ok {
+		if schemas, ok := anyOf.([]interface{}); ok {
+			matched := false
+			for _, s := range schemas {
+				if validator.matches(s, path, value) {
+					matched = true
+					break
+				}
+			}
+
+			if !matched {
+				validator.errorf(path, "does not match any of the allowed schemas")
+			}
+		}
+	}
+
+	if oneOf, ok := schema.Get("oneOf"); ok {
+		if schemas, ok := oneOf.([]interface{}); ok {
+			matched := 0
+			for _, s := range schemas {
+				if validator.matches(s, path, value) {
+					matched++
+				}
+			}
+
+			if matched != 1 {
+				validator.errorf(path, "must match exactly one of the allowed schemas, matches %d", matched)
+			}
+		}
+	}
+
+	if not, ok := schema.Get("not"); ok && validator.matches(not, path, value) {
+		validator.errorf(path, "matches a schema it must not match")
+	}
+}
+
+func (validator *schemaValidator) validateProperties(schema *JsonObject, path Path, object *JsonObject) {
+	if required, ok := schema.Get("required"); ok {
+		if names, ok := required.([]interface{}); ok {
+			for _, name := range names {
+				if key, ok := name.(string); ok {
+					if _, present := object.Get(key); !present {
+						validator.errorf(path, "missing required property %q", key)
+					}
+				}
+			}
+		}
+	}
+
+	properties, _ := schema.Get("properties")
+	propertySchemas, _ := properties.(*JsonObject)
+	additional, hasAdditional := schema.Get("additionalProperties")
+
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		if propertySchemas != nil {
+			if propertySchema, ok := propertySchemas.Get(pair.Key); ok {
+				validator.validate(propertySchema, path.Child(pair.Key), pair.Value)
+				continue
+			}
+		}
+
+		if !hasAdditional {
+			continue
+		}
+
+		if allowed, ok := additional.(bool); ok && !allowed {
+			validator.errorf(path.Child(pair.Key), "property %q is not allowed", pair.Key)
+			continue
+		}
+
+		validator.validate(additional, path.Child(pair.Key), pair.Value)
+	}
+}
+
+// only refs to somewhere in the same schema are supported
+func (validator *schemaValidator) resolve(ref interface{}) (interface{}, error) {
+	pointer, ok := ref.(string)
+	if !ok || !strings.HasPrefix(pointer, "#") {
+		return nil, fmt.Errorf("unsupported $ref %v", ref)
+	}
+
+	var current interface{} = validator.root
+	for _, part := range strings.Split(strings.TrimPrefix(pointer, "#"), "/")[1:] {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		object, ok := current.(*JsonObject)
+		if !ok {
+			return nil, fmt.Errorf("cannot resolve $ref %q", pointer)
+		}
+
+		if current, ok = object.Get(part); !ok {
+			return nil, fmt.Errorf("cannot resolve $ref %q", pointer)
+		}
+	}
+
+	return current, nil
+}
+
+func schemaNumber(schema *JsonObject, keyword string) (float64, bool) {
+	value, ok := schema.Get(keyword)
+	if !ok {
+		return 0, false
+	}
+
+	return floatValue(value)
+}
+
+func matchesType(types interface{}, value interface{}) bool {
+	switch t := types.(type) {
+	case string:
+		return matchesTypeName(t, value)
+	case []interface{}:
+		for _, name := range t {
+			if s, ok := name.(string); ok && matchesTypeName(s, value) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+func matchesTypeName(name string, value interface{}) bool {
+	actual := schemaTypeName(value)
+	if name == "number" && actual == "integer" {
+		return true
+	}
+
+	return name == actual
+}
+
+// the json schema type of a value, which tells integers apart from other numbers
+func schemaTypeName(value interface{}) string {
+	if TypeName(value) == "number" && isInteger(value) {
+		return "integer"
+	}
+
+	return TypeName(value)
+}
+
+func describeTypes(types interface{}) string {
+	if list, ok := types.([]interface{}); ok {
+		names := make([]string, 0, len(list))
+		for _, name := range list {
+			names = append(names, fmt.Sprint(name))
+		}
+
+		return strings.Join(names, " or ")
+	}
+
+	return fmt.Sprint(types)
+}
@@ -0,0 +1,256 @@
+package orderedjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+// groups in the layout of the JSON-Schema-Test-Suite, most of them taken from its draft 2020-12
+// tests for the keywords ValidateSchema supports
+const schemaSuite = `[
+	{
+		"description": "integer type matches integers",
+		"schema": {"type": "integer"},
+		"tests": [
+			{"description": "an integer", "data": 1, "valid": true},
+			{"description": "a float with zero fractional part", "data": 1.0, "valid": true},
+			{"description": "a float", "data": 1.1, "valid": false},
+			{"description": "a string", "data": "foo", "valid": false},
+			{"description": "a string that is still an integer", "data": "1", "valid": false},
+			{"description": "an object", "data": {}, "valid": false},
+			{"description": "an array", "data": [], "valid": false},
+			{"description": "a boolean", "data": true, "valid": false},
+			{"description": "null", "data": null, "valid": false}
+		]
+	},
+	{
+		"description": "number type matches numbers",
+		"schema": {"type": "number"},
+		"tests": [
+			{"description": "an integer", "data": 1, "valid": true},
+			{"description": "a float", "data": 1.1, "valid": true},
+			{"description": "a string that is still a number", "data": "1", "valid": false},
+			{"description": "null", "data": null, "valid": false}
+		]
+	},
+	{
+		"description": "the other types",
+		"schema": {"properties": {"s": {"type": "string"}, "o": {"type": "object"}, "a": {"type": "array"}, "b": {"type": "boolean"}, "n": {"type": "null"}}},
+		"tests": [
+			{"description": "all of them right", "data": {"s": "", "o": {}, "a": [], "b": false, "n": null}, "valid": true},
+			{"description": "a number for a string", "data": {"s": 1}, "valid": false},
+			{"description": "an array for an object", "data": {"o": []}, "valid": false},
+			{"description": "an object for an array", "data": {"a": {}}, "valid": false},
+			{"description": "zero for a boolean", "data": {"b": 0}, "valid": false},
+			{"description": "an empty string for a boolean", "data": {"b": ""}, "valid": false},
+			{"description": "false for null", "data": {"n": false}, "valid": false},
+			{"description": "zero for null", "data": {"n": 0}, "valid": false}
+		]
+	},
+	{
+		"description": "multiple types can be allowed",
+		"schema": {"type": ["integer", "string"]},
+		"tests": [
+			{"description": "an integer", "data": 1, "valid": true},
+			{"description": "a string", "data": "foo", "valid": true},
+			{"description": "a float", "data": 1.1, "valid": false},
+			{"description": "an object", "data": {}, "valid": false}
+		]
+	},
+	{
+		"description": "minimum validation",
+		"schema": {"minimum": 1.1},
+		"tests": [
+			{"description": "above the minimum", "data": 2.6, "valid": true},
+			{"description": "boundary point is valid", "data": 1.1, "valid": true},
+			{"description": "below the minimum", "data": 0.6, "valid": false},
+			{"description": "ignores non-numbers", "data": "x", "valid": true}
+		]
+	},
+	{
+		"description": "minimum validation with a signed integer",
+		"schema": {"minimum": -2},
+		"tests": [
+			{"description": "boundary point is valid", "data": -2, "valid": true},
+			{"description": "boundary point with float is valid", "data": -2.0, "valid": true},
+			{"description": "float below the minimum", "data": -2.0001, "valid": false},
+			{"description": "int below the minimum", "data": -3, "valid": false}
+		]
+	},
+	{
+		"description": "exclusiveMinimum validation",
+		"schema": {"exclusiveMinimum": 1.1},
+		"tests": [
+			{"description": "above the exclusiveMinimum", "data": 1.2, "valid": true},
+			{"description": "boundary point is invalid", "data": 1.1, "valid": false},
+			{"description": "below the exclusiveMinimum", "data": 0.6, "valid": false},
+			{"description": "ignores non-numbers", "data": "x", "valid": true}
+		]
+	},
+	{
+		"description": "maximum and exclusiveMaximum validation",
+		"schema": {"properties": {"max": {"maximum": 3.0}, "exclusive": {"exclusiveMaximum": 3.0}}},
+		"tests": [
+			{"description": "below both", "data": {"max": 2.6, "exclusive": 2.2}, "valid": true},
+			{"description": "boundary point of maximum is valid", "data": {"max": 3}, "valid": true},
+			{"description": "above the maximum", "data": {"max": 3.5}, "valid": false},
+			{"description": "boundary point of exclusiveMaximum is invalid", "data": {"exclusive": 3.0}, "valid": false}
+		]
+	},
+	{
+		"description": "pattern validation",
+		"schema": {"pattern": "^a*$"},
+		"tests": [
+			{"description": "a matching pattern is valid", "data": "aaa", "valid": true},
+			{"description": "a non-matching pattern is invalid", "data": "abc", "valid": false},
+			{"description": "ignores booleans", "data": true, "valid": true},
+			{"description": "ignores objects", "data": {}, "valid": true},
+			{"description": "ignores null", "data": null, "valid": true}
+		]
+	},
+	{
+		"description": "pattern is not anchored",
+		"schema": {"pattern": "a+"},
+		"tests": [
+			{"description": "matches a substring", "data": "xxaayy", "valid": true}
+		]
+	},
+	{
+		"description": "required validation",
+		"schema": {"properties": {"foo": {}, "bar": {}}, "required": ["foo"]},
+		"tests": [
+			{"description": "present required property is valid", "data": {"foo": 1}, "valid": true},
+			{"description": "non-present required property is invalid", "data": {"bar": 1}, "valid": false},
+			{"description": "ignores arrays", "data": [], "valid": true},
+			{"description": "ignores strings", "data": "", "valid": true}
+		]
+	},
+	{
+		"description": "required with escaped characters",
+		"schema": {"required": ["foo\nbar", "foo\"bar", "foo\\bar"]},
+		"tests": [
+			{"description": "object with all properties present is valid", "data": {"foo\nbar": 1, "foo\"bar": 1, "foo\\bar": 1}, "valid": true},
+			{"description": "object with some properties missing is invalid", "data": {"foo\nbar": "1", "foo\"bar": "1"}, "valid": false}
+		]
+	},
+	{
+		"description": "root pointer ref",
+		"schema": {"properties": {"foo": {"$ref": "#"}}, "additionalProperties": false},
+		"tests": [
+			{"description": "match", "data": {"foo": false}, "valid": true},
+			{"description": "recursive match", "data": {"foo": {"foo": false}}, "valid": true},
+			{"description": "mismatch", "data": {"bar": false}, "valid": false},
+			{"description": "recursive mismatch", "data": {"foo": {"bar": false}}, "valid": false}
+		]
+	},
+	{
+		"description": "relative pointer ref to object",
+		"schema": {"properties": {"foo": {"type": "integer"}, "bar": {"$ref": "#/properties/foo"}}},
+		"tests": [
+			{"description": "match", "data": {"bar": 3}, "valid": true},
+			{"description": "mismatch", "data": {"bar": true}, "valid": false}
+		]
+	},
+	{
+		"description": "escaped pointer ref",
+		"schema": {
+			"$defs": {"tilde~field": {"type": "integer"}, "slash/field": {"type": "integer"}},
+			"properties": {"tilde": {"$ref": "#/$defs/tilde~0field"}, "slash": {"$ref": "#/$defs/slash~1field"}}
+		},
+		"tests": [
+			{"description": "slash invalid", "data": {"slash": "aoeu"}, "valid": false},
+			{"description": "tilde invalid", "data": {"tilde": "aoeu"}, "valid": false},
+			{"description": "slash valid", "data": {"slash": 123}, "valid": true},
+			{"description": "tilde valid", "data": {"tilde": 123}, "valid": true}
+		]
+	},
+	{
+		"description": "refs to $defs and definitions",
+		"schema": {
+			"$defs": {"positive": {"type": "integer", "exclusiveMinimum": 0}},
+			"definitions": {"name": {"type": "string", "pattern": "^[a-z]+$"}},
+			"items": {"properties": {"id": {"$ref": "#/$defs/positive"}, "name": {"$ref": "#/definitions/name"}}}
+		},
+		"tests": [
+			{"description": "valid items", "data": [{"id": 1, "name": "a"}, {"id": 2}], "valid": true},
+			{"description": "an id of zero", "data": [{"id": 1}, {"id": 0}], "valid": false},
+			{"description": "a name with digits", "data": [{"name": "a1"}], "valid": false}
+		]
+	},
+	{
+		"description": "$ref to boolean schemas",
+		"schema": {"$defs": {"yes": true, "no": false}, "properties": {"any": {"$ref": "#/$defs/yes"}, "none": {"$ref": "#/$defs/no"}}},
+		"tests": [
+			{"description": "anything goes through true", "data": {"any": {"x": [1]}}, "valid": true},
+			{"description": "nothing goes through false", "data": {"none": null}, "valid": false}
+		]
+	},
+	{
+		"description": "refs that can't be resolved",
+		"schema": {"properties": {"local": {"$ref": "#/$defs/missing"}, "remote": {"$ref": "http://example.com/schema"}}},
+		"tests": [
+			{"description": "a missing definition", "data": {"local": 1}, "valid": false},
+			{"description": "a remote schema", "data": {"remote": 1}, "valid": false},
+			{"description": "neither is used", "data": {}, "valid": true}
+		]
+	}
+]`
+
+func TestValidateSchemaSuite(t *testing.T) {
+	suite, err := FromStdJSONValue([]byte(schemaSuite))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, group := range suite.([]interface{}) {
+		group := group.(*JsonObject)
+		schema := group.Value("schema").(*JsonObject)
+		for _, test := range group.Value("tests").([]interface{}) {
+			test := test.(*JsonObject)
+			errs := ValidateSchema(schema, test.Value("data"))
+			if valid := test.Value("valid").(bool); valid != (len(errs) == 0) {
+				t.Errorf("%s, %s: ValidateSchema = %v, want valid %v", group.Value("description"), test.Value("description"), errs, valid)
+			}
+		}
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		schema string
+		data   string
+		want   []string
+	}{
+		{`{"properties":{"a":{"type":"integer"},"b":{"minimum":5}},"required":["c"]}`, `{"b":1,"a":"x"}`, []string{`.: missing required property "c"`, `.b: must be at least 5`, `.a: expected integer, got string`}},
+		{`{"items":{"type":["string","null"]},"maxItems":2}`, `["a",1,{}]`, []string{`.: must have at most 2 items`, `[1]: expected string or null, got integer`, `[2]: expected string or null, got object`}},
+		{`{"additionalProperties":false,"properties":{"a":{}}}`, `{"a":1,"b":2,"c d":3}`, []string{`.b: property "b" is not allowed`, `["c d"]: property "c d" is not allowed`}},
+		{`{"additionalProperties":{"type":"string","minLength":2}}`, `{"a":"é","b":"ok"}`, []string{`.a: must be at least 2 characters long`}},
+		{`{"enum":[1,"a",null]}`, `2`, []string{`.: must be one of 1, "a", null`}},
+		{`{"const":{"a":[1]}}`, `{"a":[1.0]}`, nil},
+		{`{"oneOf":[{"type":"integer"},{"minimum":0}]}`, `1`, []string{`.: must match exactly one of the allowed schemas, matches 2`}},
+		{`{"anyOf":[{"type":"string"},{"type":"null"}]}`, `1`, []string{`.: does not match any of the allowed schemas`}},
+		{`{"allOf":[{"minimum":0},{"maximum":1}],"not":{"const":0.5}}`, `0.5`, []string{`.: matches a schema it must not match`}},
+		{`{"pattern":"("}`, `"x"`, []string{`.: invalid pattern "(" in schema: error parsing regexp: missing closing ): ` + "`(`"}},
+	}
+
+	for _, test := range tests {
+		schema, err := ParseOptions{}.Parse([]byte(test.schema))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := FromStdJSONValue([]byte(test.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, err := range ValidateSchema(schema, data) {
+			got = append(got, err.Error())
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ValidateSchema(%s, %s) = %q, want %q", test.schema, test.data, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package orderedjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Path is where a value sits in the tree: a string for every object key and an int for every array
+// index on the way down.
+type Path []interface{}
+
+// String renders the path jq style, e.g. .scripts.build or .files[0]
+func (path Path) String() string {
+	if len(path) == 0 {
+		return "."
+	}
+
+	var result strings.Builder
+	for _, element := range path {
+		switch e := element.(type) {
+		case int:
+			result.WriteString(fmt.Sprintf("[%d]", e))
+		case string:
+			if isQueryIdentifier(e) {
+				result.WriteString("." + e)
+			} else {
+				quoted, _ := marshalValue(e)
+				result.WriteString("[" + quoted + "]")
+			}
+		}
+	}
+
+	return result.String()
+}
+
+func isQueryIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i, char := range s {
+		if !(unicode.IsLetter(char) || unicode.IsDigit(char) || char == '_') || (i == 0 && char >= '0' && char <= '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Child is path with element (a key or an index) added. it doesn't share the backing array of
+// path, so visitors can hold on to paths.
+func (path Path) Child(element interface{}) Path {
+	result := make(Path, len(path), len(path)+1)
+	copy(result, path)
+	return append(result, element)
+}
+
+// Visitor gets called for every value in the tree, in document order. returning false from
+// VisitObject or VisitArray skips the children of that value.
+type Visitor interface {
+	VisitObject(path Path, object *JsonObject) bool
+	VisitArray(path Path, array []interface{}) bool
+	VisitString(path Path, value string)
+	VisitNumber(path Path, value float64)
+	VisitBool(path Path, value bool)
+	VisitNull(path Path)
+}
+
+// BaseVisitor does nothing, embed it to only implement the methods you care about.
+type BaseVisitor struct{}
+
+func (BaseVisitor) VisitObject(path Path, object *JsonObject) bool { return true }
+func (BaseVisitor) VisitArray(path Path, array []interface{}) bool { return true }
+func (BaseVisitor) VisitString(path Path, value string)            {}
+func (BaseVisitor) VisitNumber(path Path, value float64)           {}
+func (BaseVisitor) VisitBool(path Path, value bool)                {}
+func (BaseVisitor) VisitNull(path Path)                            {}
+
+// VisitorFunc is a Visitor calling one function for every value, whatever its type, for passes that
+// treat them all alike. for objects and arrays, it returns whether to visit their children.
+type VisitorFunc func(path Path, value interface{}) bool
+
+func (fn VisitorFunc) VisitObject(path Path, object *JsonObject) bool { return fn(path, object) }
+func (fn VisitorFunc) VisitArray(path Path, array []interface{}) bool { return fn(path, array) }
+func (fn VisitorFunc) VisitString(path Path, value string)            { fn(path, value) }
+func (fn VisitorFunc) VisitNumber(path Path, value float64)           { fn(path, value) }
+func (fn VisitorFunc) VisitBool(path Path, value bool)                { fn(path, value) }
+func (fn VisitorFunc) VisitNull(path Path)                            { fn(path, nil) }
+
+// Accept walks tree depth first, calling visitor for each value.
+func Accept(tree interface{}, visitor Visitor) error {
+	return accept(Path{}, tree, visitor)
+}
+
+func accept(path Path, value interface{}, visitor Visitor) error {
+	switch v := value.(type) {
+	case *JsonObject:
+		if !visitor.VisitObject(path, v) {
+			return nil
+		}
+
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			if err := accept(path.Child(pair.Key), pair.Value, visitor); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if !visitor.VisitArray(path, v) {
+			return nil
+		}
+
+		for i, item := range v {
+			if err := accept(path.Child(i), item, visitor); err != nil {
+				return err
+			}
+		}
+	case string:
+		visitor.VisitString(path, v)
+	case float64:
+		visitor.VisitNumber(path, v)
+	case json.Number:
+		number, ok := floatValue(v)
+		if !ok {
+			return fmt.Errorf("%s: %s is out of range of a float64", path, v)
+		}
+
+		visitor.VisitNumber(path, number)
+	case bool:
+		visitor.VisitBool(path, v)
+	case nil:
+		visitor.VisitNull(path)
+	default:
+		return fmt.Errorf("%s: cannot visit value of type %T", path, value)
+	}
+
+	return nil
+}
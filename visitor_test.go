@@ -0,0 +1,74 @@
+package orderedjson
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// writes down every call
+type recordingVisitor struct {
+	calls []string
+	// objects and arrays at these paths aren't gone into
+	skip map[string]bool
+}
+
+func (v *recordingVisitor) record(kind string, path Path) {
+	v.calls = append(v.calls, kind+" "+path.String())
+}
+
+func (v *recordingVisitor) VisitObject(path Path, object *JsonObject) bool {
+	v.record("object", path)
+	return !v.skip[path.String()]
+}
+
+func (v *recordingVisitor) VisitArray(path Path, array []interface{}) bool {
+	v.record("array", path)
+	return !v.skip[path.String()]
+}
+
+func (v *recordingVisitor) VisitString(path Path, value string)  { v.record("string", path) }
+func (v *recordingVisitor) VisitNumber(path Path, value float64) { v.record("number", path) }
+func (v *recordingVisitor) VisitBool(path Path, value bool)      { v.record("bool", path) }
+func (v *recordingVisitor) VisitNull(path Path)                  { v.record("null", path) }
+
+func TestAccept(t *testing.T) {
+	tree, err := ParseOptions{}.Parse([]byte(`{"b": [1, "x", {"c": null}], "a": true, "d": {"e": 1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		skip []string
+		want []string
+	}{
+		{nil, []string{"object .", "array .b", "number .b[0]", "string .b[1]", "object .b[2]", "null .b[2].c", "bool .a", "object .d", "number .d.e"}},
+		{[]string{".b", ".d"}, []string{"object .", "array .b", "bool .a", "object .d"}},
+		{[]string{"."}, []string{"object ."}},
+	}
+
+	for _, test := range tests {
+		visitor := &recordingVisitor{skip: make(map[string]bool)}
+		for _, path := range test.skip {
+			visitor.skip[path] = true
+		}
+
+		if err := Accept(tree, visitor); err != nil {
+			t.Errorf("skipping %v: %v", test.skip, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(visitor.calls, test.want) {
+			t.Errorf("skipping %v visited %q, want %q", test.skip, visitor.calls, test.want)
+		}
+	}
+
+	// json.Numbers from NumberJSONNumber are visited as numbers, as long as a float64 can hold them
+	if err := Accept([]interface{}{json.Number("1")}, &recordingVisitor{}); err != nil {
+		t.Errorf("Accept(json.Number): %v", err)
+	}
+
+	if err := Accept([]interface{}{json.Number("1e400")}, &recordingVisitor{}); err == nil {
+		t.Errorf("Accept took a json.Number out of range of a float64")
+	}
+}
@@ -0,0 +1,185 @@
+package orderedjson
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchOptions controls WatchFile. the file is watched with fsnotify (inotify, kqueue and friends).
+// WatchFile falls back to polling when fsnotify can't watch it, and polling can be asked for with
+// Poll, e.g. for network file systems that don't send events.
+type WatchOptions struct {
+	// look at the file every Interval instead of waiting for events
+	Poll bool
+	// how often to look at the file when polling, 250ms by default
+	Interval time.Duration
+	// how long the file has to stay unchanged before it is parsed, so a burst of writes only
+	// triggers one reload. 100ms by default.
+	Debounce time.Duration
+	Parse    ParseOptions
+}
+
+// WatchFile is WatchOptions{}.WatchFile
+func WatchFile(path string, onChange func(tree *JsonObject, changes []Change, err error)) (stop func(), err error) {
+	return WatchOptions{}.WatchFile(path, onChange)
+}
+
+// WatchFile parses the file at path and then calls onChange from a separate goroutine every time its
+// contents change, with the new tree and the changes since the previous good version. when the new
+// contents fail to parse (or the file goes away) onChange gets the error, and the previous tree is
+// kept to diff against. call stop to stop watching.
+func (opts WatchOptions) WatchFile(path string, onChange func(tree *JsonObject, changes []Change, err error)) (stop func(), err error) {
+	if opts.Interval == 0 {
+		opts.Interval = 250 * time.Millisecond
+	}
+
+	if opts.Debounce == 0 {
+		opts.Debounce = 100 * time.Millisecond
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := opts.Parse.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &watcher{opts: opts, path: path, onChange: onChange, raw: raw, tree: tree}
+	done := make(chan struct{})
+	if !opts.Poll {
+		// the directory is watched rather than the file, so editors that save by writing a new file
+		// and renaming it over the old one don't end the watch
+		events, err := fsnotify.NewWatcher()
+		if err == nil {
+			if err = events.Add(filepath.Dir(path)); err == nil {
+				go w.notify(events, done)
+				return func() { close(done) }, nil
+			}
+
+			events.Close()
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	go w.poll(info, done)
+	return func() { close(done) }, nil
+}
+
+type watcher struct {
+	opts     WatchOptions
+	path     string
+	onChange func(tree *JsonObject, changes []Change, err error)
+	// the last contents that parsed, and their tree
+	raw  []byte
+	tree *JsonObject
+	// the last error passed to onChange, nil once the file is fine again
+	lastErr error
+}
+
+func (w *watcher) notify(events *fsnotify.Watcher, done chan struct{}) {
+	defer events.Close()
+
+	// the debounce timer, started by the first event of a burst
+	timer := time.NewTimer(w.opts.Debounce)
+	timer.Stop()
+	name := filepath.Clean(w.path)
+	for {
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case event, ok := <-events.Events:
+			if !ok {
+				return
+			}
+
+			if filepath.Clean(event.Name) == name && !event.Has(fsnotify.Chmod) {
+				timer.Reset(w.opts.Debounce)
+			}
+		case _, ok := <-events.Errors:
+			if !ok {
+				return
+			}
+
+			// events may have been dropped, so look at the file anyway
+			timer.Reset(w.opts.Debounce)
+		case <-timer.C:
+			w.reload()
+		}
+	}
+}
+
+func (w *watcher) poll(info os.FileInfo, done chan struct{}) {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+	lastModTime, lastSize := info.ModTime(), info.Size()
+	var changedAt time.Time
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(w.path)
+		if err != nil {
+			if w.lastErr == nil || w.lastErr.Error() != err.Error() {
+				w.onChange(nil, nil, err)
+			}
+
+			w.lastErr = err
+			lastModTime, lastSize = time.Time{}, -1
+			continue
+		}
+
+		if !info.ModTime().Equal(lastModTime) || info.Size() != lastSize {
+			lastModTime, lastSize = info.ModTime(), info.Size()
+			changedAt = time.Now()
+			continue
+		}
+
+		if changedAt.IsZero() || time.Since(changedAt) < w.opts.Debounce {
+			continue
+		}
+
+		changedAt = time.Time{}
+		w.reload()
+	}
+}
+
+// reads and parses the file again, and tells onChange if anything changed
+func (w *watcher) reload() {
+	contents, err := os.ReadFile(w.path)
+	if err == nil && bytes.Equal(contents, w.raw) {
+		w.lastErr = nil
+		return
+	}
+
+	var next *JsonObject
+	if err == nil {
+		next, err = w.opts.Parse.Parse(contents)
+	}
+
+	if err != nil {
+		w.lastErr = err
+		w.onChange(nil, nil, err)
+		return
+	}
+
+	w.raw, w.lastErr = contents, nil
+	changes := Diff(w.tree, next)
+	w.tree = next
+	w.onChange(next, changes, nil)
+}
@@ -0,0 +1,103 @@
+package orderedjson
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type watchEvent struct {
+	tree    string
+	changes string
+	err     error
+}
+
+func TestWatchFile(t *testing.T) {
+	for _, poll := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "watched.json")
+		write := func(contents string) {
+			t.Helper()
+			if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		events := make(chan watchEvent, 10)
+		next := func() watchEvent {
+			t.Helper()
+			select {
+			case event := <-events:
+				return event
+			case <-time.After(5 * time.Second):
+				t.Fatalf("poll %v: no change seen", poll)
+				return watchEvent{}
+			}
+		}
+
+		write(`{"a":1,"b":2}`)
+		opts := WatchOptions{Poll: poll, Interval: 5 * time.Millisecond, Debounce: 30 * time.Millisecond}
+		stop, err := opts.WatchFile(path, func(tree *JsonObject, changes []Change, err error) {
+			text, _ := MarshalCompact(tree)
+			events <- watchEvent{text, describeChanges(changes), err}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a burst of writes is one reload
+		write(`{"b":2,"a":1}`)
+		write(`{"b":2,"a":1,"c":33}`)
+		write(`{"b":2,"a":1,"c":3}`)
+		if event := next(); event.err != nil || event.tree != `{"b":2,"a":1,"c":3}` || event.changes != "move .a, add .c" {
+			t.Errorf("poll %v: after writing = %+v", poll, event)
+		}
+
+		write(`{"a":`)
+		if event := next(); event.err == nil {
+			t.Errorf("poll %v: after writing bad json = %+v, want an error", poll, event)
+		}
+
+		// replaced like editors do, and diffed against the last version that parsed
+		temporary := filepath.Join(filepath.Dir(path), "new.json")
+		if err := os.WriteFile(temporary, []byte(`{"a":1,"b":2,"c":3}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Rename(temporary, path); err != nil {
+			t.Fatal(err)
+		}
+
+		if event := next(); event.err != nil || event.tree != `{"a":1,"b":2,"c":3}` || event.changes != "move .b" {
+			t.Errorf("poll %v: after renaming = %+v", poll, event)
+		}
+
+		if err := os.Remove(path); err != nil {
+			t.Fatal(err)
+		}
+
+		if event := next(); !errors.Is(event.err, fs.ErrNotExist) {
+			t.Errorf("poll %v: after removing = %+v, want %v", poll, event, fs.ErrNotExist)
+		}
+
+		stop()
+		write(`{"after":"stop"}`)
+		select {
+		case event := <-events:
+			t.Errorf("poll %v: after stop = %+v", poll, event)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	if _, err := WatchFile(filepath.Join(t.TempDir(), "missing.json"), nil); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("WatchFile(missing) = %v, want %v", err, fs.ErrNotExist)
+	}
+
+	path := filepath.Join(t.TempDir(), "bad.json")
+	os.WriteFile(path, []byte(`[1]`), 0o644)
+	if _, err := WatchFile(path, nil); err == nil {
+		t.Errorf("WatchFile(array): want an error")
+	}
+}
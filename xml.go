@@ -0,0 +1,356 @@
+package orderedjson
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+// XMLOptions are the conventions for turning elements into objects and back. the zero value uses
+// the usual ones: attributes are keys starting with @, and text next to child elements or
+// attributes goes under #text.
+type XMLOptions struct {
+	// what attribute keys start with, "@" when empty
+	AttributePrefix string
+	// the key for the text of an element that also has attributes or children, "#text" when empty
+	TextKey string
+	// when set, the children of every element go in an array under this key, each as an object
+	// with one key (or TextKey for text), in document order. an element with only text is still a
+	// string. by default children are keys of the element's object and repeated ones are grouped
+	// into an array, which loses where they were between their siblings: <a/><b/><a/> comes back
+	// as <a/><a/><b/>.
+	ChildrenKey string
+	// ToXML: when set, every element goes on its own line, indented this much per level
+	Indent string
+	// ToXML: when set, the tree is the content of a root element with this name, instead of having
+	// to be an object with one key for the root element
+	Root string
+}
+
+func (opts XMLOptions) attributePrefix() string {
+	if opts.AttributePrefix == "" {
+		return "@"
+	}
+
+	return opts.AttributePrefix
+}
+
+func (opts XMLOptions) textKey() string {
+	if opts.TextKey == "" {
+		return "#text"
+	}
+
+	return opts.TextKey
+}
+
+// FromXML converts an xml document to an object with one key, the root element. the keys of an
+// element's object are in the order of its attributes and then its children. an element with only
+// text becomes a string, and an empty one null. all text stays a string, since xml has no types.
+// names keep their namespace prefixes as written (soap:Envelope), and xmlns declarations are
+// attributes like any other.
+func FromXML(data []byte, opts XMLOptions) (*JsonObject, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return nil, errors.New("no root element")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			name, value, err := opts.readElement(decoder, start)
+			if err != nil {
+				return nil, err
+			}
+
+			root := orderedmap.New[string, interface{}]()
+			root.Set(name, value)
+			return root, nil
+		}
+	}
+}
+
+func xmlName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+
+	return name.Space + ":" + name.Local
+}
+
+// reads everything up to start's end element
+func (opts XMLOptions) readElement(decoder *xml.Decoder, start xml.StartElement) (string, interface{}, error) {
+	name := xmlName(start.Name)
+	object := orderedmap.New[string, interface{}]()
+	for _, attr := range start.Attr {
+		object.Set(opts.attributePrefix()+xmlName(attr.Name), attr.Value)
+	}
+
+	var text strings.Builder
+	var children []interface{}
+	addChild := func(key string, value interface{}) {
+		if opts.ChildrenKey != "" {
+			child := orderedmap.New[string, interface{}]()
+			child.Set(key, value)
+			children = append(children, child)
+			return
+		}
+
+		existing, ok := object.Get(key)
+		if !ok {
+			object.Set(key, value)
+		} else if group, ok := existing.([]interface{}); ok {
+			object.Set(key, append(group, value))
+		} else {
+			object.Set(key, []interface{}{existing, value})
+		}
+	}
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return "", nil, fmt.Errorf("unexpected end of input, <%s> isn't closed", name)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			childName, value, err := opts.readElement(decoder, t)
+			if err != nil {
+				return "", nil, err
+			}
+
+			addChild(childName, value)
+		case xml.CharData:
+			if opts.ChildrenKey != "" {
+				if trimmed := strings.TrimSpace(string(t)); trimmed != "" {
+					addChild(opts.textKey(), trimmed)
+				}
+			} else {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if end := xmlName(t.Name); end != name {
+				line, column := decoder.InputPos()
+				return "", nil, fmt.Errorf("line %d, column %d: </%s> closes <%s>", line, column, end, name)
+			}
+
+			content := strings.TrimSpace(text.String())
+			if opts.ChildrenKey != "" && len(children) == 1 && object.Len() == 0 {
+				// an element with only text is a string here too
+				if only := children[0].(*JsonObject).Oldest(); only.Key == opts.textKey() {
+					return name, only.Value, nil
+				}
+			}
+
+			if opts.ChildrenKey != "" && len(children) > 0 {
+				object.Set(opts.ChildrenKey, children)
+			}
+
+			switch {
+			case object.Len() == 0 && content == "":
+				return name, nil, nil
+			case object.Len() == 0:
+				return name, content, nil
+			case content != "":
+				object.Set(opts.textKey(), content)
+			}
+
+			return name, object, nil
+		}
+	}
+}
+
+// ToXML converts tree, an object with one key for the root element (see XMLOptions.Root), to xml,
+// the opposite of FromXML: keys starting with the attribute prefix are attributes, the text key is
+// text, arrays are repeated elements and null is an empty element. elements are written in key
+// order.
+func ToXML(tree *JsonObject, opts XMLOptions) ([]byte, error) {
+	writer := &xmlWriter{opts: opts}
+	if opts.Root != "" {
+		if err := writer.element(opts.Root, tree, 0); err != nil {
+			return nil, err
+		}
+	} else {
+		if tree.Len() != 1 {
+			return nil, fmt.Errorf("expected an object with one key for the root element, found %d keys", tree.Len())
+		}
+
+		if err := writer.element(tree.Oldest().Key, tree.Oldest().Value, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Indent != "" {
+		writer.WriteByte('\n')
+	}
+
+	return writer.Bytes(), nil
+}
+
+type xmlWriter struct {
+	bytes.Buffer
+	opts XMLOptions
+}
+
+func (writer *xmlWriter) newline(depth int) {
+	if writer.opts.Indent != "" && writer.Len() > 0 {
+		writer.WriteByte('\n')
+		writer.WriteString(strings.Repeat(writer.opts.Indent, depth))
+	}
+}
+
+func (writer *xmlWriter) element(name string, value interface{}, depth int) error {
+	if !isXMLName(name) {
+		return fmt.Errorf("%q isn't a valid element name", name)
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if _, nested := item.([]interface{}); nested {
+				return fmt.Errorf("<%s> has an array in an array, which xml has no way to write", name)
+			}
+
+			if err := writer.element(name, item, depth); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case nil:
+		writer.newline(depth)
+		writer.WriteString("<" + name + "/>")
+		return nil
+	case *JsonObject:
+		return writer.object(name, v, depth)
+	}
+
+	text, err := xmlText(value)
+	if err != nil {
+		return fmt.Errorf("<%s>: %w", name, err)
+	}
+
+	writer.newline(depth)
+	writer.WriteString("<" + name + ">")
+	xml.EscapeText(writer, []byte(text))
+	writer.WriteString("</" + name + ">")
+	return nil
+}
+
+func (writer *xmlWriter) object(name string, object *JsonObject, depth int) error {
+	writer.newline(depth)
+	writer.WriteString("<" + name)
+
+	var text string
+	var children []*orderedmap.Pair[string, interface{}]
+	for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+		switch {
+		case pair.Key == writer.opts.textKey():
+			value, err := xmlText(pair.Value)
+			if err != nil {
+				return fmt.Errorf("<%s> %s: %w", name, pair.Key, err)
+			}
+
+			text = value
+		case strings.HasPrefix(pair.Key, writer.opts.attributePrefix()):
+			attr := strings.TrimPrefix(pair.Key, writer.opts.attributePrefix())
+			value, err := xmlText(pair.Value)
+			if err != nil || !isXMLName(attr) {
+				return fmt.Errorf("<%s> has an attribute %q that can't be written: %v", name, attr, err)
+			}
+
+			writer.WriteString(" " + attr + `="`)
+			xml.EscapeText(writer, []byte(value))
+			writer.WriteByte('"')
+		case pair.Key == writer.opts.ChildrenKey:
+			ordered, ok := pair.Value.([]interface{})
+			if !ok {
+				return fmt.Errorf("<%s> %s is %s, expected an array", name, pair.Key, TypeName(pair.Value))
+			}
+
+			for _, child := range ordered {
+				entry, ok := child.(*JsonObject)
+				if !ok || entry.Len() != 1 {
+					return fmt.Errorf("<%s> %s has an element that isn't an object with one key", name, pair.Key)
+				}
+
+				children = append(children, entry.Oldest())
+			}
+		default:
+			children = append(children, pair)
+		}
+	}
+
+	if text == "" && len(children) == 0 {
+		writer.WriteString("/>")
+		return nil
+	}
+
+	writer.WriteByte('>')
+	xml.EscapeText(writer, []byte(text))
+	for _, child := range children {
+		if child.Key == writer.opts.textKey() {
+			// text between children, when they come from the ChildrenKey
+			value, err := xmlText(child.Value)
+			if err != nil {
+				return fmt.Errorf("<%s> %s: %w", name, child.Key, err)
+			}
+
+			writer.newline(depth + 1)
+			xml.EscapeText(writer, []byte(value))
+			continue
+		}
+
+		if err := writer.element(child.Key, child.Value, depth+1); err != nil {
+			return err
+		}
+	}
+
+	if len(children) > 0 {
+		writer.newline(depth)
+	}
+
+	writer.WriteString("</" + name + ">")
+	return nil
+}
+
+// the text of a value that can go in an attribute or text node
+func xmlText(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case *JsonObject, []interface{}:
+		return "", fmt.Errorf("expected text, found %s", TypeName(value))
+	}
+
+	return marshalValue(value)
+}
+
+// whether name can be an element or attribute name. namespace prefixes (soap:Envelope) are
+// allowed, since FromXML keeps them.
+func isXMLName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, char := range name {
+		letter := char == '_' || char == ':' || char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char > 0x7f
+		if !letter && (i == 0 || !(char == '-' || char == '.' || char >= '0' && char <= '9')) {
+			return false
+		}
+	}
+
+	return true
+}
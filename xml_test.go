@@ -0,0 +1,129 @@
+package orderedjson
+
+import (
+	"testing"
+)
+
+func TestFromXML(t *testing.T) {
+	tests := []struct {
+		xml  string
+		opts XMLOptions
+		want string
+	}{
+		{`<root a="1" b="x"><z>1</z><y/><z>2</z></root>`, XMLOptions{}, `{"root":{"@a":"1","@b":"x","z":["1","2"],"y":null}}`},
+		{`<a>text</a>`, XMLOptions{}, `{"a":"text"}`},
+		{`<a></a>`, XMLOptions{}, `{"a":null}`},
+		{"<a>\n  <b> x </b>\n</a>", XMLOptions{}, `{"a":{"b":"x"}}`},
+		{`<a>&lt;&amp;&gt; &#233;<![CDATA[<b>]]></a>`, XMLOptions{}, `{"a":"<&> é<b>"}`},
+		{`<?xml version="1.0"?><!-- comment --><!DOCTYPE a><a n="1"/>`, XMLOptions{}, `{"a":{"@n":"1"}}`},
+		{`<soap:Envelope xmlns:soap="urn:s"><soap:Body/></soap:Envelope>`, XMLOptions{}, `{"soap:Envelope":{"@xmlns:soap":"urn:s","soap:Body":null}}`},
+
+		// text next to attributes or children
+		{`<p lang="en">hi</p>`, XMLOptions{}, `{"p":{"@lang":"en","#text":"hi"}}`},
+		{`<p>hi <b>there</b></p>`, XMLOptions{}, `{"p":{"b":"there","#text":"hi"}}`},
+		{`<p lang="en">hi</p>`, XMLOptions{AttributePrefix: "-", TextKey: "_"}, `{"p":{"-lang":"en","_":"hi"}}`},
+
+		// the order of children
+		{`<a x="1">t<b/>u<c>2</c><b/></a>`, XMLOptions{ChildrenKey: "$"}, `{"a":{"@x":"1","$":[{"#text":"t"},{"b":null},{"#text":"u"},{"c":"2"},{"b":null}]}}`},
+		{`<a><b><c/></b></a>`, XMLOptions{ChildrenKey: "$"}, `{"a":{"$":[{"b":{"$":[{"c":null}]}}]}}`},
+	}
+
+	for _, test := range tests {
+		tree, err := FromXML([]byte(test.xml), test.opts)
+		if err != nil {
+			t.Errorf("FromXML(%s): %v", test.xml, err)
+			continue
+		}
+
+		if got, _ := MarshalCompact(tree); got != test.want {
+			t.Errorf("FromXML(%s) = %s, want %s", test.xml, got, test.want)
+		}
+	}
+
+	for _, input := range []string{``, `<!-- only a comment -->`, `<a>`, `<a></b>`, `<a><b></a>`, `<a x=1/>`} {
+		if tree, err := FromXML([]byte(input), XMLOptions{}); err == nil {
+			t.Errorf("FromXML(%s) = %v, want an error", input, tree)
+		}
+	}
+}
+
+func TestToXML(t *testing.T) {
+	tests := []struct {
+		input string
+		opts  XMLOptions
+		want  string
+	}{
+		{`{"root":{"@a":"1","z":["1","2"],"y":null,"n":1.5,"t":true}}`, XMLOptions{}, `<root a="1"><z>1</z><z>2</z><y/><n>1.5</n><t>true</t></root>`},
+		{`{"p":{"@lang":"en","#text":"hi","b":"x"}}`, XMLOptions{}, `<p lang="en">hi<b>x</b></p>`},
+		{`{"a":{"@q":"\"<&>","#text":"<&>"}}`, XMLOptions{}, `<a q="&#34;&lt;&amp;&gt;">&lt;&amp;&gt;</a>`},
+		{`{"a":{"@x":null}}`, XMLOptions{}, `<a x=""/>`},
+		{`{"a":{}}`, XMLOptions{}, `<a/>`},
+		{`{"soap:Envelope":{"@xmlns:soap":"urn:s","soap:Body":null}}`, XMLOptions{}, `<soap:Envelope xmlns:soap="urn:s"><soap:Body/></soap:Envelope>`},
+		{`{"b":[1,2],"a":"x"}`, XMLOptions{Root: "doc"}, `<doc><b>1</b><b>2</b><a>x</a></doc>`},
+		{`{"a":{"@x":"1","b":{"c":["1",null]},"d":"2"}}`, XMLOptions{Indent: "  "}, "<a x=\"1\">\n  <b>\n    <c>1</c>\n    <c/>\n  </b>\n  <d>2</d>\n</a>\n"},
+		{`{"a":{"$":[{"#text":"t"},{"b":null},{"c":"2"},{"b":null}]}}`, XMLOptions{ChildrenKey: "$"}, `<a>t<b/><c>2</c><b/></a>`},
+		{`{"p":{"-lang":"en","_":"hi"}}`, XMLOptions{AttributePrefix: "-", TextKey: "_"}, `<p lang="en">hi</p>`},
+	}
+
+	for _, test := range tests {
+		tree, err := ParseOptions{}.Parse([]byte(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ToXML(tree, test.opts)
+		if err != nil || string(got) != test.want {
+			t.Errorf("ToXML(%s) = %s, %v, want %s", test.input, got, err, test.want)
+		}
+	}
+
+	for _, input := range []string{`{}`, `{"a":1,"b":2}`, `{"1a":1}`, `{"a b":1}`, `{"a":[[1]]}`, `{"a":{"@x":{}}}`, `{"a":{"@1":"x"}}`, `{"a":{"#text":[]}}`} {
+		tree, err := ParseOptions{}.Parse([]byte(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got, err := ToXML(tree, XMLOptions{}); err == nil {
+			t.Errorf("ToXML(%s) = %s, want an error", input, got)
+		}
+	}
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	tests := []struct {
+		xml  string
+		opts XMLOptions
+	}{
+		{`<root a="1" b="&lt;x&gt;"><z>1</z><z>2</z><y/><w k="v">text</w></root>`, XMLOptions{}},
+		{`<ns:a xmlns:ns="urn:x"><ns:b>é &amp; ü</ns:b></ns:a>`, XMLOptions{}},
+		{`<a x="1">t<b/>u<c>2</c><b/></a>`, XMLOptions{ChildrenKey: "$"}},
+		{"<a>\n  <b>\n    <c>1</c>\n  </b>\n</a>\n", XMLOptions{Indent: "  "}},
+	}
+
+	for _, test := range tests {
+		tree, err := FromXML([]byte(test.xml), test.opts)
+		if err != nil {
+			t.Fatalf("FromXML(%s): %v", test.xml, err)
+		}
+
+		if got, err := ToXML(tree, test.opts); err != nil || string(got) != test.xml {
+			t.Errorf("ToXML(FromXML(%s)) = %s, %v", test.xml, got, err)
+		}
+	}
+
+	// the other way, keys of repeated elements only come back together
+	tree, _ := ParseOptions{}.Parse([]byte(`{"a":{"@id":"1","b":["x",null],"c":{"d":"y"},"#text":"z"}}`))
+	data, err := ToXML(tree, XMLOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	back, err := FromXML(data, XMLOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := MarshalCompact(back); got != `{"a":{"@id":"1","b":["x",null],"c":{"d":"y"},"#text":"z"}}` {
+		t.Errorf("FromXML(ToXML) = %s, from %s", got, data)
+	}
+}
@@ -0,0 +1,260 @@
+package orderedjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ToYAML converts the tree to a YAML document, with mappings in the same order as the objects.
+func ToYAML(tree interface{}) ([]byte, error) {
+	node, err := valueToYAMLNode(tree)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func valueToYAMLNode(value interface{}) (*yaml.Node, error) {
+	switch v := value.(type) {
+	case *JsonObject:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for pair := v.Oldest(); pair != nil; pair = pair.Next() {
+			child, err := valueToYAMLNode(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: pair.Key}, child)
+		}
+
+		return node, nil
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			child, err := valueToYAMLNode(item)
+			if err != nil {
+				return nil, err
+			}
+
+			node.Content = append(node.Content, child)
+		}
+
+		return node, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}, nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.FormatFloat(v, 'f', -1, 64)}, nil
+		}
+
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	case json.Number:
+		// written as it is, so big integers keep their digits
+		if strings.ContainsAny(string(v), ".eE") {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: string(v)}, nil
+		}
+
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: string(v)}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}, nil
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+
+	return nil, fmt.Errorf("cannot convert %T to yaml", value)
+}
+
+// FromYAML parses a YAML document whose top level is a mapping. mapping keys keep the order they
+// were written in.
+func FromYAML(data []byte) (*JsonObject, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	if doc.Kind == 0 {
+		return nil, nil
+	}
+
+	value, err := yamlNodeToValue(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, ok := value.(*JsonObject)
+	if !ok {
+		return nil, fmt.Errorf("invalid top level yaml value: expected a mapping, got %s", TypeName(value))
+	}
+
+	return tree, nil
+}
+
+func yamlNodeToValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+
+		return yamlNodeToValue(node.Content[0])
+	case yaml.AliasNode:
+		return yamlNodeToValue(node.Alias)
+	case yaml.MappingNode:
+		tree := orderedmap.New[string, interface{}]()
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+
+			if key.Tag == "!!merge" {
+				if err := mergeYAMLNode(tree, value); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("yaml line %d: only scalar mapping keys can be converted to json", key.Line)
+			}
+
+			child, err := yamlNodeToValue(value)
+			if err != nil {
+				return nil, err
+			}
+
+			tree.Set(key.Value, child)
+		}
+
+		return tree, nil
+	case yaml.SequenceNode:
+		result := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			child, err := yamlNodeToValue(item)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, child)
+		}
+
+		return result, nil
+	case yaml.ScalarNode:
+		return yamlScalarToValue(node)
+	}
+
+	return nil, fmt.Errorf("yaml line %d: unsupported node", node.Line)
+}
+
+// `<<: *anchor` merges, which show up a lot in CI configs. keys that are already set win.
+func mergeYAMLNode(tree *JsonObject, node *yaml.Node) error {
+	sources := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		sources = node.Content
+	}
+
+	for _, source := range sources {
+		value, err := yamlNodeToValue(source)
+		if err != nil {
+			return err
+		}
+
+		object, ok := value.(*JsonObject)
+		if !ok {
+			return fmt.Errorf("yaml line %d: can only merge mappings", source.Line)
+		}
+
+		for pair := object.Oldest(); pair != nil; pair = pair.Next() {
+			if _, present := tree.Get(pair.Key); !present {
+				tree.Set(pair.Key, pair.Value)
+			}
+		}
+	}
+
+	return nil
+}
+
+func yamlScalarToValue(node *yaml.Node) (interface{}, error) {
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool", "!!int", "!!float":
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case int:
+			return yamlInteger(int64(v)), nil
+		case int64:
+			return yamlInteger(v), nil
+		case uint64:
+			if v > 1<<53 {
+				return json.Number(strconv.FormatUint(v, 10)), nil
+			}
+
+			return float64(v), nil
+		case float64:
+			return v, nil
+		}
+
+		return nil, fmt.Errorf("yaml line %d: unsupported scalar %q", node.Line, node.Value)
+	}
+
+	// strings, timestamps, binary etc. all just stay as their text
+	return node.Value, nil
+}
+
+// integers a float64 can't hold exactly are json.Numbers, so they keep their digits
+func yamlInteger(n int64) interface{} {
+	if n > 1<<53 || n < -1<<53 {
+		return json.Number(strconv.FormatInt(n, 10))
+	}
+
+	return float64(n)
+}
+
+// FromYAMLStream calls each with every document of a yaml stream, in order, as tree values. the
+// documents don't have to be mappings.
+func FromYAMLStream(r io.Reader, each func(value interface{}) error) error {
+	decoder := yaml.NewDecoder(r)
+	for count := 1; ; count++ {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("document %d: %w", count, err)
+		}
+
+		value, err := yamlNodeToValue(&doc)
+		if err != nil {
+			return fmt.Errorf("document %d: %w", count, err)
+		}
+
+		if err := each(value); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package orderedjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestToYAML(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		back  string
+	}{
+		{`{"z":1,"a":{"y":true,"b":null},"list":[1.5,"x",[]]}`, "z: 1\na:\n  y: true\n  b: null\nlist:\n  - 1.5\n  - x\n  - []\n", ""},
+		{`{}`, "{}\n", ""},
+		{`{"s":"true","n":"1","e":"","q":"a: b","m":"two\nlines"}`, "s: \"true\"\nn: \"1\"\ne: \"\"\nq: 'a: b'\nm: |-\n  two\n  lines\n", ""},
+		{`{"big":1e300,"whole":1e15,"neg":-0.25}`, "big: 1e+300\nwhole: 1e+15\nneg: -0.25\n", `{"big":1e+300,"whole":1000000000000000,"neg":-0.25}`},
+		{`{"key with: colon":{},"-":[{"a":1},{"b":2}]}`, "'key with: colon': {}\n'-':\n  - a: 1\n  - b: 2\n", ""},
+	}
+
+	for _, test := range tests {
+		tree, err := ParseOptions{}.Parse([]byte(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ToYAML(tree)
+		if err != nil || string(got) != test.want {
+			t.Errorf("ToYAML(%s) = %q, %v, want %q", test.input, got, err, test.want)
+			continue
+		}
+
+		// and back
+		back, err := FromYAML(got)
+		if err != nil {
+			t.Errorf("FromYAML(%q): %v", got, err)
+			continue
+		}
+
+		want := test.input
+		if test.back != "" {
+			want = test.back
+		}
+
+		if text, _ := MarshalCompact(back); text != want {
+			t.Errorf("FromYAML(ToYAML(%s)) = %s, want %s", test.input, text, want)
+		}
+	}
+
+	if data, err := ToYAML(map[string]interface{}{"a": 1}); err == nil {
+		t.Errorf("ToYAML(map) = %q, want an error", data)
+	}
+}
+
+func TestFromYAML(t *testing.T) {
+	tests := []struct {
+		yaml string
+		want string
+	}{
+		{"z: 1\na: 2\nm:\n  y: x\n  b: [1, 2]\n", `{"z":1,"a":2,"m":{"y":"x","b":[1,2]}}`},
+		// anchors and aliases are copies of what they point at
+		{"base: &b\n  z: 1\n  a: 2\ncopy: *b\nlist: [*b]\n", `{"base":{"z":1,"a":2},"copy":{"z":1,"a":2},"list":[{"z":1,"a":2}]}`},
+		// merged keys come where the merge is, and the keys already there win
+		{"base: &b\n  z: 1\n  a: 2\nx:\n  a: 3\n  <<: *b\n  c: 4\n", `{"base":{"z":1,"a":2},"x":{"a":3,"z":1,"c":4}}`},
+		{"one: &one {a: 1}\ntwo: &two {b: 2, a: 5}\nx:\n  <<: [*one, *two]\n", `{"one":{"a":1},"two":{"b":2,"a":5},"x":{"a":1,"b":2}}`},
+		{"s: yes\nt: true\nn: ~\nd: 2001-12-14\ni: 0x1F\nf: .5\nq: '1'\n", `{"s":"yes","t":true,"n":null,"d":"2001-12-14","i":31,"f":0.5,"q":"1"}`},
+		{"big: 12345678901234567890\nneg: -9007199254740993\nexact: 9007199254740992\n", `{"big":12345678901234567890,"neg":-9007199254740993,"exact":9007199254740992}`},
+		{"---\na: 1\n", `{"a":1}`},
+	}
+
+	for _, test := range tests {
+		tree, err := FromYAML([]byte(test.yaml))
+		if err != nil {
+			t.Errorf("FromYAML(%q): %v", test.yaml, err)
+			continue
+		}
+
+		if got, _ := MarshalCompact(tree); got != test.want {
+			t.Errorf("FromYAML(%q) = %s, want %s", test.yaml, got, test.want)
+		}
+	}
+
+	// integers a float64 can't hold are json.Numbers
+	tree, _ := FromYAML([]byte("big: 12345678901234567890\nsmall: 1\n"))
+	if big, ok := tree.Value("big").(json.Number); !ok || big != "12345678901234567890" {
+		t.Errorf("big = %#v", tree.Value("big"))
+	}
+
+	if small, ok := tree.Value("small").(float64); !ok || small != 1 {
+		t.Errorf("small = %#v", tree.Value("small"))
+	}
+
+	for _, input := range []string{"- 1\n", "a\n", "a: [1\n", "[1]: 2\n", "a: &x 1\nb:\n  <<: *x\n"} {
+		if tree, err := FromYAML([]byte(input)); err == nil {
+			t.Errorf("FromYAML(%q) = %v, want an error", input, tree)
+		}
+	}
+}
+
+func TestFromYAMLStream(t *testing.T) {
+	var docs []string
+	err := FromYAMLStream(strings.NewReader("b: 1\na: 2\n---\n- x\n---\nnull\n"), func(value interface{}) error {
+		text, err := MarshalCompact(value)
+		docs = append(docs, text)
+		return err
+	})
+
+	if err != nil || strings.Join(docs, " ") != `{"b":1,"a":2} ["x"] null` {
+		t.Errorf("FromYAMLStream = %v, %v", docs, err)
+	}
+
+	if err := FromYAMLStream(bytes.NewReader([]byte("a: 1\n---\na: [\n")), func(interface{}) error { return nil }); err == nil || !strings.HasPrefix(err.Error(), "document 2: ") {
+		t.Errorf("FromYAMLStream(bad second document) = %v", err)
+	}
+}